@@ -0,0 +1,135 @@
+package attest
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSignVerify(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		signer crypto.Signer
+	}{
+		{name: "ed25519", signer: edKey},
+		{name: "ecdsa", signer: ecKey},
+		{name: "rsa", signer: rsaKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statement := NewStatement("a.qmd", "abc123", map[string]string{"server": "test"})
+
+			envelope, err := Sign(statement, tt.signer)
+			if err != nil {
+				t.Fatalf("Sign() error = %v", err)
+			}
+
+			got, err := Verify(envelope, tt.signer.Public())
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if got.Subject[0].Digest["sha256"] != "abc123" {
+				t.Errorf("Subject = %+v", got.Subject)
+			}
+
+			envelope.Payload = append([]byte(nil), envelope.Payload...)
+			envelope.Payload[len(envelope.Payload)-2] ^= 1
+			if _, err := Verify(envelope, tt.signer.Public()); err == nil {
+				t.Error("Verify() accepted a tampered payload")
+			}
+		})
+	}
+}
+
+func TestVerifyWrongKey(t *testing.T) {
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	other, _, _ := ed25519.GenerateKey(rand.Reader)
+
+	envelope, err := Sign(NewStatement("a.qmd", "abc123", nil), key)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	if _, err := Verify(envelope, other); err == nil {
+		t.Error("Verify() accepted a signature from a different key")
+	}
+}
+
+func TestLoadKeys(t *testing.T) {
+	dir := t.TempDir()
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := x509.MarshalECPrivateKey(ecKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privPath := filepath.Join(dir, "key.pem")
+	writePEM(t, privPath, "EC PRIVATE KEY", der)
+
+	pubDER, err := x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubPath := filepath.Join(dir, "key.pub.pem")
+	writePEM(t, pubPath, "PUBLIC KEY", pubDER)
+
+	signer, err := LoadSigner(privPath)
+	if err != nil {
+		t.Fatalf("LoadSigner() error = %v", err)
+	}
+
+	for _, path := range []string{pubPath, privPath} {
+		pub, err := LoadPublicKey(path)
+		if err != nil {
+			t.Fatalf("LoadPublicKey(%s) error = %v", path, err)
+		}
+		if !ecKey.PublicKey.Equal(pub) {
+			t.Errorf("LoadPublicKey(%s) returned a different key", path)
+		}
+	}
+
+	envelope, err := Sign(NewStatement("a.qmd", "abc123", nil), signer)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	if _, err := Verify(envelope, &ecKey.PublicKey); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+
+	if _, err := LoadSigner(filepath.Join(dir, "missing.pem")); err == nil {
+		t.Error("LoadSigner() expected error for missing file")
+	}
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,128 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	EnvVarLog  = "QMDVERIFY_AUDIT_LOG"
+	MaxSize    = 10 * 1024 * 1024
+	MaxBackups = 5
+)
+
+const (
+	OutcomeSuccess      = "success"
+	OutcomeIncompatible = "incompatible"
+	OutcomeError        = "error"
+)
+
+type File struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+type Entry struct {
+	Time       time.Time `json:"time"`
+	Command    string    `json:"command"`
+	Args       []string  `json:"args"`
+	Files      []File    `json:"files,omitempty"`
+	Server     string    `json:"server,omitempty"`
+	Profile    string    `json:"profile,omitempty"`
+	Org        string    `json:"org,omitempty"`
+	Outcome    string    `json:"outcome"`
+	ExitCode   int       `json:"exit_code"`
+	Error      string    `json:"error,omitempty"`
+	DurationMS int64     `json:"duration_ms"`
+	CLIVersion string    `json:"cli_version"`
+}
+
+// Path returns the configured audit log, or "" when auditing is off.
+func Path() string {
+	return os.Getenv(EnvVarLog)
+}
+
+// Append writes the entry as one JSON line, rotating the log first if it
+// would grow past MaxSize.
+func Append(path string, entry Entry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+	line = append(line, '\n')
+
+	if info, err := os.Stat(path); err == nil && info.Size()+int64(len(line)) > MaxSize {
+		if err := rotate(path); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(line); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+
+	return nil
+}
+
+// rotate shifts path.1..path.N-1 up by one, dropping the oldest, and moves
+// the current log to path.1.
+func rotate(path string) error {
+	os.Remove(fmt.Sprintf("%s.%d", path, MaxBackups))
+	for i := MaxBackups - 1; i >= 1; i-- {
+		from := fmt.Sprintf("%s.%d", path, i)
+		if _, err := os.Stat(from); err == nil {
+			if err := os.Rename(from, fmt.Sprintf("%s.%d", path, i+1)); err != nil {
+				return fmt.Errorf("failed to rotate audit log: %w", err)
+			}
+		}
+	}
+
+	if err := os.Rename(path, path+".1"); err != nil {
+		return fmt.Errorf("failed to rotate audit log: %w", err)
+	}
+
+	return nil
+}
+
+// RedactArgs hides the values of flags that look like credentials.
+func RedactArgs(args []string) []string {
+	redacted := make([]string, len(args))
+	copy(redacted, args)
+
+	for i := 0; i < len(redacted); i++ {
+		arg := redacted[i]
+		if !strings.HasPrefix(arg, "-") || !isSecretFlag(arg) {
+			continue
+		}
+		if name, _, ok := strings.Cut(arg, "="); ok {
+			redacted[i] = name + "=REDACTED"
+			continue
+		}
+		if i+1 < len(redacted) {
+			redacted[i+1] = "REDACTED"
+			i++
+		}
+	}
+
+	return redacted
+}
+
+var secretFlags = map[string]bool{
+	"token":    true,
+	"password": true,
+	"secret":   true,
+}
+
+func isSecretFlag(arg string) bool {
+	name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+	return secretFlags[name]
+}
@@ -0,0 +1,129 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestAppend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	for _, outcome := range []string{OutcomeSuccess, OutcomeIncompatible} {
+		if err := Append(path, Entry{Command: "qmdverify check", Outcome: outcome}); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var outcomes []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("line is not JSON: %v", err)
+		}
+		outcomes = append(outcomes, entry.Outcome)
+	}
+
+	if want := []string{OutcomeSuccess, OutcomeIncompatible}; !reflect.DeepEqual(outcomes, want) {
+		t.Errorf("outcomes = %v, want %v", outcomes, want)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("audit log mode = %o, want 600", perm)
+	}
+}
+
+func TestAppendRotates(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.log")
+
+	if err := os.WriteFile(path, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(path, MaxSize); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= MaxBackups; i++ {
+		if err := os.WriteFile(path+"."+string(rune('0'+i)), []byte{byte(i)}, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := Append(path, Entry{Command: "qmdverify check", Outcome: OutcomeSuccess}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() >= MaxSize {
+		t.Errorf("log was not rotated, size = %d", info.Size())
+	}
+
+	rotated, err := os.Stat(path + ".1")
+	if err != nil || rotated.Size() != MaxSize {
+		t.Errorf("%s.1 should hold the previous log: %v", path, err)
+	}
+
+	data, err := os.ReadFile(path + ".2")
+	if err != nil || len(data) != 1 || data[0] != 1 {
+		t.Errorf("%s.2 = %v, %v; want old backup 1", path, data, err)
+	}
+
+	data, err = os.ReadFile(path + "." + string(rune('0'+MaxBackups)))
+	if err != nil || data[0] != MaxBackups-1 {
+		t.Errorf("oldest backup should be dropped, got %v, %v", data, err)
+	}
+}
+
+func TestRedactArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{
+			name: "no secrets",
+			args: []string{"check", "--device", "rmpp", "a.qmd"},
+			want: []string{"check", "--device", "rmpp", "a.qmd"},
+		},
+		{
+			name: "separate value",
+			args: []string{"check", "--token", "abc123", "a.qmd"},
+			want: []string{"check", "--token", "REDACTED", "a.qmd"},
+		},
+		{
+			name: "inline value",
+			args: []string{"check", "--token=abc123", "a.qmd"},
+			want: []string{"check", "--token=REDACTED", "a.qmd"},
+		},
+		{
+			name: "boolean flag mentioning token",
+			args: []string{"auth", "login", "--with-token"},
+			want: []string{"auth", "login", "--with-token"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RedactArgs(tt.args); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RedactArgs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,409 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/attest"
+)
+
+const (
+	FormatVersion = 1
+	ManifestName  = "manifest.json"
+	SignatureName = "manifest.sig.json"
+	HashtableDir  = "hashtables"
+	TreeDir       = "trees"
+	PredicateType = "https://github.com/rmitchellscott/rm-qmd-verify-cli/bundle/v1"
+)
+
+var ErrUnsigned = errors.New("bundle manifest is not signed")
+
+// Entry describes one file in the bundle. Path is relative to the bundle
+// root and uses forward slashes.
+type Entry struct {
+	Path       string `json:"path"`
+	SHA256     string `json:"sha256"`
+	Size       int64  `json:"size"`
+	Name       string `json:"name,omitempty"`
+	OSVersion  string `json:"os_version"`
+	Device     string `json:"device"`
+	EntryCount int    `json:"entry_count,omitempty"`
+}
+
+type Manifest struct {
+	FormatVersion int       `json:"format_version"`
+	Server        string    `json:"server"`
+	ServerVersion string    `json:"server_version,omitempty"`
+	CLIVersion    string    `json:"cli_version"`
+	CreatedAt     time.Time `json:"created_at"`
+	Hashtables    []Entry   `json:"hashtables"`
+	Trees         []Entry   `json:"trees,omitempty"`
+}
+
+func (m *Manifest) entries() []Entry {
+	return append(append([]Entry{}, m.Hashtables...), m.Trees...)
+}
+
+// Describe fills in the size and SHA-256 of a staged file.
+func Describe(dir string, entry *Entry) error {
+	f, err := os.Open(filepath.Join(dir, filepath.FromSlash(entry.Path)))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return fmt.Errorf("failed to hash %s: %w", entry.Path, err)
+	}
+	entry.Size = n
+	entry.SHA256 = hex.EncodeToString(h.Sum(nil))
+	return nil
+}
+
+// FileSHA256 returns the hex-encoded SHA-256 of the file at path.
+func FileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Write archives the manifest and the staged files under dir into out. The
+// archive is compressed according to its extension: .zst for zstd, .gz or
+// .tgz for gzip, anything else is a plain tar. With a signer, the manifest
+// is signed as a DSSE envelope stored next to it.
+func Write(out, dir string, manifest *Manifest, signer crypto.Signer) (err error) {
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	var signature []byte
+	if signer != nil {
+		sum := sha256.Sum256(manifestData)
+		statement := attest.NewStatement(ManifestName, hex.EncodeToString(sum[:]), nil)
+		statement.PredicateType = PredicateType
+		envelope, err := attest.Sign(statement, signer)
+		if err != nil {
+			return err
+		}
+		if signature, err = json.MarshalIndent(envelope, "", "  "); err != nil {
+			return fmt.Errorf("failed to encode manifest signature: %w", err)
+		}
+	}
+
+	f, err := os.Create(out)
+	if err != nil {
+		return fmt.Errorf("failed to create bundle: %w", err)
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(out)
+		}
+	}()
+
+	compressed, err := compressor(out, f)
+	if err != nil {
+		return err
+	}
+
+	tw := tar.NewWriter(compressed)
+	if err := writeBytes(tw, ManifestName, manifestData); err != nil {
+		return err
+	}
+	if signature != nil {
+		if err := writeBytes(tw, SignatureName, signature); err != nil {
+			return err
+		}
+	}
+	for _, entry := range manifest.entries() {
+		if err := writeFile(tw, dir, entry); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if err := compressed.Close(); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return nil
+}
+
+func compressor(name string, w io.Writer) (io.WriteCloser, error) {
+	switch {
+	case strings.HasSuffix(name, ".zst"):
+		return zstd.NewWriter(w)
+	case strings.HasSuffix(name, ".gz"), strings.HasSuffix(name, ".tgz"):
+		return gzip.NewWriter(w), nil
+	}
+	return nopWriteCloser{w}, nil
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+func writeBytes(tw *tar.Writer, name string, data []byte) error {
+	header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: time.Now()}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+func writeFile(tw *tar.Writer, dir string, entry Entry) error {
+	f, err := os.Open(filepath.Join(dir, filepath.FromSlash(entry.Path)))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	header := &tar.Header{Name: entry.Path, Mode: 0644, Size: entry.Size, ModTime: time.Now()}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write %s: %w", entry.Path, err)
+	}
+	if _, err := io.Copy(tw, f); err != nil {
+		return fmt.Errorf("failed to write %s: %w", entry.Path, err)
+	}
+	return nil
+}
+
+// Extract unpacks a bundle into dir and checks every file against the
+// manifest. The manifest and its signature come first in the archive, so
+// with a public key the signature is verified before anything is written;
+// without one, the signature is not checked. Files not listed in the
+// manifest are refused, and files are only moved into dir once the whole
+// bundle has been verified, so a bad bundle leaves nothing behind.
+func Extract(bundlePath, dir string, publicKey crypto.PublicKey) (*Manifest, error) {
+	f, err := os.Open(bundlePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bundle: %w", err)
+	}
+	defer f.Close()
+
+	r, err := decompressor(bufio.NewReader(f))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	staging, err := os.MkdirTemp(dir, ".tmp-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+
+	var manifestData, signature []byte
+	var manifest *Manifest
+	pending := make(map[string]Entry)
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bundle: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name := path.Clean(header.Name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return nil, fmt.Errorf("bundle contains unsafe path %q", header.Name)
+		}
+
+		switch {
+		case name == ManifestName && manifest == nil:
+			if manifestData, err = io.ReadAll(tr); err != nil {
+				return nil, fmt.Errorf("failed to read manifest: %w", err)
+			}
+			continue
+		case name == SignatureName && manifest == nil:
+			if signature, err = io.ReadAll(tr); err != nil {
+				return nil, fmt.Errorf("failed to read manifest signature: %w", err)
+			}
+			continue
+		}
+
+		if manifest == nil {
+			if manifest, err = readManifest(manifestData, signature, publicKey); err != nil {
+				return nil, err
+			}
+			for _, entry := range manifest.entries() {
+				pending[entry.Path] = entry
+			}
+		}
+
+		entry, ok := pending[name]
+		if !ok {
+			return nil, fmt.Errorf("bundle contains %s, which is not listed in the manifest", header.Name)
+		}
+		delete(pending, name)
+		if err := extractEntry(tr, staging, entry); err != nil {
+			return nil, err
+		}
+	}
+
+	if manifest == nil {
+		if manifest, err = readManifest(manifestData, signature, publicKey); err != nil {
+			return nil, err
+		}
+	}
+	for _, entry := range manifest.entries() {
+		if _, ok := pending[entry.Path]; ok {
+			return nil, fmt.Errorf("bundle is missing %s", entry.Path)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(staging, ManifestName), manifestData, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write manifest: %w", err)
+	}
+	for _, name := range append(entryPaths(manifest), ManifestName) {
+		dest := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create directory: %w", err)
+		}
+		if err := os.Rename(filepath.Join(staging, filepath.FromSlash(name)), dest); err != nil {
+			return nil, fmt.Errorf("failed to extract %s: %w", name, err)
+		}
+	}
+
+	return manifest, nil
+}
+
+// readManifest verifies the manifest signature, when there is a public key,
+// and parses the manifest.
+func readManifest(manifestData, signature []byte, publicKey crypto.PublicKey) (*Manifest, error) {
+	if manifestData == nil {
+		return nil, fmt.Errorf("bundle has no %s before its files", ManifestName)
+	}
+	if publicKey != nil {
+		if err := verifySignature(manifestData, signature, publicKey); err != nil {
+			return nil, err
+		}
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	if manifest.FormatVersion != FormatVersion {
+		return nil, fmt.Errorf("unsupported bundle format version %d", manifest.FormatVersion)
+	}
+	return &manifest, nil
+}
+
+func entryPaths(m *Manifest) []string {
+	var paths []string
+	for _, entry := range m.entries() {
+		paths = append(paths, entry.Path)
+	}
+	return paths
+}
+
+// extractEntry writes one bundle file under dir, checking it against its
+// manifest entry as it is written.
+func extractEntry(r io.Reader, dir string, entry Entry) error {
+	h := sha256.New()
+	if err := extractFile(io.TeeReader(r, h), filepath.Join(dir, filepath.FromSlash(entry.Path))); err != nil {
+		return err
+	}
+	if hex.EncodeToString(h.Sum(nil)) != entry.SHA256 {
+		return fmt.Errorf("checksum mismatch for %s", entry.Path)
+	}
+	return nil
+}
+
+func decompressor(r *bufio.Reader) (io.ReadCloser, error) {
+	magic, _ := r.Peek(4)
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		decoder, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bundle: %w", err)
+		}
+		return decoder.IOReadCloser(), nil
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bundle: %w", err)
+		}
+		return gz, nil
+	}
+	return io.NopCloser(r), nil
+}
+
+func extractFile(r io.Reader, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	f, err := os.Create(dest)
+	if err != nil {
+		return fmt.Errorf("failed to extract %s: %w", dest, err)
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, r); err != nil {
+		return fmt.Errorf("failed to extract %s: %w", dest, err)
+	}
+	return nil
+}
+
+func verifySignature(manifestData, signature []byte, publicKey crypto.PublicKey) error {
+	if signature == nil {
+		return ErrUnsigned
+	}
+
+	var envelope attest.Envelope
+	if err := json.Unmarshal(signature, &envelope); err != nil {
+		return fmt.Errorf("failed to parse manifest signature: %w", err)
+	}
+
+	statement, err := attest.Verify(&envelope, publicKey)
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(manifestData)
+	if statement.PredicateType != PredicateType || len(statement.Subject) != 1 ||
+		statement.Subject[0].Digest["sha256"] != hex.EncodeToString(sum[:]) {
+		return fmt.Errorf("manifest signature does not match the manifest")
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package bundle
+
+import (
+	"archive/tar"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func stage(t *testing.T) (string, *Manifest) {
+	t.Helper()
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"hashtables/3.22.0.64-rmpp": "hashtab data",
+		"trees/3.22.0.64-rmpp.tar":  "tree data",
+	}
+	for name, content := range files {
+		dest := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(dest, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	manifest := &Manifest{
+		FormatVersion: FormatVersion,
+		Server:        "https://qmd.example.com",
+		CLIVersion:    "v1.0.0",
+		CreatedAt:     time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		Hashtables:    []Entry{{Path: "hashtables/3.22.0.64-rmpp", Name: "3.22.0.64-rmpp", OSVersion: "3.22.0.64", Device: "rmpp"}},
+		Trees:         []Entry{{Path: "trees/3.22.0.64-rmpp.tar", OSVersion: "3.22.0.64", Device: "rmpp"}},
+	}
+	for _, entries := range [][]Entry{manifest.Hashtables, manifest.Trees} {
+		for i := range entries {
+			if err := Describe(dir, &entries[i]); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	return dir, manifest
+}
+
+func TestWriteExtract(t *testing.T) {
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPub, _, _ := ed25519.GenerateKey(rand.Reader)
+
+	for _, name := range []string{"data.tar.zst", "data.tar.gz", "data.tar"} {
+		t.Run(name, func(t *testing.T) {
+			dir, manifest := stage(t)
+			out := filepath.Join(t.TempDir(), name)
+			if err := Write(out, dir, manifest, key); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+
+			dest := t.TempDir()
+			got, err := Extract(out, dest, pub)
+			if err != nil {
+				t.Fatalf("Extract() error = %v", err)
+			}
+			if got.Server != manifest.Server || len(got.Hashtables) != 1 || len(got.Trees) != 1 {
+				t.Errorf("Extract() manifest = %+v", got)
+			}
+
+			data, err := os.ReadFile(filepath.Join(dest, "hashtables", "3.22.0.64-rmpp"))
+			if err != nil || string(data) != "hashtab data" {
+				t.Errorf("extracted hashtable = %q, %v", data, err)
+			}
+
+			rejected := t.TempDir()
+			if _, err := Extract(out, rejected, otherPub); err == nil {
+				t.Error("Extract() expected error for the wrong public key")
+			}
+			assertEmpty(t, rejected)
+		})
+	}
+}
+
+func TestExtract_Unsigned(t *testing.T) {
+	dir, manifest := stage(t)
+	out := filepath.Join(t.TempDir(), "data.tar.zst")
+	if err := Write(out, dir, manifest, nil); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	if _, err := Extract(out, t.TempDir(), nil); err != nil {
+		t.Errorf("Extract() without a key error = %v", err)
+	}
+
+	pub, _, _ := ed25519.GenerateKey(rand.Reader)
+	if _, err := Extract(out, t.TempDir(), pub); !errors.Is(err, ErrUnsigned) {
+		t.Errorf("Extract() error = %v, want ErrUnsigned", err)
+	}
+}
+
+func TestExtract_ChecksumMismatch(t *testing.T) {
+	dir, manifest := stage(t)
+	manifest.Hashtables[0].SHA256 = "0000"
+	out := filepath.Join(t.TempDir(), "data.tar")
+	if err := Write(out, dir, manifest, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	dest := t.TempDir()
+	if _, err := Extract(out, dest, nil); err == nil {
+		t.Error("Extract() expected checksum mismatch")
+	}
+	assertEmpty(t, dest)
+}
+
+func TestExtract_UnlistedFile(t *testing.T) {
+	dir, manifest := stage(t)
+	manifest.Trees = nil
+	manifestData, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(t.TempDir(), "data.tar")
+	f, err := os.Create(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tar.NewWriter(f)
+	if err := writeBytes(tw, ManifestName, manifestData); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFile(tw, dir, manifest.Hashtables[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeBytes(tw, "hashtables/extra", []byte("unlisted")); err != nil {
+		t.Fatal(err)
+	}
+	tw.Close()
+	f.Close()
+
+	dest := t.TempDir()
+	if _, err := Extract(out, dest, nil); err == nil || !strings.Contains(err.Error(), "not listed in the manifest") {
+		t.Errorf("Extract() error = %v, want an unlisted file error", err)
+	}
+	assertEmpty(t, dest)
+}
+
+func TestExtract_ManifestAfterFiles(t *testing.T) {
+	dir, manifest := stage(t)
+	manifest.Trees = nil
+	manifestData, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(t.TempDir(), "data.tar")
+	f, err := os.Create(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tar.NewWriter(f)
+	if err := writeFile(tw, dir, manifest.Hashtables[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeBytes(tw, ManifestName, manifestData); err != nil {
+		t.Fatal(err)
+	}
+	tw.Close()
+	f.Close()
+
+	dest := t.TempDir()
+	if _, err := Extract(out, dest, nil); err == nil {
+		t.Error("Extract() expected error for a manifest after the files")
+	}
+	assertEmpty(t, dest)
+}
+
+// assertEmpty fails if a rejected bundle left anything in dir.
+func assertEmpty(t *testing.T, dir string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		t.Errorf("rejected bundle left %s behind", entry.Name())
+	}
+}
+
+func TestExtract_UnsafePath(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "evil.tar")
+	f, err := os.Create(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tar.NewWriter(f)
+	if err := writeBytes(tw, "../escape", []byte("x")); err != nil {
+		t.Fatal(err)
+	}
+	tw.Close()
+	f.Close()
+
+	if _, err := Extract(out, t.TempDir(), nil); err == nil {
+		t.Error("Extract() expected error for a path outside the bundle")
+	}
+}
+
+func TestFileSHA256(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.qmd")
+	if err := os.WriteFile(path, []byte("abc"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := FileSHA256(path)
+	if err != nil {
+		t.Fatalf("FileSHA256() error = %v", err)
+	}
+	want := "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+	if got != want {
+		t.Errorf("FileSHA256() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,71 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDir(t *testing.T) {
+	t.Setenv(EnvVarCacheDir, "/tmp/custom-cache")
+
+	dir, err := Dir()
+	if err != nil {
+		t.Fatalf("Dir() error = %v", err)
+	}
+	if dir != "/tmp/custom-cache" {
+		t.Errorf("Dir() = %v, want %v", dir, "/tmp/custom-cache")
+	}
+}
+
+func TestReadWriteJSON(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(EnvVarCacheDir, dir)
+
+	type payload struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+	}
+
+	var missing payload
+	found, err := ReadJSON("missing.json", &missing)
+	if err != nil || found {
+		t.Fatalf("ReadJSON() on missing file = %v, %v; want false, nil", found, err)
+	}
+
+	want := payload{Name: "hashtables", Count: 3}
+	if err := WriteJSON("nested/data.json", want); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+
+	var got payload
+	found, err = ReadJSON("nested/data.json", &got)
+	if err != nil || !found {
+		t.Fatalf("ReadJSON() = %v, %v; want true, nil", found, err)
+	}
+	if got != want {
+		t.Errorf("ReadJSON() = %+v, want %+v", got, want)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(dir, "nested"))
+	if err != nil {
+		t.Fatalf("Failed to read cache dir: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("cache dir has %d entries, want 1 (temp files should be cleaned up)", len(entries))
+	}
+}
+
+func TestReadJSONCorrupt(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(EnvVarCacheDir, dir)
+
+	if err := os.WriteFile(filepath.Join(dir, "bad.json"), []byte("{not json"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	var v map[string]any
+	if _, err := ReadJSON("bad.json", &v); err == nil {
+		t.Error("ReadJSON() expected error for corrupt file")
+	}
+}
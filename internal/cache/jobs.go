@@ -0,0 +1,103 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const jobsDir = "jobs"
+
+// JobResults is a job's results as fetched from the server. Note is the
+// --note given to the run, saying what changed since the previous one.
+type JobResults struct {
+	JobID     string          `json:"job_id"`
+	Server    string          `json:"server"`
+	FetchedAt time.Time       `json:"fetched_at"`
+	Note      string          `json:"note,omitempty"`
+	Results   json.RawMessage `json:"results"`
+}
+
+// SaveJobResults stores the raw results of a completed job and the run's
+// note under jobs/<id>.json.
+func SaveJobResults(server, jobID, note string, body []byte) error {
+	name, err := jobFile(jobID)
+	if err != nil {
+		return err
+	}
+	if !json.Valid(body) {
+		return fmt.Errorf("results of job %s are not valid JSON", jobID)
+	}
+
+	return WriteJSON(name, JobResults{
+		JobID:     jobID,
+		Server:    server,
+		FetchedAt: time.Now().UTC(),
+		Note:      note,
+		Results:   body,
+	})
+}
+
+// LoadJobResults returns the stored results of a job, or false if there are
+// none.
+func LoadJobResults(jobID string) (*JobResults, bool, error) {
+	name, err := jobFile(jobID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var job JobResults
+	found, err := ReadJSON(name, &job)
+	if err != nil || !found {
+		return nil, false, err
+	}
+	return &job, true, nil
+}
+
+// ListJobResults returns every stored job, most recently fetched first.
+func ListJobResults() ([]JobResults, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(filepath.Join(dir, jobsDir))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stored jobs: %w", err)
+	}
+
+	var jobs []JobResults
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() || strings.HasPrefix(id, ".") {
+			continue
+		}
+		job, found, err := LoadJobResults(id)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			jobs = append(jobs, *job)
+		}
+	}
+
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].FetchedAt.After(jobs[j].FetchedAt) })
+	return jobs, nil
+}
+
+func jobFile(jobID string) (string, error) {
+	if jobID == "" || strings.HasPrefix(jobID, ".") || strings.ContainsAny(jobID, `/\`) {
+		return "", fmt.Errorf("invalid job ID %q", jobID)
+	}
+	return path.Join(jobsDir, jobID+".json"), nil
+}
@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJobResults(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(EnvVarCacheDir, dir)
+
+	if _, found, err := LoadJobResults("missing"); found || err != nil {
+		t.Errorf("LoadJobResults() on a missing job = %v, %v", found, err)
+	}
+
+	body := []byte(`{"total_checked":1}`)
+	if err := SaveJobResults("https://qmd.example.com", "job-1", "new SwipeArea API", body); err != nil {
+		t.Fatalf("SaveJobResults() error = %v", err)
+	}
+	if err := SaveJobResults("https://qmd.example.com", "job-2", "", []byte(`{}`)); err != nil {
+		t.Fatalf("SaveJobResults() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "jobs", "job-1.json")); err != nil {
+		t.Errorf("results not stored under jobs/: %v", err)
+	}
+
+	job, found, err := LoadJobResults("job-1")
+	if err != nil || !found {
+		t.Fatalf("LoadJobResults() = %v, %v", found, err)
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, job.Results); err != nil {
+		t.Fatal(err)
+	}
+	if job.JobID != "job-1" || job.Server != "https://qmd.example.com" || compact.String() != string(body) || job.FetchedAt.IsZero() || job.Note != "new SwipeArea API" {
+		t.Errorf("LoadJobResults() = %+v", job)
+	}
+
+	jobs, err := ListJobResults()
+	if err != nil || len(jobs) != 2 {
+		t.Errorf("ListJobResults() = %+v, %v", jobs, err)
+	}
+
+	for _, id := range []string{"", "../escape", ".hidden", `a\b`} {
+		if err := SaveJobResults("", id, "", body); err == nil {
+			t.Errorf("SaveJobResults(%q) expected error for an unsafe job ID", id)
+		}
+	}
+	if err := SaveJobResults("", "job-3", "", []byte("not json")); err == nil {
+		t.Error("SaveJobResults() expected error for invalid JSON")
+	}
+}
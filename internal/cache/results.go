@@ -0,0 +1,48 @@
+package cache
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+)
+
+const resultsDir = "results"
+
+var resultKeyPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// LoadResults reads the check results stored under key into v, or returns
+// false if there are none. A hit marks the entry as used.
+func LoadResults(key string, v any) (bool, error) {
+	name, err := resultsFile(key)
+	if err != nil {
+		return false, err
+	}
+
+	found, err := ReadJSON(name, v)
+	if err != nil || !found {
+		return false, err
+	}
+	if dir, err := Dir(); err == nil {
+		Touch(filepath.Join(dir, filepath.FromSlash(name)))
+	}
+	return true, nil
+}
+
+// SaveResults stores check results under results/<key>.json. The key is a
+// hex SHA-256 of everything the results depend on, so entries never need
+// invalidating; stale ones are left to prune.
+func SaveResults(key string, v any) error {
+	name, err := resultsFile(key)
+	if err != nil {
+		return err
+	}
+	return WriteJSON(name, v)
+}
+
+func resultsFile(key string) (string, error) {
+	if !resultKeyPattern.MatchString(key) {
+		return "", fmt.Errorf("invalid result key %q", key)
+	}
+	return path.Join(resultsDir, key+".json"), nil
+}
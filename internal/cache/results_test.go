@@ -0,0 +1,37 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResults(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(EnvVarCacheDir, dir)
+	key := strings.Repeat("ab", 32)
+
+	var got map[string]int
+	if found, err := LoadResults(key, &got); found || err != nil {
+		t.Errorf("LoadResults() on a missing key = %v, %v", found, err)
+	}
+
+	if err := SaveResults(key, map[string]int{"total_checked": 3}); err != nil {
+		t.Fatalf("SaveResults() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "results", key+".json")); err != nil {
+		t.Errorf("results not stored under results/: %v", err)
+	}
+
+	found, err := LoadResults(key, &got)
+	if err != nil || !found || got["total_checked"] != 3 {
+		t.Errorf("LoadResults() = %v, %v, %v", got, found, err)
+	}
+
+	for _, bad := range []string{"", "../jobs/x", strings.Repeat("AB", 32)} {
+		if err := SaveResults(bad, got); err == nil {
+			t.Errorf("SaveResults(%q) succeeded, want an invalid key error", bad)
+		}
+	}
+}
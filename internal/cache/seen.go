@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"sort"
+)
+
+const (
+	seenVersionsFile   = "seen-versions.json"
+	seenHashtablesFile = "seen-hashtables.json"
+)
+
+// Seen records, per server host, the keys (OS versions, hashtable names) that
+// have been observed so later runs can tell which ones are new.
+type Seen struct {
+	file    string
+	servers map[string][]string
+}
+
+func LoadSeenVersions() (*Seen, error) {
+	return LoadSeen(seenVersionsFile)
+}
+
+func LoadSeenHashtables() (*Seen, error) {
+	return LoadSeen(seenHashtablesFile)
+}
+
+func LoadSeen(file string) (*Seen, error) {
+	seen := &Seen{file: file, servers: make(map[string][]string)}
+	if _, err := ReadJSON(file, &seen.servers); err != nil {
+		seen.servers = make(map[string][]string)
+		return seen, err
+	}
+	return seen, nil
+}
+
+func (s *Seen) Entries(server string) []string {
+	return s.servers[server]
+}
+
+// NewSince returns the keys not previously recorded for server. It returns
+// nil when the server has never been seen, so a first run does not flag
+// everything as new.
+func (s *Seen) NewSince(server string, keys []string) map[string]bool {
+	previous, ok := s.servers[server]
+	if !ok {
+		return nil
+	}
+
+	known := make(map[string]bool, len(previous))
+	for _, k := range previous {
+		known[k] = true
+	}
+
+	added := make(map[string]bool)
+	for _, k := range keys {
+		if !known[k] {
+			added[k] = true
+		}
+	}
+
+	return added
+}
+
+func (s *Seen) Record(server string, keys []string) {
+	set := make(map[string]bool)
+	for _, k := range s.servers[server] {
+		set[k] = true
+	}
+	for _, k := range keys {
+		set[k] = true
+	}
+
+	merged := make([]string, 0, len(set))
+	for k := range set {
+		merged = append(merged, k)
+	}
+	sort.Strings(merged)
+
+	s.servers[server] = merged
+}
+
+func (s *Seen) Save() error {
+	return WriteJSON(s.file, s.servers)
+}
@@ -0,0 +1,60 @@
+package cache
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSeen(t *testing.T) {
+	t.Setenv(EnvVarCacheDir, t.TempDir())
+
+	seen, err := LoadSeenVersions()
+	if err != nil {
+		t.Fatalf("LoadSeenVersions() error = %v", err)
+	}
+
+	server := "https://qmdverify.example.com"
+	if added := seen.NewSince(server, []string{"3.22.0.64"}); added != nil {
+		t.Errorf("NewSince() on unseen server = %v, want nil", added)
+	}
+
+	seen.Record(server, []string{"3.22.0.64", "3.20.0.92", "3.22.0.64"})
+	if err := seen.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	reloaded, err := LoadSeenVersions()
+	if err != nil {
+		t.Fatalf("LoadSeenVersions() error = %v", err)
+	}
+	if want := []string{"3.20.0.92", "3.22.0.64"}; !reflect.DeepEqual(reloaded.Entries(server), want) {
+		t.Errorf("reloaded versions = %v, want %v", reloaded.Entries(server), want)
+	}
+
+	added := reloaded.NewSince(server, []string{"3.20.0.92", "3.22.0.64", "3.23.0.64"})
+	if want := map[string]bool{"3.23.0.64": true}; !reflect.DeepEqual(added, want) {
+		t.Errorf("NewSince() = %v, want %v", added, want)
+	}
+
+	if added := reloaded.NewSince("https://other.example.com", []string{"3.23.0.64"}); added != nil {
+		t.Errorf("NewSince() for other server = %v, want nil", added)
+	}
+}
+
+func TestSeenFilesAreIndependent(t *testing.T) {
+	t.Setenv(EnvVarCacheDir, t.TempDir())
+
+	versions, _ := LoadSeenVersions()
+	versions.Record("server", []string{"3.22.0.64"})
+	if err := versions.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	hashtables, err := LoadSeenHashtables()
+	if err != nil {
+		t.Fatalf("LoadSeenHashtables() error = %v", err)
+	}
+	if entries := hashtables.Entries("server"); len(entries) != 0 {
+		t.Errorf("LoadSeenHashtables() Entries = %v, want empty", entries)
+	}
+}
@@ -0,0 +1,287 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const EnvVarMaxSize = "QMDVERIFY_CACHE_MAX_SIZE"
+
+// StateArea holds the files kept directly in the cache directory, such as
+// the seen-version records.
+const StateArea = "state"
+
+// unitDepth is how deep below an area's directory its evictable items sit.
+// A sync mirror keeps hashtables in hashtabs/hashtables/<name> next to its
+// manifest, and every downloaded tree is a trees/<name> directory that is
+// evicted as a whole. Files above that depth are bookkeeping, kept by prune
+// and only removed by clear. In other areas every file is an item.
+var unitDepth = map[string]int{
+	"hashtabs": 2,
+	"trees":    1,
+}
+
+// Item is one entry in the cache: a file, or a directory evicted as a whole.
+type Item struct {
+	Path     string
+	Area     string
+	Size     int64
+	LastUsed time.Time
+	// Pinned items are bookkeeping that prune never removes.
+	Pinned bool
+}
+
+// Scan lists the items in the cache directory dir. Hidden files, such as
+// interrupted temporary writes, are skipped. A missing dir holds no items.
+func Scan(dir string) ([]Item, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache directory: %w", err)
+	}
+
+	var items []Item
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		if !entry.IsDir() {
+			item, err := newItem(dir, path, StateArea)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+			continue
+		}
+		areaItems, err := scanArea(dir, path, name)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, areaItems...)
+	}
+
+	sort.Slice(items, func(i, j int) bool { return items[i].Path < items[j].Path })
+	return items, nil
+}
+
+func scanArea(dir, root, area string) ([]Item, error) {
+	depth, nested := unitDepth[area]
+
+	var items []Item
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == root {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, _ := filepath.Rel(root, path)
+		level := strings.Count(filepath.ToSlash(rel), "/") + 1
+		switch {
+		case nested && level == depth:
+			item, err := newItem(dir, path, area)
+			if err != nil {
+				return err
+			}
+			items = append(items, item)
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+		case !d.IsDir():
+			item, err := newItem(dir, path, area)
+			if err != nil {
+				return err
+			}
+			item.Pinned = nested
+			items = append(items, item)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache directory %s: %w", area, err)
+	}
+	return items, nil
+}
+
+// newItem describes path. A directory's size is the total of its files and
+// it was last used when it or any file in it was last modified.
+func newItem(dir, path, area string) (Item, error) {
+	rel, _ := filepath.Rel(dir, path)
+	item := Item{Path: filepath.ToSlash(rel), Area: area}
+
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			item.Size += info.Size()
+		}
+		if info.ModTime().After(item.LastUsed) {
+			item.LastUsed = info.ModTime()
+		}
+		return nil
+	})
+	if err != nil {
+		return Item{}, fmt.Errorf("failed to read cache entry %s: %w", item.Path, err)
+	}
+	return item, nil
+}
+
+// Prune removes the unpinned items in dir last used before now minus
+// olderThan, then the least recently used ones until the cache is no larger
+// than maxSize. A zero olderThan or maxSize disables that limit.
+func Prune(dir string, olderThan time.Duration, maxSize int64, now time.Time) ([]Item, error) {
+	items, err := Scan(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	var candidates []Item
+	for _, item := range items {
+		total += item.Size
+		if !item.Pinned {
+			candidates = append(candidates, item)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].LastUsed.Before(candidates[j].LastUsed)
+	})
+
+	var removed []Item
+	for _, item := range candidates {
+		stale := olderThan > 0 && item.LastUsed.Before(now.Add(-olderThan))
+		over := maxSize > 0 && total > maxSize
+		if !stale && !over {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(dir, filepath.FromSlash(item.Path))); err != nil {
+			return removed, fmt.Errorf("failed to remove %s: %w", item.Path, err)
+		}
+		total -= item.Size
+		removed = append(removed, item)
+	}
+	return removed, nil
+}
+
+// Clear removes every item in dir, or only those in the given areas.
+func Clear(dir string, areas []string) ([]Item, error) {
+	items, err := Scan(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := make(map[string]bool, len(areas))
+	for _, area := range areas {
+		selected[area] = true
+	}
+
+	var removed []Item
+	for _, item := range items {
+		if len(areas) > 0 && !selected[item.Area] {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(dir, filepath.FromSlash(item.Path))); err != nil {
+			return removed, fmt.Errorf("failed to remove %s: %w", item.Path, err)
+		}
+		removed = append(removed, item)
+	}
+	return removed, nil
+}
+
+// Enforce prunes the cache down to QMDVERIFY_CACHE_MAX_SIZE, if set.
+func Enforce() ([]Item, error) {
+	value := os.Getenv(EnvVarMaxSize)
+	if value == "" {
+		return nil, nil
+	}
+	maxSize, err := ParseSize(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", EnvVarMaxSize, err)
+	}
+
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	return Prune(dir, 0, maxSize, time.Now())
+}
+
+// Touch marks a cached file or directory as used now, so size-limited
+// pruning evicts it last. Paths outside the cache directory, such as an
+// extracted bundle passed to --local, are left alone.
+func Touch(path string) {
+	dir, err := Dir()
+	if err != nil {
+		return
+	}
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return
+	}
+	now := time.Now()
+	os.Chtimes(path, now, now)
+}
+
+// ParseSize parses a size such as 500MB, 2GiB or 1048576. Units are powers
+// of 1024 either way.
+func ParseSize(s string) (int64, error) {
+	value := strings.ToUpper(strings.TrimSpace(s))
+	number := strings.TrimRight(value, "KMGTIB ")
+	unit := strings.TrimSpace(value[len(number):])
+
+	multipliers := map[string]int64{
+		"": 1, "B": 1,
+		"K": 1 << 10, "KB": 1 << 10, "KIB": 1 << 10,
+		"M": 1 << 20, "MB": 1 << 20, "MIB": 1 << 20,
+		"G": 1 << 30, "GB": 1 << 30, "GIB": 1 << 30,
+		"T": 1 << 40, "TB": 1 << 40, "TIB": 1 << 40,
+	}
+	multiplier, ok := multipliers[unit]
+	n, err := strconv.ParseFloat(number, 64)
+	if !ok || err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q (e.g. 500MB, 2GB)", s)
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
+// ParseAge parses an age such as 30d, 2w or 12h.
+func ParseAge(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if number, ok := strings.CutSuffix(s, suffix); ok {
+			n, err := strconv.ParseFloat(number, 64)
+			if err != nil || n < 0 {
+				break
+			}
+			return time.Duration(n * float64(unit)), nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age %q (e.g. 30d, 2w, 12h)", s)
+	}
+	return d, nil
+}
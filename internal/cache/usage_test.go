@@ -0,0 +1,250 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// populate builds a cache with a mirror, a tree and a state file, each last
+// used the given number of days before now.
+func populate(t *testing.T, now time.Time) string {
+	t.Helper()
+	dir := t.TempDir()
+
+	files := []struct {
+		path string
+		size int
+		age  int
+	}{
+		{"seen-versions.json", 10, 1},
+		{"hashtabs/manifest.json", 20, 90},
+		{"hashtabs/hashtables/3.20.0.92-rmpp", 100, 60},
+		{"hashtabs/hashtables/3.22.0.64-rmpp", 100, 2},
+		{"trees/3.22.0.64-rmpp/qml/Main.qml", 50, 40},
+		{"trees/3.22.0.64-rmpp/qml/Other.qml", 50, 40},
+		{".tmp-123", 5, 90},
+	}
+	for _, f := range files {
+		path := filepath.Join(dir, filepath.FromSlash(f.path))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, make([]byte, f.size), 0644); err != nil {
+			t.Fatal(err)
+		}
+		used := now.Add(-time.Duration(f.age) * 24 * time.Hour)
+		if err := os.Chtimes(path, used, used); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	treeUsed := now.Add(-40 * 24 * time.Hour)
+	for _, d := range []string{"trees/3.22.0.64-rmpp/qml", "trees/3.22.0.64-rmpp"} {
+		if err := os.Chtimes(filepath.Join(dir, filepath.FromSlash(d)), treeUsed, treeUsed); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func paths(items []Item) []string {
+	var out []string
+	for _, item := range items {
+		out = append(out, item.Path)
+	}
+	return out
+}
+
+func TestScan(t *testing.T) {
+	now := time.Now()
+	items, err := Scan(populate(t, now))
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	want := []Item{
+		{Path: "hashtabs/hashtables/3.20.0.92-rmpp", Area: "hashtabs", Size: 100},
+		{Path: "hashtabs/hashtables/3.22.0.64-rmpp", Area: "hashtabs", Size: 100},
+		{Path: "hashtabs/manifest.json", Area: "hashtabs", Size: 20, Pinned: true},
+		{Path: "seen-versions.json", Area: StateArea, Size: 10},
+		{Path: "trees/3.22.0.64-rmpp", Area: "trees", Size: 100},
+	}
+	for i := range items {
+		items[i].LastUsed = time.Time{}
+	}
+	if !reflect.DeepEqual(items, want) {
+		t.Errorf("Scan() = %+v, want %+v", items, want)
+	}
+
+	if items, err := Scan(filepath.Join(t.TempDir(), "missing")); err != nil || len(items) != 0 {
+		t.Errorf("Scan() on a missing dir = %v, %v", items, err)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name      string
+		olderThan time.Duration
+		maxSize   int64
+		want      []string
+	}{
+		{
+			name:      "older than 30 days",
+			olderThan: 30 * 24 * time.Hour,
+			want:      []string{"hashtabs/hashtables/3.20.0.92-rmpp", "trees/3.22.0.64-rmpp"},
+		},
+		{
+			name:    "size limit evicts least recently used",
+			maxSize: 150,
+			want:    []string{"hashtabs/hashtables/3.20.0.92-rmpp", "trees/3.22.0.64-rmpp"},
+		},
+		{
+			name:    "within limit",
+			maxSize: 1000,
+		},
+		{
+			name:      "both limits",
+			olderThan: 50 * 24 * time.Hour,
+			maxSize:   200,
+			want:      []string{"hashtabs/hashtables/3.20.0.92-rmpp", "trees/3.22.0.64-rmpp"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := populate(t, now)
+			removed, err := Prune(dir, tt.olderThan, tt.maxSize, now)
+			if err != nil {
+				t.Fatalf("Prune() error = %v", err)
+			}
+			if got := paths(removed); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Prune() removed %v, want %v", got, tt.want)
+			}
+			for _, path := range tt.want {
+				if _, err := os.Stat(filepath.Join(dir, path)); !os.IsNotExist(err) {
+					t.Errorf("%s still exists", path)
+				}
+			}
+			if _, err := os.Stat(filepath.Join(dir, "hashtabs", "manifest.json")); err != nil {
+				t.Errorf("Prune() removed the mirror manifest: %v", err)
+			}
+		})
+	}
+}
+
+func TestClear(t *testing.T) {
+	dir := populate(t, time.Now())
+
+	removed, err := Clear(dir, []string{"trees"})
+	if err != nil {
+		t.Fatalf("Clear() error = %v", err)
+	}
+	if got := paths(removed); !reflect.DeepEqual(got, []string{"trees/3.22.0.64-rmpp"}) {
+		t.Errorf("Clear(trees) removed %v", got)
+	}
+
+	if _, err := Clear(dir, nil); err != nil {
+		t.Fatalf("Clear() error = %v", err)
+	}
+	if items, _ := Scan(dir); len(items) != 0 {
+		t.Errorf("Clear() left %v", paths(items))
+	}
+}
+
+func TestTouch(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(EnvVarCacheDir, filepath.Join(dir, "cache"))
+
+	old := time.Now().Add(-48 * time.Hour)
+	inside := filepath.Join(dir, "cache", "hashtabs", "ht")
+	outside := filepath.Join(dir, "bundle", "ht")
+	for _, path := range []string{inside, outside} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, old, old); err != nil {
+			t.Fatal(err)
+		}
+		Touch(path)
+	}
+
+	if info, _ := os.Stat(inside); !info.ModTime().After(old.Add(time.Hour)) {
+		t.Error("Touch() did not update a cached file")
+	}
+	if info, _ := os.Stat(outside); info.ModTime().After(old.Add(time.Hour)) {
+		t.Error("Touch() updated a file outside the cache")
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{in: "1048576", want: 1 << 20},
+		{in: "500MB", want: 500 << 20},
+		{in: "2GiB", want: 2 << 30},
+		{in: "1.5k", want: 1536},
+		{in: "10 mb", want: 10 << 20},
+		{in: "lots", wantErr: true},
+		{in: "5PB", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSize(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, %v; want %d, wantErr %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{in: "30d", want: 30 * 24 * time.Hour},
+		{in: "2w", want: 14 * 24 * time.Hour},
+		{in: "12h", want: 12 * time.Hour},
+		{in: "soon", wantErr: true},
+		{in: "-1d", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseAge(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseAge(%q) = %v, %v; want %v, wantErr %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestEnforce(t *testing.T) {
+	dir := populate(t, time.Now())
+	t.Setenv(EnvVarCacheDir, dir)
+
+	t.Setenv(EnvVarMaxSize, "")
+	if removed, err := Enforce(); err != nil || len(removed) != 0 {
+		t.Errorf("Enforce() without a limit = %v, %v", paths(removed), err)
+	}
+
+	t.Setenv(EnvVarMaxSize, "200B")
+	removed, err := Enforce()
+	if err != nil {
+		t.Fatalf("Enforce() error = %v", err)
+	}
+	if got := paths(removed); !reflect.DeepEqual(got, []string{"hashtabs/hashtables/3.20.0.92-rmpp", "trees/3.22.0.64-rmpp"}) {
+		t.Errorf("Enforce() removed %v", got)
+	}
+
+	t.Setenv(EnvVarMaxSize, "huge")
+	if _, err := Enforce(); err == nil {
+		t.Error("Enforce() expected error for an invalid size")
+	}
+}
@@ -0,0 +1,83 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/config"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/display"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/expected"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/i18n"
+	"github.com/spf13/cobra"
+)
+
+var (
+	expectedMatrix string
+	assertStrict   bool
+)
+
+var assertCmd = &cobra.Command{
+	Use:   "assert --expected <matrix> <file.qmd>",
+	Short: "Fail if results deviate from an expected compatibility matrix",
+	Long: `Check a QMD file and compare the results against an expected matrix.
+Any deviation fails the run: losing compatibility, but also gaining it, which
+usually means the wrong file was checked.
+
+The expected matrix is YAML (device -> OS version -> true/false), CSV
+(device,os_version,compatible) or a compatibility manifest written by
+'qmdverify stamp'.`,
+	Example: `  qmdverify assert --expected matrix.yaml myfile.qmd
+  qmdverify assert --expected matrix.csv --strict myfile.qmd
+  qmdverify assert --expected myfile.qmd.compat.json myfile.qmd`,
+	SilenceUsage: true,
+	Args:         cobra.ExactArgs(1),
+	RunE:         runAssert,
+}
+
+func init() {
+	assertCmd.Flags().StringVar(&expectedMatrix, "expected", "", "Expected matrix file (YAML, CSV or compatibility manifest)")
+	assertCmd.Flags().BoolVar(&assertStrict, "strict", false, "Also fail on results the expected matrix doesn't mention, such as new OS versions")
+	assertCmd.MarkFlagRequired("expected")
+}
+
+func runAssert(cmd *cobra.Command, args []string) error {
+	want, err := expected.Load(expectedMatrix)
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	path := args[0]
+	if err := validateQMDFile(path); err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+	auditFiles([]string{path})
+
+	cfg, err := config.Load()
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+	client := newAPIClient(cfg)
+
+	fmt.Fprintf(os.Stderr, "%s\n\n", i18n.T(i18n.MsgUploadingFile, filepath.Base(path), cfg.ServerHost))
+
+	response, err := newComparer(cfg, client).CompareQMD(cmd.Context(), path)
+	if err != nil {
+		display.RenderError(os.Stderr, fmt.Errorf("%s: %w", i18n.T(i18n.MsgErrCheckFailed), err))
+		return err
+	}
+
+	deviations := expected.Compare(want, response, assertStrict)
+	if err := display.RenderAssertReport(os.Stdout, deviations, want.Len()); err != nil {
+		return err
+	}
+
+	if len(deviations) > 0 {
+		return checkFailed(cmd, &CheckError{Outcome: ErrIncompatible})
+	}
+
+	return nil
+}
@@ -0,0 +1,318 @@
+package commands
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/attest"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/bundle"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/display"
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+const (
+	attestationSuffix = ".att.json"
+	signatureSuffix   = ".sig.json"
+)
+
+var (
+	verifyAttestationKey string
+	verifyReportKey      string
+	verifyReportInputs   []string
+)
+
+var verifyAttestationCmd = &cobra.Command{
+	Use:   "verify-attestation <file.qmd>",
+	Short: "Verify a signed check attestation",
+	Long: `Verify <file>.qmd.att.json written by 'check --attest': the signature must
+match the given public key and the attested SHA-256 must match the file.`,
+	Example:      `  qmdverify verify-attestation myfile.qmd --key key.pub.pem`,
+	SilenceUsage: true,
+	Args:         cobra.ExactArgs(1),
+	RunE:         runVerifyAttestation,
+}
+
+var verifyReportCmd = &cobra.Command{
+	Use:   "verify-report <report>",
+	Short: "Verify a report signed with --sign-outputs",
+	Long: `Verify <report>.sig.json written by 'check --sign-outputs': the signature must
+match the given public key and the signed SHA-256 must match the report. Each
+--input must be one of the QMD files the report was generated from.`,
+	Example:      `  qmdverify verify-report report.xml --key key.pub.pem --input myfile.qmd`,
+	SilenceUsage: true,
+	Args:         cobra.ExactArgs(1),
+	RunE:         runVerifyReport,
+}
+
+func init() {
+	verifyAttestationCmd.Flags().StringVar(&verifyAttestationKey, "key", "", "PEM public key (or private key) to verify against")
+	verifyAttestationCmd.MarkFlagRequired("key")
+	verifyReportCmd.Flags().StringVar(&verifyReportKey, "key", "", "PEM public key (or private key) to verify against")
+	verifyReportCmd.Flags().StringArrayVar(&verifyReportInputs, "input", nil, "Also require this file to be one of the report's inputs (can be repeated)")
+	verifyReportCmd.MarkFlagRequired("key")
+}
+
+// reportProvenance is the predicate of a signed report. The server
+// fingerprint is a digest of the server URL and the fingerprints of every
+// hashtable it offered, so a report can be tied to the hashtables it was
+// checked against.
+type reportProvenance struct {
+	Format            string           `json:"format"`
+	Server            string           `json:"server"`
+	ServerVersion     string           `json:"server_version,omitempty"`
+	ServerFingerprint string           `json:"server_fingerprint"`
+	CLIVersion        string           `json:"cli_version"`
+	GeneratedAt       time.Time        `json:"generated_at"`
+	Inputs            []attest.Subject `json:"inputs"`
+}
+
+// writeAttestations signs the unfiltered results of each root file and writes
+// them next to the file.
+func writeAttestations(ctx context.Context, client *api.Client, server string, results map[string]*api.ComparisonResponse, filePaths, relativePaths []string) error {
+	signer, err := attest.LoadSigner(attestKey)
+	if err != nil {
+		return err
+	}
+
+	stamps, err := newStampContext(ctx, client, server)
+	if err != nil {
+		return err
+	}
+
+	for _, filename := range sortedResultNames(results) {
+		path := rootFilePath(filename, filePaths, relativePaths)
+
+		stamp, err := stamps.stamp(path, results[filename])
+		if err != nil {
+			return err
+		}
+
+		envelope, err := attest.Sign(attest.NewStatement(stamp.File, stamp.SHA256, stamp), signer)
+		if err != nil {
+			return err
+		}
+
+		data, err := json.MarshalIndent(envelope, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode attestation: %w", err)
+		}
+		if err := os.WriteFile(path+attestationSuffix, append(data, '\n'), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path+attestationSuffix, err)
+		}
+
+		fmt.Fprintf(os.Stderr, "Wrote attestation %s\n", filename+attestationSuffix)
+	}
+
+	return nil
+}
+
+func validateSignOutputs(files []outputFile) error {
+	switch {
+	case attestKey == "":
+		return fmt.Errorf("--sign-outputs requires --key")
+	case localCheck:
+		return fmt.Errorf("--sign-outputs cannot be used with --local")
+	case len(files) == 0:
+		return fmt.Errorf("--sign-outputs needs at least one --output format=path file")
+	}
+	return nil
+}
+
+// signOutputFiles writes a signed provenance record next to each report
+// written with --output format=path.
+func signOutputFiles(ctx context.Context, client *api.Client, server string, files []outputFile, filePaths, relativePaths []string) error {
+	signer, err := attest.LoadSigner(attestKey)
+	if err != nil {
+		return err
+	}
+
+	stamps, err := newStampContext(ctx, client, server)
+	if err != nil {
+		return err
+	}
+
+	inputs := make([]attest.Subject, len(filePaths))
+	for i, path := range filePaths {
+		sum, err := bundle.FileSHA256(path)
+		if err != nil {
+			return err
+		}
+		inputs[i] = attest.Subject{Name: relativePaths[i], Digest: map[string]string{"sha256": sum}}
+	}
+
+	for _, file := range files {
+		sum, err := bundle.FileSHA256(file.path)
+		if err != nil {
+			return err
+		}
+
+		statement := attest.NewStatement(filepath.Base(file.path), sum, reportProvenance{
+			Format:            file.format,
+			Server:            stamps.server,
+			ServerVersion:     stamps.serverVersion,
+			ServerFingerprint: serverFingerprint(stamps.server, stamps.hashtables),
+			CLIVersion:        Version,
+			GeneratedAt:       stamps.verifiedAt,
+			Inputs:            inputs,
+		})
+		statement.PredicateType = attest.ReportPredicateType
+
+		envelope, err := attest.Sign(statement, signer)
+		if err != nil {
+			return err
+		}
+
+		data, err := json.MarshalIndent(envelope, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode signature: %w", err)
+		}
+		if err := os.WriteFile(file.path+signatureSuffix, append(data, '\n'), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", file.path+signatureSuffix, err)
+		}
+
+		fmt.Fprintf(os.Stderr, "Wrote signature %s\n", file.path+signatureSuffix)
+	}
+
+	return nil
+}
+
+func serverFingerprint(server string, hashtables []api.HashtableInfo) string {
+	fingerprints := make([]string, len(hashtables))
+	for i, ht := range hashtables {
+		fingerprints[i] = hashtableFingerprint(ht)
+	}
+	sort.Strings(fingerprints)
+
+	sum := sha256.Sum256([]byte(server + "\n" + strings.Join(fingerprints, "\n")))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func runVerifyAttestation(cmd *cobra.Command, args []string) error {
+	path := args[0]
+
+	err := verifyAttestation(path, path+attestationSuffix, verifyAttestationKey)
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	return display.RenderSuccess(os.Stdout, fmt.Sprintf("✓ Attestation verified for %s", filepath.Base(path)))
+}
+
+func verifyAttestation(path, attestationPath, keyPath string) error {
+	publicKey, err := attest.LoadPublicKey(keyPath)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(attestationPath)
+	if err != nil {
+		return fmt.Errorf("failed to read attestation: %w", err)
+	}
+
+	var envelope attest.Envelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return fmt.Errorf("failed to parse attestation: %w", err)
+	}
+
+	statement, err := attest.Verify(&envelope, publicKey)
+	if err != nil {
+		return err
+	}
+	if statement.PredicateType != attest.PredicateType {
+		return fmt.Errorf("%s is not a compatibility attestation (predicate type '%s')", filepath.Base(attestationPath), statement.PredicateType)
+	}
+
+	sum, err := bundle.FileSHA256(path)
+	if err != nil {
+		return err
+	}
+
+	for _, subject := range statement.Subject {
+		if subject.Digest["sha256"] == sum {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("attestation does not match %s (sha256 %s)", filepath.Base(path), sum)
+}
+
+func runVerifyReport(cmd *cobra.Command, args []string) error {
+	path := args[0]
+
+	provenance, err := verifyReport(path, path+signatureSuffix, verifyReportKey, verifyReportInputs)
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	return display.RenderSuccess(os.Stdout, fmt.Sprintf("✓ Report verified for %s: %s output from qmdverify %s against %s (%s), %d input(s)",
+		filepath.Base(path), provenance.Format, provenance.CLIVersion, provenance.Server, provenance.ServerFingerprint, len(provenance.Inputs)))
+}
+
+func verifyReport(path, signaturePath, keyPath string, inputs []string) (*reportProvenance, error) {
+	publicKey, err := attest.LoadPublicKey(keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(signaturePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signature: %w", err)
+	}
+
+	var envelope attest.Envelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("failed to parse signature: %w", err)
+	}
+
+	statement, err := attest.Verify(&envelope, publicKey)
+	if err != nil {
+		return nil, err
+	}
+	if statement.PredicateType != attest.ReportPredicateType {
+		return nil, fmt.Errorf("%s is not a report signature (predicate type '%s')", filepath.Base(signaturePath), statement.PredicateType)
+	}
+
+	sum, err := bundle.FileSHA256(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(statement.Subject) != 1 || statement.Subject[0].Digest["sha256"] != sum {
+		return nil, fmt.Errorf("signature does not match %s (sha256 %s)", filepath.Base(path), sum)
+	}
+
+	predicate, err := json.Marshal(statement.Predicate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode provenance: %w", err)
+	}
+	var provenance reportProvenance
+	if err := json.Unmarshal(predicate, &provenance); err != nil {
+		return nil, fmt.Errorf("failed to decode provenance: %w", err)
+	}
+
+	recorded := make(map[string]bool, len(provenance.Inputs))
+	for _, input := range provenance.Inputs {
+		recorded[input.Digest["sha256"]] = true
+	}
+	for _, input := range inputs {
+		sum, err := bundle.FileSHA256(input)
+		if err != nil {
+			return nil, err
+		}
+		if !recorded[sum] {
+			return nil, fmt.Errorf("%s is not one of the report's inputs (sha256 %s)", filepath.Base(input), sum)
+		}
+	}
+
+	return &provenance, nil
+}
@@ -0,0 +1,181 @@
+package commands
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/attest"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/bundle"
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+func TestVerifyAttestation(t *testing.T) {
+	dir := t.TempDir()
+	qmdPath := filepath.Join(dir, "a.qmd")
+	if err := os.WriteFile(qmdPath, []byte("REPLACE foo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPath := filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	sum, err := bundle.FileSHA256(qmdPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	envelope, err := attest.Sign(attest.NewStatement("a.qmd", sum, compatStamp{File: "a.qmd", SHA256: sum}), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	attPath := qmdPath + attestationSuffix
+	if err := os.WriteFile(attPath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := verifyAttestation(qmdPath, attPath, keyPath); err != nil {
+		t.Fatalf("verifyAttestation() error = %v", err)
+	}
+
+	statement := attest.NewStatement("a.qmd", sum, nil)
+	statement.PredicateType = attest.ReportPredicateType
+	envelope, err = attest.Sign(statement, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err = json.Marshal(envelope); err != nil {
+		t.Fatal(err)
+	}
+	otherPath := filepath.Join(dir, "report.sig.json")
+	if err := os.WriteFile(otherPath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyAttestation(qmdPath, otherPath, keyPath); err == nil || !strings.Contains(err.Error(), "not a compatibility attestation") {
+		t.Errorf("verifyAttestation() of a report signature error = %v", err)
+	}
+
+	if err := os.WriteFile(qmdPath, []byte("REPLACE bar"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyAttestation(qmdPath, attPath, keyPath); err == nil {
+		t.Error("verifyAttestation() accepted a modified file")
+	}
+}
+
+func TestSignOutputFiles(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/version":
+			json.NewEncoder(w).Encode(api.VersionResponse{Version: "v2.0.0"})
+		case "/api/hashtables":
+			json.NewEncoder(w).Encode(api.HashtablesResponse{Hashtables: []api.HashtableInfo{
+				{Name: "3.22.0.64-rmpp", OSVersion: "3.22.0.64", Device: "rmpp", EntryCount: 10},
+			}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	qmdPath := filepath.Join(dir, "a.qmd")
+	otherPath := filepath.Join(dir, "b.qmd")
+	reportPath := filepath.Join(dir, "report.xml")
+	for path, content := range map[string]string{qmdPath: "REPLACE foo", otherPath: "REPLACE bar", reportPath: "<testsuites/>"} {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPath := filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() { attestKey = "" }()
+	attestKey = keyPath
+	files := []outputFile{{format: "junit", path: reportPath}}
+	if err := signOutputFiles(context.Background(), api.NewClient(server.URL), server.URL, files, []string{qmdPath}, []string{"a.qmd"}); err != nil {
+		t.Fatalf("signOutputFiles(context.Background()) error = %v", err)
+	}
+
+	provenance, err := verifyReport(reportPath, reportPath+signatureSuffix, keyPath, []string{qmdPath})
+	if err != nil {
+		t.Fatalf("verifyReport() error = %v", err)
+	}
+	if provenance.Format != "junit" || provenance.ServerVersion != "v2.0.0" || provenance.Inputs[0].Name != "a.qmd" {
+		t.Errorf("verifyReport() provenance = %+v", provenance)
+	}
+	if !strings.HasPrefix(provenance.ServerFingerprint, "sha256:") {
+		t.Errorf("server fingerprint = %q", provenance.ServerFingerprint)
+	}
+
+	if _, err := verifyReport(reportPath, reportPath+signatureSuffix, keyPath, []string{otherPath}); err == nil {
+		t.Error("verifyReport() accepted a file that is not an input")
+	}
+
+	if err := os.WriteFile(reportPath, []byte("<testsuites></testsuites>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifyReport(reportPath, reportPath+signatureSuffix, keyPath, nil); err == nil {
+		t.Error("verifyReport() accepted a modified report")
+	}
+}
+
+func TestValidateSignOutputs(t *testing.T) {
+	defer func() { attestKey, localCheck = "", false }()
+	files := []outputFile{{format: "junit", path: "report.xml"}}
+
+	tests := []struct {
+		name    string
+		key     string
+		local   bool
+		files   []outputFile
+		wantErr bool
+	}{
+		{name: "valid", key: "key.pem", files: files},
+		{name: "no key", files: files, wantErr: true},
+		{name: "local", key: "key.pem", local: true, files: files, wantErr: true},
+		{name: "no output files", key: "key.pem", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attestKey, localCheck = tt.key, tt.local
+			if err := validateSignOutputs(tt.files); (err != nil) != tt.wantErr {
+				t.Errorf("validateSignOutputs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/audit"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	noAudit      bool
+	auditPath    string
+	auditEntry   *audit.Entry
+	auditStarted time.Time
+)
+
+// beginAudit starts an audit entry for the invocation when an audit log is
+// configured and --no-audit was not given.
+func beginAudit(cmd *cobra.Command) {
+	auditPath = audit.Path()
+	if noAudit || auditPath == "" {
+		return
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		cfg = &config.Config{Profile: config.ActiveProfile()}
+	}
+	auditStarted = time.Now()
+	auditEntry = &audit.Entry{
+		Time:       auditStarted.UTC(),
+		Command:    cmd.CommandPath(),
+		Args:       audit.RedactArgs(os.Args[1:]),
+		Server:     cfg.ServerHost,
+		Profile:    cfg.Profile,
+		Org:        cfg.Org,
+		CLIVersion: Version,
+	}
+}
+
+// auditFiles records the files an invocation operates on, with their hashes.
+func auditFiles(paths []string) {
+	if auditEntry == nil {
+		return
+	}
+
+	for _, path := range paths {
+		file := audit.File{Path: path}
+		if sum, err := cachedSHA256(path); err == nil {
+			file.SHA256 = sum
+		}
+		auditEntry.Files = append(auditEntry.Files, file)
+	}
+}
+
+func finishAudit(exitCode int, err error) {
+	if auditEntry == nil {
+		return
+	}
+
+	entry := *auditEntry
+	auditEntry = nil
+
+	entry.ExitCode = exitCode
+	entry.DurationMS = time.Since(auditStarted).Milliseconds()
+	switch {
+	case err != nil:
+		entry.Outcome = audit.OutcomeError
+		entry.Error = err.Error()
+	case exitCode != 0:
+		entry.Outcome = audit.OutcomeIncompatible
+	default:
+		entry.Outcome = audit.OutcomeSuccess
+	}
+
+	if err := audit.Append(auditPath, entry); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", err)
+	}
+}
@@ -0,0 +1,81 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/audit"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/config"
+)
+
+func TestAudit(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "audit.log")
+	t.Setenv(audit.EnvVarLog, logPath)
+	t.Setenv(config.EnvVarConfigDir, dir)
+	t.Setenv(config.EnvVarHost, "https://qmdverify.example.com")
+
+	qmdPath := filepath.Join(dir, "a.qmd")
+	if err := os.WriteFile(qmdPath, []byte("abc"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		exitCode    int
+		err         error
+		wantOutcome string
+	}{
+		{name: "success", wantOutcome: audit.OutcomeSuccess},
+		{name: "incompatible", exitCode: 1, wantOutcome: audit.OutcomeIncompatible},
+		{name: "error", exitCode: 1, err: errors.New("boom"), wantOutcome: audit.OutcomeError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Remove(logPath)
+
+			beginAudit(checkCmd)
+			auditFiles([]string{qmdPath})
+			finishAudit(tt.exitCode, tt.err)
+
+			data, err := os.ReadFile(logPath)
+			if err != nil {
+				t.Fatalf("audit log not written: %v", err)
+			}
+
+			var entry audit.Entry
+			if err := json.Unmarshal(data, &entry); err != nil {
+				t.Fatal(err)
+			}
+			if entry.Outcome != tt.wantOutcome || entry.ExitCode != tt.exitCode {
+				t.Errorf("entry = %+v", entry)
+			}
+			if entry.Server != "https://qmdverify.example.com" || !strings.HasSuffix(entry.Command, "check") {
+				t.Errorf("entry = %+v", entry)
+			}
+			if len(entry.Files) != 1 || entry.Files[0].SHA256 != "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad" {
+				t.Errorf("Files = %+v", entry.Files)
+			}
+		})
+	}
+}
+
+func TestNoAudit(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "audit.log")
+	t.Setenv(audit.EnvVarLog, logPath)
+
+	noAudit = true
+	t.Cleanup(func() { noAudit = false })
+
+	beginAudit(checkCmd)
+	finishAudit(0, nil)
+
+	if _, err := os.Stat(logPath); !os.IsNotExist(err) {
+		t.Errorf("audit log written despite --no-audit: %v", err)
+	}
+}
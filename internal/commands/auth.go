@@ -0,0 +1,542 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/x/term"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/config"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/display"
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+const (
+	defaultDevicePollInterval = 5 * time.Second
+	ssoTimeout                = 5 * time.Minute
+)
+
+var (
+	loginWithToken bool
+	ssoWithCookie  bool
+)
+
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Manage server credentials",
+	Long: `Log in to a qmdverify server and store the credentials for later runs.
+Credentials are stored per profile (--profile or QMDVERIFY_PROFILE) in the
+user config directory, and are sent only to the server they were issued for.`,
+}
+
+var authLoginCmd = &cobra.Command{
+	Use:   "login",
+	Short: "Log in to the server",
+	Long: `Log in using the server's device-code flow: open the printed URL, enter the
+code, and the CLI picks up the token once it is approved. If the server does
+not support device login, paste a token instead.
+
+Use --with-token to read a token from stdin for non-interactive use.`,
+	Example: `  qmdverify auth login
+  qmdverify auth login --profile work
+  echo "$TOKEN" | qmdverify auth login --with-token`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE:         runAuthLogin,
+}
+
+var authSSOCmd = &cobra.Command{
+	Use:   "sso",
+	Short: "Log in through an SSO proxy in front of the server",
+	Long: `Log in to a server behind a session-based SSO proxy such as Authelia or
+oauth2-proxy. The browser opens the server's SSO page; once you are signed in,
+the server hands the session cookie back to the CLI, which stores it in the
+profile's cookie jar and sends it with later requests.
+
+Use --with-cookie to read a name=value session cookie, e.g. copied from the
+browser's developer tools, from stdin instead.`,
+	Example: `  qmdverify auth sso
+  echo "authelia_session=abc123" | qmdverify auth sso --with-cookie`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE:         runAuthSSO,
+}
+
+var authLogoutCmd = &cobra.Command{
+	Use:          "logout",
+	Short:        "Remove stored credentials for the profile",
+	Example:      `  qmdverify auth logout`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE:         runAuthLogout,
+}
+
+var authStatusCmd = &cobra.Command{
+	Use:          "status",
+	Short:        "Show and validate the stored credentials",
+	Example:      `  qmdverify auth status`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE:         runAuthStatus,
+}
+
+func init() {
+	authLoginCmd.Flags().BoolVar(&loginWithToken, "with-token", false, "Read a token from stdin instead of logging in interactively")
+	authSSOCmd.Flags().BoolVar(&ssoWithCookie, "with-cookie", false, "Read a name=value session cookie from stdin instead of opening a browser")
+
+	authCmd.AddCommand(authLoginCmd)
+	authCmd.AddCommand(authSSOCmd)
+	authCmd.AddCommand(authLogoutCmd)
+	authCmd.AddCommand(authStatusCmd)
+}
+
+func runAuthLogin(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+	client := api.NewClient(cfg.ServerHost)
+	client.Org = cfg.Org
+
+	store, err := config.LoadCredentialStore()
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	var token *api.TokenResponse
+	if loginWithToken {
+		token, err = readToken(os.Stdin)
+	} else {
+		token, err = deviceLogin(cmd.Context(), client, time.Sleep)
+		if errors.Is(err, api.ErrAuthNotSupported) {
+			fmt.Fprintln(os.Stderr, "Server does not support device login.")
+			token, err = promptToken()
+		}
+	}
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	creds, err := validateToken(cmd.Context(), client, cfg.ServerHost, token)
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+	creds.Org = cfg.Org
+
+	store.Set(cfg.Profile, creds)
+	if err := store.Save(); err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	message := fmt.Sprintf("✓ Logged in to %s", cfg.ServerHost)
+	if creds.User != "" {
+		message += " as " + creds.User
+	}
+	if creds.Org != "" {
+		message += " in " + creds.Org
+	}
+	return display.RenderSuccess(os.Stdout, fmt.Sprintf("%s (profile %s)", message, cfg.Profile))
+}
+
+func runAuthLogout(cmd *cobra.Command, args []string) error {
+	profile := config.ActiveProfile()
+
+	store, err := config.LoadCredentialStore()
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	jar, err := config.LoadCookieJar(profile)
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+	hadCookies, err := jar.Clear()
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	if !store.Delete(profile) {
+		if hadCookies {
+			return display.RenderSuccess(os.Stdout, fmt.Sprintf("✓ Logged out (profile %s)", profile))
+		}
+		return display.RenderInfo(os.Stdout, fmt.Sprintf("Not logged in (profile %s)", profile))
+	}
+
+	if err := store.Save(); err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	return display.RenderSuccess(os.Stdout, fmt.Sprintf("✓ Logged out (profile %s)", profile))
+}
+
+func runAuthStatus(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	store, err := config.LoadCredentialStore()
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	creds, ok := store.Get(cfg.Profile)
+	if !ok {
+		err := fmt.Errorf("not logged in (profile %s)", cfg.Profile)
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	fmt.Printf("Profile: %s\n", cfg.Profile)
+	fmt.Printf("  Server: %s\n", creds.Server)
+	if creds.User != "" {
+		fmt.Printf("  User: %s\n", creds.User)
+	}
+	if creds.Org != "" {
+		fmt.Printf("  Organization: %s\n", creds.Org)
+	}
+	if !creds.ExpiresAt.IsZero() {
+		fmt.Printf("  Expires: %s\n", creds.ExpiresAt.Local().Format(time.RFC1123))
+	}
+	if jar, err := config.LoadCookieJar(cfg.Profile); err == nil {
+		if server, err := url.Parse(creds.Server); err == nil && jar.Has(server) {
+			fmt.Println("  Session: SSO cookie")
+		}
+	}
+
+	client := newAPIClient(&config.Config{
+		ServerHost:   creds.Server,
+		Profile:      cfg.Profile,
+		Token:        creds.Token,
+		Org:          creds.Org,
+		RefreshToken: creds.RefreshToken,
+		TokenExpiry:  creds.ExpiresAt,
+	})
+	if _, err := client.WhoAmI(cmd.Context()); err != nil {
+		if errors.Is(err, api.ErrAuthNotSupported) {
+			fmt.Println("  Status: stored (server cannot validate tokens)")
+			return nil
+		}
+		fmt.Printf("  Status: invalid (%s)\n", err)
+		return err
+	}
+
+	fmt.Println("  Status: valid")
+	return nil
+}
+
+// deviceLogin runs the device authorization flow until the user approves the
+// request or the code expires.
+func deviceLogin(ctx context.Context, client *api.Client, sleep func(time.Duration)) (*api.TokenResponse, error) {
+	code, err := client.StartDeviceLogin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	uri := code.VerificationURIComplete
+	if uri == "" {
+		uri = code.VerificationURI
+	}
+	fmt.Fprintf(os.Stderr, "Open %s and enter the code: %s\n", uri, code.UserCode)
+	fmt.Fprintln(os.Stderr, "Waiting for approval...")
+
+	interval := time.Duration(code.Interval) * time.Second
+	if interval <= 0 {
+		interval = defaultDevicePollInterval
+	}
+	deadline := time.Now().Add(time.Duration(code.ExpiresIn) * time.Second)
+
+	for code.ExpiresIn <= 0 || time.Now().Before(deadline) {
+		sleep(interval)
+
+		token, err := client.PollDeviceToken(ctx, code.DeviceCode)
+		switch {
+		case err == nil:
+			return token, nil
+		case errors.Is(err, api.ErrAuthorizationPending):
+		case errors.Is(err, api.ErrSlowDown):
+			interval += defaultDevicePollInterval
+		default:
+			return nil, err
+		}
+	}
+
+	return nil, fmt.Errorf("device code expired before it was approved")
+}
+
+func promptToken() (*api.TokenResponse, error) {
+	fd := os.Stdin.Fd()
+	if !term.IsTerminal(fd) {
+		return readToken(os.Stdin)
+	}
+
+	fmt.Fprint(os.Stderr, "Paste token: ")
+	secret, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token: %w", err)
+	}
+
+	return tokenFromString(string(secret))
+}
+
+func readToken(r io.Reader) (*api.TokenResponse, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read token: %w", err)
+	}
+	return tokenFromString(line)
+}
+
+func tokenFromString(s string) (*api.TokenResponse, error) {
+	token := strings.TrimSpace(s)
+	if token == "" {
+		return nil, fmt.Errorf("no token provided")
+	}
+	return &api.TokenResponse{AccessToken: token}, nil
+}
+
+// validateToken checks the token against the server before it is stored.
+// Servers without a validation endpoint are trusted with a warning.
+func validateToken(ctx context.Context, client *api.Client, server string, token *api.TokenResponse) (config.Credentials, error) {
+	creds := config.Credentials{
+		Server:       server,
+		Token:        token.AccessToken,
+		RefreshToken: token.RefreshToken,
+	}
+	if token.ExpiresIn > 0 {
+		creds.ExpiresAt = time.Now().UTC().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+
+	client.Token = token.AccessToken
+	whoami, err := client.WhoAmI(ctx)
+	switch {
+	case err == nil:
+		creds.User = whoami.User
+	case errors.Is(err, api.ErrAuthNotSupported):
+		fmt.Fprintln(os.Stderr, "Warning: server cannot validate tokens; storing it unchecked")
+	default:
+		return config.Credentials{}, fmt.Errorf("token validation failed: %w", err)
+	}
+
+	return creds, nil
+}
+
+func runAuthSSO(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	server, err := url.Parse(cfg.ServerHost)
+	if err != nil {
+		err = fmt.Errorf("invalid server URL %q: %w", cfg.ServerHost, err)
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	store, err := config.LoadCredentialStore()
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+	jar, err := config.LoadCookieJar(cfg.Profile)
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	var cookie *http.Cookie
+	if ssoWithCookie {
+		cookie, err = readCookie(os.Stdin)
+	} else {
+		cookie, err = ssoLogin(cmd.Context(), cfg.ServerHost, openBrowser, ssoTimeout)
+	}
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+	cookie.Path = "/"
+	cookie.Secure = server.Scheme == "https"
+	jar.SetCookies(server, []*http.Cookie{cookie})
+	if err := jar.Save(); err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	client := api.NewClient(cfg.ServerHost)
+	client.Org = cfg.Org
+	client.HTTPClient.Jar = jar
+	user, err := validateSession(cmd.Context(), client)
+	if err != nil {
+		jar.Clear()
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	creds, ok := store.Get(cfg.Profile)
+	if !ok || strings.TrimSuffix(creds.Server, "/") != cfg.ServerHost {
+		creds = config.Credentials{Server: cfg.ServerHost}
+	}
+	if user != "" {
+		creds.User = user
+	}
+	creds.Org = cfg.Org
+	store.Set(cfg.Profile, creds)
+	if err := store.Save(); err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	message := fmt.Sprintf("✓ Stored SSO session for %s", cfg.ServerHost)
+	if user != "" {
+		message += " as " + user
+	}
+	return display.RenderSuccess(os.Stdout, fmt.Sprintf("%s (profile %s)", message, cfg.Profile))
+}
+
+// ssoLogin opens the server's SSO page in the browser and waits for the
+// server to redirect back to a loopback listener with the session cookie:
+//
+//	GET /callback?state=<state>&cookie=<name>=<value>
+func ssoLogin(ctx context.Context, server string, open func(string) error, timeout time.Duration) (*http.Cookie, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to start SSO callback listener: %w", err)
+	}
+	defer listener.Close()
+
+	state, err := randomState()
+	if err != nil {
+		return nil, err
+	}
+
+	type result struct {
+		cookie *http.Cookie
+		err    error
+	}
+	results := make(chan result, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("state") != state {
+			http.Error(w, "Invalid state.", http.StatusBadRequest)
+			return
+		}
+
+		var res result
+		if msg := query.Get("error"); msg != "" {
+			res.err = fmt.Errorf("SSO login failed: %s", msg)
+		} else {
+			res.cookie, res.err = parseCookie(query.Get("cookie"))
+		}
+		if res.err != nil {
+			http.Error(w, res.err.Error(), http.StatusBadRequest)
+		} else {
+			fmt.Fprintln(w, "Logged in. You can close this window and return to the terminal.")
+		}
+		select {
+		case results <- res:
+		default:
+		}
+	})
+	callbackServer := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go callbackServer.Serve(listener)
+	defer callbackServer.Close()
+
+	callback := fmt.Sprintf("http://%s/callback", listener.Addr())
+	loginURL := fmt.Sprintf("%s/api/auth/sso?redirect_uri=%s&state=%s", server, url.QueryEscape(callback), state)
+	fmt.Fprintf(os.Stderr, "Opening %s\n", loginURL)
+	if err := open(loginURL); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not open a browser; open the URL above to continue.")
+	}
+	fmt.Fprintln(os.Stderr, "Waiting for SSO login...")
+
+	select {
+	case res := <-results:
+		return res.cookie, res.err
+	case <-time.After(timeout):
+		return nil, fmt.Errorf("SSO login timed out after %s", timeout)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func randomState() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate SSO state: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func readCookie(r io.Reader) (*http.Cookie, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read cookie: %w", err)
+	}
+	return parseCookie(line)
+}
+
+// parseCookie parses a name=value session cookie.
+func parseCookie(s string) (*http.Cookie, error) {
+	name, value, ok := strings.Cut(strings.TrimSpace(s), "=")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" || value == "" {
+		return nil, fmt.Errorf("expected a session cookie as name=value")
+	}
+	return &http.Cookie{Name: name, Value: strings.TrimSpace(value)}, nil
+}
+
+// validateSession checks that the server accepts the session cookie. Servers
+// without a validation endpoint are trusted with a warning.
+func validateSession(ctx context.Context, client *api.Client) (string, error) {
+	whoami, err := client.WhoAmI(ctx)
+	switch {
+	case err == nil:
+		return whoami.User, nil
+	case errors.Is(err, api.ErrAuthNotSupported):
+		fmt.Fprintln(os.Stderr, "Warning: server cannot validate sessions; storing the cookie unchecked")
+		return "", nil
+	default:
+		return "", fmt.Errorf("session validation failed: %w", err)
+	}
+}
+
+var openBrowser = func(target string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", target)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", target)
+	default:
+		cmd = exec.Command("xdg-open", target)
+	}
+	return cmd.Start()
+}
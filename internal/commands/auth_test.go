@@ -0,0 +1,187 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+func TestDeviceLogin(t *testing.T) {
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/auth/device":
+			json.NewEncoder(w).Encode(api.DeviceCodeResponse{
+				DeviceCode:      "dev-1",
+				UserCode:        "ABCD-EFGH",
+				VerificationURI: "https://example.com/device",
+				ExpiresIn:       600,
+				Interval:        1,
+			})
+		case "/api/auth/token":
+			polls++
+			if polls < 3 {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "authorization_pending"})
+				return
+			}
+			json.NewEncoder(w).Encode(api.TokenResponse{AccessToken: "abc123", RefreshToken: "refresh"})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	var waited []time.Duration
+	token, err := deviceLogin(context.Background(), api.NewClient(server.URL), func(d time.Duration) { waited = append(waited, d) })
+	if err != nil {
+		t.Fatalf("deviceLogin(context.Background()) error = %v", err)
+	}
+	if token.AccessToken != "abc123" || token.RefreshToken != "refresh" {
+		t.Errorf("token = %+v", token)
+	}
+	if len(waited) != 3 || waited[0] != time.Second {
+		t.Errorf("waited = %v, want three 1s waits", waited)
+	}
+}
+
+func TestValidateToken(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		wantUser string
+		wantErr  bool
+	}{
+		{name: "valid", status: http.StatusOK, wantUser: "scott"},
+		{name: "rejected", status: http.StatusUnauthorized, wantErr: true},
+		{name: "no validation endpoint", status: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") != "Bearer abc123" {
+					t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+				}
+				w.WriteHeader(tt.status)
+				json.NewEncoder(w).Encode(api.WhoAmIResponse{User: "scott"})
+			}))
+			defer server.Close()
+
+			creds, err := validateToken(context.Background(), api.NewClient(server.URL), server.URL, &api.TokenResponse{AccessToken: "abc123", ExpiresIn: 60})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateToken(context.Background()) error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if creds.User != tt.wantUser || creds.Token != "abc123" || creds.Server != server.URL {
+				t.Errorf("creds = %+v", creds)
+			}
+			if creds.ExpiresAt.IsZero() {
+				t.Error("ExpiresAt not set")
+			}
+		})
+	}
+}
+
+func TestReadToken(t *testing.T) {
+	token, err := readToken(strings.NewReader("  abc123\n"))
+	if err != nil || token.AccessToken != "abc123" {
+		t.Errorf("readToken() = %+v, %v", token, err)
+	}
+
+	if _, err := readToken(strings.NewReader("\n")); err == nil {
+		t.Error("readToken() expected error for empty input")
+	}
+}
+
+func TestSSOLogin(t *testing.T) {
+	tests := []struct {
+		name     string
+		cookie   string
+		loginErr string
+		wantErr  bool
+	}{
+		{name: "cookie returned", cookie: "authelia_session=abc123"},
+		{name: "login failed", loginErr: "access denied", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			open := func(target string) error {
+				login, err := url.Parse(target)
+				if err != nil {
+					return err
+				}
+				if login.Path != "/api/auth/sso" {
+					t.Errorf("opened %s, want the server's SSO page", target)
+				}
+				callback := login.Query().Get("redirect_uri")
+				state := login.Query().Get("state")
+				go func() {
+					// A forged callback with the wrong state is rejected.
+					if resp, err := http.Get(callback + "?state=wrong&cookie=evil=1"); err == nil {
+						resp.Body.Close()
+					}
+					query := url.Values{"state": {state}, "cookie": {tt.cookie}, "error": {tt.loginErr}}
+					if resp, err := http.Get(callback + "?" + query.Encode()); err == nil {
+						resp.Body.Close()
+					}
+				}()
+				return nil
+			}
+
+			cookie, err := ssoLogin(context.Background(), "https://verify.example.com", open, 5*time.Second)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ssoLogin() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && cookie.String() != tt.cookie {
+				t.Errorf("ssoLogin() cookie = %s, want %s", cookie, tt.cookie)
+			}
+		})
+	}
+}
+
+func TestSSOLoginCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	open := func(string) error {
+		cancel()
+		return nil
+	}
+	if _, err := ssoLogin(ctx, "https://verify.example.com", open, time.Minute); !errors.Is(err, context.Canceled) {
+		t.Errorf("ssoLogin() error = %v after an interrupt, want context.Canceled", err)
+	}
+}
+
+func TestParseCookie(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{input: "session=abc\n", want: "session=abc"},
+		{input: " _oauth2_proxy = a=b ", want: "_oauth2_proxy=a=b"},
+		{input: "session", wantErr: true},
+		{input: "=abc", wantErr: true},
+		{input: "session=", wantErr: true},
+	}
+	for _, tt := range tests {
+		cookie, err := parseCookie(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseCookie(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && cookie.String() != tt.want {
+			t.Errorf("parseCookie(%q) = %s, want %s", tt.input, cookie, tt.want)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package commands
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/attest"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/bundle"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/config"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/display"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/tree"
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+var (
+	bundleOutput   string
+	bundleTrees    bool
+	bundleKey      string
+	bundleUnsigned bool
+)
+
+var bundleCmd = &cobra.Command{
+	Use:   "bundle-server-data",
+	Short: "Download all server data into an offline bundle",
+	Long: `Download every hashtable (and, with --trees, every QML tree) from the server
+into a single archive for air-gapped machines. The archive contains a
+manifest with the SHA-256 of each file, signed with --key so the offline side
+can verify where the data came from. Check files against the bundle with
+'qmdverify check --bundle'.
+
+The archive is compressed according to its extension: .tar.zst, .tar.gz or
+.tar.`,
+	Example: `  qmdverify bundle-server-data -o verify-data.tar.zst --key key.pem
+  qmdverify bundle-server-data -o verify-data.tar.zst --key key.pem --trees
+  qmdverify bundle-server-data -o verify-data.tar.gz --unsigned`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE:         runBundle,
+}
+
+func init() {
+	bundleCmd.Flags().StringVarP(&bundleOutput, "output", "o", "verify-data.tar.zst", "Bundle file to write")
+	bundleCmd.Flags().BoolVar(&bundleTrees, "trees", false, "Also include QML trees")
+	bundleCmd.Flags().StringVar(&bundleKey, "key", "", "PEM private key used to sign the manifest")
+	bundleCmd.Flags().BoolVar(&bundleUnsigned, "unsigned", false, "Write the bundle without a manifest signature")
+}
+
+func runBundle(cmd *cobra.Command, args []string) error {
+	var signer crypto.Signer
+	switch {
+	case bundleKey != "":
+		var err error
+		if signer, err = attest.LoadSigner(bundleKey); err != nil {
+			display.RenderError(os.Stderr, err)
+			return err
+		}
+	case !bundleUnsigned:
+		err := fmt.Errorf("--key is required to sign the bundle manifest (use --unsigned to skip signing)")
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+	client := newAPIClient(cfg)
+
+	staging, err := os.MkdirTemp("", "qmdverify-bundle-*")
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+	defer os.RemoveAll(staging)
+
+	manifest, err := stageServerData(cmd.Context(), client, cfg.ServerHost, staging)
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	if err := bundle.Write(bundleOutput, staging, manifest, signer); err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	message := fmt.Sprintf("✓ Wrote %s (%d hashtables, %d trees)", bundleOutput, len(manifest.Hashtables), len(manifest.Trees))
+	if signer == nil {
+		message += ", unsigned"
+	}
+	return display.RenderSuccess(os.Stdout, message)
+}
+
+// stageServerData downloads everything the bundle needs into dir and
+// returns the manifest describing it.
+func stageServerData(ctx context.Context, client *api.Client, server, dir string) (*bundle.Manifest, error) {
+	manifest := &bundle.Manifest{
+		FormatVersion: bundle.FormatVersion,
+		Server:        server,
+		CLIVersion:    Version,
+		CreatedAt:     time.Now().UTC(),
+		Hashtables:    make([]bundle.Entry, 0),
+	}
+	if version, err := client.GetVersion(ctx); err == nil {
+		manifest.ServerVersion = version.Version
+	}
+
+	fmt.Fprintf(os.Stderr, "Fetching hashtables from %s...\n", server)
+	hashtables, err := client.ListHashtables(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list hashtables: %w", err)
+	}
+
+	for _, ht := range hashtables.Hashtables {
+		if ht.Name == "" || filepath.Base(ht.Name) != ht.Name || ht.Name == ".." {
+			return nil, fmt.Errorf("server returned an invalid hashtable name %q", ht.Name)
+		}
+		entry := bundle.Entry{
+			Path:       path.Join(bundle.HashtableDir, ht.Name),
+			Name:       ht.Name,
+			OSVersion:  ht.OSVersion,
+			Device:     ht.Device,
+			EntryCount: ht.EntryCount,
+		}
+		fmt.Fprintf(os.Stderr, "  %s\n", entry.Path)
+		if err := stageDownload(dir, &entry, func(f *os.File) (int64, error) {
+			return client.DownloadHashtable(ctx, ht.Name, f)
+		}); err != nil {
+			return nil, err
+		}
+		manifest.Hashtables = append(manifest.Hashtables, entry)
+	}
+
+	if !bundleTrees {
+		return manifest, nil
+	}
+
+	fmt.Fprintf(os.Stderr, "Fetching QML trees from %s...\n", server)
+	trees, err := client.ListTrees(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trees: %w", err)
+	}
+
+	for _, tree := range trees.Trees {
+		entry := bundle.Entry{
+			Path:      path.Join(bundle.TreeDir, fmt.Sprintf("%s-%s.tar", tree.Version, tree.Device)),
+			OSVersion: tree.Version,
+			Device:    tree.Device,
+		}
+		fmt.Fprintf(os.Stderr, "  %s\n", entry.Path)
+		if err := stageDownload(dir, &entry, func(f *os.File) (int64, error) {
+			return client.DownloadTree(ctx, tree.Device, tree.Version, f)
+		}); err != nil {
+			return nil, err
+		}
+		manifest.Trees = append(manifest.Trees, entry)
+	}
+
+	return manifest, nil
+}
+
+func stageDownload(dir string, entry *bundle.Entry, download func(*os.File) (int64, error)) error {
+	dest := filepath.Join(dir, filepath.FromSlash(entry.Path))
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return fmt.Errorf("failed to create staging directory: %w", err)
+	}
+
+	f, err := os.Create(dest)
+	if err != nil {
+		return fmt.Errorf("failed to stage %s: %w", entry.Path, err)
+	}
+	_, err = download(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", entry.Path, err)
+	}
+
+	return bundle.Describe(dir, entry)
+}
+
+// extractCheckBundle verifies the --bundle archive against --bundle-key and
+// unpacks it into a temporary directory for a local check. With trees, each
+// bundled tree is also unpacked under trees/<version>-<device>. The caller
+// removes the directory.
+func extractCheckBundle(path string, trees bool) (string, error) {
+	var publicKey crypto.PublicKey
+	switch {
+	case checkBundleKey != "":
+		var err error
+		if publicKey, err = attest.LoadPublicKey(checkBundleKey); err != nil {
+			return "", err
+		}
+	case !bundleNoVerify:
+		return "", fmt.Errorf("--bundle-key is required to verify the bundle manifest (use --bundle-unsigned to skip verification)")
+	}
+
+	dir, err := os.MkdirTemp("", "qmdverify-bundle-*")
+	if err != nil {
+		return "", err
+	}
+	manifest, err := bundle.Extract(path, dir, publicKey)
+	if err == nil && trees {
+		err = unpackBundleTrees(dir, manifest)
+	}
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	return dir, nil
+}
+
+func unpackBundleTrees(dir string, manifest *bundle.Manifest) error {
+	for _, entry := range manifest.Trees {
+		f, err := os.Open(filepath.Join(dir, filepath.FromSlash(entry.Path)))
+		if err != nil {
+			return err
+		}
+		_, err = tree.Unpack(f, filepath.Join(dir, bundle.TreeDir, tree.Name(entry.OSVersion, entry.Device)))
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("failed to unpack %s: %w", entry.Path, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package commands
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/bundle"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/config"
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+	"github.com/rmitchellscott/rm-qmd-verify/pkg/hashtab"
+)
+
+func TestStageServerData(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/version":
+			json.NewEncoder(w).Encode(api.VersionResponse{Version: "v2.0.0"})
+		case "/api/hashtables":
+			json.NewEncoder(w).Encode(api.HashtablesResponse{Hashtables: []api.HashtableInfo{
+				{Name: "3.22.0.64-rmpp", OSVersion: "3.22.0.64", Device: "rmpp", EntryCount: 10},
+			}})
+		case "/api/hashtables/3.22.0.64-rmpp/download":
+			w.Write([]byte("hashtab"))
+		case "/api/trees":
+			json.NewEncoder(w).Encode(api.TreesResponse{Trees: []api.TreeInfo{{Version: "3.22.0.64", Device: "rmpp"}}})
+		case "/api/trees/rmpp/3.22.0.64/download":
+			w.Write([]byte("tree"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	defer func() { bundleTrees = false }()
+	bundleTrees = true
+
+	dir := t.TempDir()
+	manifest, err := stageServerData(context.Background(), api.NewClient(server.URL), server.URL, dir)
+	if err != nil {
+		t.Fatalf("stageServerData(context.Background()) error = %v", err)
+	}
+
+	if manifest.ServerVersion != "v2.0.0" || len(manifest.Hashtables) != 1 || len(manifest.Trees) != 1 {
+		t.Fatalf("manifest = %+v", manifest)
+	}
+	ht := manifest.Hashtables[0]
+	if ht.Path != "hashtables/3.22.0.64-rmpp" || ht.Size != 7 || ht.SHA256 == "" || ht.EntryCount != 10 {
+		t.Errorf("hashtable entry = %+v", ht)
+	}
+	if manifest.Trees[0].Path != "trees/3.22.0.64-rmpp.tar" {
+		t.Errorf("tree entry = %+v", manifest.Trees[0])
+	}
+
+	out := filepath.Join(t.TempDir(), "data.tar.zst")
+	if err := bundle.Write(out, dir, manifest, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(out); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestStageServerData_InvalidName(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(api.HashtablesResponse{Hashtables: []api.HashtableInfo{{Name: "../escape"}}})
+	}))
+	defer server.Close()
+
+	if _, err := stageServerData(context.Background(), api.NewClient(server.URL), server.URL, t.TempDir()); err == nil {
+		t.Error("stageServerData(context.Background()) expected error for a hashtable name with a path")
+	}
+}
+
+func TestNewCheckerBundle(t *testing.T) {
+	defer func() { localCheck, checkBundle, checkBundleKey, bundleNoVerify = false, "", "", false }()
+
+	staging := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(staging, bundle.HashtableDir), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := hashtab.WriteHashlist([]uint64{1}, filepath.Join(staging, bundle.HashtableDir, "3.22.0.64-rmpp")); err != nil {
+		t.Fatal(err)
+	}
+	manifest := &bundle.Manifest{
+		FormatVersion: bundle.FormatVersion,
+		Hashtables:    []bundle.Entry{{Path: "hashtables/3.22.0.64-rmpp", OSVersion: "3.22.0.64", Device: "rmpp"}},
+	}
+	if err := bundle.Describe(staging, &manifest.Hashtables[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	writeKey := func(name string) (string, ed25519.PrivateKey) {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path, key
+	}
+	keyPath, key := writeKey("key.pem")
+	otherKeyPath, _ := writeKey("other.pem")
+
+	out := filepath.Join(dir, "data.tar.zst")
+	if err := bundle.Write(out, staging, manifest, key); err != nil {
+		t.Fatal(err)
+	}
+	qmd := filepath.Join(dir, "a.qmd")
+	if err := os.WriteFile(qmd, []byte("AFFECT [[1]]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{ServerHost: "http://127.0.0.1:1"}
+	localCheck, checkBundle, checkBundleKey = true, out, keyPath
+	checker, server, err := newChecker(cfg, api.NewClient(cfg.ServerHost))
+	if err != nil {
+		t.Fatalf("newChecker() error = %v", err)
+	}
+	if server != out {
+		t.Errorf("newChecker() server = %q, want %q", server, out)
+	}
+	response, err := checker.CompareQMD(context.Background(), qmd)
+	if err != nil {
+		t.Fatalf("CompareQMD() error = %v", err)
+	}
+	if len(response.Compatible) != 1 || response.Compatible[0].OSVersion != "3.22.0.64" {
+		t.Errorf("CompareQMD() = %+v, want compatible with 3.22.0.64", response)
+	}
+
+	checkBundleKey = otherKeyPath
+	if _, _, err := newChecker(cfg, api.NewClient(cfg.ServerHost)); err == nil {
+		t.Error("newChecker() expected error for a bundle signed with another key")
+	}
+
+	checkBundleKey = ""
+	if _, _, err := newChecker(cfg, api.NewClient(cfg.ServerHost)); err == nil || !strings.Contains(err.Error(), "--bundle-key") {
+		t.Errorf("newChecker() without --bundle-key error = %v", err)
+	}
+}
@@ -0,0 +1,209 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/cache"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/display"
+	"github.com/spf13/cobra"
+)
+
+var (
+	pruneOlderThan string
+	pruneMaxSize   string
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Inspect and clean up the on-disk cache",
+	Long: `Inspect and clean up the cache directory, which holds the sync mirror,
+downloaded QML trees, stored job results, cached check results and the
+records of seen versions. The directory is QMDVERIFY_CACHE_DIR, or qmdverify
+in the user cache directory.
+
+Set QMDVERIFY_CACHE_MAX_SIZE (e.g. 2GB) to prune the least recently used
+entries automatically after 'sync' and 'tree download'.`,
+}
+
+var cacheStatsCmd = &cobra.Command{
+	Use:          "stats",
+	Short:        "Show what the cache holds and how large it is",
+	Example:      `  qmdverify cache stats`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE:         runCacheStats,
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear [area...]",
+	Short: "Remove everything in the cache, or only the given areas",
+	Long: `Remove everything in the cache. Name areas (as shown by 'cache stats', e.g.
+trees or hashtabs) to clear only those.`,
+	Example: `  qmdverify cache clear
+  qmdverify cache clear trees`,
+	SilenceUsage: true,
+	RunE:         runCacheClear,
+}
+
+var cachePruneCmd = &cobra.Command{
+	Use:   "prune --older-than <age> | --max-size <size>",
+	Short: "Remove stale entries or evict entries down to a size limit",
+	Long: `Remove cache entries that have not been used recently, or the least recently
+used ones until the cache fits a size limit. An entry is a mirrored hashtable,
+a downloaded QML tree or a cache file; the mirror's manifest is kept. Local
+checks mark the hashtables and trees they read as used.`,
+	Example: `  qmdverify cache prune --older-than 30d
+  qmdverify cache prune --max-size 500MB`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE:         runCachePrune,
+}
+
+func init() {
+	cachePruneCmd.Flags().StringVar(&pruneOlderThan, "older-than", "", "Remove entries not used for this long (e.g. 30d, 2w, 12h)")
+	cachePruneCmd.Flags().StringVar(&pruneMaxSize, "max-size", "", "Evict least recently used entries until the cache is at most this size (e.g. 500MB, 2GB)")
+	cacheCmd.AddCommand(cacheStatsCmd)
+	cacheCmd.AddCommand(cacheClearCmd)
+	cacheCmd.AddCommand(cachePruneCmd)
+}
+
+func runCacheStats(cmd *cobra.Command, args []string) error {
+	dir, err := cache.Dir()
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	items, err := cache.Scan(dir)
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	renderCacheStats(os.Stdout, dir, items)
+	return nil
+}
+
+// renderCacheStats prints one line per area and a total.
+func renderCacheStats(w io.Writer, dir string, items []cache.Item) {
+	type areaStats struct {
+		items    int
+		size     int64
+		lastUsed time.Time
+	}
+	areas := make(map[string]*areaStats)
+	total := &areaStats{}
+	for _, item := range items {
+		a := areas[item.Area]
+		if a == nil {
+			a = &areaStats{}
+			areas[item.Area] = a
+		}
+		for _, s := range []*areaStats{a, total} {
+			s.items++
+			s.size += item.Size
+			if item.LastUsed.After(s.lastUsed) {
+				s.lastUsed = item.LastUsed
+			}
+		}
+	}
+
+	names := make([]string, 0, len(areas))
+	for name := range areas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(w, "Directory: %s\n\n", dir)
+	if len(items) == 0 {
+		fmt.Fprintln(w, "Cache is empty")
+		return
+	}
+	line := func(name string, s *areaStats) {
+		fmt.Fprintf(w, "%-10s %6d items %11s   last used %s\n", name, s.items, formatBytes(s.size), s.lastUsed.Local().Format("2006-01-02 15:04"))
+	}
+	for _, name := range names {
+		line(name, areas[name])
+	}
+	line("Total", total)
+}
+
+func runCacheClear(cmd *cobra.Command, args []string) error {
+	dir, err := cache.Dir()
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	removed, err := cache.Clear(dir, args)
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	return display.RenderSuccess(os.Stdout, fmt.Sprintf("✓ Removed %s from %s", describeRemoved(removed), dir))
+}
+
+func runCachePrune(cmd *cobra.Command, args []string) error {
+	if pruneOlderThan == "" && pruneMaxSize == "" {
+		err := fmt.Errorf("specify --older-than, --max-size or both")
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	var olderThan time.Duration
+	var maxSize int64
+	var err error
+	if pruneOlderThan != "" {
+		olderThan, err = cache.ParseAge(pruneOlderThan)
+	}
+	if err == nil && pruneMaxSize != "" {
+		maxSize, err = cache.ParseSize(pruneMaxSize)
+	}
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	dir, err := cache.Dir()
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	removed, err := cache.Prune(dir, olderThan, maxSize, time.Now())
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	for _, item := range removed {
+		fmt.Fprintf(os.Stderr, "  - %s\n", item.Path)
+	}
+	return display.RenderSuccess(os.Stdout, fmt.Sprintf("✓ Pruned %s from %s", describeRemoved(removed), dir))
+}
+
+// enforceCacheLimit applies QMDVERIFY_CACHE_MAX_SIZE after a command that
+// grows the cache. Failures are warnings; the command itself succeeded.
+func enforceCacheLimit() {
+	removed, err := cache.Enforce()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return
+	}
+	if len(removed) > 0 {
+		fmt.Fprintf(os.Stderr, "Pruned %s to stay within %s\n", describeRemoved(removed), cache.EnvVarMaxSize)
+	}
+}
+
+func describeRemoved(items []cache.Item) string {
+	var size int64
+	for _, item := range items {
+		size += item.Size
+	}
+	return fmt.Sprintf("%d entries (%s)", len(items), formatBytes(size))
+}
@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/cache"
+)
+
+func TestRenderCacheStats(t *testing.T) {
+	used := time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)
+	items := []cache.Item{
+		{Path: "hashtabs/hashtables/a", Area: "hashtabs", Size: 1024, LastUsed: used.Add(-time.Hour)},
+		{Path: "hashtabs/hashtables/b", Area: "hashtabs", Size: 1024, LastUsed: used},
+		{Path: "seen-versions.json", Area: cache.StateArea, Size: 100, LastUsed: used.Add(-24 * time.Hour)},
+	}
+
+	var buf bytes.Buffer
+	renderCacheStats(&buf, "/cache", items)
+	out := buf.String()
+	for _, want := range []string{
+		"Directory: /cache",
+		"hashtabs        2 items     2.0 KiB   last used 2026-03-01 12:00",
+		"state           1 items       100 B   last used 2026-02-28 12:00",
+		"Total           3 items     2.1 KiB   last used 2026-03-01 12:00",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("renderCacheStats() missing %q in:\n%s", want, out)
+		}
+	}
+
+	buf.Reset()
+	renderCacheStats(&buf, "/cache", nil)
+	if !strings.Contains(buf.String(), "Cache is empty") {
+		t.Errorf("renderCacheStats() for an empty cache = %q", buf.String())
+	}
+}
+
+func TestCachePrune(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(cache.EnvVarCacheDir, dir)
+	old := filepath.Join(dir, "trees", "3.20.0.92-rmpp")
+	if err := os.MkdirAll(old, 0755); err != nil {
+		t.Fatal(err)
+	}
+	stale := time.Now().Add(-60 * 24 * time.Hour)
+	if err := os.Chtimes(old, stale, stale); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() { pruneOlderThan, pruneMaxSize = "", "" }()
+	if err := cachePruneCmd.RunE(nil, nil); err == nil {
+		t.Error("cachePruneCmd.RunE() expected error without a limit")
+	}
+
+	pruneOlderThan = "nonsense"
+	if err := cachePruneCmd.RunE(nil, nil); err == nil {
+		t.Error("cachePruneCmd.RunE() expected error for an invalid age")
+	}
+
+	pruneOlderThan = "30d"
+	if err := cachePruneCmd.RunE(nil, nil); err != nil {
+		t.Fatalf("cachePruneCmd.RunE() error = %v", err)
+	}
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Error("cache prune kept a tree unused for 60 days")
+	}
+}
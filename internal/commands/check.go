@@ -1,16 +1,36 @@
 package commands
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
-	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/api"
+	"github.com/charmbracelet/x/term"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/bundle"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/cache"
 	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/config"
 	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/display"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/formatter"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/i18n"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/ignore"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/local"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/picker"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/policy"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/store"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/tree"
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var checkCmd = &cobra.Command{
@@ -24,10 +44,25 @@ compatibility across multiple reMarkable device types and OS versions.`,
   qmdverify check myfile.qmd --verbose
   qmdverify check --device rmpp myfile.qmd
   qmdverify check --version 3.22 myfile.qmd
-  qmdverify check --device rmpp --device rmppm --version 3.22.4.2 myfile.qmd`,
+  qmdverify check --device rmpp --device rmppm --version 3.22.4.2 myfile.qmd
+  qmdverify check --timeline myfile.qmd
+  qmdverify check --output toltec ./overlays/
+  qmdverify check --output junit ./overlays/   # runs qmdverify-format-junit
+  qmdverify check --output table --output junit=report.xml ./overlays/
+  qmdverify check --format-template '{{range .Incompatible}}{{.Device}} {{.OSVersion}}{{"\n"}}{{end}}' myfile.qmd
+  qmdverify check --query '.incompatible[].os_version' myfile.qmd
+  qmdverify check --porcelain ./overlays/
+  qmdverify check --attest --key key.pem myfile.qmd
+  qmdverify check --output junit=report.xml --sign-outputs --key key.pem ./overlays/
+  git diff --name-only | qmdverify check --file-list -`,
 	SilenceUsage: true,
-	Args:         cobra.MinimumNArgs(1),
-	RunE:         runCheck,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if fileList != "" {
+			return nil
+		}
+		return cobra.MinimumNArgs(1)(cmd, args)
+	},
+	RunE: runCheck,
 }
 
 func init() {
@@ -35,6 +70,342 @@ func init() {
 	checkCmd.Flags().StringSliceVar(&versionFilter, "version", nil, "Filter by version prefix (can be repeated, e.g., 3.22 or 3.22.4.2)")
 	checkCmd.Flags().StringSliceVarP(&fileFilter, "file", "f", nil, "Filter output to specific files (can be repeated, supports glob patterns)")
 	checkCmd.Flags().BoolVar(&failedOnly, "failed-only", false, "Only show files with incompatibilities")
+	checkCmd.Flags().StringVar(&fileList, "file-list", "", "Read newline-separated files to check from this file ('-' for stdin)")
+	checkCmd.Flags().StringVar(&runNote, "note", "", "Note saved with the run's stored results and shown by jobs list, e.g. what changed since the last run")
+	checkCmd.Flags().BoolVar(&pickFiles, "pick", false, "Choose which of the collected files to check with an interactive fuzzy finder")
+	checkCmd.Flags().StringVar(&symbolsMode, "symbols", display.SymbolsDefault, "Status indicators: default (✓ ✗ —), safe (colorblind-friendly OK/FAIL/N/A), emoji (✅ ❌ ➖) or plain (Y N -)")
+	checkCmd.Flags().IntVar(&preflightWorkers, "workers", runtime.NumCPU(), "Number of files to sniff and hash at once before upload")
+	checkCmd.Flags().IntVar(&concurrency, "concurrency", 1, "Split batch checks into up to this many jobs uploaded at once, keeping files that LOAD each other together")
+	checkCmd.Flags().BoolVar(&lowMemory, "low-memory", false, "Find, hash and upload files in batches as they are found, streaming each from disk, to bound memory on very large trees")
+	checkCmd.Flags().BoolVar(&printTrailer, "trailer", false, "End text output with a QMDVERIFY_RESULT line of counts and the exit code for scripts")
+	checkCmd.Flags().BoolVar(&githubActions, "github", false, "Append the verdict, counts and oldest compatible versions to $GITHUB_OUTPUT and a Markdown matrix to $GITHUB_STEP_SUMMARY")
+	checkCmd.Flags().IntVar(&maxDepth, "max-depth", -1, "Only descend this many directory levels below each directory argument (0 = top level only)")
+	checkCmd.Flags().BoolVar(&noRecursive, "no-recursive", false, "Only check files directly in each directory argument (same as --max-depth 0)")
+	checkCmd.Flags().BoolVar(&timeline, "timeline", false, "Show a per-device firmware timeline instead of the matrix")
+	checkCmd.Flags().BoolVar(&showTotals, "totals", false, "Add per-device and per-version pass counts to the matrix")
+	checkCmd.Flags().BoolVar(&incompatibleOnly, "incompatible-only", false, "Hide matrix rows where every device is compatible (totals and the summary still count every version)")
+	checkCmd.Flags().IntVar(&latestVersions, "latest", 0, "Only show the newest N OS versions of each device in the matrix (0 = all)")
+	checkCmd.Flags().BoolVar(&transposeMatrix, "transpose", false, "List devices as rows and OS versions as columns in the matrix")
+	checkCmd.Flags().StringVar(&sortVersions, "sort-versions", sortDesc, "Order matrix rows by version: desc (newest first) or asc")
+	checkCmd.Flags().StringSliceVar(&deviceOrder, "device-order", nil, "Devices to show first in the matrix, in this order (e.g. rmpp,rm2)")
+	checkCmd.Flags().BoolVar(&showLegend, "legend", false, "Print a legend explaining the matrix symbols")
+	checkCmd.Flags().StringArrayVar(&outputFlags, "output", []string{outputText}, "Output format (text, json, toltec, porcelain, cyclonedx, sarif, or an installed qmdverify-format-* plugin). Use format=path to also write a format to a file (can be repeated)")
+	checkCmd.Flags().StringVar(&formatTmpl, "format-template", "", "Render each file's results through a Go text/template")
+	checkCmd.Flags().StringVar(&templateFile, "template-file", "", "Read the --format-template from a file")
+	checkCmd.Flags().StringVar(&queryExpr, "query", "", "Print values selected from each file's JSON result with a jq-style path (e.g. '.incompatible[].os_version')")
+	checkCmd.Flags().BoolVar(&porcelain, "porcelain", false, "Stable tab-separated output for scripts (same as --output porcelain)")
+	checkCmd.Flags().BoolVar(&attestResults, "attest", false, "Write a signed attestation of the results next to each root file (requires --key)")
+	checkCmd.Flags().StringVar(&attestKey, "key", "", "PEM private key used to sign attestations and --sign-outputs reports")
+	checkCmd.Flags().BoolVar(&signOutputs, "sign-outputs", false, "Write a signed provenance record next to each --output format=path file (requires --key)")
+	checkCmd.Flags().BoolVar(&localCheck, "local", false, "Check against the hashtables mirrored by 'qmdverify sync' instead of the server")
+	checkCmd.Flags().StringVar(&hashtableDir, "hashtable-dir", "", "Check against the hashtable and hashlist files in this directory instead of the sync mirror (implies --local unless --hybrid is set)")
+	checkCmd.Flags().StringVar(&checkBundle, "bundle", "", "Check against the hashtables (and with --mode tree, the trees) in this offline bundle instead of the sync mirror (implies --local unless --hybrid is set)")
+	checkCmd.Flags().StringVar(&checkBundleKey, "bundle-key", "", "PEM public key the --bundle manifest must be signed with")
+	checkCmd.Flags().BoolVar(&bundleNoVerify, "bundle-unsigned", false, "Use the --bundle without verifying its manifest signature")
+	checkCmd.Flags().StringVar(&localMode, "mode", local.ModeHashtable, "Local validation mode with --local: hashtable, or tree to also check against downloaded QML trees")
+	checkCmd.Flags().StringArrayVar(&withDeps, "with-dep", nil, "Upload this dependency file along with the checked files (can be repeated)")
+	checkCmd.Flags().BoolVar(&requireDeps, "require-deps", false, "Fail instead of warning when a checked file LOADs a file that is not part of the upload")
+	checkCmd.Flags().BoolVar(&depsAsWarnings, "deps-as-warnings", false, "Report incompatibilities caused only by dependency files as warnings (exit code 2 instead of 1)")
+	checkCmd.Flags().BoolVar(&depsOnly, "deps-only", false, "Only report on files that other checked files depend on, skipping root overlays")
+	checkCmd.Flags().BoolVar(&retryErrors, "retry-errors", false, "Resubmit files whose results carry server processing errors once and merge the retried results")
+	checkCmd.Flags().BoolVar(&hybridCheck, "hybrid", false, "Check against the local mirror first and only upload files that pass at least one mirrored hashtable")
+	checkCmd.Flags().StringVar(&limitRate, "limit-rate", "", "Limit upload bandwidth to this many bytes per second (e.g. 500K, 2M)")
+	checkCmd.Flags().StringArrayVar(&failOnFlags, "fail-on", nil, "Only fail on incompatibilities with these devices and versions, e.g. device=rmpp,version=3.22 (can be repeated)")
+	checkCmd.Flags().BoolVar(&exitZero, "exit-zero", false, "Exit 0 even when incompatibilities are found or nothing was checked (server errors still exit 3)")
+	checkCmd.Flags().BoolVar(&noCache, "no-cache", false, "Check every file against the server instead of reusing cached results for unchanged files")
+	checkCmd.Flags().StringVar(&chunkedUpload, "chunked-upload", api.ChunkedAuto, "Upload large batches in resumable chunks: auto (above 64MB, when the server supports it), always or never")
+}
+
+const (
+	outputText      = "text"
+	outputTable     = "table"
+	outputToltec    = "toltec"
+	outputPorcelain = "porcelain"
+	outputCycloneDX = "cyclonedx"
+	outputJSON      = "json"
+	outputSARIF     = "sarif"
+
+	sortAsc  = "asc"
+	sortDesc = "desc"
+)
+
+func init() {
+	formatter.Register(outputToltec, formatter.Func(func(w io.Writer, report *formatter.Report) error {
+		return display.RenderToltec(w, intersectResponses(report.Responses()))
+	}))
+	formatter.Register(outputPorcelain, formatter.Func(func(w io.Writer, report *formatter.Report) error {
+		return display.RenderPorcelain(w, report.Results)
+	}))
+	formatter.Register(outputCycloneDX, formatter.Func(formatter.CycloneDX))
+	formatter.Register(outputSARIF, formatter.Func(formatter.SARIF))
+	formatter.Register(outputJSON, formatter.Func(func(w io.Writer, report *formatter.Report) error {
+		return display.RenderJSON(w, report)
+	}))
+}
+
+// outputFile is an --output format=path destination.
+type outputFile struct {
+	format    string
+	path      string
+	formatter formatter.Formatter
+}
+
+// parseOutputs splits the --output values into the one format written to
+// stdout and the formats written to files.
+func parseOutputs(values []string) (string, []outputFile, error) {
+	stdout := ""
+	var files []outputFile
+	for _, value := range values {
+		format, path, toFile := strings.Cut(value, "=")
+		if format == outputTable {
+			format = outputText
+		}
+		if !toFile {
+			if stdout != "" && stdout != format {
+				return "", nil, fmt.Errorf("only one --output can write to stdout; use format=path for the others")
+			}
+			stdout = format
+			continue
+		}
+		if format == "" || path == "" {
+			return "", nil, fmt.Errorf("invalid --output %q: expected format=path", value)
+		}
+		files = append(files, outputFile{format: format, path: path})
+	}
+	if stdout == "" {
+		stdout = outputText
+	}
+	return stdout, files, nil
+}
+
+// resolveOutputFiles looks up the formatter for each file destination.
+func resolveOutputFiles(files []outputFile) error {
+	for i := range files {
+		if files[i].format == outputText {
+			files[i].formatter = textFormatter
+			continue
+		}
+		f, err := formatter.Lookup(files[i].format)
+		if err != nil {
+			formats := append([]string{outputText}, formatter.Available()...)
+			return fmt.Errorf("%s", i18n.T(i18n.MsgErrInvalidOutput, files[i].format, strings.Join(formats, ", ")))
+		}
+		files[i].formatter = f
+	}
+	return nil
+}
+
+// writeOutputFiles writes the report to every format=path destination.
+func writeOutputFiles(files []outputFile, report *formatter.Report) error {
+	for _, file := range files {
+		f, err := os.Create(file.path)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", file.path, err)
+		}
+		err = file.formatter.Format(f, report)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write %s output to %s: %w", file.format, file.path, err)
+		}
+	}
+	return nil
+}
+
+// textFormatter renders the human-readable output for --output text=path,
+// with a header per file and the device summary when there is more than one.
+var textFormatter = formatter.Func(func(w io.Writer, report *formatter.Report) error {
+	newVersions := make(map[string]bool)
+	for _, version := range report.NewVersions {
+		newVersions[version] = true
+	}
+
+	sharedDeps := sharedDependencyRefs(report.SharedDependencies)
+	files := report.Files()
+	for _, name := range files {
+		if len(files) > 1 {
+			fmt.Fprintf(w, "\n=== %s ===\n\n", name)
+		}
+		if err := renderResults(w, report.Results[name], newVersions); err != nil {
+			return err
+		}
+		renderSharedDependencyRefs(w, sharedDeps[name])
+	}
+	if err := display.RenderSharedDependencies(w, report.SharedDependencies); err != nil {
+		return err
+	}
+	if len(files) > 1 {
+		if err := display.RenderDeviceSummary(w, report.Results); err != nil {
+			return err
+		}
+	}
+	if report.Timing != nil {
+		return display.RenderTiming(w, report.Timing)
+	}
+	return nil
+})
+
+// resolveFormatter returns the formatter for --output, --query or the
+// template flags, or nil for the built-in text output.
+func resolveFormatter(format string) (formatter.Formatter, error) {
+	if porcelain {
+		if queryExpr != "" || formatTmpl != "" || templateFile != "" || (format != outputText && format != outputPorcelain) {
+			return nil, fmt.Errorf("--porcelain cannot be combined with --output, --query or an output template")
+		}
+		format = outputPorcelain
+	}
+
+	if queryExpr != "" {
+		if formatTmpl != "" || templateFile != "" || format != outputText {
+			return nil, fmt.Errorf("--query cannot be combined with --output or an output template")
+		}
+		return formatter.NewQuery(queryExpr)
+	}
+
+	if formatTmpl != "" || templateFile != "" {
+		return resolveTemplate(format)
+	}
+
+	if format == outputText {
+		return nil, nil
+	}
+
+	f, err := formatter.Lookup(format)
+	if err != nil {
+		formats := append([]string{outputText}, formatter.Available()...)
+		return nil, fmt.Errorf("%s", i18n.T(i18n.MsgErrInvalidOutput, format, strings.Join(formats, ", ")))
+	}
+	return f, nil
+}
+
+func resolveTemplate(format string) (formatter.Formatter, error) {
+	if formatTmpl != "" && templateFile != "" {
+		return nil, fmt.Errorf("--format-template and --template-file cannot be used together")
+	}
+	if format != outputText {
+		return nil, fmt.Errorf("--output %s cannot be combined with an output template", format)
+	}
+
+	text := formatTmpl
+	if templateFile != "" {
+		data, err := os.ReadFile(templateFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template file: %w", err)
+		}
+		text = string(data)
+	}
+
+	return formatter.NewTemplate(text)
+}
+
+// newReport builds the input handed to a formatter.
+func newReport(server string, results map[string]*api.ComparisonResponse, newVersions map[string]bool) *formatter.Report {
+	report := &formatter.Report{
+		Server:     server,
+		CLIVersion: Version,
+		Results:    results,
+	}
+	for version := range newVersions {
+		report.NewVersions = append(report.NewVersions, version)
+	}
+	sort.Strings(report.NewVersions)
+	report.SupportWindows = make(map[string][]formatter.SupportWindow, len(results))
+	for file, response := range results {
+		report.SupportWindows[file] = display.ComputeSupportWindows(response)
+	}
+	report.CollapseSharedDependencies()
+	return report
+}
+
+// describeInputs records the SHA-256 of each reported file, its location
+// when SARIF is requested and, when a CycloneDX manifest is requested from a
+// server check, the server version. Files that can't be read are left
+// without a digest.
+func describeInputs(ctx context.Context, report *formatter.Report, client *api.Client, files []outputFile, filePaths, relativePaths []string) {
+	report.Digests = make(map[string]string, len(report.Results))
+	for filename := range report.Results {
+		if sum, err := cachedSHA256(rootFilePath(filename, filePaths, relativePaths)); err == nil {
+			report.Digests[filename] = sum
+		}
+	}
+
+	if writesFormat(outputSARIF, files) {
+		report.Locations = fileLocations(report.Results, filePaths, relativePaths)
+	}
+
+	if localCheck || !writesFormat(outputCycloneDX, files) {
+		return
+	}
+	if v, err := client.GetVersion(ctx); err == nil {
+		report.ServerVersion = v.Version
+	}
+}
+
+// fileLocations returns the path of each reported file relative to the
+// working directory, leaving out files outside it.
+func fileLocations(results map[string]*api.ComparisonResponse, filePaths, relativePaths []string) map[string]string {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil
+	}
+	locations := make(map[string]string, len(results))
+	for filename := range results {
+		abs, err := filepath.Abs(rootFilePath(filename, filePaths, relativePaths))
+		if err != nil {
+			continue
+		}
+		if rel, err := filepath.Rel(cwd, abs); err == nil && !strings.HasPrefix(rel, "..") {
+			locations[filename] = filepath.ToSlash(rel)
+		}
+	}
+	return locations
+}
+
+// writesFormat reports whether format is written to stdout or any file.
+func writesFormat(format string, files []outputFile) bool {
+	if outputFormat == format {
+		return true
+	}
+	for _, file := range files {
+		if file.format == format {
+			return true
+		}
+	}
+	return false
+}
+
+// sharedDependencyRefs maps each root file to the shared dependencies it
+// uses.
+func sharedDependencyRefs(deps []formatter.SharedDependency) map[string][]string {
+	refs := make(map[string][]string)
+	for _, dep := range deps {
+		for _, parent := range dep.UsedBy {
+			refs[parent] = append(refs[parent], dep.File)
+		}
+	}
+	return refs
+}
+
+func renderSharedDependencyRefs(w io.Writer, deps []string) {
+	if len(deps) > 0 {
+		fmt.Fprintln(w, i18n.T(i18n.MsgUsesSharedDeps, strings.Join(deps, ", ")))
+	}
+}
+
+// structuredOutput reports whether a formatter, rather than the human text
+// output, writes to stdout.
+func structuredOutput() bool {
+	return outputFormat != outputText || formatTmpl != "" || templateFile != "" || queryExpr != "" || porcelain
+}
+
+// reportWriter is where policy reports go. Machine-readable formats keep
+// stdout clean, so the report moves to stderr.
+func reportWriter() io.Writer {
+	if structuredOutput() {
+		return os.Stderr
+	}
+	return os.Stdout
 }
 
 var validDevices = map[string]bool{
@@ -44,210 +415,1083 @@ var validDevices = map[string]bool{
 	"rmppm": true,
 }
 
-func validateDeviceFilters(devices []string) error {
-	for _, device := range devices {
-		if !validDevices[device] {
-			return fmt.Errorf("invalid device '%s'. Valid devices: rm1, rm2, rmpp, rmppm", device)
+func validateDeviceFilters(devices []string) error {
+	for _, device := range devices {
+		if !validDevices[device] {
+			return fmt.Errorf("%s", i18n.T(i18n.MsgErrInvalidDevice, device))
+		}
+	}
+	return nil
+}
+
+func matchesVersionPrefix(version, prefix string) bool {
+	versionParts := strings.Split(version, ".")
+	prefixParts := strings.Split(prefix, ".")
+
+	if len(prefixParts) > len(versionParts) {
+		return false
+	}
+
+	for i, prefixPart := range prefixParts {
+		prefixNum, err1 := strconv.Atoi(prefixPart)
+		versionNum, err2 := strconv.Atoi(versionParts[i])
+
+		if err1 != nil || err2 != nil {
+			if versionParts[i] != prefixPart {
+				return false
+			}
+			continue
+		}
+
+		if versionNum != prefixNum {
+			return false
+		}
+	}
+
+	return true
+}
+
+func matchesFilter(result api.ComparisonResult, devices, versions []string) bool {
+	deviceMatch := len(devices) == 0
+	for _, d := range devices {
+		if result.Device == d {
+			deviceMatch = true
+			break
+		}
+	}
+
+	versionMatch := len(versions) == 0
+	for _, v := range versions {
+		if matchesVersionPrefix(result.OSVersion, v) {
+			versionMatch = true
+			break
+		}
+	}
+
+	return deviceMatch && versionMatch
+}
+
+func filterResponse(response *api.ComparisonResponse, devices, versions []string) *api.ComparisonResponse {
+	if len(devices) == 0 && len(versions) == 0 {
+		return response
+	}
+
+	filtered := &api.ComparisonResponse{
+		Compatible:   make([]api.ComparisonResult, 0),
+		Incompatible: make([]api.ComparisonResult, 0),
+	}
+
+	for _, result := range response.Compatible {
+		if matchesFilter(result, devices, versions) {
+			filtered.Compatible = append(filtered.Compatible, result)
+		}
+	}
+
+	for _, result := range response.Incompatible {
+		if matchesFilter(result, devices, versions) {
+			filtered.Incompatible = append(filtered.Incompatible, result)
+		}
+	}
+
+	filtered.TotalChecked = len(filtered.Compatible) + len(filtered.Incompatible)
+
+	return filtered
+}
+
+// runCheck checks the files named by args. A check whose results fail the
+// run returns a CheckError.
+func runCheck(cmd *cobra.Command, args []string) error {
+	return checkFailed(cmd, checkFiles(cmd, args))
+}
+
+func checkFiles(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	timer := newRunTimer()
+
+	if err := validateDeviceFilters(deviceFilter); err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+	failOn, err := parseFailOn(failOnFlags)
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	if fileList != "" {
+		listed, err := readFileList(fileList)
+		if err != nil {
+			display.RenderError(os.Stderr, err)
+			return err
+		}
+		args = append(args, listed...)
+	}
+
+	rcs := newRCResolver(cmd)
+	if len(args) > 0 {
+		if err := rcs.applyRunFlags(determineBaseDir(args)); err != nil {
+			display.RenderError(os.Stderr, err)
+			return err
+		}
+	}
+
+	stdoutFormat, outputFiles, err := parseOutputs(outputFlags)
+	if err == nil {
+		err = resolveOutputFiles(outputFiles)
+	}
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+	outputFormat = stdoutFormat
+
+	if err := validateMatrixOrder(); err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	if err := display.SetSymbols(symbolsMode, symbolGlyphs); err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	outputFormatter, err := resolveFormatter(outputFormat)
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	if printTrailer && !structuredOutput() {
+		trailer = &resultTrailer{}
+	}
+
+	if hashtableDir != "" && checkBundle != "" {
+		err := fmt.Errorf("--hashtable-dir and --bundle cannot be used together")
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	if (hashtableDir != "" || checkBundle != "") && !hybridCheck {
+		localCheck = true
+	}
+
+	if attestResults && attestKey == "" {
+		err := fmt.Errorf("--attest requires --key")
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	if attestResults && localCheck {
+		err := fmt.Errorf("--attest cannot be used with --local")
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	if signOutputs {
+		if err := validateSignOutputs(outputFiles); err != nil {
+			display.RenderError(os.Stderr, err)
+			return err
+		}
+	}
+
+	switch chunkedUpload {
+	case api.ChunkedAuto, api.ChunkedAlways, api.ChunkedNever:
+	default:
+		err := fmt.Errorf("invalid --chunked-upload %q: must be %s, %s or %s", chunkedUpload, api.ChunkedAuto, api.ChunkedAlways, api.ChunkedNever)
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	uploadRate, err := parseRate(limitRate)
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	if concurrency < 1 {
+		err := fmt.Errorf("--concurrency must be at least 1")
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	if lowMemory {
+		if err := validateLowMemory(structuredOutput() || len(outputFiles) > 0); err != nil {
+			display.RenderError(os.Stderr, err)
+			return err
+		}
+		rules, err := loadPolicyRules()
+		if err != nil {
+			display.RenderError(os.Stderr, err)
+			return err
+		}
+		cfg, err := config.Load()
+		if err != nil {
+			display.RenderError(os.Stderr, err)
+			return err
+		}
+		client := newAPIClient(cfg)
+		client.ChunkedUpload = chunkedUpload
+		client.UploadRateLimit = uploadRate
+		checker, server, err := newChecker(cfg, client)
+		if err != nil {
+			display.RenderError(os.Stderr, err)
+			return err
+		}
+		return runLowMemoryCheck(ctx, args, &lowMemoryCheck{checker: checker, rcs: rcs, server: server, rules: rules, failOn: failOn})
+	}
+
+	filePaths, relativePaths, err := collectQMDFiles(args)
+	if err == nil && pickFiles && len(filePaths) > 0 {
+		filePaths, relativePaths, err = pickQMDFiles(filePaths, relativePaths, picker.Run)
+	}
+	if err == nil && len(withDeps) > 0 {
+		filePaths, relativePaths, err = attachDependencies(filePaths, relativePaths, withDeps, determineBaseDir(args))
+	}
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	if len(filePaths) == 0 {
+		err := fmt.Errorf("%s", i18n.T(i18n.MsgErrNoQMDFiles))
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	if err := preflightFiles(filePaths, preflightWorkers); err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	if !localCheck {
+		if err := checkMissingDependencies(os.Stderr, filePaths, relativePaths); err != nil {
+			display.RenderError(os.Stderr, err)
+			return err
+		}
+	}
+
+	auditFiles(filePaths)
+
+	rules, err := loadPolicyRules()
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+	client := newAPIClient(cfg)
+	client.OnTiming = timer.record
+	client.ChunkedUpload = chunkedUpload
+	client.UploadRateLimit = uploadRate
+	if tty := term.IsTerminal(os.Stderr.Fd()); (tty || verbose) && concurrency == 1 {
+		client.OnUploadProgress = newUploadProgress(os.Stderr, tty)
+	}
+	checker, server, err := newChecker(cfg, client)
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+	if concurrency > 1 && !localCheck {
+		checker = &parallelComparer{comparer: checker, workers: concurrency}
+	}
+	if !noCache && !localCheck && !hybridCheck && len(cfg.Mirrors) == 0 {
+		checker = &cachingComparer{
+			comparer:  checker,
+			inventory: func(ctx context.Context) (string, error) { return hashtableInventory(ctx, client) },
+			warn:      os.Stderr,
+		}
+	}
+
+	if len(filePaths) == 1 && depsOnly {
+		err := fmt.Errorf("--deps-only needs several files or a directory so dependencies can be identified")
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	if len(filePaths) == 1 {
+		if localCheck {
+			fmt.Fprintf(os.Stderr, "%s\n\n", i18n.T(i18n.MsgCheckingFile, filepath.Base(filePaths[0]), server))
+		} else {
+			fmt.Fprintf(os.Stderr, "%s\n\n", i18n.T(i18n.MsgUploadingFile, filepath.Base(filePaths[0]), server))
+		}
+
+		response, err := checker.CompareQMD(ctx, filePaths[0])
+		if err == nil && retryErrors && hasProcessingError(response) {
+			fmt.Fprintf(os.Stderr, "%s\n\n", i18n.T(i18n.MsgRetryingFiles, 1))
+			response, err = checker.CompareQMD(ctx, filePaths[0])
+		}
+		if err != nil {
+			display.RenderError(os.Stderr, fmt.Errorf("%s: %w", i18n.T(i18n.MsgErrCheckFailed), err))
+			return serverFailure(ctx, err)
+		}
+
+		opts, err := rcs.options(filePaths[0])
+		if err != nil {
+			display.RenderError(os.Stderr, err)
+			return err
+		}
+
+		original := response
+		newVersions := trackNewVersions(server, original)
+		originalTotalChecked := response.TotalChecked
+		response = filterResponse(response, opts.devices, opts.versions)
+
+		if response.TotalChecked == 0 {
+			if originalTotalChecked == 0 {
+				fmt.Fprintln(os.Stderr, i18n.T(i18n.MsgWarnNoHashtables))
+			} else {
+				fmt.Fprintln(os.Stderr, i18n.T(i18n.MsgWarnNoFilterMatch))
+			}
+			// Machine-readable output is still written, with no results,
+			// so that consumers such as jq always get a document.
+			if outputFormatter != nil {
+				report := newReport(server, map[string]*api.ComparisonResponse{}, newVersions)
+				report.Timing = timer.finish()
+				if err := outputFormatter.Format(os.Stdout, report); err != nil {
+					display.RenderError(os.Stderr, err)
+					return err
+				}
+			}
+			return checkOutcome{noData: true}.err(exitZero, nil)
+		}
+
+		results := map[string]*api.ComparisonResponse{relativePaths[0]: response}
+		report := newReport(server, results, newVersions)
+		report.Timing = timer.finish()
+		describeInputs(ctx, report, client, outputFiles, filePaths, relativePaths)
+		if outputFormatter != nil {
+			if err := outputFormatter.Format(os.Stdout, report); err != nil {
+				display.RenderError(os.Stderr, err)
+				return err
+			}
+		} else {
+			if err := renderResults(os.Stdout, response, newVersions); err != nil {
+				return err
+			}
+			trailer.add(response)
+			if err := display.RenderTiming(os.Stdout, report.Timing); err != nil {
+				return err
+			}
+		}
+
+		if err := writeOutputFiles(outputFiles, report); err != nil {
+			display.RenderError(os.Stderr, err)
+			return err
+		}
+		if signOutputs {
+			if err := signOutputFiles(ctx, client, server, outputFiles, filePaths, relativePaths); err != nil {
+				display.RenderError(os.Stderr, err)
+				return err
+			}
+		}
+
+		failed, err := evaluateOutcome(reportWriter(), original, response, rules, failOn)
+		if err != nil {
+			return err
+		}
+		warnings := 0
+		if depsAsWarnings && len(rules) == 0 {
+			failed, warnings = downgradeDependencyFailures(selectFailures(response, failOn), relativePaths[0])
+			if outputFormatter == nil {
+				renderDependencyWarnings(os.Stdout, warnings)
+			}
+		}
+
+		if attestResults {
+			results := map[string]*api.ComparisonResponse{relativePaths[0]: original}
+			if err := writeAttestations(ctx, client, server, results, filePaths, relativePaths); err != nil {
+				display.RenderError(os.Stderr, err)
+				return err
+			}
+		}
+
+		if githubActions {
+			if err := writeGitHub(report, failed); err != nil {
+				display.RenderError(os.Stderr, err)
+				return err
+			}
+		}
+
+		return checkOutcome{
+			serverError:        hasProcessingError(selectFailures(response, failOn)),
+			incompatible:       failed,
+			dependencyWarnings: warnings,
+		}.err(exitZero, report)
+	}
+
+	if localCheck {
+		fmt.Fprintf(os.Stderr, "%s\n\n", i18n.T(i18n.MsgCheckingFiles, len(filePaths), server))
+	} else {
+		fmt.Fprintf(os.Stderr, "%s\n\n", i18n.T(i18n.MsgUploadingFiles, len(filePaths), server))
+	}
+
+	batchResponse, err := checker.CompareQMDFiles(ctx, filePaths, relativePaths)
+	if err == nil && retryErrors {
+		err = retryFailedFiles(ctx, checker, batchResponse, filePaths, relativePaths)
+	}
+	if err != nil {
+		display.RenderError(os.Stderr, fmt.Errorf("%s: %w", i18n.T(i18n.MsgErrCheckFailed), err))
+		return serverFailure(ctx, err)
+	}
+
+	rootFiles := identifyRootFiles(batchResponse)
+	shown := rootFiles
+	if depsOnly {
+		shown = dependencyFiles(batchResponse, rootFiles)
+		if len(shown) == 0 {
+			fmt.Fprintln(os.Stderr, "Warning: no dependency files found; nothing to check with --deps-only")
+		}
+	}
+
+	var allResponses []*api.ComparisonResponse
+	for _, response := range *batchResponse {
+		allResponses = append(allResponses, &response)
+	}
+	newVersions := trackNewVersions(server, allResponses...)
+
+	roots := make(map[string]*api.ComparisonResponse)
+	for filename, response := range *batchResponse {
+		if rootFiles[filename] && !depsOnly && matchesFileFilter(filename, fileFilter) {
+			roots[filename] = &response
+		}
+	}
+	sharedDeps := sharedDependencyRefs(formatter.SharedDependencies(roots))
+
+	var outcome checkOutcome
+	hasData := false
+	formatted := make(map[string]*api.ComparisonResponse)
+	for filename, response := range *batchResponse {
+		if !shown[filename] {
+			continue
+		}
+
+		if !matchesFileFilter(filename, fileFilter) {
+			continue
+		}
+
+		opts, err := rcs.options(rootFilePath(filename, filePaths, relativePaths))
+		if err != nil {
+			display.RenderError(os.Stderr, err)
+			return err
+		}
+
+		originalTotalChecked := response.TotalChecked
+		filtered := filterResponse(&response, opts.devices, opts.versions)
+		if filtered.TotalChecked > 0 {
+			hasData = true
+		}
+
+		if opts.failedOnly && len(filtered.Incompatible) == 0 {
+			continue
+		}
+
+		if filename != "" && outputFormatter == nil {
+			fmt.Printf("\n=== %s ===\n\n", filename)
+		}
+
+		if filtered.TotalChecked == 0 {
+			if originalTotalChecked == 0 {
+				fmt.Fprintln(os.Stderr, i18n.T(i18n.MsgWarnNoHashtables))
+			} else {
+				fmt.Fprintln(os.Stderr, i18n.T(i18n.MsgWarnNoFilterMatch))
+			}
+			continue
+		}
+
+		formatted[filename] = filtered
+		if outputFormatter == nil {
+			if err := renderResults(os.Stdout, filtered, newVersions); err != nil {
+				return err
+			}
+			trailer.add(filtered)
+			renderSharedDependencyRefs(os.Stdout, sharedDeps[filename])
+		}
+
+		failed, err := evaluateOutcome(reportWriter(), &response, filtered, rules, failOn)
+		if err != nil {
+			return err
+		}
+		if depsAsWarnings && len(rules) == 0 {
+			var warnings int
+			failed, warnings = downgradeDependencyFailures(selectFailures(filtered, failOn), filename)
+			if outputFormatter == nil {
+				renderDependencyWarnings(os.Stdout, warnings)
+			}
+			outcome.dependencyWarnings += warnings
+		}
+		if failed {
+			outcome.incompatible = true
+		}
+		if hasProcessingError(selectFailures(filtered, failOn)) {
+			outcome.serverError = true
+		}
+	}
+	outcome.noData = !hasData
+
+	if attestResults {
+		results := make(map[string]*api.ComparisonResponse)
+		for filename, response := range *batchResponse {
+			if rootFiles[filename] {
+				results[filename] = &response
+			}
+		}
+		if err := writeAttestations(ctx, client, server, results, filePaths, relativePaths); err != nil {
+			display.RenderError(os.Stderr, err)
+			return err
+		}
+	}
+
+	report := newReport(server, formatted, newVersions)
+	report.Timing = timer.finish()
+	describeInputs(ctx, report, client, outputFiles, filePaths, relativePaths)
+	if outputFormatter != nil {
+		if err := outputFormatter.Format(os.Stdout, report); err != nil {
+			display.RenderError(os.Stderr, err)
+			return err
+		}
+	} else if outputFormatter == nil {
+		if err := display.RenderSharedDependencies(os.Stdout, report.SharedDependencies); err != nil {
+			return err
+		}
+		if len(formatted) > 1 {
+			if err := display.RenderDeviceSummary(os.Stdout, formatted); err != nil {
+				return err
+			}
+		}
+		fmt.Println()
+		if err := display.RenderTiming(os.Stdout, report.Timing); err != nil {
+			return err
+		}
+	}
+
+	if err := writeOutputFiles(outputFiles, report); err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+	if signOutputs {
+		if err := signOutputFiles(ctx, client, server, outputFiles, filePaths, relativePaths); err != nil {
+			display.RenderError(os.Stderr, err)
+			return err
+		}
+	}
+
+	if githubActions {
+		if err := writeGitHub(report, outcome.incompatible); err != nil {
+			display.RenderError(os.Stderr, err)
+			return err
+		}
+	}
+
+	return outcome.err(exitZero, report)
+}
+
+// newUploadProgress prints batch upload progress to w. On a terminal the
+// line is redrawn in place; otherwise a line is printed as each file starts.
+func newUploadProgress(w io.Writer, tty bool) func(api.UploadProgress) {
+	current := 0
+	return func(p api.UploadProgress) {
+		line := i18n.T(i18n.MsgUploadProgress, p.Index, p.Files, p.File, formatBytes(p.BytesSent), formatBytes(p.BytesTotal))
+		switch {
+		case tty:
+			fmt.Fprintf(w, "\r\033[K%s", line)
+			if p.BytesSent == p.BytesTotal {
+				fmt.Fprint(w, "\n\n")
+			}
+		case p.Index != current:
+			fmt.Fprintln(w, line)
+		}
+		current = p.Index
+	}
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// runTimer measures a check run for the timing footer.
+type runTimer struct {
+	mu     sync.Mutex
+	start  time.Time
+	timing api.Timing
+}
+
+func newRunTimer() *runTimer {
+	return &runTimer{start: time.Now()}
+}
+
+// record adds the upload and server time of one comparison.
+func (t *runTimer) record(timing api.Timing) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.timing.UploadMS += timing.UploadMS
+	t.timing.QueueMS += timing.QueueMS
+	t.timing.ProcessingMS += timing.ProcessingMS
+}
+
+// finish returns the timing so far, with the wall time since the run began.
+func (t *runTimer) finish() *api.Timing {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	timing := t.timing
+	timing.TotalMS = time.Since(t.start).Milliseconds()
+	return &timing
+}
+
+// checkOptions are the filters for one file after merging the
+// .qmdverifyrc files above it with the command line.
+type checkOptions struct {
+	devices    []string
+	versions   []string
+	failedOnly bool
+}
+
+// rcResolver applies .qmdverifyrc files. Flags given on the command line
+// always win over pinned values.
+type rcResolver struct {
+	flags *pflag.FlagSet
+	cache map[string]checkOptions
+}
+
+func newRCResolver(cmd *cobra.Command) *rcResolver {
+	return &rcResolver{flags: cmd.Flags(), cache: make(map[string]checkOptions)}
+}
+
+// applyRunFlags pins the flags that apply to the whole run, --output,
+// --symbols and its glyphs, and the matrix ordering, from the rc files above
+// dir. It runs before the --output values are parsed, so an rc output is
+// only used when no --output was given.
+func (r *rcResolver) applyRunFlags(dir string) error {
+	rc, err := config.LoadRC(dir)
+	if err != nil {
+		return err
+	}
+	if rc.Output != nil && !r.flags.Changed("output") && !porcelain {
+		outputFlags = rc.Output
+	}
+	if rc.SortVersions != "" && !r.flags.Changed("sort-versions") {
+		sortVersions = rc.SortVersions
+	}
+	if rc.DeviceOrder != nil && !r.flags.Changed("device-order") {
+		deviceOrder = rc.DeviceOrder
+	}
+	if rc.Symbols != "" && !r.flags.Changed("symbols") {
+		symbolsMode = rc.Symbols
+	}
+	symbolGlyphs = display.Glyphs(rc.Glyphs)
+	return nil
+}
+
+func (r *rcResolver) options(filePath string) (checkOptions, error) {
+	dir := filepath.Dir(filePath)
+	if opts, ok := r.cache[dir]; ok {
+		return opts, nil
+	}
+
+	rc, err := config.LoadRC(dir)
+	if err != nil {
+		return checkOptions{}, err
+	}
+
+	opts := checkOptions{devices: deviceFilter, versions: versionFilter, failedOnly: failedOnly}
+	if rc.Devices != nil && !r.flags.Changed("device") {
+		if err := validateDeviceFilters(rc.Devices); err != nil {
+			return checkOptions{}, fmt.Errorf("%s: %w", config.RCFile, err)
 		}
+		opts.devices = rc.Devices
 	}
-	return nil
+	if rc.Versions != nil && !r.flags.Changed("version") {
+		opts.versions = rc.Versions
+	}
+	if rc.FailedOnly != nil && !r.flags.Changed("failed-only") {
+		opts.failedOnly = *rc.FailedOnly
+	}
+
+	r.cache[dir] = opts
+	return opts, nil
 }
 
-func matchesVersionPrefix(version, prefix string) bool {
-	versionParts := strings.Split(version, ".")
-	prefixParts := strings.Split(prefix, ".")
+// newChecker returns what files are checked against, and the name results
+// are reported under: the server (and any mirrors), with --local the
+// hashtables in the sync directory, --hashtable-dir or --bundle (and with
+// --mode tree the downloaded or bundled QML trees), or with --hybrid the
+// local hashtables first and then the server.
+func newChecker(cfg *config.Config, client *api.Client) (comparer, string, error) {
+	if !localCheck && !hybridCheck && localMode == local.ModeHashtable {
+		return newComparer(cfg, client), cfg.ServerHost, nil
+	}
+	if localCheck && hybridCheck {
+		return nil, "", fmt.Errorf("--local and --hybrid cannot be used together")
+	}
+	if localMode != local.ModeHashtable && localMode != local.ModeTree {
+		return nil, "", fmt.Errorf("invalid --mode %q (use %s or %s)", localMode, local.ModeHashtable, local.ModeTree)
+	}
+	if localMode == local.ModeTree && !localCheck {
+		return nil, "", fmt.Errorf("--mode %s requires --local", local.ModeTree)
+	}
 
-	if len(prefixParts) > len(versionParts) {
-		return false
+	dir, name := hashtableDir, hashtableDir
+	treeDir := ""
+	switch {
+	case checkBundle != "":
+		bundleDir, err := extractCheckBundle(checkBundle, localMode == local.ModeTree)
+		if err != nil {
+			return nil, "", err
+		}
+		defer os.RemoveAll(bundleDir)
+		dir, name = bundleDir, checkBundle
+		treeDir = filepath.Join(bundleDir, bundle.TreeDir)
+	case dir == "":
+		var err error
+		dir, err = store.DefaultDir()
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to resolve mirror directory: %w", err)
+		}
+		name = dir
+	}
+	engine, err := local.Load(dir)
+	if err != nil {
+		return nil, "", err
 	}
 
-	for i, prefixPart := range prefixParts {
-		prefixNum, err1 := strconv.Atoi(prefixPart)
-		versionNum, err2 := strconv.Atoi(versionParts[i])
+	if hybridCheck {
+		return &local.Hybrid{Local: engine, Remote: newComparer(cfg, client)}, cfg.ServerHost, nil
+	}
 
-		if err1 != nil || err2 != nil {
-			if versionParts[i] != prefixPart {
-				return false
+	if localMode == local.ModeTree {
+		if treeDir == "" {
+			if treeDir, err = tree.DefaultDir(); err != nil {
+				return nil, "", fmt.Errorf("failed to resolve tree directory: %w", err)
 			}
-			continue
 		}
-
-		if versionNum != prefixNum {
-			return false
+		if err := engine.LoadTrees(treeDir); err != nil {
+			return nil, "", err
 		}
 	}
-
-	return true
+	return engine, name, nil
 }
 
-func matchesFilter(result api.ComparisonResult, devices, versions []string) bool {
-	deviceMatch := len(devices) == 0
-	for _, d := range devices {
-		if result.Device == d {
-			deviceMatch = true
-			break
-		}
+func loadPolicyRules() ([]policy.Rule, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine working directory: %w", err)
 	}
 
-	versionMatch := len(versions) == 0
-	for _, v := range versions {
-		if matchesVersionPrefix(result.OSVersion, v) {
-			versionMatch = true
-			break
-		}
+	project, err := config.LoadProject(cwd)
+	if err != nil {
+		return nil, err
 	}
 
-	return deviceMatch && versionMatch
+	return policy.ParseRules(project.Policy)
 }
 
-func filterResponse(response *api.ComparisonResponse, devices, versions []string) *api.ComparisonResponse {
-	if len(devices) == 0 && len(versions) == 0 {
-		return response
+// evaluateOutcome reports whether a result should fail the run. With a
+// project policy, only policy violations fail; otherwise any incompatibility
+// in the filtered results does. With --fail-on, only those it selects fail.
+func evaluateOutcome(w io.Writer, original, filtered *api.ComparisonResponse, rules []policy.Rule, failOn []failSelector) (bool, error) {
+	if len(rules) == 0 {
+		return len(selectFailures(filtered, failOn).Incompatible) > 0, nil
 	}
 
-	filtered := &api.ComparisonResponse{
-		Compatible:   make([]api.ComparisonResult, 0),
-		Incompatible: make([]api.ComparisonResult, 0),
+	violations := policy.Evaluate(original, rules)
+	if err := display.RenderPolicyReport(w, violations, len(rules)); err != nil {
+		return false, err
 	}
 
-	for _, result := range response.Compatible {
-		if matchesFilter(result, devices, versions) {
-			filtered.Compatible = append(filtered.Compatible, result)
+	return len(selectViolations(violations, failOn)) > 0, nil
+}
+
+// trackNewVersions records the OS versions seen in this run and returns the
+// ones that were not present in the previous run against the same server.
+// Cache failures only produce a warning.
+func trackNewVersions(server string, responses ...*api.ComparisonResponse) map[string]bool {
+	var versions []string
+	for _, response := range responses {
+		for _, results := range [][]api.ComparisonResult{response.Compatible, response.Incompatible} {
+			for _, result := range results {
+				versions = append(versions, result.OSVersion)
+			}
 		}
 	}
 
-	for _, result := range response.Incompatible {
-		if matchesFilter(result, devices, versions) {
-			filtered.Incompatible = append(filtered.Incompatible, result)
-		}
+	seen, err := cache.LoadSeenVersions()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", err)
 	}
 
-	filtered.TotalChecked = len(filtered.Compatible) + len(filtered.Incompatible)
+	added := seen.NewSince(server, versions)
+	seen.Record(server, versions)
+	if err := seen.Save(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", err)
+	}
 
-	return filtered
+	return added
 }
 
-func runCheck(cmd *cobra.Command, args []string) error {
-	if err := validateDeviceFilters(deviceFilter); err != nil {
-		display.RenderError(err)
-		return err
+// pickQMDFiles narrows the collected files to the ones chosen in the picker,
+// which lists them by their relative paths.
+func pickQMDFiles(filePaths, relativePaths []string, pick func(io.Writer, []string) ([]int, error)) ([]string, []string, error) {
+	chosen, err := pick(os.Stderr, relativePaths)
+	if errors.Is(err, picker.ErrNotTerminal) {
+		return nil, nil, fmt.Errorf("--pick needs an interactive terminal")
 	}
-
-	filePaths, relativePaths, err := collectQMDFiles(args)
 	if err != nil {
-		display.RenderError(err)
-		return err
+		return nil, nil, err
 	}
 
-	if len(filePaths) == 0 {
-		err := fmt.Errorf("no .qmd files found")
-		display.RenderError(err)
-		return err
+	var paths, relative []string
+	for _, i := range chosen {
+		paths = append(paths, filePaths[i])
+		relative = append(relative, relativePaths[i])
 	}
+	return paths, relative, nil
+}
 
-	cfg := config.Load()
-	client := api.NewClient(cfg.ServerHost)
-
-	if len(filePaths) == 1 {
-		fmt.Printf("Uploading %s to %s...\n\n", filepath.Base(filePaths[0]), cfg.ServerHost)
+// parseRate parses a --limit-rate value such as 500K, 2M or 2MB/s into bytes
+// per second. An empty value means unlimited.
+func parseRate(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	rate, err := cache.ParseSize(strings.TrimSuffix(strings.TrimSpace(s), "/s"))
+	if err != nil || rate <= 0 {
+		return 0, fmt.Errorf("invalid --limit-rate %q (e.g. 500K, 2M)", s)
+	}
+	return rate, nil
+}
 
-		response, err := client.CompareQMD(filePaths[0])
-		if err != nil {
-			display.RenderError(fmt.Errorf("failed to check compatibility: %w", err))
-			return err
-		}
+// validateMatrixOrder checks --latest, --sort-versions and --device-order
+// after any rc files have been applied.
+func validateMatrixOrder() error {
+	if latestVersions < 0 {
+		return fmt.Errorf("--latest must not be negative")
+	}
+	if sortVersions != sortAsc && sortVersions != sortDesc {
+		return fmt.Errorf("invalid --sort-versions %q: must be %s or %s", sortVersions, sortAsc, sortDesc)
+	}
+	return validateDeviceFilters(deviceOrder)
+}
 
-		originalTotalChecked := response.TotalChecked
-		response = filterResponse(response, deviceFilter, versionFilter)
+func renderResults(w io.Writer, response *api.ComparisonResponse, newVersions map[string]bool) error {
+	if timeline {
+		return display.RenderTimeline(w, response)
+	}
+	return display.RenderComparisonResults(w, response, display.MatrixOptions{
+		Verbose:          verbose,
+		Totals:           showTotals,
+		Legend:           showLegend,
+		NewVersions:      newVersions,
+		Ascending:        sortVersions == sortAsc,
+		DeviceOrder:      deviceOrder,
+		IncompatibleOnly: incompatibleOnly,
+		Latest:           latestVersions,
+		Transpose:        transposeMatrix,
+	})
+}
 
-		if response.TotalChecked == 0 {
-			if originalTotalChecked == 0 {
-				fmt.Println("Warning: Server has no hashtables to compare against this QMD file")
-			} else {
-				fmt.Println("Warning: No devices matched your filter criteria")
+// intersectResponses combines per-file results into one response for the
+// package as a whole: a device/version pair is compatible only if every file
+// that was checked against it is compatible.
+func intersectResponses(responses []*api.ComparisonResponse) *api.ComparisonResponse {
+	type key struct{ device, version string }
+
+	combined := make(map[key]api.ComparisonResult)
+	var order []key
+	for _, response := range responses {
+		for _, results := range [][]api.ComparisonResult{response.Compatible, response.Incompatible} {
+			for _, result := range results {
+				k := key{result.Device, result.OSVersion}
+				existing, ok := combined[k]
+				if !ok {
+					order = append(order, k)
+					combined[k] = result
+					continue
+				}
+				if existing.Compatible && !result.Compatible {
+					combined[k] = result
+				}
 			}
-			return nil
 		}
+	}
+
+	merged := &api.ComparisonResponse{
+		Compatible:   make([]api.ComparisonResult, 0),
+		Incompatible: make([]api.ComparisonResult, 0),
+	}
+	for _, k := range order {
+		result := combined[k]
+		if result.Compatible {
+			merged.Compatible = append(merged.Compatible, result)
+		} else {
+			merged.Incompatible = append(merged.Incompatible, result)
+		}
+	}
+	merged.TotalChecked = len(merged.Compatible) + len(merged.Incompatible)
+
+	return merged
+}
 
-		display.RenderComparisonResults(response, verbose)
+func matchesFileFilter(filename string, filters []string) bool {
+	if len(filters) == 0 {
+		return true
+	}
 
-		if len(response.Incompatible) > 0 {
-			os.Exit(1)
+	for _, filter := range filters {
+		matched, err := filepath.Match(filter, filename)
+		if err == nil && matched {
+			return true
 		}
+		if strings.Contains(filename, filter) {
+			return true
+		}
+	}
 
-		return nil
+	return false
+}
+
+// compareFiles uploads the overlay set and returns the results for root
+// files only, keyed by relative path.
+func compareFiles(ctx context.Context, client comparer, filePaths, relativePaths []string) (map[string]*api.ComparisonResponse, error) {
+	if len(filePaths) == 0 {
+		return nil, fmt.Errorf("no .qmd files found")
 	}
 
-	fmt.Printf("Uploading %d files to %s...\n\n", len(filePaths), cfg.ServerHost)
+	if len(filePaths) == 1 {
+		response, err := client.CompareQMD(ctx, filePaths[0])
+		if err != nil {
+			return nil, fmt.Errorf("failed to check compatibility: %w", err)
+		}
+		return map[string]*api.ComparisonResponse{relativePaths[0]: response}, nil
+	}
 
-	batchResponse, err := client.CompareQMDFiles(filePaths, relativePaths)
+	batchResponse, err := client.CompareQMDFiles(ctx, filePaths, relativePaths)
 	if err != nil {
-		display.RenderError(fmt.Errorf("failed to check compatibility: %w", err))
-		return err
+		return nil, fmt.Errorf("failed to check compatibility: %w", err)
 	}
 
 	rootFiles := identifyRootFiles(batchResponse)
-
-	hasIncompatible := false
+	results := make(map[string]*api.ComparisonResponse)
 	for filename, response := range *batchResponse {
-		if !rootFiles[filename] {
-			continue
-		}
-
-		if !matchesFileFilter(filename, fileFilter) {
-			continue
+		if rootFiles[filename] {
+			results[filename] = &response
 		}
+	}
 
-		originalTotalChecked := response.TotalChecked
-		filtered := filterResponse(&response, deviceFilter, versionFilter)
-
-		if failedOnly && len(filtered.Incompatible) == 0 {
-			continue
-		}
+	return results, nil
+}
 
-		if filename != "" {
-			fmt.Printf("\n=== %s ===\n\n", filename)
+// hasProcessingError reports whether the server failed to process a file,
+// as opposed to finding it incompatible.
+func hasProcessingError(response *api.ComparisonResponse) bool {
+	for _, result := range response.Incompatible {
+		if strings.HasPrefix(result.ErrorDetail, "verification failed") {
+			return true
 		}
-
-		if filtered.TotalChecked == 0 {
-			if originalTotalChecked == 0 {
-				fmt.Println("Warning: Server has no hashtables to compare against this QMD file")
-			} else {
-				fmt.Println("Warning: No devices matched your filter criteria")
+		for _, dep := range result.DependencyResults {
+			if dep != nil && dep.Status == "error" {
+				return true
 			}
-			continue
 		}
+	}
+	return false
+}
 
-		display.RenderComparisonResults(filtered, verbose)
+// dependencyOnlyFailure reports whether an incompatible result is caused
+// only by dependencies: the root file's own entry validates and another file
+// does not.
+func dependencyOnlyFailure(result api.ComparisonResult, root string) bool {
+	rootResult, ok := result.DependencyResults[root]
+	if !ok {
+		rootResult, ok = result.DependencyResults[filepath.Base(root)]
+	}
+	if !ok || rootResult == nil || rootResult.Status != "compatible" || len(rootResult.HashErrors) > 0 {
+		return false
+	}
 
-		if len(filtered.Incompatible) > 0 {
-			hasIncompatible = true
+	for _, dep := range result.DependencyResults {
+		if dep != nil && dep != rootResult && (dep.Status != "compatible" || len(dep.HashErrors) > 0) {
+			return true
 		}
 	}
+	return false
+}
 
-	if hasIncompatible {
-		os.Exit(1)
+// downgradeDependencyFailures applies --deps-as-warnings to a filtered
+// result. It reports whether any incompatibility remains that is not caused
+// only by dependencies, and how many were downgraded.
+func downgradeDependencyFailures(response *api.ComparisonResponse, root string) (failed bool, warnings int) {
+	for _, result := range response.Incompatible {
+		if dependencyOnlyFailure(result, root) {
+			warnings++
+		} else {
+			failed = true
+		}
 	}
-
-	return nil
+	return failed, warnings
 }
 
-func matchesFileFilter(filename string, filters []string) bool {
-	if len(filters) == 0 {
-		return true
+func renderDependencyWarnings(w io.Writer, warnings int) {
+	if warnings > 0 {
+		fmt.Fprintln(w, i18n.T(i18n.MsgDependencyWarnings, warnings))
 	}
+}
 
-	for _, filter := range filters {
-		matched, err := filepath.Match(filter, filename)
-		if err == nil && matched {
-			return true
+// retryFailedFiles resubmits the files whose results carried processing
+// errors, together with the batch's dependencies, and merges the new results
+// for those files into batch.
+func retryFailedFiles(ctx context.Context, checker comparer, batch *api.BatchComparisonResponse, filePaths, relativePaths []string) error {
+	rootFiles := identifyRootFiles(batch)
+	failed := make(map[string]bool)
+	var paths, rels []string
+	for i, rel := range relativePaths {
+		response, ok := (*batch)[rel]
+		switch {
+		case ok && hasProcessingError(&response):
+			failed[rel] = true
+		case rootFiles[rel]:
+			continue
 		}
-		if strings.Contains(filename, filter) {
-			return true
+		paths = append(paths, filePaths[i])
+		rels = append(rels, rel)
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+
+	fmt.Fprintf(os.Stderr, "%s\n\n", i18n.T(i18n.MsgRetryingFiles, len(failed)))
+	retried, err := checker.CompareQMDFiles(ctx, paths, rels)
+	if err != nil {
+		return err
+	}
+	for rel, response := range *retried {
+		if failed[rel] {
+			(*batch)[rel] = response
 		}
 	}
+	return nil
+}
 
-	return false
+// dependencyFiles returns the files in the batch that other files load.
+func dependencyFiles(batchResponse *api.BatchComparisonResponse, rootFiles map[string]bool) map[string]bool {
+	deps := make(map[string]bool)
+	for filename := range *batchResponse {
+		if !rootFiles[filename] {
+			deps[filename] = true
+		}
+	}
+	return deps
 }
 
 func identifyRootFiles(batchResponse *api.BatchComparisonResponse) map[string]bool {
@@ -290,50 +1534,158 @@ func collectQMDFiles(args []string) ([]string, []string, error) {
 	var filePaths []string
 	var relativePaths []string
 
+	err := walkQMDFiles(args, func(path, relPath string) error {
+		filePaths = append(filePaths, path)
+		relativePaths = append(relativePaths, relPath)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return filePaths, relativePaths, nil
+}
+
+// walkQMDFiles calls visit with each QMD file named by args or found in the
+// directories among them, and its path relative to the base directory, as
+// the file is found.
+func walkQMDFiles(args []string, visit func(path, relPath string) error) error {
 	baseDir := determineBaseDir(args)
 
 	for _, arg := range args {
 		info, err := os.Stat(arg)
 		if err != nil {
-			return nil, nil, fmt.Errorf("failed to access %s: %w", arg, err)
+			return fmt.Errorf("failed to access %s: %w", arg, err)
 		}
 
 		if info.IsDir() {
+			var ignored ignore.Matcher
+			var visitErr error
 			err := filepath.Walk(arg, func(path string, info os.FileInfo, err error) error {
 				if err != nil {
 					return err
 				}
-				if !info.IsDir() && strings.HasSuffix(strings.ToLower(path), ".qmd") {
+				rel, _ := filepath.Rel(arg, path)
+				if path != arg && ignored.Match(rel, info.IsDir()) {
+					if info.IsDir() {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				if info.IsDir() {
+					if path != arg && exceedsMaxDepth(arg, path) {
+						return filepath.SkipDir
+					}
+					return ignored.Load(path, rel)
+				}
+				if strings.HasSuffix(strings.ToLower(path), ".qmd") {
 					if info.Size() == 0 {
-						fmt.Printf("Warning: Skipping empty file %s\n", path)
+						fmt.Fprintln(os.Stderr, i18n.T(i18n.MsgWarnSkippingEmpty, path))
 						return nil
 					}
-					filePaths = append(filePaths, path)
 					relPath, _ := filepath.Rel(baseDir, path)
-					relativePaths = append(relativePaths, relPath)
+					if visitErr = visit(path, relPath); visitErr != nil {
+						return filepath.SkipAll
+					}
 				}
 				return nil
 			})
 			if err != nil {
-				return nil, nil, fmt.Errorf("failed to walk directory %s: %w", arg, err)
+				return fmt.Errorf("failed to walk directory %s: %w", arg, err)
+			}
+			if visitErr != nil {
+				return visitErr
 			}
 		} else {
 			if err := validateQMDFile(arg); err != nil {
-				return nil, nil, err
+				return err
 			}
 			absPath, err := filepath.Abs(arg)
 			if err != nil {
 				absPath = arg
 			}
-			filePaths = append(filePaths, absPath)
 			relPath, err := filepath.Rel(baseDir, absPath)
 			if err != nil {
 				relPath = filepath.Base(arg)
 			}
-			relativePaths = append(relativePaths, relPath)
+			if err := visit(absPath, relPath); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// exceedsMaxDepth reports whether a subdirectory of root is deeper than
+// --max-depth allows. Files directly in root are at depth 0.
+func exceedsMaxDepth(root, dir string) bool {
+	limit := maxDepth
+	if noRecursive {
+		limit = 0
+	}
+	if limit < 0 {
+		return false
+	}
+
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		return false
+	}
+	return strings.Count(rel, string(filepath.Separator))+1 > limit
+}
+
+// checkMissingDependencies warns about LOADed files that are not part of the
+// upload, since the server can then only validate part of the tree. With
+// --require-deps it fails instead.
+func checkMissingDependencies(w io.Writer, filePaths, relativePaths []string) error {
+	missing := local.MissingDependencies(filePaths, relativePaths)
+	if len(missing) == 0 {
+		return nil
+	}
+
+	if requireDeps {
+		var names []string
+		for _, m := range missing {
+			names = append(names, fmt.Sprintf("%s (loaded by %s)", m.Target, m.File))
+		}
+		return fmt.Errorf("missing dependencies: %s; add them with --with-dep or check their directory", strings.Join(names, ", "))
+	}
+	for _, m := range missing {
+		fmt.Fprintf(w, "Warning: %s loads %s, which is not part of the upload; results will only cover part of the tree\n", m.File, m.Target)
+	}
+	return nil
+}
+
+// attachDependencies adds the --with-dep files to the upload, named relative
+// to baseDir like the collected files. Files already collected are skipped.
+func attachDependencies(filePaths, relativePaths, deps []string, baseDir string) ([]string, []string, error) {
+	collected := make(map[string]bool, len(filePaths))
+	for _, path := range filePaths {
+		if abs, err := filepath.Abs(path); err == nil {
+			collected[abs] = true
 		}
 	}
 
+	for _, dep := range deps {
+		if err := validateQMDFile(dep); err != nil {
+			return nil, nil, fmt.Errorf("--with-dep: %w", err)
+		}
+		absPath, err := filepath.Abs(dep)
+		if err != nil {
+			absPath = dep
+		}
+		if collected[absPath] {
+			continue
+		}
+		collected[absPath] = true
+
+		relPath, err := filepath.Rel(baseDir, absPath)
+		if err != nil {
+			relPath = filepath.Base(dep)
+		}
+		filePaths = append(filePaths, absPath)
+		relativePaths = append(relativePaths, relPath)
+	}
 	return filePaths, relativePaths, nil
 }
 
@@ -368,6 +1720,40 @@ func determineBaseDir(args []string) string {
 	return cwd
 }
 
+// readFileList reads newline-separated paths from a file, or stdin for "-".
+// Lists usually come from other tools (e.g. git diff --name-only), so
+// entries that aren't .qmd files are skipped, as are files that no longer
+// exist, with a warning.
+func readFileList(source string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if source != "-" {
+		f, err := os.Open(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open file list: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var paths []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		path := strings.TrimSpace(scanner.Text())
+		if path == "" || !strings.HasSuffix(strings.ToLower(path), ".qmd") {
+			continue
+		}
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "Warning: skipping %s from file list: file does not exist\n", path)
+			continue
+		}
+		paths = append(paths, path)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read file list: %w", err)
+	}
+	return paths, nil
+}
+
 func validateQMDFile(filePath string) error {
 	if !strings.HasSuffix(strings.ToLower(filePath), ".qmd") {
 		return fmt.Errorf("file must have .qmd extension")
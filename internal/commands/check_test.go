@@ -1,11 +1,28 @@
 package commands
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
 
-	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/api"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/bundle"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/cache"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/config"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/formatter"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/picker"
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+	"github.com/rmitchellscott/rm-qmd-verify/pkg/hashtab"
+	"github.com/spf13/cobra"
 )
 
 func TestValidateDeviceFilters(t *testing.T) {
@@ -637,3 +654,963 @@ func TestValidateQMDFile(t *testing.T) {
 		})
 	}
 }
+
+func TestResolveFormatter(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	if f, err := resolveFormatter("text"); err != nil || f != nil {
+		t.Errorf("resolveFormatter(\"text\") = %v, %v, want nil, nil", f, err)
+	}
+	if f, err := resolveFormatter("toltec"); err != nil || f == nil {
+		t.Errorf("resolveFormatter(\"toltec\") = %v, %v", f, err)
+	}
+	if f, err := resolveFormatter("json"); err != nil || f == nil {
+		t.Errorf("resolveFormatter(\"json\") = %v, %v", f, err)
+	}
+	if _, err := resolveFormatter("yaml"); err == nil {
+		t.Error("resolveFormatter(\"yaml\") expected error")
+	}
+}
+
+func TestIntersectResponses(t *testing.T) {
+	a := &api.ComparisonResponse{
+		Compatible: []api.ComparisonResult{
+			{OSVersion: "3.22.0.64", Device: "rmpp", Compatible: true},
+			{OSVersion: "3.22.0.64", Device: "rm2", Compatible: true},
+		},
+	}
+	b := &api.ComparisonResponse{
+		Compatible: []api.ComparisonResult{
+			{OSVersion: "3.22.0.64", Device: "rmpp", Compatible: true},
+		},
+		Incompatible: []api.ComparisonResult{
+			{OSVersion: "3.22.0.64", Device: "rm2", Compatible: false},
+			{OSVersion: "3.20.0.92", Device: "rm2", Compatible: false},
+		},
+	}
+
+	merged := intersectResponses([]*api.ComparisonResponse{a, b})
+
+	if len(merged.Compatible) != 1 || merged.Compatible[0].Device != "rmpp" {
+		t.Errorf("Compatible = %+v, want only rmpp 3.22.0.64", merged.Compatible)
+	}
+	if len(merged.Incompatible) != 2 {
+		t.Errorf("Incompatible = %+v, want 2 entries", merged.Incompatible)
+	}
+	if merged.TotalChecked != 3 {
+		t.Errorf("TotalChecked = %d, want 3", merged.TotalChecked)
+	}
+}
+
+func TestResolvePorcelain(t *testing.T) {
+	defer func() { porcelain, queryExpr = false, "" }()
+
+	porcelain = true
+	for _, format := range []string{"text", "porcelain"} {
+		if f, err := resolveFormatter(format); err != nil || f == nil {
+			t.Errorf("resolveFormatter(%q) = %v, %v", format, f, err)
+		}
+	}
+	if _, err := resolveFormatter("toltec"); err == nil {
+		t.Error("resolveFormatter() expected error combining --porcelain and --output toltec")
+	}
+
+	queryExpr = ".compatible"
+	if _, err := resolveFormatter("text"); err == nil {
+		t.Error("resolveFormatter() expected error combining --porcelain and --query")
+	}
+}
+
+func TestResolveQuery(t *testing.T) {
+	defer func() { queryExpr, formatTmpl = "", "" }()
+
+	queryExpr = ".incompatible[].os_version"
+	if f, err := resolveFormatter("text"); err != nil || f == nil {
+		t.Errorf("resolveFormatter() = %v, %v", f, err)
+	}
+	if _, err := resolveFormatter("toltec"); err == nil {
+		t.Error("resolveFormatter() expected error combining --query and --output")
+	}
+
+	formatTmpl = "{{.File}}"
+	if _, err := resolveFormatter("text"); err == nil {
+		t.Error("resolveFormatter() expected error combining --query and a template")
+	}
+
+	queryExpr, formatTmpl = "incompatible", ""
+	if _, err := resolveFormatter("text"); err == nil {
+		t.Error("resolveFormatter() expected error for invalid query")
+	}
+}
+
+func TestResolveTemplate(t *testing.T) {
+	defer func() { formatTmpl, templateFile = "", "" }()
+
+	path := filepath.Join(t.TempDir(), "report.tmpl")
+	if err := os.WriteFile(path, []byte("{{.File}}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		tmpl    string
+		file    string
+		output  string
+		wantErr bool
+	}{
+		{name: "inline", tmpl: "{{.File}}", output: "text"},
+		{name: "file", file: path, output: "text"},
+		{name: "both", tmpl: "{{.File}}", file: path, output: "text", wantErr: true},
+		{name: "with output", tmpl: "{{.File}}", output: "toltec", wantErr: true},
+		{name: "missing file", file: path + ".missing", output: "text", wantErr: true},
+		{name: "parse error", tmpl: "{{.File", output: "text", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formatTmpl, templateFile = tt.tmpl, tt.file
+			f, err := resolveFormatter(tt.output)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveFormatter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && f == nil {
+				t.Error("resolveFormatter() returned no formatter for a template")
+			}
+		})
+	}
+}
+
+func TestCollectQMDFilesMaxDepth(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"top.qmd", "a/one.qmd", "a/b/two.qmd", "a/b/c/three.qmd"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("content"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		maxDepth    int
+		noRecursive bool
+		want        int
+	}{
+		{name: "unlimited", maxDepth: -1, want: 4},
+		{name: "top level", maxDepth: 0, want: 1},
+		{name: "one level", maxDepth: 1, want: 2},
+		{name: "deeper than tree", maxDepth: 10, want: 4},
+		{name: "no recursive", maxDepth: -1, noRecursive: true, want: 1},
+		{name: "no recursive wins", maxDepth: 2, noRecursive: true, want: 1},
+	}
+
+	defer func() { maxDepth, noRecursive = -1, false }()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			maxDepth, noRecursive = tt.maxDepth, tt.noRecursive
+			filePaths, _, err := collectQMDFiles([]string{dir})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(filePaths) != tt.want {
+				t.Errorf("collected %v, want %d files", filePaths, tt.want)
+			}
+		})
+	}
+}
+
+func TestCollectQMDFilesIgnore(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.qmd":                  "content",
+		"scratch.tmp.qmd":           "content",
+		"experimental/try.qmd":      "content",
+		"lib/shared.qmd":            "content",
+		"lib/old.qmd":               "content",
+		".qmdignore":                "*.tmp.qmd\nexperimental/\n",
+		"lib/.qmdignore":            "old.qmd\n",
+		"other/old.qmd":             "content",
+		"other/nested/keep.tmp.qmd": "content",
+		"other/nested/.qmdignore":   "!keep.tmp.qmd\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, relativePaths, err := collectQMDFiles([]string{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]bool)
+	for _, rel := range relativePaths {
+		got[filepath.ToSlash(rel)] = true
+	}
+	want := map[string]bool{"main.qmd": true, "lib/shared.qmd": true, "other/old.qmd": true, "other/nested/keep.tmp.qmd": true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("collected %v, want %v", got, want)
+	}
+}
+
+func TestRCResolver(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "rmpp")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, ".qmdverifyrc"), []byte("version = 3.22\noutput = toltec, sarif=report.sarif\nsort-versions = asc\ndevice-order = rmpp\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sub, ".qmdverifyrc"), []byte("device = rmpp\nfailed-only = true\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	newCmd := func(args ...string) *cobra.Command {
+		cmd := &cobra.Command{}
+		cmd.Flags().StringSliceVarP(&deviceFilter, "device", "d", nil, "")
+		cmd.Flags().StringSliceVar(&versionFilter, "version", nil, "")
+		cmd.Flags().BoolVar(&failedOnly, "failed-only", false, "")
+		cmd.Flags().StringArrayVar(&outputFlags, "output", []string{outputText}, "")
+		cmd.Flags().StringVar(&sortVersions, "sort-versions", sortDesc, "")
+		cmd.Flags().StringSliceVar(&deviceOrder, "device-order", nil, "")
+		if err := cmd.Flags().Parse(args); err != nil {
+			t.Fatal(err)
+		}
+		return cmd
+	}
+	defer func() {
+		deviceFilter, versionFilter, failedOnly, outputFlags = nil, nil, false, []string{outputText}
+		sortVersions, deviceOrder = sortDesc, nil
+	}()
+
+	t.Run("rc files apply per directory", func(t *testing.T) {
+		r := newRCResolver(newCmd())
+		if err := r.applyRunFlags(root); err != nil {
+			t.Fatal(err)
+		}
+		if want := []string{outputToltec, "sarif=report.sarif"}; !reflect.DeepEqual(outputFlags, want) {
+			t.Errorf("outputFlags = %q, want %q", outputFlags, want)
+		}
+		if sortVersions != sortAsc || !reflect.DeepEqual(deviceOrder, []string{"rmpp"}) {
+			t.Errorf("ordering = %q, %v, want asc, [rmpp]", sortVersions, deviceOrder)
+		}
+
+		opts, err := r.options(filepath.Join(root, "a.qmd"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(opts, checkOptions{versions: []string{"3.22"}}) {
+			t.Errorf("root options = %+v", opts)
+		}
+
+		opts, err = r.options(filepath.Join(sub, "b.qmd"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := checkOptions{devices: []string{"rmpp"}, versions: []string{"3.22"}, failedOnly: true}
+		if !reflect.DeepEqual(opts, want) {
+			t.Errorf("sub options = %+v, want %+v", opts, want)
+		}
+	})
+
+	t.Run("flags win", func(t *testing.T) {
+		r := newRCResolver(newCmd("--version", "3.20", "--failed-only=false", "--output", "text", "--sort-versions", "desc"))
+		if err := r.applyRunFlags(root); err != nil {
+			t.Fatal(err)
+		}
+		if want := []string{outputText}; !reflect.DeepEqual(outputFlags, want) {
+			t.Errorf("outputFlags = %q, want %q", outputFlags, want)
+		}
+		if sortVersions != sortDesc {
+			t.Errorf("sortVersions = %q, want %q", sortVersions, sortDesc)
+		}
+
+		opts, err := r.options(filepath.Join(sub, "b.qmd"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := checkOptions{devices: []string{"rmpp"}, versions: []string{"3.20"}}
+		if !reflect.DeepEqual(opts, want) {
+			t.Errorf("options = %+v, want %+v", opts, want)
+		}
+	})
+}
+
+func TestReadFileList(t *testing.T) {
+	dir := t.TempDir()
+	kept := filepath.Join(dir, "kept.qmd")
+	if err := os.WriteFile(kept, []byte("content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	list := filepath.Join(dir, "files.txt")
+	content := kept + "\n\n  " + kept + "  \nREADME.md\n" + filepath.Join(dir, "deleted.qmd") + "\n"
+	if err := os.WriteFile(list, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := readFileList(list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []string{kept, kept}) {
+		t.Errorf("readFileList() = %v", got)
+	}
+
+	if _, err := readFileList(filepath.Join(dir, "missing.txt")); err == nil {
+		t.Error("expected an error for a missing list")
+	}
+}
+
+func TestParseOutputs(t *testing.T) {
+	tests := []struct {
+		name       string
+		values     []string
+		wantStdout string
+		wantFiles  []outputFile
+		wantErr    bool
+	}{
+		{name: "default", values: nil, wantStdout: outputText},
+		{name: "table alias", values: []string{"table"}, wantStdout: outputText},
+		{
+			name:       "stdout and files",
+			values:     []string{"table", "junit=report.xml", "json=results.json"},
+			wantStdout: outputText,
+			wantFiles:  []outputFile{{format: "junit", path: "report.xml"}, {format: "json", path: "results.json"}},
+		},
+		{
+			name:       "files only",
+			values:     []string{"porcelain=out.tsv"},
+			wantStdout: outputText,
+			wantFiles:  []outputFile{{format: outputPorcelain, path: "out.tsv"}},
+		},
+		{name: "repeated stdout format", values: []string{"toltec", "toltec"}, wantStdout: outputToltec},
+		{name: "two stdout formats", values: []string{"text", "toltec"}, wantErr: true},
+		{name: "missing path", values: []string{"junit="}, wantErr: true},
+		{name: "missing format", values: []string{"=report.xml"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, files, err := parseOutputs(tt.values)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseOutputs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if stdout != tt.wantStdout {
+				t.Errorf("parseOutputs() stdout = %q, want %q", stdout, tt.wantStdout)
+			}
+			if !reflect.DeepEqual(files, tt.wantFiles) {
+				t.Errorf("parseOutputs() files = %+v, want %+v", files, tt.wantFiles)
+			}
+		})
+	}
+}
+
+func TestWriteOutputFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := []outputFile{
+		{format: outputPorcelain, path: filepath.Join(dir, "out.tsv")},
+		{format: outputText, path: filepath.Join(dir, "out.txt")},
+	}
+	if err := resolveOutputFiles(files); err != nil {
+		t.Fatalf("resolveOutputFiles() error = %v", err)
+	}
+
+	results := map[string]*api.ComparisonResponse{
+		"a.qmd": {
+			Compatible:   []api.ComparisonResult{{Device: "rmpp", OSVersion: "3.22.0.64", Compatible: true}},
+			TotalChecked: 1,
+		},
+	}
+	if err := writeOutputFiles(files, newReport("http://example.com", results, nil)); err != nil {
+		t.Fatalf("writeOutputFiles() error = %v", err)
+	}
+
+	for _, file := range files {
+		data, err := os.ReadFile(file.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data) == 0 {
+			t.Errorf("%s output is empty", file.format)
+		}
+	}
+
+	if err := resolveOutputFiles([]outputFile{{format: "no-such-format", path: "x"}}); err == nil {
+		t.Error("resolveOutputFiles() expected error for an unknown format")
+	}
+}
+
+func TestDescribeInputs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(api.VersionResponse{Version: "v2.0.0"})
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.qmd")
+	if err := os.WriteFile(path, []byte("REPLACE foo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sum, err := bundle.FileSHA256(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := map[string]*api.ComparisonResponse{"a.qmd": {}, "missing.qmd": {}}
+	client := api.NewClient(server.URL)
+
+	report := newReport(server.URL, results, nil)
+	describeInputs(context.Background(), report, client, []outputFile{{format: outputPorcelain, path: "out.tsv"}}, []string{path}, []string{"a.qmd"})
+	if !reflect.DeepEqual(report.Digests, map[string]string{"a.qmd": sum}) {
+		t.Errorf("Digests = %v, want only a.qmd", report.Digests)
+	}
+	if report.ServerVersion != "" {
+		t.Errorf("ServerVersion = %q without a cyclonedx output", report.ServerVersion)
+	}
+
+	report = newReport(server.URL, results, nil)
+	describeInputs(context.Background(), report, client, []outputFile{{format: outputCycloneDX, path: "bom.json"}}, []string{path}, []string{"a.qmd"})
+	if report.ServerVersion != "v2.0.0" {
+		t.Errorf("ServerVersion = %q, want v2.0.0", report.ServerVersion)
+	}
+	if report.Locations != nil {
+		t.Errorf("Locations = %v without a sarif output", report.Locations)
+	}
+
+	t.Chdir(filepath.Dir(dir))
+	report = newReport(server.URL, results, nil)
+	describeInputs(context.Background(), report, client, []outputFile{{format: outputSARIF, path: "results.sarif"}}, []string{path}, []string{"a.qmd"})
+	if want := filepath.Base(dir) + "/a.qmd"; report.Locations["a.qmd"] != want {
+		t.Errorf("Locations = %v, want a.qmd at %s", report.Locations, want)
+	}
+}
+
+func TestValidateMatrixOrder(t *testing.T) {
+	defer func() { sortVersions, deviceOrder, latestVersions = sortDesc, nil, 0 }()
+
+	tests := []struct {
+		sort    string
+		order   []string
+		latest  int
+		wantErr bool
+	}{
+		{sort: sortDesc},
+		{sort: sortAsc, order: []string{"rmpp", "rm2"}, latest: 3},
+		{sort: "newest", wantErr: true},
+		{sort: sortDesc, order: []string{"rm3"}, wantErr: true},
+		{sort: sortDesc, latest: -1, wantErr: true},
+	}
+	for _, tt := range tests {
+		sortVersions, deviceOrder, latestVersions = tt.sort, tt.order, tt.latest
+		if err := validateMatrixOrder(); (err != nil) != tt.wantErr {
+			t.Errorf("validateMatrixOrder(%q, %v, %d) error = %v, wantErr %v", tt.sort, tt.order, tt.latest, err, tt.wantErr)
+		}
+	}
+}
+
+func TestPickQMDFiles(t *testing.T) {
+	filePaths := []string{"/src/a.qmd", "/src/ui/b.qmd", "/src/ui/c.qmd"}
+	relativePaths := []string{"a.qmd", "ui/b.qmd", "ui/c.qmd"}
+
+	var listed []string
+	pick := func(w io.Writer, items []string) ([]int, error) {
+		listed = items
+		return []int{0, 2}, nil
+	}
+	paths, relative, err := pickQMDFiles(filePaths, relativePaths, pick)
+	if err != nil {
+		t.Fatalf("pickQMDFiles() error = %v", err)
+	}
+	if !reflect.DeepEqual(listed, relativePaths) {
+		t.Errorf("picker listed %v, want the relative paths", listed)
+	}
+	if !reflect.DeepEqual(paths, []string{"/src/a.qmd", "/src/ui/c.qmd"}) || !reflect.DeepEqual(relative, []string{"a.qmd", "ui/c.qmd"}) {
+		t.Errorf("pickQMDFiles() = %v, %v", paths, relative)
+	}
+
+	notTerminal := func(io.Writer, []string) ([]int, error) { return nil, picker.ErrNotTerminal }
+	if _, _, err := pickQMDFiles(filePaths, relativePaths, notTerminal); err == nil || !strings.Contains(err.Error(), "--pick") {
+		t.Errorf("pickQMDFiles() without a terminal error = %v", err)
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    int64
+		wantErr bool
+	}{
+		{input: "", want: 0},
+		{input: "2M", want: 2 << 20},
+		{input: "500K", want: 500 << 10},
+		{input: "1.5MB/s", want: 3 << 19},
+		{input: "4096", want: 4096},
+		{input: "0", wantErr: true},
+		{input: "fast", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseRate(tt.input)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseRate(%q) = %d, %v, want %d, wantErr %v", tt.input, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestRunTimer(t *testing.T) {
+	timer := newRunTimer()
+	timer.record(api.Timing{UploadMS: 100, QueueMS: 20, ProcessingMS: 300})
+	timer.record(api.Timing{UploadMS: 50})
+
+	got := timer.finish()
+	if got.UploadMS != 150 || got.QueueMS != 20 || got.ProcessingMS != 300 {
+		t.Errorf("finish() = %+v", got)
+	}
+	if got.TotalMS < 0 {
+		t.Errorf("finish() TotalMS = %d", got.TotalMS)
+	}
+}
+
+type fakeComparer struct {
+	mu       sync.Mutex
+	batch    api.BatchComparisonResponse
+	uploaded [][]string
+}
+
+func (f *fakeComparer) CompareQMD(_ context.Context, filePath string) (*api.ComparisonResponse, error) {
+	response := f.batch[filePath]
+	return &response, nil
+}
+
+func (f *fakeComparer) CompareQMDFiles(_ context.Context, filePaths []string, relativePaths []string) (*api.BatchComparisonResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.uploaded = append(f.uploaded, relativePaths)
+	batch := make(api.BatchComparisonResponse)
+	for _, rel := range relativePaths {
+		batch[rel] = f.batch[rel]
+	}
+	return &batch, nil
+}
+
+func TestRetryFailedFiles(t *testing.T) {
+	dep := map[string]*api.ValidationResult{"lib.qmd": {Status: "compatible"}}
+	failedResult := api.ComparisonResponse{
+		Incompatible: []api.ComparisonResult{{Device: "rmpp", OSVersion: "3.22.0.64", ErrorDetail: "verification failed: timeout", DependencyResults: dep}},
+		TotalChecked: 1,
+	}
+	okResult := api.ComparisonResponse{
+		Compatible:   []api.ComparisonResult{{Device: "rmpp", OSVersion: "3.22.0.64", Compatible: true, DependencyResults: dep}},
+		TotalChecked: 1,
+	}
+	incompatibleResult := api.ComparisonResponse{
+		Incompatible: []api.ComparisonResult{{Device: "rmpp", OSVersion: "3.22.0.64", ErrorDetail: "missing 1 hash(es)"}},
+		TotalChecked: 1,
+	}
+
+	if !hasProcessingError(&failedResult) || hasProcessingError(&incompatibleResult) || hasProcessingError(&okResult) {
+		t.Fatal("hasProcessingError() misclassified a result")
+	}
+
+	batch := api.BatchComparisonResponse{
+		"a.qmd":   failedResult,
+		"b.qmd":   incompatibleResult,
+		"lib.qmd": {TotalChecked: 1},
+	}
+	checker := &fakeComparer{batch: api.BatchComparisonResponse{"a.qmd": okResult, "lib.qmd": {TotalChecked: 1}}}
+
+	rels := []string{"a.qmd", "b.qmd", "lib.qmd"}
+	if err := retryFailedFiles(context.Background(), checker, &batch, rels, rels); err != nil {
+		t.Fatalf("retryFailedFiles(context.Background()) error = %v", err)
+	}
+
+	if want := [][]string{{"a.qmd", "lib.qmd"}}; !reflect.DeepEqual(checker.uploaded, want) {
+		t.Errorf("uploaded %v, want %v", checker.uploaded, want)
+	}
+	if len(batch["a.qmd"].Compatible) != 1 {
+		t.Errorf("a.qmd was not replaced by the retried result: %+v", batch["a.qmd"])
+	}
+	if len(batch["b.qmd"].Incompatible) != 1 {
+		t.Errorf("b.qmd changed: %+v", batch["b.qmd"])
+	}
+
+	checker.uploaded = nil
+	if err := retryFailedFiles(context.Background(), checker, &batch, rels, rels); err != nil || checker.uploaded != nil {
+		t.Errorf("retryFailedFiles(context.Background()) without errors uploaded %v, err %v", checker.uploaded, err)
+	}
+}
+
+func TestNewUploadProgress(t *testing.T) {
+	updates := []api.UploadProgress{
+		{File: "a.qmd", Index: 1, Files: 2, BytesSent: 512, BytesTotal: 4096},
+		{File: "a.qmd", Index: 1, Files: 2, BytesSent: 1024, BytesTotal: 4096},
+		{File: "b.qmd", Index: 2, Files: 2, BytesSent: 4096, BytesTotal: 4096},
+	}
+
+	var plain bytes.Buffer
+	report := newUploadProgress(&plain, false)
+	for _, p := range updates {
+		report(p)
+	}
+	want := "[1/2] a.qmd (512 B of 4.0 KiB sent)\n[2/2] b.qmd (4.0 KiB of 4.0 KiB sent)\n"
+	if plain.String() != want {
+		t.Errorf("plain progress = %q, want %q", plain.String(), want)
+	}
+
+	var tty bytes.Buffer
+	report = newUploadProgress(&tty, true)
+	for _, p := range updates {
+		report(p)
+	}
+	if strings.Count(tty.String(), "\r") != len(updates) || !strings.HasSuffix(tty.String(), "\n\n") {
+		t.Errorf("terminal progress = %q", tty.String())
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := map[int64]string{
+		0:               "0 B",
+		1023:            "1023 B",
+		1536:            "1.5 KiB",
+		5 * 1024 * 1024: "5.0 MiB",
+	}
+	for n, want := range tests {
+		if got := formatBytes(n); got != want {
+			t.Errorf("formatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestSharedDependencyRefs(t *testing.T) {
+	deps := []formatter.SharedDependency{
+		{File: "lib/a.qmd", UsedBy: []string{"x.qmd", "y.qmd"}},
+		{File: "lib/b.qmd", UsedBy: []string{"y.qmd", "z.qmd"}},
+	}
+	want := map[string][]string{
+		"x.qmd": {"lib/a.qmd"},
+		"y.qmd": {"lib/a.qmd", "lib/b.qmd"},
+		"z.qmd": {"lib/b.qmd"},
+	}
+	if got := sharedDependencyRefs(deps); !reflect.DeepEqual(got, want) {
+		t.Errorf("sharedDependencyRefs() = %v, want %v", got, want)
+	}
+}
+
+func TestDependencyFiles(t *testing.T) {
+	batch := api.BatchComparisonResponse{
+		"main.qmd": {Compatible: []api.ComparisonResult{{
+			DependencyResults: map[string]*api.ValidationResult{"main.qmd": {}, "lib/shared.qmd": {}},
+		}}},
+		"lib/shared.qmd": {Compatible: []api.ComparisonResult{{}}},
+		"other.qmd":      {Compatible: []api.ComparisonResult{{}}},
+	}
+
+	got := dependencyFiles(&batch, identifyRootFiles(&batch))
+	if want := map[string]bool{"lib/shared.qmd": true}; !reflect.DeepEqual(got, want) {
+		t.Errorf("dependencyFiles() = %v, want %v", got, want)
+	}
+}
+
+func TestAttachDependencies(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"main.qmd", "lib/shared.qmd"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("content"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	main := filepath.Join(dir, "main.qmd")
+	shared := filepath.Join(dir, "lib", "shared.qmd")
+
+	tests := []struct {
+		name    string
+		deps    []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "attached", deps: []string{shared}, want: []string{"main.qmd", filepath.Join("lib", "shared.qmd")}},
+		{name: "duplicate", deps: []string{shared, shared, main}, want: []string{"main.qmd", filepath.Join("lib", "shared.qmd")}},
+		{name: "missing", deps: []string{filepath.Join(dir, "missing.qmd")}, wantErr: true},
+		{name: "not qmd", deps: []string{filepath.Join(dir, "lib")}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePaths, relativePaths, err := attachDependencies([]string{main}, []string{"main.qmd"}, tt.deps, dir)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("attachDependencies() expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("attachDependencies() error = %v", err)
+			}
+			if !reflect.DeepEqual(relativePaths, tt.want) || len(filePaths) != len(tt.want) {
+				t.Errorf("attachDependencies() = %v, %v, want %v", filePaths, relativePaths, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckMissingDependencies(t *testing.T) {
+	dir := t.TempDir()
+	main := filepath.Join(dir, "main.qmd")
+	if err := os.WriteFile(main, []byte("LOAD lib/shared.qmd\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() { requireDeps = false }()
+
+	var buf bytes.Buffer
+	if err := checkMissingDependencies(&buf, []string{main}, []string{"main.qmd"}); err != nil {
+		t.Fatalf("checkMissingDependencies() error = %v", err)
+	}
+	if want := "Warning: main.qmd loads lib/shared.qmd"; !strings.Contains(buf.String(), want) {
+		t.Errorf("checkMissingDependencies() output = %q, want it to contain %q", buf.String(), want)
+	}
+
+	requireDeps = true
+	buf.Reset()
+	if err := checkMissingDependencies(&buf, []string{main}, []string{"main.qmd"}); err == nil || !strings.Contains(err.Error(), "lib/shared.qmd") {
+		t.Errorf("checkMissingDependencies() with --require-deps error = %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("checkMissingDependencies() with --require-deps wrote %q", buf.String())
+	}
+}
+
+func TestDowngradeDependencyFailures(t *testing.T) {
+	depFailure := api.ComparisonResult{Device: "rm2", OSVersion: "3.22.4.2", DependencyResults: map[string]*api.ValidationResult{
+		"main.qmd":       {Status: "compatible"},
+		"lib/shared.qmd": {Status: "incompatible", HashErrors: []api.HashError{{HashID: 1}}},
+	}}
+	rootFailure := api.ComparisonResult{Device: "rmpp", OSVersion: "3.22.4.2", DependencyResults: map[string]*api.ValidationResult{
+		"main.qmd":       {Status: "incompatible", HashErrors: []api.HashError{{HashID: 2}}},
+		"lib/shared.qmd": {Status: "compatible"},
+	}}
+	noDeps := api.ComparisonResult{Device: "rm1", OSVersion: "3.22.4.2", ErrorDetail: "Cannot resolve hash 123"}
+
+	tests := []struct {
+		name         string
+		root         string
+		incompatible []api.ComparisonResult
+		wantFailed   bool
+		wantWarnings int
+	}{
+		{name: "dependency only", root: "main.qmd", incompatible: []api.ComparisonResult{depFailure}, wantWarnings: 1},
+		{name: "root by base name", root: "overlays/main.qmd", incompatible: []api.ComparisonResult{depFailure}, wantWarnings: 1},
+		{name: "root fails", root: "main.qmd", incompatible: []api.ComparisonResult{rootFailure}, wantFailed: true},
+		{name: "no dependency results", root: "main.qmd", incompatible: []api.ComparisonResult{noDeps}, wantFailed: true},
+		{name: "mixed", root: "main.qmd", incompatible: []api.ComparisonResult{depFailure, noDeps}, wantFailed: true, wantWarnings: 1},
+		{name: "compatible", root: "main.qmd"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failed, warnings := downgradeDependencyFailures(&api.ComparisonResponse{Incompatible: tt.incompatible}, tt.root)
+			if failed != tt.wantFailed || warnings != tt.wantWarnings {
+				t.Errorf("downgradeDependencyFailures() = %v, %d, want %v, %d", failed, warnings, tt.wantFailed, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestNewCheckerHashtableDir(t *testing.T) {
+	defer func() { localCheck, hashtableDir = false, "" }()
+	t.Setenv(cache.EnvVarCacheDir, t.TempDir())
+
+	dir := t.TempDir()
+	if err := hashtab.WriteHashlist([]uint64{1}, filepath.Join(dir, "3.22.0.64-rmpp")); err != nil {
+		t.Fatal(err)
+	}
+	qmd := filepath.Join(t.TempDir(), "a.qmd")
+	if err := os.WriteFile(qmd, []byte("AFFECT [[1]]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	localCheck, hashtableDir = true, dir
+	cfg := &config.Config{ServerHost: "http://127.0.0.1:1"}
+	checker, server, err := newChecker(cfg, api.NewClient(cfg.ServerHost))
+	if err != nil {
+		t.Fatalf("newChecker() error = %v", err)
+	}
+	if server != dir {
+		t.Errorf("newChecker() server = %q, want %q", server, dir)
+	}
+	response, err := checker.CompareQMD(context.Background(), qmd)
+	if err != nil {
+		t.Fatalf("CompareQMD() error = %v", err)
+	}
+	if len(response.Compatible) != 1 || response.Compatible[0].OSVersion != "3.22.0.64" {
+		t.Errorf("CompareQMD() = %+v, want compatible with 3.22.0.64", response)
+	}
+
+	hashtableDir = t.TempDir()
+	if _, _, err := newChecker(cfg, api.NewClient(cfg.ServerHost)); err == nil || !strings.Contains(err.Error(), hashtableDir) {
+		t.Errorf("newChecker() with an empty --hashtable-dir error = %v", err)
+	}
+}
+
+func TestRunCheck(t *testing.T) {
+	defer func() { localCheck, hashtableDir, exitZero, checkCmd.SilenceErrors = false, "", false, false }()
+	t.Setenv(cache.EnvVarCacheDir, t.TempDir())
+	t.Setenv(config.EnvVarConfigDir, t.TempDir())
+	t.Chdir(t.TempDir())
+
+	dir := t.TempDir()
+	if err := hashtab.WriteHashlist([]uint64{1}, filepath.Join(dir, "3.22.0.64-rmpp")); err != nil {
+		t.Fatal(err)
+	}
+	compatible := filepath.Join(t.TempDir(), "ok.qmd")
+	incompatible := filepath.Join(t.TempDir(), "bad.qmd")
+	if err := os.WriteFile(compatible, []byte("AFFECT [[1]]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(incompatible, []byte("AFFECT [[2]]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	localCheck, hashtableDir = true, dir
+	checkCmd.SetContext(context.Background())
+
+	if err := runCheck(checkCmd, []string{compatible}); err != nil {
+		t.Fatalf("runCheck() error = %v for a compatible file", err)
+	}
+
+	err := runCheck(checkCmd, []string{incompatible})
+	var checkErr *CheckError
+	if !errors.As(err, &checkErr) || !errors.Is(err, ErrIncompatible) {
+		t.Fatalf("runCheck() error = %v, want ErrIncompatible", err)
+	}
+	if checkErr.Report == nil || len(checkErr.Report.Results["bad.qmd"].Incompatible) != 1 {
+		t.Errorf("Report = %+v, want the incompatible result", checkErr.Report)
+	}
+	if !checkCmd.SilenceErrors {
+		t.Error("runCheck() left cobra to print the reported outcome")
+	}
+
+	exitZero = true
+	if err := runCheck(checkCmd, []string{incompatible}); err != nil {
+		t.Errorf("runCheck() error = %v with --exit-zero", err)
+	}
+}
+
+func TestRunCheckJSONWithoutData(t *testing.T) {
+	defer func() {
+		localCheck, hashtableDir, versionFilter, outputFlags = false, "", nil, []string{outputText}
+		checkCmd.SilenceErrors = false
+	}()
+	t.Setenv(cache.EnvVarCacheDir, t.TempDir())
+	t.Setenv(config.EnvVarConfigDir, t.TempDir())
+	t.Chdir(t.TempDir())
+
+	dir := t.TempDir()
+	if err := hashtab.WriteHashlist([]uint64{1}, filepath.Join(dir, "3.22.0.64-rmpp")); err != nil {
+		t.Fatal(err)
+	}
+	files := t.TempDir()
+	var paths []string
+	for _, name := range []string{"a.qmd", "b.qmd"} {
+		path := filepath.Join(files, name)
+		if err := os.WriteFile(path, []byte("AFFECT [[1]]\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	localCheck, hashtableDir = true, dir
+	versionFilter, outputFlags = []string{"9.9"}, []string{outputJSON}
+	checkCmd.SetContext(context.Background())
+
+	for _, args := range [][]string{paths[:1], {files}} {
+		out := captureStdout(t, func() {
+			if err := runCheck(checkCmd, args); !errors.Is(err, ErrNoData) {
+				t.Errorf("runCheck(%v) error = %v, want ErrNoData", args, err)
+			}
+		})
+		var got formatter.Report
+		if err := json.Unmarshal(out, &got); err != nil {
+			t.Fatalf("runCheck(%v) wrote invalid JSON: %v\n%s", args, err, out)
+		}
+		if got.Results == nil || len(got.Results) != 0 {
+			t.Errorf("runCheck(%v) results = %v, want an empty map", args, got.Results)
+		}
+	}
+}
+
+// captureStdout returns what fn writes to os.Stdout.
+func captureStdout(t *testing.T, fn func()) []byte {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	done := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- data
+	}()
+	defer func() {
+		os.Stdout = stdout
+	}()
+
+	fn()
+	w.Close()
+	return <-done
+}
+
+func TestJSONOutputSharedDependency(t *testing.T) {
+	failing := func(root string) *api.ComparisonResponse {
+		return &api.ComparisonResponse{
+			Incompatible: []api.ComparisonResult{{Device: "rm2", OSVersion: "3.22.0.64", DependencyResults: map[string]*api.ValidationResult{
+				root:      {Status: "compatible"},
+				"lib.qmd": {Status: "incompatible", HashErrors: []api.HashError{{HashID: 42, Error: "hash not found"}}},
+			}}},
+			TotalChecked: 1,
+		}
+	}
+	report := newReport("server", map[string]*api.ComparisonResponse{"a.qmd": failing("a.qmd"), "b.qmd": failing("b.qmd")}, nil)
+	report.Timing = &api.Timing{TotalMS: 10}
+
+	f, err := formatter.Lookup(outputJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := f.Format(&buf, report); err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+
+	var got formatter.Report
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Format() wrote invalid JSON: %v\n%s", err, buf.String())
+	}
+	if len(got.SharedDependencies) != 1 || got.SharedDependencies[0].File != "lib.qmd" ||
+		len(got.SharedDependencies[0].Results) != 1 || len(got.SharedDependencies[0].Results[0].HashErrors) != 1 ||
+		got.SharedDependencies[0].Results[0].HashErrors[0].HashID != 42 {
+		t.Errorf("shared_dependencies = %+v, want lib.qmd with hash 42", got.SharedDependencies)
+	}
+	if got.Timing == nil || got.Timing.TotalMS != 10 {
+		t.Errorf("timing = %+v, want the run's timing", got.Timing)
+	}
+	if dep := got.Results["a.qmd"].Incompatible[0].DependencyResults["lib.qmd"]; dep == nil || !dep.Shared {
+		t.Errorf("a.qmd's lib.qmd result = %+v, want a shared reference", dep)
+	}
+	if windows := got.SupportWindows["a.qmd"]; len(windows) != 1 || windows[0].Device != "rm2" || windows[0].Oldest != "" {
+		t.Errorf("support_windows = %+v, want rm2 with no compatible version", got.SupportWindows)
+	}
+}
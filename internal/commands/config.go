@@ -0,0 +1,171 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/config"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/display"
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Read and write the config file",
+	Long: `Read and write the per-user config file, config.yaml in the config directory
+(QMDVERIFY_CONFIG_DIR, or qmdverify in the user config directory).
+
+Keys:
+  server   Server to check against
+  org      Organization sent to multi-tenant servers
+  mirrors  Comma-separated servers whose results are merged with the server's
+  profile  Credential profile used when --profile and QMDVERIFY_PROFILE are unset
+
+Flags and environment variables take precedence over the config file. The
+server and organization also take precedence over those stored with a
+profile at login.`,
+}
+
+var configGetCmd = &cobra.Command{
+	Use:          "get <key>",
+	Short:        "Print a config value",
+	Example:      `  qmdverify config get server`,
+	SilenceUsage: true,
+	Args:         cobra.ExactArgs(1),
+	RunE:         runConfigGet,
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Set a config value",
+	Example: `  qmdverify config set server https://qmd.example.com
+  qmdverify config set mirrors https://a.example.com,https://b.example.com`,
+	SilenceUsage: true,
+	Args:         cobra.ExactArgs(2),
+	RunE:         runConfigSet,
+}
+
+var configUnsetCmd = &cobra.Command{
+	Use:          "unset <key>",
+	Short:        "Remove a config value",
+	Example:      `  qmdverify config unset org`,
+	SilenceUsage: true,
+	Args:         cobra.ExactArgs(1),
+	RunE:         runConfigUnset,
+}
+
+var configListCmd = &cobra.Command{
+	Use:          "list",
+	Short:        "Print every config value that is set",
+	Example:      `  qmdverify config list`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE:         runConfigList,
+}
+
+var configUseProfileCmd = &cobra.Command{
+	Use:   "use-profile <name>",
+	Short: "Make a credential profile the default",
+	Long: `Make a credential profile the default for commands run without --profile or
+QMDVERIFY_PROFILE. This is the same as 'config set profile <name>'.`,
+	Example:      `  qmdverify config use-profile work`,
+	SilenceUsage: true,
+	Args:         cobra.ExactArgs(1),
+	RunE:         runConfigUseProfile,
+}
+
+func init() {
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configUnsetCmd)
+	configCmd.AddCommand(configListCmd)
+	configCmd.AddCommand(configUseProfileCmd)
+}
+
+func runConfigGet(cmd *cobra.Command, args []string) error {
+	settings, err := config.LoadSettings()
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	value, set, err := settings.Get(args[0])
+	if err == nil && !set {
+		err = fmt.Errorf("%s is not set", args[0])
+	}
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+	fmt.Println(value)
+	return nil
+}
+
+func runConfigSet(cmd *cobra.Command, args []string) error {
+	return updateSettings(func(settings *config.Settings) error {
+		return settings.Set(args[0], args[1])
+	})
+}
+
+func runConfigUnset(cmd *cobra.Command, args []string) error {
+	return updateSettings(func(settings *config.Settings) error {
+		return settings.Unset(args[0])
+	})
+}
+
+func runConfigList(cmd *cobra.Command, args []string) error {
+	settings, err := config.LoadSettings()
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+	return renderSettings(os.Stdout, settings)
+}
+
+func runConfigUseProfile(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	if store, err := config.LoadCredentialStore(); err == nil {
+		if _, ok := store.Get(name); !ok {
+			fmt.Fprintf(os.Stderr, "Warning: profile %s has no stored credentials; log in with 'qmdverify auth login --profile %s'\n", name, name)
+		}
+	}
+
+	if err := updateSettings(func(settings *config.Settings) error {
+		return settings.Set(config.KeyProfile, name)
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("Using profile %s\n", name)
+	return nil
+}
+
+// updateSettings applies change to the config file and saves it.
+func updateSettings(change func(*config.Settings) error) error {
+	settings, err := config.LoadSettings()
+	if err == nil {
+		err = change(settings)
+	}
+	if err == nil {
+		err = settings.Save()
+	}
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+	return nil
+}
+
+// renderSettings prints one key=value line per key that is set.
+func renderSettings(w io.Writer, settings *config.Settings) error {
+	for _, key := range config.SettingsKeys {
+		value, set, err := settings.Get(key)
+		if err != nil {
+			return err
+		}
+		if set {
+			fmt.Fprintf(w, "%s=%s\n", key, value)
+		}
+	}
+	return nil
+}
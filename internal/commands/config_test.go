@@ -0,0 +1,25 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/config"
+)
+
+func TestRenderSettings(t *testing.T) {
+	settings := &config.Settings{
+		Server:  "https://qmd.example.com",
+		Mirrors: []string{"https://a.example.com", "https://b.example.com"},
+		Profile: "work",
+	}
+
+	var buf bytes.Buffer
+	if err := renderSettings(&buf, settings); err != nil {
+		t.Fatalf("renderSettings() error = %v", err)
+	}
+	want := "server=https://qmd.example.com\nmirrors=https://a.example.com,https://b.example.com\nprofile=work\n"
+	if buf.String() != want {
+		t.Errorf("renderSettings() = %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,103 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/config"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/display"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/rundiff"
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+const outputMarkdown = "markdown"
+
+var diffOutput string
+
+var diffCmd = &cobra.Command{
+	Use:     "diff <run-a> <run-b>",
+	Aliases: []string{"diff-runs"},
+	Short:   "Compare the results of two check runs",
+	Long: `Compare the results of two check runs and list the regressions, fixes and
+newly covered versions from the first to the second. Each run is a job ID
+from 'qmdverify jobs list', a file of saved results, such as the output of
+'qmdverify jobs results' or 'qmdverify check --output json', or a .qmd file,
+which is checked against the server first.
+
+Comparing two .qmd files shows what a new version of a mod changes, even
+when the files have different names.`,
+	Example: `  qmdverify diff 6f1c2a9e 9b04d7c1
+  qmdverify diff before.json after.json --output markdown >> CHANGELOG.md
+  qmdverify diff mod-1.2.qmd mod-1.3.qmd`,
+	SilenceUsage: true,
+	Args:         cobra.ExactArgs(2),
+	RunE:         runDiff,
+}
+
+func init() {
+	diffCmd.Flags().StringVarP(&diffOutput, "output", "o", outputTable, "Output format: table, json or markdown")
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	var write func(*rundiff.Diff) error
+	switch diffOutput {
+	case outputTable, outputText:
+		write = func(diff *rundiff.Diff) error { return display.RenderRunDiff(os.Stdout, diff) }
+	case outputJSON:
+		write = func(diff *rundiff.Diff) error { return diff.WriteJSON(os.Stdout) }
+	case outputMarkdown:
+		write = func(diff *rundiff.Diff) error { return diff.WriteMarkdown(os.Stdout) }
+	default:
+		err := fmt.Errorf("invalid --output %q: must be table, json or markdown", diffOutput)
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	var checker comparer
+	newChecker := func() (comparer, error) {
+		if checker == nil {
+			cfg, err := config.Load()
+			if err != nil {
+				return nil, err
+			}
+			checker = newComparer(cfg, newAPIClient(cfg))
+		}
+		return checker, nil
+	}
+
+	runs := make([]*rundiff.Run, len(args))
+	for i, ref := range args {
+		run, err := loadDiffRun(cmd.Context(), ref, newChecker)
+		if err != nil {
+			display.RenderError(os.Stderr, err)
+			return err
+		}
+		runs[i] = run
+	}
+
+	return write(rundiff.Compare(runs[0], runs[1]))
+}
+
+// loadDiffRun checks ref against the server when it is a .qmd file, and
+// otherwise loads it as saved results or a stored job.
+func loadDiffRun(ctx context.Context, ref string, checker func() (comparer, error)) (*rundiff.Run, error) {
+	if !strings.HasSuffix(strings.ToLower(ref), ".qmd") {
+		return rundiff.Load(ref)
+	}
+	if _, err := os.Stat(ref); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", ref, err)
+	}
+
+	c, err := checker()
+	if err != nil {
+		return nil, err
+	}
+	response, err := c.CompareQMD(ctx, ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check %s: %w", ref, err)
+	}
+	return &rundiff.Run{Name: ref, Results: map[string]*api.ComparisonResponse{"": response}}, nil
+}
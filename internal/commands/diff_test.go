@@ -0,0 +1,71 @@
+package commands
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/cache"
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+func TestLoadDiffRun(t *testing.T) {
+	dir := t.TempDir()
+	qmd := filepath.Join(dir, "mod.qmd")
+	results := filepath.Join(dir, "before.json")
+	if err := os.WriteFile(qmd, []byte("AFFECT [[1]] {}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(results, []byte(`{"mod.qmd": {"compatible": [{"device": "rm2", "os_version": "3.22.0.64", "compatible": true}]}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	checked := api.ComparisonResponse{Incompatible: []api.ComparisonResult{{Device: "rm2", OSVersion: "3.22.0.64"}}}
+	tests := []struct {
+		name        string
+		ref         string
+		wantFile    string
+		wantChecked bool
+		wantErr     string
+	}{
+		{name: "results file", ref: results, wantFile: "mod.qmd"},
+		{name: "qmd file", ref: qmd, wantChecked: true},
+		{name: "missing qmd file", ref: filepath.Join(dir, "missing.qmd"), wantErr: "failed to read"},
+		{name: "unknown run", ref: "nope", wantErr: "neither a results file nor a stored job"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(cache.EnvVarCacheDir, t.TempDir())
+			called := false
+			checker := func() (comparer, error) {
+				called = true
+				return &fakeComparer{batch: api.BatchComparisonResponse{qmd: checked}}, nil
+			}
+
+			run, err := loadDiffRun(context.Background(), tt.ref, checker)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("loadDiffRun() error = %v, want %q", err, tt.wantErr)
+				}
+				if called {
+					t.Error("checked a run that could not be loaded")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadDiffRun() error = %v", err)
+			}
+			if called != tt.wantChecked {
+				t.Errorf("checked = %v, want %v", called, tt.wantChecked)
+			}
+			if run.Name != tt.ref {
+				t.Errorf("Name = %q, want %q", run.Name, tt.ref)
+			}
+			if _, ok := run.Results[tt.wantFile]; !ok || len(run.Results) != 1 {
+				t.Errorf("Results = %v, want one result for %q", run.Results, tt.wantFile)
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/formatter"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/policy"
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+// Exit codes of a check. Any other failure exits 1, and an interrupt 130.
+const (
+	exitCodeIncompatible       = 1
+	exitCodeDependencyWarnings = 2
+	exitCodeServerError        = 3
+	exitCodeNoData             = 4
+)
+
+// Outcomes of a check that fail the run, each with its own exit code.
+var (
+	ErrIncompatible       = errors.New("incompatibilities found")
+	ErrDependencyWarnings = errors.New("only dependency warnings found")
+	ErrServer             = errors.New("server error")
+	ErrNoData             = errors.New("no results")
+)
+
+// CheckError is returned by a check whose results fail the run. Outcome is
+// ErrIncompatible, ErrDependencyWarnings, ErrServer or ErrNoData, and Report
+// holds the results when the check got as far as rendering them.
+type CheckError struct {
+	Outcome error
+	Report  *formatter.Report
+}
+
+func (e *CheckError) Error() string {
+	return e.Outcome.Error()
+}
+
+func (e *CheckError) Unwrap() error {
+	return e.Outcome
+}
+
+// ExitCode returns the exit code for an error returned by a command.
+func ExitCode(err error) int {
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, ErrServer):
+		return exitCodeServerError
+	case errors.Is(err, ErrIncompatible):
+		return exitCodeIncompatible
+	case errors.Is(err, ErrDependencyWarnings):
+		return exitCodeDependencyWarnings
+	case errors.Is(err, ErrNoData):
+		return exitCodeNoData
+	}
+	return 1
+}
+
+// failSelector is one --fail-on value: the devices and version prefixes whose
+// incompatibilities fail the run. An empty list matches everything.
+type failSelector struct {
+	devices  []string
+	versions []string
+}
+
+// parseFailOn parses --fail-on values such as "device=rmpp,version=3.22".
+// Keys may be repeated within a value, and a result fails the run if it
+// matches any of the values.
+func parseFailOn(values []string) ([]failSelector, error) {
+	selectors := make([]failSelector, 0, len(values))
+	for _, value := range values {
+		var selector failSelector
+		for _, field := range strings.Split(value, ",") {
+			key, v, ok := strings.Cut(strings.TrimSpace(field), "=")
+			v = strings.TrimSpace(v)
+			if !ok || v == "" {
+				return nil, fmt.Errorf("invalid --fail-on %q: expected key=value pairs such as device=rmpp,version=3.22", value)
+			}
+			switch key {
+			case "device":
+				if err := validateDeviceFilters([]string{v}); err != nil {
+					return nil, err
+				}
+				selector.devices = append(selector.devices, v)
+			case "version":
+				selector.versions = append(selector.versions, v)
+			default:
+				return nil, fmt.Errorf("invalid --fail-on %q: unknown key %q (expected device or version)", value, key)
+			}
+		}
+		selectors = append(selectors, selector)
+	}
+	return selectors, nil
+}
+
+func (s failSelector) matches(device, version string) bool {
+	return matchesFilter(api.ComparisonResult{Device: device, OSVersion: version}, s.devices, s.versions)
+}
+
+func matchesFailOn(selectors []failSelector, device, version string) bool {
+	if len(selectors) == 0 {
+		return true
+	}
+	for _, selector := range selectors {
+		if selector.matches(device, version) {
+			return true
+		}
+	}
+	return false
+}
+
+// selectFailures returns the incompatibilities of response that --fail-on
+// counts, or response itself without selectors.
+func selectFailures(response *api.ComparisonResponse, selectors []failSelector) *api.ComparisonResponse {
+	if len(selectors) == 0 {
+		return response
+	}
+	selected := &api.ComparisonResponse{Compatible: response.Compatible, TotalChecked: response.TotalChecked}
+	for _, result := range response.Incompatible {
+		if matchesFailOn(selectors, result.Device, result.OSVersion) {
+			selected.Incompatible = append(selected.Incompatible, result)
+		}
+	}
+	return selected
+}
+
+func selectViolations(violations []policy.Violation, selectors []failSelector) []policy.Violation {
+	if len(selectors) == 0 {
+		return violations
+	}
+	var selected []policy.Violation
+	for _, v := range violations {
+		if matchesFailOn(selectors, v.Device, v.OSVersion) {
+			selected = append(selected, v)
+		}
+	}
+	return selected
+}
+
+// checkOutcome is what a check found that decides its exit code.
+type checkOutcome struct {
+	serverError        bool
+	incompatible       bool
+	dependencyWarnings int
+	noData             bool
+}
+
+// err returns the CheckError for an outcome that fails the run, or nil.
+// --exit-zero silences every result but a server error, which means the
+// results are incomplete.
+func (o checkOutcome) err(exitZero bool, report *formatter.Report) error {
+	var outcome error
+	switch {
+	case o.serverError:
+		outcome = ErrServer
+	case exitZero:
+	case o.incompatible:
+		outcome = ErrIncompatible
+	case o.dependencyWarnings > 0:
+		outcome = ErrDependencyWarnings
+	case o.noData:
+		outcome = ErrNoData
+	}
+	if outcome == nil {
+		return nil
+	}
+	return &CheckError{Outcome: outcome, Report: report}
+}
+
+// serverFailure marks a failed check request as a server error unless the
+// run was interrupted.
+func serverFailure(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrServer, err)
+}
+
+// checkFailed silences cobra's error line for a CheckError, whose results
+// have already been reported, leaving only its exit code.
+func checkFailed(cmd *cobra.Command, err error) error {
+	var checkErr *CheckError
+	if errors.As(err, &checkErr) {
+		cmd.SilenceErrors = true
+	}
+	return err
+}
@@ -0,0 +1,146 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/policy"
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+func TestParseFailOn(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  []string
+		want    []failSelector
+		wantErr string
+	}{
+		{name: "none", want: []failSelector{}},
+		{name: "device and version", values: []string{"device=rmpp,version=3.22"}, want: []failSelector{{devices: []string{"rmpp"}, versions: []string{"3.22"}}}},
+		{name: "repeated key", values: []string{"device=rmpp, device=rmppm"}, want: []failSelector{{devices: []string{"rmpp", "rmppm"}}}},
+		{name: "several values", values: []string{"device=rm2", "version=3.23"}, want: []failSelector{{devices: []string{"rm2"}}, {versions: []string{"3.23"}}}},
+		{name: "unknown device", values: []string{"device=kindle"}, wantErr: "kindle"},
+		{name: "unknown key", values: []string{"os=3.22"}, wantErr: `unknown key "os"`},
+		{name: "missing value", values: []string{"device="}, wantErr: "expected key=value"},
+		{name: "no key", values: []string{"rmpp"}, wantErr: "expected key=value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseFailOn(tt.values)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseFailOn() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseFailOn() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseFailOn() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateOutcomeFailOn(t *testing.T) {
+	response := &api.ComparisonResponse{
+		Compatible: []api.ComparisonResult{{Device: "rmpp", OSVersion: "3.23.0.64", Compatible: true}},
+		Incompatible: []api.ComparisonResult{
+			{Device: "rm2", OSVersion: "3.22.0.64"},
+			{Device: "rmpp", OSVersion: "3.22.4.2"},
+		},
+		TotalChecked: 3,
+	}
+	rules := []policy.Rule{{Raw: "rm2 latest-1", Device: "rm2", Kind: policy.LatestN, Count: 1}}
+
+	tests := []struct {
+		name   string
+		failOn []string
+		rules  []policy.Rule
+		want   bool
+	}{
+		{name: "no selectors", want: true},
+		{name: "selected device", failOn: []string{"device=rmpp,version=3.22"}, want: true},
+		{name: "other version", failOn: []string{"device=rmpp,version=3.23"}},
+		{name: "other device", failOn: []string{"device=rm1"}},
+		{name: "any selector", failOn: []string{"device=rm1", "version=3.22.0"}, want: true},
+		{name: "policy violation selected", failOn: []string{"device=rm2"}, rules: rules, want: true},
+		{name: "policy violation not selected", failOn: []string{"device=rmpp"}, rules: rules},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failOn, err := parseFailOn(tt.failOn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := evaluateOutcome(io.Discard, response, response, tt.rules, failOn)
+			if err != nil {
+				t.Fatalf("evaluateOutcome() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("evaluateOutcome() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckOutcomeErr(t *testing.T) {
+	tests := []struct {
+		name     string
+		outcome  checkOutcome
+		exitZero bool
+		want     int
+	}{
+		{name: "compatible", want: 0},
+		{name: "incompatible", outcome: checkOutcome{incompatible: true, dependencyWarnings: 1}, want: exitCodeIncompatible},
+		{name: "dependency warnings", outcome: checkOutcome{dependencyWarnings: 2}, want: exitCodeDependencyWarnings},
+		{name: "no data", outcome: checkOutcome{noData: true}, want: exitCodeNoData},
+		{name: "server error", outcome: checkOutcome{serverError: true, incompatible: true}, want: exitCodeServerError},
+		{name: "exit zero", outcome: checkOutcome{incompatible: true, noData: true}, exitZero: true, want: 0},
+		{name: "exit zero server error", outcome: checkOutcome{serverError: true}, exitZero: true, want: exitCodeServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.outcome.err(tt.exitZero, nil)
+			if got := ExitCode(err); got != tt.want {
+				t.Errorf("ExitCode(%v) = %d, want %d", err, got, tt.want)
+			}
+			var checkErr *CheckError
+			if (err != nil) != errors.As(err, &checkErr) {
+				t.Errorf("err() = %#v, want a *CheckError", err)
+			}
+		})
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "success", want: 0},
+		{name: "other error", err: errors.New("boom"), want: 1},
+		{name: "wrapped outcome", err: fmt.Errorf("check: %w", &CheckError{Outcome: ErrNoData}), want: exitCodeNoData},
+		{name: "server failure", err: serverFailure(context.Background(), errors.New("connection refused")), want: exitCodeServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExitCode(tt.err); got != tt.want {
+				t.Errorf("ExitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := serverFailure(ctx, context.Canceled); errors.Is(err, ErrServer) {
+		t.Errorf("serverFailure() = %v for an interrupted run, want the error as is", err)
+	}
+}
@@ -0,0 +1,96 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/display"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/formatter"
+)
+
+// Files GitHub Actions reads a step's outputs and its job summary from.
+const (
+	envGitHubOutput      = "GITHUB_OUTPUT"
+	envGitHubStepSummary = "GITHUB_STEP_SUMMARY"
+)
+
+// writeGitHub appends a check's outcome to $GITHUB_OUTPUT and its matrices
+// to $GITHUB_STEP_SUMMARY. A variable that isn't set is skipped with a
+// warning, so --github can stay on when a workflow is run locally.
+func writeGitHub(report *formatter.Report, failed bool) error {
+	summary, err := githubSummary(report, failed)
+	if err != nil {
+		return err
+	}
+	for _, file := range []struct{ env, content string }{
+		{envGitHubOutput, githubOutputs(report, failed)},
+		{envGitHubStepSummary, summary},
+	} {
+		path := os.Getenv(file.env)
+		if path == "" {
+			fmt.Fprintf(os.Stderr, "Warning: --github: %s is not set\n", file.env)
+			continue
+		}
+		if err := appendFile(path, file.content); err != nil {
+			return fmt.Errorf("failed to write %s: %w", file.env, err)
+		}
+	}
+	return nil
+}
+
+// githubOutputs lists the step outputs: result (pass or fail), the counts
+// summed over every file, and min-version-<device>, the oldest version all
+// files are compatible with on that device.
+func githubOutputs(report *formatter.Report, failed bool) string {
+	var b strings.Builder
+	result := "pass"
+	if failed {
+		result = "fail"
+	}
+	fmt.Fprintf(&b, "result=%s\n", result)
+
+	total, compatible, incompatible := 0, 0, 0
+	for _, response := range report.Responses() {
+		total += response.TotalChecked
+		compatible += len(response.Compatible)
+		incompatible += len(response.Incompatible)
+	}
+	fmt.Fprintf(&b, "files=%d\ntotal=%d\ncompatible=%d\nincompatible=%d\n", len(report.Results), total, compatible, incompatible)
+
+	for _, window := range display.ComputeSupportWindows(intersectResponses(report.Responses())) {
+		fmt.Fprintf(&b, "min-version-%s=%s\n", window.Device, window.Oldest)
+	}
+	return b.String()
+}
+
+func githubSummary(report *formatter.Report, failed bool) (string, error) {
+	var b strings.Builder
+	verdict := "✅ All files are compatible"
+	if failed {
+		verdict = "❌ Incompatibilities found"
+	}
+	fmt.Fprintf(&b, "## qmdverify\n\n%s\n", verdict)
+	for _, file := range report.Files() {
+		if len(report.Results) > 1 || file != "" {
+			fmt.Fprintf(&b, "\n### `%s`\n", file)
+		}
+		b.WriteString("\n")
+		if err := display.RenderMarkdownMatrix(&b, report.Results[file]); err != nil {
+			return "", err
+		}
+	}
+	return b.String(), nil
+}
+
+func appendFile(path, content string) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(content); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,60 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/formatter"
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+func TestWriteGitHub(t *testing.T) {
+	report := &formatter.Report{Results: map[string]*api.ComparisonResponse{
+		"a.qmd": {
+			Compatible: []api.ComparisonResult{
+				{Device: "rmpp", OSVersion: "3.20.0.92", Compatible: true},
+				{Device: "rmpp", OSVersion: "3.22.0.64", Compatible: true},
+			},
+			TotalChecked: 2,
+		},
+		"b.qmd": {
+			Compatible:   []api.ComparisonResult{{Device: "rmpp", OSVersion: "3.22.0.64", Compatible: true}},
+			Incompatible: []api.ComparisonResult{{Device: "rmpp", OSVersion: "3.20.0.92", ErrorDetail: "missing 1 hash(es)"}},
+			TotalChecked: 2,
+		},
+	}}
+
+	dir := t.TempDir()
+	output := filepath.Join(dir, "output")
+	summary := filepath.Join(dir, "summary")
+	if err := os.WriteFile(output, []byte("earlier=1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(envGitHubOutput, output)
+	t.Setenv(envGitHubStepSummary, summary)
+
+	if err := writeGitHub(report, true); err != nil {
+		t.Fatalf("writeGitHub() error = %v", err)
+	}
+
+	got, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "earlier=1\nresult=fail\nfiles=2\ntotal=4\ncompatible=3\nincompatible=1\nmin-version-rmpp=3.22.0.64\n"
+	if string(got) != want {
+		t.Errorf("GITHUB_OUTPUT =\n%s\nwant\n%s", got, want)
+	}
+
+	got, err = os.ReadFile(summary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, wantLine := range []string{"❌ Incompatibilities found", "### `a.qmd`", "### `b.qmd`", "| 3.20.0.92 | ❌ |"} {
+		if !strings.Contains(string(got), wantLine) {
+			t.Errorf("GITHUB_STEP_SUMMARY missing %q:\n%s", wantLine, got)
+		}
+	}
+}
@@ -1,8 +1,14 @@
 package commands
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
 
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/hashtabfile"
 	"github.com/rmitchellscott/rm-qmd-verify/pkg/hashtab"
 	"github.com/spf13/cobra"
 )
@@ -10,22 +16,35 @@ import (
 var hashlistCmd = &cobra.Command{
 	Use:   "hashlist",
 	Short: "Hashtab to hashlist conversion utilities",
-	Long:  `Convert hashtab files (hash + strings) to hashlist files (compact hash-only binary format).`,
+	Long:  `Convert hashtab files (hash + strings) to hashlist files (compact hash-only binary format), and inspect, compare and combine them.`,
 }
 
+var (
+	hashlistCompressed bool
+	containsQuiet      bool
+	diffResolve        []string
+	mergeIntersect     bool
+	inspectTop         int
+	inspectJSON        bool
+)
+
 var hashlistCreateCmd = &cobra.Command{
 	Use:   "create <input-hashtab> <output-hashlist>",
 	Short: "Convert hashtab to hashlist (strips strings, keeps only hashes)",
 	Long: `Convert a hashtab file to a hashlist file.
 
 A hashtab file contains hash-string pairs, while a hashlist file contains only
-the hashes in a compact binary format (12 bytes per hash: 8-byte hash + 4-byte zero length).`,
+the hashes in a compact binary format (12 bytes per hash: 8-byte hash + 4-byte zero length).
+
+With --compressed, the hashes are sorted and stored as varint deltas instead,
+which is several times smaller. Compressed hashlists are read transparently by
+--local checks and the hashtab commands, but not by qmldiff or the server.`,
 	Args: cobra.ExactArgs(2),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		inputPath := args[0]
 		outputPath := args[1]
 
-		ht, err := hashtab.Load(inputPath)
+		ht, err := hashtabfile.Load(inputPath)
 		if err != nil {
 			return fmt.Errorf("failed to load hashtab: %w", err)
 		}
@@ -39,9 +58,8 @@ the hashes in a compact binary format (12 bytes per hash: 8-byte hash + 4-byte z
 			hashes = append(hashes, hash)
 		}
 
-		err = hashtab.WriteHashlist(hashes, outputPath)
-		if err != nil {
-			return fmt.Errorf("failed to write hashlist: %w", err)
+		if err := writeHashlist(outputPath, hashes); err != nil {
+			return err
 		}
 
 		fmt.Printf("✓ Converted %d hashes from %s to %s\n",
@@ -51,6 +69,346 @@ the hashes in a compact binary format (12 bytes per hash: 8-byte hash + 4-byte z
 	},
 }
 
+var hashlistContainsCmd = &cobra.Command{
+	Use:   "contains <hashlist> <hash>...",
+	Short: "Check whether hashes are present in a hashlist or hashtab",
+	Long: `Check whether each hash is present in a hashlist or hashtab, printing one
+line per hash. Hashes may be decimal or 0x-prefixed hexadecimal.
+
+Exits non-zero if any hash is absent, so scripts can test membership without
+reading the file themselves.`,
+	Example: `  qmdverify hashlist contains hashlists/3.22.4.2-rmpp 0xF4A3C2D1E0B9A877
+  qmdverify hashlist contains -q hashlists/3.22.4.2-rmpp 17607111715072197239 && echo present`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		hashes, err := parseHashes(args[1:])
+		if err != nil {
+			return err
+		}
+
+		ht, err := hashtabfile.Load(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to load hashlist: %w", err)
+		}
+
+		w := io.Writer(os.Stdout)
+		if containsQuiet {
+			w = io.Discard
+		}
+		if missing := printMembership(w, ht.Entries, args[1:], hashes); missing > 0 {
+			return fmt.Errorf("%d of %d hashes not found in %s", missing, len(hashes), args[0])
+		}
+		return nil
+	},
+}
+
+var hashlistDiffCmd = &cobra.Command{
+	Use:   "diff <a> <b>",
+	Short: "Compare the hashes of two hashlists or hashtabs",
+	Long: `List the hashes only in A (prefixed with -) and only in B (prefixed with +),
+then count those in both, to see what changed between two firmware versions.
+
+Hashes are shown with their string when either input is a hashtab that has
+one. Hashes only in a hashlist can be resolved with --resolve, which reads
+strings from other hashtabs without comparing them.`,
+	Example: `  qmdverify hashlist diff hashtabs/3.20.0.92-rmpp hashtabs/3.22.0.64-rmpp
+  qmdverify hashlist diff hashlists/3.20.0.92-rmpp hashlists/3.22.0.64-rmpp --resolve hashtabs/3.22.0.64-rmpp`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tables := make([]map[uint64]string, 2)
+		for i, path := range args {
+			ht, err := hashtabfile.Load(path)
+			if err != nil {
+				return fmt.Errorf("failed to load %s: %w", path, err)
+			}
+			tables[i] = ht.Entries
+		}
+		strs := []map[uint64]string{tables[0], tables[1]}
+		for _, path := range diffResolve {
+			ht, err := hashtabfile.Load(path)
+			if err != nil {
+				return fmt.Errorf("failed to load %s: %w", path, err)
+			}
+			strs = append(strs, ht.Entries)
+		}
+
+		onlyA, onlyB, common := diffHashes(tables[0], tables[1])
+		printHashDiff(os.Stdout, onlyA, onlyB, strs)
+		fmt.Printf("%d only in %s, %d only in %s, %d in both\n", len(onlyA), args[0], len(onlyB), args[1], common)
+		return nil
+	},
+}
+
+var hashlistMergeCmd = &cobra.Command{
+	Use:   "merge <output-hashlist> <input>...",
+	Short: "Combine hashlists or hashtabs into one deduplicated hashlist",
+	Long: `Write the union of the hashes in every input, each once and sorted, as a
+hashlist, for example to combine per-device tables into one list for quick
+local checks. With --intersect only the hashes present in every input are
+kept.`,
+	Example: `  qmdverify hashlist merge hashlists/3.22.0.64-all hashtabs/3.22.0.64-rm2 hashtabs/3.22.0.64-rmpp
+  qmdverify hashlist merge --intersect common.hashlist hashlists/*`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		outputPath, inputs := args[0], args[1:]
+		tables := make([]map[uint64]string, len(inputs))
+		for i, path := range inputs {
+			ht, err := hashtabfile.Load(path)
+			if err != nil {
+				return fmt.Errorf("failed to load %s: %w", path, err)
+			}
+			tables[i] = ht.Entries
+		}
+
+		hashes := mergeHashes(tables, mergeIntersect)
+		if err := writeHashlist(outputPath, hashes); err != nil {
+			return err
+		}
+
+		mode := "Merged"
+		if mergeIntersect {
+			mode = "Intersected"
+		}
+		fmt.Printf("✓ %s %d files into %s (%d hashes)\n", mode, len(inputs), outputPath, len(hashes))
+		return nil
+	},
+}
+
+var hashlistInspectCmd = &cobra.Command{
+	Use:   "inspect <hashlist or hashtab>",
+	Short: "Report the format, size, version, duplicates and longest strings of a file",
+	Long: `Report whether a file is a hashlist or a hashtab, its size and entry count,
+the version entry, which hashes occur more than once, and the longest
+strings, as 'hashtab stats' does with the duplicate hashes and longest
+strings listed. --top limits both lists; --json prints the report for
+scripts with every duplicate hash.`,
+	Example: `  qmdverify hashlist inspect hashtabs/3.22.0.64-rmpp
+  qmdverify hashlist inspect --json --top 50 hashtabs/3.22.0.64-rmpp`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if inspectTop < 0 {
+			return fmt.Errorf("--top must not be negative")
+		}
+		info, err := os.Stat(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to access %s: %w", args[0], err)
+		}
+		entries, err := hashtabfile.ReadFile(args[0])
+		if err != nil {
+			return err
+		}
+
+		inspection := inspectHashtab(args[0], entries, info.Size(), inspectTop)
+		if inspectJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(inspection)
+		}
+		renderInspection(os.Stdout, inspection, hashtabfile.ComputeStats(entries, info.Size()), inspectTop)
+		return nil
+	},
+}
+
+// hashtabInspection is the report of 'hashlist inspect'.
+type hashtabInspection struct {
+	File            string          `json:"file"`
+	Format          string          `json:"format"`
+	Size            int64           `json:"size"`
+	Entries         int             `json:"entries"`
+	Unique          int             `json:"unique"`
+	Version         *versionEntry   `json:"version"`
+	DuplicateHashes []uint64        `json:"duplicate_hashes"`
+	Conflicts       int             `json:"conflicting_duplicates"`
+	Longest         []inspectString `json:"longest_strings"`
+}
+
+type versionEntry struct {
+	Hash   uint64 `json:"hash"`
+	String string `json:"string"`
+}
+
+type inspectString struct {
+	Hash   uint64 `json:"hash"`
+	String string `json:"string"`
+	Length int    `json:"length"`
+}
+
+func inspectHashtab(path string, entries []hashtabfile.Entry, size int64, top int) hashtabInspection {
+	stats := hashtabfile.ComputeStats(entries, size)
+	inspection := hashtabInspection{
+		File:            path,
+		Format:          "hashtab",
+		Size:            size,
+		Entries:         stats.Entries,
+		Unique:          stats.Unique,
+		DuplicateHashes: hashtabfile.DuplicateHashes(entries),
+		Conflicts:       stats.Conflicts,
+		Longest:         []inspectString{},
+	}
+	if stats.IsHashlist() {
+		inspection.Format = "hashlist"
+	}
+	if stats.HasVersion {
+		inspection.Version = &versionEntry{Hash: hashtabfile.VersionHash, String: stats.Version}
+	}
+	if inspection.DuplicateHashes == nil {
+		inspection.DuplicateHashes = []uint64{}
+	}
+	for _, entry := range hashtabfile.Longest(entries, top) {
+		inspection.Longest = append(inspection.Longest, inspectString{Hash: entry.Hash, String: entry.String, Length: len(entry.String)})
+	}
+	return inspection
+}
+
+// renderInspection prints the stats of 'hashtab stats' followed by up to
+// top duplicate hashes and the longest strings.
+func renderInspection(w io.Writer, inspection hashtabInspection, stats hashtabfile.Stats, top int) {
+	renderHashtabStats(w, inspection.File, stats)
+	if stats.HasVersion {
+		fmt.Fprintf(w, "Version hash:   %d\n", hashtabfile.VersionHash)
+	}
+
+	if dups := inspection.DuplicateHashes; len(dups) > 0 && top > 0 {
+		fmt.Fprintln(w, "\nDuplicate hashes:")
+		for _, hash := range dups[:min(top, len(dups))] {
+			fmt.Fprintf(w, "  %d\n", hash)
+		}
+		if len(dups) > top {
+			fmt.Fprintf(w, "  ... and %d more\n", len(dups)-top)
+		}
+	}
+
+	if len(inspection.Longest) > 0 {
+		fmt.Fprintln(w, "\nLongest strings:")
+		for _, s := range inspection.Longest {
+			fmt.Fprintf(w, "  %5d  %d\t%s\n", s.Length, s.Hash, s.String)
+		}
+	}
+}
+
+// writeHashlist writes hashes as a hashlist, compressed with --compressed.
+func writeHashlist(path string, hashes []uint64) error {
+	var err error
+	if hashlistCompressed {
+		entries := make([]hashtabfile.Entry, len(hashes))
+		for i, hash := range hashes {
+			entries[i] = hashtabfile.Entry{Hash: hash}
+		}
+		err = hashtabfile.EncodeFile(path, entries, hashtabfile.Compressed)
+	} else {
+		err = hashtab.WriteHashlist(hashes, path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write hashlist: %w", err)
+	}
+	return nil
+}
+
+// mergeHashes returns the sorted union of the hashes of tables, or with
+// intersect only those in every table.
+func mergeHashes(tables []map[uint64]string, intersect bool) []uint64 {
+	counts := make(map[uint64]int)
+	for _, entries := range tables {
+		for hash := range entries {
+			counts[hash]++
+		}
+	}
+
+	hashes := make([]uint64, 0, len(counts))
+	for hash, n := range counts {
+		if !intersect || n == len(tables) {
+			hashes = append(hashes, hash)
+		}
+	}
+	slices.Sort(hashes)
+	return hashes
+}
+
+// diffHashes returns the hashes only in a and only in b, each sorted, and
+// how many are in both.
+func diffHashes(a, b map[uint64]string) (onlyA, onlyB []uint64, common int) {
+	for hash := range a {
+		if _, ok := b[hash]; ok {
+			common++
+		} else {
+			onlyA = append(onlyA, hash)
+		}
+	}
+	for hash := range b {
+		if _, ok := a[hash]; !ok {
+			onlyB = append(onlyB, hash)
+		}
+	}
+	slices.Sort(onlyA)
+	slices.Sort(onlyB)
+	return onlyA, onlyB, common
+}
+
+// printHashDiff writes "-\t<hash>[\t<string>]" for each hash only in A and
+// "+\t<hash>[\t<string>]" for each only in B, taking the string from the
+// first table that has one.
+func printHashDiff(w io.Writer, onlyA, onlyB []uint64, strs []map[uint64]string) {
+	for _, side := range []struct {
+		mark   string
+		hashes []uint64
+	}{{"-", onlyA}, {"+", onlyB}} {
+		for _, hash := range side.hashes {
+			line := fmt.Sprintf("%s\t%d", side.mark, hash)
+			if s := resolveHash(hash, strs); s != "" {
+				line += "\t" + s
+			}
+			fmt.Fprintln(w, line)
+		}
+	}
+}
+
+func resolveHash(hash uint64, strs []map[uint64]string) string {
+	for _, entries := range strs {
+		if s := entries[hash]; s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+func parseHashes(args []string) ([]uint64, error) {
+	hashes := make([]uint64, len(args))
+	for i, arg := range args {
+		hash, err := strconv.ParseUint(arg, 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hash %q: must be decimal or 0x-prefixed hexadecimal", arg)
+		}
+		hashes[i] = hash
+	}
+	return hashes, nil
+}
+
+// printMembership writes "<hash>\tpresent" or "<hash>\tabsent" for each hash,
+// echoing the hash as it was given, and returns the number absent.
+func printMembership(w io.Writer, entries map[uint64]string, args []string, hashes []uint64) int {
+	missing := 0
+	for i, hash := range hashes {
+		status := "present"
+		if _, ok := entries[hash]; !ok {
+			status = "absent"
+			missing++
+		}
+		fmt.Fprintf(w, "%s\t%s\n", args[i], status)
+	}
+	return missing
+}
+
 func init() {
+	hashlistCreateCmd.Flags().BoolVar(&hashlistCompressed, "compressed", false, "Write a compressed (delta/varint-encoded) hashlist")
+	hashlistContainsCmd.Flags().BoolVarP(&containsQuiet, "quiet", "q", false, "Print nothing; only set the exit code")
 	hashlistCmd.AddCommand(hashlistCreateCmd)
+	hashlistDiffCmd.Flags().StringArrayVar(&diffResolve, "resolve", nil, "Also read hash strings from this hashtab (can be repeated)")
+	hashlistCmd.AddCommand(hashlistContainsCmd)
+	hashlistMergeCmd.Flags().BoolVar(&mergeIntersect, "intersect", false, "Keep only the hashes present in every input")
+	hashlistMergeCmd.Flags().BoolVar(&hashlistCompressed, "compressed", false, "Write a compressed (delta/varint-encoded) hashlist")
+	hashlistCmd.AddCommand(hashlistDiffCmd)
+	hashlistInspectCmd.Flags().IntVar(&inspectTop, "top", 10, "How many duplicate hashes and longest strings to list")
+	hashlistInspectCmd.Flags().BoolVar(&inspectJSON, "json", false, "Print the report as JSON")
+	hashlistCmd.AddCommand(hashlistMergeCmd)
+	hashlistCmd.AddCommand(hashlistInspectCmd)
 }
@@ -0,0 +1,190 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/hashtabfile"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/scan"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/tree"
+	"github.com/rmitchellscott/rm-qmd-verify/pkg/hashtab"
+	"github.com/spf13/cobra"
+)
+
+// firmwareVersionFiles are read, relative to the firmware root, for the
+// version of an extracted firmware.
+var firmwareVersionFiles = []string{"usr/share/remarkable/update.conf", "etc/os-release"}
+
+var (
+	extractOutput    string
+	extractSSH       string
+	extractOSVersion string
+)
+
+var hashlistExtractCmd = &cobra.Command{
+	Use:   "extract <firmware-dir> -o <hashtab>",
+	Short: "Build a hashtab from an extracted firmware QML tree",
+	Long: `Walk the QML and JavaScript files of an extracted firmware, hash their
+identifiers and file paths as the server does, and write a normalized hashtab,
+so hashtables for a new OS release can be generated without waiting for the
+server.
+
+The version entry is read from usr/share/remarkable/update.conf or
+etc/os-release under the firmware directory unless --os-version is given.
+With --ssh the directory is on a device: it is copied over SSH with tar, and
+the version is read from the device.`,
+	Example: `  qmdverify hashlist extract ./firmware-3.23.0.64 -o hashtabs/3.23.0.64-rmpp
+  qmdverify hashlist extract --ssh root@10.11.99.1 /home/root/qml -o hashtabs/3.23.0.64-rmpp`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		root := args[0]
+		version := extractOSVersion
+		if extractSSH != "" {
+			dir, err := os.MkdirTemp("", "qmdverify-extract-*")
+			if err != nil {
+				return fmt.Errorf("failed to create temporary directory: %w", err)
+			}
+			defer os.RemoveAll(dir)
+
+			fmt.Fprintf(os.Stderr, "Copying %s from %s...\n", root, extractSSH)
+			root = filepath.Join(dir, "tree")
+			if err := copySSHTree(cmd.Context(), extractSSH, args[0], root); err != nil {
+				return err
+			}
+			if version == "" {
+				version = sshFirmwareVersion(cmd.Context(), extractSSH)
+			}
+		} else if version == "" {
+			version = firmwareVersion(root)
+		}
+
+		entries, files, err := extractHashtab(root, version)
+		if err != nil {
+			return err
+		}
+		if err := hashtabfile.WriteFile(extractOutput, entries); err != nil {
+			return err
+		}
+
+		if version == "" {
+			fmt.Fprintln(os.Stderr, "Warning: no firmware version found; the hashtab has no version entry (set one with --os-version)")
+		}
+		fmt.Printf("✓ Extracted %d entries from %d QML files to %s\n", len(entries), files, extractOutput)
+		return nil
+	},
+}
+
+// extractHashtab scans the QML tree under root into normalized entries,
+// with a version entry when version is set, and returns the number of QML
+// files scanned.
+func extractHashtab(root, version string) ([]hashtabfile.Entry, int, error) {
+	result, err := scan.Dir(root, false)
+	if err != nil {
+		return nil, 0, err
+	}
+	if result.QMLFiles == 0 {
+		return nil, 0, fmt.Errorf("no QML files found in %s", root)
+	}
+
+	entries, collisions := scannedEntries(result.Strings)
+	for _, c := range collisions {
+		fmt.Fprintf(os.Stderr, "Warning: %q and %q have the same hash %d; keeping %q\n", c[0], c[1], hashtab.DJB2Hash(c[0]), c[0])
+	}
+	if version != "" {
+		entries = append(entries, hashtabfile.Entry{Hash: hashtabfile.VersionHash, String: version})
+	}
+
+	normalized, err := hashtabfile.Normalize(entries)
+	if err != nil {
+		return nil, 0, err
+	}
+	return normalized, result.QMLFiles, nil
+}
+
+// firmwareVersion returns the version recorded in an extracted firmware, or
+// "" if there is none.
+func firmwareVersion(root string) string {
+	for _, name := range firmwareVersionFiles {
+		data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(name)))
+		if err != nil {
+			continue
+		}
+		if version := parseFirmwareVersion(data); version != "" {
+			return version
+		}
+	}
+	return ""
+}
+
+// parseFirmwareVersion reads REMARKABLE_RELEASE_VERSION from update.conf or
+// IMG_VERSION from os-release.
+func parseFirmwareVersion(data []byte) string {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if ok && (key == "REMARKABLE_RELEASE_VERSION" || key == "IMG_VERSION") {
+			if value = strings.Trim(value, `"'`); value != "" {
+				return value
+			}
+		}
+	}
+	return ""
+}
+
+// copySSHTree copies remoteDir on host into dir by running tar over ssh.
+func copySSHTree(ctx context.Context, host, remoteDir, dir string) error {
+	cmd := exec.CommandContext(ctx, "ssh", host, "tar -C "+shellQuote(remoteDir)+" -cf - .")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to run ssh: %w", err)
+	}
+
+	_, unpackErr := tree.Unpack(stdout, dir)
+	if unpackErr != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return unpackErr
+	}
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("failed to copy %s from %s: %w: %s", remoteDir, host, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// sshFirmwareVersion reads the firmware version of the device at host, or
+// returns "" if it cannot.
+func sshFirmwareVersion(ctx context.Context, host string) string {
+	for _, name := range firmwareVersionFiles {
+		out, err := exec.CommandContext(ctx, "ssh", host, "cat "+shellQuote("/"+name)).Output()
+		if err != nil {
+			continue
+		}
+		if version := parseFirmwareVersion(out); version != "" {
+			return version
+		}
+	}
+	return ""
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func init() {
+	hashlistExtractCmd.Flags().StringVarP(&extractOutput, "output", "o", "", "Hashtab file to write")
+	hashlistExtractCmd.Flags().StringVar(&extractSSH, "ssh", "", "Copy the firmware directory from this SSH host (e.g. root@10.11.99.1)")
+	hashlistExtractCmd.Flags().StringVar(&extractOSVersion, "os-version", "", "Firmware version to store in the hashtab's version entry instead of the detected one")
+	hashlistExtractCmd.MarkFlagRequired("output")
+	hashlistCmd.AddCommand(hashlistExtractCmd)
+}
@@ -0,0 +1,81 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/hashtabfile"
+	"github.com/rmitchellscott/rm-qmd-verify/pkg/hashtab"
+)
+
+func TestExtractHashtab(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"qml/Main.qml":                     "Item { width: 10; onSwiped: cb() }\n",
+		"usr/share/remarkable/update.conf": "[General]\nREMARKABLE_RELEASE_VERSION=3.23.0.64\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	version := firmwareVersion(root)
+	if version != "3.23.0.64" {
+		t.Errorf("firmwareVersion() = %q, want 3.23.0.64", version)
+	}
+
+	entries, qmlFiles, err := extractHashtab(root, version)
+	if err != nil {
+		t.Fatalf("extractHashtab() error = %v", err)
+	}
+	if qmlFiles != 1 {
+		t.Errorf("scanned %d QML files, want 1", qmlFiles)
+	}
+	got := make(map[uint64]string, len(entries))
+	for _, entry := range entries {
+		got[entry.Hash] = entry.String
+	}
+	for _, s := range []string{"/qml/Main.qml", "Item", "width", "onSwiped", "cb"} {
+		if got[hashtab.DJB2Hash(s)] != s {
+			t.Errorf("hashtab is missing %q", s)
+		}
+	}
+	if got[hashtabfile.VersionHash] != "3.23.0.64" {
+		t.Errorf("version entry = %q, want 3.23.0.64", got[hashtabfile.VersionHash])
+	}
+
+	if _, _, err := extractHashtab(t.TempDir(), ""); err == nil {
+		t.Error("extractHashtab() on a directory without QML succeeded")
+	}
+}
+
+func TestParseFirmwareVersion(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{name: "update.conf", data: "[General]\nSERVER=https://updates.cloud.remarkable.engineering\nREMARKABLE_RELEASE_VERSION=3.22.0.64\n", want: "3.22.0.64"},
+		{name: "os-release", data: "ID=codex\nIMG_VERSION=\"3.20.0.92\"\n", want: "3.20.0.92"},
+		{name: "none", data: "ID=codex\nVERSION_ID=4.0\n", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseFirmwareVersion([]byte(tt.data)); got != tt.want {
+				t.Errorf("parseFirmwareVersion() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	if got := shellQuote("/home/root/it's here"); got != `'/home/root/it'\''s here'` {
+		t.Errorf("shellQuote() = %s", got)
+	}
+}
@@ -1,11 +1,15 @@
 package commands
 
 import (
+	"bytes"
 	"encoding/binary"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/hashtabfile"
 	"github.com/rmitchellscott/rm-qmd-verify/pkg/hashtab"
 )
 
@@ -237,3 +241,178 @@ func TestHashlistCreatePreservesVersionHash(t *testing.T) {
 		t.Error("Version hash was not preserved in conversion")
 	}
 }
+
+func TestHashlistCreateCompressed(t *testing.T) {
+	tmpDir := t.TempDir()
+	inputPath := filepath.Join(tmpDir, "input.bin")
+	outputPath := filepath.Join(tmpDir, "3.22.4.2-rmpp")
+
+	var entries []hashtabfile.Entry
+	for i := uint64(1); i <= 1000; i++ {
+		entries = append(entries, hashtabfile.Entry{Hash: i * 7919, String: "property"})
+	}
+	if err := hashtabfile.WriteFile(inputPath, entries); err != nil {
+		t.Fatal(err)
+	}
+
+	hashlistCompressed = true
+	defer func() { hashlistCompressed = false }()
+	if err := hashlistCreateCmd.RunE(nil, []string{inputPath, outputPath}); err != nil {
+		t.Fatalf("hashlistCreateCmd.RunE() failed: %v", err)
+	}
+
+	stat, err := os.Stat(outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain := int64(len(entries) * 12); stat.Size()*4 > plain {
+		t.Errorf("compressed size = %d bytes, want under a quarter of %d", stat.Size(), plain)
+	}
+
+	ht, err := hashtabfile.Load(outputPath)
+	if err != nil {
+		t.Fatalf("hashtabfile.Load() error = %v", err)
+	}
+	if len(ht.Entries) != len(entries) || !ht.IsHashlist() || ht.OSVersion != "3.22.4.2" || ht.Device != "rmpp" {
+		t.Errorf("loaded hashlist = %d entries, version %q, device %q", len(ht.Entries), ht.OSVersion, ht.Device)
+	}
+	for _, entry := range entries {
+		if _, ok := ht.Entries[entry.Hash]; !ok {
+			t.Fatalf("hash %d missing from the compressed hashlist", entry.Hash)
+		}
+	}
+}
+
+func TestHashlistContains(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "3.22.4.2-rmpp")
+	if err := hashtab.WriteHashlist([]uint64{123, 0xF4A3}, path); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { containsQuiet = false }()
+	containsQuiet = true
+
+	tests := []struct {
+		name        string
+		hashes      []string
+		errContains string
+	}{
+		{name: "all present", hashes: []string{"123", "0xF4A3", "0xf4a3"}},
+		{name: "one absent", hashes: []string{"123", "456"}, errContains: "1 of 2 hashes not found"},
+		{name: "invalid hash", hashes: []string{"xyz"}, errContains: `invalid hash "xyz"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := hashlistContainsCmd.RunE(nil, append([]string{path}, tt.hashes...))
+			if tt.errContains == "" {
+				if err != nil {
+					t.Errorf("hashlistContainsCmd.RunE() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("hashlistContainsCmd.RunE() error = %v, want %q", err, tt.errContains)
+			}
+		})
+	}
+}
+
+func TestPrintMembership(t *testing.T) {
+	var buf bytes.Buffer
+	missing := printMembership(&buf, map[uint64]string{1: ""}, []string{"0x1", "2"}, []uint64{1, 2})
+	if missing != 1 {
+		t.Errorf("printMembership() = %d, want 1", missing)
+	}
+	if want := "0x1\tpresent\n2\tabsent\n"; buf.String() != want {
+		t.Errorf("printMembership() output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestDiffHashes(t *testing.T) {
+	a := map[uint64]string{1: "width", 2: "", 3: "height"}
+	b := map[uint64]string{3: "height", 4: "", 5: "onSwiped"}
+
+	onlyA, onlyB, common := diffHashes(a, b)
+	if !slices.Equal(onlyA, []uint64{1, 2}) || !slices.Equal(onlyB, []uint64{4, 5}) || common != 1 {
+		t.Fatalf("diffHashes() = %v, %v, %d", onlyA, onlyB, common)
+	}
+
+	tests := []struct {
+		name string
+		strs []map[uint64]string
+		want string
+	}{
+		{name: "inputs", strs: []map[uint64]string{a, b}, want: "-\t1\twidth\n-\t2\n+\t4\n+\t5\tonSwiped\n"},
+		{name: "resolved", strs: []map[uint64]string{a, b, {2: "x", 4: "SwipeArea"}}, want: "-\t1\twidth\n-\t2\tx\n+\t4\tSwipeArea\n+\t5\tonSwiped\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			printHashDiff(&buf, onlyA, onlyB, tt.strs)
+			if buf.String() != tt.want {
+				t.Errorf("printHashDiff() = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeHashes(t *testing.T) {
+	tables := []map[uint64]string{
+		{1: "width", 2: "", 3: "height"},
+		{3: "", 4: "", 2: "x"},
+		{2: "", 3: "", 5: ""},
+	}
+	tests := []struct {
+		name      string
+		intersect bool
+		want      []uint64
+	}{
+		{name: "union", want: []uint64{1, 2, 3, 4, 5}},
+		{name: "intersect", intersect: true, want: []uint64{2, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeHashes(tables, tt.intersect); !slices.Equal(got, tt.want) {
+				t.Errorf("mergeHashes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInspectHashtab(t *testing.T) {
+	entries := []hashtabfile.Entry{
+		{Hash: 1, String: "id"},
+		{Hash: 2, String: "anchors"},
+		{Hash: 1, String: "id"},
+		{Hash: 3, String: "x"},
+		{Hash: 3, String: "y"},
+		{Hash: hashtabfile.VersionHash, String: "3.22.4.2"},
+	}
+
+	got := inspectHashtab("3.22.4.2-rmpp", entries, 120, 1)
+	if got.Format != "hashtab" || got.Entries != 6 || got.Unique != 4 || got.Conflicts != 1 {
+		t.Errorf("inspectHashtab() = %+v", got)
+	}
+	if got.Version == nil || got.Version.String != "3.22.4.2" || got.Version.Hash != hashtabfile.VersionHash {
+		t.Errorf("Version = %+v", got.Version)
+	}
+	if !slices.Equal(got.DuplicateHashes, []uint64{1, 3}) {
+		t.Errorf("DuplicateHashes = %v, want [1 3]", got.DuplicateHashes)
+	}
+	if len(got.Longest) != 1 || got.Longest[0] != (inspectString{Hash: 2, String: "anchors", Length: 7}) {
+		t.Errorf("Longest = %+v", got.Longest)
+	}
+
+	var buf bytes.Buffer
+	renderInspection(&buf, got, hashtabfile.ComputeStats(entries, 120), 1)
+	for _, want := range []string{"Duplicate hashes:\n  1\n  ... and 1 more\n", "Longest strings:\n      7  2\tanchors\n", "Version entry:  \"3.22.4.2\""} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("renderInspection() = %q, missing %q", buf.String(), want)
+		}
+	}
+
+	hashlist := inspectHashtab("list", []hashtabfile.Entry{{Hash: 1}}, 12, 10)
+	if hashlist.Format != "hashlist" || hashlist.Version != nil || hashlist.DuplicateHashes == nil || hashlist.Longest == nil {
+		t.Errorf("inspectHashtab(hashlist) = %+v", hashlist)
+	}
+}
@@ -0,0 +1,449 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/hashtabfile"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/scan"
+	"github.com/rmitchellscott/rm-qmd-verify/pkg/hashtab"
+	"github.com/spf13/cobra"
+)
+
+var hashtabCmd = &cobra.Command{
+	Use:   "hashtab",
+	Short: "Inspect and edit hashtab files",
+	Long:  `Utilities for auditing and maintaining hashtab files (hash + strings) and hashlists before they are uploaded to a server.`,
+}
+
+var hashtabStatsCmd = &cobra.Command{
+	Use:   "stats <hashtab>",
+	Short: "Report entry counts, duplicates and string lengths of a hashtab",
+	Long: `Report the number of entries and unique hashes, duplicate hashes, the
+minimum, maximum and average string length, the version entry, and how much
+of the file is string data.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		info, err := os.Stat(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to access hashtab: %w", err)
+		}
+		entries, err := readHashtab(args[0])
+		if err != nil {
+			return err
+		}
+
+		renderHashtabStats(os.Stdout, args[0], hashtabfile.ComputeStats(entries, info.Size()))
+		return nil
+	},
+}
+
+var (
+	hashtabStrict bool
+
+	convertFrom string
+	convertTo   string
+	grepIgnore  bool
+
+	exportFormat string
+	exportOutput string
+
+	importFormat string
+	importOutput string
+
+	normalizeOutput string
+	normalizeCheck  bool
+
+	scanOutput     string
+	scanIncludeCpp bool
+	scanOSVersion  string
+)
+
+var hashtabConvertCmd = &cobra.Command{
+	Use:   "convert <input> <output>",
+	Short: "Convert a hashtab between encodings",
+	Long: `Convert a hashtab between the canonical big-endian format, little-endian
+files and the v2 revision (header with magic and entry count), as produced by
+different firmware dump tools, and compressed hashlists. The input encoding is
+detected unless --from is given; the output is canonical unless --to is given.`,
+	Example: `  qmdverify hashtab convert dump.bin hashtabs/3.22.0.64-rmpp
+  qmdverify hashtab convert --to little-endian hashtabs/3.22.0.64-rmpp dump.bin`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var from hashtabfile.Encoding
+		if convertFrom != "auto" {
+			enc, err := hashtabfile.ParseEncoding(convertFrom)
+			if err != nil {
+				return err
+			}
+			from = enc
+		}
+		to, err := hashtabfile.ParseEncoding(convertTo)
+		if err != nil {
+			return err
+		}
+
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to read hashtab: %w", err)
+		}
+		entries, from, err := hashtabfile.Decode(data, from)
+		if err != nil {
+			return fmt.Errorf("%s: %w", args[0], err)
+		}
+		if err := hashtabfile.EncodeFile(args[1], entries, to); err != nil {
+			return err
+		}
+
+		fmt.Printf("✓ Converted %d entries from %s (%s) to %s (%s)\n",
+			len(entries), args[0], from, args[1], to)
+		return nil
+	},
+}
+
+var hashtabGrepCmd = &cobra.Command{
+	Use:   "grep <pattern> <hashtab>",
+	Short: "Search the identifier strings of a hashtab",
+	Long: `Print the strings of a hashtab that match a regular expression, with their
+hashes, to discover the exact identifiers available in a firmware.`,
+	Example: `  qmdverify hashtab grep 'Swipe.*' hashtabs/3.22.0.64-rmpp
+  qmdverify hashtab grep -i '^onclicked$' hashtabs/3.22.0.64-rmpp`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pattern := args[0]
+		if grepIgnore {
+			pattern = "(?i)" + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+
+		entries, err := readHashtab(args[1])
+		if err != nil {
+			return err
+		}
+		if hashtabfile.ComputeStats(entries, 0).IsHashlist() {
+			return fmt.Errorf("%s is a hashlist and has no strings to search", args[1])
+		}
+
+		if matches := grepHashtab(os.Stdout, entries, re); matches == 0 {
+			fmt.Fprintf(os.Stderr, "No strings match %q\n", args[0])
+		}
+		return nil
+	},
+}
+
+// grepHashtab prints each distinct entry whose string matches re as
+// "<hash>\t<string>", in file order, and returns how many it printed.
+func grepHashtab(w io.Writer, entries []hashtabfile.Entry, re *regexp.Regexp) int {
+	printed := make(map[hashtabfile.Entry]bool)
+	for _, entry := range entries {
+		if entry.String == "" || printed[entry] || !re.MatchString(entry.String) {
+			continue
+		}
+		printed[entry] = true
+		fmt.Fprintf(w, "%d\t%s\n", entry.Hash, entry.String)
+	}
+	return len(printed)
+}
+
+var hashtabExportCmd = &cobra.Command{
+	Use:   "export <hashtab>",
+	Short: "Export a hashtab as CSV or JSON",
+	Long: `Write every entry of a hashtab as hash,string CSV rows or as a JSON array,
+in file order, for analysis in spreadsheets or other tools.`,
+	Example: `  qmdverify hashtab export hashtabs/3.22.0.64-rmpp --format csv > entries.csv
+  qmdverify hashtab export hashtabs/3.22.0.64-rmpp --format json -o entries.json`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var write func(io.Writer, []hashtabfile.Entry) error
+		switch exportFormat {
+		case hashtabfile.FormatCSV:
+			write = hashtabfile.WriteCSV
+		case hashtabfile.FormatJSON:
+			write = hashtabfile.WriteJSON
+		default:
+			return fmt.Errorf("invalid --format %q: must be csv or json", exportFormat)
+		}
+
+		entries, err := readHashtab(args[0])
+		if err != nil {
+			return err
+		}
+
+		if exportOutput == "" || exportOutput == "-" {
+			return write(os.Stdout, entries)
+		}
+		f, err := os.Create(exportOutput)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		if err := write(f, entries); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to write %s: %w", exportOutput, err)
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "✓ Exported %d entries to %s\n", len(entries), exportOutput)
+		return nil
+	},
+}
+
+var hashtabImportCmd = &cobra.Command{
+	Use:   "import <entries> -o <hashtab>",
+	Short: "Build a hashtab from CSV or JSON entries",
+	Long: `Build a canonical hashtab from hash,string CSV rows or a JSON array of
+{"hash", "string"} objects, as written by 'hashtab export'. Entries without a
+hash are hashed from their string, so newly discovered identifiers can be
+appended by name. The format follows the file extension unless --format is
+given; read from stdin with '-'.`,
+	Example: `  qmdverify hashtab import entries.csv -o hashtabs/3.22.0.64-rmpp
+  qmdverify hashtab import - --format json -o new.hashtab < entries.json`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format := importFormat
+		if format == "" {
+			format = strings.TrimPrefix(strings.ToLower(filepath.Ext(args[0])), ".")
+		}
+
+		var read func(io.Reader) ([]hashtabfile.Entry, error)
+		switch format {
+		case hashtabfile.FormatCSV:
+			read = hashtabfile.ReadCSV
+		case hashtabfile.FormatJSON:
+			read = hashtabfile.ReadJSON
+		default:
+			return fmt.Errorf("cannot tell the format of %s; use --format csv or --format json", args[0])
+		}
+
+		in := os.Stdin
+		if args[0] != "-" {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to open entries: %w", err)
+			}
+			defer f.Close()
+			in = f
+		}
+
+		entries, err := read(in)
+		if err != nil {
+			return fmt.Errorf("%s: %w", args[0], err)
+		}
+		if err := hashtabfile.WriteFile(importOutput, entries); err != nil {
+			return err
+		}
+
+		fmt.Printf("✓ Imported %d entries from %s to %s\n", len(entries), args[0], importOutput)
+		return nil
+	},
+}
+
+var hashtabNormalizeCmd = &cobra.Command{
+	Use:   "normalize <hashtab>",
+	Short: "Sort and deduplicate a hashtab for deterministic builds",
+	Long: `Rewrite a hashtab with its entries sorted by hash and duplicates removed,
+so hashtabs regenerated from the same firmware are byte-identical and can be
+checksummed and diffed. The file is rewritten in place unless -o is given.
+A hash with conflicting strings is an error.
+
+With --check, nothing is written and the command fails if the file is not
+already normalized.`,
+	Example: `  qmdverify hashtab normalize hashtabs/3.22.0.64-rmpp
+  qmdverify hashtab normalize --check hashtabs/*`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if normalizeOutput != "" && len(args) > 1 {
+			return fmt.Errorf("-o can only be used with a single hashtab")
+		}
+
+		var unnormalized []string
+		for _, path := range args {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("failed to read hashtab: %w", err)
+			}
+			read := hashtabfile.Read
+			if hashtabStrict {
+				read = hashtabfile.ReadStrict
+			}
+			entries, err := read(bytes.NewReader(data))
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			normalized, err := hashtabfile.Normalize(entries)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+
+			var buf bytes.Buffer
+			if err := hashtabfile.Write(&buf, normalized); err != nil {
+				return err
+			}
+			changed := !bytes.Equal(buf.Bytes(), data)
+
+			if normalizeCheck {
+				if changed {
+					unnormalized = append(unnormalized, path)
+				}
+				continue
+			}
+
+			output := path
+			if normalizeOutput != "" {
+				output = normalizeOutput
+			}
+			if !changed && output == path {
+				fmt.Printf("✓ %s is already normalized\n", path)
+				continue
+			}
+			if err := hashtabfile.WriteFile(output, normalized); err != nil {
+				return err
+			}
+			fmt.Printf("✓ Normalized %s to %s (%d entries, %d removed)\n",
+				path, output, len(normalized), len(entries)-len(normalized))
+		}
+
+		if len(unnormalized) > 0 {
+			return fmt.Errorf("not normalized: %s", strings.Join(unnormalized, ", "))
+		}
+		return nil
+	},
+}
+
+var hashtabScanCmd = &cobra.Command{
+	Use:   "scan <source-dir> -o <hashtab>",
+	Short: "Build a hashtab from a Qt/QML source tree",
+	Long: `Walk the QML and JavaScript files under a source tree, and with
+--include-cpp the C++ sources, extract the identifiers they define or use,
+hash them and write a normalized hashtab. QML file paths are added relative to
+the source directory (e.g. /qml/Main.qml) so AFFECT statements resolve.
+
+C++ sources are searched for what Qt exposes to QML: properties, invokable
+methods, signals and slots, class and registered type names, and Q_ENUM
+values.`,
+	Example: `  qmdverify hashtab scan ./qt-src --include-cpp --os-version 3.22.0.64 -o hashtabs/3.22.0.64-rmpp`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		result, err := scan.Dir(args[0], scanIncludeCpp)
+		if err != nil {
+			return err
+		}
+		if result.QMLFiles == 0 && result.CppFiles == 0 {
+			return fmt.Errorf("no QML or C++ sources found in %s", args[0])
+		}
+
+		entries, collisions := scannedEntries(result.Strings)
+		for _, c := range collisions {
+			fmt.Fprintf(os.Stderr, "Warning: %q and %q have the same hash %d; keeping %q\n", c[0], c[1], hashtab.DJB2Hash(c[0]), c[0])
+		}
+		if scanOSVersion != "" {
+			entries = append(entries, hashtabfile.Entry{Hash: hashtabfile.VersionHash, String: scanOSVersion})
+		}
+
+		normalized, err := hashtabfile.Normalize(entries)
+		if err != nil {
+			return err
+		}
+		if err := hashtabfile.WriteFile(scanOutput, normalized); err != nil {
+			return err
+		}
+
+		fmt.Printf("✓ Scanned %d QML and %d C++ files, wrote %d entries to %s\n",
+			result.QMLFiles, result.CppFiles, len(normalized), scanOutput)
+		return nil
+	},
+}
+
+// scannedEntries hashes the scanned strings. When two strings share a hash,
+// the one that sorts first is kept and the pair is returned.
+func scannedEntries(strs map[string]bool) ([]hashtabfile.Entry, [][2]string) {
+	sorted := make([]string, 0, len(strs))
+	for s := range strs {
+		sorted = append(sorted, s)
+	}
+	sort.Strings(sorted)
+
+	kept := make(map[uint64]string, len(sorted))
+	var entries []hashtabfile.Entry
+	var collisions [][2]string
+	for _, s := range sorted {
+		hash := hashtab.DJB2Hash(s)
+		if first, ok := kept[hash]; ok {
+			collisions = append(collisions, [2]string{first, s})
+			continue
+		}
+		kept[hash] = s
+		entries = append(entries, hashtabfile.Entry{Hash: hash, String: s})
+	}
+	return entries, collisions
+}
+
+// readHashtab reads a hashtab for the hashtab commands, honouring --strict.
+func readHashtab(path string) ([]hashtabfile.Entry, error) {
+	if hashtabStrict {
+		return hashtabfile.ReadFileStrict(path)
+	}
+	return hashtabfile.ReadFile(path)
+}
+
+func renderHashtabStats(w io.Writer, path string, stats hashtabfile.Stats) {
+	format := "hashtab"
+	if stats.IsHashlist() {
+		format = "hashlist"
+	}
+
+	fmt.Fprintf(w, "File:           %s (%s)\n", path, format)
+	fmt.Fprintf(w, "Entries:        %d (%d unique)\n", stats.Entries, stats.Unique)
+	fmt.Fprintf(w, "Duplicates:     %d hashes (%d with conflicting strings)\n", stats.Duplicates, stats.Conflicts)
+	if !stats.IsHashlist() {
+		fmt.Fprintf(w, "String length:  min %d, max %d, avg %.1f\n", stats.MinLength, stats.MaxLength, stats.AvgLength)
+		fmt.Fprintf(w, "Empty strings:  %d\n", stats.Empty)
+	}
+	if stats.HasVersion {
+		fmt.Fprintf(w, "Version entry:  %q\n", stats.Version)
+	} else {
+		fmt.Fprintln(w, "Version entry:  missing")
+	}
+
+	size := fmt.Sprintf("File size:      %s", formatBytes(stats.Size))
+	if stats.Entries > 0 && stats.Size > 0 {
+		size += fmt.Sprintf(" (%.1f bytes per entry, %.0f%% string data)",
+			float64(stats.Size)/float64(stats.Entries), 100*float64(stats.StringBytes)/float64(stats.Size))
+	}
+	fmt.Fprintln(w, size)
+}
+
+func init() {
+	hashtabCmd.PersistentFlags().BoolVar(&hashtabStrict, "strict", false, "Also reject zero hashes and strings that are not valid UTF-8 when reading hashtabs")
+	hashtabConvertCmd.Flags().StringVar(&convertFrom, "from", "auto", "Input encoding: auto, canonical, little-endian, v2 or compressed")
+	hashtabConvertCmd.Flags().StringVar(&convertTo, "to", string(hashtabfile.Canonical), "Output encoding: canonical, little-endian, v2 or compressed")
+	hashtabGrepCmd.Flags().BoolVarP(&grepIgnore, "ignore-case", "i", false, "Match case-insensitively")
+	hashtabExportCmd.Flags().StringVar(&exportFormat, "format", hashtabfile.FormatCSV, "Export format: csv or json")
+	hashtabExportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "Write to this file instead of stdout")
+	hashtabImportCmd.Flags().StringVar(&importFormat, "format", "", "Input format: csv or json (default: from the file extension)")
+	hashtabImportCmd.Flags().StringVarP(&importOutput, "output", "o", "", "Hashtab file to write")
+	hashtabImportCmd.MarkFlagRequired("output")
+	hashtabNormalizeCmd.Flags().StringVarP(&normalizeOutput, "output", "o", "", "Write to this file instead of rewriting the input")
+	hashtabNormalizeCmd.Flags().BoolVar(&normalizeCheck, "check", false, "Only report whether each hashtab is already normalized")
+	hashtabScanCmd.Flags().StringVarP(&scanOutput, "output", "o", "", "Hashtab file to write")
+	hashtabScanCmd.Flags().BoolVar(&scanIncludeCpp, "include-cpp", false, "Also extract identifiers exposed to QML by C++ sources")
+	hashtabScanCmd.Flags().StringVar(&scanOSVersion, "os-version", "", "Firmware version to store in the hashtab's version entry")
+	hashtabScanCmd.MarkFlagRequired("output")
+
+	hashtabCmd.AddCommand(hashtabStatsCmd)
+	hashtabCmd.AddCommand(hashtabConvertCmd)
+	hashtabCmd.AddCommand(hashtabGrepCmd)
+	hashtabCmd.AddCommand(hashtabExportCmd)
+	hashtabCmd.AddCommand(hashtabImportCmd)
+	hashtabCmd.AddCommand(hashtabNormalizeCmd)
+	hashtabCmd.AddCommand(hashtabScanCmd)
+}
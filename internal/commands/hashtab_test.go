@@ -0,0 +1,311 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/hashtabfile"
+	"github.com/rmitchellscott/rm-qmd-verify/pkg/hashtab"
+)
+
+func TestRenderHashtabStats(t *testing.T) {
+	tests := []struct {
+		name    string
+		stats   hashtabfile.Stats
+		want    []string
+		notWant []string
+	}{
+		{
+			name: "hashtab",
+			stats: hashtabfile.Stats{
+				Entries: 4, Unique: 3, Duplicates: 1, MinLength: 2, MaxLength: 5, AvgLength: 3.5,
+				HasVersion: true, Version: "3.22.4.2", Size: 100, StringBytes: 50,
+			},
+			want: []string{"(hashtab)", "4 (3 unique)", "1 hashes (0 with conflicting strings)", "min 2, max 5, avg 3.5", `"3.22.4.2"`, "25.0 bytes per entry, 50% string data"},
+		},
+		{
+			name:    "hashlist without version",
+			stats:   hashtabfile.Stats{Entries: 2, Unique: 2, Empty: 2, Size: 24},
+			want:    []string{"(hashlist)", "Version entry:  missing", "12.0 bytes per entry, 0% string data"},
+			notWant: []string{"String length"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			renderHashtabStats(&buf, "file.hashtab", tt.stats)
+			for _, want := range tt.want {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("renderHashtabStats() output missing %q:\n%s", want, buf.String())
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(buf.String(), notWant) {
+					t.Errorf("renderHashtabStats() output contains %q:\n%s", notWant, buf.String())
+				}
+			}
+		})
+	}
+}
+
+func TestHashtabConvert(t *testing.T) {
+	dir := t.TempDir()
+	entries := []hashtabfile.Entry{{Hash: 1, String: "id"}, {Hash: hashtabfile.VersionHash, String: "3.22.4.2"}}
+	input := filepath.Join(dir, "dump.bin")
+	if err := hashtabfile.EncodeFile(input, entries, hashtabfile.V2); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() { convertFrom, convertTo = "auto", string(hashtabfile.Canonical) }()
+	convertFrom, convertTo = "auto", string(hashtabfile.Canonical)
+
+	output := filepath.Join(dir, "3.22.4.2-rmpp")
+	if err := hashtabConvertCmd.RunE(nil, []string{input, output}); err != nil {
+		t.Fatalf("hashtabConvertCmd.RunE() error = %v", err)
+	}
+	got, err := hashtabfile.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, entries) {
+		t.Errorf("converted entries = %+v, want %+v", got, entries)
+	}
+
+	convertFrom = string(hashtabfile.LittleEndian)
+	if err := hashtabConvertCmd.RunE(nil, []string{input, output}); err == nil {
+		t.Error("hashtabConvertCmd.RunE() expected error decoding v2 as little-endian")
+	}
+
+	convertFrom, convertTo = "auto", "utf16"
+	if err := hashtabConvertCmd.RunE(nil, []string{input, output}); err == nil {
+		t.Error("hashtabConvertCmd.RunE() expected error for an unknown --to")
+	}
+}
+
+func TestGrepHashtab(t *testing.T) {
+	entries := []hashtabfile.Entry{
+		{Hash: 1, String: "onSwipeLeft"},
+		{Hash: 2, String: "width"},
+		{Hash: 1, String: "onSwipeLeft"},
+		{Hash: 3, String: "SwipeArea"},
+		{Hash: 4},
+	}
+
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{pattern: "Swipe.*", want: "1\tonSwipeLeft\n3\tSwipeArea\n"},
+		{pattern: "^Swipe", want: "3\tSwipeArea\n"},
+		{pattern: "(?i)^WIDTH$", want: "2\twidth\n"},
+		{pattern: "height", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			var buf bytes.Buffer
+			n := grepHashtab(&buf, entries, regexp.MustCompile(tt.pattern))
+			if buf.String() != tt.want || n != strings.Count(tt.want, "\n") {
+				t.Errorf("grepHashtab() = %d, %q, want %q", n, buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestHashtabExport(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "3.22.4.2-rmpp")
+	if err := hashtabfile.WriteFile(input, []hashtabfile.Entry{{Hash: 1, String: "id"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() { exportFormat, exportOutput = hashtabfile.FormatCSV, "" }()
+
+	tests := []struct {
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{format: "csv", want: "hash,string\n1,id\n"},
+		{format: "json", want: "\"hash\": 1"},
+		{format: "xml", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			exportFormat, exportOutput = tt.format, filepath.Join(dir, "out."+tt.format)
+			err := hashtabExportCmd.RunE(nil, []string{input})
+			if tt.wantErr {
+				if err == nil {
+					t.Error("hashtabExportCmd.RunE() expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("hashtabExportCmd.RunE() error = %v", err)
+			}
+			data, err := os.ReadFile(exportOutput)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(data), tt.want) {
+				t.Errorf("exported %q, want it to contain %q", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestHashtabImport(t *testing.T) {
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "entries.csv")
+	if err := os.WriteFile(csvPath, []byte("hash,string\n1,id\n,width\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	txtPath := filepath.Join(dir, "entries.txt")
+	if err := os.WriteFile(txtPath, []byte(`[{"hash": 1, "string": "id"}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() { importFormat, importOutput = "", "" }()
+
+	tests := []struct {
+		name    string
+		input   string
+		format  string
+		want    int
+		wantErr bool
+	}{
+		{name: "csv by extension", input: csvPath, want: 2},
+		{name: "explicit format", input: txtPath, format: "json", want: 1},
+		{name: "unknown extension", input: txtPath, wantErr: true},
+		{name: "wrong format", input: txtPath, format: "csv", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			importFormat, importOutput = tt.format, filepath.Join(dir, "out.hashtab")
+			err := hashtabImportCmd.RunE(nil, []string{tt.input})
+			if tt.wantErr {
+				if err == nil {
+					t.Error("hashtabImportCmd.RunE() expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("hashtabImportCmd.RunE() error = %v", err)
+			}
+			entries, err := hashtabfile.ReadFile(importOutput)
+			if err != nil || len(entries) != tt.want {
+				t.Errorf("imported %d entries (%v), want %d", len(entries), err, tt.want)
+			}
+		})
+	}
+}
+
+func TestHashtabNormalize(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "3.22.4.2-rmpp")
+	if err := hashtabfile.WriteFile(path, []hashtabfile.Entry{{Hash: 3, String: "x"}, {Hash: 1, String: "id"}, {Hash: 3, String: "x"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() { normalizeOutput, normalizeCheck = "", false }()
+
+	normalizeCheck = true
+	if err := hashtabNormalizeCmd.RunE(nil, []string{path}); err == nil {
+		t.Error("hashtabNormalizeCmd.RunE() --check expected error before normalizing")
+	}
+
+	normalizeCheck = false
+	if err := hashtabNormalizeCmd.RunE(nil, []string{path}); err != nil {
+		t.Fatalf("hashtabNormalizeCmd.RunE() error = %v", err)
+	}
+	got, err := hashtabfile.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []hashtabfile.Entry{{Hash: 1, String: "id"}, {Hash: 3, String: "x"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("normalized entries = %+v, want %+v", got, want)
+	}
+
+	normalizeCheck = true
+	if err := hashtabNormalizeCmd.RunE(nil, []string{path}); err != nil {
+		t.Errorf("hashtabNormalizeCmd.RunE() --check error = %v after normalizing", err)
+	}
+
+	normalizeCheck, normalizeOutput = false, filepath.Join(dir, "out")
+	if err := hashtabNormalizeCmd.RunE(nil, []string{path, path}); err == nil {
+		t.Error("hashtabNormalizeCmd.RunE() expected error for -o with several files")
+	}
+}
+
+func TestReadHashtabStrict(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "zero.hashtab")
+	if err := hashtabfile.WriteFile(path, []hashtabfile.Entry{{Hash: 0, String: "x"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() { hashtabStrict = false }()
+	if _, err := readHashtab(path); err != nil {
+		t.Errorf("readHashtab() error = %v", err)
+	}
+	hashtabStrict = true
+	if _, err := readHashtab(path); err == nil || !strings.Contains(err.Error(), "record 0 at byte offset 0") {
+		t.Errorf("readHashtab() with --strict error = %v", err)
+	}
+}
+
+func TestHashtabScan(t *testing.T) {
+	src := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(src, "qml"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "qml", "Main.qml"), []byte("Item { id: root\n  width: 10 }\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "view.h"), []byte("class View : public QObject {\n  Q_OBJECT\n  Q_PROPERTY(int zoom READ zoom)\n};\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(t.TempDir(), "3.22.4.2-rmpp")
+	defer func() { scanOutput, scanIncludeCpp, scanOSVersion = "", false, "" }()
+	scanOutput, scanIncludeCpp, scanOSVersion = out, true, "3.22.4.2"
+
+	if err := hashtabScanCmd.RunE(nil, []string{src}); err != nil {
+		t.Fatalf("hashtabScanCmd.RunE() error = %v", err)
+	}
+	entries, err := hashtabfile.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byHash := make(map[uint64]string)
+	for _, e := range entries {
+		byHash[e.Hash] = e.String
+	}
+	for _, s := range []string{"Item", "width", "/qml/Main.qml", "View", "zoom"} {
+		if byHash[hashtab.DJB2Hash(s)] != s {
+			t.Errorf("scanned hashtab is missing %q", s)
+		}
+	}
+	if byHash[hashtabfile.VersionHash] != "3.22.4.2" {
+		t.Errorf("version entry = %q, want 3.22.4.2", byHash[hashtabfile.VersionHash])
+	}
+
+	if err := hashtabScanCmd.RunE(nil, []string{t.TempDir()}); err == nil {
+		t.Error("hashtabScanCmd.RunE() expected error for a tree without sources")
+	}
+}
+
+func TestScannedEntries(t *testing.T) {
+	// "Aa" and "B@" collide under DJB2.
+	entries, collisions := scannedEntries(map[string]bool{"B@": true, "Aa": true, "id": true})
+	if len(entries) != 2 {
+		t.Errorf("scannedEntries() = %+v, want 2 entries", entries)
+	}
+	if want := [][2]string{{"Aa", "B@"}}; !reflect.DeepEqual(collisions, want) {
+		t.Errorf("scannedEntries() collisions = %v, want %v", collisions, want)
+	}
+}
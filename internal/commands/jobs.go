@@ -0,0 +1,118 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/cache"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/config"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/display"
+	"github.com/spf13/cobra"
+)
+
+var jobsCmd = &cobra.Command{
+	Use:   "jobs",
+	Short: "Inspect stored check job results",
+	Long: `The results of every completed check job are stored in the cache directory
+under jobs/<id>.json as soon as they are fetched, so they survive crashes and
+stay available after the server discards the job. A check's --note is stored
+with them.`,
+}
+
+var jobsListCmd = &cobra.Command{
+	Use:          "list",
+	Short:        "List stored job results, most recent first",
+	Example:      `  qmdverify jobs list`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE:         runJobsList,
+}
+
+var jobsResultsCmd = &cobra.Command{
+	Use:   "results <job-id>",
+	Short: "Print the raw results JSON of a job",
+	Long: `Print the raw results JSON of a job. Stored results are used when present;
+otherwise they are fetched from the server and stored.`,
+	Example:      `  qmdverify jobs results 6f1c2a9e`,
+	SilenceUsage: true,
+	Args:         cobra.ExactArgs(1),
+	RunE:         runJobsResults,
+}
+
+func init() {
+	jobsCmd.AddCommand(jobsListCmd)
+	jobsCmd.AddCommand(jobsResultsCmd)
+}
+
+func runJobsList(cmd *cobra.Command, args []string) error {
+	jobs, err := cache.ListJobResults()
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	renderJobs(os.Stdout, jobs)
+	return nil
+}
+
+func renderJobs(w io.Writer, jobs []cache.JobResults) {
+	if len(jobs) == 0 {
+		fmt.Fprintln(w, "No stored job results")
+		return
+	}
+	for _, job := range jobs {
+		fmt.Fprintf(w, "%s\t%s\t%s", job.JobID, job.FetchedAt.Local().Format("2006-01-02 15:04:05"), job.Server)
+		if job.Note != "" {
+			fmt.Fprintf(w, "\t%s", job.Note)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+func runJobsResults(cmd *cobra.Command, args []string) error {
+	jobID := args[0]
+
+	results, err := jobResults(cmd.Context(), jobID)
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, results, "", "  "); err != nil {
+		buf.Reset()
+		buf.Write(results)
+	}
+	buf.WriteByte('\n')
+	_, err = buf.WriteTo(os.Stdout)
+	return err
+}
+
+// jobResults returns the stored results of a job, fetching and storing them
+// from the server when they aren't stored yet.
+func jobResults(ctx context.Context, jobID string) ([]byte, error) {
+	job, found, err := cache.LoadJobResults(jobID)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		return job.Results, nil
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	body, err := newAPIClient(cfg).GetJobResults(ctx, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("no stored results for job %s, and fetching them failed: %w", jobID, err)
+	}
+	if err := cache.SaveJobResults(cfg.ServerHost, jobID, "", body); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to store results of job %s: %s\n", jobID, err)
+	}
+	return body, nil
+}
@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/cache"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/config"
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+func TestJobResults(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/api/results/job-1" {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(api.ErrorResponse{Error: "job not found"})
+			return
+		}
+		json.NewEncoder(w).Encode(api.ComparisonResponse{TotalChecked: 1})
+	}))
+	defer server.Close()
+
+	t.Setenv(cache.EnvVarCacheDir, t.TempDir())
+	t.Setenv(config.EnvVarHost, server.URL)
+
+	for i := 0; i < 2; i++ {
+		body, err := jobResults(context.Background(), "job-1")
+		if err != nil {
+			t.Fatalf("jobResults(context.Background()) error = %v", err)
+		}
+		var response api.ComparisonResponse
+		if err := json.Unmarshal(body, &response); err != nil || response.TotalChecked != 1 {
+			t.Errorf("jobResults(context.Background()) = %s, %v", body, err)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("server got %d requests, want 1 (the second lookup should use the stored results)", requests)
+	}
+
+	if _, err := jobResults(context.Background(), "job-2"); err == nil || !strings.Contains(err.Error(), "job not found") {
+		t.Errorf("jobResults(context.Background()) error = %v for a job the server discarded", err)
+	}
+}
+
+func TestRenderJobs(t *testing.T) {
+	var buf bytes.Buffer
+	renderJobs(&buf, nil)
+	if !strings.Contains(buf.String(), "No stored job results") {
+		t.Errorf("renderJobs(nil) = %q", buf.String())
+	}
+
+	buf.Reset()
+	fetched := time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)
+	renderJobs(&buf, []cache.JobResults{{JobID: "job-1", Server: "https://qmd.example.com", FetchedAt: fetched}})
+	if want := "job-1\t2026-03-01 12:00:00\thttps://qmd.example.com\n"; buf.String() != want {
+		t.Errorf("renderJobs() = %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	renderJobs(&buf, []cache.JobResults{{JobID: "job-2", Server: "https://qmd.example.com", FetchedAt: fetched, Note: "after migrating to new SwipeArea API"}})
+	if want := "job-2\t2026-03-01 12:00:00\thttps://qmd.example.com\tafter migrating to new SwipeArea API\n"; buf.String() != want {
+		t.Errorf("renderJobs() = %q, want %q", buf.String(), want)
+	}
+}
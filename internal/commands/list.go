@@ -2,10 +2,11 @@ package commands
 
 import (
 	"fmt"
+	"os"
 
-	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/api"
 	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/config"
 	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/display"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/i18n"
 	"github.com/spf13/cobra"
 )
 
@@ -34,35 +35,39 @@ func init() {
 }
 
 func runList(cmd *cobra.Command, args []string) error {
-	cfg := config.Load()
-	client := api.NewClient(cfg.ServerHost)
+	cfg, err := config.Load()
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+	client := newAPIClient(cfg)
 
-	fmt.Printf("Fetching hashtables from %s...\n\n", cfg.ServerHost)
+	fmt.Fprintf(os.Stderr, "%s\n\n", i18n.T(i18n.MsgFetchingHashtables, cfg.ServerHost))
 
-	response, err := client.ListHashtables()
+	response, err := client.ListHashtables(cmd.Context())
 	if err != nil {
-		display.RenderError(fmt.Errorf("failed to list hashtables: %w", err))
+		display.RenderError(os.Stderr, fmt.Errorf("%s: %w", i18n.T(i18n.MsgErrListHashtables), err))
 		return err
 	}
 
-	display.RenderHashtableList(response)
-
-	return nil
+	return display.RenderHashtableList(os.Stdout, response)
 }
 
 func runListTrees(cmd *cobra.Command, args []string) error {
-	cfg := config.Load()
-	client := api.NewClient(cfg.ServerHost)
+	cfg, err := config.Load()
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+	client := newAPIClient(cfg)
 
-	fmt.Printf("Fetching QML trees from %s...\n\n", cfg.ServerHost)
+	fmt.Fprintf(os.Stderr, "%s\n\n", i18n.T(i18n.MsgFetchingTrees, cfg.ServerHost))
 
-	response, err := client.ListTrees()
+	response, err := client.ListTrees(cmd.Context())
 	if err != nil {
-		display.RenderError(fmt.Errorf("failed to list trees: %w", err))
+		display.RenderError(os.Stderr, fmt.Errorf("%s: %w", i18n.T(i18n.MsgErrListTrees), err))
 		return err
 	}
 
-	display.RenderTreeList(response)
-
-	return nil
+	return display.RenderTreeList(os.Stdout, response)
 }
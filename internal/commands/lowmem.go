@@ -0,0 +1,160 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/display"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/i18n"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/policy"
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+// lowMemoryBatch is how many files a --low-memory check uploads at once.
+const lowMemoryBatch = 50
+
+// validateLowMemory rejects the flags that need every file or result at
+// once, which --low-memory never holds.
+func validateLowMemory(structured bool) error {
+	conflicts := []struct {
+		set  bool
+		flag string
+	}{
+		{pickFiles, "--pick"},
+		{len(withDeps) > 0, "--with-dep"},
+		{depsOnly, "--deps-only"},
+		{retryErrors, "--retry-errors"},
+		{attestResults, "--attest"},
+		{signOutputs, "--sign"},
+		{localCheck, "--local"},
+		{hybridCheck, "--hybrid"},
+		{githubActions, "--github"},
+		{concurrency > 1, "--concurrency"},
+		{structured, "--output, --query or an output template"},
+	}
+	for _, c := range conflicts {
+		if c.set {
+			return fmt.Errorf("--low-memory cannot be combined with %s", c.flag)
+		}
+	}
+	return nil
+}
+
+// lowMemoryCheck checks files as they are found, lowMemoryBatch at a time,
+// streaming each batch from disk and printing its results before the next
+// is collected. Only the counts outlive a batch.
+type lowMemoryCheck struct {
+	checker comparer
+	rcs     *rcResolver
+	server  string
+	rules   []policy.Rule
+	failOn  []failSelector
+
+	filePaths     []string
+	relativePaths []string
+
+	files   int
+	batches int
+	failed  int
+}
+
+func (c *lowMemoryCheck) add(ctx context.Context, path, relPath string) error {
+	c.filePaths = append(c.filePaths, path)
+	c.relativePaths = append(c.relativePaths, relPath)
+	if len(c.filePaths) < lowMemoryBatch {
+		return nil
+	}
+	return c.flush(ctx)
+}
+
+func (c *lowMemoryCheck) flush(ctx context.Context) error {
+	if len(c.filePaths) == 0 {
+		return nil
+	}
+	defer func() {
+		c.filePaths, c.relativePaths = nil, nil
+	}()
+
+	if err := preflightFiles(c.filePaths, preflightWorkers); err != nil {
+		return err
+	}
+	c.batches++
+	fmt.Fprintf(os.Stderr, "Batch %d: uploading %d files to %s...\n", c.batches, len(c.filePaths), c.server)
+
+	batchResponse, err := c.checker.CompareQMDFiles(ctx, c.filePaths, c.relativePaths)
+	if err != nil {
+		return fmt.Errorf("%s: %w", i18n.T(i18n.MsgErrCheckFailed), err)
+	}
+
+	var allResponses []*api.ComparisonResponse
+	for _, response := range *batchResponse {
+		allResponses = append(allResponses, &response)
+	}
+	newVersions := trackNewVersions(c.server, allResponses...)
+
+	rootFiles := identifyRootFiles(batchResponse)
+	var filenames []string
+	for filename := range *batchResponse {
+		if rootFiles[filename] && matchesFileFilter(filename, fileFilter) {
+			filenames = append(filenames, filename)
+		}
+	}
+	sort.Strings(filenames)
+
+	for _, filename := range filenames {
+		response := (*batchResponse)[filename]
+		c.files++
+
+		opts, err := c.rcs.options(rootFilePath(filename, c.filePaths, c.relativePaths))
+		if err != nil {
+			return err
+		}
+		filtered := filterResponse(&response, opts.devices, opts.versions)
+		if opts.failedOnly && len(filtered.Incompatible) == 0 {
+			continue
+		}
+
+		fmt.Printf("\n=== %s ===\n\n", filename)
+		if filtered.TotalChecked == 0 {
+			fmt.Fprintln(os.Stderr, i18n.T(i18n.MsgWarnNoFilterMatch))
+			continue
+		}
+		if err := renderResults(os.Stdout, filtered, newVersions); err != nil {
+			return err
+		}
+		trailer.add(filtered)
+
+		failed, err := evaluateOutcome(os.Stdout, &response, filtered, c.rules, c.failOn)
+		if err != nil {
+			return err
+		}
+		if failed {
+			c.failed++
+		}
+	}
+	return nil
+}
+
+func runLowMemoryCheck(ctx context.Context, args []string, c *lowMemoryCheck) error {
+	err := walkQMDFiles(args, func(path, relPath string) error {
+		return c.add(ctx, path, relPath)
+	})
+	if err == nil {
+		err = c.flush(ctx)
+	}
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	if c.files == 0 {
+		err := fmt.Errorf("%s", i18n.T(i18n.MsgErrNoQMDFiles))
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	fmt.Printf("\nChecked %d files in %d batches: %d with incompatibilities\n", c.files, c.batches, c.failed)
+	return checkOutcome{incompatible: c.failed > 0}.err(exitZero, nil)
+}
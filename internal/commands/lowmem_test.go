@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/cache"
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+func TestLowMemoryCheckBatches(t *testing.T) {
+	t.Setenv(cache.EnvVarCacheDir, t.TempDir())
+	defer func() { preflightDigests = nil }()
+
+	dir := t.TempDir()
+	compatible := api.ComparisonResponse{
+		Compatible:   []api.ComparisonResult{{Device: "rmpp", OSVersion: "3.22.0.64", Compatible: true}},
+		TotalChecked: 1,
+	}
+	incompatible := api.ComparisonResponse{
+		Incompatible: []api.ComparisonResult{{Device: "rmpp", OSVersion: "3.22.0.64", ErrorDetail: "missing 1 hash(es)"}},
+		TotalChecked: 1,
+	}
+	checker := &fakeComparer{batch: make(api.BatchComparisonResponse)}
+	files := 2*lowMemoryBatch + 7
+	for i := 0; i < files; i++ {
+		name := fmt.Sprintf("f%03d.qmd", i)
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("AFFECT [[123]]\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		checker.batch[name] = compatible
+		if i%10 == 0 {
+			checker.batch[name] = incompatible
+		}
+	}
+
+	c := &lowMemoryCheck{checker: checker, rcs: newRCResolver(checkCmd), server: "test"}
+	if err := walkQMDFiles([]string{dir}, func(path, relPath string) error {
+		return c.add(context.Background(), path, relPath)
+	}); err != nil {
+		t.Fatalf("walkQMDFiles() error = %v", err)
+	}
+	if err := c.flush(context.Background()); err != nil {
+		t.Fatalf("flush() error = %v", err)
+	}
+
+	var sizes []int
+	for _, batch := range checker.uploaded {
+		sizes = append(sizes, len(batch))
+	}
+	if want := []int{lowMemoryBatch, lowMemoryBatch, 7}; fmt.Sprint(sizes) != fmt.Sprint(want) {
+		t.Errorf("uploaded batches of %v, want %v", sizes, want)
+	}
+	if c.files != files || c.batches != 3 || c.failed != 11 {
+		t.Errorf("checked %d files in %d batches with %d failed, want %d in 3 with 11", c.files, c.batches, c.failed, files)
+	}
+	if len(c.filePaths) != 0 {
+		t.Errorf("%d files left buffered after flush", len(c.filePaths))
+	}
+}
+
+func TestValidateLowMemory(t *testing.T) {
+	defer func() { pickFiles, localCheck, chunkedUpload = false, false, api.ChunkedAuto }()
+
+	tests := []struct {
+		name       string
+		set        func()
+		structured bool
+		wantErr    string
+	}{
+		{name: "plain", set: func() {}},
+		{name: "pick", set: func() { pickFiles = true }, wantErr: "--pick"},
+		{name: "local", set: func() { localCheck = true }, wantErr: "--local"},
+		{name: "structured output", set: func() {}, structured: true, wantErr: "--output"},
+		{name: "chunked", set: func() { chunkedUpload = api.ChunkedAlways }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pickFiles, localCheck, chunkedUpload = false, false, api.ChunkedAuto
+			tt.set()
+			err := validateLowMemory(tt.structured)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("validateLowMemory() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("validateLowMemory() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
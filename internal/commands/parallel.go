@@ -0,0 +1,109 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/local"
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+// parallelComparer splits a batch check into up to workers jobs submitted at
+// once and merges their results. Files that LOAD one another stay in the
+// same job, so dependency results are the same as for a single batch.
+type parallelComparer struct {
+	comparer
+	workers int
+}
+
+func (p *parallelComparer) CompareQMDFiles(ctx context.Context, filePaths []string, relativePaths []string) (*api.BatchComparisonResponse, error) {
+	jobs := splitJobs(local.DependencyGroups(filePaths, relativePaths), p.workers)
+	if len(jobs) <= 1 {
+		return p.comparer.CompareQMDFiles(ctx, filePaths, relativePaths)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	responses := make([]*api.BatchComparisonResponse, len(jobs))
+	errs := make([]error, len(jobs))
+	var wg sync.WaitGroup
+	for i, job := range jobs {
+		paths := make([]string, len(job))
+		rels := make([]string, len(job))
+		for j, index := range job {
+			paths[j], rels[j] = filePaths[index], relativePaths[index]
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			responses[i], errs[i] = p.comparer.CompareQMDFiles(ctx, paths, rels)
+			if errs[i] != nil {
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := firstJobError(errs); err != nil {
+		return nil, err
+	}
+	merged := make(api.BatchComparisonResponse, len(filePaths))
+	for _, response := range responses {
+		for file, result := range *response {
+			merged[file] = result
+		}
+	}
+	return &merged, nil
+}
+
+// firstJobError returns the first error in job order, preferring one that
+// is not the cancellation caused by another job failing.
+func firstJobError(errs []error) error {
+	var canceled error
+	for _, err := range errs {
+		switch {
+		case err == nil:
+		case errors.Is(err, context.Canceled):
+			if canceled == nil {
+				canceled = err
+			}
+		default:
+			return err
+		}
+	}
+	return canceled
+}
+
+// splitJobs packs dependency groups into at most n jobs of similar size,
+// placing the largest groups first. Each job lists its files in their
+// original order, and jobs are ordered by their first file.
+func splitJobs(groups [][]int, n int) [][]int {
+	order := make([]int, len(groups))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return len(groups[order[a]]) > len(groups[order[b]])
+	})
+
+	jobs := make([][]int, min(n, len(groups)))
+	for _, g := range order {
+		smallest := 0
+		for j := range jobs {
+			if len(jobs[j]) < len(jobs[smallest]) {
+				smallest = j
+			}
+		}
+		jobs[smallest] = append(jobs[smallest], groups[g]...)
+	}
+
+	for _, job := range jobs {
+		sort.Ints(job)
+	}
+	sort.Slice(jobs, func(a, b int) bool { return jobs[a][0] < jobs[b][0] })
+	return jobs
+}
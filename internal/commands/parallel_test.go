@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+func TestSplitJobs(t *testing.T) {
+	tests := []struct {
+		name   string
+		groups [][]int
+		n      int
+		want   [][]int
+	}{
+		{name: "one job", groups: [][]int{{0}, {1}, {2}}, n: 1, want: [][]int{{0, 1, 2}}},
+		{name: "round robin", groups: [][]int{{0}, {1}, {2}, {3}}, n: 2, want: [][]int{{0, 2}, {1, 3}}},
+		{name: "fewer groups than workers", groups: [][]int{{0, 1}, {2}}, n: 4, want: [][]int{{0, 1}, {2}}},
+		{name: "large group alone", groups: [][]int{{0}, {1, 2, 3, 4}, {5}, {6}}, n: 2, want: [][]int{{0, 5, 6}, {1, 2, 3, 4}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitJobs(tt.groups, tt.n); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitJobs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParallelComparer(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.qmd":   "LOAD lib.qmd\n",
+		"lib.qmd": "[[1]]\n",
+		"b.qmd":   "[[2]]\n",
+		"c.qmd":   "[[3]]\n",
+	}
+	checker := &fakeComparer{batch: make(api.BatchComparisonResponse)}
+	var paths, rels []string
+	for _, name := range []string{"a.qmd", "b.qmd", "c.qmd", "lib.qmd"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(files[name]), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+		rels = append(rels, name)
+		checker.batch[name] = api.ComparisonResponse{TotalChecked: 1, Mode: name}
+	}
+
+	parallel := &parallelComparer{comparer: checker, workers: 2}
+	response, err := parallel.CompareQMDFiles(context.Background(), paths, rels)
+	if err != nil {
+		t.Fatalf("CompareQMDFiles() error = %v", err)
+	}
+	if !reflect.DeepEqual(*response, checker.batch) {
+		t.Errorf("CompareQMDFiles() = %v, want every file's result", *response)
+	}
+
+	var jobs []string
+	for _, job := range checker.uploaded {
+		jobs = append(jobs, filepath.Join(job...))
+	}
+	sort.Strings(jobs)
+	if want := []string{filepath.Join("a.qmd", "lib.qmd"), filepath.Join("b.qmd", "c.qmd")}; !reflect.DeepEqual(jobs, want) {
+		t.Errorf("uploaded jobs %v, want %v", jobs, want)
+	}
+}
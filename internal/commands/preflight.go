@@ -0,0 +1,98 @@
+package commands
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/bundle"
+)
+
+// sniffLen is how much of a file content sniffing looks at.
+const sniffLen = 512
+
+// preflightDigests holds the SHA-256 of each file pre-validated by this
+// invocation, so reports and the audit log don't hash them again.
+var preflightDigests map[string]string
+
+// preflightFiles checks that every collected file is a non-empty text file
+// and hashes it, with up to workers files in flight. The first failure in
+// file order is returned.
+func preflightFiles(filePaths []string, workers int) error {
+	if workers < 1 {
+		return fmt.Errorf("--workers must be at least 1")
+	}
+
+	sums := make([]string, len(filePaths))
+	errs := make([]error, len(filePaths))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(workers, len(filePaths)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				sums[i], errs[i] = preflightFile(filePaths[i])
+			}
+		}()
+	}
+	for i := range filePaths {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	digests := make(map[string]string, len(filePaths))
+	for i, path := range filePaths {
+		if errs[i] != nil {
+			return errs[i]
+		}
+		digests[path] = sums[i]
+	}
+	preflightDigests = digests
+	return nil
+}
+
+// preflightFile sniffs the start of a file for binary content and returns
+// its SHA-256, reading it once.
+func preflightFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	head = head[:n]
+	if n == 0 {
+		return "", fmt.Errorf("file is empty: %s", path)
+	}
+	if kind := http.DetectContentType(head); !strings.HasPrefix(kind, "text/") {
+		return "", fmt.Errorf("%s is not a QMD file: its content looks like %s", path, strings.Split(kind, ";")[0])
+	}
+
+	h := sha256.New()
+	h.Write(head)
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// cachedSHA256 returns the digest recorded by pre-validation, hashing files
+// it didn't see.
+func cachedSHA256(path string) (string, error) {
+	if sum, ok := preflightDigests[path]; ok {
+		return sum, nil
+	}
+	return bundle.FileSHA256(path)
+}
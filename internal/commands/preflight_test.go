@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/bundle"
+)
+
+func TestPreflightFiles(t *testing.T) {
+	defer func() { preflightDigests = nil }()
+
+	dir := t.TempDir()
+	write := func(name string, content []byte) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	var files []string
+	for i := 0; i < 20; i++ {
+		files = append(files, write(fmt.Sprintf("f%02d.qmd", i), []byte(strings.Repeat("AFFECT [[123]]\n", 100+i))))
+	}
+
+	tests := []struct {
+		name    string
+		files   []string
+		workers int
+		wantErr string
+	}{
+		{name: "one worker", files: files, workers: 1},
+		{name: "more workers than files", files: files, workers: 64},
+		{name: "empty file", files: append([]string{write("empty.qmd", nil)}, files...), workers: 4, wantErr: "file is empty"},
+		{name: "binary file", files: append(files[:2:2], write("zipped.qmd", []byte("PK\x03\x04\x14\x00\x00\x00"))), workers: 4, wantErr: "looks like application/zip"},
+		{name: "missing file", files: []string{filepath.Join(dir, "missing.qmd")}, workers: 1, wantErr: "failed to open"},
+		{name: "no workers", files: files, workers: 0, wantErr: "--workers must be at least 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			preflightDigests = nil
+			err := preflightFiles(tt.files, tt.workers)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("preflightFiles() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("preflightFiles() error = %v", err)
+			}
+			for _, path := range tt.files {
+				want, err := bundle.FileSHA256(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got, _ := cachedSHA256(path); got != want || preflightDigests[path] != want {
+					t.Errorf("digest of %s = %q, want %q", filepath.Base(path), got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package commands
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/cache"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/local"
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+// resultCacheVersion is mixed into every key so a change to what is cached
+// misses old entries.
+const resultCacheVersion = "qmdverify-results/v1"
+
+// cachingComparer reuses the results of files whose content, and that of
+// every file they LOAD, is unchanged since they were last checked against
+// the same hashtables. Files that LOAD one another are cached together,
+// and results with server processing errors are not cached.
+type cachingComparer struct {
+	comparer
+	// inventory identifies the server and the hashtables it checks
+	// against. When it fails, the cache is bypassed.
+	inventory func(context.Context) (string, error)
+	warn      io.Writer
+
+	fingerprint string
+	checked     bool
+}
+
+func (c *cachingComparer) CompareQMD(ctx context.Context, filePath string) (*api.ComparisonResponse, error) {
+	inventory, ok := c.server(ctx)
+	if !ok {
+		return c.comparer.CompareQMD(ctx, filePath)
+	}
+
+	key, err := resultKey(inventory, "file", []string{filePath}, []string{filepath.Base(filePath)})
+	if err == nil {
+		var cached api.ComparisonResponse
+		if found, _ := cache.LoadResults(key, &cached); found {
+			fmt.Fprintf(c.warn, "Using cached results for %s\n\n", filepath.Base(filePath))
+			return &cached, nil
+		}
+	}
+
+	response, err := c.comparer.CompareQMD(ctx, filePath)
+	if err != nil {
+		return nil, err
+	}
+	if key != "" && !hasProcessingError(response) {
+		c.save(key, response)
+	}
+	return response, nil
+}
+
+func (c *cachingComparer) CompareQMDFiles(ctx context.Context, filePaths []string, relativePaths []string) (*api.BatchComparisonResponse, error) {
+	inventory, ok := c.server(ctx)
+	if !ok {
+		return c.comparer.CompareQMDFiles(ctx, filePaths, relativePaths)
+	}
+
+	type miss struct {
+		key  string
+		rels []string
+	}
+	merged := make(api.BatchComparisonResponse, len(filePaths))
+	var misses []miss
+	var missed []int
+	for _, group := range local.DependencyGroups(filePaths, relativePaths) {
+		paths := make([]string, len(group))
+		rels := make([]string, len(group))
+		for i, index := range group {
+			paths[i], rels[i] = filePaths[index], relativePaths[index]
+		}
+
+		key, err := resultKey(inventory, "batch", paths, rels)
+		if err == nil {
+			var cached api.BatchComparisonResponse
+			if found, _ := cache.LoadResults(key, &cached); found && coversFiles(cached, rels) {
+				for _, rel := range rels {
+					merged[rel] = cached[rel]
+				}
+				continue
+			}
+		}
+		misses = append(misses, miss{key: key, rels: rels})
+		missed = append(missed, group...)
+	}
+
+	if hits := len(filePaths) - len(missed); hits > 0 {
+		fmt.Fprintf(c.warn, "Using cached results for %d of %d files\n\n", hits, len(filePaths))
+	}
+	if len(missed) == 0 {
+		return &merged, nil
+	}
+
+	sort.Ints(missed)
+	paths := make([]string, len(missed))
+	rels := make([]string, len(missed))
+	for i, index := range missed {
+		paths[i], rels[i] = filePaths[index], relativePaths[index]
+	}
+	fresh, err := c.comparer.CompareQMDFiles(ctx, paths, rels)
+	if err != nil {
+		return nil, err
+	}
+	for file, result := range *fresh {
+		merged[file] = result
+	}
+
+	for _, m := range misses {
+		if m.key == "" || !coversFiles(*fresh, m.rels) {
+			continue
+		}
+		group := make(api.BatchComparisonResponse, len(m.rels))
+		for _, rel := range m.rels {
+			group[rel] = (*fresh)[rel]
+		}
+		if !batchHasProcessingError(group) {
+			c.save(m.key, group)
+		}
+	}
+	return &merged, nil
+}
+
+// server returns the inventory fingerprint, fetching it on first use. A
+// failure is reported once and disables the cache for the run.
+func (c *cachingComparer) server(ctx context.Context) (string, bool) {
+	if !c.checked {
+		c.checked = true
+		fingerprint, err := c.inventory(ctx)
+		if err != nil {
+			fmt.Fprintf(c.warn, "Warning: not using cached results: %v\n", err)
+		}
+		c.fingerprint = fingerprint
+	}
+	return c.fingerprint, c.fingerprint != ""
+}
+
+func (c *cachingComparer) save(key string, v any) {
+	if err := cache.SaveResults(key, v); err != nil {
+		fmt.Fprintf(c.warn, "Warning: failed to cache results: %v\n", err)
+	}
+}
+
+// hashtableInventory fingerprints the server, organization and every
+// hashtable the server lists, so adding or updating a hashtable misses
+// results cached before.
+func hashtableInventory(ctx context.Context, client *api.Client) (string, error) {
+	hashtables, err := client.ListHashtables(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to list hashtables: %w", err)
+	}
+
+	lines := make([]string, 0, len(hashtables.Hashtables))
+	for _, h := range hashtables.Hashtables {
+		lines = append(lines, fmt.Sprintf("%s %s %s %s %d", h.Name, h.Device, h.OSVersion, h.SHA256, h.EntryCount))
+	}
+	sort.Strings(lines)
+
+	digest := sha256.New()
+	fmt.Fprintf(digest, "%s\n%s\n%s\n", resultCacheVersion, client.BaseURL, client.Org)
+	for _, line := range lines {
+		fmt.Fprintln(digest, line)
+	}
+	return hex.EncodeToString(digest.Sum(nil)), nil
+}
+
+// resultKey digests the inventory with the name and content of each file
+// checked together.
+func resultKey(inventory, kind string, filePaths, names []string) (string, error) {
+	digest := sha256.New()
+	fmt.Fprintf(digest, "%s\n%s\n", inventory, kind)
+	for i, filePath := range filePaths {
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return "", err
+		}
+		sum := sha256.Sum256(data)
+		fmt.Fprintf(digest, "%s %s\n", hex.EncodeToString(sum[:]), names[i])
+	}
+	return hex.EncodeToString(digest.Sum(nil)), nil
+}
+
+func coversFiles(batch api.BatchComparisonResponse, rels []string) bool {
+	for _, rel := range rels {
+		if _, ok := batch[rel]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func batchHasProcessingError(batch api.BatchComparisonResponse) bool {
+	for _, response := range batch {
+		if hasProcessingError(&response) {
+			return true
+		}
+	}
+	return false
+}
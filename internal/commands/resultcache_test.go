@@ -0,0 +1,128 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/cache"
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+func TestCachingComparer(t *testing.T) {
+	t.Setenv(cache.EnvVarCacheDir, t.TempDir())
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.qmd":   "LOAD lib.qmd\n",
+		"lib.qmd": "[[1]]\n",
+		"c.qmd":   "[[2]]\n",
+	}
+	rels := []string{"a.qmd", "c.qmd", "lib.qmd"}
+	var paths []string
+	for _, rel := range rels {
+		path := filepath.Join(dir, rel)
+		if err := os.WriteFile(path, []byte(files[rel]), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+
+	ok := api.ComparisonResponse{Compatible: []api.ComparisonResult{{Device: "rm2", OSVersion: "3.22.0.64", Compatible: true}}, TotalChecked: 1}
+	failed := api.ComparisonResponse{Incompatible: []api.ComparisonResult{{Device: "rm2", OSVersion: "3.22.0.64", ErrorDetail: "verification failed: timeout"}}, TotalChecked: 1}
+	inner := &fakeComparer{batch: api.BatchComparisonResponse{"a.qmd": ok, "c.qmd": failed, "lib.qmd": ok}}
+	inventory := "inventory-1"
+	var warnings bytes.Buffer
+	c := &cachingComparer{
+		comparer:  inner,
+		inventory: func(context.Context) (string, error) { return inventory, nil },
+		warn:      &warnings,
+	}
+
+	check := func(wantUploaded []string) {
+		t.Helper()
+		inner.uploaded = nil
+		batch, err := c.CompareQMDFiles(context.Background(), paths, rels)
+		if err != nil {
+			t.Fatalf("CompareQMDFiles() error = %v", err)
+		}
+		if len(*batch) != len(rels) {
+			t.Errorf("CompareQMDFiles() returned %d results, want %d", len(*batch), len(rels))
+		}
+		var uploaded []string
+		for _, batch := range inner.uploaded {
+			uploaded = append(uploaded, batch...)
+		}
+		if !reflect.DeepEqual(uploaded, wantUploaded) {
+			t.Errorf("uploaded %v, want %v", uploaded, wantUploaded)
+		}
+	}
+
+	check([]string{"a.qmd", "c.qmd", "lib.qmd"})
+	// c.qmd's processing error is not cached.
+	check([]string{"c.qmd"})
+	if !strings.Contains(warnings.String(), "Using cached results for 2 of 3 files") {
+		t.Errorf("warnings = %q, want a cache hit count", warnings.String())
+	}
+
+	// A change to a dependency misses the files that LOAD it.
+	if err := os.WriteFile(paths[2], []byte("[[3]]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	check([]string{"a.qmd", "c.qmd", "lib.qmd"})
+
+	// New hashtables miss everything.
+	c.checked, inventory = false, "inventory-2"
+	check([]string{"a.qmd", "c.qmd", "lib.qmd"})
+
+	// A failed inventory bypasses the cache.
+	c.checked = false
+	c.inventory = func(context.Context) (string, error) { return "", errors.New("offline") }
+	check([]string{"a.qmd", "c.qmd", "lib.qmd"})
+	if !strings.Contains(warnings.String(), "not using cached results: offline") {
+		t.Errorf("warnings = %q, want the inventory error", warnings.String())
+	}
+}
+
+func TestCachingComparer_Single(t *testing.T) {
+	t.Setenv(cache.EnvVarCacheDir, t.TempDir())
+	path := filepath.Join(t.TempDir(), "mod.qmd")
+	if err := os.WriteFile(path, []byte("[[1]]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	inner := &countingComparer{response: api.ComparisonResponse{TotalChecked: 1}}
+	c := &cachingComparer{
+		comparer:  inner,
+		inventory: func(context.Context) (string, error) { return "inventory", nil },
+		warn:      &bytes.Buffer{},
+	}
+	for range 2 {
+		response, err := c.CompareQMD(context.Background(), path)
+		if err != nil {
+			t.Fatalf("CompareQMD() error = %v", err)
+		}
+		if response.TotalChecked != 1 {
+			t.Errorf("CompareQMD() = %+v", response)
+		}
+	}
+	if inner.calls != 1 {
+		t.Errorf("checked the file %d times, want 1", inner.calls)
+	}
+}
+
+type countingComparer struct {
+	comparer
+	response api.ComparisonResponse
+	calls    int
+}
+
+func (c *countingComparer) CompareQMD(context.Context, string) (*api.ComparisonResponse, error) {
+	c.calls++
+	response := c.response
+	return &response, nil
+}
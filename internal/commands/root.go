@@ -1,18 +1,88 @@
 package commands
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/cache"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/config"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/discovery"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/display"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/i18n"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/local"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/mirror"
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
 	"github.com/spf13/cobra"
 )
 
 var (
-	verbose       bool
-	deviceFilter  []string
-	versionFilter []string
-	fileFilter    []string
-	failedOnly    bool
+	verbose          bool
+	deviceFilter     []string
+	versionFilter    []string
+	fileFilter       []string
+	failedOnly       bool
+	langFlag         string
+	timeline         bool
+	showTotals       bool
+	showLegend       bool
+	sortVersions     string
+	incompatibleOnly bool
+	latestVersions   int
+	transposeMatrix  bool
+	runNote          string
+	deviceOrder      []string
+	outputFormat     string
+	outputFlags      []string
+	formatTmpl       string
+	templateFile     string
+	queryExpr        string
+	porcelain        bool
+	attestResults    bool
+	attestKey        string
+	signOutputs      bool
+	profileFlag      string
+	orgFlag          string
+	tokenFlag        string
+	mirrorFlags      []string
+	discoverFlag     string
+	localCheck       bool
+	hashtableDir     string
+	checkBundle      string
+	checkBundleKey   string
+	bundleNoVerify   bool
+	hybridCheck      bool
+	chunkedUpload    string
+	limitRate        string
+	pickFiles        bool
+	symbolsMode      string
+	symbolGlyphs     display.Glyphs
+	localMode        string
+	maxDepth         int
+	preflightWorkers int
+	concurrency      int
+	lowMemory        bool
+	printTrailer     bool
+	githubActions    bool
+	noRecursive      bool
+	fileList         string
+	retryErrors      bool
+	httpRetries      int
+	retryMaxDelay    time.Duration
+	retryUploads     bool
+	depsOnly         bool
+	withDeps         []string
+	requireDeps      bool
+	depsAsWarnings   bool
+	noCache          bool
+	failOnFlags      []string
+	exitZero         bool
 )
 
 var rootCmd = &cobra.Command{
@@ -27,34 +97,246 @@ and OS versions.`,
   qmdverify myfile.qmd --verbose
   qmdverify --device rmpp myfile.qmd
   qmdverify --device rmpp --version 3.22 myfile.qmd
+  qmdverify --timeline myfile.qmd
   qmdverify list
   qmdverify version`,
 	SilenceUsage: true,
 	Args:         cobra.MaximumNArgs(1),
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		config.SetProfile(profileFlag)
+		config.SetOrg(orgFlag)
+		config.SetToken(tokenFlag)
+		config.SetMirrors(mirrorFlags)
+		if httpRetries < 0 {
+			err := fmt.Errorf("--retries must not be negative")
+			display.RenderError(os.Stderr, err)
+			return err
+		}
+		if err := discoverServer(); err != nil {
+			display.RenderError(os.Stderr, err)
+			return err
+		}
+		beginInvocation(cmd)
+		if langFlag != "" {
+			return i18n.SetLanguage(langFlag)
+		}
+		return i18n.SetLanguage(i18n.Detect())
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if len(args) == 1 {
+		if len(args) == 1 || fileList != "" {
 			return checkCmd.RunE(cmd, args)
 		}
 		return cmd.Help()
 	},
 }
 
+// Execute runs the root command and exits with the code ExitCode maps its
+// error to. An interrupt or SIGTERM cancels the command's context,
+// abandoning in-flight uploads and job polling.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err := rootCmd.ExecuteContext(ctx)
+	interrupted := ctx.Err() != nil
+	stop()
+	if err != nil {
+		code := ExitCode(err)
+		if interrupted {
+			code = 130
+		}
+		var checkErr *CheckError
+		if errors.As(err, &checkErr) {
+			finishInvocation(code, nil)
+		} else {
+			finishInvocation(code, err)
+			fmt.Fprintln(os.Stderr, err)
+		}
+		os.Exit(code)
+	}
+	finishInvocation(0, nil)
+}
+
+func beginInvocation(cmd *cobra.Command) {
+	beginAudit(cmd)
+	beginTelemetry(cmd)
+}
+
+func finishInvocation(exitCode int, err error) {
+	writeTrailer(os.Stdout, exitCode)
+	finishAudit(exitCode, err)
+	recordTelemetry(err)
+}
+
+func retryPolicy() api.RetryPolicy {
+	return api.RetryPolicy{Retries: httpRetries, MaxDelay: retryMaxDelay, Uploads: retryUploads}
+}
+
+func newAPIClient(cfg *config.Config) *api.Client {
+	client := api.NewClient(cfg.ServerHost)
+	client.Token = cfg.Token
+	client.Org = cfg.Org
+	client.RefreshToken = cfg.RefreshToken
+	client.TokenExpiry = cfg.TokenExpiry
+	client.Retry = retryPolicy()
+	client.OnTokenRefresh = func(token *api.TokenResponse) {
+		var expiresAt time.Time
+		if token.ExpiresIn > 0 {
+			expiresAt = time.Now().UTC().Add(time.Duration(token.ExpiresIn) * time.Second)
+		}
+		if err := config.UpdateToken(cfg.Profile, token.AccessToken, token.RefreshToken, expiresAt); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to save refreshed token: %s\n", err)
+		}
+	}
+	if jar, err := config.LoadCookieJar(cfg.Profile); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to load cookies: %s\n", err)
+	} else {
+		jar.OnSaveError = func(err error) {
+			fmt.Fprintf(os.Stderr, "Warning: failed to save cookies: %s\n", err)
+		}
+		client.HTTPClient.Jar = jar
+	}
+	client.OnJobResults = func(jobID string, body []byte) {
+		if err := cache.SaveJobResults(cfg.ServerHost, jobID, runNote, body); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to store results of job %s: %s\n", jobID, err)
+		}
+	}
+	return client
+}
+
+// discoverServer resolves --discover or QMDVERIFY_DISCOVER, unless
+// QMDVERIFY_HOST names the server explicitly.
+func discoverServer() error {
+	domain := discoverFlag
+	if domain == "" {
+		domain = os.Getenv(discovery.EnvVarDomain)
+	}
+	if domain == "" || os.Getenv(config.EnvVarHost) != "" {
+		return nil
+	}
+
+	host, err := discovery.Discover(domain)
+	if err != nil {
+		return fmt.Errorf("server discovery failed: %w", err)
+	}
+	config.SetDiscoveredHost(host)
+	return nil
+}
+
+// comparer checks files against one server or a merged set of mirrors.
+type comparer interface {
+	CompareQMD(ctx context.Context, filePath string) (*api.ComparisonResponse, error)
+	CompareQMDFiles(ctx context.Context, filePaths []string, relativePaths []string) (*api.BatchComparisonResponse, error)
+}
+
+// newComparer returns the primary client, or a mirror set when mirrors are
+// configured. Credentials are only sent to the primary server.
+func newComparer(cfg *config.Config, primary *api.Client) comparer {
+	if len(cfg.Mirrors) == 0 {
+		return primary
+	}
+
+	set := &mirror.Set{
+		Clients: []*api.Client{primary},
+		OnError: func(err error) {
+			fmt.Fprintf(os.Stderr, "Warning: mirror skipped: %s\n", err)
+		},
+		OnConflict: func(c mirror.Conflict) {
+			target := fmt.Sprintf("%s %s", c.Device, c.OSVersion)
+			if c.File != "" {
+				target = c.File + ": " + target
+			}
+			fmt.Fprintf(os.Stderr, "Warning: servers disagree on %s (compatible on %s; incompatible on %s)\n",
+				target, strings.Join(c.Compatible, ", "), strings.Join(c.Incompatible, ", "))
+		},
+	}
+	for _, host := range cfg.Mirrors {
+		client := api.NewClient(host)
+		client.Org = cfg.Org
+		client.Retry = primary.Retry
+		set.Clients = append(set.Clients, client)
 	}
+	return set
 }
 
 func init() {
+	rootCmd.PersistentFlags().StringVar(&langFlag, "lang", "", "Output language (en, de, fr). Defaults to QMDVERIFY_LANG or LANG")
+	rootCmd.PersistentFlags().StringVar(&profileFlag, "profile", "", "Credential profile to use. Defaults to QMDVERIFY_PROFILE or 'default'")
+	rootCmd.PersistentFlags().StringVar(&orgFlag, "org", "", "Organization sent to multi-tenant servers. Defaults to QMDVERIFY_ORG or the profile's organization")
+	rootCmd.PersistentFlags().StringVar(&tokenFlag, "token", "", "Bearer token sent to the server on every request. Defaults to QMDVERIFY_TOKEN or the profile's stored token")
+	rootCmd.PersistentFlags().StringVar(&discoverFlag, "discover", "", "Find the server for this domain via DNS SRV or /.well-known/qmdverify. Defaults to QMDVERIFY_DISCOVER")
+	rootCmd.PersistentFlags().StringSliceVar(&mirrorFlags, "mirror", nil, "Additional server whose results are merged with the primary's (can be repeated). Defaults to QMDVERIFY_MIRRORS")
+	rootCmd.PersistentFlags().IntVar(&httpRetries, "retries", 2, "Retry requests that fail with a network error or 5xx response this many times, with exponential backoff")
+	rootCmd.PersistentFlags().DurationVar(&retryMaxDelay, "retry-max-delay", 10*time.Second, "Longest pause between retries")
+	rootCmd.PersistentFlags().BoolVar(&retryUploads, "retry-uploads", false, "Also retry failed file uploads, which may submit a job twice")
+	rootCmd.PersistentFlags().BoolVar(&noAudit, "no-audit", false, "Do not write this invocation to the audit log (QMDVERIFY_AUDIT_LOG)")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Show detailed error messages for incompatible devices")
 	rootCmd.Flags().StringSliceVarP(&deviceFilter, "device", "d", nil, "Filter by device (can be repeated: rm1, rm2, rmpp, rmppm)")
 	rootCmd.Flags().StringSliceVar(&versionFilter, "version", nil, "Filter by version prefix (can be repeated, e.g., 3.22 or 3.22.4.2)")
 	rootCmd.Flags().StringSliceVarP(&fileFilter, "file", "f", nil, "Filter output to specific files (can be repeated, supports glob patterns)")
 	rootCmd.Flags().BoolVar(&failedOnly, "failed-only", false, "Only show files with incompatibilities")
+	rootCmd.Flags().StringVar(&fileList, "file-list", "", "Read newline-separated files to check from this file ('-' for stdin)")
+	rootCmd.Flags().StringVar(&runNote, "note", "", "Note saved with the run's stored results and shown by jobs list, e.g. what changed since the last run")
+	rootCmd.Flags().BoolVar(&pickFiles, "pick", false, "Choose which of the collected files to check with an interactive fuzzy finder")
+	rootCmd.Flags().StringVar(&symbolsMode, "symbols", display.SymbolsDefault, "Status indicators: default (✓ ✗ —), safe (colorblind-friendly OK/FAIL/N/A), emoji (✅ ❌ ➖) or plain (Y N -)")
+	rootCmd.Flags().IntVar(&preflightWorkers, "workers", runtime.NumCPU(), "Number of files to sniff and hash at once before upload")
+	rootCmd.Flags().IntVar(&concurrency, "concurrency", 1, "Split batch checks into up to this many jobs uploaded at once, keeping files that LOAD each other together")
+	rootCmd.Flags().BoolVar(&lowMemory, "low-memory", false, "Find, hash and upload files in batches as they are found, streaming each from disk, to bound memory on very large trees")
+	rootCmd.Flags().BoolVar(&printTrailer, "trailer", false, "End text output with a QMDVERIFY_RESULT line of counts and the exit code for scripts")
+	rootCmd.Flags().BoolVar(&githubActions, "github", false, "Append the verdict, counts and oldest compatible versions to $GITHUB_OUTPUT and a Markdown matrix to $GITHUB_STEP_SUMMARY")
+	rootCmd.Flags().IntVar(&maxDepth, "max-depth", -1, "Only descend this many directory levels below each directory argument (0 = top level only)")
+	rootCmd.Flags().BoolVar(&noRecursive, "no-recursive", false, "Only check files directly in each directory argument (same as --max-depth 0)")
+	rootCmd.Flags().BoolVar(&timeline, "timeline", false, "Show a per-device firmware timeline instead of the matrix")
+	rootCmd.Flags().BoolVar(&showTotals, "totals", false, "Add per-device and per-version pass counts to the matrix")
+	rootCmd.Flags().BoolVar(&incompatibleOnly, "incompatible-only", false, "Hide matrix rows where every device is compatible (totals and the summary still count every version)")
+	rootCmd.Flags().IntVar(&latestVersions, "latest", 0, "Only show the newest N OS versions of each device in the matrix (0 = all)")
+	rootCmd.Flags().BoolVar(&transposeMatrix, "transpose", false, "List devices as rows and OS versions as columns in the matrix")
+	rootCmd.Flags().StringVar(&sortVersions, "sort-versions", sortDesc, "Order matrix rows by version: desc (newest first) or asc")
+	rootCmd.Flags().StringSliceVar(&deviceOrder, "device-order", nil, "Devices to show first in the matrix, in this order (e.g. rmpp,rm2)")
+	rootCmd.Flags().BoolVar(&showLegend, "legend", false, "Print a legend explaining the matrix symbols")
+	rootCmd.Flags().StringArrayVar(&outputFlags, "output", []string{outputText}, "Output format (text, json, toltec, porcelain, cyclonedx, sarif, or an installed qmdverify-format-* plugin). Use format=path to also write a format to a file (can be repeated)")
+	rootCmd.Flags().StringVar(&formatTmpl, "format-template", "", "Render each file's results through a Go text/template")
+	rootCmd.Flags().StringVar(&templateFile, "template-file", "", "Read the --format-template from a file")
+	rootCmd.Flags().StringVar(&queryExpr, "query", "", "Print values selected from each file's JSON result with a jq-style path (e.g. '.incompatible[].os_version')")
+	rootCmd.Flags().BoolVar(&porcelain, "porcelain", false, "Stable tab-separated output for scripts (same as --output porcelain)")
+	rootCmd.Flags().BoolVar(&attestResults, "attest", false, "Write a signed attestation of the results next to each root file (requires --key)")
+	rootCmd.Flags().StringVar(&attestKey, "key", "", "PEM private key used to sign attestations and --sign-outputs reports")
+	rootCmd.Flags().BoolVar(&signOutputs, "sign-outputs", false, "Write a signed provenance record next to each --output format=path file (requires --key)")
+	rootCmd.Flags().BoolVar(&localCheck, "local", false, "Check against the hashtables mirrored by 'qmdverify sync' instead of the server")
+	rootCmd.Flags().StringVar(&hashtableDir, "hashtable-dir", "", "Check against the hashtable and hashlist files in this directory instead of the sync mirror (implies --local unless --hybrid is set)")
+	rootCmd.Flags().StringVar(&checkBundle, "bundle", "", "Check against the hashtables (and with --mode tree, the trees) in this offline bundle instead of the sync mirror (implies --local unless --hybrid is set)")
+	rootCmd.Flags().StringVar(&checkBundleKey, "bundle-key", "", "PEM public key the --bundle manifest must be signed with")
+	rootCmd.Flags().BoolVar(&bundleNoVerify, "bundle-unsigned", false, "Use the --bundle without verifying its manifest signature")
+	rootCmd.Flags().StringVar(&localMode, "mode", local.ModeHashtable, "Local validation mode with --local: hashtable, or tree to also check against downloaded QML trees")
+	rootCmd.Flags().StringArrayVar(&withDeps, "with-dep", nil, "Upload this dependency file along with the checked files (can be repeated)")
+	rootCmd.Flags().BoolVar(&requireDeps, "require-deps", false, "Fail instead of warning when a checked file LOADs a file that is not part of the upload")
+	rootCmd.Flags().BoolVar(&depsAsWarnings, "deps-as-warnings", false, "Report incompatibilities caused only by dependency files as warnings (exit code 2 instead of 1)")
+	rootCmd.Flags().BoolVar(&depsOnly, "deps-only", false, "Only report on files that other checked files depend on, skipping root overlays")
+	rootCmd.Flags().BoolVar(&retryErrors, "retry-errors", false, "Resubmit files whose results carry server processing errors once and merge the retried results")
+	rootCmd.Flags().BoolVar(&hybridCheck, "hybrid", false, "Check against the local mirror first and only upload files that pass at least one mirrored hashtable")
+	rootCmd.Flags().StringVar(&limitRate, "limit-rate", "", "Limit upload bandwidth to this many bytes per second (e.g. 500K, 2M)")
+	rootCmd.Flags().StringArrayVar(&failOnFlags, "fail-on", nil, "Only fail on incompatibilities with these devices and versions, e.g. device=rmpp,version=3.22 (can be repeated)")
+	rootCmd.Flags().BoolVar(&exitZero, "exit-zero", false, "Exit 0 even when incompatibilities are found or nothing was checked (server errors still exit 3)")
+	rootCmd.Flags().BoolVar(&noCache, "no-cache", false, "Check every file against the server instead of reusing cached results for unchanged files")
+	rootCmd.Flags().StringVar(&chunkedUpload, "chunked-upload", api.ChunkedAuto, "Upload large batches in resumable chunks: auto (above 64MB, when the server supports it), always or never")
 
 	rootCmd.AddCommand(checkCmd)
 	rootCmd.AddCommand(listCmd)
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(hashlistCmd)
+	rootCmd.AddCommand(hashtabCmd)
+	rootCmd.AddCommand(watchCmd)
+	rootCmd.AddCommand(watchHashtablesCmd)
+	rootCmd.AddCommand(subscribeCmd)
+	rootCmd.AddCommand(stampCmd)
+	rootCmd.AddCommand(verifyAttestationCmd)
+	rootCmd.AddCommand(verifyReportCmd)
+	rootCmd.AddCommand(authCmd)
+	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(telemetryCmd)
+	rootCmd.AddCommand(assertCmd)
+	rootCmd.AddCommand(bundleCmd)
+	rootCmd.AddCommand(syncCmd)
+	rootCmd.AddCommand(treeCmd)
+	rootCmd.AddCommand(cacheCmd)
+	rootCmd.AddCommand(jobsCmd)
+	rootCmd.AddCommand(diffCmd)
 }
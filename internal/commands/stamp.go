@@ -0,0 +1,236 @@
+package commands
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/bundle"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/config"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/display"
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+const stampSuffix = ".compat.json"
+
+var stampCmd = &cobra.Command{
+	Use:   "stamp [file.qmd...] [directory]",
+	Short: "Write a compatibility manifest next to each QMD file",
+	Long: `Check compatibility and write <file>.qmd.compat.json next to each root file.
+The manifest records the file's SHA-256, the verified device/version matrix,
+fingerprints of the server hashtables it was checked against, the time of
+verification and the CLI version, so it can ship alongside the overlay as
+proof of verification.
+
+Hashtable fingerprints are SHA-256 digests of the metadata the server reports
+for each hashtable (name, OS version, device and entry count).`,
+	Example: `  qmdverify stamp myfile.qmd
+  qmdverify stamp ./qmd-files/`,
+	SilenceUsage: true,
+	Args:         cobra.MinimumNArgs(1),
+	RunE:         runStamp,
+}
+
+type compatStamp struct {
+	File          string                     `json:"file"`
+	SHA256        string                     `json:"sha256"`
+	Server        string                     `json:"server"`
+	ServerVersion string                     `json:"server_version,omitempty"`
+	CLIVersion    string                     `json:"cli_version"`
+	VerifiedAt    time.Time                  `json:"verified_at"`
+	Matrix        map[string]map[string]bool `json:"matrix"`
+	Hashtables    []stampHashtable           `json:"hashtables"`
+}
+
+type stampHashtable struct {
+	Name        string `json:"name"`
+	OSVersion   string `json:"os_version"`
+	Device      string `json:"device"`
+	EntryCount  int    `json:"entry_count,omitempty"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+func runStamp(cmd *cobra.Command, args []string) error {
+	filePaths, relativePaths, err := collectQMDFiles(args)
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	auditFiles(filePaths)
+
+	cfg, err := config.Load()
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+	client := newAPIClient(cfg)
+
+	fmt.Fprintf(os.Stderr, "Checking %d file(s) against %s...\n", len(filePaths), cfg.ServerHost)
+
+	results, err := compareFiles(cmd.Context(), newComparer(cfg, client), filePaths, relativePaths)
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	stamps, err := newStampContext(cmd.Context(), client, cfg.ServerHost)
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	for _, filename := range sortedResultNames(results) {
+		path := rootFilePath(filename, filePaths, relativePaths)
+
+		stamp, err := stamps.stamp(path, results[filename])
+		if err != nil {
+			display.RenderError(os.Stderr, err)
+			return err
+		}
+
+		if err := writeStamp(path+stampSuffix, stamp); err != nil {
+			display.RenderError(os.Stderr, err)
+			return err
+		}
+
+		display.RenderSuccess(os.Stdout, fmt.Sprintf("✓ Wrote %s", filename+stampSuffix))
+	}
+
+	return nil
+}
+
+// stampContext holds the server details shared by every manifest written in
+// one run.
+type stampContext struct {
+	server        string
+	serverVersion string
+	hashtables    []api.HashtableInfo
+	verifiedAt    time.Time
+}
+
+func newStampContext(ctx context.Context, client *api.Client, server string) (*stampContext, error) {
+	hashtables, err := client.ListHashtables(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list hashtables: %w", err)
+	}
+
+	stamps := &stampContext{
+		server:     server,
+		hashtables: hashtables.Hashtables,
+		verifiedAt: time.Now().UTC(),
+	}
+	if v, err := client.GetVersion(ctx); err == nil {
+		stamps.serverVersion = v.Version
+	}
+
+	return stamps, nil
+}
+
+func (c *stampContext) stamp(path string, response *api.ComparisonResponse) (compatStamp, error) {
+	sum, err := bundle.FileSHA256(path)
+	if err != nil {
+		return compatStamp{}, err
+	}
+
+	stamp := buildStamp(response, c.hashtables)
+	stamp.File = filepath.Base(path)
+	stamp.SHA256 = sum
+	stamp.Server = c.server
+	stamp.ServerVersion = c.serverVersion
+	stamp.CLIVersion = Version
+	stamp.VerifiedAt = c.verifiedAt
+
+	return stamp, nil
+}
+
+func sortedResultNames(results map[string]*api.ComparisonResponse) []string {
+	filenames := make([]string, 0, len(results))
+	for filename := range results {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+	return filenames
+}
+
+// rootFilePath maps a result's relative filename back to the path it was
+// collected from.
+func rootFilePath(filename string, filePaths, relativePaths []string) string {
+	for i, rel := range relativePaths {
+		if rel == filename {
+			return filePaths[i]
+		}
+	}
+	return filename
+}
+
+func buildStamp(response *api.ComparisonResponse, hashtables []api.HashtableInfo) compatStamp {
+	known := make(map[string]api.HashtableInfo, len(hashtables))
+	for _, ht := range hashtables {
+		known[hashtableKey(ht)] = ht
+	}
+
+	stamp := compatStamp{
+		Matrix:     make(map[string]map[string]bool),
+		Hashtables: make([]stampHashtable, 0),
+	}
+
+	used := make(map[string]bool)
+	for _, results := range [][]api.ComparisonResult{response.Compatible, response.Incompatible} {
+		for _, result := range results {
+			if stamp.Matrix[result.Device] == nil {
+				stamp.Matrix[result.Device] = make(map[string]bool)
+			}
+			stamp.Matrix[result.Device][result.OSVersion] = result.Compatible
+
+			ht, ok := known[result.Hashtable]
+			if !ok {
+				ht = api.HashtableInfo{Name: result.Hashtable, OSVersion: result.OSVersion, Device: result.Device}
+			}
+			key := hashtableKey(ht)
+			if used[key] {
+				continue
+			}
+			used[key] = true
+
+			stamp.Hashtables = append(stamp.Hashtables, stampHashtable{
+				Name:        ht.Name,
+				OSVersion:   ht.OSVersion,
+				Device:      ht.Device,
+				EntryCount:  ht.EntryCount,
+				Fingerprint: hashtableFingerprint(ht),
+			})
+		}
+	}
+
+	sort.Slice(stamp.Hashtables, func(i, j int) bool {
+		return stamp.Hashtables[i].Name < stamp.Hashtables[j].Name
+	})
+
+	return stamp
+}
+
+func hashtableFingerprint(ht api.HashtableInfo) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\n%s\n%s\n%d", ht.Name, ht.OSVersion, ht.Device, ht.EntryCount)))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func writeStamp(path string, stamp compatStamp) error {
+	data, err := json.MarshalIndent(stamp, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	return nil
+}
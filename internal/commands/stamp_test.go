@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"testing"
+
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+func TestBuildStamp(t *testing.T) {
+	response := &api.ComparisonResponse{
+		Compatible: []api.ComparisonResult{
+			{Hashtable: "3.22.0.64-rmpp", OSVersion: "3.22.0.64", Device: "rmpp", Compatible: true},
+		},
+		Incompatible: []api.ComparisonResult{
+			{Hashtable: "3.23.0.64-rmpp", OSVersion: "3.23.0.64", Device: "rmpp", Compatible: false},
+		},
+	}
+	hashtables := []api.HashtableInfo{
+		{Name: "3.22.0.64-rmpp", OSVersion: "3.22.0.64", Device: "rmpp", EntryCount: 11000},
+		{Name: "3.20.0.92-rmpp", OSVersion: "3.20.0.92", Device: "rmpp", EntryCount: 10000},
+	}
+
+	stamp := buildStamp(response, hashtables)
+
+	if !stamp.Matrix["rmpp"]["3.22.0.64"] || stamp.Matrix["rmpp"]["3.23.0.64"] {
+		t.Errorf("Matrix = %v", stamp.Matrix)
+	}
+
+	if len(stamp.Hashtables) != 2 {
+		t.Fatalf("Hashtables = %+v, want the two used hashtables", stamp.Hashtables)
+	}
+	if stamp.Hashtables[0].Name != "3.22.0.64-rmpp" || stamp.Hashtables[0].EntryCount != 11000 {
+		t.Errorf("Hashtables[0] = %+v", stamp.Hashtables[0])
+	}
+	if stamp.Hashtables[1].Name != "3.23.0.64-rmpp" || stamp.Hashtables[1].Fingerprint == "" {
+		t.Errorf("Hashtables[1] = %+v", stamp.Hashtables[1])
+	}
+}
+
+func TestHashtableFingerprint(t *testing.T) {
+	a := api.HashtableInfo{Name: "3.22.0.64-rmpp", OSVersion: "3.22.0.64", Device: "rmpp", EntryCount: 11000}
+	b := a
+	b.EntryCount = 11001
+
+	if hashtableFingerprint(a) != hashtableFingerprint(a) {
+		t.Error("fingerprint is not stable")
+	}
+	if hashtableFingerprint(a) == hashtableFingerprint(b) {
+		t.Error("fingerprint does not change with entry count")
+	}
+}
@@ -0,0 +1,302 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/cache"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/config"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/osversion"
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+const (
+	subscribeVersionsFile = "subscribe-versions.json"
+	subscribeFailuresFile = "subscribe-failures.json"
+
+	// subscribeMaxAttempts is how many polls re-check a new version before
+	// a failing re-check or hook is given up on.
+	subscribeMaxAttempts = 3
+)
+
+var (
+	subscribeInterval time.Duration
+	subscribeHook     string
+	subscribeOnce     bool
+)
+
+var subscribeCmd = &cobra.Command{
+	Use:   "subscribe [file.qmd...] [directory]",
+	Short: "Re-check overlays whenever the server gains a new OS version",
+	Long: `Keep running and poll the server for new OS versions. When one appears,
+re-validate the given overlay files and report regressions: devices where a
+file was compatible with the previous OS version but is incompatible with the
+new one.
+
+The --on-new-version command is run after each re-check. It receives a JSON
+report on stdin and the environment variables QMDVERIFY_NEW_VERSIONS
+(comma-separated) and QMDVERIFY_REGRESSIONS (count).
+
+Run it under a service manager (systemd, launchd) or nohup to keep it in the
+background.`,
+	Example: `  qmdverify subscribe ./overlays
+  qmdverify subscribe ./overlays --on-new-version ./notify.sh
+  qmdverify subscribe ./overlays --interval 15m`,
+	SilenceUsage: true,
+	Args:         cobra.MinimumNArgs(1),
+	RunE:         runSubscribe,
+}
+
+func init() {
+	subscribeCmd.Flags().DurationVar(&subscribeInterval, "interval", time.Hour, "Time between polls")
+	subscribeCmd.Flags().StringVar(&subscribeHook, "on-new-version", "", "Command to run after re-checking on a new OS version")
+	subscribeCmd.Flags().BoolVar(&subscribeOnce, "once", false, "Poll a single time and exit")
+}
+
+type regression struct {
+	File            string `json:"file"`
+	Device          string `json:"device"`
+	OSVersion       string `json:"os_version"`
+	PreviousVersion string `json:"previous_version"`
+	ErrorDetail     string `json:"error_detail,omitempty"`
+}
+
+type subscriptionReport struct {
+	Server      string       `json:"server"`
+	CheckedAt   time.Time    `json:"checked_at"`
+	NewVersions []string     `json:"new_versions"`
+	Regressions []regression `json:"regressions"`
+}
+
+func runSubscribe(cmd *cobra.Command, args []string) error {
+	if subscribeInterval <= 0 {
+		return fmt.Errorf("--interval must be positive")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	client := newAPIClient(cfg)
+
+	fmt.Fprintf(os.Stderr, "Subscribed to new OS versions on %s (polling every %s)...\n", cfg.ServerHost, subscribeInterval)
+
+	ctx := cmd.Context()
+	for {
+		if err := pollSubscription(ctx, client, cfg.ServerHost, args); err != nil && ctx.Err() == nil {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", err)
+		}
+
+		if subscribeOnce || !waitInterval(ctx, subscribeInterval) {
+			return nil
+		}
+	}
+}
+
+func pollSubscription(ctx context.Context, client *api.Client, server string, paths []string) error {
+	hashtables, err := client.ListHashtables(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list hashtables: %w", err)
+	}
+
+	var versions []string
+	for _, ht := range hashtables.Hashtables {
+		versions = append(versions, ht.OSVersion)
+	}
+
+	seen, err := cache.LoadSeen(subscribeVersionsFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", err)
+	}
+	added := seen.NewSince(server, versions)
+	if len(added) == 0 {
+		seen.Record(server, versions)
+		return seen.Save()
+	}
+
+	newVersions := make([]string, 0, len(added))
+	for v := range added {
+		newVersions = append(newVersions, v)
+	}
+	sort.Slice(newVersions, func(i, j int) bool {
+		return osversion.Compare(newVersions[i], newVersions[j]) < 0
+	})
+
+	fmt.Printf("New OS version(s) on %s: %s\n", server, strings.Join(newVersions, ", "))
+
+	if err := recheckSubscription(ctx, client, server, paths, added, newVersions); err != nil {
+		return subscriptionFailed(seen, server, versions, newVersions, err)
+	}
+
+	// Only now are the new versions marked as seen, so a failed re-check or
+	// hook is retried on the next poll.
+	clearSubscriptionFailures(server)
+	seen.Record(server, versions)
+	return seen.Save()
+}
+
+// recheckSubscription re-validates paths after new versions appear, reports
+// the regressions and runs the --on-new-version command.
+func recheckSubscription(ctx context.Context, client *api.Client, server string, paths []string, added map[string]bool, newVersions []string) error {
+	filePaths, relativePaths, err := collectQMDFiles(paths)
+	if err != nil {
+		return err
+	}
+
+	results, err := compareFiles(ctx, client, filePaths, relativePaths)
+	if err != nil {
+		return err
+	}
+
+	report := subscriptionReport{
+		Server:      server,
+		CheckedAt:   time.Now().UTC(),
+		NewVersions: newVersions,
+		Regressions: make([]regression, 0),
+	}
+
+	for _, filename := range sortedResultNames(results) {
+		report.Regressions = append(report.Regressions, findRegressions(filename, results[filename], added)...)
+	}
+
+	if len(report.Regressions) == 0 {
+		fmt.Println("  No regressions")
+	}
+	for _, r := range report.Regressions {
+		fmt.Printf("  • %s: %s %s (was compatible on %s)\n", r.File, r.Device, r.OSVersion, r.PreviousVersion)
+	}
+
+	if subscribeHook != "" {
+		if err := runSubscriptionHook(subscribeHook, report); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// subscriptionFailed counts a failed re-check against the new versions.
+// Once they have failed subscribeMaxAttempts polls in a row they are marked
+// as seen anyway, so a hook that keeps failing does not re-run the check and
+// re-notify forever.
+func subscriptionFailed(seen *cache.Seen, server string, versions, newVersions []string, failure error) error {
+	failures := make(map[string]map[string]int)
+	if _, err := cache.ReadJSON(subscribeFailuresFile, &failures); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", err)
+	}
+	counts := failures[server]
+	if counts == nil {
+		counts = make(map[string]int)
+		failures[server] = counts
+	}
+	attempts := 0
+	for _, v := range newVersions {
+		counts[v]++
+		attempts = max(attempts, counts[v])
+	}
+
+	if attempts < subscribeMaxAttempts {
+		if err := cache.WriteJSON(subscribeFailuresFile, failures); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", err)
+		}
+		return fmt.Errorf("%w (attempt %d of %d, retrying on the next poll)", failure, attempts, subscribeMaxAttempts)
+	}
+
+	delete(failures, server)
+	if err := cache.WriteJSON(subscribeFailuresFile, failures); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", err)
+	}
+	seen.Record(server, versions)
+	if err := seen.Save(); err != nil {
+		return err
+	}
+	return fmt.Errorf("%w (giving up on %s after %d attempts)", failure, strings.Join(newVersions, ", "), attempts)
+}
+
+// clearSubscriptionFailures forgets the failed attempts counted for server
+// once its new versions have been re-checked.
+func clearSubscriptionFailures(server string) {
+	failures := make(map[string]map[string]int)
+	if ok, err := cache.ReadJSON(subscribeFailuresFile, &failures); err != nil || !ok || failures[server] == nil {
+		return
+	}
+	delete(failures, server)
+	if err := cache.WriteJSON(subscribeFailuresFile, failures); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", err)
+	}
+}
+
+// findRegressions reports new-version results that are incompatible while the
+// newest older version for the same device was compatible.
+func findRegressions(filename string, response *api.ComparisonResponse, newVersions map[string]bool) []regression {
+	byDevice := make(map[string][]api.ComparisonResult)
+	for _, results := range [][]api.ComparisonResult{response.Compatible, response.Incompatible} {
+		for _, result := range results {
+			byDevice[result.Device] = append(byDevice[result.Device], result)
+		}
+	}
+
+	devices := make([]string, 0, len(byDevice))
+	for device := range byDevice {
+		devices = append(devices, device)
+	}
+	sort.Strings(devices)
+
+	var regressions []regression
+	for _, device := range devices {
+		results := byDevice[device]
+		sort.Slice(results, func(i, j int) bool {
+			return osversion.Compare(results[i].OSVersion, results[j].OSVersion) < 0
+		})
+
+		var previous *api.ComparisonResult
+		for i := range results {
+			result := results[i]
+			if newVersions[result.OSVersion] {
+				if !result.Compatible && previous != nil && previous.Compatible {
+					regressions = append(regressions, regression{
+						File:            filename,
+						Device:          device,
+						OSVersion:       result.OSVersion,
+						PreviousVersion: previous.OSVersion,
+						ErrorDetail:     result.ErrorDetail,
+					})
+				}
+				continue
+			}
+			previous = &results[i]
+		}
+	}
+
+	return regressions
+}
+
+func runSubscriptionHook(hook string, report subscriptionReport) error {
+	payload, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+
+	cmd := exec.Command(hook)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		"QMDVERIFY_NEW_VERSIONS="+strings.Join(report.NewVersions, ","),
+		fmt.Sprintf("QMDVERIFY_REGRESSIONS=%d", len(report.Regressions)),
+	)
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("--on-new-version command failed: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/cache"
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+func TestFindRegressions(t *testing.T) {
+	response := &api.ComparisonResponse{
+		Compatible: []api.ComparisonResult{
+			{OSVersion: "3.22.0.64", Device: "rm2", Compatible: true},
+			{OSVersion: "3.22.0.64", Device: "rmpp", Compatible: true},
+			{OSVersion: "3.24.0.1", Device: "rmpp", Compatible: true},
+		},
+		Incompatible: []api.ComparisonResult{
+			{OSVersion: "3.24.0.1", Device: "rm2", Compatible: false, ErrorDetail: "hash not found"},
+			{OSVersion: "3.23.0.10", Device: "rmppm", Compatible: false},
+			{OSVersion: "3.24.0.1", Device: "rmppm", Compatible: false},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		newVersions map[string]bool
+		want        []regression
+	}{
+		{
+			name:        "new version breaks previously compatible device",
+			newVersions: map[string]bool{"3.24.0.1": true},
+			want: []regression{
+				{File: "a.qmd", Device: "rm2", OSVersion: "3.24.0.1", PreviousVersion: "3.22.0.64", ErrorDetail: "hash not found"},
+			},
+		},
+		{
+			name:        "no new versions",
+			newVersions: map[string]bool{},
+			want:        nil,
+		},
+		{
+			name:        "new version with no prior result",
+			newVersions: map[string]bool{"3.22.0.64": true},
+			want:        nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := findRegressions("a.qmd", response, tt.newVersions)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findRegressions() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPollSubscriptionKeepsUncheckedVersions(t *testing.T) {
+	t.Setenv(cache.EnvVarCacheDir, t.TempDir())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(api.HashtablesResponse{Hashtables: []api.HashtableInfo{
+			{OSVersion: "3.22.0.64"},
+			{OSVersion: "3.23.0.10"},
+		}})
+	}))
+	defer server.Close()
+
+	seen, err := cache.LoadSeen(subscribeVersionsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen.Record("test", []string{"3.22.0.64"})
+	if err := seen.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// An empty directory has no files to re-check, so every check fails.
+	empty := t.TempDir()
+	for attempt := 1; attempt <= subscribeMaxAttempts; attempt++ {
+		err := pollSubscription(context.Background(), api.NewClient(server.URL), "test", []string{empty})
+		if err == nil {
+			t.Fatalf("pollSubscription() attempt %d expected an error", attempt)
+		}
+
+		seen, err := cache.LoadSeen(subscribeVersionsFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		added := seen.NewSince("test", []string{"3.22.0.64", "3.23.0.10"})
+		if attempt < subscribeMaxAttempts && !reflect.DeepEqual(added, map[string]bool{"3.23.0.10": true}) {
+			t.Errorf("NewSince() = %v after failed attempt %d, want 3.23.0.10 still new", added, attempt)
+		}
+		if attempt == subscribeMaxAttempts && len(added) != 0 {
+			t.Errorf("NewSince() = %v after %d failed attempts, want 3.23.0.10 given up on", added, attempt)
+		}
+	}
+
+	failures := make(map[string]map[string]int)
+	if _, err := cache.ReadJSON(subscribeFailuresFile, &failures); err != nil || failures["test"] != nil {
+		t.Errorf("failures = %v, %v, want none left for the server", failures, err)
+	}
+}
@@ -0,0 +1,124 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/config"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/display"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/store"
+	"github.com/spf13/cobra"
+)
+
+var syncDir string
+
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Mirror the server's hashtables into a local directory",
+	Long: `Mirror every hashtable on the server into a local directory. Only new or
+changed hashtables are downloaded, compared by the checksums the server
+publishes; hashtables removed from the server are removed locally.
+
+The directory uses the same layout as an extracted offline bundle, so it can
+be used for offline checks.`,
+	Example: `  qmdverify sync
+  qmdverify sync --dir ~/.cache/qmdverify/hashtabs`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE:         runSync,
+}
+
+var syncVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check the local mirror against the server's checksums",
+	Long: `Recompute the SHA-256 of every hashtable in the local mirror and compare it
+with the checksum the server publishes. Corrupt, truncated or missing files
+are downloaded again.`,
+	Example: `  qmdverify sync verify
+  qmdverify sync verify --dir ~/.cache/qmdverify/hashtabs`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE:         runSyncVerify,
+}
+
+func init() {
+	syncCmd.PersistentFlags().StringVar(&syncDir, "dir", "", "Mirror directory. Defaults to hashtabs in the cache directory")
+	syncCmd.AddCommand(syncVerifyCmd)
+}
+
+func mirrorStore() (*store.Store, error) {
+	dir := syncDir
+	if dir == "" {
+		var err error
+		if dir, err = store.DefaultDir(); err != nil {
+			return nil, fmt.Errorf("failed to resolve mirror directory: %w", err)
+		}
+	}
+	return store.Open(dir), nil
+}
+
+func runSync(cmd *cobra.Command, args []string) error {
+	s, err := mirrorStore()
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+	client := newAPIClient(cfg)
+
+	fmt.Fprintf(os.Stderr, "Syncing hashtables from %s into %s...\n", cfg.ServerHost, s.Dir)
+	result, err := s.Sync(cmd.Context(), client, cfg.ServerHost)
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	for _, name := range result.Added {
+		fmt.Fprintf(os.Stderr, "  + %s\n", name)
+	}
+	for _, name := range result.Updated {
+		fmt.Fprintf(os.Stderr, "  ~ %s\n", name)
+	}
+	for _, name := range result.Removed {
+		fmt.Fprintf(os.Stderr, "  - %s\n", name)
+	}
+
+	enforceCacheLimit()
+
+	return display.RenderSuccess(os.Stdout, fmt.Sprintf("✓ Synced %s (%d added, %d updated, %d removed, %d unchanged)",
+		s.Dir, len(result.Added), len(result.Updated), len(result.Removed), len(result.Unchanged)))
+}
+
+func runSyncVerify(cmd *cobra.Command, args []string) error {
+	s, err := mirrorStore()
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+	client := newAPIClient(cfg)
+
+	fmt.Fprintf(os.Stderr, "Verifying %s against %s...\n", s.Dir, cfg.ServerHost)
+	result, err := s.Verify(cmd.Context(), client)
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	for _, name := range result.Repaired {
+		fmt.Fprintf(os.Stderr, "  ~ %s (re-downloaded)\n", name)
+	}
+
+	return display.RenderSuccess(os.Stdout, fmt.Sprintf("✓ Verified %s (%d ok, %d repaired)",
+		s.Dir, len(result.OK), len(result.Repaired)))
+}
@@ -0,0 +1,137 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/config"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/display"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/telemetry"
+	"github.com/spf13/cobra"
+)
+
+var (
+	telemetryEndpoint string
+	telemetryCommand  string
+	telemetryStarted  time.Time
+)
+
+var telemetryCmd = &cobra.Command{
+	Use:   "telemetry",
+	Short: "Manage anonymous usage telemetry (off by default)",
+	Long: `Telemetry is opt-in. When enabled, each run sends the command name, its
+duration, whether it succeeded, the CLI version and the OS/architecture.
+Arguments, file names and file contents are never sent.
+
+Events go to the configured server's /api/telemetry endpoint unless another
+endpoint is set with 'telemetry enable --endpoint' or
+QMDVERIFY_TELEMETRY_ENDPOINT. QMDVERIFY_TELEMETRY=0 or DO_NOT_TRACK=1 turn it
+off regardless of the stored setting.`,
+}
+
+var telemetryStatusCmd = &cobra.Command{
+	Use:          "status",
+	Short:        "Show whether telemetry is enabled",
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE:         runTelemetryStatus,
+}
+
+var telemetryEnableCmd = &cobra.Command{
+	Use:   "enable",
+	Short: "Opt in to anonymous usage telemetry",
+	Example: `  qmdverify telemetry enable
+  qmdverify telemetry enable --endpoint https://telemetry.example.com/qmdverify`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE:         runTelemetryEnable,
+}
+
+var telemetryDisableCmd = &cobra.Command{
+	Use:          "disable",
+	Short:        "Opt out of usage telemetry",
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE:         runTelemetryDisable,
+}
+
+func init() {
+	telemetryEnableCmd.Flags().StringVar(&telemetryEndpoint, "endpoint", "", "Send events to this URL instead of the server's /api/telemetry")
+
+	telemetryCmd.AddCommand(telemetryStatusCmd)
+	telemetryCmd.AddCommand(telemetryEnableCmd)
+	telemetryCmd.AddCommand(telemetryDisableCmd)
+}
+
+func runTelemetryStatus(cmd *cobra.Command, args []string) error {
+	settings, err := telemetry.LoadSettings()
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	status := "disabled"
+	switch {
+	case settings.Enabled && telemetry.DisabledByEnv():
+		status = "disabled by environment"
+	case settings.Enabled:
+		status = "enabled"
+	}
+
+	fmt.Printf("Telemetry: %s\n", status)
+	if cfg, err := config.Load(); err == nil && settings.Enabled {
+		fmt.Printf("  Endpoint: %s\n", settings.ResolveEndpoint(cfg.ServerHost))
+	}
+
+	return nil
+}
+
+func runTelemetryEnable(cmd *cobra.Command, args []string) error {
+	settings := &telemetry.Settings{Enabled: true, Endpoint: telemetryEndpoint}
+	if err := settings.Save(); err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	return display.RenderSuccess(os.Stdout, "✓ Telemetry enabled. Thank you!")
+}
+
+func runTelemetryDisable(cmd *cobra.Command, args []string) error {
+	settings := &telemetry.Settings{Enabled: false}
+	if err := settings.Save(); err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	return display.RenderSuccess(os.Stdout, "✓ Telemetry disabled")
+}
+
+func beginTelemetry(cmd *cobra.Command) {
+	telemetryCommand = cmd.CommandPath()
+	telemetryStarted = time.Now()
+}
+
+// recordTelemetry sends the invocation's event when the user has opted in.
+// An incompatible result still counts as success; only errors are failures.
+// Send failures are ignored so telemetry can never affect a run.
+func recordTelemetry(err error) {
+	command := telemetryCommand
+	telemetryCommand = ""
+	if command == "" || strings.HasPrefix(command, telemetryCmd.CommandPath()) {
+		return
+	}
+
+	settings, loadErr := telemetry.LoadSettings()
+	if loadErr != nil || !settings.Active() {
+		return
+	}
+
+	cfg, cfgErr := config.Load()
+	if cfgErr != nil {
+		return
+	}
+	event := telemetry.NewEvent(command, time.Since(telemetryStarted), err == nil, Version)
+	telemetry.Send(settings.ResolveEndpoint(cfg.ServerHost), event)
+}
@@ -0,0 +1,47 @@
+package commands
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/config"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/telemetry"
+)
+
+func TestRecordTelemetry(t *testing.T) {
+	t.Setenv(config.EnvVarConfigDir, t.TempDir())
+	t.Setenv(telemetry.EnvVarTelemetry, "")
+	t.Setenv("DO_NOT_TRACK", "")
+
+	events := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		events++
+	}))
+	defer server.Close()
+	t.Setenv(telemetry.EnvVarEndpoint, server.URL)
+
+	beginTelemetry(checkCmd)
+	recordTelemetry(nil)
+	if events != 0 {
+		t.Fatalf("sent %d events without opting in", events)
+	}
+
+	settings := &telemetry.Settings{Enabled: true}
+	if err := settings.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	beginTelemetry(checkCmd)
+	recordTelemetry(errors.New("boom"))
+	if events != 1 {
+		t.Errorf("sent %d events after opting in, want 1", events)
+	}
+
+	beginTelemetry(telemetryStatusCmd)
+	recordTelemetry(nil)
+	if events != 1 {
+		t.Errorf("telemetry commands should not be reported, sent %d", events)
+	}
+}
@@ -0,0 +1,38 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+// resultTrailer counts the results a text check prints, for the line
+// --trailer ends stdout with.
+type resultTrailer struct {
+	total        int
+	compatible   int
+	incompatible int
+}
+
+// trailer is set when this invocation prints a trailer line.
+var trailer *resultTrailer
+
+func (t *resultTrailer) add(response *api.ComparisonResponse) {
+	if t == nil {
+		return
+	}
+	t.total += response.TotalChecked
+	t.compatible += len(response.Compatible)
+	t.incompatible += len(response.Incompatible)
+}
+
+// writeTrailer prints the counts and exit code as one line of key=value
+// pairs whose keys and order don't change, so scripts can match it.
+func writeTrailer(w io.Writer, exitCode int) {
+	if trailer == nil {
+		return
+	}
+	fmt.Fprintf(w, "QMDVERIFY_RESULT total=%d compatible=%d incompatible=%d exit=%d\n",
+		trailer.total, trailer.compatible, trailer.incompatible, exitCode)
+}
@@ -0,0 +1,46 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+func TestWriteTrailer(t *testing.T) {
+	defer func() { trailer = nil }()
+
+	var none bytes.Buffer
+	trailer = nil
+	trailer.add(&api.ComparisonResponse{TotalChecked: 1})
+	writeTrailer(&none, 0)
+	if none.Len() != 0 {
+		t.Errorf("writeTrailer() without --trailer wrote %q", none.String())
+	}
+
+	trailer = &resultTrailer{}
+	trailer.add(&api.ComparisonResponse{
+		Compatible:   []api.ComparisonResult{{Device: "rmpp", OSVersion: "3.22.0.64"}, {Device: "rm2", OSVersion: "3.22.0.64"}},
+		Incompatible: []api.ComparisonResult{{Device: "rm1", OSVersion: "3.22.0.64"}},
+		TotalChecked: 3,
+	})
+	trailer.add(&api.ComparisonResponse{
+		Compatible:   []api.ComparisonResult{{Device: "rmpp", OSVersion: "3.20.0.92"}},
+		TotalChecked: 1,
+	})
+
+	tests := []struct {
+		exitCode int
+		want     string
+	}{
+		{0, "QMDVERIFY_RESULT total=4 compatible=3 incompatible=1 exit=0\n"},
+		{1, "QMDVERIFY_RESULT total=4 compatible=3 incompatible=1 exit=1\n"},
+	}
+	for _, tt := range tests {
+		var got bytes.Buffer
+		writeTrailer(&got, tt.exitCode)
+		if got.String() != tt.want {
+			t.Errorf("writeTrailer(%d) = %q, want %q", tt.exitCode, got.String(), tt.want)
+		}
+	}
+}
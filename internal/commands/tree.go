@@ -0,0 +1,121 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/config"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/display"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/tree"
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+var (
+	treeOutput string
+	treeAll    bool
+)
+
+var treeCmd = &cobra.Command{
+	Use:   "tree",
+	Short: "QML tree utilities",
+	Long:  `Download the QML trees the server uses for tree-based validation.`,
+}
+
+var treeDownloadCmd = &cobra.Command{
+	Use:   "download [<version>-<device>...]",
+	Short: "Download QML trees from the server",
+	Long: `Download QML trees from the server and unpack each into <dir>/<version>-<device>,
+replacing any previous copy. Use 'qmdverify list trees' to see what is
+available.`,
+	Example: `  qmdverify tree download 3.22.4.2-rmpp
+  qmdverify tree download 3.22.4.2-rmpp 3.22.4.2-rm2 -o ./trees/
+  qmdverify tree download --all`,
+	SilenceUsage: true,
+	RunE:         runTreeDownload,
+}
+
+func init() {
+	treeDownloadCmd.Flags().StringVarP(&treeOutput, "output", "o", "", "Directory to unpack trees into. Defaults to trees in the cache directory")
+	treeDownloadCmd.Flags().BoolVar(&treeAll, "all", false, "Download every tree on the server")
+	treeCmd.AddCommand(treeDownloadCmd)
+}
+
+func runTreeDownload(cmd *cobra.Command, args []string) error {
+	if treeAll == (len(args) > 0) {
+		err := fmt.Errorf("specify trees to download or --all, not both")
+		if !treeAll {
+			err = fmt.Errorf("specify at least one tree (e.g. 3.22.4.2-rmpp) or --all")
+		}
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+
+	dir := treeOutput
+	if dir == "" {
+		var err error
+		if dir, err = tree.DefaultDir(); err != nil {
+			display.RenderError(os.Stderr, err)
+			return err
+		}
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		display.RenderError(os.Stderr, err)
+		return err
+	}
+	client := newAPIClient(cfg)
+
+	var trees []api.TreeInfo
+	if treeAll {
+		response, err := client.ListTrees(cmd.Context())
+		if err != nil {
+			err = fmt.Errorf("failed to list trees: %w", err)
+			display.RenderError(os.Stderr, err)
+			return err
+		}
+		trees = response.Trees
+	} else {
+		for _, name := range args {
+			version, device, err := tree.ParseName(name)
+			if err != nil {
+				display.RenderError(os.Stderr, err)
+				return err
+			}
+			trees = append(trees, api.TreeInfo{Version: version, Device: device})
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Downloading %d QML trees from %s...\n", len(trees), cfg.ServerHost)
+	for _, info := range trees {
+		name := tree.Name(info.Version, info.Device)
+		count, err := downloadTree(cmd.Context(), client, info, filepath.Join(dir, name))
+		if err != nil {
+			err = fmt.Errorf("failed to download %s: %w", name, err)
+			display.RenderError(os.Stderr, err)
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "  %s (%d files)\n", name, count)
+	}
+
+	enforceCacheLimit()
+
+	return display.RenderSuccess(os.Stdout, fmt.Sprintf("✓ Downloaded %d QML trees to %s", len(trees), dir))
+}
+
+// downloadTree streams the archive straight into the unpacker.
+func downloadTree(ctx context.Context, client *api.Client, info api.TreeInfo, dest string) (int, error) {
+	pr, pw := io.Pipe()
+	go func() {
+		_, err := client.DownloadTree(ctx, info.Device, info.Version, pw)
+		pw.CloseWithError(err)
+	}()
+
+	count, err := tree.Unpack(pr, dest)
+	pr.CloseWithError(err)
+	return count, err
+}
@@ -0,0 +1,50 @@
+package commands
+
+import (
+	"archive/tar"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+func TestDownloadTree(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/trees/rmpp/3.22.4.2/download" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"tree not found"}`))
+			return
+		}
+		tw := tar.NewWriter(w)
+		tw.WriteHeader(&tar.Header{Name: "main.qml", Mode: 0644, Size: 7, Typeflag: tar.TypeReg})
+		tw.Write([]byte("Item {}"))
+		tw.Close()
+	}))
+	defer server.Close()
+
+	client := api.NewClient(server.URL)
+	dest := filepath.Join(t.TempDir(), "3.22.4.2-rmpp")
+
+	count, err := downloadTree(context.Background(), client, api.TreeInfo{Version: "3.22.4.2", Device: "rmpp"}, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("count = %d, want 1", count)
+	}
+	if data, err := os.ReadFile(filepath.Join(dest, "main.qml")); err != nil || string(data) != "Item {}" {
+		t.Errorf("main.qml = %q, %v", data, err)
+	}
+
+	missing := filepath.Join(t.TempDir(), "3.20.0.92-rm2")
+	if _, err := downloadTree(context.Background(), client, api.TreeInfo{Version: "3.20.0.92", Device: "rm2"}, missing); err == nil {
+		t.Error("expected an error for a missing tree")
+	}
+	if _, err := os.Stat(missing); !os.IsNotExist(err) {
+		t.Errorf("failed download left %s behind: %v", missing, err)
+	}
+}
@@ -3,7 +3,6 @@ package commands
 import (
 	"fmt"
 
-	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/api"
 	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/config"
 	"github.com/spf13/cobra"
 )
@@ -26,12 +25,15 @@ func runVersion(cmd *cobra.Command, args []string) error {
 	fmt.Printf("  Version: %s\n", Version)
 	fmt.Println()
 
-	cfg := config.Load()
-	client := api.NewClient(cfg.ServerHost)
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	client := newAPIClient(cfg)
 
 	fmt.Printf("Server (%s)\n", cfg.ServerHost)
 
-	serverVersion, err := client.GetVersion()
+	serverVersion, err := client.GetVersion(cmd.Context())
 	if err != nil {
 		fmt.Printf("  Error: %s\n", err.Error())
 	} else {
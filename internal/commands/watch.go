@@ -0,0 +1,186 @@
+package commands
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/x/term"
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/cobra"
+)
+
+// clearScreen moves the cursor home and clears the terminal.
+const clearScreen = "\033[H\033[2J"
+
+var (
+	watchDebounce time.Duration
+	watchNoClear  bool
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch <file or directory>... [-- check flags]",
+	Short: "Re-check QMD files whenever they change",
+	Long: `Check QMD files, then watch them and their directories and re-run the
+check whenever a .qmd file is written, created, renamed or removed. On a
+terminal the screen is cleared before each run, so the matrix updates in place.
+
+Arguments after -- are passed to check, so any of its flags can be used.`,
+	Example: `  qmdverify watch ./overlays/
+  qmdverify watch mymod.qmd -- --device rmpp --failed-only
+  qmdverify watch --debounce 1s ./overlays/ -- --local`,
+	SilenceUsage: true,
+	Args:         cobra.MinimumNArgs(1),
+	RunE:         runWatch,
+}
+
+func init() {
+	watchCmd.Flags().DurationVar(&watchDebounce, "debounce", 300*time.Millisecond, "How long to wait for changes to settle before re-checking")
+	watchCmd.Flags().BoolVar(&watchNoClear, "no-clear", false, "Do not clear the screen before each check")
+}
+
+func runWatch(cmd *cobra.Command, args []string) error {
+	paths, checkArgs := args, []string(nil)
+	if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+		paths, checkArgs = args[:dash], args[dash:]
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("watch needs at least one file or directory")
+	}
+	if watchDebounce <= 0 {
+		return fmt.Errorf("--debounce must be positive")
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to start watcher: %w", err)
+	}
+	defer watcher.Close()
+	targets := newWatchTargets(paths)
+	for _, path := range paths {
+		if err := addWatches(watcher, path); err != nil {
+			return err
+		}
+	}
+
+	clear := !watchNoClear && term.IsTerminal(os.Stdout.Fd())
+	check := func() {
+		if clear {
+			fmt.Fprint(os.Stdout, clearScreen)
+		}
+		if err := rerunCheck(cmd.Context(), cmd, append(append([]string{}, paths...), checkArgs...)); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", err)
+		}
+		fmt.Fprintf(os.Stderr, "\n[%s] Watching %s for changes. Press Ctrl-C to stop.\n", time.Now().Format(time.TimeOnly), strings.Join(paths, ", "))
+	}
+	check()
+
+	debounce := time.NewTimer(watchDebounce)
+	debounce.Stop()
+	for {
+		select {
+		case <-cmd.Context().Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					if err := addWatches(watcher, event.Name); err != nil {
+						fmt.Fprintf(os.Stderr, "Warning: %s\n", err)
+					}
+				}
+			}
+			if targets.changed(event) {
+				debounce.Reset(watchDebounce)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", err)
+		case <-debounce.C:
+			check()
+		}
+	}
+}
+
+// addWatches watches path, or for a file the directory holding it, so that
+// editors which save by replacing the file are still seen. Directories are
+// watched with all their subdirectories.
+func addWatches(watcher *fsnotify.Watcher, path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to access %s: %w", path, err)
+	}
+	if !info.IsDir() {
+		return watcher.Add(filepath.Dir(path))
+	}
+
+	return filepath.WalkDir(path, func(dir string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+		if dir != path && strings.HasPrefix(entry.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if err := watcher.Add(dir); err != nil {
+			return fmt.Errorf("failed to watch %s: %w", dir, err)
+		}
+		return nil
+	})
+}
+
+// watchTargets is what a watch re-checks: files named on the command line
+// and QMD files anywhere under the directories named.
+type watchTargets struct {
+	files map[string]bool
+	dirs  []string
+}
+
+func newWatchTargets(paths []string) *watchTargets {
+	targets := &watchTargets{files: make(map[string]bool)}
+	for _, path := range paths {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			abs = path
+		}
+		if info, err := os.Stat(abs); err == nil && info.IsDir() {
+			targets.dirs = append(targets.dirs, abs)
+		} else {
+			targets.files[abs] = true
+		}
+	}
+	return targets
+}
+
+// changed reports whether event writes, creates, renames or removes one of
+// the targets. Chmod events and editor swap files are ignored.
+func (t *watchTargets) changed(event fsnotify.Event) bool {
+	if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) && !event.Has(fsnotify.Remove) && !event.Has(fsnotify.Rename) {
+		return false
+	}
+	name, err := filepath.Abs(event.Name)
+	if err != nil {
+		name = event.Name
+	}
+	if t.files[name] {
+		return true
+	}
+	if !strings.HasSuffix(strings.ToLower(name), ".qmd") {
+		return false
+	}
+	for _, dir := range t.dirs {
+		if rel, err := filepath.Rel(dir, name); err == nil && !strings.HasPrefix(rel, "..") {
+			return true
+		}
+	}
+	return false
+}
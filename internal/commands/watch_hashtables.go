@@ -0,0 +1,223 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"time"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/cache"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/config"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/notify"
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var (
+	watchInterval   time.Duration
+	watchWebhook    string
+	watchCheckPaths []string
+	watchOnce       bool
+)
+
+var watchHashtablesCmd = &cobra.Command{
+	Use:   "watch-hashtables",
+	Short: "Watch the server for new hashtables",
+	Long: `Poll the server's hashtable list and report device/version entries that
+were not present on the previous poll. Known hashtables are remembered in the
+cache directory, so restarting the watcher does not re-report them.
+
+When new hashtables appear, a notification is printed and optionally POSTed as
+JSON to a webhook, and a check can be re-run automatically.`,
+	Example: `  qmdverify watch-hashtables
+  qmdverify watch-hashtables --interval 30m
+  qmdverify watch-hashtables --webhook https://hooks.example.com/qmd
+  qmdverify watch-hashtables --check ./overlays/
+  qmdverify watch-hashtables --once`,
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE:         runWatchHashtables,
+}
+
+func init() {
+	watchHashtablesCmd.Flags().DurationVar(&watchInterval, "interval", time.Hour, "Time between polls")
+	watchHashtablesCmd.Flags().StringVar(&watchWebhook, "webhook", "", "URL to POST a JSON notification to when new hashtables appear")
+	watchHashtablesCmd.Flags().StringSliceVar(&watchCheckPaths, "check", nil, "Re-run check on these files or directories when new hashtables appear (can be repeated)")
+	watchHashtablesCmd.Flags().BoolVar(&watchOnce, "once", false, "Poll a single time and exit")
+}
+
+type hashtableNotification struct {
+	Server     string              `json:"server"`
+	DetectedAt time.Time           `json:"detected_at"`
+	Hashtables []api.HashtableInfo `json:"hashtables"`
+}
+
+func runWatchHashtables(cmd *cobra.Command, args []string) error {
+	if watchInterval <= 0 {
+		return fmt.Errorf("--interval must be positive")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	client := newAPIClient(cfg)
+
+	fmt.Fprintf(os.Stderr, "Watching %s for new hashtables every %s...\n", cfg.ServerHost, watchInterval)
+
+	ctx := cmd.Context()
+	for {
+		if err := pollHashtables(ctx, cmd, client, cfg.ServerHost); err != nil && ctx.Err() == nil {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", err)
+		}
+
+		if watchOnce || !waitInterval(ctx, watchInterval) {
+			return nil
+		}
+	}
+}
+
+// waitInterval waits for d between polls, and reports false without waiting
+// it out once ctx is done, so an interrupt stops the loop.
+func waitInterval(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+func pollHashtables(ctx context.Context, cmd *cobra.Command, client *api.Client, server string) error {
+	response, err := client.ListHashtables(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list hashtables: %w", err)
+	}
+
+	added, err := detectNewHashtables(server, response.Hashtables)
+	if err != nil {
+		return err
+	}
+
+	if len(added) == 0 {
+		return nil
+	}
+
+	notification := hashtableNotification{
+		Server:     server,
+		DetectedAt: time.Now().UTC(),
+		Hashtables: added,
+	}
+
+	fmt.Printf("[%s] %d new hashtable(s) on %s:\n", notification.DetectedAt.Format(time.RFC3339), len(added), server)
+	for _, ht := range added {
+		fmt.Printf("  • %s (%s %s)\n", ht.Name, ht.Device, ht.OSVersion)
+	}
+
+	if watchWebhook != "" {
+		if err := notify.PostWebhook(watchWebhook, notification); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", err)
+		}
+	}
+
+	if len(watchCheckPaths) > 0 {
+		if err := rerunCheck(ctx, cmd, watchCheckPaths); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", err)
+		}
+	}
+
+	return nil
+}
+
+// detectNewHashtables compares the server's hashtables against those seen on
+// earlier polls and records the current set. The first poll for a server only
+// establishes the baseline.
+func detectNewHashtables(server string, hashtables []api.HashtableInfo) ([]api.HashtableInfo, error) {
+	seen, err := cache.LoadSeenHashtables()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", err)
+	}
+
+	keys := make([]string, 0, len(hashtables))
+	for _, ht := range hashtables {
+		keys = append(keys, hashtableKey(ht))
+	}
+
+	newKeys := seen.NewSince(server, keys)
+	seen.Record(server, keys)
+	if err := seen.Save(); err != nil {
+		return nil, err
+	}
+
+	var added []api.HashtableInfo
+	for _, ht := range hashtables {
+		if newKeys[hashtableKey(ht)] {
+			added = append(added, ht)
+		}
+	}
+
+	sort.Slice(added, func(i, j int) bool {
+		return hashtableKey(added[i]) < hashtableKey(added[j])
+	})
+
+	return added, nil
+}
+
+func hashtableKey(ht api.HashtableInfo) string {
+	if ht.Name != "" {
+		return ht.Name
+	}
+	return ht.OSVersion + "-" + ht.Device
+}
+
+// rerunCheck runs check in a child process so that an incompatible result,
+// which exits non-zero, does not stop the watcher. The root flags given to
+// this invocation are passed on, so the check uses the same server and
+// credentials, and the check is stopped along with ctx.
+func rerunCheck(ctx context.Context, cmd *cobra.Command, args []string) error {
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate qmdverify executable: %w", err)
+	}
+
+	flags, env := inheritedFlags(cmd)
+	check := exec.CommandContext(ctx, self, append(append([]string{"check"}, flags...), args...)...)
+	check.Stdout = os.Stdout
+	check.Stderr = os.Stderr
+	check.Env = append(os.Environ(), env...)
+
+	if err := check.Run(); err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			return nil
+		}
+		return fmt.Errorf("failed to run check: %w", err)
+	}
+
+	return nil
+}
+
+// inheritedFlags returns the root persistent flags set on cmd's command line
+// as arguments for a child check. --token is passed in the environment
+// instead, so it does not show up in the process list.
+func inheritedFlags(cmd *cobra.Command) (args, env []string) {
+	cmd.Root().PersistentFlags().Visit(func(f *pflag.Flag) {
+		switch value := f.Value.(type) {
+		case pflag.SliceValue:
+			for _, v := range value.GetSlice() {
+				args = append(args, "--"+f.Name+"="+v)
+			}
+		default:
+			if f.Name == "token" {
+				env = append(env, config.EnvVarToken+"="+f.Value.String())
+				return
+			}
+			args = append(args, "--"+f.Name+"="+f.Value.String())
+		}
+	})
+	return args, env
+}
@@ -0,0 +1,106 @@
+package commands
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/cache"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/config"
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+func TestDetectNewHashtables(t *testing.T) {
+	t.Setenv(cache.EnvVarCacheDir, t.TempDir())
+
+	server := "https://qmdverify.example.com"
+	initial := []api.HashtableInfo{
+		{Name: "3.22.0.64-rmpp", OSVersion: "3.22.0.64", Device: "rmpp"},
+		{Name: "3.22.0.64-rm2", OSVersion: "3.22.0.64", Device: "rm2"},
+	}
+
+	added, err := detectNewHashtables(server, initial)
+	if err != nil {
+		t.Fatalf("detectNewHashtables() error = %v", err)
+	}
+	if len(added) != 0 {
+		t.Errorf("first poll reported %v, want baseline only", added)
+	}
+
+	added, err = detectNewHashtables(server, initial)
+	if err != nil {
+		t.Fatalf("detectNewHashtables() error = %v", err)
+	}
+	if len(added) != 0 {
+		t.Errorf("unchanged poll reported %v", added)
+	}
+
+	updated := append([]api.HashtableInfo{
+		{Name: "3.24.0.1-rmppm", OSVersion: "3.24.0.1", Device: "rmppm"},
+		{Name: "3.24.0.1-rmpp", OSVersion: "3.24.0.1", Device: "rmpp"},
+	}, initial...)
+
+	added, err = detectNewHashtables(server, updated)
+	if err != nil {
+		t.Fatalf("detectNewHashtables() error = %v", err)
+	}
+	want := []api.HashtableInfo{
+		{Name: "3.24.0.1-rmpp", OSVersion: "3.24.0.1", Device: "rmpp"},
+		{Name: "3.24.0.1-rmppm", OSVersion: "3.24.0.1", Device: "rmppm"},
+	}
+	if !reflect.DeepEqual(added, want) {
+		t.Errorf("detectNewHashtables() = %v, want %v", added, want)
+	}
+}
+
+func TestHashtableKey(t *testing.T) {
+	if got := hashtableKey(api.HashtableInfo{Name: "3.22.0.64-rmpp"}); got != "3.22.0.64-rmpp" {
+		t.Errorf("hashtableKey() = %v", got)
+	}
+	if got := hashtableKey(api.HashtableInfo{OSVersion: "3.22.0.64", Device: "rm2"}); got != "3.22.0.64-rm2" {
+		t.Errorf("hashtableKey() without name = %v", got)
+	}
+}
+
+func TestWaitInterval(t *testing.T) {
+	if !waitInterval(context.Background(), time.Millisecond) {
+		t.Error("waitInterval() = false after the interval")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	if waitInterval(ctx, time.Hour) {
+		t.Error("waitInterval() = true after an interrupt")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("waitInterval() waited %v after an interrupt", elapsed)
+	}
+}
+
+func TestInheritedFlags(t *testing.T) {
+	root := &cobra.Command{Use: "qmdverify"}
+	root.PersistentFlags().String("profile", "", "")
+	root.PersistentFlags().String("org", "", "")
+	root.PersistentFlags().String("token", "", "")
+	root.PersistentFlags().StringSlice("mirror", nil, "")
+	child := &cobra.Command{Use: "watch-hashtables"}
+	root.AddCommand(child)
+
+	for name, value := range map[string]string{"profile": "work", "token": "secret", "mirror": "https://a.example.com,https://b.example.com"} {
+		if err := root.PersistentFlags().Set(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	args, env := inheritedFlags(child)
+	wantArgs := []string{"--mirror=https://a.example.com", "--mirror=https://b.example.com", "--profile=work"}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("inheritedFlags() args = %v, want %v", args, wantArgs)
+	}
+	if wantEnv := []string{config.EnvVarToken + "=secret"}; !reflect.DeepEqual(env, wantEnv) {
+		t.Errorf("inheritedFlags() env = %v, want %v", env, wantEnv)
+	}
+}
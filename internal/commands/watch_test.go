@@ -0,0 +1,43 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+func TestWatchTargetsChanged(t *testing.T) {
+	dir := t.TempDir()
+	overlays := filepath.Join(dir, "overlays")
+	if err := os.Mkdir(overlays, 0755); err != nil {
+		t.Fatal(err)
+	}
+	single := filepath.Join(dir, "mod.qmd")
+	if err := os.WriteFile(single, []byte("AFFECT [[1]]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	targets := newWatchTargets([]string{overlays, single})
+
+	tests := []struct {
+		name  string
+		event fsnotify.Event
+		want  bool
+	}{
+		{name: "write to named file", event: fsnotify.Event{Name: single, Op: fsnotify.Write}, want: true},
+		{name: "sibling of named file", event: fsnotify.Event{Name: filepath.Join(dir, "other.qmd"), Op: fsnotify.Write}},
+		{name: "file in watched directory", event: fsnotify.Event{Name: filepath.Join(overlays, "a.qmd"), Op: fsnotify.Create}, want: true},
+		{name: "file in subdirectory", event: fsnotify.Event{Name: filepath.Join(overlays, "sub", "b.qmd"), Op: fsnotify.Rename}, want: true},
+		{name: "removed file", event: fsnotify.Event{Name: filepath.Join(overlays, "a.qmd"), Op: fsnotify.Remove}, want: true},
+		{name: "swap file", event: fsnotify.Event{Name: filepath.Join(overlays, ".a.qmd.swp"), Op: fsnotify.Write}},
+		{name: "chmod", event: fsnotify.Event{Name: single, Op: fsnotify.Chmod}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := targets.changed(tt.event); got != tt.want {
+				t.Errorf("changed(%v) = %v, want %v", tt.event, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// UnixScheme selects a server listening on a local socket, as in
+// unix:///var/run/qmdverify.sock.
+const UnixScheme = "unix"
+
+// NormalizeHost validates a server address and returns it in the form the
+// client expects. An address without a scheme, such as localhost:8080 or
+// verify.internal, gets https:// when its port is 443 and http:// otherwise.
+// IPv6 literals are bracketed and their zones escaped, so fe80::1%eth0
+// becomes [fe80::1%25eth0]. One trailing slash is dropped.
+func NormalizeHost(host string) (string, error) {
+	host = strings.TrimSpace(host)
+	if host == "" {
+		return "", fmt.Errorf("server address is empty")
+	}
+
+	if socket, ok := strings.CutPrefix(host, UnixScheme+":"); ok {
+		socket = "/" + strings.TrimLeft(socket, "/")
+		if socket == "/" {
+			return "", fmt.Errorf("invalid server address %q: missing socket path", host)
+		}
+		return UnixScheme + "://" + socket, nil
+	}
+
+	scheme, rest, found := strings.Cut(host, "://")
+	if !found {
+		scheme, rest = "http", host
+	}
+	authority, path, hasPath := strings.Cut(rest, "/")
+	authority = fixIPv6(authority)
+	if _, port, err := net.SplitHostPort(authority); !found && err == nil && port == "443" {
+		scheme = "https"
+	}
+	host = scheme + "://" + authority
+	if hasPath {
+		host += "/" + path
+	}
+
+	u, err := url.Parse(host)
+	if err != nil {
+		return "", fmt.Errorf("invalid server address %q: %w", host, err)
+	}
+	switch {
+	case u.Scheme != "http" && u.Scheme != "https":
+		return "", fmt.Errorf("invalid server address %q: scheme must be http, https or %s", host, UnixScheme)
+	case u.Hostname() == "":
+		return "", fmt.Errorf("invalid server address %q: missing host", host)
+	case u.RawQuery != "" || u.Fragment != "":
+		return "", fmt.Errorf("invalid server address %q: must not have a query or fragment", host)
+	}
+	if port := u.Port(); port != "" {
+		if _, err := net.LookupPort("tcp", port); err != nil {
+			return "", fmt.Errorf("invalid server address %q: bad port %q", host, port)
+		}
+	}
+
+	return strings.TrimSuffix(host, "/"), nil
+}
+
+// fixIPv6 brackets a bare IPv6 literal and escapes its zone, leaving other
+// hosts alone. A bare literal cannot carry a port, since fd00::1:8080 is
+// itself an address.
+func fixIPv6(authority string) string {
+	if addr, ok := strings.CutPrefix(authority, "["); ok {
+		addr, port, ok := strings.Cut(addr, "]")
+		if !ok {
+			return authority
+		}
+		return "[" + escapeZone(addr) + "]" + port
+	}
+
+	addr, _, _ := strings.Cut(authority, "%")
+	if strings.Count(authority, ":") < 2 || net.ParseIP(addr) == nil {
+		return authority
+	}
+	return "[" + escapeZone(authority) + "]"
+}
+
+// escapeZone percent-encodes the % that starts an IPv6 zone, as URLs require.
+func escapeZone(addr string) string {
+	i := strings.Index(addr, "%")
+	if i < 0 || strings.HasPrefix(addr[i:], "%25") {
+		return addr
+	}
+	return addr[:i] + "%25" + addr[i+1:]
+}
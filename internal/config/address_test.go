@@ -0,0 +1,41 @@
+package config
+
+import "testing"
+
+func TestNormalizeHost(t *testing.T) {
+	tests := []struct {
+		host    string
+		want    string
+		wantErr bool
+	}{
+		{host: "https://verify.example.com/", want: "https://verify.example.com"},
+		{host: "http://localhost:8080/base", want: "http://localhost:8080/base"},
+		{host: "localhost:8080", want: "http://localhost:8080"},
+		{host: "verify.internal", want: "http://verify.internal"},
+		{host: "verify.example.com:443", want: "https://verify.example.com:443"},
+		{host: "10.0.0.5:9000/qmd/", want: "http://10.0.0.5:9000/qmd"},
+		{host: "http://[fd00::1]:8080", want: "http://[fd00::1]:8080"},
+		{host: "[fd00::1]:443", want: "https://[fd00::1]:443"},
+		{host: "fd00::1", want: "http://[fd00::1]"},
+		{host: "http://fd00::1/", want: "http://[fd00::1]"},
+		{host: "http://[fe80::1%eth0]:8080", want: "http://[fe80::1%25eth0]:8080"},
+		{host: "fe80::1%eth0", want: "http://[fe80::1%25eth0]"},
+		{host: "http://[fe80::1%25eth0]:8080/qmd", want: "http://[fe80::1%25eth0]:8080/qmd"},
+		{host: "http://[fd00::1", wantErr: true},
+		{host: "unix:///var/run/qmdverify.sock", want: "unix:///var/run/qmdverify.sock"},
+		{host: "unix:/var/run/qmdverify.sock", want: "unix:///var/run/qmdverify.sock"},
+		{host: "unix://", wantErr: true},
+		{host: "", wantErr: true},
+		{host: "ftp://verify.example.com", wantErr: true},
+		{host: "http://", wantErr: true},
+		{host: "localhost:port", wantErr: true},
+		{host: "localhost:99999", wantErr: true},
+		{host: "https://verify.example.com/?debug=1", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeHost(tt.host)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("NormalizeHost(%q) = %q, %v, want %q, wantErr %v", tt.host, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -1,32 +1,144 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strings"
+	"time"
 )
 
 const (
-	DefaultHost = "http://qmdverify.scottlabs.io"
-	EnvVarHost  = "QMDVERIFY_HOST"
+	DefaultHost   = "http://qmdverify.scottlabs.io"
+	EnvVarHost    = "QMDVERIFY_HOST"
+	EnvVarMirrors = "QMDVERIFY_MIRRORS"
+)
+
+var (
+	mirrorsOverride []string
+	discoveredHost  string
 )
 
 type Config struct {
 	ServerHost string
+	Mirrors    []string
+	Profile    string
+	Token      string
+	Org        string
+
+	RefreshToken string
+	TokenExpiry  time.Time
 }
 
-func Load() *Config {
+// Load resolves the server from QMDVERIFY_HOST, then a discovered server,
+// then the config file, then the active profile's stored server, then the
+// default. Stored credentials are only used when they belong to the
+// resolved server. The organization follows the same
+// order: --org, QMDVERIFY_ORG, the config file, then the profile, and so
+// does the token: --token, QMDVERIFY_TOKEN, then the stored credentials. An
+// invalid server or mirror address, or an unreadable config file, is an
+// error.
+func Load() (*Config, error) {
+	settings, err := LoadSettings()
+	if err != nil {
+		return nil, err
+	}
+	cfg := &Config{Profile: ActiveProfile()}
+
+	var creds Credentials
+	if store, err := LoadCredentialStore(); err == nil {
+		creds, _ = store.Get(cfg.Profile)
+	}
+
 	host := os.Getenv(EnvVarHost)
+	if host == "" {
+		host = discoveredHost
+	}
+	if host == "" {
+		host = settings.Server
+	}
+	if host == "" {
+		host = creds.Server
+	}
 	if host == "" {
 		host = DefaultHost
 	}
 
-	host = strings.TrimSuffix(host, "/")
+	if cfg.ServerHost, err = NormalizeHost(host); err != nil {
+		return nil, err
+	}
+	if cfg.Mirrors, err = resolveMirrors(cfg.ServerHost, settings.Mirrors); err != nil {
+		return nil, err
+	}
 
-	return &Config{
-		ServerHost: host,
+	cfg.Org = orgOverride
+	if cfg.Org == "" {
+		cfg.Org = os.Getenv(EnvVarOrg)
+	}
+	if cfg.Org == "" {
+		cfg.Org = settings.Org
 	}
+	if cfg.Org == "" {
+		cfg.Org = creds.Org
+	}
+	cfg.Token = tokenOverride
+	if cfg.Token == "" {
+		cfg.Token = os.Getenv(EnvVarToken)
+	}
+	if cfg.Token == "" && creds.Token != "" && strings.TrimSuffix(creds.Server, "/") == cfg.ServerHost {
+		cfg.Token = creds.Token
+		cfg.RefreshToken = creds.RefreshToken
+		cfg.TokenExpiry = creds.ExpiresAt
+	}
+
+	return cfg, nil
+}
+
+// SetDiscoveredHost sets the server found through DNS SRV or well-known
+// discovery. QMDVERIFY_HOST still takes precedence.
+func SetDiscoveredHost(host string) {
+	discoveredHost = host
+}
+
+// SetMirrors sets additional servers whose results are merged with the
+// primary server's, taking precedence over QMDVERIFY_MIRRORS and the config
+// file.
+func SetMirrors(mirrors []string) {
+	mirrorsOverride = mirrors
 }
 
+func resolveMirrors(primary string, configured []string) ([]string, error) {
+	raw := mirrorsOverride
+	if len(raw) == 0 {
+		raw = strings.Split(os.Getenv(EnvVarMirrors), ",")
+	}
+	if strings.TrimSpace(strings.Join(raw, "")) == "" {
+		raw = configured
+	}
+
+	var mirrors []string
+	seen := map[string]bool{primary: true}
+	for _, mirror := range raw {
+		if strings.TrimSpace(mirror) == "" {
+			continue
+		}
+		mirror, err := NormalizeHost(mirror)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mirror: %w", err)
+		}
+		if seen[mirror] {
+			continue
+		}
+		seen[mirror] = true
+		mirrors = append(mirrors, mirror)
+	}
+	return mirrors, nil
+}
+
+// APIEndpoint appends path to the server address, bracketing an IPv6 host
+// and escaping its zone if the address was not already normalized.
 func (c *Config) APIEndpoint(path string) string {
+	if host, err := NormalizeHost(c.ServerHost); err == nil {
+		return host + path
+	}
 	return c.ServerHost + path
 }
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -60,7 +61,10 @@ func TestLoad(t *testing.T) {
 				t.Setenv(EnvVarHost, tt.envValue)
 			}
 
-			cfg := Load()
+			cfg, err := Load()
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
 
 			if cfg.ServerHost != tt.wantHost {
 				t.Errorf("Load() ServerHost = %v, want %v", cfg.ServerHost, tt.wantHost)
@@ -69,6 +73,21 @@ func TestLoad(t *testing.T) {
 	}
 }
 
+func TestLoad_InvalidHost(t *testing.T) {
+	t.Setenv(EnvVarConfigDir, t.TempDir())
+
+	t.Setenv(EnvVarHost, "ftp://verify.example.com")
+	if _, err := Load(); err == nil {
+		t.Error("Load() accepted an unsupported scheme")
+	}
+
+	t.Setenv(EnvVarHost, "https://verify.example.com")
+	t.Setenv(EnvVarMirrors, "https://eu.example.com,http://")
+	if _, err := Load(); err == nil {
+		t.Error("Load() accepted an invalid mirror")
+	}
+}
+
 func TestConfig_APIEndpoint(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -112,6 +131,18 @@ func TestConfig_APIEndpoint(t *testing.T) {
 			path:       "/api/test",
 			want:       "http://localhost:8080/api/test",
 		},
+		{
+			name:       "ipv6 literal",
+			serverHost: "http://[fd00::1]:8080",
+			path:       "/api/test",
+			want:       "http://[fd00::1]:8080/api/test",
+		},
+		{
+			name:       "ipv6 link-local zone",
+			serverHost: "http://[fe80::1%eth0]:8080",
+			path:       "/api/test",
+			want:       "http://[fe80::1%25eth0]:8080/api/test",
+		},
 		{
 			name:       "server with path already",
 			serverHost: "https://example.com/base",
@@ -134,3 +165,50 @@ func TestConfig_APIEndpoint(t *testing.T) {
 		})
 	}
 }
+
+func TestLoad_Mirrors(t *testing.T) {
+	t.Setenv(EnvVarConfigDir, t.TempDir())
+	t.Setenv(EnvVarHost, "https://primary.example.com")
+	t.Setenv(EnvVarMirrors, " https://eu.example.com/, https://primary.example.com,,https://eu.example.com,https://us.example.com")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	want := []string{"https://eu.example.com", "https://us.example.com"}
+	if !reflect.DeepEqual(cfg.Mirrors, want) {
+		t.Errorf("Load() Mirrors = %v, want %v", cfg.Mirrors, want)
+	}
+
+	SetMirrors([]string{"https://flag.example.com"})
+	defer SetMirrors(nil)
+	if cfg := mustLoad(t); !reflect.DeepEqual(cfg.Mirrors, []string{"https://flag.example.com"}) {
+		t.Errorf("Load() Mirrors = %v, want the --mirror override", cfg.Mirrors)
+	}
+}
+
+func TestLoad_DiscoveredHost(t *testing.T) {
+	t.Setenv(EnvVarConfigDir, t.TempDir())
+	t.Setenv(EnvVarHost, "")
+
+	SetDiscoveredHost("https://discovered.example.com")
+	defer SetDiscoveredHost("")
+
+	if cfg := mustLoad(t); cfg.ServerHost != "https://discovered.example.com" {
+		t.Errorf("Load() ServerHost = %v, want the discovered server", cfg.ServerHost)
+	}
+
+	t.Setenv(EnvVarHost, "https://explicit.example.com")
+	if cfg := mustLoad(t); cfg.ServerHost != "https://explicit.example.com" {
+		t.Errorf("Load() ServerHost = %v, want QMDVERIFY_HOST to win", cfg.ServerHost)
+	}
+}
+
+func mustLoad(t *testing.T) *Config {
+	t.Helper()
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	return cfg
+}
@@ -0,0 +1,210 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const cookiesFile = "cookies.json"
+
+// storedCookie is a cookie as set by the server at URL. Replaying it into a
+// jar with the same URL restores its scope.
+type storedCookie struct {
+	URL      string    `json:"url"`
+	Name     string    `json:"name"`
+	Value    string    `json:"value"`
+	Domain   string    `json:"domain,omitempty"`
+	Path     string    `json:"path,omitempty"`
+	Expires  time.Time `json:"expires,omitempty"`
+	Secure   bool      `json:"secure,omitempty"`
+	HttpOnly bool      `json:"http_only,omitempty"`
+}
+
+// CookieJar is an http.CookieJar that keeps a profile's cookies, such as the
+// session of an SSO proxy in front of the server, in the user config
+// directory. Cookies are saved as soon as the server sets them.
+type CookieJar struct {
+	path    string
+	profile string
+	jar     *cookiejar.Jar
+	mu      sync.Mutex
+	cookies []storedCookie
+
+	// OnSaveError is called when cookies set by the server cannot be saved.
+	OnSaveError func(error)
+}
+
+type cookieFile struct {
+	Profiles map[string][]storedCookie `json:"profiles"`
+}
+
+// LoadCookieJar returns the cookie jar of a profile. Expired cookies are
+// dropped.
+func LoadCookieJar(profile string) (*CookieJar, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+
+	jar, _ := cookiejar.New(nil)
+	j := &CookieJar{path: filepath.Join(dir, cookiesFile), profile: profile, jar: jar}
+
+	file, err := j.read()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for _, c := range file.Profiles[profile] {
+		if !c.Expires.IsZero() && !c.Expires.After(now) {
+			continue
+		}
+		u, err := url.Parse(c.URL)
+		if err != nil {
+			continue
+		}
+		j.cookies = append(j.cookies, c)
+		j.jar.SetCookies(u, []*http.Cookie{c.cookie()})
+	}
+	return j, nil
+}
+
+func (j *CookieJar) Cookies(u *url.URL) []*http.Cookie {
+	return j.jar.Cookies(u)
+}
+
+// SetCookies stores cookies set by a response from u and saves the jar.
+func (j *CookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.jar.SetCookies(u, cookies)
+
+	j.mu.Lock()
+	now := time.Now()
+	for _, c := range cookies {
+		stored := storedCookie{
+			URL:      (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}).String(),
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			Expires:  c.Expires,
+			Secure:   c.Secure,
+			HttpOnly: c.HttpOnly,
+		}
+		if c.MaxAge > 0 {
+			stored.Expires = now.Add(time.Duration(c.MaxAge) * time.Second).UTC()
+		}
+		j.cookies = replaceCookie(j.cookies, stored, u.Host)
+		if c.MaxAge < 0 || (!stored.Expires.IsZero() && !stored.Expires.After(now)) {
+			j.cookies = j.cookies[:len(j.cookies)-1]
+		}
+	}
+	j.mu.Unlock()
+
+	if err := j.Save(); err != nil && j.OnSaveError != nil {
+		j.OnSaveError(err)
+	}
+}
+
+// replaceCookie removes any cookie c replaces and appends c.
+func replaceCookie(cookies []storedCookie, c storedCookie, host string) []storedCookie {
+	kept := cookies[:0]
+	for _, existing := range cookies {
+		u, err := url.Parse(existing.URL)
+		if err == nil && u.Host == host && existing.Name == c.Name && existing.Domain == c.Domain && existing.Path == c.Path {
+			continue
+		}
+		kept = append(kept, existing)
+	}
+	return append(kept, c)
+}
+
+// Has reports whether the jar holds cookies for u.
+func (j *CookieJar) Has(u *url.URL) bool {
+	return len(j.jar.Cookies(u)) > 0
+}
+
+// Clear removes every cookie of the profile and reports whether there were
+// any.
+func (j *CookieJar) Clear() (bool, error) {
+	j.mu.Lock()
+	had := len(j.cookies) > 0
+	j.cookies = nil
+	j.jar, _ = cookiejar.New(nil)
+	j.mu.Unlock()
+
+	return had, j.Save()
+}
+
+// Save writes the profile's cookies, leaving other profiles' untouched. The
+// file is readable only by the current user.
+func (j *CookieJar) Save() error {
+	file, err := j.read()
+	if err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	if len(j.cookies) == 0 {
+		delete(file.Profiles, j.profile)
+	} else {
+		file.Profiles[j.profile] = append([]storedCookie(nil), j.cookies...)
+	}
+	j.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(j.path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cookies: %w", err)
+	}
+
+	tmp := j.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write cookies: %w", err)
+	}
+	if err := os.Rename(tmp, j.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write cookies: %w", err)
+	}
+	return nil
+}
+
+func (j *CookieJar) read() (*cookieFile, error) {
+	file := &cookieFile{Profiles: make(map[string][]storedCookie)}
+
+	data, err := os.ReadFile(j.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return file, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cookies: %w", err)
+	}
+	if err := json.Unmarshal(data, file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", j.path, err)
+	}
+	if file.Profiles == nil {
+		file.Profiles = make(map[string][]storedCookie)
+	}
+	return file, nil
+}
+
+func (c storedCookie) cookie() *http.Cookie {
+	return &http.Cookie{
+		Name:     c.Name,
+		Value:    c.Value,
+		Domain:   c.Domain,
+		Path:     c.Path,
+		Expires:  c.Expires,
+		Secure:   c.Secure,
+		HttpOnly: c.HttpOnly,
+	}
+}
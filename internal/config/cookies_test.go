@@ -0,0 +1,66 @@
+package config
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCookieJar(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(EnvVarConfigDir, dir)
+
+	server, _ := url.Parse("https://verify.example.com/api/compare")
+	other, _ := url.Parse("https://other.example.com/")
+
+	jar, err := LoadCookieJar("work")
+	if err != nil {
+		t.Fatalf("LoadCookieJar() error = %v", err)
+	}
+	jar.SetCookies(server, []*http.Cookie{
+		{Name: "session", Value: "abc", Path: "/"},
+		{Name: "short", Value: "gone", Path: "/", MaxAge: -1},
+	})
+
+	info, err := os.Stat(filepath.Join(dir, cookiesFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("cookies file mode = %o, want 600", perm)
+	}
+
+	reloaded, err := LoadCookieJar("work")
+	if err != nil {
+		t.Fatalf("LoadCookieJar() error = %v", err)
+	}
+	cookies := reloaded.Cookies(server)
+	if len(cookies) != 1 || cookies[0].Name != "session" || cookies[0].Value != "abc" {
+		t.Errorf("reloaded Cookies() = %v, want session=abc", cookies)
+	}
+	if reloaded.Has(other) {
+		t.Error("cookies sent to a different host")
+	}
+
+	reloaded.SetCookies(server, []*http.Cookie{{Name: "session", Value: "def", Path: "/"}})
+	again, _ := LoadCookieJar("work")
+	if cookies := again.Cookies(server); len(cookies) != 1 || cookies[0].Value != "def" {
+		t.Errorf("updated Cookies() = %v, want session=def", cookies)
+	}
+
+	personal, _ := LoadCookieJar(DefaultProfile)
+	if personal.Has(server) {
+		t.Error("cookies shared between profiles")
+	}
+
+	had, err := again.Clear()
+	if err != nil || !had {
+		t.Fatalf("Clear() = %v, %v", had, err)
+	}
+	cleared, _ := LoadCookieJar("work")
+	if cleared.Has(server) {
+		t.Error("cookies kept after Clear()")
+	}
+}
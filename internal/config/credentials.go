@@ -0,0 +1,180 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	EnvVarConfigDir = "QMDVERIFY_CONFIG_DIR"
+	EnvVarProfile   = "QMDVERIFY_PROFILE"
+	EnvVarOrg       = "QMDVERIFY_ORG"
+	EnvVarToken     = "QMDVERIFY_TOKEN"
+	DefaultProfile  = "default"
+	credentialsFile = "credentials.json"
+)
+
+var (
+	profileOverride string
+	orgOverride     string
+	tokenOverride   string
+)
+
+type Credentials struct {
+	Server       string    `json:"server"`
+	Token        string    `json:"token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at,omitempty"`
+	User         string    `json:"user,omitempty"`
+	Org          string    `json:"org,omitempty"`
+}
+
+type CredentialStore struct {
+	path     string
+	Profiles map[string]Credentials `json:"profiles"`
+}
+
+// SetProfile selects the profile used by Load, taking precedence over
+// QMDVERIFY_PROFILE.
+func SetProfile(name string) {
+	profileOverride = name
+}
+
+// SetOrg selects the organization sent to the server, taking precedence over
+// QMDVERIFY_ORG and the profile's stored organization.
+func SetOrg(org string) {
+	orgOverride = org
+}
+
+// SetToken sets the bearer token sent to the server, taking precedence over
+// QMDVERIFY_TOKEN and the profile's stored token.
+func SetToken(token string) {
+	tokenOverride = token
+}
+
+// ActiveProfile returns the profile selected by --profile, QMDVERIFY_PROFILE
+// or the config file, or the default profile.
+func ActiveProfile() string {
+	if profileOverride != "" {
+		return profileOverride
+	}
+	if profile := os.Getenv(EnvVarProfile); profile != "" {
+		return profile
+	}
+	if settings, err := LoadSettings(); err == nil && settings.Profile != "" {
+		return settings.Profile
+	}
+	return DefaultProfile
+}
+
+// Dir returns the per-user configuration directory, QMDVERIFY_CONFIG_DIR if
+// set.
+func Dir() (string, error) {
+	if dir := os.Getenv(EnvVarConfigDir); dir != "" {
+		return dir, nil
+	}
+
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate config directory: %w", err)
+	}
+
+	return filepath.Join(base, "qmdverify"), nil
+}
+
+func LoadCredentialStore() (*CredentialStore, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+
+	store := &CredentialStore{
+		path:     filepath.Join(dir, credentialsFile),
+		Profiles: make(map[string]Credentials),
+	}
+
+	data, err := os.ReadFile(store.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials: %w", err)
+	}
+
+	if err := json.Unmarshal(data, store); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", store.path, err)
+	}
+	if store.Profiles == nil {
+		store.Profiles = make(map[string]Credentials)
+	}
+
+	return store, nil
+}
+
+func (s *CredentialStore) Path() string {
+	return s.path
+}
+
+func (s *CredentialStore) Get(profile string) (Credentials, bool) {
+	creds, ok := s.Profiles[profile]
+	return creds, ok
+}
+
+func (s *CredentialStore) Set(profile string, creds Credentials) {
+	s.Profiles[profile] = creds
+}
+
+// UpdateToken replaces the tokens stored for a profile after a refresh.
+func UpdateToken(profile, token, refreshToken string, expiresAt time.Time) error {
+	store, err := LoadCredentialStore()
+	if err != nil {
+		return err
+	}
+
+	creds, ok := store.Get(profile)
+	if !ok {
+		return nil
+	}
+
+	creds.Token = token
+	if refreshToken != "" {
+		creds.RefreshToken = refreshToken
+	}
+	creds.ExpiresAt = expiresAt
+	store.Set(profile, creds)
+
+	return store.Save()
+}
+
+func (s *CredentialStore) Delete(profile string) bool {
+	_, ok := s.Profiles[profile]
+	delete(s.Profiles, profile)
+	return ok
+}
+
+// Save writes the store readable only by the current user.
+func (s *CredentialStore) Save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode credentials: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write credentials: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write credentials: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,206 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCredentialStore(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(EnvVarConfigDir, dir)
+
+	store, err := LoadCredentialStore()
+	if err != nil {
+		t.Fatalf("LoadCredentialStore() error = %v", err)
+	}
+	if _, ok := store.Get(DefaultProfile); ok {
+		t.Error("new store has credentials")
+	}
+
+	store.Set("work", Credentials{Server: "https://work.example.com", Token: "abc"})
+	if err := store.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, credentialsFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("credentials file mode = %o, want 600", perm)
+	}
+
+	reloaded, err := LoadCredentialStore()
+	if err != nil {
+		t.Fatalf("LoadCredentialStore() error = %v", err)
+	}
+	creds, ok := reloaded.Get("work")
+	if !ok || creds.Token != "abc" {
+		t.Errorf("Get(work) = %+v, %v", creds, ok)
+	}
+
+	if !reloaded.Delete("work") || reloaded.Delete("work") {
+		t.Error("Delete() should report whether the profile existed")
+	}
+}
+
+func TestActiveProfile(t *testing.T) {
+	t.Setenv(EnvVarConfigDir, t.TempDir())
+	t.Cleanup(func() { SetProfile("") })
+
+	t.Setenv(EnvVarProfile, "")
+	if got := ActiveProfile(); got != DefaultProfile {
+		t.Errorf("ActiveProfile() = %q, want %q", got, DefaultProfile)
+	}
+
+	t.Setenv(EnvVarProfile, "ci")
+	if got := ActiveProfile(); got != "ci" {
+		t.Errorf("ActiveProfile() = %q, want ci", got)
+	}
+
+	SetProfile("work")
+	if got := ActiveProfile(); got != "work" {
+		t.Errorf("ActiveProfile() = %q, want work", got)
+	}
+}
+
+func TestLoadWithCredentials(t *testing.T) {
+	t.Setenv(EnvVarConfigDir, t.TempDir())
+	t.Setenv(EnvVarProfile, "")
+
+	store, err := LoadCredentialStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set(DefaultProfile, Credentials{Server: "https://work.example.com", Token: "abc"})
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		host      string
+		wantHost  string
+		wantToken string
+	}{
+		{name: "profile server", host: "", wantHost: "https://work.example.com", wantToken: "abc"},
+		{name: "same server via env", host: "https://work.example.com/", wantHost: "https://work.example.com", wantToken: "abc"},
+		{name: "different server", host: "https://other.example.com", wantHost: "https://other.example.com", wantToken: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(EnvVarHost, tt.host)
+			cfg := mustLoad(t)
+			if cfg.ServerHost != tt.wantHost {
+				t.Errorf("ServerHost = %q, want %q", cfg.ServerHost, tt.wantHost)
+			}
+			if cfg.Token != tt.wantToken {
+				t.Errorf("Token = %q, want %q", cfg.Token, tt.wantToken)
+			}
+		})
+	}
+}
+
+func TestUpdateToken(t *testing.T) {
+	t.Setenv(EnvVarConfigDir, t.TempDir())
+
+	store, err := LoadCredentialStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set("work", Credentials{Server: "https://work.example.com", Token: "old", RefreshToken: "refresh-1", User: "scott"})
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	expires := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := UpdateToken("work", "new", "", expires); err != nil {
+		t.Fatalf("UpdateToken() error = %v", err)
+	}
+	if err := UpdateToken("missing", "new", "", expires); err != nil {
+		t.Fatalf("UpdateToken() for unknown profile error = %v", err)
+	}
+
+	reloaded, err := LoadCredentialStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	creds, _ := reloaded.Get("work")
+	want := Credentials{Server: "https://work.example.com", Token: "new", RefreshToken: "refresh-1", ExpiresAt: expires, User: "scott"}
+	if creds != want {
+		t.Errorf("creds = %+v, want %+v", creds, want)
+	}
+	if _, ok := reloaded.Get("missing"); ok {
+		t.Error("UpdateToken() created an unknown profile")
+	}
+}
+
+func TestLoadOrg(t *testing.T) {
+	t.Setenv(EnvVarConfigDir, t.TempDir())
+	t.Setenv(EnvVarProfile, "")
+	t.Setenv(EnvVarHost, "")
+	t.Cleanup(func() { SetOrg("") })
+
+	store, err := LoadCredentialStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set(DefaultProfile, Credentials{Server: "https://work.example.com", Token: "abc", Org: "team-a"})
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(EnvVarOrg, "")
+	if got := mustLoad(t).Org; got != "team-a" {
+		t.Errorf("Org = %q, want profile org team-a", got)
+	}
+
+	t.Setenv(EnvVarOrg, "team-b")
+	if got := mustLoad(t).Org; got != "team-b" {
+		t.Errorf("Org = %q, want env org team-b", got)
+	}
+
+	SetOrg("team-c")
+	if got := mustLoad(t).Org; got != "team-c" {
+		t.Errorf("Org = %q, want flag org team-c", got)
+	}
+}
+
+func TestLoadToken(t *testing.T) {
+	t.Setenv(EnvVarConfigDir, t.TempDir())
+	t.Setenv(EnvVarProfile, "")
+	t.Setenv(EnvVarHost, "")
+	t.Cleanup(func() { SetToken("") })
+
+	store, err := LoadCredentialStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set(DefaultProfile, Credentials{Server: "https://work.example.com", Token: "stored", RefreshToken: "refresh"})
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(EnvVarToken, "")
+	if cfg := mustLoad(t); cfg.Token != "stored" || cfg.RefreshToken != "refresh" {
+		t.Errorf("Token = %q, RefreshToken = %q, want the stored credentials", cfg.Token, cfg.RefreshToken)
+	}
+
+	t.Setenv(EnvVarToken, "from-env")
+	if cfg := mustLoad(t); cfg.Token != "from-env" || cfg.RefreshToken != "" {
+		t.Errorf("Token = %q, RefreshToken = %q, want env token without refresh", cfg.Token, cfg.RefreshToken)
+	}
+
+	SetToken("from-flag")
+	if got := mustLoad(t).Token; got != "from-flag" {
+		t.Errorf("Token = %q, want flag token", got)
+	}
+
+	t.Setenv(EnvVarHost, "https://other.example.com")
+	if got := mustLoad(t).Token; got != "from-flag" {
+		t.Errorf("Token = %q, want flag token for any server", got)
+	}
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+const ProjectConfigFile = ".qmdverify.yaml"
+
+type ProjectConfig struct {
+	Path   string   `yaml:"-"`
+	Policy []string `yaml:"policy"`
+}
+
+// LoadProject searches startDir and its parents for a project config file.
+// A missing file is not an error and yields an empty config.
+func LoadProject(startDir string) (*ProjectConfig, error) {
+	dir, err := filepath.Abs(startDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve directory %s: %w", startDir, err)
+	}
+
+	for {
+		path := filepath.Join(dir, ProjectConfigFile)
+		if _, err := os.Stat(path); err == nil {
+			return loadProjectFile(path)
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return &ProjectConfig{}, nil
+		}
+		dir = parent
+	}
+}
+
+func loadProjectFile(path string) (*ProjectConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read project config %s: %w", path, err)
+	}
+
+	var cfg ProjectConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse project config %s: %w", path, err)
+	}
+	cfg.Path = path
+
+	return &cfg, nil
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadProject(t *testing.T) {
+	t.Run("no config file", func(t *testing.T) {
+		cfg, err := LoadProject(t.TempDir())
+		if err != nil {
+			t.Fatalf("LoadProject() error = %v", err)
+		}
+		if cfg.Path != "" || len(cfg.Policy) != 0 {
+			t.Errorf("LoadProject() = %+v, want empty config", cfg)
+		}
+	})
+
+	t.Run("config in parent directory", func(t *testing.T) {
+		root := t.TempDir()
+		nested := filepath.Join(root, "overlays", "nested")
+		if err := os.MkdirAll(nested, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		content := "policy:\n  - \"rmpp >= 3.20\"\n  - \"rm2 latest-3\"\n"
+		if err := os.WriteFile(filepath.Join(root, ProjectConfigFile), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+
+		cfg, err := LoadProject(nested)
+		if err != nil {
+			t.Fatalf("LoadProject() error = %v", err)
+		}
+		if cfg.Path != filepath.Join(root, ProjectConfigFile) {
+			t.Errorf("LoadProject() Path = %v", cfg.Path)
+		}
+		want := []string{"rmpp >= 3.20", "rm2 latest-3"}
+		if !reflect.DeepEqual(cfg.Policy, want) {
+			t.Errorf("LoadProject() Policy = %v, want %v", cfg.Policy, want)
+		}
+	})
+
+	t.Run("invalid yaml", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, ProjectConfigFile), []byte("policy: [\n"), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		if _, err := LoadProject(dir); err == nil {
+			t.Error("LoadProject() expected error for invalid YAML")
+		}
+	})
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const RCFile = ".qmdverifyrc"
+
+// RC holds flags pinned by .qmdverifyrc files. Each line is "key = value"
+// for the keys device, version, failed-only, output, sort-versions,
+// device-order and symbols; device, version, output and device-order accept
+// comma-separated lists, and output values take the same format=path form as
+// --output. The symbol-compatible, symbol-incompatible and
+// symbol-no-data keys replace single status glyphs. Unset fields leave the
+// flag alone.
+type RC struct {
+	Devices      []string
+	Versions     []string
+	FailedOnly   *bool
+	Output       []string
+	SortVersions string
+	DeviceOrder  []string
+	Symbols      string
+	Glyphs       Glyphs
+}
+
+// Glyphs holds custom status indicators; empty fields keep the defaults.
+type Glyphs struct {
+	Compatible   string
+	Incompatible string
+	NoData       string
+}
+
+// LoadRC merges every rc file from the filesystem root down to dir, so a
+// subdirectory's file overrides its parents' for the keys it sets.
+func LoadRC(dir string) (*RC, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve directory %s: %w", dir, err)
+	}
+
+	var dirs []string
+	for {
+		dirs = append(dirs, dir)
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	rc := &RC{}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		path := filepath.Join(dirs[i], RCFile)
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if err := rc.parse(path, data); err != nil {
+			return nil, err
+		}
+	}
+	return rc, nil
+}
+
+func (rc *RC) parse(path string, data []byte) error {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(text, "=")
+		if !ok {
+			return fmt.Errorf("%s:%d: expected key = value", path, line)
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "device":
+			rc.Devices = splitList(value)
+		case "version":
+			rc.Versions = splitList(value)
+		case "failed-only":
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("%s:%d: failed-only must be true or false", path, line)
+			}
+			rc.FailedOnly = &b
+		case "output":
+			rc.Output = splitList(value)
+		case "sort-versions":
+			if value != "asc" && value != "desc" {
+				return fmt.Errorf("%s:%d: sort-versions must be asc or desc", path, line)
+			}
+			rc.SortVersions = value
+		case "device-order":
+			rc.DeviceOrder = splitList(value)
+		case "symbols":
+			if value != "default" && value != "safe" && value != "emoji" && value != "plain" {
+				return fmt.Errorf("%s:%d: symbols must be default, safe, emoji or plain", path, line)
+			}
+			rc.Symbols = value
+		case "symbol-compatible":
+			rc.Glyphs.Compatible = value
+		case "symbol-incompatible":
+			rc.Glyphs.Incompatible = value
+		case "symbol-no-data":
+			rc.Glyphs.NoData = value
+		default:
+			return fmt.Errorf("%s:%d: unknown key %q", path, line, key)
+		}
+	}
+	return scanner.Err()
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadRC(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "overlays", "rmpp-only")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	write := func(dir, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, RCFile), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write rc file: %v", err)
+		}
+	}
+	write(root, "# defaults\nversion = 3.20, 3.22\nfailed-only = true\noutput = toltec\n")
+	write(sub, "device = rmpp\nfailed-only = false\nsort-versions = asc\ndevice-order = rmpp, rm2\nsymbols = safe\nsymbol-incompatible = 🛑\n")
+
+	t.Run("no rc files", func(t *testing.T) {
+		rc, err := LoadRC(t.TempDir())
+		if err != nil {
+			t.Fatalf("LoadRC() error = %v", err)
+		}
+		if !reflect.DeepEqual(rc, &RC{}) {
+			t.Errorf("LoadRC() = %+v, want empty", rc)
+		}
+	})
+
+	t.Run("root", func(t *testing.T) {
+		rc, err := LoadRC(root)
+		if err != nil {
+			t.Fatalf("LoadRC() error = %v", err)
+		}
+		if !reflect.DeepEqual(rc.Versions, []string{"3.20", "3.22"}) || rc.FailedOnly == nil || !*rc.FailedOnly || !reflect.DeepEqual(rc.Output, []string{"toltec"}) || rc.Devices != nil {
+			t.Errorf("LoadRC() = %+v", rc)
+		}
+	})
+
+	t.Run("subdirectory overrides parent", func(t *testing.T) {
+		rc, err := LoadRC(sub)
+		if err != nil {
+			t.Fatalf("LoadRC() error = %v", err)
+		}
+		if !reflect.DeepEqual(rc.Devices, []string{"rmpp"}) || !reflect.DeepEqual(rc.Versions, []string{"3.20", "3.22"}) {
+			t.Errorf("LoadRC() = %+v", rc)
+		}
+		if rc.FailedOnly == nil || *rc.FailedOnly {
+			t.Errorf("FailedOnly = %v, want false", rc.FailedOnly)
+		}
+		if rc.SortVersions != "asc" || !reflect.DeepEqual(rc.DeviceOrder, []string{"rmpp", "rm2"}) {
+			t.Errorf("LoadRC() ordering = %q, %v", rc.SortVersions, rc.DeviceOrder)
+		}
+		if rc.Symbols != "safe" || rc.Glyphs != (Glyphs{Incompatible: "🛑"}) {
+			t.Errorf("Symbols = %q, Glyphs = %+v", rc.Symbols, rc.Glyphs)
+		}
+	})
+
+	t.Run("invalid lines", func(t *testing.T) {
+		for _, content := range []string{"device rmpp\n", "color = red\n", "failed-only = maybe\n", "sort-versions = random\n", "symbols = loud\n"} {
+			dir := t.TempDir()
+			write(dir, content)
+			if _, err := LoadRC(dir); err == nil || !strings.Contains(err.Error(), RCFile+":1") {
+				t.Errorf("LoadRC(%q) error = %v, want a line reference", content, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,159 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const settingsFile = "config.yaml"
+
+// Settings keys, in the order they are listed.
+const (
+	KeyServer  = "server"
+	KeyOrg     = "org"
+	KeyMirrors = "mirrors"
+	KeyProfile = "profile"
+)
+
+var SettingsKeys = []string{KeyServer, KeyOrg, KeyMirrors, KeyProfile}
+
+// Settings is the per-user config file. Its values apply when neither a flag
+// nor an environment variable sets them.
+type Settings struct {
+	path    string
+	Server  string   `yaml:"server,omitempty"`
+	Org     string   `yaml:"org,omitempty"`
+	Mirrors []string `yaml:"mirrors,omitempty"`
+	Profile string   `yaml:"profile,omitempty"`
+}
+
+// LoadSettings reads the config file. A missing file yields empty settings.
+func LoadSettings() (*Settings, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+
+	settings := &Settings{path: filepath.Join(dir, settingsFile)}
+	data, err := os.ReadFile(settings.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return settings, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	if err := yaml.Unmarshal(data, settings); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", settings.path, err)
+	}
+	return settings, nil
+}
+
+func (s *Settings) Path() string {
+	return s.path
+}
+
+// Get returns the value of key, with mirrors joined by commas, and whether
+// it is set.
+func (s *Settings) Get(key string) (string, bool, error) {
+	var value string
+	switch key {
+	case KeyServer:
+		value = s.Server
+	case KeyOrg:
+		value = s.Org
+	case KeyMirrors:
+		value = strings.Join(s.Mirrors, ",")
+	case KeyProfile:
+		value = s.Profile
+	default:
+		return "", false, unknownKey(key)
+	}
+	return value, value != "", nil
+}
+
+// Set validates and sets key. Servers are normalized as Load would, and
+// mirrors are given comma-separated.
+func (s *Settings) Set(key, value string) error {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return fmt.Errorf("%s cannot be empty; use unset to remove it", key)
+	}
+
+	switch key {
+	case KeyServer:
+		host, err := NormalizeHost(value)
+		if err != nil {
+			return err
+		}
+		s.Server = host
+	case KeyOrg:
+		s.Org = value
+	case KeyMirrors:
+		var mirrors []string
+		for _, mirror := range strings.Split(value, ",") {
+			if strings.TrimSpace(mirror) == "" {
+				continue
+			}
+			host, err := NormalizeHost(mirror)
+			if err != nil {
+				return fmt.Errorf("invalid mirror: %w", err)
+			}
+			mirrors = append(mirrors, host)
+		}
+		s.Mirrors = mirrors
+	case KeyProfile:
+		s.Profile = value
+	default:
+		return unknownKey(key)
+	}
+	return nil
+}
+
+// Unset removes key.
+func (s *Settings) Unset(key string) error {
+	switch key {
+	case KeyServer:
+		s.Server = ""
+	case KeyOrg:
+		s.Org = ""
+	case KeyMirrors:
+		s.Mirrors = nil
+	case KeyProfile:
+		s.Profile = ""
+	default:
+		return unknownKey(key)
+	}
+	return nil
+}
+
+// Save writes the config file readable only by the current user.
+func (s *Settings) Save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return nil
+}
+
+func unknownKey(key string) error {
+	return fmt.Errorf("unknown config key %q (use %s)", key, strings.Join(SettingsKeys, ", "))
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSettings(t *testing.T) {
+	t.Setenv(EnvVarConfigDir, t.TempDir())
+
+	settings, err := LoadSettings()
+	if err != nil {
+		t.Fatalf("LoadSettings() error = %v", err)
+	}
+	if _, set, _ := settings.Get(KeyServer); set {
+		t.Error("server set in a missing config file")
+	}
+
+	for key, value := range map[string]string{
+		KeyServer:  "https://qmd.example.com/",
+		KeyOrg:     "team-a",
+		KeyMirrors: "https://a.example.com, https://b.example.com/",
+		KeyProfile: "work",
+	} {
+		if err := settings.Set(key, value); err != nil {
+			t.Fatalf("Set(%s) error = %v", key, err)
+		}
+	}
+	if err := settings.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded, err := LoadSettings()
+	if err != nil {
+		t.Fatalf("LoadSettings() error = %v", err)
+	}
+	want := &Settings{
+		path:    settings.Path(),
+		Server:  "https://qmd.example.com",
+		Org:     "team-a",
+		Mirrors: []string{"https://a.example.com", "https://b.example.com"},
+		Profile: "work",
+	}
+	if !reflect.DeepEqual(loaded, want) {
+		t.Errorf("LoadSettings() = %+v, want %+v", loaded, want)
+	}
+	if got, _, _ := loaded.Get(KeyMirrors); got != "https://a.example.com,https://b.example.com" {
+		t.Errorf("Get(mirrors) = %q", got)
+	}
+
+	if err := loaded.Unset(KeyOrg); err != nil {
+		t.Fatalf("Unset() error = %v", err)
+	}
+	if _, set, _ := loaded.Get(KeyOrg); set {
+		t.Error("org still set after Unset()")
+	}
+
+	for _, tt := range []struct{ key, value string }{
+		{"color", "always"},
+		{KeyServer, "ftp://qmd.example.com"},
+		{KeyOrg, " "},
+	} {
+		if err := loaded.Set(tt.key, tt.value); err == nil {
+			t.Errorf("Set(%q, %q) expected error", tt.key, tt.value)
+		}
+	}
+}
+
+func TestLoadSettingsPrecedence(t *testing.T) {
+	t.Setenv(EnvVarConfigDir, t.TempDir())
+	t.Setenv(EnvVarProfile, "")
+	t.Setenv(EnvVarHost, "")
+	t.Setenv(EnvVarOrg, "")
+	t.Setenv(EnvVarMirrors, "")
+
+	store, err := LoadCredentialStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set("work", Credentials{Server: "https://login.example.com", Token: "abc", Org: "team-a"})
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	settings, err := LoadSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings.Set(KeyProfile, "work")
+	if err := settings.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if cfg := mustLoad(t); cfg.Profile != "work" || cfg.ServerHost != "https://login.example.com" || cfg.Token != "abc" {
+		t.Errorf("Load() = %+v, want the work profile's server and token", cfg)
+	}
+
+	settings.Set(KeyServer, "https://config.example.com")
+	settings.Set(KeyOrg, "team-b")
+	settings.Set(KeyMirrors, "https://mirror.example.com")
+	if err := settings.Save(); err != nil {
+		t.Fatal(err)
+	}
+	cfg := mustLoad(t)
+	if cfg.ServerHost != "https://config.example.com" || cfg.Org != "team-b" || cfg.Token != "" {
+		t.Errorf("Load() = %+v, want the config file's server and org without the profile's token", cfg)
+	}
+	if !reflect.DeepEqual(cfg.Mirrors, []string{"https://mirror.example.com"}) {
+		t.Errorf("Mirrors = %v, want the config file's mirror", cfg.Mirrors)
+	}
+
+	t.Setenv(EnvVarHost, "https://env.example.com")
+	t.Setenv(EnvVarOrg, "team-c")
+	if cfg := mustLoad(t); cfg.ServerHost != "https://env.example.com" || cfg.Org != "team-c" {
+		t.Errorf("Load() = %+v, want the environment to win", cfg)
+	}
+}
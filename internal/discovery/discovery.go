@@ -0,0 +1,133 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/cache"
+)
+
+const (
+	EnvVarDomain = "QMDVERIFY_DISCOVER"
+	Service      = "qmdverify"
+	WellKnown    = "/.well-known/qmdverify"
+	Timeout      = 5 * time.Second
+	CacheTTL     = time.Hour
+	cacheFile    = "discovery.json"
+)
+
+var ErrNotFound = errors.New("no qmdverify server advertised")
+
+// Seams for tests.
+var (
+	lookupSRV = func(ctx context.Context, domain string) ([]*net.SRV, error) {
+		_, records, err := net.DefaultResolver.LookupSRV(ctx, Service, "tcp", domain)
+		return records, err
+	}
+	wellKnownURL = func(domain string) string {
+		return "https://" + domain + WellKnown
+	}
+	now = time.Now
+)
+
+// WellKnownDocument is served at https://<domain>/.well-known/qmdverify.
+type WellKnownDocument struct {
+	Server string `json:"server"`
+}
+
+type cachedServer struct {
+	Server     string    `json:"server"`
+	ResolvedAt time.Time `json:"resolved_at"`
+}
+
+// Discover finds the API endpoint for a domain, first from the
+// _qmdverify._tcp.<domain> SRV record, then from the well-known document.
+// Results are cached for CacheTTL.
+func Discover(domain string) (string, error) {
+	domain = strings.TrimSuffix(strings.TrimSpace(domain), ".")
+	if domain == "" {
+		return "", fmt.Errorf("discovery domain is empty")
+	}
+
+	cached := make(map[string]cachedServer)
+	cache.ReadJSON(cacheFile, &cached)
+	if entry, ok := cached[domain]; ok && now().Sub(entry.ResolvedAt) < CacheTTL {
+		return entry.Server, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+
+	server, srvErr := fromSRV(ctx, domain)
+	if srvErr != nil {
+		var err error
+		server, err = fromWellKnown(ctx, domain)
+		if err != nil {
+			return "", fmt.Errorf("%w for %s: SRV: %v; well-known: %v", ErrNotFound, domain, srvErr, err)
+		}
+	}
+
+	cached[domain] = cachedServer{Server: server, ResolvedAt: now().UTC()}
+	cache.WriteJSON(cacheFile, cached)
+
+	return server, nil
+}
+
+func fromSRV(ctx context.Context, domain string) (string, error) {
+	records, err := lookupSRV(ctx, domain)
+	if err != nil {
+		return "", err
+	}
+	if len(records) == 0 || records[0].Target == "." {
+		return "", fmt.Errorf("no SRV records")
+	}
+
+	// The resolver returns records sorted by priority and randomized by
+	// weight, so the first one is the one to use.
+	record := records[0]
+	host := strings.TrimSuffix(record.Target, ".")
+	switch record.Port {
+	case 443:
+		return "https://" + host, nil
+	case 80:
+		return "http://" + host, nil
+	}
+	return "https://" + net.JoinHostPort(host, strconv.Itoa(int(record.Port))), nil
+}
+
+func fromWellKnown(ctx context.Context, domain string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, wellKnownURL(domain), nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	var doc WellKnownDocument
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return "", fmt.Errorf("invalid document: %w", err)
+	}
+
+	u, err := url.Parse(doc.Server)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("invalid server URL %q", doc.Server)
+	}
+
+	return strings.TrimSuffix(doc.Server, "/"), nil
+}
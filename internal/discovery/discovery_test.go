@@ -0,0 +1,125 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/cache"
+)
+
+func stubSRV(t *testing.T, records []*net.SRV, err error) *int {
+	t.Helper()
+	calls := 0
+	original := lookupSRV
+	lookupSRV = func(ctx context.Context, domain string) ([]*net.SRV, error) {
+		calls++
+		return records, err
+	}
+	t.Cleanup(func() { lookupSRV = original })
+	return &calls
+}
+
+func stubWellKnown(t *testing.T, handler http.HandlerFunc) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	original := wellKnownURL
+	wellKnownURL = func(domain string) string { return server.URL + WellKnown }
+	t.Cleanup(func() {
+		wellKnownURL = original
+		server.Close()
+	})
+}
+
+func TestDiscover_SRV(t *testing.T) {
+	tests := []struct {
+		name   string
+		record *net.SRV
+		want   string
+	}{
+		{name: "https port", record: &net.SRV{Target: "qmd.example.com.", Port: 443}, want: "https://qmd.example.com"},
+		{name: "http port", record: &net.SRV{Target: "qmd.example.com.", Port: 80}, want: "http://qmd.example.com"},
+		{name: "custom port", record: &net.SRV{Target: "qmd.example.com.", Port: 8443}, want: "https://qmd.example.com:8443"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(cache.EnvVarCacheDir, t.TempDir())
+			stubSRV(t, []*net.SRV{tt.record}, nil)
+
+			got, err := Discover("example.com")
+			if err != nil {
+				t.Fatalf("Discover() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Discover() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiscover_WellKnown(t *testing.T) {
+	t.Setenv(cache.EnvVarCacheDir, t.TempDir())
+	stubSRV(t, nil, errors.New("no such host"))
+	stubWellKnown(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != WellKnown {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(WellKnownDocument{Server: "https://qmd.example.com/"})
+	})
+
+	got, err := Discover("example.com")
+	if err != nil {
+		t.Fatalf("Discover() error = %v", err)
+	}
+	if got != "https://qmd.example.com" {
+		t.Errorf("Discover() = %q", got)
+	}
+}
+
+func TestDiscover_NotFound(t *testing.T) {
+	t.Setenv(cache.EnvVarCacheDir, t.TempDir())
+	stubSRV(t, nil, errors.New("no such host"))
+
+	for _, body := range []string{`{"server": "ftp://qmd.example.com"}`, `not json`} {
+		stubWellKnown(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(body))
+		})
+		if _, err := Discover("example.com"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Discover() with %s error = %v, want ErrNotFound", body, err)
+		}
+	}
+}
+
+func TestDiscover_Cache(t *testing.T) {
+	t.Setenv(cache.EnvVarCacheDir, t.TempDir())
+	calls := stubSRV(t, []*net.SRV{{Target: "qmd.example.com.", Port: 443}}, nil)
+
+	current := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	originalNow := now
+	now = func() time.Time { return current }
+	defer func() { now = originalNow }()
+
+	for i := 0; i < 2; i++ {
+		if _, err := Discover("example.com"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if *calls != 1 {
+		t.Errorf("SRV looked up %d times, want 1 while cached", *calls)
+	}
+
+	current = current.Add(CacheTTL + time.Minute)
+	if _, err := Discover("example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if *calls != 2 {
+		t.Errorf("SRV looked up %d times, want 2 after the cache expired", *calls)
+	}
+}
@@ -0,0 +1,39 @@
+package display
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/expected"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/i18n"
+)
+
+var deviationMessages = map[expected.Kind]string{
+	expected.Gained:     i18n.MsgAssertGained,
+	expected.Lost:       i18n.MsgAssertLost,
+	expected.Missing:    i18n.MsgAssertMissing,
+	expected.Unexpected: i18n.MsgAssertUnexpected,
+}
+
+func RenderAssertReport(w io.Writer, deviations []expected.Deviation, entryCount int) error {
+	var output strings.Builder
+
+	if len(deviations) == 0 {
+		fmt.Fprintln(&output, compatibleStyle.Render(i18n.T(i18n.MsgAssertPassed, entryCount)))
+		_, err := io.WriteString(w, output.String())
+		return err
+	}
+
+	fmt.Fprintln(&output, incompatibleStyle.Render(i18n.T(i18n.MsgAssertDeviations, len(deviations))))
+	for _, d := range deviations {
+		line := fmt.Sprintf("  • %s: %s (%s) — %s", d.Kind, d.OSVersion, d.Device, i18n.T(deviationMessages[d.Kind]))
+		if d.Detail != "" {
+			line += ": " + d.Detail
+		}
+		fmt.Fprintln(&output, errorStyle.Render(line))
+	}
+
+	_, err := io.WriteString(w, output.String())
+	return err
+}
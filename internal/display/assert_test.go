@@ -0,0 +1,33 @@
+package display
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/expected"
+)
+
+func TestRenderAssertReport(t *testing.T) {
+	var buf bytes.Buffer
+	if err := RenderAssertReport(&buf, nil, 4); err != nil {
+		t.Fatalf("RenderAssertReport() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "4 entries") {
+		t.Errorf("passing report = %q", buf.String())
+	}
+
+	buf.Reset()
+	deviations := []expected.Deviation{
+		{Kind: expected.Gained, Device: "rm2", OSVersion: "3.20.0.92"},
+		{Kind: expected.Lost, Device: "rmpp", OSVersion: "3.22.0.64", Detail: "hash not found"},
+	}
+	if err := RenderAssertReport(&buf, deviations, 4); err != nil {
+		t.Fatalf("RenderAssertReport() error = %v", err)
+	}
+	for _, want := range []string{"(2)", "gained: 3.20.0.92 (rm2)", "lost: 3.22.0.64 (rmpp)", "hash not found"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("report missing %q:\n%s", want, buf.String())
+		}
+	}
+}
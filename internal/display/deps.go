@@ -0,0 +1,52 @@
+package display
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/formatter"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/i18n"
+)
+
+// RenderSharedDependencies prints each shared dependency once, with the
+// files that use it and the hashtables it fails on.
+func RenderSharedDependencies(w io.Writer, deps []formatter.SharedDependency) error {
+	if len(deps) == 0 {
+		return nil
+	}
+
+	var output strings.Builder
+	fmt.Fprintln(&output, titleStyle.Render(i18n.T(i18n.MsgSharedDepsTitle, len(deps))))
+
+	for _, dep := range deps {
+		fmt.Fprintf(&output, " %s  %s\n", dep.File, noDataStyle.Render("("+i18n.T(i18n.MsgSharedDepUsedBy, strings.Join(dep.UsedBy, ", "))+")"))
+
+		compatible := 0
+		var failures []string
+		for _, result := range dep.Results {
+			if result.Compatible() {
+				compatible++
+				continue
+			}
+			line := fmt.Sprintf("%s %s", result.Device, result.OSVersion)
+			if len(result.HashErrors) > 0 {
+				line += ": " + i18n.T(i18n.MsgSharedDepHashErrors, len(result.HashErrors))
+			}
+			failures = append(failures, line)
+		}
+
+		summary := i18n.T(i18n.MsgSharedDepCompatible, compatible, len(dep.Results))
+		if len(failures) == 0 {
+			fmt.Fprintf(&output, "   %s %s\n", compatibleMark(), summary)
+		} else {
+			fmt.Fprintf(&output, "   %s\n", summary)
+		}
+		for _, failure := range failures {
+			fmt.Fprintf(&output, "   %s %s\n", incompatibleMark(), failure)
+		}
+	}
+
+	_, err := io.WriteString(w, output.String())
+	return err
+}
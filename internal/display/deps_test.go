@@ -0,0 +1,51 @@
+package display
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/formatter"
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+func TestRenderSharedDependencies(t *testing.T) {
+	deps := []formatter.SharedDependency{
+		{
+			File:   "lib/common.qmd",
+			UsedBy: []string{"a.qmd", "b.qmd"},
+			Results: []formatter.DependencyResult{
+				{Device: "rm2", OSVersion: "3.23.0.64", Status: "incompatible", HashErrors: []api.HashError{{HashID: 1}, {HashID: 2}}},
+				{Device: "rmpp", OSVersion: "3.23.0.64", Status: "compatible"},
+			},
+		},
+		{
+			File:    "lib/ok.qmd",
+			UsedBy:  []string{"a.qmd", "c.qmd"},
+			Results: []formatter.DependencyResult{{Device: "rmpp", OSVersion: "3.23.0.64", Status: "compatible"}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := RenderSharedDependencies(&buf, deps); err != nil {
+		t.Fatalf("RenderSharedDependencies() error = %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"Shared dependencies (2)",
+		"lib/common.qmd",
+		"used by a.qmd, b.qmd",
+		"compatible with 1 of 2 hashtables",
+		"rm2 3.23.0.64: 2 hash error(s)",
+		"compatible with 1 of 1 hashtables",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("RenderSharedDependencies() output missing %q:\n%s", want, out)
+		}
+	}
+
+	buf.Reset()
+	if err := RenderSharedDependencies(&buf, nil); err != nil || buf.Len() != 0 {
+		t.Errorf("RenderSharedDependencies(nil) = %q, %v", buf.String(), err)
+	}
+}
@@ -0,0 +1,106 @@
+package display
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/i18n"
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+// DeviceSummary counts how many files of a batch run work on one device.
+type DeviceSummary struct {
+	Device       string `json:"device"`
+	Compatible   int    `json:"compatible"`
+	Incompatible int    `json:"incompatible"`
+	NoData       int    `json:"no_data"`
+}
+
+// ComputeDeviceSummaries aggregates per-file results by device. A file is
+// compatible with a device only if every checked version of it is.
+func ComputeDeviceSummaries(results map[string]*api.ComparisonResponse) []DeviceSummary {
+	deviceSet := make(map[string]matrixCell)
+	perFile := make(map[string]map[string]bool)
+	for file, response := range results {
+		status := make(map[string]bool)
+		for _, result := range response.Compatible {
+			deviceSet[result.Device] = matrixCell{}
+			if _, seen := status[result.Device]; !seen {
+				status[result.Device] = true
+			}
+		}
+		for _, result := range response.Incompatible {
+			deviceSet[result.Device] = matrixCell{}
+			status[result.Device] = false
+		}
+		perFile[file] = status
+	}
+
+	devices := getDeviceOrder(map[string]map[string]matrixCell{"": deviceSet})
+	summaries := make([]DeviceSummary, 0, len(devices))
+	for _, device := range devices {
+		summary := DeviceSummary{Device: device}
+		for _, status := range perFile {
+			compatible, hasData := status[device]
+			switch {
+			case !hasData:
+				summary.NoData++
+			case compatible:
+				summary.Compatible++
+			default:
+				summary.Incompatible++
+			}
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
+
+// RenderDeviceSummary prints a devices × files table for a batch run.
+func RenderDeviceSummary(w io.Writer, results map[string]*api.ComparisonResponse) error {
+	summaries := ComputeDeviceSummaries(results)
+	if len(summaries) == 0 {
+		return nil
+	}
+
+	var output strings.Builder
+	fmt.Fprintln(&output, titleStyle.Render(i18n.T(i18n.MsgDeviceSummaryTitle, len(results))))
+
+	headers := []string{
+		i18n.T(i18n.MsgHeaderDevice),
+		i18n.T(i18n.MsgHeaderFilesCompatible),
+		i18n.T(i18n.MsgHeaderFilesIncompatible),
+		i18n.T(i18n.MsgHeaderFilesNoData),
+	}
+	colWidths := []int{10, 14, 14, 14}
+	for i, header := range headers {
+		if width := lipgloss.Width(header) + 2; width > colWidths[i] {
+			colWidths[i] = width
+		}
+	}
+
+	renderTableHeaderToBuilder(&output, headers, colWidths)
+	renderTableSeparatorToBuilder(&output, colWidths)
+
+	for _, summary := range summaries {
+		compatible := fmt.Sprintf("%d", summary.Compatible)
+		incompatible := fmt.Sprintf("%d", summary.Incompatible)
+		if summary.Incompatible > 0 {
+			incompatible = incompatibleStyle.Render(incompatible)
+		} else if summary.NoData == 0 {
+			compatible = compatibleStyle.Render(compatible)
+		}
+		row := []string{
+			summary.Device,
+			compatible,
+			incompatible,
+			noDataStyle.Render(fmt.Sprintf("%d", summary.NoData)),
+		}
+		renderTableRowToBuilder(&output, row, colWidths)
+	}
+
+	_, err := io.WriteString(w, output.String())
+	return err
+}
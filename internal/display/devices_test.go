@@ -0,0 +1,69 @@
+package display
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+func TestComputeDeviceSummaries(t *testing.T) {
+	results := map[string]*api.ComparisonResponse{
+		"a.qmd": {
+			Compatible: []api.ComparisonResult{
+				{Device: "rmpp", OSVersion: "3.22.0.64"},
+				{Device: "rm2", OSVersion: "3.22.0.64"},
+			},
+			Incompatible: []api.ComparisonResult{
+				{Device: "rm2", OSVersion: "3.23.0.64"},
+			},
+		},
+		"b.qmd": {
+			Compatible: []api.ComparisonResult{
+				{Device: "rmpp", OSVersion: "3.22.0.64"},
+				{Device: "rmpp", OSVersion: "3.23.0.64"},
+			},
+		},
+		"c.qmd": {
+			Compatible: []api.ComparisonResult{
+				{Device: "rm2", OSVersion: "3.22.0.64"},
+			},
+		},
+	}
+
+	want := []DeviceSummary{
+		{Device: "rm2", Compatible: 1, Incompatible: 1, NoData: 1},
+		{Device: "rmpp", Compatible: 2, NoData: 1},
+	}
+	if got := ComputeDeviceSummaries(results); !reflect.DeepEqual(got, want) {
+		t.Errorf("ComputeDeviceSummaries() = %+v, want %+v", got, want)
+	}
+
+	if got := ComputeDeviceSummaries(nil); len(got) != 0 {
+		t.Errorf("ComputeDeviceSummaries(nil) = %+v, want empty", got)
+	}
+}
+
+func TestRenderDeviceSummary(t *testing.T) {
+	results := map[string]*api.ComparisonResponse{
+		"a.qmd": {Compatible: []api.ComparisonResult{{Device: "rmpp", OSVersion: "3.22.0.64"}}},
+		"b.qmd": {Incompatible: []api.ComparisonResult{{Device: "rmpp", OSVersion: "3.22.0.64"}}},
+	}
+
+	var buf bytes.Buffer
+	if err := RenderDeviceSummary(&buf, results); err != nil {
+		t.Fatalf("RenderDeviceSummary() error = %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"Device summary (2 files)", "rmpp", "Incompatible"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("RenderDeviceSummary() output missing %q:\n%s", want, out)
+		}
+	}
+
+	if err := RenderDeviceSummary(failingWriter{}, results); err == nil {
+		t.Error("RenderDeviceSummary() expected error from a failing writer")
+	}
+}
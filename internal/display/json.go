@@ -0,0 +1,32 @@
+package display
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/formatter"
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+// RenderJSON writes the whole report: the results keyed by file in the
+// server's own shapes, with the dependencies several files share, the
+// timing and each file's support windows alongside. Empty result lists are
+// written as [] rather than null.
+func RenderJSON(w io.Writer, report *formatter.Report) error {
+	normalized := *report
+	normalized.Results = make(map[string]*api.ComparisonResponse, len(report.Results))
+	for file, response := range report.Results {
+		copied := *response
+		if copied.Compatible == nil {
+			copied.Compatible = []api.ComparisonResult{}
+		}
+		if copied.Incompatible == nil {
+			copied.Incompatible = []api.ComparisonResult{}
+		}
+		normalized.Results[file] = &copied
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(&normalized)
+}
@@ -0,0 +1,53 @@
+package display
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/formatter"
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+func TestRenderJSON(t *testing.T) {
+	compatible := &api.ComparisonResponse{
+		Compatible:   []api.ComparisonResult{{OSVersion: "3.22.0.64", Device: "rmpp", Compatible: true}},
+		TotalChecked: 1,
+	}
+	incompatible := &api.ComparisonResponse{
+		Incompatible: []api.ComparisonResult{{OSVersion: "3.22.0.64", Device: "rm2", ErrorDetail: "missing 1 hash(es)"}},
+		TotalChecked: 1,
+	}
+	report := &formatter.Report{
+		Server:  "https://qmdverify.example.com",
+		Results: map[string]*api.ComparisonResponse{"a.qmd": compatible, "b.qmd": incompatible},
+		Timing:  &api.Timing{TotalMS: 120},
+		SupportWindows: map[string][]formatter.SupportWindow{
+			"a.qmd": {{Device: "rmpp", Oldest: "3.22.0.64", Newest: "3.22.0.64"}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := RenderJSON(&buf, report); err != nil {
+		t.Fatalf("RenderJSON() error = %v", err)
+	}
+	var got formatter.Report
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("RenderJSON() wrote invalid JSON: %v\n%s", err, buf.String())
+	}
+	if len(got.Results) != 2 || len(got.Results["a.qmd"].Compatible) != 1 || got.Results["b.qmd"].Incompatible[0].ErrorDetail != "missing 1 hash(es)" {
+		t.Errorf("RenderJSON() results = %+v, want both files keyed by name", got.Results)
+	}
+	if got.Timing == nil || got.Timing.TotalMS != 120 {
+		t.Errorf("RenderJSON() timing = %+v, want the report's timing", got.Timing)
+	}
+	if windows := got.SupportWindows["a.qmd"]; len(windows) != 1 || windows[0].Oldest != "3.22.0.64" {
+		t.Errorf("RenderJSON() support windows = %+v", got.SupportWindows)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(`"incompatible": []`)) {
+		t.Errorf("RenderJSON() did not write an empty incompatible list:\n%s", buf.String())
+	}
+	if compatible.Incompatible != nil {
+		t.Error("RenderJSON() modified its input")
+	}
+}
@@ -0,0 +1,49 @@
+package display
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+// RenderMarkdownMatrix writes the compatibility matrix as a GitHub-flavored
+// Markdown table, newest version first, with emoji rather than colors for
+// the statuses.
+func RenderMarkdownMatrix(w io.Writer, response *api.ComparisonResponse) error {
+	matrix := buildCompatibilityMatrix(response)
+	devices := getDeviceOrder(matrix)
+	versions := getSortedVersions(matrix)
+	glyphs := symbolSets[SymbolsEmoji]
+
+	var output strings.Builder
+	if len(versions) == 0 {
+		fmt.Fprintln(&output, "_No hashtables were checked._")
+		_, err := io.WriteString(w, output.String())
+		return err
+	}
+
+	fmt.Fprintf(&output, "| OS Version | %s |\n", strings.Join(devices, " | "))
+	fmt.Fprintf(&output, "|---%s|\n", strings.Repeat("|:---:", len(devices)))
+	for _, version := range versions {
+		cells := make([]string, len(devices))
+		for i, device := range devices {
+			cell := matrix[version][device]
+			switch {
+			case !cell.hasData:
+				cells[i] = glyphs.noData
+			case cell.compatible:
+				cells[i] = glyphs.compatible
+			default:
+				cells[i] = glyphs.incompatible
+			}
+		}
+		fmt.Fprintf(&output, "| %s | %s |\n", version, strings.Join(cells, " | "))
+	}
+	fmt.Fprintf(&output, "\n%d checked · %d compatible · %d incompatible\n",
+		response.TotalChecked, len(response.Compatible), len(response.Incompatible))
+
+	_, err := io.WriteString(w, output.String())
+	return err
+}
@@ -0,0 +1,38 @@
+package display
+
+import (
+	"bytes"
+	"testing"
+
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+func TestRenderMarkdownMatrix(t *testing.T) {
+	response := &api.ComparisonResponse{
+		Compatible: []api.ComparisonResult{
+			{OSVersion: "3.22.0.64", Device: "rmpp", Compatible: true},
+			{OSVersion: "3.20.0.92", Device: "rmpp", Compatible: true},
+			{OSVersion: "3.20.0.92", Device: "rm2", Compatible: true},
+		},
+		Incompatible: []api.ComparisonResult{
+			{OSVersion: "3.22.0.64", Device: "rm2", Compatible: false},
+		},
+		TotalChecked: 4,
+	}
+
+	var buf bytes.Buffer
+	if err := RenderMarkdownMatrix(&buf, response); err != nil {
+		t.Fatalf("RenderMarkdownMatrix() error = %v", err)
+	}
+
+	want := `| OS Version | rm2 | rmpp |
+|---|:---:|:---:|
+| 3.22.0.64 | ❌ | ✅ |
+| 3.20.0.92 | ✅ | ✅ |
+
+4 checked · 3 compatible · 1 incompatible
+`
+	if got := buf.String(); got != want {
+		t.Errorf("RenderMarkdownMatrix() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,33 @@
+package display
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/i18n"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/policy"
+)
+
+func RenderPolicyReport(w io.Writer, violations []policy.Violation, ruleCount int) error {
+	var output strings.Builder
+	fmt.Fprintln(&output)
+
+	if len(violations) == 0 {
+		fmt.Fprintln(&output, compatibleStyle.Render(i18n.T(i18n.MsgPolicySatisfied, ruleCount)))
+		_, err := io.WriteString(w, output.String())
+		return err
+	}
+
+	fmt.Fprintln(&output, incompatibleStyle.Render(i18n.T(i18n.MsgPolicyViolations, len(violations))))
+	for _, v := range violations {
+		line := fmt.Sprintf("  • %s: %s (%s)", v.Rule, v.OSVersion, v.Device)
+		if v.Detail != "" {
+			line += " — " + v.Detail
+		}
+		fmt.Fprintln(&output, errorStyle.Render(line))
+	}
+
+	_, err := io.WriteString(w, output.String())
+	return err
+}
@@ -0,0 +1,64 @@
+package display
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/osversion"
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+// PorcelainVersion is bumped whenever the porcelain format changes in a way
+// that could break an existing parser. Adding new line types is not such a
+// change; parsers must ignore lines they don't recognise.
+const PorcelainVersion = 1
+
+var porcelainEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+// RenderPorcelain writes results in the stable, tab-separated porcelain
+// format:
+//
+//	version	1
+//	result	<file>	<device>	<os_version>	<compatible|incompatible>	<error_detail>
+//
+// Results are sorted by file, device and OS version. Tabs, newlines and
+// backslashes inside fields are escaped as \t, \n and \\.
+func RenderPorcelain(w io.Writer, results map[string]*api.ComparisonResponse) error {
+	files := make([]string, 0, len(results))
+	for file := range results {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	var output strings.Builder
+	fmt.Fprintf(&output, "version\t%d\n", PorcelainVersion)
+
+	for _, file := range files {
+		response := results[file]
+		rows := append(append([]api.ComparisonResult{}, response.Compatible...), response.Incompatible...)
+		sort.SliceStable(rows, func(i, j int) bool {
+			if rows[i].Device != rows[j].Device {
+				return rows[i].Device < rows[j].Device
+			}
+			return osversion.Compare(rows[i].OSVersion, rows[j].OSVersion) < 0
+		})
+
+		for _, row := range rows {
+			status := "incompatible"
+			if row.Compatible {
+				status = "compatible"
+			}
+			fields := []string{"result", file, row.Device, row.OSVersion, status, row.ErrorDetail}
+			for i, field := range fields {
+				fields[i] = porcelainEscaper.Replace(field)
+			}
+			output.WriteString(strings.Join(fields, "\t"))
+			output.WriteByte('\n')
+		}
+	}
+
+	_, err := io.WriteString(w, output.String())
+	return err
+}
@@ -0,0 +1,41 @@
+package display
+
+import (
+	"bytes"
+	"testing"
+
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+func TestRenderPorcelain(t *testing.T) {
+	results := map[string]*api.ComparisonResponse{
+		"b.qmd": {
+			Compatible: []api.ComparisonResult{
+				{Device: "rmpp", OSVersion: "3.22.0.64", Compatible: true},
+			},
+		},
+		"a.qmd": {
+			Compatible: []api.ComparisonResult{
+				{Device: "rmpp", OSVersion: "3.22.0.64", Compatible: true},
+				{Device: "rmpp", OSVersion: "3.9.5.1", Compatible: true},
+			},
+			Incompatible: []api.ComparisonResult{
+				{Device: "rm2", OSVersion: "3.22.0.64", ErrorDetail: "hash not found:\n\tfoo"},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := RenderPorcelain(&buf, results); err != nil {
+		t.Fatalf("RenderPorcelain() error = %v", err)
+	}
+
+	want := "version\t1\n" +
+		"result\ta.qmd\trm2\t3.22.0.64\tincompatible\thash not found:\\n\\tfoo\n" +
+		"result\ta.qmd\trmpp\t3.9.5.1\tcompatible\t\n" +
+		"result\ta.qmd\trmpp\t3.22.0.64\tcompatible\t\n" +
+		"result\tb.qmd\trmpp\t3.22.0.64\tcompatible\t\n"
+	if buf.String() != want {
+		t.Errorf("RenderPorcelain() =\n%q\nwant\n%q", buf.String(), want)
+	}
+}
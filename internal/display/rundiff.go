@@ -0,0 +1,60 @@
+package display
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/rundiff"
+)
+
+// RenderRunDiff lists the changes between two runs, grouped by kind.
+func RenderRunDiff(w io.Writer, diff *rundiff.Diff) error {
+	var output strings.Builder
+
+	fmt.Fprintf(&output, "Comparing %s → %s\n", diff.From.Name, diff.To.Name)
+	for _, run := range []*rundiff.Run{diff.From, diff.To} {
+		if run.Note != "" {
+			fmt.Fprintln(&output, noDataStyle.Render(fmt.Sprintf("  %s: %s", run.Name, run.Note)))
+		}
+	}
+	fmt.Fprintln(&output)
+
+	if len(diff.Changes) == 0 {
+		fmt.Fprintln(&output, compatibleStyle.Render("No compatibility changes"))
+		_, err := io.WriteString(w, output.String())
+		return err
+	}
+
+	for _, kind := range rundiff.Kinds {
+		count := diff.Count(kind)
+		if count == 0 {
+			continue
+		}
+		fmt.Fprintf(&output, "%s (%d):\n", kind.Heading(), count)
+		for _, change := range diff.Changes {
+			if change.Kind != kind {
+				continue
+			}
+			mark := incompatibleMark()
+			if change.Compatible {
+				mark = compatibleMark()
+			}
+			line := fmt.Sprintf("%s (%s)", change.OSVersion, change.Device)
+			if change.File != "" {
+				line = change.File + ": " + line
+			}
+			if change.Detail != "" {
+				line += " — " + change.Detail
+			}
+			fmt.Fprintf(&output, "  %s %s\n", mark, line)
+		}
+		fmt.Fprintln(&output)
+	}
+
+	fmt.Fprintf(&output, "Summary: %d regressions | %d fixes | %d newly covered\n",
+		diff.Count(rundiff.Regression), diff.Count(rundiff.Fix), diff.Count(rundiff.Covered))
+
+	_, err := io.WriteString(w, output.String())
+	return err
+}
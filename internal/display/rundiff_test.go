@@ -0,0 +1,49 @@
+package display
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/rundiff"
+)
+
+func TestRenderRunDiff(t *testing.T) {
+	diff := &rundiff.Diff{
+		From: &rundiff.Run{Name: "job-1"},
+		To:   &rundiff.Run{Name: "job-2", Note: "after migrating to new SwipeArea API"},
+		Changes: []rundiff.Change{
+			{Kind: rundiff.Regression, Device: "rmpp", OSVersion: "3.22.0.64", Detail: "hash not found"},
+			{Kind: rundiff.Fix, Device: "rm2", OSVersion: "3.22.0.64", Compatible: true},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := RenderRunDiff(&buf, diff); err != nil {
+		t.Fatalf("RenderRunDiff() error = %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"Comparing job-1 → job-2",
+		"job-2: after migrating to new SwipeArea API",
+		"Regressions (1):\n  ✗ 3.22.0.64 (rmpp) — hash not found",
+		"Fixes (1):\n  ✓ 3.22.0.64 (rm2)",
+		"Summary: 1 regressions | 1 fixes | 0 newly covered",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("RenderRunDiff() output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "Newly covered") {
+		t.Errorf("RenderRunDiff() shows an empty section:\n%s", out)
+	}
+
+	buf.Reset()
+	diff.Changes = nil
+	if err := RenderRunDiff(&buf, diff); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "No compatibility changes") {
+		t.Errorf("RenderRunDiff() without changes = %q", buf.String())
+	}
+}
@@ -0,0 +1,80 @@
+package display
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/formatter"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/i18n"
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+type SupportWindow = formatter.SupportWindow
+
+func ComputeSupportWindows(response *api.ComparisonResponse) []SupportWindow {
+	matrix := buildCompatibilityMatrix(response)
+	devices := getDeviceOrder(matrix)
+	versions := getChronologicalVersions(matrix)
+
+	windows := make([]SupportWindow, 0, len(devices))
+	for _, device := range devices {
+		windows = append(windows, computeSupportWindow(buildTimeline(matrix, versions, device), device))
+	}
+
+	return windows
+}
+
+func computeSupportWindow(entries []timelineEntry, device string) SupportWindow {
+	window := SupportWindow{Device: device}
+
+	first, last := -1, -1
+	for i, entry := range entries {
+		if !entry.compatible {
+			continue
+		}
+		if first < 0 {
+			first = i
+		}
+		last = i
+	}
+
+	if first < 0 {
+		return window
+	}
+
+	window.Oldest = entries[first].version
+	window.Newest = entries[last].version
+	for _, entry := range entries[first:last] {
+		if !entry.compatible {
+			window.Gaps = append(window.Gaps, entry.version)
+		}
+	}
+
+	return window
+}
+
+func renderSupportWindowsToBuilder(output *strings.Builder, windows []SupportWindow) {
+	deviceColWidth := 6
+	for _, window := range windows {
+		if len(window.Device) > deviceColWidth {
+			deviceColWidth = len(window.Device)
+		}
+	}
+
+	fmt.Fprintln(output, i18n.T(i18n.MsgSupportedOS))
+	for _, window := range windows {
+		deviceCell := lipgloss.NewStyle().Width(deviceColWidth).Render(window.Device)
+
+		if window.Oldest == "" {
+			fmt.Fprintf(output, " %s %s\n", deviceCell, noDataStyle.Render(i18n.T(i18n.MsgSupportedNone)))
+			continue
+		}
+
+		line := fmt.Sprintf("%s – %s", window.Oldest, window.Newest)
+		if len(window.Gaps) > 0 {
+			line += " " + incompatibleStyle.Render(i18n.T(i18n.MsgSupportedGaps, strings.Join(window.Gaps, ", ")))
+		}
+		fmt.Fprintf(output, " %s %s\n", deviceCell, line)
+	}
+}
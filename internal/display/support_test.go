@@ -0,0 +1,86 @@
+package display
+
+import (
+	"reflect"
+	"testing"
+
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+func TestComputeSupportWindows(t *testing.T) {
+	tests := []struct {
+		name     string
+		response *api.ComparisonResponse
+		want     []SupportWindow
+	}{
+		{
+			name: "contiguous window",
+			response: &api.ComparisonResponse{
+				Compatible: []api.ComparisonResult{
+					{Device: "rmpp", OSVersion: "3.20.0.92"},
+					{Device: "rmpp", OSVersion: "3.22.0.64"},
+				},
+				Incompatible: []api.ComparisonResult{
+					{Device: "rmpp", OSVersion: "3.23.0.64"},
+				},
+			},
+			want: []SupportWindow{
+				{Device: "rmpp", Oldest: "3.20.0.92", Newest: "3.22.0.64"},
+			},
+		},
+		{
+			name: "window with gap",
+			response: &api.ComparisonResponse{
+				Compatible: []api.ComparisonResult{
+					{Device: "rm2", OSVersion: "3.20.0.92"},
+					{Device: "rm2", OSVersion: "3.23.0.64"},
+				},
+				Incompatible: []api.ComparisonResult{
+					{Device: "rm2", OSVersion: "3.21.0.79"},
+					{Device: "rm2", OSVersion: "3.22.0.64"},
+				},
+			},
+			want: []SupportWindow{
+				{Device: "rm2", Oldest: "3.20.0.92", Newest: "3.23.0.64", Gaps: []string{"3.21.0.79", "3.22.0.64"}},
+			},
+		},
+		{
+			name: "never compatible",
+			response: &api.ComparisonResponse{
+				Incompatible: []api.ComparisonResult{
+					{Device: "rm1", OSVersion: "3.22.0.64"},
+				},
+			},
+			want: []SupportWindow{
+				{Device: "rm1"},
+			},
+		},
+		{
+			name: "devices in display order",
+			response: &api.ComparisonResponse{
+				Compatible: []api.ComparisonResult{
+					{Device: "rmpp", OSVersion: "3.22.0.64"},
+					{Device: "rm1", OSVersion: "3.22.0.64"},
+				},
+			},
+			want: []SupportWindow{
+				{Device: "rm1", Oldest: "3.22.0.64", Newest: "3.22.0.64"},
+				{Device: "rmpp", Oldest: "3.22.0.64", Newest: "3.22.0.64"},
+			},
+		},
+		{
+			name:     "empty response",
+			response: &api.ComparisonResponse{},
+			want:     []SupportWindow{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ComputeSupportWindows(tt.response)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ComputeSupportWindows() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package display
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Symbol modes for the status indicators in the matrix, timeline, legend and
+// dependency summaries.
+const (
+	SymbolsDefault = "default"
+	// SymbolsSafe spells statuses out and uses a colorblind-safe palette
+	// (Okabe-Ito sky blue and orange), so they don't rely on red and green.
+	SymbolsSafe = "safe"
+	// SymbolsEmoji renders well when output is pasted into chat.
+	SymbolsEmoji = "emoji"
+	// SymbolsPlain sticks to ASCII letters for logs.
+	SymbolsPlain = "plain"
+)
+
+// SymbolModes lists the accepted --symbols values.
+var SymbolModes = []string{SymbolsDefault, SymbolsSafe, SymbolsEmoji, SymbolsPlain}
+
+// Glyphs overrides the indicators of a symbol mode. Empty fields keep the
+// mode's own.
+type Glyphs struct {
+	Compatible   string
+	Incompatible string
+	NoData       string
+}
+
+type symbolSet struct {
+	compatible        string
+	incompatible      string
+	noData            string
+	compatibleColor   lipgloss.Color
+	incompatibleColor lipgloss.Color
+}
+
+var symbolSets = map[string]symbolSet{
+	SymbolsDefault: {"✓", "✗", "—", "#00FF00", "#FF0000"},
+	SymbolsSafe:    {"OK", "FAIL", "N/A", "#56B4E9", "#E69F00"},
+	SymbolsEmoji:   {"✅", "❌", "➖", "#00FF00", "#FF0000"},
+	SymbolsPlain:   {"Y", "N", "-", "#00FF00", "#FF0000"},
+}
+
+var symbols = symbolSets[SymbolsDefault]
+
+// SetSymbols selects the status indicators and their colors, replacing any
+// glyph set in overrides.
+func SetSymbols(mode string, overrides Glyphs) error {
+	set, ok := symbolSets[mode]
+	if !ok {
+		last := len(SymbolModes) - 1
+		return fmt.Errorf("invalid --symbols %q: must be %s or %s", mode, strings.Join(SymbolModes[:last], ", "), SymbolModes[last])
+	}
+	if overrides.Compatible != "" {
+		set.compatible = overrides.Compatible
+	}
+	if overrides.Incompatible != "" {
+		set.incompatible = overrides.Incompatible
+	}
+	if overrides.NoData != "" {
+		set.noData = overrides.NoData
+	}
+	symbols = set
+	compatibleStyle = compatibleStyle.Foreground(set.compatibleColor)
+	incompatibleStyle = incompatibleStyle.Foreground(set.incompatibleColor)
+	firstBrokenStyle = firstBrokenStyle.Foreground(set.incompatibleColor)
+	return nil
+}
+
+// symbolWidth is the widest indicator in cells.
+func symbolWidth() int {
+	return max(lipgloss.Width(symbols.compatible), lipgloss.Width(symbols.incompatible), lipgloss.Width(symbols.noData))
+}
+
+func compatibleMark() string {
+	return compatibleStyle.Render(symbols.compatible)
+}
+
+func incompatibleMark() string {
+	return incompatibleStyle.Render(symbols.incompatible)
+}
+
+func noDataMark() string {
+	return noDataStyle.Render(symbols.noData)
+}
@@ -0,0 +1,57 @@
+package display
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+func TestSetSymbols(t *testing.T) {
+	defer SetSymbols(SymbolsDefault, Glyphs{})
+
+	response := &api.ComparisonResponse{
+		Compatible:   []api.ComparisonResult{{Device: "rmpp", OSVersion: "3.22.4.2"}},
+		Incompatible: []api.ComparisonResult{{Device: "rm2", OSVersion: "3.22.4.2"}},
+		TotalChecked: 2,
+	}
+	response.Incompatible = append(response.Incompatible, api.ComparisonResult{Device: "rm2", OSVersion: "3.20.0.92"})
+
+	tests := []struct {
+		mode     string
+		glyphs   Glyphs
+		want     []string
+		wantNone []string
+	}{
+		{mode: SymbolsDefault, want: []string{"✓", "✗", "—"}},
+		{mode: SymbolsSafe, want: []string{"OK", "FAIL", "N/A"}, wantNone: []string{"✓", "✗", "—"}},
+		{mode: SymbolsEmoji, want: []string{"✅", "❌", "➖"}, wantNone: []string{"✓", "✗", "—"}},
+		{mode: SymbolsPlain, want: []string{" Y ", " N ", " - "}, wantNone: []string{"✓", "✗", "—"}},
+		{mode: SymbolsDefault, glyphs: Glyphs{Compatible: "PASS", NoData: "?"}, want: []string{"PASS", "✗", "?"}, wantNone: []string{"✓", "—"}},
+	}
+	for _, tt := range tests {
+		if err := SetSymbols(tt.mode, tt.glyphs); err != nil {
+			t.Fatalf("SetSymbols(%s) error = %v", tt.mode, err)
+		}
+		var buf bytes.Buffer
+		if err := RenderComparisonResults(&buf, response, MatrixOptions{Legend: true}); err != nil {
+			t.Fatalf("RenderComparisonResults() error = %v", err)
+		}
+		out := buf.String()
+		for _, want := range tt.want {
+			if !strings.Contains(out, want) {
+				t.Errorf("%s symbols: output missing %q:\n%s", tt.mode, want, out)
+			}
+		}
+		for _, unwanted := range tt.wantNone {
+			if strings.Contains(out, unwanted) {
+				t.Errorf("%s symbols: output contains %q:\n%s", tt.mode, unwanted, out)
+			}
+		}
+	}
+
+	if err := SetSymbols("loud", Glyphs{}); err == nil {
+		t.Error("SetSymbols(loud) should fail")
+	}
+}
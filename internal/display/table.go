@@ -2,11 +2,14 @@ package display
 
 import (
 	"fmt"
+	"io"
 	"sort"
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
-	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/api"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/i18n"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/osversion"
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
 )
 
 var (
@@ -40,137 +43,401 @@ var (
 
 	infoStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("#00BFFF"))
+
+	newVersionStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#FFD700")).
+			Bold(true)
 )
 
+type MatrixOptions struct {
+	Verbose     bool
+	Totals      bool
+	Legend      bool
+	NewVersions map[string]bool
+	// Ascending lists the oldest version first instead of the newest.
+	Ascending bool
+	// DeviceOrder puts these devices first, in this order.
+	DeviceOrder []string
+	// IncompatibleOnly hides versions that are compatible on every device.
+	// Totals and the summary still cover every version.
+	IncompatibleOnly bool
+	// Latest keeps only each device's newest N versions when positive.
+	Latest int
+	// Transpose lists devices down the side and versions across the top.
+	Transpose bool
+}
+
 type matrixCell struct {
-	compatible bool
-	hasData    bool
-	errorDetail string
+	compatible   bool
+	hasData      bool
+	errorDetail  string
+	dependencies map[string]*api.ValidationResult
+	// tree is set when the result came from tree validation rather than
+	// the hashtable alone.
+	tree bool
 }
 
-func RenderComparisonResults(response *api.ComparisonResponse, verbose bool) {
+// treeMarker follows the symbol of a tree-validated cell.
+const treeMarker = "ᵀ"
+
+// cellDetail is an incompatible cell listed under the matrix in verbose
+// mode.
+type cellDetail struct {
+	label        string
+	dependencies map[string]*api.ValidationResult
+}
+
+func RenderComparisonResults(w io.Writer, response *api.ComparisonResponse, opts MatrixOptions) error {
 	matrix := buildCompatibilityMatrix(response)
-	devices := getDeviceOrder(matrix)
+	if opts.Latest > 0 {
+		matrix = latestVersions(matrix, opts.Latest)
+	}
+	devices := preferDevices(getDeviceOrder(matrix), opts.DeviceOrder)
 	versions := getSortedVersions(matrix)
+	if opts.Ascending {
+		versions = getChronologicalVersions(matrix)
+	}
 
 	if len(versions) == 0 {
-		fmt.Println(infoStyle.Render("No compatibility data available"))
-		return
+		return RenderInfo(w, i18n.T(i18n.MsgNoCompatibilityData))
+	}
+
+	if opts.IncompatibleOnly {
+		versions = incompatibleVersions(matrix, versions)
+		if len(versions) == 0 {
+			var output strings.Builder
+			fmt.Fprintln(&output)
+			fmt.Fprintln(&output, compatibleStyle.Render(i18n.T(i18n.MsgAllVersionsCompatible, len(matrix))))
+			fmt.Fprintln(&output)
+			renderSummaryToBuilder(&output, response)
+			_, err := io.WriteString(w, output.String())
+			return err
+		}
 	}
 
-	tableStr := buildMatrixTable(matrix, versions, devices, verbose)
+	tableStr := buildMatrixTable(matrix, versions, devices, opts)
 
-	title := "reMarkable QMD Verifier"
+	title := i18n.T(i18n.MsgMatrixTitle)
 	titleWidth := lipgloss.Width(tableStr)
 	centeredTitle := lipgloss.NewStyle().
 		Width(titleWidth).
 		Align(lipgloss.Center).
 		Render(title)
 
-	fmt.Println()
-	fmt.Println(centeredTitle)
-	fmt.Println()
-	fmt.Println(tableStr)
-	fmt.Println()
+	var output strings.Builder
+	fmt.Fprintln(&output)
+	fmt.Fprintln(&output, centeredTitle)
+	fmt.Fprintln(&output)
+	fmt.Fprintln(&output, tableStr)
+	fmt.Fprintln(&output)
+
+	if opts.Legend {
+		renderLegendToBuilder(&output)
+		fmt.Fprintln(&output)
+	}
+
+	renderSupportWindowsToBuilder(&output, ComputeSupportWindows(response))
+	fmt.Fprintln(&output)
+
+	renderSummaryToBuilder(&output, response)
 
+	_, err := io.WriteString(w, output.String())
+	return err
+}
+
+func renderSummaryToBuilder(output *strings.Builder, response *api.ComparisonResponse) {
 	var compatibleCount string
 	if len(response.Compatible) > 0 {
-		compatibleCount = compatibleStyle.Render(fmt.Sprintf("%d compatible", len(response.Compatible)))
+		compatibleCount = compatibleStyle.Render(i18n.T(i18n.MsgCompatibleCount, len(response.Compatible)))
 	} else {
-		compatibleCount = fmt.Sprintf("%d compatible", len(response.Compatible))
+		compatibleCount = i18n.T(i18n.MsgCompatibleCount, len(response.Compatible))
 	}
 
 	var incompatibleCount string
 	if len(response.Incompatible) > 0 {
-		incompatibleCount = incompatibleStyle.Render(fmt.Sprintf("%d incompatible", len(response.Incompatible)))
+		incompatibleCount = incompatibleStyle.Render(i18n.T(i18n.MsgIncompatibleCount, len(response.Incompatible)))
 	} else {
-		incompatibleCount = fmt.Sprintf("%d incompatible", len(response.Incompatible))
+		incompatibleCount = i18n.T(i18n.MsgIncompatibleCount, len(response.Incompatible))
 	}
 
-	summary := fmt.Sprintf("Summary: %d checked | %s | %s",
+	summary := i18n.T(i18n.MsgSummary,
 		response.TotalChecked,
 		compatibleCount,
 		incompatibleCount)
-	fmt.Println(summary)
+	fmt.Fprintln(output, summary)
+
+	tree, hashtable := 0, 0
+	for _, results := range [][]api.ComparisonResult{response.Compatible, response.Incompatible} {
+		for _, result := range results {
+			if treeValidated(result) {
+				tree++
+			} else {
+				hashtable++
+			}
+		}
+	}
+	if tree > 0 {
+		fmt.Fprintln(output, noDataStyle.Render(i18n.T(i18n.MsgValidationModes, tree, hashtable)))
+	}
 }
 
-func buildMatrixTable(matrix map[string]map[string]matrixCell, versions []string, devices []string, verbose bool) string {
+// matrixLayout is the grid drawn for a matrix: versions down and devices
+// across, or devices down and versions across when transposed. Both are
+// drawn from the same cells and totals.
+type matrixLayout struct {
+	rows, cols []string
+	// at maps a row and column back to a version and device.
+	at       func(row, col string) (version, device string)
+	label    func(key string) string
+	rowTotal func(row string) (passed, total int)
+	colTotal func(col string) (passed, total int)
+	// total covers every version, like the device totals, even when only
+	// some versions are shown.
+	total func() (passed, total int)
+}
+
+func newMatrixLayout(matrix map[string]map[string]matrixCell, versions []string, devices []string, opts MatrixOptions) matrixLayout {
+	newTag := i18n.T(i18n.MsgNewTag)
+	label := func(key string) string {
+		if opts.NewVersions[key] {
+			return key + " " + newVersionStyle.Render(newTag)
+		}
+		return key
+	}
+	versionTotal := func(version string) (int, int) { return countVersionPasses(matrix[version], devices) }
+	deviceTotal := func(device string) (int, int) {
+		return countDevicePasses(matrix, getSortedVersions(matrix), device)
+	}
+	grandTotal := func() (allPassed, allTotal int) {
+		for _, device := range devices {
+			passed, total := deviceTotal(device)
+			allPassed += passed
+			allTotal += total
+		}
+		return allPassed, allTotal
+	}
+
+	if opts.Transpose {
+		return matrixLayout{
+			rows:     devices,
+			cols:     versions,
+			at:       func(row, col string) (string, string) { return col, row },
+			label:    label,
+			rowTotal: deviceTotal,
+			colTotal: versionTotal,
+			total:    grandTotal,
+		}
+	}
+	return matrixLayout{
+		rows:     versions,
+		cols:     devices,
+		at:       func(row, col string) (string, string) { return row, col },
+		label:    label,
+		rowTotal: versionTotal,
+		colTotal: deviceTotal,
+		total:    grandTotal,
+	}
+}
+
+func buildMatrixTable(matrix map[string]map[string]matrixCell, versions []string, devices []string, opts MatrixOptions) string {
 	var output strings.Builder
+	layout := newMatrixLayout(matrix, versions, devices, opts)
 
-	deviceColWidth := 6
-	for _, device := range devices {
-		if len(device) > deviceColWidth {
-			deviceColWidth = len(device)
+	cellColWidth := max(6, symbolWidth()+lipgloss.Width(treeMarker)+1)
+	for _, col := range layout.cols {
+		width := lipgloss.Width(layout.label(col))
+		if opts.Transpose {
+			width += 2
+		}
+		if width > cellColWidth {
+			cellColWidth = width
 		}
 	}
 
-	versionColWidth := 15
-	for _, version := range versions {
-		if len(version) > versionColWidth {
-			versionColWidth = len(version)
+	labelColWidth := 15
+	if opts.Transpose {
+		labelColWidth = 6
+	}
+	for _, row := range layout.rows {
+		if width := lipgloss.Width(layout.label(row)); width > labelColWidth {
+			labelColWidth = width
 		}
 	}
 
-	renderMatrixHeaderToBuilder(&output, devices, versionColWidth, deviceColWidth)
-	renderMatrixSeparatorToBuilder(&output, len(devices), versionColWidth, deviceColWidth)
+	totalsColWidth := 0
+	if opts.Totals {
+		totalsColWidth = 8
+		if width := lipgloss.Width(i18n.T(i18n.MsgTotalsLabel)) + 2; width > totalsColWidth {
+			totalsColWidth = width
+		}
+	}
 
-	var errorDetails []string
+	renderMatrixHeaderToBuilder(&output, layout, labelColWidth, cellColWidth, totalsColWidth)
+	renderMatrixSeparatorToBuilder(&output, len(layout.cols), labelColWidth, cellColWidth, totalsColWidth)
 
-	for _, version := range versions {
-		deviceRow := matrix[version]
+	var errorDetails []cellDetail
 
-		versionCell := versionCellStyle.Width(versionColWidth).Render(version)
-		output.WriteString(" " + versionCell + " ")
+	for _, row := range layout.rows {
+		rowCell := versionCellStyle.Width(labelColWidth).Render(layout.label(row))
+		output.WriteString(" " + rowCell + " ")
 
-		for _, device := range devices {
-			cell, exists := deviceRow[device]
+		for _, col := range layout.cols {
+			version, device := layout.at(row, col)
+			cell, exists := matrix[version][device]
 			var content string
 			if !exists || !cell.hasData {
-				content = noDataStyle.Render("—")
+				content = noDataMark()
 			} else if cell.compatible {
-				content = compatibleStyle.Render("✓")
+				content = compatibleMark()
 			} else {
-				content = incompatibleStyle.Render("✗")
-				if verbose && cell.errorDetail != "" {
-					errorDetails = append(errorDetails, fmt.Sprintf("%s (%s): %s",
-						version, device, cell.errorDetail))
+				content = incompatibleMark()
+				if opts.Verbose && (cell.errorDetail != "" || len(cell.dependencies) > 0) {
+					label := fmt.Sprintf("%s (%s)", version, device)
+					if cell.errorDetail != "" {
+						label += ": " + cell.errorDetail
+					}
+					errorDetails = append(errorDetails, cellDetail{label: label, dependencies: cell.dependencies})
 				}
 			}
 
-			cellRendered := cellStyle.Width(deviceColWidth).Render(content)
+			if exists && cell.tree {
+				content += treeMarker
+			}
+
+			cellRendered := cellStyle.Width(cellColWidth).Render(content)
 			output.WriteString(cellRendered)
 		}
+		if opts.Totals {
+			passed, total := layout.rowTotal(row)
+			output.WriteString(cellStyle.Width(totalsColWidth).Render(fmt.Sprintf("%d/%d", passed, total)))
+		}
 		output.WriteString("\n")
 	}
 
-	if verbose && len(errorDetails) > 0 {
+	if opts.Totals {
+		renderMatrixTotalsToBuilder(&output, layout, labelColWidth, cellColWidth, totalsColWidth)
+	}
+
+	if opts.Verbose && len(errorDetails) > 0 {
 		output.WriteString("\n")
-		output.WriteString(errorStyle.Render("Error Details:") + "\n")
+		output.WriteString(errorStyle.Render(i18n.T(i18n.MsgErrorDetails)) + "\n")
 		for _, detail := range errorDetails {
-			output.WriteString(errorStyle.Render("  • "+detail) + "\n")
+			output.WriteString(errorStyle.Render("  • "+detail.label) + "\n")
+			renderDependencyTreeToBuilder(&output, detail.dependencies)
 		}
 	}
 
 	return output.String()
 }
 
-func renderMatrixHeaderToBuilder(output *strings.Builder, devices []string, versionColWidth, deviceColWidth int) {
-	versionHeader := versionCellStyle.Width(versionColWidth).Render("")
-	output.WriteString(" " + versionHeader + " ")
+// renderDependencyTreeToBuilder lists a cell's dependency results under its
+// error detail, so it is clear whether the root file or a dependency failed.
+func renderDependencyTreeToBuilder(output *strings.Builder, deps map[string]*api.ValidationResult) {
+	names := make([]string, 0, len(deps))
+	for name := range deps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for i, name := range names {
+		branch := "├─"
+		if i == len(names)-1 {
+			branch = "└─"
+		}
 
-	for _, device := range devices {
-		headerCell := headerStyle.Width(deviceColWidth).Render(device)
+		dep := deps[name]
+		line := fmt.Sprintf("%s %s", branch, name)
+		if dep == nil {
+			output.WriteString("      " + noDataStyle.Render(line) + "\n")
+			continue
+		}
+		line += " → " + dep.Status
+		if len(dep.HashErrors) > 0 {
+			line += " → " + i18n.T(i18n.MsgSharedDepHashErrors, len(dep.HashErrors))
+		}
+
+		style := errorStyle
+		if dep.Status == "compatible" && len(dep.HashErrors) == 0 {
+			style = noDataStyle
+		}
+		output.WriteString("      " + style.Render(line) + "\n")
+	}
+}
+
+func renderMatrixHeaderToBuilder(output *strings.Builder, layout matrixLayout, labelColWidth, cellColWidth, totalsColWidth int) {
+	corner := versionCellStyle.Width(labelColWidth).Render("")
+	output.WriteString(" " + corner + " ")
+
+	for _, col := range layout.cols {
+		headerCell := headerStyle.Width(cellColWidth).Render(layout.label(col))
 		output.WriteString(headerCell)
 	}
+	if totalsColWidth > 0 {
+		output.WriteString(headerStyle.Width(totalsColWidth).Render(i18n.T(i18n.MsgTotalsLabel)))
+	}
 	output.WriteString("\n")
 }
 
-func renderMatrixSeparatorToBuilder(output *strings.Builder, deviceCount, versionColWidth, deviceColWidth int) {
-	totalWidth := versionColWidth + 2 + (deviceColWidth * deviceCount)
+func renderMatrixSeparatorToBuilder(output *strings.Builder, colCount, labelColWidth, cellColWidth, totalsColWidth int) {
+	totalWidth := labelColWidth + 2 + (cellColWidth * colCount) + totalsColWidth
 	output.WriteString(strings.Repeat("─", totalWidth))
 	output.WriteString("\n")
 }
 
+func renderMatrixTotalsToBuilder(output *strings.Builder, layout matrixLayout, labelColWidth, cellColWidth, totalsColWidth int) {
+	renderMatrixSeparatorToBuilder(output, len(layout.cols), labelColWidth, cellColWidth, totalsColWidth)
+
+	label := versionCellStyle.Width(labelColWidth).Render(i18n.T(i18n.MsgTotalsLabel))
+	output.WriteString(" " + label + " ")
+
+	for _, col := range layout.cols {
+		passed, total := layout.colTotal(col)
+		output.WriteString(cellStyle.Width(cellColWidth).Render(fmt.Sprintf("%d/%d", passed, total)))
+	}
+	allPassed, allTotal := layout.total()
+	output.WriteString(cellStyle.Width(totalsColWidth).Render(fmt.Sprintf("%d/%d", allPassed, allTotal)))
+	output.WriteString("\n")
+}
+
+func renderLegendToBuilder(output *strings.Builder) {
+	fmt.Fprintf(output, "%s  %s %s   %s %s   %s %s\n",
+		i18n.T(i18n.MsgLegend),
+		compatibleMark(), i18n.T(i18n.MsgLegendCompatible),
+		incompatibleMark(), i18n.T(i18n.MsgLegendIncompatible),
+		noDataMark(), i18n.T(i18n.MsgLegendNoData))
+	fmt.Fprintf(output, "%s  %s %s\n",
+		strings.Repeat(" ", lipgloss.Width(i18n.T(i18n.MsgLegend))),
+		treeMarker, i18n.T(i18n.MsgLegendTree))
+}
+
+func countVersionPasses(row map[string]matrixCell, devices []string) (passed, total int) {
+	for _, device := range devices {
+		cell, exists := row[device]
+		if !exists || !cell.hasData {
+			continue
+		}
+		total++
+		if cell.compatible {
+			passed++
+		}
+	}
+	return passed, total
+}
+
+func countDevicePasses(matrix map[string]map[string]matrixCell, versions []string, device string) (passed, total int) {
+	for _, version := range versions {
+		cell, exists := matrix[version][device]
+		if !exists || !cell.hasData {
+			continue
+		}
+		total++
+		if cell.compatible {
+			passed++
+		}
+	}
+	return passed, total
+}
+
 func buildCompatibilityMatrix(response *api.ComparisonResponse) map[string]map[string]matrixCell {
 	matrix := make(map[string]map[string]matrixCell)
 
@@ -181,6 +448,7 @@ func buildCompatibilityMatrix(response *api.ComparisonResponse) map[string]map[s
 		matrix[result.OSVersion][result.Device] = matrixCell{
 			compatible: true,
 			hasData:    true,
+			tree:       treeValidated(result),
 		}
 	}
 
@@ -189,15 +457,21 @@ func buildCompatibilityMatrix(response *api.ComparisonResponse) map[string]map[s
 			matrix[result.OSVersion] = make(map[string]matrixCell)
 		}
 		matrix[result.OSVersion][result.Device] = matrixCell{
-			compatible:  false,
-			hasData:     true,
-			errorDetail: result.ErrorDetail,
+			compatible:   false,
+			hasData:      true,
+			errorDetail:  result.ErrorDetail,
+			dependencies: result.DependencyResults,
+			tree:         treeValidated(result),
 		}
 	}
 
 	return matrix
 }
 
+func treeValidated(result api.ComparisonResult) bool {
+	return result.TreeValidationUsed || result.ValidationMode == "tree"
+}
+
 func getDeviceOrder(matrix map[string]map[string]matrixCell) []string {
 	deviceSet := make(map[string]bool)
 	for _, devices := range matrix {
@@ -236,6 +510,68 @@ func getDeviceOrder(matrix map[string]map[string]matrixCell) []string {
 	return devices
 }
 
+// latestVersions trims the matrix to the newest n versions of each device.
+func latestVersions(matrix map[string]map[string]matrixCell, n int) map[string]map[string]matrixCell {
+	kept := make(map[string]map[string]matrixCell)
+	counts := make(map[string]int)
+	for _, version := range getSortedVersions(matrix) {
+		for device, cell := range matrix[version] {
+			if counts[device] >= n {
+				continue
+			}
+			counts[device]++
+			if kept[version] == nil {
+				kept[version] = make(map[string]matrixCell)
+			}
+			kept[version][device] = cell
+		}
+	}
+	return kept
+}
+
+// incompatibleVersions keeps the versions with at least one incompatible
+// device.
+func incompatibleVersions(matrix map[string]map[string]matrixCell, versions []string) []string {
+	var kept []string
+	for _, version := range versions {
+		for _, cell := range matrix[version] {
+			if cell.hasData && !cell.compatible {
+				kept = append(kept, version)
+				break
+			}
+		}
+	}
+	return kept
+}
+
+// preferDevices moves the preferred devices to the front, in the given
+// order, keeping the rest in their default order.
+func preferDevices(devices, preferred []string) []string {
+	if len(preferred) == 0 {
+		return devices
+	}
+
+	present := make(map[string]bool, len(devices))
+	for _, device := range devices {
+		present[device] = true
+	}
+
+	ordered := make([]string, 0, len(devices))
+	placed := make(map[string]bool, len(devices))
+	for _, device := range preferred {
+		if present[device] && !placed[device] {
+			ordered = append(ordered, device)
+			placed[device] = true
+		}
+	}
+	for _, device := range devices {
+		if !placed[device] {
+			ordered = append(ordered, device)
+		}
+	}
+	return ordered
+}
+
 func getSortedVersions(matrix map[string]map[string]matrixCell) []string {
 	versions := make([]string, 0, len(matrix))
 	for version := range matrix {
@@ -250,41 +586,26 @@ func getSortedVersions(matrix map[string]map[string]matrixCell) []string {
 }
 
 func compareVersions(v1, v2 string) int {
-	p1 := strings.Split(v1, ".")
-	p2 := strings.Split(v2, ".")
-
-	maxLen := len(p1)
-	if len(p2) > maxLen {
-		maxLen = len(p2)
-	}
-
-	for i := 0; i < maxLen; i++ {
-		var n1, n2 int
-		if i < len(p1) {
-			fmt.Sscanf(p1[i], "%d", &n1)
-		}
-		if i < len(p2) {
-			fmt.Sscanf(p2[i], "%d", &n2)
-		}
-
-		if n1 != n2 {
-			return n1 - n2
-		}
-	}
-
-	return 0
+	return osversion.Compare(v1, v2)
 }
 
-func RenderHashtableList(response *api.HashtablesResponse) {
-	fmt.Println(titleStyle.Render("Available Hashtables"))
-	fmt.Println()
+func RenderHashtableList(w io.Writer, response *api.HashtablesResponse) error {
+	var output strings.Builder
+	fmt.Fprintln(&output, titleStyle.Render(i18n.T(i18n.MsgHashtablesTitle)))
+	fmt.Fprintln(&output)
 
 	if response.Count == 0 {
-		fmt.Println(infoStyle.Render("No hashtables available on the server"))
-		return
+		fmt.Fprintln(&output, infoStyle.Render(i18n.T(i18n.MsgNoHashtables)))
+		_, err := io.WriteString(w, output.String())
+		return err
 	}
 
-	headers := []string{"Device", "OS Version", "Hashtable", "Entries"}
+	headers := []string{
+		i18n.T(i18n.MsgHeaderDevice),
+		i18n.T(i18n.MsgHeaderOSVersion),
+		i18n.T(i18n.MsgHeaderHashtable),
+		i18n.T(i18n.MsgHeaderEntries),
+	}
 	colWidths := []int{10, 12, 25, 10}
 
 	for _, ht := range response.Hashtables {
@@ -299,8 +620,8 @@ func RenderHashtableList(response *api.HashtablesResponse) {
 		}
 	}
 
-	renderTableHeader(headers, colWidths)
-	renderTableSeparator(colWidths)
+	renderTableHeaderToBuilder(&output, headers, colWidths)
+	renderTableSeparatorToBuilder(&output, colWidths)
 
 	for _, ht := range response.Hashtables {
 		row := []string{
@@ -309,49 +630,59 @@ func RenderHashtableList(response *api.HashtablesResponse) {
 			ht.Name,
 			fmt.Sprintf("%d", ht.EntryCount),
 		}
-		renderTableRow(row, colWidths)
+		renderTableRowToBuilder(&output, row, colWidths)
 	}
 
-	fmt.Println()
-	fmt.Printf("Total Hashtables: %d\n", response.Count)
+	fmt.Fprintln(&output)
+	fmt.Fprintln(&output, i18n.T(i18n.MsgTotalHashtables, response.Count))
+
+	_, err := io.WriteString(w, output.String())
+	return err
 }
 
-func renderTableHeader(headers []string, widths []int) {
+func renderTableHeaderToBuilder(output *strings.Builder, headers []string, widths []int) {
 	var cells []string
 	for i, header := range headers {
 		cell := lipgloss.NewStyle().Width(widths[i]).Render(header)
 		cells = append(cells, cell)
 	}
-	fmt.Println(" " + lipgloss.JoinHorizontal(lipgloss.Left, cells...))
+	fmt.Fprintln(output, " "+lipgloss.JoinHorizontal(lipgloss.Left, cells...))
 }
 
-func renderTableSeparator(widths []int) {
+func renderTableSeparatorToBuilder(output *strings.Builder, widths []int) {
 	totalWidth := 0
 	for _, width := range widths {
 		totalWidth += width
 	}
-	fmt.Println(strings.Repeat("─", totalWidth+len(widths)))
+	fmt.Fprintln(output, strings.Repeat("─", totalWidth+len(widths)))
 }
 
-func renderTableRow(cells []string, widths []int) {
+func renderTableRowToBuilder(output *strings.Builder, cells []string, widths []int) {
 	var renderedCells []string
 	for i, cell := range cells {
 		rendered := lipgloss.NewStyle().Width(widths[i]).Render(cell)
 		renderedCells = append(renderedCells, rendered)
 	}
-	fmt.Println(" " + lipgloss.JoinHorizontal(lipgloss.Left, renderedCells...))
+	fmt.Fprintln(output, " "+lipgloss.JoinHorizontal(lipgloss.Left, renderedCells...))
 }
 
-func RenderTreeList(response *api.TreesResponse) {
-	fmt.Println(titleStyle.Render("Available QML Trees"))
-	fmt.Println()
+func RenderTreeList(w io.Writer, response *api.TreesResponse) error {
+	var output strings.Builder
+	fmt.Fprintln(&output, titleStyle.Render(i18n.T(i18n.MsgTreesTitle)))
+	fmt.Fprintln(&output)
 
 	if response.Count == 0 {
-		fmt.Println(infoStyle.Render("No QML trees available on the server"))
-		return
+		fmt.Fprintln(&output, infoStyle.Render(i18n.T(i18n.MsgNoTrees)))
+		_, err := io.WriteString(w, output.String())
+		return err
 	}
 
-	headers := []string{"Device", "OS Version", "QML Files", "Directory"}
+	headers := []string{
+		i18n.T(i18n.MsgHeaderDevice),
+		i18n.T(i18n.MsgHeaderOSVersion),
+		i18n.T(i18n.MsgHeaderQMLFiles),
+		i18n.T(i18n.MsgHeaderDirectory),
+	}
 	colWidths := []int{10, 15, 12, 30}
 
 	for _, tree := range response.Trees {
@@ -366,8 +697,8 @@ func RenderTreeList(response *api.TreesResponse) {
 		}
 	}
 
-	renderTableHeader(headers, colWidths)
-	renderTableSeparator(colWidths)
+	renderTableHeaderToBuilder(&output, headers, colWidths)
+	renderTableSeparatorToBuilder(&output, colWidths)
 
 	for _, tree := range response.Trees {
 		row := []string{
@@ -376,21 +707,27 @@ func RenderTreeList(response *api.TreesResponse) {
 			fmt.Sprintf("%d", tree.QMLCount),
 			tree.Directory,
 		}
-		renderTableRow(row, colWidths)
+		renderTableRowToBuilder(&output, row, colWidths)
 	}
 
-	fmt.Println()
-	fmt.Printf("Total Trees: %d\n", response.Count)
+	fmt.Fprintln(&output)
+	fmt.Fprintln(&output, i18n.T(i18n.MsgTotalTrees, response.Count))
+
+	_, err := io.WriteString(w, output.String())
+	return err
 }
 
-func RenderError(err error) {
-	fmt.Println(errorStyle.Render(fmt.Sprintf("Error: %s", err.Error())))
+func RenderError(w io.Writer, err error) error {
+	_, writeErr := fmt.Fprintln(w, errorStyle.Render(i18n.T(i18n.MsgErrorPrefix, err.Error())))
+	return writeErr
 }
 
-func RenderSuccess(message string) {
-	fmt.Println(compatibleStyle.Render(message))
+func RenderSuccess(w io.Writer, message string) error {
+	_, err := fmt.Fprintln(w, compatibleStyle.Render(message))
+	return err
 }
 
-func RenderInfo(message string) {
-	fmt.Println(infoStyle.Render(message))
+func RenderInfo(w io.Writer, message string) error {
+	_, err := fmt.Fprintln(w, infoStyle.Render(message))
+	return err
 }
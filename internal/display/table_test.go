@@ -1,10 +1,13 @@
 package display
 
 import (
+	"bytes"
+	"errors"
 	"reflect"
+	"strings"
 	"testing"
 
-	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/api"
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
 )
 
 func TestCompareVersions(t *testing.T) {
@@ -239,6 +242,28 @@ func TestGetDeviceOrder(t *testing.T) {
 	}
 }
 
+func TestPreferDevices(t *testing.T) {
+	defaults := []string{"rm1", "rm2", "rmpp", "rmppm"}
+	tests := []struct {
+		name      string
+		preferred []string
+		want      []string
+	}{
+		{name: "no preference", preferred: nil, want: defaults},
+		{name: "primary device first", preferred: []string{"rmpp"}, want: []string{"rmpp", "rm1", "rm2", "rmppm"}},
+		{name: "full order", preferred: []string{"rmppm", "rmpp", "rm2", "rm1"}, want: []string{"rmppm", "rmpp", "rm2", "rm1"}},
+		{name: "absent and repeated devices ignored", preferred: []string{"rmppm", "other", "rmppm"}, want: []string{"rmppm", "rm1", "rm2", "rmpp"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := preferDevices(defaults, tt.preferred); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("preferDevices() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestBuildCompatibilityMatrix(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -331,3 +356,348 @@ func TestBuildCompatibilityMatrix(t *testing.T) {
 		})
 	}
 }
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestRenderComparisonResults(t *testing.T) {
+	response := &api.ComparisonResponse{
+		Compatible: []api.ComparisonResult{
+			{Device: "rmpp", OSVersion: "3.22.4.2", Compatible: true},
+		},
+		Incompatible: []api.ComparisonResult{
+			{Device: "rm2", OSVersion: "3.22.4.2", Compatible: false, ErrorDetail: "Cannot resolve hash 123"},
+		},
+		TotalChecked: 2,
+	}
+
+	t.Run("writes matrix and summary", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := RenderComparisonResults(&buf, response, MatrixOptions{Verbose: true}); err != nil {
+			t.Fatalf("RenderComparisonResults() error = %v", err)
+		}
+
+		out := buf.String()
+		for _, want := range []string{"reMarkable QMD Verifier", "3.22.4.2", "Cannot resolve hash 123", "Summary: 2 checked"} {
+			if !strings.Contains(out, want) {
+				t.Errorf("RenderComparisonResults() output missing %q:\n%s", want, out)
+			}
+		}
+	})
+
+	t.Run("no data", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := RenderComparisonResults(&buf, &api.ComparisonResponse{}, MatrixOptions{}); err != nil {
+			t.Fatalf("RenderComparisonResults() error = %v", err)
+		}
+		if !strings.Contains(buf.String(), "No compatibility data available") {
+			t.Errorf("RenderComparisonResults() output = %q", buf.String())
+		}
+	})
+
+	t.Run("propagates write errors", func(t *testing.T) {
+		if err := RenderComparisonResults(failingWriter{}, response, MatrixOptions{}); err == nil {
+			t.Error("RenderComparisonResults() expected error from failing writer")
+		}
+	})
+}
+
+func TestRenderHashtableList(t *testing.T) {
+	response := &api.HashtablesResponse{
+		Hashtables: []api.HashtableInfo{
+			{Name: "3.22.4.2-rmpp", OSVersion: "3.22.4.2", Device: "rmpp", EntryCount: 11217},
+		},
+		Count: 1,
+	}
+
+	var buf bytes.Buffer
+	if err := RenderHashtableList(&buf, response); err != nil {
+		t.Fatalf("RenderHashtableList() error = %v", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{"Available Hashtables", "3.22.4.2-rmpp", "11217", "Total Hashtables: 1"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("RenderHashtableList() output missing %q:\n%s", want, out)
+		}
+	}
+
+	if err := RenderHashtableList(failingWriter{}, response); err == nil {
+		t.Error("RenderHashtableList() expected error from failing writer")
+	}
+}
+
+func TestRenderTreeList(t *testing.T) {
+	response := &api.TreesResponse{
+		Trees: []api.TreeInfo{
+			{Version: "3.22.4.2", Device: "rmppm", QMLCount: 518, Directory: "3.22.4.2-rmppm"},
+		},
+		Count: 1,
+	}
+
+	var buf bytes.Buffer
+	if err := RenderTreeList(&buf, response); err != nil {
+		t.Fatalf("RenderTreeList() error = %v", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{"Available QML Trees", "3.22.4.2-rmppm", "518", "Total Trees: 1"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("RenderTreeList() output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestCountPasses(t *testing.T) {
+	response := &api.ComparisonResponse{
+		Compatible: []api.ComparisonResult{
+			{Device: "rmpp", OSVersion: "3.22.4.2"},
+			{Device: "rmpp", OSVersion: "3.22.0.64"},
+			{Device: "rm2", OSVersion: "3.22.0.64"},
+		},
+		Incompatible: []api.ComparisonResult{
+			{Device: "rm2", OSVersion: "3.22.4.2"},
+		},
+	}
+	matrix := buildCompatibilityMatrix(response)
+	devices := getDeviceOrder(matrix)
+	versions := getSortedVersions(matrix)
+
+	if passed, total := countVersionPasses(matrix["3.22.4.2"], devices); passed != 1 || total != 2 {
+		t.Errorf("countVersionPasses(3.22.4.2) = %d/%d, want 1/2", passed, total)
+	}
+	if passed, total := countDevicePasses(matrix, versions, "rmpp"); passed != 2 || total != 2 {
+		t.Errorf("countDevicePasses(rmpp) = %d/%d, want 2/2", passed, total)
+	}
+	if passed, total := countDevicePasses(matrix, versions, "rm1"); passed != 0 || total != 0 {
+		t.Errorf("countDevicePasses(rm1) = %d/%d, want 0/0", passed, total)
+	}
+}
+
+func TestRenderComparisonResultsTotalsAndLegend(t *testing.T) {
+	response := &api.ComparisonResponse{
+		Compatible: []api.ComparisonResult{
+			{Device: "rmpp", OSVersion: "3.22.4.2"},
+		},
+		Incompatible: []api.ComparisonResult{
+			{Device: "rm2", OSVersion: "3.22.4.2"},
+		},
+		TotalChecked: 2,
+	}
+
+	var buf bytes.Buffer
+	if err := RenderComparisonResults(&buf, response, MatrixOptions{Totals: true, Legend: true}); err != nil {
+		t.Fatalf("RenderComparisonResults() error = %v", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{"Pass", "1/2", "Legend:", "no data"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("RenderComparisonResults() output missing %q:\n%s", want, out)
+		}
+	}
+
+	buf.Reset()
+	if err := RenderComparisonResults(&buf, response, MatrixOptions{}); err != nil {
+		t.Fatalf("RenderComparisonResults() error = %v", err)
+	}
+	if strings.Contains(buf.String(), "Legend:") || strings.Contains(buf.String(), "Pass") {
+		t.Errorf("RenderComparisonResults() rendered totals or legend without options:\n%s", buf.String())
+	}
+}
+
+func TestRenderComparisonResultsNewVersions(t *testing.T) {
+	response := &api.ComparisonResponse{
+		Compatible: []api.ComparisonResult{
+			{Device: "rmpp", OSVersion: "3.23.0.64"},
+			{Device: "rmpp", OSVersion: "3.22.0.64"},
+		},
+		TotalChecked: 2,
+	}
+
+	var buf bytes.Buffer
+	opts := MatrixOptions{NewVersions: map[string]bool{"3.23.0.64": true}}
+	if err := RenderComparisonResults(&buf, response, opts); err != nil {
+		t.Fatalf("RenderComparisonResults() error = %v", err)
+	}
+
+	out := buf.String()
+	if !strings.Contains(out, "3.23.0.64 NEW") {
+		t.Errorf("RenderComparisonResults() missing NEW tag:\n%s", out)
+	}
+	if strings.Contains(out, "3.22.0.64 NEW") {
+		t.Errorf("RenderComparisonResults() tagged a known version:\n%s", out)
+	}
+}
+
+func TestRenderComparisonResultsIncompatibleOnly(t *testing.T) {
+	response := &api.ComparisonResponse{
+		Compatible: []api.ComparisonResult{
+			{Device: "rmpp", OSVersion: "3.23.0.64"},
+			{Device: "rmpp", OSVersion: "3.22.0.64"},
+			{Device: "rm2", OSVersion: "3.22.0.64"},
+		},
+		Incompatible: []api.ComparisonResult{
+			{Device: "rm2", OSVersion: "3.23.0.64"},
+		},
+		TotalChecked: 4,
+	}
+
+	var buf bytes.Buffer
+	if err := RenderComparisonResults(&buf, response, MatrixOptions{IncompatibleOnly: true, Totals: true}); err != nil {
+		t.Fatalf("RenderComparisonResults() error = %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, " 3.23.0.64  ") || strings.Contains(out, " 3.22.0.64  ") {
+		t.Errorf("RenderComparisonResults() rows not filtered:\n%s", out)
+	}
+	for _, want := range []string{"3/4", "Summary: 4 checked"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("RenderComparisonResults() output missing %q:\n%s", want, out)
+		}
+	}
+
+	buf.Reset()
+	response.Compatible = append(response.Compatible, response.Incompatible...)
+	response.Incompatible = nil
+	if err := RenderComparisonResults(&buf, response, MatrixOptions{IncompatibleOnly: true}); err != nil {
+		t.Fatalf("RenderComparisonResults() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "All 2 versions are compatible") {
+		t.Errorf("RenderComparisonResults() output for an all-compatible matrix:\n%s", buf.String())
+	}
+}
+
+func TestLatestVersions(t *testing.T) {
+	matrix := map[string]map[string]matrixCell{
+		"3.23.0.64": {"rmpp": {compatible: true, hasData: true}},
+		"3.22.4.2":  {"rmppm": {compatible: true, hasData: true}},
+		"3.22.0.64": {"rmpp": {compatible: true, hasData: true}, "rm2": {compatible: false, hasData: true}},
+		"3.20.0.92": {"rmpp": {compatible: true, hasData: true}, "rm2": {compatible: true, hasData: true}},
+	}
+
+	got := latestVersions(matrix, 1)
+	want := map[string]map[string]matrixCell{
+		"3.23.0.64": {"rmpp": {compatible: true, hasData: true}},
+		"3.22.4.2":  {"rmppm": {compatible: true, hasData: true}},
+		"3.22.0.64": {"rm2": {compatible: false, hasData: true}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("latestVersions(1) = %v, want %v", got, want)
+	}
+
+	if got := latestVersions(matrix, 10); !reflect.DeepEqual(got, matrix) {
+		t.Errorf("latestVersions(10) = %v, want the full matrix", got)
+	}
+}
+
+func TestRenderComparisonResultsDependencyTree(t *testing.T) {
+	response := &api.ComparisonResponse{
+		Incompatible: []api.ComparisonResult{{
+			Device: "rm2", OSVersion: "3.22.4.2", ErrorDetail: "verification failed",
+			DependencyResults: map[string]*api.ValidationResult{
+				"main.qmd":       {Status: "compatible"},
+				"lib/shared.qmd": {Status: "incompatible", HashErrors: []api.HashError{{HashID: 1}, {HashID: 2}}},
+			},
+		}},
+		TotalChecked: 1,
+	}
+
+	var buf bytes.Buffer
+	if err := RenderComparisonResults(&buf, response, MatrixOptions{Verbose: true}); err != nil {
+		t.Fatalf("RenderComparisonResults() error = %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"├─ lib/shared.qmd → incompatible → 2 hash error(s)", "└─ main.qmd → compatible"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("RenderComparisonResults() output missing %q:\n%s", want, out)
+		}
+	}
+
+	buf.Reset()
+	if err := RenderComparisonResults(&buf, response, MatrixOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "lib/shared.qmd") {
+		t.Errorf("RenderComparisonResults() without Verbose shows the dependency tree:\n%s", buf.String())
+	}
+}
+
+func TestRenderComparisonResultsValidationMode(t *testing.T) {
+	response := &api.ComparisonResponse{
+		Compatible: []api.ComparisonResult{
+			{Device: "rmpp", OSVersion: "3.22.4.2", Compatible: true, ValidationMode: "tree", TreeValidationUsed: true},
+			{Device: "rm2", OSVersion: "3.22.4.2", Compatible: true, ValidationMode: "hashtable"},
+		},
+		TotalChecked: 2,
+	}
+
+	var buf bytes.Buffer
+	if err := RenderComparisonResults(&buf, response, MatrixOptions{Legend: true}); err != nil {
+		t.Fatalf("RenderComparisonResults() error = %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"✓ᵀ", "ᵀ tree-validated", "Validation: 1 tree-validated (ᵀ) | 1 hashtable only"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("RenderComparisonResults() output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Count(out, "✓ᵀ") != 1 {
+		t.Errorf("RenderComparisonResults() marked %d cells, want 1:\n%s", strings.Count(out, "✓ᵀ"), out)
+	}
+
+	buf.Reset()
+	response.Compatible[0].ValidationMode, response.Compatible[0].TreeValidationUsed = "hashtable", false
+	if err := RenderComparisonResults(&buf, response, MatrixOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), treeMarker) {
+		t.Errorf("RenderComparisonResults() marks hashtable-only results:\n%s", buf.String())
+	}
+}
+
+func TestRenderComparisonResultsTranspose(t *testing.T) {
+	response := &api.ComparisonResponse{
+		Compatible: []api.ComparisonResult{
+			{Device: "rmpp", OSVersion: "3.22.4.2"},
+			{Device: "rm2", OSVersion: "3.22.0.64"},
+		},
+		Incompatible: []api.ComparisonResult{
+			{Device: "rm2", OSVersion: "3.22.4.2"},
+		},
+		TotalChecked: 3,
+	}
+
+	var buf bytes.Buffer
+	if err := RenderComparisonResults(&buf, response, MatrixOptions{Transpose: true, Totals: true}); err != nil {
+		t.Fatalf("RenderComparisonResults() error = %v", err)
+	}
+
+	lines := strings.Split(buf.String(), "\n")
+	find := func(prefix string) string {
+		for _, line := range lines {
+			if strings.HasPrefix(strings.TrimSpace(line), prefix) {
+				return line
+			}
+		}
+		t.Fatalf("no line starting with %q:\n%s", prefix, buf.String())
+		return ""
+	}
+
+	header := find("3.22.4.2")
+	if strings.Index(header, "3.22.4.2") > strings.Index(header, "3.22.0.64") || !strings.Contains(header, "Pass") {
+		t.Errorf("header = %q, want versions newest first across the top", header)
+	}
+	if rm2 := strings.Fields(find("rm2")); !reflect.DeepEqual(rm2, []string{"rm2", "✗", "✓", "1/2"}) {
+		t.Errorf("rm2 row = %v", rm2)
+	}
+	if rmpp := strings.Fields(find("rmpp")); !reflect.DeepEqual(rmpp, []string{"rmpp", "✓", "—", "1/1"}) {
+		t.Errorf("rmpp row = %v", rmpp)
+	}
+	if totals := strings.Fields(find("Pass ")); !reflect.DeepEqual(totals, []string{"Pass", "1/2", "1/1", "2/3"}) {
+		t.Errorf("totals row = %v", totals)
+	}
+}
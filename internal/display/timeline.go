@@ -0,0 +1,113 @@
+package display
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/i18n"
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+var firstBrokenStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("#FF0000")).
+	Bold(true).
+	Underline(true)
+
+type timelineEntry struct {
+	version    string
+	compatible bool
+}
+
+func RenderTimeline(w io.Writer, response *api.ComparisonResponse) error {
+	matrix := buildCompatibilityMatrix(response)
+	devices := getDeviceOrder(matrix)
+	versions := getChronologicalVersions(matrix)
+
+	if len(versions) == 0 {
+		return RenderInfo(w, i18n.T(i18n.MsgNoCompatibilityData))
+	}
+
+	deviceColWidth := 6
+	for _, device := range devices {
+		if len(device) > deviceColWidth {
+			deviceColWidth = len(device)
+		}
+	}
+
+	var output strings.Builder
+	fmt.Fprintln(&output)
+	fmt.Fprintln(&output, i18n.T(i18n.MsgTimelineTitle))
+	fmt.Fprintln(&output)
+
+	indent := strings.Repeat(" ", deviceColWidth+2)
+	for _, device := range devices {
+		entries := buildTimeline(matrix, versions, device)
+		broken := firstBrokenIndex(entries)
+
+		var strip []string
+		for i, entry := range entries {
+			strip = append(strip, renderTimelineEntry(entry, i == broken))
+		}
+
+		deviceCell := lipgloss.NewStyle().Width(deviceColWidth).Render(device)
+		fmt.Fprintf(&output, " %s %s\n", deviceCell, strings.Join(strip, noDataStyle.Render(" → ")))
+
+		if broken >= 0 {
+			fmt.Fprintf(&output, "%s%s\n", indent, incompatibleStyle.Render(i18n.T(i18n.MsgFirstBroken, entries[broken].version)))
+		}
+	}
+
+	fmt.Fprintln(&output)
+	renderSummaryToBuilder(&output, response)
+
+	_, err := io.WriteString(w, output.String())
+	return err
+}
+
+func renderTimelineEntry(entry timelineEntry, firstBroken bool) string {
+	if entry.compatible {
+		return compatibleMark() + " " + entry.version
+	}
+	if firstBroken {
+		return incompatibleMark() + " " + firstBrokenStyle.Render(entry.version)
+	}
+	return incompatibleMark() + " " + entry.version
+}
+
+func getChronologicalVersions(matrix map[string]map[string]matrixCell) []string {
+	versions := getSortedVersions(matrix)
+	for i, j := 0, len(versions)-1; i < j; i, j = i+1, j-1 {
+		versions[i], versions[j] = versions[j], versions[i]
+	}
+	return versions
+}
+
+func buildTimeline(matrix map[string]map[string]matrixCell, versions []string, device string) []timelineEntry {
+	var entries []timelineEntry
+	for _, version := range versions {
+		cell, exists := matrix[version][device]
+		if !exists || !cell.hasData {
+			continue
+		}
+		entries = append(entries, timelineEntry{version: version, compatible: cell.compatible})
+	}
+	return entries
+}
+
+// firstBrokenIndex returns the index of the first incompatible entry that
+// follows a compatible one, or -1 if the device never regressed.
+func firstBrokenIndex(entries []timelineEntry) int {
+	seenCompatible := false
+	for i, entry := range entries {
+		if entry.compatible {
+			seenCompatible = true
+			continue
+		}
+		if seenCompatible {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,135 @@
+package display
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+func TestFirstBrokenIndex(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []timelineEntry
+		want    int
+	}{
+		{
+			name:    "empty timeline",
+			entries: nil,
+			want:    -1,
+		},
+		{
+			name: "always compatible",
+			entries: []timelineEntry{
+				{version: "3.20.0.92", compatible: true},
+				{version: "3.22.0.64", compatible: true},
+			},
+			want: -1,
+		},
+		{
+			name: "never compatible",
+			entries: []timelineEntry{
+				{version: "3.20.0.92", compatible: false},
+				{version: "3.22.0.64", compatible: false},
+			},
+			want: -1,
+		},
+		{
+			name: "breaks on newest version",
+			entries: []timelineEntry{
+				{version: "3.20.0.92", compatible: true},
+				{version: "3.22.0.64", compatible: true},
+				{version: "3.23.0.64", compatible: false},
+			},
+			want: 2,
+		},
+		{
+			name: "starts compatible after early failures",
+			entries: []timelineEntry{
+				{version: "3.19.0.0", compatible: false},
+				{version: "3.20.0.92", compatible: true},
+				{version: "3.22.0.64", compatible: false},
+				{version: "3.23.0.64", compatible: false},
+			},
+			want: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := firstBrokenIndex(tt.entries); got != tt.want {
+				t.Errorf("firstBrokenIndex() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildTimeline(t *testing.T) {
+	response := &api.ComparisonResponse{
+		Compatible: []api.ComparisonResult{
+			{Device: "rmpp", OSVersion: "3.20.0.92"},
+			{Device: "rmpp", OSVersion: "3.22.0.64"},
+			{Device: "rm2", OSVersion: "3.20.0.92"},
+		},
+		Incompatible: []api.ComparisonResult{
+			{Device: "rmpp", OSVersion: "3.23.0.64"},
+		},
+	}
+
+	matrix := buildCompatibilityMatrix(response)
+	versions := getChronologicalVersions(matrix)
+
+	wantVersions := []string{"3.20.0.92", "3.22.0.64", "3.23.0.64"}
+	if !reflect.DeepEqual(versions, wantVersions) {
+		t.Fatalf("getChronologicalVersions() = %v, want %v", versions, wantVersions)
+	}
+
+	got := buildTimeline(matrix, versions, "rmpp")
+	want := []timelineEntry{
+		{version: "3.20.0.92", compatible: true},
+		{version: "3.22.0.64", compatible: true},
+		{version: "3.23.0.64", compatible: false},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("buildTimeline(rmpp) = %v, want %v", got, want)
+	}
+
+	got = buildTimeline(matrix, versions, "rm2")
+	want = []timelineEntry{
+		{version: "3.20.0.92", compatible: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("buildTimeline(rm2) = %v, want %v", got, want)
+	}
+}
+
+func TestRenderTimeline(t *testing.T) {
+	response := &api.ComparisonResponse{
+		Compatible: []api.ComparisonResult{
+			{Device: "rmpp", OSVersion: "3.20.0.92"},
+			{Device: "rmpp", OSVersion: "3.22.0.64"},
+		},
+		Incompatible: []api.ComparisonResult{
+			{Device: "rmpp", OSVersion: "3.23.0.64"},
+		},
+		TotalChecked: 3,
+	}
+
+	var buf bytes.Buffer
+	if err := RenderTimeline(&buf, response); err != nil {
+		t.Fatalf("RenderTimeline() error = %v", err)
+	}
+
+	out := buf.String()
+	if !strings.Contains(out, "3.20.0.92 → ✓ 3.22.0.64 → ✗ 3.23.0.64") {
+		t.Errorf("RenderTimeline() missing chronological strip:\n%s", out)
+	}
+	if !strings.Contains(out, "first broken: 3.23.0.64") {
+		t.Errorf("RenderTimeline() missing first broken marker:\n%s", out)
+	}
+	if !strings.Contains(out, "Summary: 3 checked") {
+		t.Errorf("RenderTimeline() missing summary:\n%s", out)
+	}
+}
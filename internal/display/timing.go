@@ -0,0 +1,34 @@
+package display
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/i18n"
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+// RenderTiming prints the timing footer. Upload and server times are left
+// out when they were not measured.
+func RenderTiming(w io.Writer, timing *api.Timing) error {
+	parts := []string{i18n.T(i18n.MsgTimingTotal, formatMillis(timing.TotalMS))}
+	if timing.UploadMS > 0 {
+		parts = append(parts, i18n.T(i18n.MsgTimingUpload, formatMillis(timing.UploadMS)))
+	}
+	if timing.QueueMS > 0 || timing.ProcessingMS > 0 {
+		parts = append(parts, i18n.T(i18n.MsgTimingServer, formatMillis(timing.QueueMS), formatMillis(timing.ProcessingMS)))
+	}
+
+	_, err := fmt.Fprintln(w, noDataStyle.Render(strings.Join(parts, " | ")))
+	return err
+}
+
+func formatMillis(ms int64) string {
+	d := time.Duration(ms) * time.Millisecond
+	if d >= time.Second {
+		d = d.Round(10 * time.Millisecond)
+	}
+	return d.String()
+}
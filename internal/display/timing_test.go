@@ -0,0 +1,49 @@
+package display
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+func TestRenderTiming(t *testing.T) {
+	tests := []struct {
+		name    string
+		timing  api.Timing
+		want    []string
+		notWant []string
+	}{
+		{
+			name:   "server timing",
+			timing: api.Timing{UploadMS: 1234, QueueMS: 120, ProcessingMS: 2100, TotalMS: 4012},
+			want:   []string{"4.01s total", "1.23s upload", "120ms queued, 2.1s processing"},
+		},
+		{
+			name:    "local check",
+			timing:  api.Timing{TotalMS: 42},
+			want:    []string{"42ms total"},
+			notWant: []string{"upload", "server"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := RenderTiming(&buf, &tt.timing); err != nil {
+				t.Fatalf("RenderTiming() error = %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("RenderTiming() = %q, missing %q", buf.String(), want)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(buf.String(), notWant) {
+					t.Errorf("RenderTiming() = %q, should not contain %q", buf.String(), notWant)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package display
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+// RenderToltec writes the compatible devices and OS versions as bash
+// variables that can be pasted into (or sourced from) a toltec package
+// recipe. Devices without a compatible version are left out of archs.
+func RenderToltec(w io.Writer, response *api.ComparisonResponse) error {
+	matrix := buildCompatibilityMatrix(response)
+	devices := getDeviceOrder(matrix)
+	versions := getChronologicalVersions(matrix)
+
+	var output strings.Builder
+	fmt.Fprintln(&output, "# Generated by qmdverify")
+
+	var archs []string
+	compatibleByDevice := make(map[string][]string)
+	for _, device := range devices {
+		for _, entry := range buildTimeline(matrix, versions, device) {
+			if entry.compatible {
+				compatibleByDevice[device] = append(compatibleByDevice[device], entry.version)
+			}
+		}
+		if len(compatibleByDevice[device]) > 0 {
+			archs = append(archs, device)
+		}
+	}
+
+	fmt.Fprintf(&output, "archs=(%s)\n", strings.Join(archs, " "))
+	for _, device := range archs {
+		fmt.Fprintf(&output, "qmd_compatible_os_%s=(%s)\n", device, strings.Join(compatibleByDevice[device], " "))
+	}
+
+	_, err := io.WriteString(w, output.String())
+	return err
+}
@@ -0,0 +1,36 @@
+package display
+
+import (
+	"bytes"
+	"testing"
+
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+func TestRenderToltec(t *testing.T) {
+	response := &api.ComparisonResponse{
+		Compatible: []api.ComparisonResult{
+			{OSVersion: "3.22.0.64", Device: "rmpp", Compatible: true},
+			{OSVersion: "3.20.0.92", Device: "rmpp", Compatible: true},
+			{OSVersion: "3.20.0.92", Device: "rm2", Compatible: true},
+		},
+		Incompatible: []api.ComparisonResult{
+			{OSVersion: "3.22.0.64", Device: "rm2", Compatible: false},
+			{OSVersion: "3.22.4.2", Device: "rmppm", Compatible: false},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := RenderToltec(&buf, response); err != nil {
+		t.Fatalf("RenderToltec() error = %v", err)
+	}
+
+	want := `# Generated by qmdverify
+archs=(rm2 rmpp)
+qmd_compatible_os_rm2=(3.20.0.92)
+qmd_compatible_os_rmpp=(3.20.0.92 3.22.0.64)
+`
+	if got := buf.String(); got != want {
+		t.Errorf("RenderToltec() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,196 @@
+package expected
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/osversion"
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+	"gopkg.in/yaml.v3"
+)
+
+// Matrix maps device -> OS version -> whether the file should be compatible.
+// It has the same shape as the matrix in a compatibility manifest.
+type Matrix map[string]map[string]bool
+
+type Kind string
+
+const (
+	Gained     Kind = "gained"
+	Lost       Kind = "lost"
+	Missing    Kind = "missing"
+	Unexpected Kind = "unexpected"
+)
+
+type Deviation struct {
+	Kind      Kind   `json:"kind"`
+	Device    string `json:"device"`
+	OSVersion string `json:"os_version"`
+	Detail    string `json:"detail,omitempty"`
+}
+
+// Load reads an expected matrix from CSV (.csv) or YAML. JSON and
+// compatibility manifests (*.qmd.compat.json) are read as YAML; a top-level
+// "matrix" key is used when present.
+func Load(path string) (Matrix, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read expected matrix: %w", err)
+	}
+
+	var matrix Matrix
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		matrix, err = ParseCSV(strings.NewReader(string(data)))
+	} else {
+		matrix, err = ParseYAML(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	if matrix.Len() == 0 {
+		return nil, fmt.Errorf("expected matrix %s is empty", path)
+	}
+
+	return matrix, nil
+}
+
+func ParseYAML(data []byte) (Matrix, error) {
+	var wrapped struct {
+		Matrix Matrix `yaml:"matrix"`
+	}
+	if err := yaml.Unmarshal(data, &wrapped); err == nil && len(wrapped.Matrix) > 0 {
+		return wrapped.Matrix, nil
+	}
+
+	var matrix Matrix
+	if err := yaml.Unmarshal(data, &matrix); err != nil {
+		return nil, err
+	}
+	return matrix, nil
+}
+
+// ParseCSV reads "device,os_version,compatible" rows. A header row is
+// skipped, and the compatible column accepts true/false, yes/no and 1/0.
+func ParseCSV(r io.Reader) (Matrix, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 3
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	matrix := make(Matrix)
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if line == 1 && strings.EqualFold(record[0], "device") {
+			continue
+		}
+
+		compatible, err := parseCompatible(record[2])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		matrix.Set(record[0], record[1], compatible)
+	}
+
+	return matrix, nil
+}
+
+func parseCompatible(value string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "yes", "y", "compatible":
+		return true, nil
+	case "no", "n", "incompatible":
+		return false, nil
+	}
+	compatible, err := strconv.ParseBool(strings.TrimSpace(value))
+	if err != nil {
+		return false, fmt.Errorf("invalid compatible value '%s'", value)
+	}
+	return compatible, nil
+}
+
+func (m Matrix) Set(device, version string, compatible bool) {
+	if m[device] == nil {
+		m[device] = make(map[string]bool)
+	}
+	m[device][version] = compatible
+}
+
+func (m Matrix) Len() int {
+	n := 0
+	for _, versions := range m {
+		n += len(versions)
+	}
+	return n
+}
+
+// FromResponse builds a matrix from actual results.
+func FromResponse(response *api.ComparisonResponse) Matrix {
+	matrix := make(Matrix)
+	for _, results := range [][]api.ComparisonResult{response.Compatible, response.Incompatible} {
+		for _, result := range results {
+			matrix.Set(result.Device, result.OSVersion, result.Compatible)
+		}
+	}
+	return matrix
+}
+
+// Compare returns every difference between the expected matrix and the
+// actual results: gained and lost compatibility, and expected entries the
+// server no longer checks. Results absent from the expected matrix are only
+// reported when strict is set.
+func Compare(want Matrix, response *api.ComparisonResponse, strict bool) []Deviation {
+	got := FromResponse(response)
+	details := make(map[[2]string]string)
+	for _, result := range response.Incompatible {
+		details[[2]string{result.Device, result.OSVersion}] = result.ErrorDetail
+	}
+
+	var deviations []Deviation
+	for device, versions := range want {
+		for version, wantCompatible := range versions {
+			gotCompatible, ok := got[device][version]
+			switch {
+			case !ok:
+				deviations = append(deviations, Deviation{Kind: Missing, Device: device, OSVersion: version})
+			case gotCompatible && !wantCompatible:
+				deviations = append(deviations, Deviation{Kind: Gained, Device: device, OSVersion: version})
+			case !gotCompatible && wantCompatible:
+				deviations = append(deviations, Deviation{Kind: Lost, Device: device, OSVersion: version, Detail: details[[2]string{device, version}]})
+			}
+		}
+	}
+
+	if strict {
+		for device, versions := range got {
+			for version := range versions {
+				if _, ok := want[device][version]; !ok {
+					deviations = append(deviations, Deviation{Kind: Unexpected, Device: device, OSVersion: version})
+				}
+			}
+		}
+	}
+
+	sort.Slice(deviations, func(i, j int) bool {
+		if deviations[i].Device != deviations[j].Device {
+			return deviations[i].Device < deviations[j].Device
+		}
+		return osversion.Compare(deviations[i].OSVersion, deviations[j].OSVersion) < 0
+	})
+
+	return deviations
+}
@@ -0,0 +1,144 @@
+package expected
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+func TestParseCSV(t *testing.T) {
+	input := `device,os_version,compatible
+rmpp, 3.22.0.64, true
+# comment
+rm2,3.22.0.64,no
+rm2,3.20.0.92,yes
+`
+	matrix, err := ParseCSV(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseCSV() error = %v", err)
+	}
+
+	want := Matrix{
+		"rmpp": {"3.22.0.64": true},
+		"rm2":  {"3.22.0.64": false, "3.20.0.92": true},
+	}
+	if !reflect.DeepEqual(matrix, want) {
+		t.Errorf("ParseCSV() = %v, want %v", matrix, want)
+	}
+
+	if _, err := ParseCSV(strings.NewReader("rm2,3.22,maybe\n")); err == nil {
+		t.Error("ParseCSV() expected error for invalid compatible value")
+	}
+	if _, err := ParseCSV(strings.NewReader("rm2,3.22\n")); err == nil {
+		t.Error("ParseCSV() expected error for missing column")
+	}
+}
+
+func TestParseYAML(t *testing.T) {
+	want := Matrix{
+		"rmpp": {"3.22.0.64": true, "3.22": false},
+	}
+
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "plain", input: "rmpp:\n  3.22.0.64: true\n  3.22: false\n"},
+		{name: "manifest", input: `{"file": "a.qmd", "matrix": {"rmpp": {"3.22.0.64": true, "3.22": false}}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matrix, err := ParseYAML([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("ParseYAML() error = %v", err)
+			}
+			if !reflect.DeepEqual(matrix, want) {
+				t.Errorf("ParseYAML() = %v, want %v", matrix, want)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	csvPath := filepath.Join(dir, "matrix.csv")
+	if err := os.WriteFile(csvPath, []byte("rmpp,3.22.0.64,true\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if matrix, err := Load(csvPath); err != nil || matrix.Len() != 1 {
+		t.Errorf("Load(csv) = %v, %v", matrix, err)
+	}
+
+	emptyPath := filepath.Join(dir, "empty.yaml")
+	if err := os.WriteFile(emptyPath, []byte("{}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(emptyPath); err == nil {
+		t.Error("Load() expected error for empty matrix")
+	}
+
+	if _, err := Load(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Error("Load() expected error for missing file")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	want := Matrix{
+		"rmpp": {"3.22.0.64": true, "3.20.0.92": false, "3.18.0.1": true},
+		"rm2":  {"3.22.0.64": true},
+	}
+	response := &api.ComparisonResponse{
+		Compatible: []api.ComparisonResult{
+			{Device: "rmpp", OSVersion: "3.22.0.64", Compatible: true},
+			{Device: "rmpp", OSVersion: "3.20.0.92", Compatible: true},
+			{Device: "rmppm", OSVersion: "3.22.4.2", Compatible: true},
+		},
+		Incompatible: []api.ComparisonResult{
+			{Device: "rm2", OSVersion: "3.22.0.64", ErrorDetail: "hash not found"},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		strict bool
+		want   []Deviation
+	}{
+		{
+			name: "lenient",
+			want: []Deviation{
+				{Kind: Lost, Device: "rm2", OSVersion: "3.22.0.64", Detail: "hash not found"},
+				{Kind: Missing, Device: "rmpp", OSVersion: "3.18.0.1"},
+				{Kind: Gained, Device: "rmpp", OSVersion: "3.20.0.92"},
+			},
+		},
+		{
+			name:   "strict",
+			strict: true,
+			want: []Deviation{
+				{Kind: Lost, Device: "rm2", OSVersion: "3.22.0.64", Detail: "hash not found"},
+				{Kind: Missing, Device: "rmpp", OSVersion: "3.18.0.1"},
+				{Kind: Gained, Device: "rmpp", OSVersion: "3.20.0.92"},
+				{Kind: Unexpected, Device: "rmppm", OSVersion: "3.22.4.2"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Compare(want, response, tt.strict)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Compare() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if got := Compare(FromResponse(response), response, true); len(got) != 0 {
+		t.Errorf("Compare() against itself = %+v, want none", got)
+	}
+}
@@ -0,0 +1,149 @@
+package formatter
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"time"
+
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+const cycloneDXSpecVersion = "1.5"
+
+// Properties use this namespace, since CycloneDX has no fields for
+// compatibility claims.
+const cycloneDXNamespace = "qmdverify:"
+
+type cycloneDXBOM struct {
+	BOMFormat   string               `json:"bomFormat"`
+	SpecVersion string               `json:"specVersion"`
+	Version     int                  `json:"version"`
+	Metadata    cycloneDXMetadata    `json:"metadata"`
+	Components  []cycloneDXComponent `json:"components"`
+}
+
+type cycloneDXMetadata struct {
+	Timestamp  string              `json:"timestamp"`
+	Tools      cycloneDXTools      `json:"tools"`
+	Properties []cycloneDXProperty `json:"properties,omitempty"`
+}
+
+type cycloneDXTools struct {
+	Components []cycloneDXComponent `json:"components"`
+}
+
+type cycloneDXComponent struct {
+	Type       string              `json:"type"`
+	BOMRef     string              `json:"bom-ref,omitempty"`
+	Name       string              `json:"name"`
+	Version    string              `json:"version,omitempty"`
+	Hashes     []cycloneDXHash     `json:"hashes,omitempty"`
+	Properties []cycloneDXProperty `json:"properties,omitempty"`
+}
+
+type cycloneDXHash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+
+type cycloneDXProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// CycloneDX writes the report as a CycloneDX JSON document with one file
+// component per QMD file. Each component carries its SHA-256 and, as
+// properties, the device/OS versions it was verified compatible and
+// incompatible with and the hashtables used as evidence.
+func CycloneDX(w io.Writer, report *Report) error {
+	bom := cycloneDXBOM{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: cycloneDXSpecVersion,
+		Version:     1,
+		Metadata: cycloneDXMetadata{
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+			Tools: cycloneDXTools{Components: []cycloneDXComponent{
+				{Type: "application", Name: "qmdverify", Version: report.CLIVersion},
+			}},
+			Properties: cycloneDXProperties("server", report.Server, "server_version", report.ServerVersion),
+		},
+		Components: make([]cycloneDXComponent, 0, len(report.Results)),
+	}
+
+	for _, file := range report.Files() {
+		component := cycloneDXComponent{Type: "file", BOMRef: file, Name: file}
+		if sum := report.Digests[file]; sum != "" {
+			component.Hashes = []cycloneDXHash{{Alg: "SHA-256", Content: sum}}
+		}
+		component.Properties = compatibilityClaims(report.Results[file])
+		bom.Components = append(bom.Components, component)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(bom)
+}
+
+// compatibilityClaims lists "<device> <os_version>" claims, sorted, followed
+// by the hashtables and validation modes the claims rest on.
+func compatibilityClaims(response *api.ComparisonResponse) []cycloneDXProperty {
+	var compatible, incompatible []string
+	hashtables := make(map[string]bool)
+	modes := make(map[string]bool)
+	for _, results := range [][]api.ComparisonResult{response.Compatible, response.Incompatible} {
+		for _, result := range results {
+			claim := result.Device + " " + result.OSVersion
+			if result.Compatible {
+				compatible = append(compatible, claim)
+			} else {
+				incompatible = append(incompatible, claim)
+			}
+			if result.Hashtable != "" {
+				hashtables[result.Hashtable] = true
+			}
+			if result.ValidationMode != "" {
+				modes[result.ValidationMode] = true
+			}
+		}
+	}
+	sort.Strings(compatible)
+	sort.Strings(incompatible)
+
+	var properties []cycloneDXProperty
+	for _, group := range []struct {
+		name   string
+		values []string
+	}{
+		{"compatible", compatible},
+		{"incompatible", incompatible},
+		{"hashtable", sortedKeys(hashtables)},
+		{"validation_mode", sortedKeys(modes)},
+	} {
+		for _, value := range group.values {
+			properties = append(properties, cycloneDXProperty{Name: cycloneDXNamespace + group.name, Value: value})
+		}
+	}
+	return properties
+}
+
+// cycloneDXProperties builds namespaced properties from name/value pairs,
+// skipping empty values.
+func cycloneDXProperties(pairs ...string) []cycloneDXProperty {
+	var properties []cycloneDXProperty
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i+1] != "" {
+			properties = append(properties, cycloneDXProperty{Name: cycloneDXNamespace + pairs[i], Value: pairs[i+1]})
+		}
+	}
+	return properties
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
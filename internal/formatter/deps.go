@@ -0,0 +1,130 @@
+package formatter
+
+import (
+	"sort"
+
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+// SharedDependency is a dependency loaded by more than one root file. Its
+// results are reported once instead of under every parent.
+type SharedDependency struct {
+	File    string             `json:"file"`
+	UsedBy  []string           `json:"used_by"`
+	Results []DependencyResult `json:"results"`
+}
+
+// DependencyResult is a shared dependency's result against one hashtable.
+type DependencyResult struct {
+	Device     string          `json:"device"`
+	OSVersion  string          `json:"os_version"`
+	Status     string          `json:"status"`
+	HashErrors []api.HashError `json:"hash_errors,omitempty"`
+}
+
+// Compatible reports whether the dependency validated cleanly.
+func (r DependencyResult) Compatible() bool {
+	return r.Status == "compatible" && len(r.HashErrors) == 0
+}
+
+// SharedDependencies finds the dependencies used by two or more of the
+// given root files, in file order.
+func SharedDependencies(results map[string]*api.ComparisonResponse) []SharedDependency {
+	usedBy := make(map[string][]string)
+	first := make(map[string][]DependencyResult)
+
+	files := make([]string, 0, len(results))
+	for file := range results {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		response := results[file]
+		seen := make(map[string]bool)
+		for _, result := range append(append([]api.ComparisonResult{}, response.Compatible...), response.Incompatible...) {
+			for dep, validation := range result.DependencyResults {
+				if dep == file || validation == nil {
+					continue
+				}
+				if !seen[dep] {
+					seen[dep] = true
+					usedBy[dep] = append(usedBy[dep], file)
+				}
+				if len(usedBy[dep]) == 1 {
+					first[dep] = append(first[dep], DependencyResult{
+						Device:     result.Device,
+						OSVersion:  result.OSVersion,
+						Status:     validation.Status,
+						HashErrors: validation.HashErrors,
+					})
+				}
+			}
+		}
+	}
+
+	var shared []SharedDependency
+	for dep, parents := range usedBy {
+		if len(parents) < 2 {
+			continue
+		}
+		depResults := first[dep]
+		sort.Slice(depResults, func(i, j int) bool {
+			if depResults[i].Device != depResults[j].Device {
+				return depResults[i].Device < depResults[j].Device
+			}
+			return depResults[i].OSVersion < depResults[j].OSVersion
+		})
+		shared = append(shared, SharedDependency{File: dep, UsedBy: parents, Results: depResults})
+	}
+	sort.Slice(shared, func(i, j int) bool { return shared[i].File < shared[j].File })
+	return shared
+}
+
+// collapseShared returns copies of results in which each parent's entry for
+// a shared dependency is reduced to a reference without the hash errors,
+// which are reported once under SharedDependencies instead.
+func collapseShared(results map[string]*api.ComparisonResponse, shared []SharedDependency) map[string]*api.ComparisonResponse {
+	if len(shared) == 0 {
+		return results
+	}
+	isShared := make(map[string]bool, len(shared))
+	for _, dep := range shared {
+		isShared[dep.File] = true
+	}
+
+	collapse := func(in []api.ComparisonResult) []api.ComparisonResult {
+		out := make([]api.ComparisonResult, len(in))
+		for i, result := range in {
+			out[i] = result
+			if result.DependencyResults == nil {
+				continue
+			}
+			deps := make(map[string]*api.ValidationResult, len(result.DependencyResults))
+			for dep, validation := range result.DependencyResults {
+				if isShared[dep] && validation != nil {
+					validation = &api.ValidationResult{Status: validation.Status, Shared: true}
+				}
+				deps[dep] = validation
+			}
+			out[i].DependencyResults = deps
+		}
+		return out
+	}
+
+	collapsed := make(map[string]*api.ComparisonResponse, len(results))
+	for file, response := range results {
+		copied := *response
+		copied.Compatible = collapse(response.Compatible)
+		copied.Incompatible = collapse(response.Incompatible)
+		collapsed[file] = &copied
+	}
+	return collapsed
+}
+
+// CollapseSharedDependencies moves the results of dependencies shared by
+// several root files into report.SharedDependencies.
+func (r *Report) CollapseSharedDependencies() {
+	r.SharedDependencies = SharedDependencies(r.Results)
+	r.Results = collapseShared(r.Results, r.SharedDependencies)
+}
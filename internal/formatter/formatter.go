@@ -0,0 +1,134 @@
+package formatter
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+// PluginPrefix is the executable name prefix for external formatters:
+// --output foo runs qmdverify-format-foo from PATH.
+const PluginPrefix = "qmdverify-format-"
+
+var ErrUnknownFormat = errors.New("unknown output format")
+
+// Report is everything a formatter receives. Plugins get it as JSON on stdin.
+type Report struct {
+	Server        string                             `json:"server"`
+	ServerVersion string                             `json:"server_version,omitempty"`
+	CLIVersion    string                             `json:"cli_version"`
+	NewVersions   []string                           `json:"new_versions,omitempty"`
+	Results       map[string]*api.ComparisonResponse `json:"results"`
+	Timing        *api.Timing                        `json:"timing,omitempty"`
+
+	// Digests maps each checked file to its SHA-256.
+	Digests map[string]string `json:"digests,omitempty"`
+
+	// Locations maps each checked file to its path relative to the working
+	// directory, for formats that point at files in a checkout.
+	Locations map[string]string `json:"locations,omitempty"`
+
+	SharedDependencies []SharedDependency `json:"shared_dependencies,omitempty"`
+
+	// SupportWindows maps each checked file to the oldest and newest OS
+	// version compatible with each device, and the gaps between them.
+	SupportWindows map[string][]SupportWindow `json:"support_windows,omitempty"`
+}
+
+// SupportWindow is the range of OS versions a file supports on one device.
+type SupportWindow struct {
+	Device string   `json:"device"`
+	Oldest string   `json:"oldest_compatible,omitempty"`
+	Newest string   `json:"newest_compatible,omitempty"`
+	Gaps   []string `json:"gaps,omitempty"`
+}
+
+// Files returns the checked files in sorted order.
+func (r *Report) Files() []string {
+	files := make([]string, 0, len(r.Results))
+	for file := range r.Results {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// Responses returns the results in file order.
+func (r *Report) Responses() []*api.ComparisonResponse {
+	files := r.Files()
+	responses := make([]*api.ComparisonResponse, 0, len(files))
+	for _, file := range files {
+		responses = append(responses, r.Results[file])
+	}
+	return responses
+}
+
+type Formatter interface {
+	Format(w io.Writer, report *Report) error
+}
+
+// Func adapts a plain function to the Formatter interface.
+type Func func(w io.Writer, report *Report) error
+
+func (f Func) Format(w io.Writer, report *Report) error {
+	return f(w, report)
+}
+
+var builtins = map[string]Formatter{}
+
+// Register adds a built-in formatter. Built-ins take precedence over plugins
+// of the same name.
+func Register(name string, f Formatter) {
+	builtins[name] = f
+}
+
+// Lookup returns the built-in formatter with the given name, or the
+// qmdverify-format-<name> plugin found on PATH.
+func Lookup(name string) (Formatter, error) {
+	if f, ok := builtins[name]; ok {
+		return f, nil
+	}
+
+	plugin, err := findPlugin(name)
+	if err != nil {
+		return nil, err
+	}
+	return plugin, nil
+}
+
+// Available lists built-in formatter names followed by the plugins on PATH.
+func Available() []string {
+	var names []string
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var plugins []string
+	seen := make(map[string]bool)
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name, ok := strings.CutPrefix(entry.Name(), PluginPrefix)
+			if !ok || name == "" || seen[name] || builtins[name] != nil {
+				continue
+			}
+			if _, err := findPlugin(name); err != nil {
+				continue
+			}
+			seen[name] = true
+			plugins = append(plugins, name)
+		}
+	}
+	sort.Strings(plugins)
+
+	return append(names, plugins...)
+}
@@ -0,0 +1,331 @@
+package formatter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+func writePlugin(t *testing.T, dir, name, script string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("shell plugins are not supported on windows")
+	}
+	path := filepath.Join(dir, PluginPrefix+name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("PATH", dir)
+	writePlugin(t, dir, "csv", "cat")
+
+	Register("builtin-test", Func(func(w io.Writer, report *Report) error { return nil }))
+
+	if _, err := Lookup("builtin-test"); err != nil {
+		t.Errorf("Lookup(builtin) error = %v", err)
+	}
+
+	f, err := Lookup("csv")
+	if err != nil {
+		t.Fatalf("Lookup(plugin) error = %v", err)
+	}
+	if plugin, ok := f.(*Plugin); !ok || plugin.Name != "csv" {
+		t.Errorf("Lookup(plugin) = %#v", f)
+	}
+
+	for _, name := range []string{"missing", "", "../csv"} {
+		if _, err := Lookup(name); !errors.Is(err, ErrUnknownFormat) {
+			t.Errorf("Lookup(%q) error = %v, want ErrUnknownFormat", name, err)
+		}
+	}
+
+	if got := Available(); !reflect.DeepEqual(got, []string{"builtin-test", "csv"}) {
+		t.Errorf("Available() = %v", got)
+	}
+}
+
+func TestPlugin_Format(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	writePlugin(t, dir, "echo", `printf '%s: ' "$QMDVERIFY_FORMAT"; cat`)
+	writePlugin(t, dir, "fail", "exit 3")
+
+	report := &Report{
+		Server:     "https://qmd.example.com",
+		CLIVersion: "v1.0.0",
+		Results: map[string]*api.ComparisonResponse{
+			"a.qmd": {TotalChecked: 1},
+		},
+	}
+
+	f, err := Lookup("echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := f.Format(&buf, report); err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+	body, ok := strings.CutPrefix(buf.String(), "echo: ")
+	if !ok {
+		t.Fatalf("plugin did not get QMDVERIFY_FORMAT: %s", buf.String())
+	}
+	var got Report
+	if err := json.Unmarshal([]byte(body), &got); err != nil {
+		t.Fatalf("plugin stdin was not a JSON report: %v", err)
+	}
+	if got.Server != report.Server || got.Results["a.qmd"].TotalChecked != 1 {
+		t.Errorf("plugin received %+v", got)
+	}
+
+	f, err = Lookup("fail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Format(io.Discard, report); err == nil {
+		t.Error("Format() expected error for failing plugin")
+	}
+}
+
+func TestReport_Responses(t *testing.T) {
+	a := &api.ComparisonResponse{TotalChecked: 1}
+	b := &api.ComparisonResponse{TotalChecked: 2}
+	report := &Report{Results: map[string]*api.ComparisonResponse{"b.qmd": b, "a.qmd": a}}
+
+	got := report.Responses()
+	if len(got) != 2 || got[0] != a || got[1] != b {
+		t.Errorf("Responses() = %v, want [a b]", got)
+	}
+}
+
+func TestNewTemplate(t *testing.T) {
+	report := &Report{
+		Server: "https://qmd.example.com",
+		Results: map[string]*api.ComparisonResponse{
+			"b.qmd": {
+				Incompatible: []api.ComparisonResult{{Device: "rm2", OSVersion: "3.22.0.64"}},
+			},
+			"a.qmd": {
+				Compatible:   []api.ComparisonResult{{Device: "rmpp", OSVersion: "3.22.0.64"}},
+				Incompatible: []api.ComparisonResult{{Device: "rm1", OSVersion: "3.20.0.92"}},
+			},
+		},
+	}
+
+	f, err := NewTemplate(`{{range .Incompatible}}{{$.File}} {{upper .Device}} {{.OSVersion}}{{"\n"}}{{end}}`)
+	if err != nil {
+		t.Fatalf("NewTemplate() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := f.Format(&buf, report); err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+	want := "a.qmd RM1 3.20.0.92\nb.qmd RM2 3.22.0.64\n"
+	if buf.String() != want {
+		t.Errorf("Format() = %q, want %q", buf.String(), want)
+	}
+
+	if _, err := NewTemplate("{{range .Incompatible}"); err == nil {
+		t.Error("NewTemplate() expected parse error")
+	}
+
+	f, err = NewTemplate(`{{.Missing}}`)
+	if err != nil {
+		t.Fatalf("NewTemplate() error = %v", err)
+	}
+	if err := f.Format(io.Discard, report); err == nil {
+		t.Error("Format() expected error for unknown field")
+	}
+}
+
+func TestNewQuery(t *testing.T) {
+	report := &Report{
+		Results: map[string]*api.ComparisonResponse{
+			"b.qmd": {Incompatible: []api.ComparisonResult{{Device: "rm2", OSVersion: "3.22.0.64"}}},
+			"a.qmd": {Incompatible: []api.ComparisonResult{{Device: "rm1", OSVersion: "3.20.0.92"}}},
+		},
+	}
+
+	f, err := NewQuery(".incompatible[].os_version")
+	if err != nil {
+		t.Fatalf("NewQuery() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := f.Format(&buf, report); err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+	if want := "3.20.0.92\n3.22.0.64\n"; buf.String() != want {
+		t.Errorf("Format() = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestCollapseSharedDependencies(t *testing.T) {
+	libErr := &api.ValidationResult{Status: "incompatible", HashErrors: []api.HashError{{HashID: 1, Error: "missing"}}}
+	libOK := &api.ValidationResult{Status: "compatible"}
+	result := func(device string, compatible bool, lib *api.ValidationResult, self string) api.ComparisonResult {
+		return api.ComparisonResult{
+			Device:            device,
+			OSVersion:         "3.22.0.64",
+			Compatible:        compatible,
+			DependencyResults: map[string]*api.ValidationResult{self: {Status: "compatible"}, "lib.qmd": lib},
+		}
+	}
+
+	report := &Report{Results: map[string]*api.ComparisonResponse{
+		"a.qmd": {Compatible: []api.ComparisonResult{result("rmpp", true, libOK, "a.qmd")}, Incompatible: []api.ComparisonResult{result("rm2", false, libErr, "a.qmd")}},
+		"b.qmd": {Compatible: []api.ComparisonResult{result("rmpp", true, libOK, "b.qmd")}, Incompatible: []api.ComparisonResult{result("rm2", false, libErr, "b.qmd")}},
+		"c.qmd": {Compatible: []api.ComparisonResult{{Device: "rmpp", OSVersion: "3.22.0.64"}}},
+	}}
+	original := report.Results["a.qmd"]
+
+	report.CollapseSharedDependencies()
+
+	want := []SharedDependency{{
+		File:   "lib.qmd",
+		UsedBy: []string{"a.qmd", "b.qmd"},
+		Results: []DependencyResult{
+			{Device: "rm2", OSVersion: "3.22.0.64", Status: "incompatible", HashErrors: libErr.HashErrors},
+			{Device: "rmpp", OSVersion: "3.22.0.64", Status: "compatible"},
+		},
+	}}
+	if !reflect.DeepEqual(report.SharedDependencies, want) {
+		t.Errorf("SharedDependencies = %+v, want %+v", report.SharedDependencies, want)
+	}
+
+	ref := report.Results["b.qmd"].Incompatible[0].DependencyResults["lib.qmd"]
+	if !ref.Shared || ref.Status != "incompatible" || len(ref.HashErrors) != 0 {
+		t.Errorf("parent reference = %+v, want a shared reference without hash errors", ref)
+	}
+	if self := report.Results["b.qmd"].Incompatible[0].DependencyResults["b.qmd"]; self.Shared {
+		t.Errorf("root file's own result was collapsed: %+v", self)
+	}
+	if original.Incompatible[0].DependencyResults["lib.qmd"] != libErr {
+		t.Error("CollapseSharedDependencies() modified the caller's results")
+	}
+}
+
+func TestCycloneDX(t *testing.T) {
+	report := &Report{
+		Server:        "https://qmd.example.com",
+		ServerVersion: "v2.0.0",
+		CLIVersion:    "v1.0.0",
+		Results: map[string]*api.ComparisonResponse{
+			"a.qmd": {
+				Compatible:   []api.ComparisonResult{{Hashtable: "3.22.4.2-rmpp", Device: "rmpp", OSVersion: "3.22.4.2", Compatible: true, ValidationMode: "tree"}},
+				Incompatible: []api.ComparisonResult{{Hashtable: "3.20.0.92-rm2", Device: "rm2", OSVersion: "3.20.0.92", ValidationMode: "hashtab"}},
+			},
+			"b.qmd": {},
+		},
+		Digests: map[string]string{"a.qmd": "abc123"},
+	}
+
+	var buf bytes.Buffer
+	if err := CycloneDX(&buf, report); err != nil {
+		t.Fatalf("CycloneDX() error = %v", err)
+	}
+
+	var bom cycloneDXBOM
+	if err := json.Unmarshal(buf.Bytes(), &bom); err != nil {
+		t.Fatalf("CycloneDX() output is not JSON: %v", err)
+	}
+	if bom.BOMFormat != "CycloneDX" || bom.SpecVersion != cycloneDXSpecVersion || len(bom.Components) != 2 {
+		t.Fatalf("CycloneDX() bom = %+v", bom)
+	}
+	if got := bom.Metadata.Tools.Components[0]; got.Name != "qmdverify" || got.Version != "v1.0.0" {
+		t.Errorf("tool = %+v", got)
+	}
+	wantMeta := []cycloneDXProperty{{"qmdverify:server", "https://qmd.example.com"}, {"qmdverify:server_version", "v2.0.0"}}
+	if !reflect.DeepEqual(bom.Metadata.Properties, wantMeta) {
+		t.Errorf("metadata properties = %+v, want %+v", bom.Metadata.Properties, wantMeta)
+	}
+
+	a := bom.Components[0]
+	if a.Name != "a.qmd" || !reflect.DeepEqual(a.Hashes, []cycloneDXHash{{"SHA-256", "abc123"}}) {
+		t.Errorf("component = %+v", a)
+	}
+	wantClaims := []cycloneDXProperty{
+		{"qmdverify:compatible", "rmpp 3.22.4.2"},
+		{"qmdverify:incompatible", "rm2 3.20.0.92"},
+		{"qmdverify:hashtable", "3.20.0.92-rm2"},
+		{"qmdverify:hashtable", "3.22.4.2-rmpp"},
+		{"qmdverify:validation_mode", "hashtab"},
+		{"qmdverify:validation_mode", "tree"},
+	}
+	if !reflect.DeepEqual(a.Properties, wantClaims) {
+		t.Errorf("claims = %+v, want %+v", a.Properties, wantClaims)
+	}
+	if b := bom.Components[1]; b.Hashes != nil || b.Properties != nil {
+		t.Errorf("component without results = %+v", b)
+	}
+}
+
+func TestSARIF(t *testing.T) {
+	report := &Report{
+		CLIVersion: "v1.0.0",
+		Results: map[string]*api.ComparisonResponse{
+			"a.qmd": {
+				Compatible: []api.ComparisonResult{{Device: "rmpp", OSVersion: "3.22.4.2", Compatible: true}},
+				Incompatible: []api.ComparisonResult{{
+					Hashtable:   "3.20.0.92-rm2",
+					Device:      "rm2",
+					OSVersion:   "3.20.0.92",
+					ErrorDetail: "missing 1 hash(es)",
+					DependencyResults: map[string]*api.ValidationResult{
+						"lib.qmd": {Status: "incompatible", HashErrors: []api.HashError{{HashID: 123, Error: "not found"}}},
+					},
+				}},
+			},
+			"b.qmd": {},
+		},
+		Locations: map[string]string{"a.qmd": "overlays/a.qmd"},
+	}
+
+	var buf bytes.Buffer
+	if err := SARIF(&buf, report); err != nil {
+		t.Fatalf("SARIF() error = %v", err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("SARIF() output is not JSON: %v", err)
+	}
+	if log.Version != sarifVersion || len(log.Runs) != 1 {
+		t.Fatalf("SARIF() log = %+v", log)
+	}
+	run := log.Runs[0]
+	if run.Tool.Driver.Name != "qmdverify" || run.Tool.Driver.Version != "v1.0.0" || len(run.Tool.Driver.Rules) != 1 {
+		t.Errorf("driver = %+v", run.Tool.Driver)
+	}
+	if len(run.Results) != 1 {
+		t.Fatalf("SARIF() results = %+v, want one per incompatible version", run.Results)
+	}
+
+	result := run.Results[0]
+	if result.RuleID != sarifRuleID || result.Level != "error" {
+		t.Errorf("result = %+v", result)
+	}
+	if uri := result.Locations[0].PhysicalLocation.ArtifactLocation.URI; uri != "overlays/a.qmd" {
+		t.Errorf("artifact uri = %q, want overlays/a.qmd", uri)
+	}
+	wantText := "a.qmd is incompatible with rm2 3.20.0.92: missing 1 hash(es)\n- lib.qmd: hash 123: not found"
+	if result.Message.Text != wantText {
+		t.Errorf("message = %q, want %q", result.Message.Text, wantText)
+	}
+	if result.Properties["hashtable"] != "3.20.0.92-rm2" {
+		t.Errorf("properties = %v", result.Properties)
+	}
+}
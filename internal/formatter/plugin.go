@@ -0,0 +1,50 @@
+package formatter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Plugin is an external formatter executable. It receives the report as JSON
+// on stdin and writes the formatted output to stdout.
+type Plugin struct {
+	Name string
+	Path string
+}
+
+func findPlugin(name string) (*Plugin, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, name)
+	}
+
+	path, err := exec.LookPath(PluginPrefix + name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, name)
+	}
+
+	return &Plugin{Name: name, Path: path}, nil
+}
+
+func (p *Plugin) Format(w io.Writer, report *Report) error {
+	input, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+
+	cmd := exec.Command(p.Path)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = w
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), "QMDVERIFY_FORMAT="+p.Name)
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("formatter plugin %s failed: %w", p.Path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package formatter
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/query"
+)
+
+type queryFormatter struct {
+	query *query.Query
+}
+
+// NewQuery returns a formatter that applies a jq-style expression to each
+// file's JSON result and prints one value per line.
+func NewQuery(expr string) (Formatter, error) {
+	q, err := query.Parse(expr)
+	if err != nil {
+		return nil, err
+	}
+	return &queryFormatter{query: q}, nil
+}
+
+func (f *queryFormatter) Format(w io.Writer, report *Report) error {
+	for _, file := range report.Files() {
+		values, err := f.query.Run(report.Results[file])
+		if err != nil {
+			return fmt.Errorf("query failed for %s: %w", file, err)
+		}
+		for _, value := range values {
+			line, err := query.FormatValue(value)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
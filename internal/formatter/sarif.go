@@ -0,0 +1,179 @@
+package formatter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+
+	// sarifRuleID is the one rule every incompatible result is reported
+	// under.
+	sarifRuleID = "qmd-incompatible"
+)
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	Name             string       `json:"name"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+	FullDescription  sarifMessage `json:"fullDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID              string            `json:"ruleId"`
+	Level               string            `json:"level"`
+	Message             sarifMessage      `json:"message"`
+	Locations           []sarifLocation   `json:"locations"`
+	PartialFingerprints map[string]string `json:"partialFingerprints"`
+	Properties          map[string]any    `json:"properties,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// SARIF writes the report as a SARIF 2.1.0 log for code scanning, with one
+// error result per incompatible device/OS version of each file. Results are
+// located at the file's path relative to the working directory when known,
+// and carry the server's error detail and each hash error found.
+func SARIF(w io.Writer, report *Report) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "qmdverify",
+			Version:        report.CLIVersion,
+			InformationURI: "https://github.com/rmitchellscott/rm-qmd-verify-cli",
+			Rules: []sarifRule{{
+				ID:               sarifRuleID,
+				Name:             "IncompatibleOSVersion",
+				ShortDescription: sarifMessage{Text: "QMD file is incompatible with a reMarkable OS version"},
+				FullDescription:  sarifMessage{Text: "The QMD file references hashes that are missing from or invalid in the hashtable of this device and OS version, so it will not apply there."},
+			}},
+		}},
+		Results: []sarifResult{},
+	}
+
+	for _, file := range report.Files() {
+		uri := file
+		if location := report.Locations[file]; location != "" {
+			uri = location
+		}
+		uri = filepath.ToSlash(uri)
+
+		for _, result := range report.Results[file].Incompatible {
+			run.Results = append(run.Results, sarifResult{
+				RuleID:  sarifRuleID,
+				Level:   "error",
+				Message: sarifMessage{Text: sarifText(file, result)},
+				Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: uri},
+					Region:           sarifRegion{StartLine: 1},
+				}}},
+				PartialFingerprints: map[string]string{
+					"qmdverify/v1": file + ":" + result.Device + ":" + result.OSVersion,
+				},
+				Properties: sarifProperties(result),
+			})
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{Schema: sarifSchema, Version: sarifVersion, Runs: []sarifRun{run}})
+}
+
+// sarifText describes an incompatible result, listing hash errors by the
+// file they were found in.
+func sarifText(file string, result api.ComparisonResult) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s is incompatible with %s %s", file, result.Device, result.OSVersion)
+	if result.ErrorDetail != "" {
+		fmt.Fprintf(&b, ": %s", result.ErrorDetail)
+	}
+	for _, line := range hashErrors(result) {
+		fmt.Fprintf(&b, "\n- %s", line)
+	}
+	return b.String()
+}
+
+func sarifProperties(result api.ComparisonResult) map[string]any {
+	properties := map[string]any{
+		"device":     result.Device,
+		"os_version": result.OSVersion,
+	}
+	if result.Hashtable != "" {
+		properties["hashtable"] = result.Hashtable
+	}
+	if errs := hashErrors(result); len(errs) > 0 {
+		properties["hash_errors"] = errs
+	}
+	return properties
+}
+
+// hashErrors lists the hash errors of a result as "<file>: hash <id>:
+// <error>", sorted by file.
+func hashErrors(result api.ComparisonResult) []string {
+	files := make([]string, 0, len(result.DependencyResults))
+	for file := range result.DependencyResults {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	var lines []string
+	for _, file := range files {
+		dep := result.DependencyResults[file]
+		if dep == nil {
+			continue
+		}
+		for _, e := range dep.HashErrors {
+			lines = append(lines, fmt.Sprintf("%s: hash %d: %s", file, e.HashID, e.Error))
+		}
+	}
+	return lines
+}
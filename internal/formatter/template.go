@@ -0,0 +1,51 @@
+package formatter
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+// TemplateData is the dot for each execution of a user template. The
+// embedded response exposes .Compatible, .Incompatible and .TotalChecked.
+type TemplateData struct {
+	File   string
+	Server string
+	*api.ComparisonResponse
+}
+
+type templateFormatter struct {
+	tmpl *template.Template
+}
+
+var templateFuncs = template.FuncMap{
+	"join":  strings.Join,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// NewTemplate parses a text/template that is executed once per file.
+func NewTemplate(text string) (Formatter, error) {
+	tmpl, err := template.New("output").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid output template: %w", err)
+	}
+	return &templateFormatter{tmpl: tmpl}, nil
+}
+
+func (f *templateFormatter) Format(w io.Writer, report *Report) error {
+	for _, file := range report.Files() {
+		data := TemplateData{
+			File:               file,
+			Server:             report.Server,
+			ComparisonResponse: report.Results[file],
+		}
+		if err := f.tmpl.Execute(w, data); err != nil {
+			return fmt.Errorf("failed to render output template: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package hashtabfile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Encoding is a hashtab file layout. Canonical is what qmldiff, the server
+// and the rest of this tool read; little-endian and v2 are produced by some
+// firmware dump tools, and compressed is this tool's compact hashlist.
+type Encoding string
+
+const (
+	// Canonical entries are a big-endian hash and string length followed
+	// by the string.
+	Canonical Encoding = "canonical"
+	// LittleEndian entries have the canonical layout with little-endian
+	// integers.
+	LittleEndian Encoding = "little-endian"
+	// V2 files start with the magic "HTB2" and a big-endian uint32 entry
+	// count, followed by canonical entries.
+	V2 Encoding = "v2"
+	// Compressed hashlists start with the magic "HLZ1" and a uvarint hash
+	// count, followed by the sorted hashes as uvarint deltas. Strings are
+	// not stored.
+	Compressed Encoding = "compressed"
+)
+
+var (
+	v2Magic         = []byte("HTB2")
+	compressedMagic = []byte("HLZ1")
+)
+
+// Encodings lists the supported encodings.
+var Encodings = []Encoding{Canonical, LittleEndian, V2, Compressed}
+
+// ParseEncoding validates an encoding name.
+func ParseEncoding(name string) (Encoding, error) {
+	for _, enc := range Encodings {
+		if string(enc) == name {
+			return enc, nil
+		}
+	}
+	return "", fmt.Errorf("unknown hashtab encoding %q (expected canonical, little-endian, v2 or compressed)", name)
+}
+
+// Detect guesses the encoding of data. A V2 or compressed magic wins;
+// otherwise the file is canonical when it decodes as such, and little-endian
+// when only that decodes.
+func Detect(data []byte) (Encoding, error) {
+	if bytes.HasPrefix(data, v2Magic) {
+		return V2, nil
+	}
+	if bytes.HasPrefix(data, compressedMagic) {
+		return Compressed, nil
+	}
+	if _, err := (decoder{order: binary.BigEndian}).read(bytes.NewReader(data)); err == nil {
+		return Canonical, nil
+	}
+	if _, err := (decoder{order: binary.LittleEndian}).read(bytes.NewReader(data)); err == nil {
+		return LittleEndian, nil
+	}
+	return "", fmt.Errorf("not a hashtab in any supported encoding")
+}
+
+// Decode reads entries encoded as enc. An empty enc detects the encoding.
+func Decode(data []byte, enc Encoding) ([]Entry, Encoding, error) {
+	if enc == "" {
+		detected, err := Detect(data)
+		if err != nil {
+			return nil, "", err
+		}
+		enc = detected
+	}
+
+	var entries []Entry
+	var err error
+	switch enc {
+	case Canonical:
+		entries, err = decoder{order: binary.BigEndian}.read(bytes.NewReader(data))
+	case LittleEndian:
+		entries, err = decoder{order: binary.LittleEndian}.read(bytes.NewReader(data))
+	case V2:
+		entries, err = decodeV2(data)
+	case Compressed:
+		entries, err = decodeCompressed(data)
+	default:
+		return nil, "", fmt.Errorf("unknown hashtab encoding %q", enc)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid %s hashtab: %w", enc, err)
+	}
+	return entries, enc, nil
+}
+
+func decodeV2(data []byte) ([]Entry, error) {
+	header := len(v2Magic) + 4
+	if len(data) < header || !bytes.HasPrefix(data, v2Magic) {
+		return nil, fmt.Errorf("missing v2 header")
+	}
+	count := binary.BigEndian.Uint32(data[len(v2Magic):header])
+
+	entries, err := decoder{order: binary.BigEndian, base: int64(header)}.read(bytes.NewReader(data[header:]))
+	if err != nil {
+		return nil, err
+	}
+	if uint32(len(entries)) != count {
+		return nil, fmt.Errorf("header declares %d entries, file has %d", count, len(entries))
+	}
+	return entries, nil
+}
+
+func decodeCompressed(data []byte) ([]Entry, error) {
+	if !bytes.HasPrefix(data, compressedMagic) {
+		return nil, fmt.Errorf("missing compressed hashlist header")
+	}
+	pos := len(compressedMagic)
+	count, n := binary.Uvarint(data[pos:])
+	if n <= 0 {
+		return nil, fmt.Errorf("invalid hash count at byte offset %d", pos)
+	}
+	pos += n
+	if count > uint64(len(data)-pos) {
+		return nil, fmt.Errorf("header declares %d hashes, file has at most %d", count, len(data)-pos)
+	}
+
+	entries := make([]Entry, 0, count)
+	var hash uint64
+	for i := uint64(0); i < count; i++ {
+		delta, n := binary.Uvarint(data[pos:])
+		if n <= 0 {
+			return nil, &FormatError{Index: int(i), Offset: int64(pos), Reason: "invalid delta"}
+		}
+		if (i > 0 && delta == 0) || hash+delta < hash {
+			return nil, &FormatError{Index: int(i), Offset: int64(pos), Reason: "hashes are not strictly increasing"}
+		}
+		hash += delta
+		pos += n
+		entries = append(entries, Entry{Hash: hash})
+	}
+	if pos != len(data) {
+		return nil, fmt.Errorf("%d trailing bytes after %d hashes", len(data)-pos, count)
+	}
+	return entries, nil
+}
+
+// encodeCompressed writes the distinct hashes of entries, which must have no
+// strings.
+func encodeCompressed(w io.Writer, entries []Entry) error {
+	for _, entry := range entries {
+		if entry.String != "" {
+			return fmt.Errorf("the compressed encoding only stores hashes; create a hashlist first")
+		}
+	}
+	normalized, err := Normalize(entries)
+	if err != nil {
+		return err
+	}
+
+	buf := binary.AppendUvarint(append([]byte{}, compressedMagic...), uint64(len(normalized)))
+	var previous uint64
+	for _, entry := range normalized {
+		buf = binary.AppendUvarint(buf, entry.Hash-previous)
+		previous = entry.Hash
+	}
+	_, err = w.Write(buf)
+	return err
+}
+
+// Encode writes entries to w as enc.
+func Encode(w io.Writer, entries []Entry, enc Encoding) error {
+	switch enc {
+	case Canonical:
+		return writeEntries(w, entries, binary.BigEndian)
+	case LittleEndian:
+		return writeEntries(w, entries, binary.LittleEndian)
+	case V2:
+		header := binary.BigEndian.AppendUint32(append([]byte{}, v2Magic...), uint32(len(entries)))
+		if _, err := w.Write(header); err != nil {
+			return err
+		}
+		return writeEntries(w, entries, binary.BigEndian)
+	case Compressed:
+		return encodeCompressed(w, entries)
+	default:
+		return fmt.Errorf("unknown hashtab encoding %q", enc)
+	}
+}
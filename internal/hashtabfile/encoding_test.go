@@ -0,0 +1,106 @@
+package hashtabfile
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	entries := []Entry{{Hash: 1, String: "id"}, {Hash: VersionHash, String: "3.22.4.2"}, {Hash: 3}}
+
+	for _, enc := range []Encoding{Canonical, LittleEndian, V2} {
+		t.Run(string(enc), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Encode(&buf, entries, enc); err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+
+			got, detected, err := Decode(buf.Bytes(), "")
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if detected != enc {
+				t.Errorf("Decode() detected %s, want %s", detected, enc)
+			}
+			if !reflect.DeepEqual(got, entries) {
+				t.Errorf("Decode() = %+v, want %+v", got, entries)
+			}
+		})
+	}
+}
+
+func TestDecode_Errors(t *testing.T) {
+	var v2 bytes.Buffer
+	if err := Encode(&v2, []Entry{{Hash: 1, String: "id"}}, V2); err != nil {
+		t.Fatal(err)
+	}
+	miscounted := append([]byte{}, v2.Bytes()...)
+	miscounted[7] = 2
+
+	tests := []struct {
+		name string
+		data []byte
+		enc  Encoding
+	}{
+		{name: "garbage", data: []byte{1, 2, 3}},
+		{name: "v2 count mismatch", data: miscounted},
+		{name: "v2 forced on canonical", data: []byte{}, enc: V2},
+		{name: "unknown encoding", data: []byte{}, enc: "v9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := Decode(tt.data, tt.enc); err == nil {
+				t.Error("Decode() expected error")
+			}
+		})
+	}
+}
+
+func TestCompressed(t *testing.T) {
+	entries := []Entry{{Hash: VersionHash}, {Hash: 300}, {Hash: 1}, {Hash: 300}}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, entries, Compressed); err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	var canonical bytes.Buffer
+	Write(&canonical, entries)
+	if buf.Len() >= canonical.Len()/2 {
+		t.Errorf("compressed size %d, canonical %d", buf.Len(), canonical.Len())
+	}
+
+	got, detected, err := Decode(buf.Bytes(), "")
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	want := []Entry{{Hash: 1}, {Hash: 300}, {Hash: VersionHash}}
+	if detected != Compressed || !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode() = %+v, %s, want %+v", got, detected, want)
+	}
+
+	if err := Encode(&bytes.Buffer{}, []Entry{{Hash: 1, String: "id"}}, Compressed); err == nil {
+		t.Error("Encode() expected error for entries with strings")
+	}
+
+	data := buf.Bytes()
+	for name, bad := range map[string][]byte{
+		"truncated":     data[:len(data)-1],
+		"trailing":      append(append([]byte{}, data...), 0),
+		"count too big": append(append([]byte{}, compressedMagic...), 100, 1),
+		"zero delta":    append(append([]byte{}, compressedMagic...), 2, 1, 0),
+	} {
+		if _, _, err := Decode(bad, Compressed); err == nil {
+			t.Errorf("Decode(%s) expected error", name)
+		}
+	}
+}
+
+func TestParseEncoding(t *testing.T) {
+	if enc, err := ParseEncoding("little-endian"); err != nil || enc != LittleEndian {
+		t.Errorf("ParseEncoding(little-endian) = %q, %v", enc, err)
+	}
+	if _, err := ParseEncoding("utf16"); err == nil {
+		t.Error("ParseEncoding(utf16) expected error")
+	}
+}
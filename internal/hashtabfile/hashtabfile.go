@@ -0,0 +1,220 @@
+// Package hashtabfile reads and writes hashtab and hashlist files entry by
+// entry. Unlike hashtab.Load it keeps the entries in file order, including
+// duplicates and zero hashes, so files can be audited and rewritten.
+package hashtabfile
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"unicode/utf8"
+
+	"github.com/rmitchellscott/rm-qmd-verify/pkg/hashtab"
+)
+
+// VersionHash is the hash of the entry whose string is the firmware version
+// the hashtab was generated from.
+const VersionHash uint64 = 17607111715072197239
+
+// entryHeaderSize is the 8-byte hash and 4-byte string length before each
+// entry's string.
+const entryHeaderSize = 12
+
+// maxStringLength guards against reading a corrupt length as a huge
+// allocation.
+const maxStringLength = 1 << 24
+
+// Entry is one hash and its string. Hashlist entries have no string.
+type Entry struct {
+	Hash   uint64
+	String string
+}
+
+// FormatError locates a malformed record. Index counts records from 0 and
+// Offset is the byte offset of the record's start in the file.
+type FormatError struct {
+	Index  int
+	Offset int64
+	Reason string
+}
+
+func (e *FormatError) Error() string {
+	return fmt.Sprintf("record %d at byte offset %d (0x%x): %s", e.Index, e.Offset, e.Offset, e.Reason)
+}
+
+// Read decodes the entries of a big-endian hashtab or hashlist.
+func Read(r io.Reader) ([]Entry, error) {
+	return decoder{order: binary.BigEndian}.read(r)
+}
+
+// ReadStrict is Read with the checks of strict mode: besides malformed
+// records, it rejects the reserved zero hash and strings that are not valid
+// UTF-8, which readers would otherwise accept silently.
+func ReadStrict(r io.Reader) ([]Entry, error) {
+	return decoder{order: binary.BigEndian, strict: true}.read(r)
+}
+
+// decoder reads entries in one byte order. base is the offset of the first
+// entry in the file, for headers that precede it.
+type decoder struct {
+	order  binary.ByteOrder
+	base   int64
+	strict bool
+}
+
+func (d decoder) read(r io.Reader) ([]Entry, error) {
+	br := bufio.NewReader(r)
+	var entries []Entry
+	var header [entryHeaderSize]byte
+	offset := d.base
+	for {
+		fail := func(format string, args ...any) error {
+			return &FormatError{Index: len(entries), Offset: offset, Reason: fmt.Sprintf(format, args...)}
+		}
+
+		n, err := io.ReadFull(br, header[:])
+		switch {
+		case err == io.EOF:
+			return entries, nil
+		case errors.Is(err, io.ErrUnexpectedEOF) && n < 8:
+			return nil, fail("truncated hash: %d of 8 bytes", n)
+		case errors.Is(err, io.ErrUnexpectedEOF):
+			return nil, fail("bad length prefix: %d of 4 bytes", n-8)
+		case err != nil:
+			return nil, fail("%v", err)
+		}
+
+		hash := d.order.Uint64(header[:8])
+		length := d.order.Uint32(header[8:])
+		if length > maxStringLength {
+			return nil, fail("overlong record: string length %d exceeds the maximum of %d", length, maxStringLength)
+		}
+		data := make([]byte, length)
+		if n, err := io.ReadFull(br, data); err != nil {
+			return nil, fail("truncated string: %d of %d bytes", n, length)
+		}
+		if d.strict && hash == 0 {
+			return nil, fail("hash 0 is reserved")
+		}
+		if d.strict && !utf8.Valid(data) {
+			return nil, fail("string is not valid UTF-8")
+		}
+
+		entries = append(entries, Entry{Hash: hash, String: string(data)})
+		offset += entryHeaderSize + int64(length)
+	}
+}
+
+// ReadFile reads the entries of the hashtab at path. Compressed hashlists
+// are detected and decoded transparently.
+func ReadFile(path string) ([]Entry, error) {
+	return readFile(path, Read)
+}
+
+// ReadFileStrict reads the hashtab at path in strict mode.
+func ReadFileStrict(path string) ([]Entry, error) {
+	return readFile(path, ReadStrict)
+}
+
+func readFile(path string, read func(io.Reader) ([]Entry, error)) ([]Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open hashtab file: %w", err)
+	}
+
+	var entries []Entry
+	if bytes.HasPrefix(data, compressedMagic) {
+		entries, err = decodeCompressed(data)
+	} else {
+		entries, err = read(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// Load is hashtab.Load with support for compressed hashlists.
+func Load(path string) (*hashtab.Hashtab, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open hashtab file: %w", err)
+	}
+	magic := make([]byte, len(compressedMagic))
+	_, err = io.ReadFull(f, magic)
+	f.Close()
+	if err != nil || !bytes.Equal(magic, compressedMagic) {
+		return hashtab.Load(path)
+	}
+
+	entries, err := ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	name := filepath.Base(path)
+	osVersion, device := hashtab.ParseVersion(name)
+	ht := &hashtab.Hashtab{Name: name, Path: path, OSVersion: osVersion, Device: device, Entries: make(map[uint64]string, len(entries))}
+	for _, entry := range entries {
+		if entry.Hash != 0 {
+			ht.Entries[entry.Hash] = ""
+		}
+	}
+	return ht, nil
+}
+
+// Write encodes entries in the canonical big-endian format.
+func Write(w io.Writer, entries []Entry) error {
+	return writeEntries(w, entries, binary.BigEndian)
+}
+
+func writeEntries(w io.Writer, entries []Entry, order binary.ByteOrder) error {
+	bw := bufio.NewWriter(w)
+	var header [entryHeaderSize]byte
+	for _, entry := range entries {
+		order.PutUint64(header[:8], entry.Hash)
+		order.PutUint32(header[8:], uint32(len(entry.String)))
+		if _, err := bw.Write(header[:]); err != nil {
+			return err
+		}
+		if _, err := bw.WriteString(entry.String); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteFile writes entries to path in the canonical format, replacing it.
+func WriteFile(path string, entries []Entry) error {
+	return EncodeFile(path, entries, Canonical)
+}
+
+// EncodeFile writes entries to path as enc. The file is replaced only once
+// it has been written in full, so path may also be the input.
+func EncodeFile(path string, entries []Entry, enc Encoding) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := Encode(tmp, entries, enc); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package hashtabfile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadWrite(t *testing.T) {
+	entries := []Entry{{Hash: 1, String: "id"}, {Hash: 2}, {Hash: 1, String: "id"}, {Hash: VersionHash, String: "3.22.4.2"}}
+
+	path := filepath.Join(t.TempDir(), "3.22.4.2-rmpp")
+	if err := WriteFile(path, entries); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	got, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, entries) {
+		t.Errorf("ReadFile() = %+v, want %+v", got, entries)
+	}
+}
+
+func TestRead_Errors(t *testing.T) {
+	var valid bytes.Buffer
+	if err := Write(&valid, []Entry{{Hash: 1, String: "id"}, {Hash: 2, String: "hello"}}); err != nil {
+		t.Fatal(err)
+	}
+	data := valid.Bytes()
+	second := int64(entryHeaderSize + 2)
+
+	huge := append([]byte{}, data[:second]...)
+	huge = binary.BigEndian.AppendUint64(huge, 3)
+	huge = binary.BigEndian.AppendUint32(huge, maxStringLength+1)
+
+	var zero, badUTF8 bytes.Buffer
+	Write(&zero, []Entry{{Hash: 1, String: "id"}, {Hash: 0, String: "x"}})
+	Write(&badUTF8, []Entry{{Hash: 1, String: "\xff"}})
+
+	tests := []struct {
+		name       string
+		data       []byte
+		strict     bool
+		wantIndex  int
+		wantOffset int64
+		wantReason string
+	}{
+		{name: "truncated hash", data: data[:second+5], wantIndex: 1, wantOffset: second, wantReason: "truncated hash: 5 of 8 bytes"},
+		{name: "bad length prefix", data: data[:second+10], wantIndex: 1, wantOffset: second, wantReason: "bad length prefix: 2 of 4 bytes"},
+		{name: "truncated string", data: data[:len(data)-1], wantIndex: 1, wantOffset: second, wantReason: "truncated string: 4 of 5 bytes"},
+		{name: "overlong record", data: huge, wantIndex: 1, wantOffset: second, wantReason: "overlong record"},
+		{name: "strict zero hash", data: zero.Bytes(), strict: true, wantIndex: 1, wantOffset: second, wantReason: "hash 0 is reserved"},
+		{name: "strict invalid UTF-8", data: badUTF8.Bytes(), strict: true, wantReason: "not valid UTF-8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			read := Read
+			if tt.strict {
+				read = ReadStrict
+			}
+			_, err := read(bytes.NewReader(tt.data))
+			var formatErr *FormatError
+			if !errors.As(err, &formatErr) {
+				t.Fatalf("Read() error = %v, want a FormatError", err)
+			}
+			if formatErr.Index != tt.wantIndex || formatErr.Offset != tt.wantOffset || !strings.Contains(formatErr.Reason, tt.wantReason) {
+				t.Errorf("Read() error = %+v, want index %d, offset %d, reason %q", formatErr, tt.wantIndex, tt.wantOffset, tt.wantReason)
+			}
+		})
+	}
+
+	for _, lenient := range [][]byte{zero.Bytes(), badUTF8.Bytes()} {
+		if _, err := Read(bytes.NewReader(lenient)); err != nil {
+			t.Errorf("Read() error = %v, want strict-only checks skipped", err)
+		}
+	}
+
+	if entries, err := Read(bytes.NewReader(nil)); err != nil || len(entries) != 0 {
+		t.Errorf("Read(empty) = %v, %v", entries, err)
+	}
+}
+
+func TestDecode_V2Offset(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Encode(&buf, []Entry{{Hash: 1, String: "id"}}, V2); err != nil {
+		t.Fatal(err)
+	}
+	data := append(buf.Bytes(), 0, 0, 0)
+	binary.BigEndian.PutUint32(data[4:8], 2)
+
+	_, _, err := Decode(data, V2)
+	var formatErr *FormatError
+	if !errors.As(err, &formatErr) || formatErr.Offset != int64(8+entryHeaderSize+2) {
+		t.Errorf("Decode() error = %v, want a FormatError at the file offset", err)
+	}
+}
@@ -0,0 +1,30 @@
+package hashtabfile
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Normalize returns entries sorted by hash with duplicates removed, so that
+// hashtabs built from the same data are byte-identical. Zero hashes, which
+// readers skip, are dropped. A hash with two different strings is an error
+// since either choice would silently change the table.
+func Normalize(entries []Entry) ([]Entry, error) {
+	strs := make(map[uint64]string, len(entries))
+	for _, entry := range entries {
+		if entry.Hash == 0 {
+			continue
+		}
+		if previous, ok := strs[entry.Hash]; ok && previous != entry.String {
+			return nil, fmt.Errorf("hash %d has conflicting strings %q and %q", entry.Hash, previous, entry.String)
+		}
+		strs[entry.Hash] = entry.String
+	}
+
+	normalized := make([]Entry, 0, len(strs))
+	for hash, str := range strs {
+		normalized = append(normalized, Entry{Hash: hash, String: str})
+	}
+	sort.Slice(normalized, func(i, j int) bool { return normalized[i].Hash < normalized[j].Hash })
+	return normalized, nil
+}
@@ -0,0 +1,54 @@
+package hashtabfile
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []Entry
+		want    []Entry
+		wantErr bool
+	}{
+		{
+			name:    "sorts and deduplicates",
+			entries: []Entry{{Hash: VersionHash, String: "3.22.4.2"}, {Hash: 3, String: "x"}, {Hash: 1, String: "id"}, {Hash: 3, String: "x"}, {Hash: 0, String: "junk"}},
+			want:    []Entry{{Hash: 1, String: "id"}, {Hash: 3, String: "x"}, {Hash: VersionHash, String: "3.22.4.2"}},
+		},
+		{
+			name:    "hashlist",
+			entries: []Entry{{Hash: 2}, {Hash: 1}},
+			want:    []Entry{{Hash: 1}, {Hash: 2}},
+		},
+		{
+			name:    "empty",
+			entries: nil,
+			want:    []Entry{},
+		},
+		{
+			name:    "conflicting strings",
+			entries: []Entry{{Hash: 3, String: "x"}, {Hash: 3, String: "y"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Normalize(tt.entries)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Normalize() expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Normalize() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Normalize() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
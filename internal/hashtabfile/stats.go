@@ -0,0 +1,122 @@
+package hashtabfile
+
+import "sort"
+
+// Stats summarizes a hashtab file.
+type Stats struct {
+	Entries int
+	Unique  int
+	// Duplicates counts hashes that occur more than once; Conflicts counts
+	// those whose occurrences carry different strings.
+	Duplicates int
+	Conflicts  int
+	// Empty counts entries without a string, as in a hashlist.
+	Empty int
+	// String lengths cover entries with a string, except the version entry.
+	MinLength int
+	MaxLength int
+	AvgLength float64
+
+	HasVersion bool
+	Version    string
+
+	Size        int64
+	StringBytes int64
+}
+
+// IsHashlist reports whether no entry carries a string.
+func (s Stats) IsHashlist() bool {
+	return s.Empty == s.Entries
+}
+
+// ComputeStats summarizes entries read from a file of size bytes.
+func ComputeStats(entries []Entry, size int64) Stats {
+	stats := Stats{Entries: len(entries), Size: size}
+
+	seen := make(map[uint64]string, len(entries))
+	counted := make(map[uint64]bool)
+	conflicting := make(map[uint64]bool)
+	var total, lengths int
+	for _, entry := range entries {
+		stats.StringBytes += int64(len(entry.String))
+		if previous, ok := seen[entry.Hash]; ok {
+			if !counted[entry.Hash] {
+				counted[entry.Hash] = true
+				stats.Duplicates++
+			}
+			if previous != entry.String && !conflicting[entry.Hash] {
+				conflicting[entry.Hash] = true
+				stats.Conflicts++
+			}
+		} else {
+			seen[entry.Hash] = entry.String
+		}
+
+		if entry.String == "" {
+			stats.Empty++
+		}
+		if entry.Hash == VersionHash {
+			stats.HasVersion = true
+			stats.Version = entry.String
+			continue
+		}
+		if entry.String == "" {
+			continue
+		}
+
+		n := len(entry.String)
+		if lengths == 0 || n < stats.MinLength {
+			stats.MinLength = n
+		}
+		if n > stats.MaxLength {
+			stats.MaxLength = n
+		}
+		total += n
+		lengths++
+	}
+	stats.Unique = len(seen)
+	if lengths > 0 {
+		stats.AvgLength = float64(total) / float64(lengths)
+	}
+	return stats
+}
+
+// Longest returns up to n distinct entries with the longest strings, longest
+// first and then by hash. The version entry is left out.
+func Longest(entries []Entry, n int) []Entry {
+	seen := make(map[Entry]bool)
+	var candidates []Entry
+	for _, entry := range entries {
+		if entry.String == "" || entry.Hash == VersionHash || seen[entry] {
+			continue
+		}
+		seen[entry] = true
+		candidates = append(candidates, entry)
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if len(a.String) != len(b.String) {
+			return len(a.String) > len(b.String)
+		}
+		return a.Hash < b.Hash
+	})
+	return candidates[:min(n, len(candidates))]
+}
+
+// DuplicateHashes returns the hashes that occur more than once, sorted.
+func DuplicateHashes(entries []Entry) []uint64 {
+	counts := make(map[uint64]int, len(entries))
+	for _, entry := range entries {
+		counts[entry.Hash]++
+	}
+
+	var hashes []uint64
+	for hash, n := range counts {
+		if n > 1 {
+			hashes = append(hashes, hash)
+		}
+	}
+	sort.Slice(hashes, func(i, j int) bool { return hashes[i] < hashes[j] })
+	return hashes
+}
@@ -0,0 +1,69 @@
+package hashtabfile
+
+import "testing"
+
+func TestComputeStats(t *testing.T) {
+	entries := []Entry{
+		{Hash: 1, String: "id"},
+		{Hash: 2, String: "width"},
+		{Hash: 1, String: "id"},
+		{Hash: 3, String: "x"},
+		{Hash: 3, String: "y"},
+		{Hash: 4},
+		{Hash: VersionHash, String: "3.22.4.2"},
+	}
+
+	got := ComputeStats(entries, 200)
+	want := Stats{
+		Entries: 7, Unique: 5, Duplicates: 2, Conflicts: 1, Empty: 1,
+		MinLength: 1, MaxLength: 5, AvgLength: 2.2,
+		HasVersion: true, Version: "3.22.4.2",
+		Size: 200, StringBytes: 19,
+	}
+	if got != want {
+		t.Errorf("ComputeStats() = %+v, want %+v", got, want)
+	}
+	if got.IsHashlist() {
+		t.Error("IsHashlist() = true for a hashtab")
+	}
+
+	hashlist := ComputeStats([]Entry{{Hash: 1}, {Hash: VersionHash}}, 24)
+	if !hashlist.IsHashlist() || !hashlist.HasVersion || hashlist.MaxLength != 0 {
+		t.Errorf("ComputeStats(hashlist) = %+v", hashlist)
+	}
+}
+
+func TestLongest(t *testing.T) {
+	entries := []Entry{
+		{Hash: 1, String: "id"},
+		{Hash: 5, String: "height"},
+		{Hash: 2, String: "width"},
+		{Hash: 5, String: "height"},
+		{Hash: 3},
+		{Hash: 4, String: "anchors"},
+		{Hash: VersionHash, String: "3.22.4.2"},
+		{Hash: 0, String: "depth"},
+	}
+
+	got := Longest(entries, 3)
+	want := []Entry{{Hash: 4, String: "anchors"}, {Hash: 5, String: "height"}, {Hash: 0, String: "depth"}}
+	if len(got) != len(want) {
+		t.Fatalf("Longest() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Longest()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+	if all := Longest(entries, 100); len(all) != 5 {
+		t.Errorf("Longest(100) returned %d entries, want 5", len(all))
+	}
+}
+
+func TestDuplicateHashes(t *testing.T) {
+	entries := []Entry{{Hash: 9}, {Hash: 1, String: "a"}, {Hash: 9, String: "b"}, {Hash: 1, String: "a"}, {Hash: 2}}
+	got := DuplicateHashes(entries)
+	if len(got) != 2 || got[0] != 1 || got[1] != 9 {
+		t.Errorf("DuplicateHashes() = %v, want [1 9]", got)
+	}
+}
@@ -0,0 +1,128 @@
+package hashtabfile
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/rmitchellscott/rm-qmd-verify/pkg/hashtab"
+)
+
+// Text formats for exporting entries.
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// textEntry is an entry in JSON exports.
+type textEntry struct {
+	Hash   uint64 `json:"hash"`
+	String string `json:"string"`
+}
+
+// importEntry is an entry in JSON imports, where the hash may be left out.
+type importEntry struct {
+	Hash   *uint64 `json:"hash"`
+	String string  `json:"string"`
+}
+
+// WriteCSV writes entries as hash,string rows after a header row.
+func WriteCSV(w io.Writer, entries []Entry) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"hash", "string"}); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := cw.Write([]string{strconv.FormatUint(entry.Hash, 10), entry.String}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON writes entries as an array of {"hash", "string"} objects.
+func WriteJSON(w io.Writer, entries []Entry) error {
+	out := make([]textEntry, len(entries))
+	for i, entry := range entries {
+		out[i] = textEntry{Hash: entry.Hash, String: entry.String}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// ReadCSV reads hash,string rows as written by WriteCSV. The header row is
+// optional, and a row with an empty hash is hashed from its string.
+func ReadCSV(r io.Reader) ([]Entry, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = 2
+
+	var entries []Entry
+	for line := 1; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if line == 1 && record[0] == "hash" && record[1] == "string" {
+			continue
+		}
+
+		entry, err := newEntry(record[0], record[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		entries = append(entries, entry)
+	}
+}
+
+// ReadJSON reads an array of {"hash", "string"} objects as written by
+// WriteJSON. An entry without a hash is hashed from its string.
+func ReadJSON(r io.Reader) ([]Entry, error) {
+	var in []importEntry
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&in); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	entries := make([]Entry, 0, len(in))
+	for i, e := range in {
+		hash := ""
+		if e.Hash != nil {
+			hash = strconv.FormatUint(*e.Hash, 10)
+		}
+		entry, err := newEntry(hash, e.String)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i+1, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func newEntry(hash, str string) (Entry, error) {
+	if len(str) > maxStringLength {
+		return Entry{}, fmt.Errorf("string is too long")
+	}
+	if hash == "" {
+		if str == "" {
+			return Entry{}, fmt.Errorf("needs a hash or a string")
+		}
+		return Entry{Hash: hashtab.DJB2Hash(str), String: str}, nil
+	}
+
+	h, err := strconv.ParseUint(hash, 10, 64)
+	if err != nil {
+		return Entry{}, fmt.Errorf("invalid hash %q", hash)
+	}
+	if h == 0 {
+		return Entry{}, fmt.Errorf("hash 0 is reserved")
+	}
+	return Entry{Hash: h, String: str}, nil
+}
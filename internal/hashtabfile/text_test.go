@@ -0,0 +1,81 @@
+package hashtabfile
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rmitchellscott/rm-qmd-verify/pkg/hashtab"
+)
+
+func TestWriteText(t *testing.T) {
+	entries := []Entry{{Hash: 1, String: "id"}, {Hash: VersionHash, String: "3.22.4.2"}, {Hash: 3, String: `say "hi", bye`}, {Hash: 4}}
+
+	var csv bytes.Buffer
+	if err := WriteCSV(&csv, entries); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+	wantCSV := "hash,string\n1,id\n17607111715072197239,3.22.4.2\n3,\"say \"\"hi\"\", bye\"\n4,\n"
+	if csv.String() != wantCSV {
+		t.Errorf("WriteCSV() = %q, want %q", csv.String(), wantCSV)
+	}
+
+	var js bytes.Buffer
+	if err := WriteJSON(&js, entries[:2]); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	wantJSON := "[\n  {\n    \"hash\": 1,\n    \"string\": \"id\"\n  },\n  {\n    \"hash\": 17607111715072197239,\n    \"string\": \"3.22.4.2\"\n  }\n]\n"
+	if js.String() != wantJSON {
+		t.Errorf("WriteJSON() = %q, want %q", js.String(), wantJSON)
+	}
+}
+
+func TestReadText(t *testing.T) {
+	entries := []Entry{{Hash: 1, String: "id"}, {Hash: VersionHash, String: "3.22.4.2"}, {Hash: 3, String: `say "hi", bye`}, {Hash: 4}}
+
+	var csvData, jsonData bytes.Buffer
+	if err := WriteCSV(&csvData, entries); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteJSON(&jsonData, entries); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, read := range map[string]func(io.Reader) ([]Entry, error){"csv": ReadCSV, "json": ReadJSON} {
+		data := csvData.Bytes()
+		if name == "json" {
+			data = jsonData.Bytes()
+		}
+		got, err := read(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("Read%s() error = %v", name, err)
+		}
+		if !reflect.DeepEqual(got, entries) {
+			t.Errorf("Read%s() = %+v, want %+v", name, got, entries)
+		}
+	}
+
+	got, err := ReadCSV(strings.NewReader("1,id\n,width\n"))
+	if err != nil {
+		t.Fatalf("ReadCSV() without header error = %v", err)
+	}
+	if want := []Entry{{Hash: 1, String: "id"}, {Hash: hashtab.DJB2Hash("width"), String: "width"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadCSV() = %+v, want %+v", got, want)
+	}
+	if got, err := ReadJSON(strings.NewReader(`[{"string": "width"}]`)); err != nil || got[0].Hash != hashtab.DJB2Hash("width") {
+		t.Errorf("ReadJSON() without hash = %+v, %v", got, err)
+	}
+
+	for _, input := range []string{"x,id\n", "0,id\n", ",\n", "1,id,extra\n"} {
+		if _, err := ReadCSV(strings.NewReader(input)); err == nil {
+			t.Errorf("ReadCSV(%q) expected error", input)
+		}
+	}
+	for _, input := range []string{`{}`, `[{"hash": -1}]`, `[{"hash": 1, "name": "id"}]`, `[{}]`} {
+		if _, err := ReadJSON(strings.NewReader(input)); err == nil {
+			t.Errorf("ReadJSON(%q) expected error", input)
+		}
+	}
+}
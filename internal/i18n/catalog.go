@@ -0,0 +1,297 @@
+package i18n
+
+const (
+	MsgUploadingFile           = "uploading_file"
+	MsgUploadingFiles          = "uploading_files"
+	MsgRetryingFiles           = "retrying_files"
+	MsgUploadProgress          = "upload_progress"
+	MsgSharedDepsTitle         = "shared_deps_title"
+	MsgSharedDepUsedBy         = "shared_dep_used_by"
+	MsgSharedDepCompatible     = "shared_dep_compatible"
+	MsgSharedDepHashErrors     = "shared_dep_hash_errors"
+	MsgUsesSharedDeps          = "uses_shared_deps"
+	MsgDependencyWarnings      = "dependency_warnings"
+	MsgCheckingFile            = "checking_file"
+	MsgCheckingFiles           = "checking_files"
+	MsgFetchingHashtables      = "fetching_hashtables"
+	MsgFetchingTrees           = "fetching_trees"
+	MsgWarnNoHashtables        = "warn_no_hashtables"
+	MsgWarnNoFilterMatch       = "warn_no_filter_match"
+	MsgWarnSkippingEmpty       = "warn_skipping_empty"
+	MsgErrorPrefix             = "error_prefix"
+	MsgErrNoQMDFiles           = "err_no_qmd_files"
+	MsgErrInvalidDevice        = "err_invalid_device"
+	MsgErrInvalidOutput        = "err_invalid_output"
+	MsgErrCheckFailed          = "err_check_failed"
+	MsgErrListHashtables       = "err_list_hashtables"
+	MsgErrListTrees            = "err_list_trees"
+	MsgMatrixTitle             = "matrix_title"
+	MsgNoCompatibilityData     = "no_compatibility_data"
+	MsgSummary                 = "summary"
+	MsgCompatibleCount         = "compatible_count"
+	MsgIncompatibleCount       = "incompatible_count"
+	MsgErrorDetails            = "error_details"
+	MsgHashtablesTitle         = "hashtables_title"
+	MsgNoHashtables            = "no_hashtables"
+	MsgTotalHashtables         = "total_hashtables"
+	MsgTreesTitle              = "trees_title"
+	MsgNoTrees                 = "no_trees"
+	MsgTotalTrees              = "total_trees"
+	MsgHeaderDevice            = "header_device"
+	MsgHeaderOSVersion         = "header_os_version"
+	MsgHeaderHashtable         = "header_hashtable"
+	MsgHeaderEntries           = "header_entries"
+	MsgHeaderQMLFiles          = "header_qml_files"
+	MsgHeaderDirectory         = "header_directory"
+	MsgTimelineTitle           = "timeline_title"
+	MsgFirstBroken             = "first_broken"
+	MsgSupportedOS             = "supported_os"
+	MsgSupportedNone           = "supported_none"
+	MsgSupportedGaps           = "supported_gaps"
+	MsgPolicySatisfied         = "policy_satisfied"
+	MsgPolicyViolations        = "policy_violations"
+	MsgTotalsLabel             = "totals_label"
+	MsgLegend                  = "legend"
+	MsgLegendCompatible        = "legend_compatible"
+	MsgLegendIncompatible      = "legend_incompatible"
+	MsgLegendNoData            = "legend_no_data"
+	MsgLegendTree              = "legend_tree"
+	MsgValidationModes         = "validation_modes"
+	MsgNewTag                  = "new_tag"
+	MsgAssertPassed            = "assert_passed"
+	MsgAssertDeviations        = "assert_deviations"
+	MsgAssertGained            = "assert_gained"
+	MsgAssertLost              = "assert_lost"
+	MsgAssertMissing           = "assert_missing"
+	MsgAssertUnexpected        = "assert_unexpected"
+	MsgDeviceSummaryTitle      = "device_summary_title"
+	MsgHeaderFilesCompatible   = "header_files_compatible"
+	MsgHeaderFilesIncompatible = "header_files_incompatible"
+	MsgHeaderFilesNoData       = "header_files_no_data"
+	MsgAllVersionsCompatible   = "all_versions_compatible"
+	MsgTimingTotal             = "timing_total"
+	MsgTimingUpload            = "timing_upload"
+	MsgTimingServer            = "timing_server"
+)
+
+var catalogs = map[string]map[string]string{
+	"en": {
+		MsgUploadingFile:           "Uploading %s to %s...",
+		MsgUploadingFiles:          "Uploading %d files to %s...",
+		MsgRetryingFiles:           "Retrying %d file(s) with server errors...",
+		MsgUploadProgress:          "[%d/%d] %s (%s of %s sent)",
+		MsgSharedDepsTitle:         "Shared dependencies (%d)",
+		MsgSharedDepUsedBy:         "used by %s",
+		MsgSharedDepCompatible:     "compatible with %d of %d hashtables",
+		MsgSharedDepHashErrors:     "%d hash error(s)",
+		MsgUsesSharedDeps:          "Shared dependencies: %s (see below)",
+		MsgDependencyWarnings:      "Dependency warnings: %d incompatibilities caused only by dependency files",
+		MsgCheckingFile:            "Checking %s against hashtables in %s...",
+		MsgCheckingFiles:           "Checking %d files against hashtables in %s...",
+		MsgFetchingHashtables:      "Fetching hashtables from %s...",
+		MsgFetchingTrees:           "Fetching QML trees from %s...",
+		MsgWarnNoHashtables:        "Warning: Server has no hashtables to compare against this QMD file",
+		MsgWarnNoFilterMatch:       "Warning: No devices matched your filter criteria",
+		MsgWarnSkippingEmpty:       "Warning: Skipping empty file %s",
+		MsgErrorPrefix:             "Error: %s",
+		MsgErrNoQMDFiles:           "no .qmd files found",
+		MsgErrInvalidDevice:        "invalid device '%s'. Valid devices: rm1, rm2, rmpp, rmppm",
+		MsgErrInvalidOutput:        "invalid output format '%s'. Valid formats: %s",
+		MsgErrCheckFailed:          "failed to check compatibility",
+		MsgErrListHashtables:       "failed to list hashtables",
+		MsgErrListTrees:            "failed to list trees",
+		MsgMatrixTitle:             "reMarkable QMD Verifier",
+		MsgNoCompatibilityData:     "No compatibility data available",
+		MsgSummary:                 "Summary: %d checked | %s | %s",
+		MsgCompatibleCount:         "%d compatible",
+		MsgIncompatibleCount:       "%d incompatible",
+		MsgErrorDetails:            "Error Details:",
+		MsgHashtablesTitle:         "Available Hashtables",
+		MsgNoHashtables:            "No hashtables available on the server",
+		MsgTotalHashtables:         "Total Hashtables: %d",
+		MsgTreesTitle:              "Available QML Trees",
+		MsgNoTrees:                 "No QML trees available on the server",
+		MsgTotalTrees:              "Total Trees: %d",
+		MsgHeaderDevice:            "Device",
+		MsgHeaderOSVersion:         "OS Version",
+		MsgHeaderHashtable:         "Hashtable",
+		MsgHeaderEntries:           "Entries",
+		MsgHeaderQMLFiles:          "QML Files",
+		MsgHeaderDirectory:         "Directory",
+		MsgTimelineTitle:           "reMarkable QMD Verifier — Timeline",
+		MsgFirstBroken:             "first broken: %s",
+		MsgSupportedOS:             "Supported OS versions:",
+		MsgSupportedNone:           "none",
+		MsgSupportedGaps:           "(gaps: %s)",
+		MsgPolicySatisfied:         "Policy satisfied (%d rules)",
+		MsgPolicyViolations:        "Policy violations (%d):",
+		MsgTotalsLabel:             "Pass",
+		MsgLegend:                  "Legend:",
+		MsgLegendCompatible:        "compatible",
+		MsgLegendIncompatible:      "incompatible",
+		MsgLegendNoData:            "no data",
+		MsgLegendTree:              "tree-validated (the others were checked against the hashtable only)",
+		MsgValidationModes:         "Validation: %d tree-validated (ᵀ) | %d hashtable only",
+		MsgNewTag:                  "NEW",
+		MsgAssertPassed:            "Results match the expected matrix (%d entries)",
+		MsgAssertDeviations:        "Deviations from the expected matrix (%d):",
+		MsgAssertGained:            "expected incompatible, now compatible",
+		MsgAssertLost:              "expected compatible, now incompatible",
+		MsgAssertMissing:           "expected, but not checked by the server",
+		MsgAssertUnexpected:        "not in the expected matrix",
+		MsgDeviceSummaryTitle:      "Device summary (%d files)",
+		MsgHeaderFilesCompatible:   "Compatible",
+		MsgHeaderFilesIncompatible: "Incompatible",
+		MsgHeaderFilesNoData:       "No data",
+		MsgAllVersionsCompatible:   "All %d versions are compatible on every device",
+		MsgTimingTotal:             "Time: %s total",
+		MsgTimingUpload:            "%s upload",
+		MsgTimingServer:            "server: %s queued, %s processing",
+	},
+	"de": {
+		MsgUploadingFile:           "Lade %s auf %s hoch...",
+		MsgUploadingFiles:          "Lade %d Dateien auf %s hoch...",
+		MsgRetryingFiles:           "Wiederhole %d Datei(en) mit Serverfehlern...",
+		MsgUploadProgress:          "[%d/%d] %s (%s von %s gesendet)",
+		MsgSharedDepsTitle:         "Gemeinsame Abhängigkeiten (%d)",
+		MsgSharedDepUsedBy:         "verwendet von %s",
+		MsgSharedDepCompatible:     "kompatibel mit %d von %d Hashtabellen",
+		MsgSharedDepHashErrors:     "%d Hash-Fehler",
+		MsgUsesSharedDeps:          "Gemeinsame Abhängigkeiten: %s (siehe unten)",
+		MsgDependencyWarnings:      "Abhängigkeitswarnungen: %d Inkompatibilitäten, die nur von Abhängigkeitsdateien verursacht werden",
+		MsgCheckingFile:            "Prüfe %s gegen die Hashtabellen in %s...",
+		MsgCheckingFiles:           "Prüfe %d Dateien gegen die Hashtabellen in %s...",
+		MsgFetchingHashtables:      "Rufe Hashtabellen von %s ab...",
+		MsgFetchingTrees:           "Rufe QML-Bäume von %s ab...",
+		MsgWarnNoHashtables:        "Warnung: Der Server hat keine Hashtabellen, mit denen diese QMD-Datei verglichen werden kann",
+		MsgWarnNoFilterMatch:       "Warnung: Keine Geräte entsprechen den Filterkriterien",
+		MsgWarnSkippingEmpty:       "Warnung: Leere Datei %s wird übersprungen",
+		MsgErrorPrefix:             "Fehler: %s",
+		MsgErrNoQMDFiles:           "keine .qmd-Dateien gefunden",
+		MsgErrInvalidDevice:        "ungültiges Gerät '%s'. Gültige Geräte: rm1, rm2, rmpp, rmppm",
+		MsgErrInvalidOutput:        "ungültiges Ausgabeformat '%s'. Gültige Formate: %s",
+		MsgErrCheckFailed:          "Kompatibilitätsprüfung fehlgeschlagen",
+		MsgErrListHashtables:       "Hashtabellen konnten nicht abgerufen werden",
+		MsgErrListTrees:            "QML-Bäume konnten nicht abgerufen werden",
+		MsgMatrixTitle:             "reMarkable QMD-Prüfer",
+		MsgNoCompatibilityData:     "Keine Kompatibilitätsdaten verfügbar",
+		MsgSummary:                 "Zusammenfassung: %d geprüft | %s | %s",
+		MsgCompatibleCount:         "%d kompatibel",
+		MsgIncompatibleCount:       "%d inkompatibel",
+		MsgErrorDetails:            "Fehlerdetails:",
+		MsgHashtablesTitle:         "Verfügbare Hashtabellen",
+		MsgNoHashtables:            "Auf dem Server sind keine Hashtabellen verfügbar",
+		MsgTotalHashtables:         "Hashtabellen gesamt: %d",
+		MsgTreesTitle:              "Verfügbare QML-Bäume",
+		MsgNoTrees:                 "Auf dem Server sind keine QML-Bäume verfügbar",
+		MsgTotalTrees:              "QML-Bäume gesamt: %d",
+		MsgHeaderDevice:            "Gerät",
+		MsgHeaderOSVersion:         "OS-Version",
+		MsgHeaderHashtable:         "Hashtabelle",
+		MsgHeaderEntries:           "Einträge",
+		MsgHeaderQMLFiles:          "QML-Dateien",
+		MsgHeaderDirectory:         "Verzeichnis",
+		MsgTimelineTitle:           "reMarkable QMD-Prüfer — Zeitleiste",
+		MsgFirstBroken:             "zuerst defekt: %s",
+		MsgSupportedOS:             "Unterstützte OS-Versionen:",
+		MsgSupportedNone:           "keine",
+		MsgSupportedGaps:           "(Lücken: %s)",
+		MsgPolicySatisfied:         "Richtlinie erfüllt (%d Regeln)",
+		MsgPolicyViolations:        "Richtlinienverstöße (%d):",
+		MsgTotalsLabel:             "OK",
+		MsgLegend:                  "Legende:",
+		MsgLegendCompatible:        "kompatibel",
+		MsgLegendIncompatible:      "inkompatibel",
+		MsgLegendNoData:            "keine Daten",
+		MsgLegendTree:              "baumvalidiert (die anderen wurden nur gegen die Hashtabelle geprüft)",
+		MsgValidationModes:         "Validierung: %d baumvalidiert (ᵀ) | %d nur Hashtabelle",
+		MsgNewTag:                  "NEU",
+		MsgAssertPassed:            "Ergebnisse entsprechen der erwarteten Matrix (%d Einträge)",
+		MsgAssertDeviations:        "Abweichungen von der erwarteten Matrix (%d):",
+		MsgAssertGained:            "inkompatibel erwartet, jetzt kompatibel",
+		MsgAssertLost:              "kompatibel erwartet, jetzt inkompatibel",
+		MsgAssertMissing:           "erwartet, aber vom Server nicht geprüft",
+		MsgAssertUnexpected:        "nicht in der erwarteten Matrix",
+		MsgDeviceSummaryTitle:      "Gerätezusammenfassung (%d Dateien)",
+		MsgHeaderFilesCompatible:   "Kompatibel",
+		MsgHeaderFilesIncompatible: "Inkompatibel",
+		MsgHeaderFilesNoData:       "Keine Daten",
+		MsgAllVersionsCompatible:   "Alle %d Versionen sind auf allen Geräten kompatibel",
+		MsgTimingTotal:             "Zeit: %s gesamt",
+		MsgTimingUpload:            "%s Upload",
+		MsgTimingServer:            "Server: %s Warteschlange, %s Verarbeitung",
+	},
+	"fr": {
+		MsgUploadingFile:           "Envoi de %s vers %s...",
+		MsgUploadingFiles:          "Envoi de %d fichiers vers %s...",
+		MsgRetryingFiles:           "Nouvel essai pour %d fichier(s) en erreur côté serveur...",
+		MsgUploadProgress:          "[%d/%d] %s (%s sur %s envoyés)",
+		MsgSharedDepsTitle:         "Dépendances partagées (%d)",
+		MsgSharedDepUsedBy:         "utilisée par %s",
+		MsgSharedDepCompatible:     "compatible avec %d tables sur %d",
+		MsgSharedDepHashErrors:     "%d erreur(s) de hash",
+		MsgUsesSharedDeps:          "Dépendances partagées : %s (voir ci-dessous)",
+		MsgDependencyWarnings:      "Avertissements de dépendances : %d incompatibilités causées uniquement par des fichiers de dépendance",
+		MsgCheckingFile:            "Vérification de %s avec les tables de hachage de %s...",
+		MsgCheckingFiles:           "Vérification de %d fichiers avec les tables de hachage de %s...",
+		MsgFetchingHashtables:      "Récupération des tables de hachage depuis %s...",
+		MsgFetchingTrees:           "Récupération des arbres QML depuis %s...",
+		MsgWarnNoHashtables:        "Avertissement : le serveur n'a aucune table de hachage pour comparer ce fichier QMD",
+		MsgWarnNoFilterMatch:       "Avertissement : aucun appareil ne correspond à vos critères de filtre",
+		MsgWarnSkippingEmpty:       "Avertissement : fichier vide %s ignoré",
+		MsgErrorPrefix:             "Erreur : %s",
+		MsgErrNoQMDFiles:           "aucun fichier .qmd trouvé",
+		MsgErrInvalidDevice:        "appareil '%s' invalide. Appareils valides : rm1, rm2, rmpp, rmppm",
+		MsgErrInvalidOutput:        "format de sortie '%s' invalide. Formats valides : %s",
+		MsgErrCheckFailed:          "échec de la vérification de compatibilité",
+		MsgErrListHashtables:       "impossible de lister les tables de hachage",
+		MsgErrListTrees:            "impossible de lister les arbres QML",
+		MsgMatrixTitle:             "Vérificateur QMD reMarkable",
+		MsgNoCompatibilityData:     "Aucune donnée de compatibilité disponible",
+		MsgSummary:                 "Résumé : %d vérifiés | %s | %s",
+		MsgCompatibleCount:         "%d compatibles",
+		MsgIncompatibleCount:       "%d incompatibles",
+		MsgErrorDetails:            "Détails des erreurs :",
+		MsgHashtablesTitle:         "Tables de hachage disponibles",
+		MsgNoHashtables:            "Aucune table de hachage disponible sur le serveur",
+		MsgTotalHashtables:         "Total des tables de hachage : %d",
+		MsgTreesTitle:              "Arbres QML disponibles",
+		MsgNoTrees:                 "Aucun arbre QML disponible sur le serveur",
+		MsgTotalTrees:              "Total des arbres : %d",
+		MsgHeaderDevice:            "Appareil",
+		MsgHeaderOSVersion:         "Version OS",
+		MsgHeaderHashtable:         "Table",
+		MsgHeaderEntries:           "Entrées",
+		MsgHeaderQMLFiles:          "Fichiers QML",
+		MsgHeaderDirectory:         "Répertoire",
+		MsgTimelineTitle:           "Vérificateur QMD reMarkable — Chronologie",
+		MsgFirstBroken:             "première incompatibilité : %s",
+		MsgSupportedOS:             "Versions OS prises en charge :",
+		MsgSupportedNone:           "aucune",
+		MsgSupportedGaps:           "(lacunes : %s)",
+		MsgPolicySatisfied:         "Politique respectée (%d règles)",
+		MsgPolicyViolations:        "Violations de la politique (%d) :",
+		MsgTotalsLabel:             "OK",
+		MsgLegend:                  "Légende :",
+		MsgLegendCompatible:        "compatible",
+		MsgLegendIncompatible:      "incompatible",
+		MsgLegendNoData:            "aucune donnée",
+		MsgLegendTree:              "validé par arbre (les autres ont été vérifiés uniquement avec la table de hachage)",
+		MsgValidationModes:         "Validation : %d validé(s) par arbre (ᵀ) | %d table de hachage uniquement",
+		MsgNewTag:                  "NOUVEAU",
+		MsgAssertPassed:            "Les résultats correspondent à la matrice attendue (%d entrées)",
+		MsgAssertDeviations:        "Écarts par rapport à la matrice attendue (%d) :",
+		MsgAssertGained:            "incompatible attendu, désormais compatible",
+		MsgAssertLost:              "compatible attendu, désormais incompatible",
+		MsgAssertMissing:           "attendu, mais non vérifié par le serveur",
+		MsgAssertUnexpected:        "absent de la matrice attendue",
+		MsgDeviceSummaryTitle:      "Résumé par appareil (%d fichiers)",
+		MsgHeaderFilesCompatible:   "Compatibles",
+		MsgHeaderFilesIncompatible: "Incompatibles",
+		MsgHeaderFilesNoData:       "Sans données",
+		MsgAllVersionsCompatible:   "Les %d versions sont compatibles sur tous les appareils",
+		MsgTimingTotal:             "Durée : %s au total",
+		MsgTimingUpload:            "%s d'envoi",
+		MsgTimingServer:            "serveur : %s en file, %s de traitement",
+	},
+}
@@ -0,0 +1,82 @@
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	DefaultLanguage = "en"
+	EnvVarLang      = "QMDVERIFY_LANG"
+)
+
+var current = DefaultLanguage
+
+func SetLanguage(lang string) error {
+	normalized := Normalize(lang)
+	if _, ok := catalogs[normalized]; !ok {
+		return fmt.Errorf("unsupported language '%s'. Supported languages: %s", lang, strings.Join(Supported(), ", "))
+	}
+	current = normalized
+	return nil
+}
+
+func Language() string {
+	return current
+}
+
+// Detect resolves the language from QMDVERIFY_LANG and the POSIX locale
+// variables, falling back to English for unset or unsupported locales.
+func Detect() string {
+	for _, env := range []string{EnvVarLang, "LC_ALL", "LC_MESSAGES", "LANG"} {
+		value := os.Getenv(env)
+		if value == "" {
+			continue
+		}
+		lang := Normalize(value)
+		if _, ok := catalogs[lang]; ok {
+			return lang
+		}
+		return DefaultLanguage
+	}
+	return DefaultLanguage
+}
+
+func Normalize(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if i := strings.IndexAny(lang, ".@"); i >= 0 {
+		lang = lang[:i]
+	}
+	if i := strings.IndexAny(lang, "_-"); i >= 0 {
+		lang = lang[:i]
+	}
+	if lang == "c" || lang == "posix" {
+		return DefaultLanguage
+	}
+	return lang
+}
+
+func Supported() []string {
+	langs := make([]string, 0, len(catalogs))
+	for lang := range catalogs {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+func T(key string, args ...any) string {
+	format, ok := catalogs[current][key]
+	if !ok {
+		format, ok = catalogs[DefaultLanguage][key]
+	}
+	if !ok {
+		format = key
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
@@ -0,0 +1,129 @@
+package i18n
+
+import (
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		lang string
+		want string
+	}{
+		{name: "bare code", lang: "de", want: "de"},
+		{name: "locale with region", lang: "de_DE", want: "de"},
+		{name: "locale with encoding", lang: "fr_FR.UTF-8", want: "fr"},
+		{name: "locale with modifier", lang: "de_DE@euro", want: "de"},
+		{name: "BCP 47 tag", lang: "fr-CA", want: "fr"},
+		{name: "uppercase", lang: "EN_US", want: "en"},
+		{name: "C locale", lang: "C", want: "en"},
+		{name: "POSIX locale", lang: "POSIX", want: "en"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Normalize(tt.lang); got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.lang, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{
+			name: "nothing set",
+			env:  map[string]string{},
+			want: "en",
+		},
+		{
+			name: "LANG only",
+			env:  map[string]string{"LANG": "de_DE.UTF-8"},
+			want: "de",
+		},
+		{
+			name: "LC_ALL overrides LANG",
+			env:  map[string]string{"LC_ALL": "fr_FR.UTF-8", "LANG": "de_DE.UTF-8"},
+			want: "fr",
+		},
+		{
+			name: "QMDVERIFY_LANG overrides locale",
+			env:  map[string]string{EnvVarLang: "de", "LC_ALL": "fr_FR.UTF-8"},
+			want: "de",
+		},
+		{
+			name: "unsupported locale falls back to English",
+			env:  map[string]string{"LANG": "ja_JP.UTF-8"},
+			want: "en",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{EnvVarLang, "LC_ALL", "LC_MESSAGES", "LANG"} {
+				t.Setenv(key, tt.env[key])
+			}
+			if got := Detect(); got != tt.want {
+				t.Errorf("Detect() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetLanguage(t *testing.T) {
+	defer SetLanguage(DefaultLanguage)
+
+	if err := SetLanguage("de_DE.UTF-8"); err != nil {
+		t.Fatalf("SetLanguage() error = %v", err)
+	}
+	if Language() != "de" {
+		t.Errorf("Language() = %q, want %q", Language(), "de")
+	}
+
+	if err := SetLanguage("xx"); err == nil {
+		t.Error("SetLanguage() expected error for unsupported language")
+	}
+	if Language() != "de" {
+		t.Errorf("Language() changed after failed SetLanguage: %q", Language())
+	}
+}
+
+func TestT(t *testing.T) {
+	defer SetLanguage(DefaultLanguage)
+
+	SetLanguage("en")
+	if got := T(MsgCompatibleCount, 3); got != "3 compatible" {
+		t.Errorf("T() = %q, want %q", got, "3 compatible")
+	}
+
+	SetLanguage("de")
+	if got := T(MsgCompatibleCount, 3); got != "3 kompatibel" {
+		t.Errorf("T() = %q, want %q", got, "3 kompatibel")
+	}
+
+	if got := T("missing_key"); got != "missing_key" {
+		t.Errorf("T() for unknown key = %q, want key itself", got)
+	}
+}
+
+func TestCatalogsComplete(t *testing.T) {
+	for lang, catalog := range catalogs {
+		if lang == DefaultLanguage {
+			continue
+		}
+		for key := range catalogs[DefaultLanguage] {
+			if _, ok := catalog[key]; !ok {
+				t.Errorf("catalog %q missing key %q", lang, key)
+			}
+		}
+		for key := range catalog {
+			if _, ok := catalogs[DefaultLanguage][key]; !ok {
+				t.Errorf("catalog %q has unknown key %q", lang, key)
+			}
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package ignore
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// FileName is the ignore file honoured in every scanned directory.
+const FileName = ".qmdignore"
+
+type rule struct {
+	base     string
+	pattern  *regexp.Regexp
+	anchored bool
+	negate   bool
+	dirOnly  bool
+}
+
+// Matcher holds the rules of every ignore file loaded so far. Paths are
+// slash-separated and relative to the scan root; a rule only applies below
+// the directory its ignore file was found in.
+type Matcher struct {
+	rules []rule
+}
+
+// Load adds the rules from dir's ignore file, if it has one. rel is dir's
+// path relative to the scan root ("" or "." for the root itself).
+func (m *Matcher) Load(dir, rel string) error {
+	data, err := os.ReadFile(filepath.Join(dir, FileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", filepath.Join(dir, FileName), err)
+	}
+	return m.Add(rel, data)
+}
+
+// Add parses gitignore-style patterns that apply below base.
+func (m *Matcher) Add(base string, data []byte) error {
+	base = strings.Trim(path.Clean("/"+filepath.ToSlash(base)), "/")
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), " \t\r")
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		r := rule{base: base}
+		if strings.HasPrefix(text, "!") {
+			r.negate = true
+			text = text[1:]
+		} else if strings.HasPrefix(text, `\`) {
+			text = text[1:]
+		}
+		if strings.HasSuffix(text, "/") {
+			r.dirOnly = true
+			text = strings.TrimRight(text, "/")
+		}
+		if strings.Contains(text, "/") {
+			r.anchored = true
+			text = strings.TrimPrefix(text, "/")
+		}
+		if text == "" {
+			continue
+		}
+
+		pattern, err := regexp.Compile("^" + globToRegexp(text) + "$")
+		if err != nil {
+			return fmt.Errorf("%s line %d: invalid pattern %q", FileName, line, scanner.Text())
+		}
+		r.pattern = pattern
+		m.rules = append(m.rules, r)
+	}
+	return scanner.Err()
+}
+
+// Match reports whether the path is ignored. As in gitignore, the last
+// matching rule wins and a negated rule re-includes the path.
+func (m *Matcher) Match(rel string, isDir bool) bool {
+	rel = strings.Trim(path.Clean("/"+filepath.ToSlash(rel)), "/")
+
+	ignored := false
+	for _, r := range m.rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+
+		sub := rel
+		if r.base != "" {
+			if !strings.HasPrefix(rel, r.base+"/") {
+				continue
+			}
+			sub = rel[len(r.base)+1:]
+		}
+		if !r.anchored {
+			sub = path.Base(sub)
+		}
+
+		if r.pattern.MatchString(sub) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			b.WriteString("/.*")
+			i += 2
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
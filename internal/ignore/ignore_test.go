@@ -0,0 +1,77 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	var m Matcher
+	rules := `# throwaway files
+*.tmp.qmd
+experimental/
+/build
+docs/**/draft-*.qmd
+!keep.tmp.qmd
+vendor/*/
+\#literal.qmd
+`
+	if err := m.Add("", []byte(rules)); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Add("sub", []byte("local.qmd\n/anchored.qmd\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"a.tmp.qmd", false, true},
+		{"deep/nested/a.tmp.qmd", false, true},
+		{"keep.tmp.qmd", false, false},
+		{"main.qmd", false, false},
+		{"experimental", true, true},
+		{"nested/experimental", true, true},
+		{"experimental", false, false},
+		{"build", true, true},
+		{"nested/build", true, false},
+		{"docs/draft-1.qmd", false, true},
+		{"docs/a/b/draft-2.qmd", false, true},
+		{"docs/a/final.qmd", false, false},
+		{"vendor/lib", true, true},
+		{"vendor", true, false},
+		{"#literal.qmd", false, true},
+		{"sub/local.qmd", false, true},
+		{"sub/x/local.qmd", false, true},
+		{"local.qmd", false, false},
+		{"sub/anchored.qmd", false, true},
+		{"sub/x/anchored.qmd", false, false},
+	}
+
+	for _, tt := range tests {
+		if got := m.Match(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("Match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	var m Matcher
+	if err := m.Load(dir, ""); err != nil {
+		t.Fatalf("missing ignore file: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, FileName), []byte("*.bak.qmd\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Load(dir, "."); err != nil {
+		t.Fatal(err)
+	}
+	if !m.Match("x/old.bak.qmd", false) {
+		t.Error("rule from the loaded file did not apply")
+	}
+}
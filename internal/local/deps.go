@@ -0,0 +1,106 @@
+package local
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// MissingDependency is a LOAD whose target is not among the files being
+// checked. File and Target are relative to the batch.
+type MissingDependency struct {
+	File   string
+	Target string
+}
+
+// MissingDependencies parses each file and reports the literal LOAD targets
+// that are not part of the set. Files that cannot be read or parsed are
+// skipped; the check itself reports them. Hashed targets cannot be resolved
+// without a hashtable and are not reported.
+func MissingDependencies(filePaths, relativePaths []string) []MissingDependency {
+	present := make(map[string]bool, len(relativePaths))
+	for _, rel := range relativePaths {
+		present[filepath.ToSlash(rel)] = true
+	}
+
+	var missing []MissingDependency
+	for i, filePath := range filePaths {
+		rel := filepath.ToSlash(relativePaths[i])
+		reported := make(map[string]bool)
+		for _, target := range loadTargets(filePath, rel) {
+			if present[target] || reported[target] {
+				continue
+			}
+			reported[target] = true
+			missing = append(missing, MissingDependency{File: rel, Target: target})
+		}
+	}
+	return missing
+}
+
+// DependencyGroups partitions the files into groups that LOAD one another,
+// directly or through other files of the set, by their literal targets.
+// Each group holds indexes into filePaths in order, and groups are ordered
+// by their first file. Files that cannot be read or parsed are on their own.
+func DependencyGroups(filePaths, relativePaths []string) [][]int {
+	index := make(map[string]int, len(relativePaths))
+	for i, rel := range relativePaths {
+		index[filepath.ToSlash(rel)] = i
+	}
+
+	parent := make([]int, len(filePaths))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	for i, filePath := range filePaths {
+		for _, target := range loadTargets(filePath, filepath.ToSlash(relativePaths[i])) {
+			if j, ok := index[target]; ok {
+				a, b := find(i), find(j)
+				parent[max(a, b)] = min(a, b)
+			}
+		}
+	}
+
+	var groups [][]int
+	group := make(map[int]int)
+	for i := range filePaths {
+		root := find(i)
+		g, ok := group[root]
+		if !ok {
+			g = len(groups)
+			group[root] = g
+			groups = append(groups, nil)
+		}
+		groups[g] = append(groups[g], i)
+	}
+	return groups
+}
+
+// loadTargets returns the literal LOAD targets of a file, relative to the
+// batch like rel.
+func loadTargets(filePath, rel string) []string {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil
+	}
+	parsed, err := Parse(string(content))
+	if err != nil {
+		return nil
+	}
+
+	var targets []string
+	for _, d := range parsed.Directives {
+		if d.Keyword == "LOAD" && d.Target != "" {
+			targets = append(targets, path.Join(path.Dir(rel), filepath.ToSlash(d.Target)))
+		}
+	}
+	return targets
+}
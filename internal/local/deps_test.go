@@ -0,0 +1,55 @@
+package local
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMissingDependencies(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"root.qmd":       "LOAD lib/shared.qmd\nLOAD lib/icons.qmd\nLOAD lib/icons.qmd\nLOAD [[42]]\n",
+		"lib/shared.qmd": "LOAD util.qmd\n[[1]]\n",
+		"broken.qmd":     "LOAD gone.qmd\n[[",
+	})
+
+	rels := []string{"root.qmd", filepath.Join("lib", "shared.qmd"), "broken.qmd", "absent.qmd"}
+	var paths []string
+	for _, rel := range rels {
+		paths = append(paths, filepath.Join(dir, rel))
+	}
+
+	got := MissingDependencies(paths, rels)
+	want := []MissingDependency{
+		{File: "root.qmd", Target: "lib/icons.qmd"},
+		{File: "lib/shared.qmd", Target: "lib/util.qmd"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MissingDependencies() = %+v, want %+v", got, want)
+	}
+}
+
+func TestDependencyGroups(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.qmd":          "LOAD lib/shared.qmd\n",
+		"b.qmd":          "[[1]]\n",
+		"c.qmd":          "LOAD lib/util.qmd\n",
+		"lib/shared.qmd": "LOAD util.qmd\n",
+		"lib/util.qmd":   "[[2]]\n",
+		"d.qmd":          "LOAD [[42]]\n",
+	})
+
+	rels := []string{"a.qmd", "b.qmd", "c.qmd", filepath.Join("lib", "shared.qmd"), filepath.Join("lib", "util.qmd"), "d.qmd", "absent.qmd"}
+	var paths []string
+	for _, rel := range rels {
+		paths = append(paths, filepath.Join(dir, rel))
+	}
+
+	got := DependencyGroups(paths, rels)
+	want := [][]int{{0, 2, 3, 4}, {1}, {5}, {6}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DependencyGroups() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,184 @@
+package local
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/bundle"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/cache"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/hashtabfile"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/tree"
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+	"github.com/rmitchellscott/rm-qmd-verify/pkg/hashtab"
+)
+
+const (
+	ModeHashtable = "hashtable"
+	ModeTree      = "tree"
+)
+
+const (
+	StatusCompatible   = "compatible"
+	StatusIncompatible = "incompatible"
+)
+
+// Engine checks QMD files against hashtables on disk, producing the same
+// results the server does in hashtable mode. In tree mode, files are also
+// checked against downloaded QML trees.
+type Engine struct {
+	Dir        string
+	Hashtables []*hashtab.Hashtab
+	Mode       string
+	Trees      map[string]*tree.Tree
+}
+
+// Load reads every hashtab or hashlist file under dir. A sync mirror or
+// extracted bundle is read from its hashtables/ subdirectory; any other
+// directory is read as-is. Like the server, files that fail to load are
+// skipped.
+func Load(dir string) (*Engine, error) {
+	root := dir
+	if info, err := os.Stat(filepath.Join(dir, bundle.HashtableDir)); err == nil && info.IsDir() {
+		root = filepath.Join(dir, bundle.HashtableDir)
+	}
+
+	engine := &Engine{Dir: dir}
+	loaded := make(map[string]bool)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if d.IsDir() || strings.HasPrefix(name, ".") || name == bundle.ManifestName || name == bundle.SignatureName || loaded[name] {
+			return nil
+		}
+
+		ht, err := hashtabfile.Load(path)
+		if err != nil {
+			return nil
+		}
+		engine.Hashtables = append(engine.Hashtables, ht)
+		loaded[name] = true
+		cache.Touch(path)
+		return nil
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read hashtables from %s: %w", dir, err)
+	}
+
+	if len(engine.Hashtables) == 0 {
+		return nil, fmt.Errorf("no hashtables found in %s (run 'qmdverify sync' first)", dir)
+	}
+	return engine, nil
+}
+
+// LoadTrees switches the engine to tree mode using the trees under dir.
+func (e *Engine) LoadTrees(dir string) error {
+	trees, err := tree.LoadAll(dir)
+	if err != nil {
+		return err
+	}
+	if len(trees) == 0 {
+		return fmt.Errorf("no QML trees found in %s (run 'qmdverify tree download --all' first)", dir)
+	}
+	e.Mode = ModeTree
+	e.Trees = trees
+	return nil
+}
+
+// Compare checks QMD content against every loaded hashtable.
+func (e *Engine) Compare(content []byte) *api.ComparisonResponse {
+	response := &api.ComparisonResponse{
+		Compatible:   make([]api.ComparisonResult, 0),
+		Incompatible: make([]api.ComparisonResult, 0),
+		Mode:         ModeHashtable,
+	}
+
+	hashes, extractErr := ExtractHashes(string(content))
+	for _, ht := range e.Hashtables {
+		addResult(response, hashtableResult(ht, hashes, extractErr))
+	}
+	response.TotalChecked = len(e.Hashtables)
+
+	return response
+}
+
+func addResult(response *api.ComparisonResponse, result api.ComparisonResult) {
+	if result.Compatible {
+		response.Compatible = append(response.Compatible, result)
+	} else {
+		response.Incompatible = append(response.Incompatible, result)
+	}
+}
+
+func hashtableResult(ht *hashtab.Hashtab, hashes []uint64, extractErr error) api.ComparisonResult {
+	result := api.ComparisonResult{
+		Hashtable:      ht.Name,
+		OSVersion:      ht.OSVersion,
+		Device:         ht.Device,
+		ValidationMode: ModeHashtable,
+	}
+
+	if extractErr != nil {
+		result.ErrorDetail = fmt.Sprintf("verification failed: %v", extractErr)
+	} else if missing := countMissing(ht, hashes); missing > 0 {
+		result.ErrorDetail = fmt.Sprintf("missing %d hash(es)", missing)
+	} else {
+		result.Compatible = true
+	}
+	return result
+}
+
+func countMissing(ht *hashtab.Hashtab, hashes []uint64) int {
+	missing := 0
+	for _, hash := range hashes {
+		if _, ok := ht.Entries[hash]; !ok {
+			missing++
+		}
+	}
+	return missing
+}
+
+func (e *Engine) CompareQMD(ctx context.Context, filePath string) (*api.ComparisonResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return e.compareFile(filePath, filepath.Base(filePath))
+}
+
+func (e *Engine) compareFile(filePath, relativePath string) (*api.ComparisonResponse, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	if len(content) == 0 {
+		return nil, fmt.Errorf("file is empty")
+	}
+	if e.Mode != ModeTree {
+		return e.Compare(content), nil
+	}
+	return e.compareTree(filePath, relativePath, content), nil
+}
+
+// CompareQMDFiles checks each file, keyed by its relative path. In tree
+// mode, files a root file LOADs are reported in its dependency results. A
+// cancelled context stops the check between files.
+func (e *Engine) CompareQMDFiles(ctx context.Context, filePaths []string, relativePaths []string) (*api.BatchComparisonResponse, error) {
+	batch := make(api.BatchComparisonResponse)
+	for i, filePath := range filePaths {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		response, err := e.compareFile(filePath, relativePaths[i])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", relativePaths[i], err)
+		}
+		batch[relativePaths[i]] = *response
+	}
+	return &batch, nil
+}
@@ -0,0 +1,133 @@
+package local
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/hashtabfile"
+	"github.com/rmitchellscott/rm-qmd-verify/pkg/hashtab"
+)
+
+func writeHashtables(t *testing.T, dir string, tables map[string][]uint64) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, hashes := range tables {
+		if err := hashtab.WriteHashlist(hashes, filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("mirror layout", func(t *testing.T) {
+		dir := t.TempDir()
+		writeHashtables(t, filepath.Join(dir, "hashtables"), map[string][]uint64{"3.22.0.64-rmpp": {1}})
+		if err := os.WriteFile(filepath.Join(dir, "manifest.json"), []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		engine, err := Load(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(engine.Hashtables) != 1 || engine.Hashtables[0].Device != "rmpp" {
+			t.Errorf("hashtables = %+v", engine.Hashtables)
+		}
+	})
+
+	t.Run("plain directory", func(t *testing.T) {
+		dir := t.TempDir()
+		writeHashtables(t, dir, map[string][]uint64{"3.22.0.64-rmpp": {1}, "3.22.0.64-rm2": {1}})
+		if err := os.WriteFile(filepath.Join(dir, ".tmp-123"), []byte("partial"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		engine, err := Load(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(engine.Hashtables) != 2 {
+			t.Errorf("loaded %d hashtables, want 2", len(engine.Hashtables))
+		}
+	})
+
+	t.Run("compressed hashlist", func(t *testing.T) {
+		dir := t.TempDir()
+		entries := []hashtabfile.Entry{{Hash: 1}, {Hash: 42}}
+		if err := hashtabfile.EncodeFile(filepath.Join(dir, "3.22.0.64-rmpp"), entries, hashtabfile.Compressed); err != nil {
+			t.Fatal(err)
+		}
+
+		engine, err := Load(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(engine.Hashtables) != 1 || len(engine.Hashtables[0].Entries) != 2 || engine.Hashtables[0].Device != "rmpp" {
+			t.Errorf("hashtables = %+v", engine.Hashtables)
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		for _, dir := range []string{t.TempDir(), filepath.Join(t.TempDir(), "missing")} {
+			if _, err := Load(dir); err == nil || !strings.Contains(err.Error(), "no hashtables") {
+				t.Errorf("Load(%s) err = %v, want no hashtables", dir, err)
+			}
+		}
+	})
+}
+
+func TestCompare(t *testing.T) {
+	dir := t.TempDir()
+	writeHashtables(t, dir, map[string][]uint64{
+		"3.22.0.64-rmpp": {1, 2, 3},
+		"3.20.0.92-rmpp": {1},
+	})
+	engine, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response := engine.Compare([]byte("AFFECT [[1]]\nINSERT { id: ~&2&~ }\n"))
+	if response.TotalChecked != 2 || len(response.Compatible) != 1 || len(response.Incompatible) != 1 {
+		t.Fatalf("response = %+v", response)
+	}
+	if got := response.Compatible[0]; got.OSVersion != "3.22.0.64" || got.ValidationMode != ModeHashtable {
+		t.Errorf("compatible = %+v", got)
+	}
+	if got := response.Incompatible[0]; got.OSVersion != "3.20.0.92" || got.ErrorDetail != "missing 1 hash(es)" {
+		t.Errorf("incompatible = %+v", got)
+	}
+
+	response = engine.Compare([]byte("[[oops]]"))
+	if len(response.Incompatible) != 2 || response.Incompatible[0].ErrorDetail != "verification failed: invalid hash: no digits found" {
+		t.Errorf("invalid file response = %+v", response)
+	}
+}
+
+func TestCompareQMDFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeHashtables(t, dir, map[string][]uint64{"3.22.0.64-rmpp": {1}})
+	engine, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := t.TempDir()
+	a := filepath.Join(files, "a.qmd")
+	b := filepath.Join(files, "b.qmd")
+	os.WriteFile(a, []byte("[[1]]"), 0644)
+	os.WriteFile(b, []byte("[[2]]"), 0644)
+
+	batch, err := engine.CompareQMDFiles(context.Background(), []string{a, b}, []string{"a.qmd", "b.qmd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len((*batch)["a.qmd"].Compatible) != 1 || len((*batch)["b.qmd"].Incompatible) != 1 {
+		t.Errorf("batch = %+v", *batch)
+	}
+}
@@ -0,0 +1,363 @@
+package local
+
+import (
+	"fmt"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Directive is an AFFECT or LOAD statement. Target is the literal path it
+// names; a hashed path leaves Target empty and sets Hash instead.
+type Directive struct {
+	Keyword string
+	Target  string
+	Hash    uint64
+}
+
+// Parsed is what the local engine needs from a QMD file.
+type Parsed struct {
+	Hashes     []uint64
+	Directives []Directive
+}
+
+// ExtractHashes returns the distinct hashes a QMD file references: hashed
+// values ([[123]]) in the diff itself and hashed identifiers (~&123&~) in
+// its QML blocks. It follows the server's lexer exactly, including its
+// errors, so local results match the server's for hashtable-mode checks.
+func ExtractHashes(content string) ([]uint64, error) {
+	parsed, err := Parse(content)
+	if err != nil {
+		return nil, err
+	}
+	return parsed.Hashes, nil
+}
+
+// Parse extracts the hashes and the AFFECT and LOAD directives of a QMD
+// file. Directives are found by looking ahead only, so they never change
+// how the rest of the file is lexed.
+func Parse(content string) (*Parsed, error) {
+	parsed := &Parsed{}
+	seen := make(map[uint64]bool)
+	add := func(hash uint64) {
+		if !seen[hash] {
+			seen[hash] = true
+			parsed.Hashes = append(parsed.Hashes, hash)
+		}
+	}
+	directive := func(d Directive) {
+		parsed.Directives = append(parsed.Directives, d)
+	}
+
+	if err := lexDiff(&stream{input: content}, add, directive); err != nil {
+		return nil, err
+	}
+	return parsed, nil
+}
+
+type stream struct {
+	input string
+	pos   int
+}
+
+func (s *stream) peek() (rune, bool) {
+	return s.peekOffset(0)
+}
+
+func (s *stream) peekOffset(offset int) (rune, bool) {
+	pos := s.pos
+	for i := 0; i < offset; i++ {
+		if pos >= len(s.input) {
+			return 0, false
+		}
+		_, size := utf8.DecodeRuneInString(s.input[pos:])
+		pos += size
+	}
+	if pos >= len(s.input) {
+		return 0, false
+	}
+	r, _ := utf8.DecodeRuneInString(s.input[pos:])
+	if r == utf8.RuneError {
+		return 0, false
+	}
+	return r, true
+}
+
+func (s *stream) advance() {
+	if _, ok := s.peek(); ok {
+		_, size := utf8.DecodeRuneInString(s.input[s.pos:])
+		s.pos += size
+	}
+}
+
+func (s *stream) collectWhile(cond func(rune) bool) string {
+	start := s.pos
+	for {
+		r, ok := s.peek()
+		if !ok || !cond(r) {
+			break
+		}
+		s.advance()
+	}
+	return s.input[start:s.pos]
+}
+
+func isQuote(r rune) bool {
+	return r == '\'' || r == '"' || r == '`'
+}
+
+func lexDiff(s *stream, add func(uint64), directive func(Directive)) error {
+	for {
+		r, ok := s.peek()
+		if !ok {
+			return nil
+		}
+
+		var err error
+		switch {
+		case r == '[':
+			if next, ok := s.peekOffset(1); ok && next == '[' {
+				err = lexHashedValue(s, add)
+			} else {
+				s.advance()
+			}
+		case r == '{':
+			err = lexBracedQML(s, add)
+		case r == ';':
+			s.collectWhile(func(r rune) bool { return r != '\n' })
+		case isQuote(r):
+			err = lexString(s)
+		case unicode.IsLetter(r) || r == '_':
+			ident := s.collectWhile(func(r rune) bool {
+				return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+			})
+			switch ident {
+			case "STREAM":
+				s.collectWhile(func(r rune) bool { return unicode.IsSpace(r) && r != '\n' })
+				err = lexStreamQML(s, add)
+			case "AFFECT", "LOAD":
+				if d, ok := peekDirective(*s, ident); ok {
+					directive(d)
+				}
+			}
+		default:
+			s.advance()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// peekDirective reads the argument of a directive from a copy of the
+// stream, leaving the caller's position untouched.
+func peekDirective(s stream, keyword string) (Directive, bool) {
+	s.collectWhile(func(r rune) bool { return r == ' ' || r == '\t' })
+
+	if r, ok := s.peek(); ok && r == '[' {
+		if next, ok := s.peekOffset(1); ok && next == '[' {
+			s.advance()
+			s.advance()
+			if r, ok := s.peek(); ok && isQuote(r) {
+				s.advance()
+			}
+			digits := s.collectWhile(unicode.IsDigit)
+			hash, err := strconv.ParseUint(digits, 10, 64)
+			if err != nil {
+				return Directive{}, false
+			}
+			return Directive{Keyword: keyword, Hash: hash}, true
+		}
+	}
+
+	target := s.collectWhile(func(r rune) bool {
+		return !unicode.IsSpace(r) && r != ';' && r != '{'
+	})
+	if target == "" {
+		return Directive{}, false
+	}
+	return Directive{Keyword: keyword, Target: target}, true
+}
+
+func lexHashedValue(s *stream, add func(uint64)) error {
+	s.advance()
+	s.advance()
+
+	if r, ok := s.peek(); ok && isQuote(r) {
+		s.advance()
+	}
+
+	digits := s.collectWhile(unicode.IsDigit)
+	if digits == "" {
+		return fmt.Errorf("invalid hash: no digits found")
+	}
+
+	if r, ok := s.peek(); !ok || r != ']' {
+		return fmt.Errorf("invalid hash: expected ']'")
+	}
+	s.advance()
+	if r, ok := s.peek(); !ok || r != ']' {
+		return fmt.Errorf("invalid hash: expected second ']'")
+	}
+	s.advance()
+
+	hash, err := strconv.ParseUint(digits, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid hash value: %s", digits)
+	}
+	add(hash)
+	return nil
+}
+
+func lexBracedQML(s *stream, add func(uint64)) error {
+	s.advance()
+	start := s.pos
+
+	for depth := 1; depth > 0; {
+		r, ok := s.peek()
+		if !ok {
+			return fmt.Errorf("unterminated QML block")
+		}
+		if r == '{' {
+			depth++
+		} else if r == '}' {
+			depth--
+		}
+		s.advance()
+	}
+
+	if err := lexQML(&stream{input: s.input[start : s.pos-1]}, add); err != nil {
+		return fmt.Errorf("failed to lex QML code: %w", err)
+	}
+	return nil
+}
+
+func lexStreamQML(s *stream, add func(uint64)) error {
+	start := s.pos
+
+	delim, ok := s.peek()
+	if !ok {
+		return fmt.Errorf("expected delimiter after STREAM")
+	}
+	s.advance()
+
+	for {
+		r, ok := s.peek()
+		if !ok {
+			return fmt.Errorf("unterminated STREAM block")
+		}
+		if r == delim {
+			end := s.pos
+			s.advance()
+			if err := lexQML(&stream{input: s.input[start+1 : end]}, add); err != nil {
+				return fmt.Errorf("failed to lex STREAM QML code: %w", err)
+			}
+			return nil
+		}
+		s.advance()
+	}
+}
+
+func lexString(s *stream) error {
+	quote, _ := s.peek()
+	s.advance()
+
+	for {
+		r, ok := s.peek()
+		if !ok {
+			return fmt.Errorf("unterminated string")
+		}
+		s.advance()
+		if r == quote {
+			return nil
+		}
+		if r == '\\' {
+			s.advance()
+		}
+	}
+}
+
+// lexQML only needs to recognise the tokens that can contain other tokens'
+// start characters (strings and comments) and the hash extensions; every
+// other QML token is skipped one rune at a time, which the server's lexer
+// is equivalent to for hash extraction.
+func lexQML(s *stream, add func(uint64)) error {
+	for {
+		r, ok := s.peek()
+		if !ok {
+			return nil
+		}
+
+		var err error
+		next, hasNext := s.peekOffset(1)
+		switch {
+		case r == '~' && hasNext && next == '&':
+			err = lexHashExtension(s, add)
+		case isQuote(r):
+			err = lexString(s)
+		case r == '/' && hasNext && (next == '/' || next == '*'):
+			err = lexComment(s)
+		default:
+			s.advance()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func lexHashExtension(s *stream, add func(uint64)) error {
+	s.advance()
+	s.advance()
+
+	if r, ok := s.peek(); ok && isQuote(r) {
+		s.advance()
+	}
+
+	start := s.pos
+	for {
+		r, ok := s.peek()
+		if !ok {
+			return fmt.Errorf("unexpected end of input in hash extension")
+		}
+		if next, hasNext := s.peekOffset(1); r == '&' && hasNext && next == '~' {
+			break
+		}
+		s.advance()
+	}
+	digits := s.input[start:s.pos]
+	s.advance()
+	s.advance()
+
+	hash, err := strconv.ParseUint(digits, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid hash value: %s", digits)
+	}
+	add(hash)
+	return nil
+}
+
+func lexComment(s *stream) error {
+	s.advance()
+	second, _ := s.peek()
+	s.advance()
+
+	if second == '/' {
+		s.collectWhile(func(r rune) bool { return r != '\n' })
+		return nil
+	}
+
+	for {
+		r, ok := s.peek()
+		if !ok {
+			return fmt.Errorf("unterminated comment")
+		}
+		s.advance()
+		if r == '*' {
+			if next, ok := s.peek(); ok && next == '/' {
+				s.advance()
+				return nil
+			}
+		}
+	}
+}
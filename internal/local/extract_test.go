@@ -0,0 +1,98 @@
+package local
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestExtractHashes(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []uint64
+		wantErr string
+	}{
+		{
+			name:    "diff hashes",
+			content: "AFFECT /SomeFile.qml\n\tTRAVERSE Root [[12345678901234567890]]\n\tTRAVERSE Child [[9876543210987654321]]\n",
+			want:    []uint64{9876543210987654321, 12345678901234567890},
+		},
+		{
+			name:    "quoted diff hashes",
+			content: "TRAVERSE Root [['12345678901234567890]]\nTRAVERSE Child [[\"9876543210987654321]]\n",
+			want:    []uint64{9876543210987654321, 12345678901234567890},
+		},
+		{
+			name:    "QML hashes",
+			content: "INSERT {\n\tid: ~&12345678901234567890&~\n\ttext: ~&\"9876543210987654321&~\n}\n",
+			want:    []uint64{9876543210987654321, 12345678901234567890},
+		},
+		{
+			name:    "STREAM block",
+			content: "INSERT STREAM |id: ~&42&~|\n",
+			want:    []uint64{42},
+		},
+		{
+			name:    "JavaScript arrays are not hashes",
+			content: "INSERT {\n\tvar result = array[[5]];\n\tvar nested = matrix[[0]][[1]];\n}\n",
+		},
+		{
+			name:    "hashes in strings and comments are ignored",
+			content: "; [[1]]\nINSERT {\n\t// ~&2&~\n\t/* ~&3&~ */\n\ttext: \"~&4&~\"\n}\n\"[[5]]\"\n",
+		},
+		{
+			name:    "duplicates are reported once",
+			content: "[[7]] [[7]] {~&7&~}",
+			want:    []uint64{7},
+		},
+		{name: "empty", content: ""},
+		{name: "no digits", content: "[[abc]]", wantErr: "invalid hash: no digits found"},
+		{name: "unclosed hash", content: "[[12]", wantErr: "invalid hash: expected second ']'"},
+		{name: "unterminated block", content: "INSERT { id: 1", wantErr: "unterminated QML block"},
+		{name: "unterminated string", content: "'abc", wantErr: "unterminated string"},
+		{name: "bad extension", content: "{~&12x&~}", wantErr: "failed to lex QML code: invalid hash value: 12x"},
+		{name: "unterminated comment", content: "{/* abc}", wantErr: "failed to lex QML code: unterminated comment"},
+		{name: "unterminated STREAM", content: "STREAM |abc", wantErr: "unterminated STREAM block"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExtractHashes(tt.content)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			sort.Slice(got, func(i, j int) bool { return got[i] < got[j] })
+			if len(got) != 0 || len(tt.want) != 0 {
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseDirectives(t *testing.T) {
+	parsed, err := Parse("LOAD ../shared.qmd\nAFFECT [[100]]\nAFFECT /qml/Main.qml\n{ AFFECT x }\nAFFECT\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Directive{
+		{Keyword: "LOAD", Target: "../shared.qmd"},
+		{Keyword: "AFFECT", Hash: 100},
+		{Keyword: "AFFECT", Target: "/qml/Main.qml"},
+	}
+	if !reflect.DeepEqual(parsed.Directives, want) {
+		t.Errorf("directives = %+v, want %+v", parsed.Directives, want)
+	}
+	if !reflect.DeepEqual(parsed.Hashes, []uint64{100}) {
+		t.Errorf("hashes = %v", parsed.Hashes)
+	}
+}
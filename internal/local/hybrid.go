@@ -0,0 +1,77 @@
+package local
+
+import (
+	"context"
+
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+// Remote is the server-side half of a hybrid check.
+type Remote interface {
+	CompareQMD(ctx context.Context, filePath string) (*api.ComparisonResponse, error)
+	CompareQMDFiles(ctx context.Context, filePaths []string, relativePaths []string) (*api.BatchComparisonResponse, error)
+}
+
+// Hybrid checks files against the local hashtables first. Files that fail
+// every local hashtable are reported from the local results without being
+// uploaded; the rest go to the server for its deeper tree-based validation,
+// and are reported from its results, which also cover versions the local
+// hashtables may lack.
+type Hybrid struct {
+	Local  *Engine
+	Remote Remote
+}
+
+func (h *Hybrid) CompareQMD(ctx context.Context, filePath string) (*api.ComparisonResponse, error) {
+	response, err := h.Local.CompareQMD(ctx, filePath)
+	if err != nil {
+		return nil, err
+	}
+	if failsEverywhere(response) {
+		return response, nil
+	}
+	return h.Remote.CompareQMD(ctx, filePath)
+}
+
+func (h *Hybrid) CompareQMDFiles(ctx context.Context, filePaths []string, relativePaths []string) (*api.BatchComparisonResponse, error) {
+	batch, err := h.Local.CompareQMDFiles(ctx, filePaths, relativePaths)
+	if err != nil {
+		return nil, err
+	}
+
+	var passedPaths, passedRelative []string
+	for i, name := range relativePaths {
+		if response := (*batch)[name]; !failsEverywhere(&response) {
+			passedPaths = append(passedPaths, filePaths[i])
+			passedRelative = append(passedRelative, name)
+			delete(*batch, name)
+		}
+	}
+
+	switch len(passedPaths) {
+	case 0:
+		return batch, nil
+	case 1:
+		response, err := h.Remote.CompareQMD(ctx, passedPaths[0])
+		if err != nil {
+			return nil, err
+		}
+		(*batch)[passedRelative[0]] = *response
+	default:
+		remote, err := h.Remote.CompareQMDFiles(ctx, passedPaths, passedRelative)
+		if err != nil {
+			return nil, err
+		}
+		for name, response := range *remote {
+			(*batch)[name] = response
+		}
+	}
+
+	return batch, nil
+}
+
+// failsEverywhere reports whether a local result is incompatible with every
+// hashtable, so the server has nothing to add.
+func failsEverywhere(response *api.ComparisonResponse) bool {
+	return len(response.Compatible) == 0 && len(response.Incompatible) > 0
+}
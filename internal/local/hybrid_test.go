@@ -0,0 +1,119 @@
+package local
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+type fakeRemote struct {
+	uploaded []string
+}
+
+func (f *fakeRemote) CompareQMD(_ context.Context, filePath string) (*api.ComparisonResponse, error) {
+	f.uploaded = append(f.uploaded, filepath.Base(filePath))
+	return &api.ComparisonResponse{Mode: "tree", TotalChecked: 1}, nil
+}
+
+func (f *fakeRemote) CompareQMDFiles(_ context.Context, filePaths []string, relativePaths []string) (*api.BatchComparisonResponse, error) {
+	batch := make(api.BatchComparisonResponse)
+	for i, path := range filePaths {
+		f.uploaded = append(f.uploaded, filepath.Base(path))
+		batch[relativePaths[i]] = api.ComparisonResponse{Mode: "tree", TotalChecked: 1}
+	}
+	return &batch, nil
+}
+
+func TestHybrid(t *testing.T) {
+	dir := t.TempDir()
+	writeHashtables(t, dir, map[string][]uint64{"3.22.0.64-rmpp": {1}, "3.23.0.10-rmpp": {1, 3}})
+	engine, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := t.TempDir()
+	contents := map[string]string{"pass1.qmd": "[[1]]", "pass2.qmd": "[[1]]", "fail.qmd": "[[2]]", "partial.qmd": "[[3]]"}
+	var paths, names []string
+	for _, name := range []string{"pass1.qmd", "pass2.qmd", "fail.qmd", "partial.qmd"} {
+		path := filepath.Join(files, name)
+		if err := os.WriteFile(path, []byte(contents[name]), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+		names = append(names, name)
+	}
+
+	tests := []struct {
+		name         string
+		paths, names []string
+		wantUploaded []string
+	}{
+		{"batch", paths, names, []string{"partial.qmd", "pass1.qmd", "pass2.qmd"}},
+		{"one passing", paths[1:3], names[1:3], []string{"pass2.qmd"}},
+		{"partly compatible", paths[2:], names[2:], []string{"partial.qmd"}},
+		{"all failing", paths[2:3], names[2:3], nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remote := &fakeRemote{}
+			h := &Hybrid{Local: engine, Remote: remote}
+
+			batch, err := h.CompareQMDFiles(context.Background(), tt.paths, tt.names)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sort.Strings(remote.uploaded)
+			if !reflect.DeepEqual(remote.uploaded, tt.wantUploaded) {
+				t.Errorf("uploaded %v, want %v", remote.uploaded, tt.wantUploaded)
+			}
+			if len(*batch) != len(tt.names) {
+				t.Errorf("batch has %d files, want %d", len(*batch), len(tt.names))
+			}
+			for name, response := range *batch {
+				wantMode := "tree"
+				if name == "fail.qmd" {
+					wantMode = ModeHashtable
+				}
+				if response.Mode != wantMode {
+					t.Errorf("%s mode = %q, want %q", name, response.Mode, wantMode)
+				}
+			}
+		})
+	}
+
+	t.Run("single file", func(t *testing.T) {
+		remote := &fakeRemote{}
+		h := &Hybrid{Local: engine, Remote: remote}
+
+		response, err := h.CompareQMD(context.Background(), paths[2])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.Mode != ModeHashtable || len(remote.uploaded) != 0 {
+			t.Errorf("failing file: mode %q, uploaded %v", response.Mode, remote.uploaded)
+		}
+
+		response, err = h.CompareQMD(context.Background(), paths[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.Mode != "tree" || len(remote.uploaded) != 1 {
+			t.Errorf("passing file: mode %q, uploaded %v", response.Mode, remote.uploaded)
+		}
+
+		response, err = h.CompareQMD(context.Background(), paths[3])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.Mode != "tree" || len(remote.uploaded) != 2 {
+			t.Errorf("partly compatible file: mode %q, uploaded %v", response.Mode, remote.uploaded)
+		}
+	})
+}
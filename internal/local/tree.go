@@ -0,0 +1,160 @@
+package local
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/tree"
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+	"github.com/rmitchellscott/rm-qmd-verify/pkg/hashtab"
+)
+
+// qmdFile is a root file or one of the files it LOADs, keyed by its path
+// relative to the batch.
+type qmdFile struct {
+	rel    string
+	parsed *Parsed
+	err    error
+}
+
+// compareTree validates the file and everything it LOADs against the QML
+// tree of each hashtable's OS version and device: every hash must be in the
+// hashtable and every AFFECTed file must exist in the tree. Hashtables
+// without a downloaded tree fall back to hashtable mode.
+func (e *Engine) compareTree(filePath, relativePath string, content []byte) *api.ComparisonResponse {
+	response := &api.ComparisonResponse{
+		Compatible:   make([]api.ComparisonResult, 0),
+		Incompatible: make([]api.ComparisonResult, 0),
+		Mode:         ModeTree,
+	}
+
+	files := loadDependencies(filePath, relativePath, content)
+	root := files[0]
+
+	for _, ht := range e.Hashtables {
+		tr, ok := e.Trees[tree.Name(ht.OSVersion, ht.Device)]
+		if !ok {
+			var hashes []uint64
+			if root.parsed != nil {
+				hashes = root.parsed.Hashes
+			}
+			addResult(response, hashtableResult(ht, hashes, root.err))
+			continue
+		}
+		addResult(response, treeResult(ht, tr, files))
+	}
+	response.TotalChecked = len(e.Hashtables)
+
+	return response
+}
+
+// loadDependencies parses the root file and, transitively, every file it
+// LOADs. The root file comes first.
+func loadDependencies(filePath, relativePath string, content []byte) []*qmdFile {
+	parsed, err := Parse(string(content))
+	files := []*qmdFile{{rel: relativePath, parsed: parsed, err: err}}
+	paths := []string{filePath}
+	seen := map[string]bool{relativePath: true}
+
+	for i := 0; i < len(files); i++ {
+		if files[i].parsed == nil {
+			continue
+		}
+		for _, d := range files[i].parsed.Directives {
+			if d.Keyword != "LOAD" || d.Target == "" {
+				continue
+			}
+			rel := path.Join(path.Dir(files[i].rel), filepath.ToSlash(d.Target))
+			if seen[rel] {
+				continue
+			}
+			seen[rel] = true
+
+			dep := &qmdFile{rel: rel}
+			depPath := filepath.Join(filepath.Dir(paths[i]), filepath.FromSlash(d.Target))
+			if data, err := os.ReadFile(depPath); err != nil {
+				dep.err = fmt.Errorf("failed to load dependency: %w", err)
+			} else {
+				dep.parsed, dep.err = Parse(string(data))
+			}
+			files = append(files, dep)
+			paths = append(paths, depPath)
+		}
+	}
+
+	return files
+}
+
+func treeResult(ht *hashtab.Hashtab, tr *tree.Tree, files []*qmdFile) api.ComparisonResult {
+	result := api.ComparisonResult{
+		Hashtable:          ht.Name,
+		OSVersion:          ht.OSVersion,
+		Device:             ht.Device,
+		Compatible:         true,
+		ValidationMode:     ModeTree,
+		TreeValidationUsed: true,
+		DependencyResults:  make(map[string]*api.ValidationResult),
+	}
+
+	errorCount := 0
+	for _, f := range files {
+		v := validateFile(ht, tr, f, &result)
+		if len(v.HashErrors) > 0 {
+			v.Status = StatusIncompatible
+			if result.Compatible {
+				result.ErrorDetail = fmt.Sprintf("%s: %s", f.rel, v.HashErrors[0].Error)
+			}
+			result.Compatible = false
+			errorCount += len(v.HashErrors)
+		}
+		result.DependencyResults[f.rel] = v
+	}
+	result.FilesModified = result.FilesProcessed - result.FilesWithErrors
+
+	if errorCount > 1 {
+		result.ErrorDetail += fmt.Sprintf(" (and %d more)", errorCount-1)
+	}
+	return result
+}
+
+func validateFile(ht *hashtab.Hashtab, tr *tree.Tree, f *qmdFile, result *api.ComparisonResult) *api.ValidationResult {
+	v := &api.ValidationResult{Status: StatusCompatible}
+	if f.err != nil {
+		v.HashErrors = append(v.HashErrors, api.HashError{Error: fmt.Sprintf("verification failed: %v", f.err)})
+		return v
+	}
+
+	for _, hash := range f.parsed.Hashes {
+		if _, ok := ht.Entries[hash]; !ok {
+			v.HashErrors = append(v.HashErrors, api.HashError{HashID: hash, Error: "hash not found in hashtable"})
+		}
+	}
+
+	for _, d := range f.parsed.Directives {
+		if d.Keyword != "AFFECT" {
+			continue
+		}
+		target := d.Target
+		if target == "" {
+			resolved, ok := ht.Entries[d.Hash]
+			if !ok {
+				continue
+			}
+			if resolved == "" {
+				v.HashErrors = append(v.HashErrors, api.HashError{HashID: d.Hash, Error: "cannot resolve AFFECT path: hashtable has no strings"})
+				continue
+			}
+			target = resolved
+		}
+
+		result.FilesProcessed++
+		if !tr.Has(target) {
+			result.FilesWithErrors++
+			v.HashErrors = append(v.HashErrors, api.HashError{HashID: d.Hash, Error: fmt.Sprintf("%s not found in QML tree", target)})
+		}
+	}
+
+	return v
+}
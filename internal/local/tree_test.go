@@ -0,0 +1,145 @@
+package local
+
+import (
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeHashtab writes a hashtab with strings, which hashlists lack.
+func writeHashtab(t *testing.T, path string, entries map[uint64]string) {
+	t.Helper()
+	var data []byte
+	for hash, str := range entries {
+		data = binary.BigEndian.AppendUint64(data, hash)
+		data = binary.BigEndian.AppendUint32(data, uint32(len(str)))
+		data = append(data, str...)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		dest := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(dest, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCompareTree(t *testing.T) {
+	hashtables := t.TempDir()
+	writeHashtab(t, filepath.Join(hashtables, "3.22.4.2-rmpp"), map[uint64]string{100: "/qml/Main.qml", 1: "id"})
+	writeHashtab(t, filepath.Join(hashtables, "3.20.0.92-rmpp"), map[uint64]string{100: "/qml/Main.qml", 1: "id"})
+	writeHashtab(t, filepath.Join(hashtables, "3.22.4.2-rm2"), map[uint64]string{1: "id"})
+
+	trees := t.TempDir()
+	writeFiles(t, trees, map[string]string{
+		"3.22.4.2-rmpp/qml/Main.qml":   "Item {}",
+		"3.20.0.92-rmpp/qml/Other.qml": "Item {}",
+	})
+
+	engine, err := Load(hashtables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.LoadTrees(trees); err != nil {
+		t.Fatal(err)
+	}
+
+	files := t.TempDir()
+	writeFiles(t, files, map[string]string{
+		"root.qmd":       "LOAD lib/shared.qmd\nAFFECT [[100]]\nTRAVERSE [[1]]\n",
+		"lib/shared.qmd": "AFFECT /qml/Main.qml\n",
+	})
+
+	response, err := engine.CompareQMD(context.Background(), filepath.Join(files, "root.qmd"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Mode != ModeTree || response.TotalChecked != 3 {
+		t.Fatalf("response = %+v", response)
+	}
+
+	if len(response.Compatible) != 1 || response.Compatible[0].Hashtable != "3.22.4.2-rmpp" {
+		t.Fatalf("compatible = %+v", response.Compatible)
+	}
+	ok := response.Compatible[0]
+	if !ok.TreeValidationUsed || ok.FilesProcessed != 2 || len(ok.DependencyResults) != 2 || ok.DependencyResults["lib/shared.qmd"].Status != StatusCompatible {
+		t.Errorf("tree result = %+v", ok)
+	}
+
+	for _, r := range response.Incompatible {
+		switch r.Hashtable {
+		case "3.20.0.92-rmpp":
+			if !r.TreeValidationUsed || !strings.Contains(r.ErrorDetail, "/qml/Main.qml not found in QML tree") || r.FilesWithErrors != 2 {
+				t.Errorf("missing file result = %+v", r)
+			}
+		case "3.22.4.2-rm2":
+			if r.TreeValidationUsed || r.ValidationMode != ModeHashtable || r.ErrorDetail != "missing 1 hash(es)" {
+				t.Errorf("fallback result = %+v", r)
+			}
+		default:
+			t.Errorf("unexpected result %+v", r)
+		}
+	}
+}
+
+func TestCompareTreeDependencies(t *testing.T) {
+	hashtables := t.TempDir()
+	writeHashtab(t, filepath.Join(hashtables, "3.22.4.2-rmpp"), map[uint64]string{1: "id"})
+	trees := t.TempDir()
+	writeFiles(t, trees, map[string]string{"3.22.4.2-rmpp/qml/Main.qml": ""})
+
+	engine, err := Load(hashtables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.LoadTrees(trees); err != nil {
+		t.Fatal(err)
+	}
+
+	files := t.TempDir()
+	writeFiles(t, files, map[string]string{
+		"a.qmd":     "LOAD b.qmd\nLOAD missing.qmd\n",
+		"b.qmd":     "LOAD a.qmd\n[[2]]\n",
+		"other.qmd": "[[1]]",
+	})
+
+	batch, err := engine.CompareQMDFiles(context.Background(),
+		[]string{filepath.Join(files, "a.qmd"), filepath.Join(files, "b.qmd"), filepath.Join(files, "other.qmd")},
+		[]string{"a.qmd", "b.qmd", "other.qmd"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := (*batch)["a.qmd"]
+	if len(a.Incompatible) != 1 {
+		t.Fatalf("a.qmd = %+v", a)
+	}
+	deps := a.Incompatible[0].DependencyResults
+	if len(deps) != 3 || deps["b.qmd"].Status != StatusIncompatible || deps["b.qmd"].HashErrors[0].HashID != 2 ||
+		deps["missing.qmd"].Status != StatusIncompatible || deps["a.qmd"].Status != StatusCompatible {
+		t.Errorf("dependency results = %+v", deps)
+	}
+	if len((*batch)["other.qmd"].Compatible) != 1 {
+		t.Errorf("other.qmd = %+v", (*batch)["other.qmd"])
+	}
+}
+
+func TestLoadTreesEmpty(t *testing.T) {
+	engine := &Engine{}
+	if err := engine.LoadTrees(t.TempDir()); err == nil || !strings.Contains(err.Error(), "no QML trees") {
+		t.Errorf("err = %v, want no QML trees", err)
+	}
+}
@@ -0,0 +1,200 @@
+package mirror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+// Conflict records servers that disagree about one device/OS version pair.
+type Conflict struct {
+	File         string
+	Device       string
+	OSVersion    string
+	Compatible   []string
+	Incompatible []string
+}
+
+// Set checks files against several servers and merges their results, so
+// regional mirrors that carry different hashtables give complete coverage.
+// The first client is the primary server.
+type Set struct {
+	Clients []*api.Client
+
+	// OnError is called for each server that fails while at least one other
+	// server succeeds. The error names the server.
+	OnError func(err error)
+	// OnConflict is called for each pair the servers disagree on.
+	OnConflict func(Conflict)
+}
+
+func (s *Set) CompareQMD(ctx context.Context, filePath string) (*api.ComparisonResponse, error) {
+	var servers []string
+	var responses []*api.ComparisonResponse
+	var errs []error
+
+	for _, client := range s.Clients {
+		response, err := client.CompareQMD(ctx, filePath)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", client.Address(), err))
+			continue
+		}
+		servers = append(servers, client.Address())
+		responses = append(responses, response)
+	}
+
+	if err := s.settle(len(responses), errs); err != nil {
+		return nil, err
+	}
+
+	merged, conflicts := Merge(servers, responses)
+	s.report("", conflicts)
+	return merged, nil
+}
+
+func (s *Set) CompareQMDFiles(ctx context.Context, filePaths []string, relativePaths []string) (*api.BatchComparisonResponse, error) {
+	var servers []string
+	var batches []*api.BatchComparisonResponse
+	var errs []error
+
+	for _, client := range s.Clients {
+		batch, err := client.CompareQMDFiles(ctx, filePaths, relativePaths)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", client.Address(), err))
+			continue
+		}
+		servers = append(servers, client.Address())
+		batches = append(batches, batch)
+	}
+
+	if err := s.settle(len(batches), errs); err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]bool)
+	for _, batch := range batches {
+		for file := range *batch {
+			files[file] = true
+		}
+	}
+
+	merged := make(api.BatchComparisonResponse)
+	for _, file := range sortedKeys(files) {
+		var fileServers []string
+		var responses []*api.ComparisonResponse
+		for i, batch := range batches {
+			if response, ok := (*batch)[file]; ok {
+				fileServers = append(fileServers, servers[i])
+				responses = append(responses, &response)
+			}
+		}
+
+		response, conflicts := Merge(fileServers, responses)
+		s.report(file, conflicts)
+		merged[file] = *response
+	}
+
+	return &merged, nil
+}
+
+// settle fails only when every server failed, reporting the others.
+func (s *Set) settle(succeeded int, errs []error) error {
+	if succeeded == 0 {
+		return errors.Join(errs...)
+	}
+	if s.OnError != nil {
+		for _, err := range errs {
+			s.OnError(err)
+		}
+	}
+	return nil
+}
+
+func (s *Set) report(file string, conflicts []Conflict) {
+	if s.OnConflict == nil {
+		return
+	}
+	for _, conflict := range conflicts {
+		conflict.File = file
+		s.OnConflict(conflict)
+	}
+}
+
+// Merge unions the results from several servers. Where servers disagree on
+// a device/OS version pair, the majority wins and a tie counts as
+// incompatible; every disagreement is returned as a conflict.
+func Merge(servers []string, responses []*api.ComparisonResponse) (*api.ComparisonResponse, []Conflict) {
+	type key struct{ device, version string }
+	type votes struct {
+		compatible, incompatible             []string
+		compatibleResult, incompatibleResult api.ComparisonResult
+	}
+
+	tally := make(map[key]*votes)
+	var order []key
+	for i, response := range responses {
+		for _, results := range [][]api.ComparisonResult{response.Compatible, response.Incompatible} {
+			for _, result := range results {
+				k := key{result.Device, result.OSVersion}
+				v, ok := tally[k]
+				if !ok {
+					v = &votes{}
+					tally[k] = v
+					order = append(order, k)
+				}
+				if result.Compatible {
+					if len(v.compatible) == 0 {
+						v.compatibleResult = result
+					}
+					v.compatible = append(v.compatible, servers[i])
+				} else {
+					if len(v.incompatible) == 0 {
+						v.incompatibleResult = result
+					}
+					v.incompatible = append(v.incompatible, servers[i])
+				}
+			}
+		}
+	}
+
+	merged := &api.ComparisonResponse{
+		Compatible:   make([]api.ComparisonResult, 0),
+		Incompatible: make([]api.ComparisonResult, 0),
+	}
+	if len(responses) > 0 {
+		merged.Mode = responses[0].Mode
+	}
+
+	var conflicts []Conflict
+	for _, k := range order {
+		v := tally[k]
+		if len(v.compatible) > len(v.incompatible) {
+			merged.Compatible = append(merged.Compatible, v.compatibleResult)
+		} else {
+			merged.Incompatible = append(merged.Incompatible, v.incompatibleResult)
+		}
+		if len(v.compatible) > 0 && len(v.incompatible) > 0 {
+			conflicts = append(conflicts, Conflict{
+				Device:       k.device,
+				OSVersion:    k.version,
+				Compatible:   v.compatible,
+				Incompatible: v.incompatible,
+			})
+		}
+	}
+	merged.TotalChecked = len(merged.Compatible) + len(merged.Incompatible)
+
+	return merged, conflicts
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,121 @@
+package mirror
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+func result(device, version string, compatible bool) api.ComparisonResult {
+	return api.ComparisonResult{Device: device, OSVersion: version, Compatible: compatible}
+}
+
+func TestMerge(t *testing.T) {
+	a := &api.ComparisonResponse{
+		Compatible:   []api.ComparisonResult{result("rmpp", "3.22.0.64", true), result("rm2", "3.22.0.64", true)},
+		Incompatible: []api.ComparisonResult{result("rm1", "3.20.0.92", false)},
+	}
+	b := &api.ComparisonResponse{
+		Compatible:   []api.ComparisonResult{result("rmpp", "3.22.0.64", true), result("rmppm", "3.22.4.2", true)},
+		Incompatible: []api.ComparisonResult{result("rm2", "3.22.0.64", false)},
+	}
+	c := &api.ComparisonResponse{
+		Compatible: []api.ComparisonResult{result("rm2", "3.22.0.64", true)},
+	}
+
+	t.Run("union with tie", func(t *testing.T) {
+		merged, conflicts := Merge([]string{"a", "b"}, []*api.ComparisonResponse{a, b})
+
+		if merged.TotalChecked != 4 {
+			t.Errorf("TotalChecked = %d, want 4", merged.TotalChecked)
+		}
+		wantIncompatible := []api.ComparisonResult{result("rm2", "3.22.0.64", false), result("rm1", "3.20.0.92", false)}
+		if !reflect.DeepEqual(merged.Incompatible, wantIncompatible) {
+			t.Errorf("Incompatible = %+v, want %+v (a tie counts as incompatible)", merged.Incompatible, wantIncompatible)
+		}
+
+		wantConflicts := []Conflict{{Device: "rm2", OSVersion: "3.22.0.64", Compatible: []string{"a"}, Incompatible: []string{"b"}}}
+		if !reflect.DeepEqual(conflicts, wantConflicts) {
+			t.Errorf("conflicts = %+v, want %+v", conflicts, wantConflicts)
+		}
+	})
+
+	t.Run("majority wins", func(t *testing.T) {
+		merged, conflicts := Merge([]string{"a", "b", "c"}, []*api.ComparisonResponse{a, b, c})
+
+		for _, r := range merged.Incompatible {
+			if r.Device == "rm2" {
+				t.Errorf("rm2 3.22.0.64 should be compatible by majority, got %+v", merged.Incompatible)
+			}
+		}
+		if len(conflicts) != 1 || !reflect.DeepEqual(conflicts[0].Compatible, []string{"a", "c"}) {
+			t.Errorf("conflicts = %+v", conflicts)
+		}
+	})
+
+	t.Run("agreement", func(t *testing.T) {
+		_, conflicts := Merge([]string{"a", "a2"}, []*api.ComparisonResponse{a, a})
+		if len(conflicts) != 0 {
+			t.Errorf("conflicts = %+v, want none", conflicts)
+		}
+	})
+}
+
+func newServer(t *testing.T, response api.ComparisonResponse) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/compare":
+			json.NewEncoder(w).Encode(api.CompareJobResponse{JobID: "job"})
+		case "/api/results/job":
+			json.NewEncoder(w).Encode(response)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestSet_CompareQMD(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.qmd")
+	if err := os.WriteFile(path, []byte("content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	primary := newServer(t, api.ComparisonResponse{Compatible: []api.ComparisonResult{result("rmpp", "3.22.0.64", true)}})
+	regional := newServer(t, api.ComparisonResponse{Incompatible: []api.ComparisonResult{result("rm2", "3.20.0.92", false)}})
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer broken.Close()
+
+	var skipped []error
+	set := &Set{
+		Clients: []*api.Client{api.NewClient(primary.URL), api.NewClient(regional.URL), api.NewClient(broken.URL)},
+		OnError: func(err error) { skipped = append(skipped, err) },
+	}
+
+	merged, err := set.CompareQMD(context.Background(), path)
+	if err != nil {
+		t.Fatalf("CompareQMD() error = %v", err)
+	}
+	if merged.TotalChecked != 2 || len(merged.Compatible) != 1 || len(merged.Incompatible) != 1 {
+		t.Errorf("CompareQMD() = %+v, want coverage from both healthy servers", merged)
+	}
+	if len(skipped) != 1 {
+		t.Errorf("OnError called %d times, want 1", len(skipped))
+	}
+
+	set = &Set{Clients: []*api.Client{api.NewClient(broken.URL)}}
+	if _, err := set.CompareQMD(context.Background(), path); err == nil {
+		t.Error("CompareQMD() expected error when every server fails")
+	}
+}
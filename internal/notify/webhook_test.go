@@ -0,0 +1,43 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPostWebhook(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		var received map[string]string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "POST" {
+				t.Errorf("Expected POST request, got %s", r.Method)
+			}
+			if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %v, want application/json", ct)
+			}
+			json.NewDecoder(r.Body).Decode(&received)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		if err := PostWebhook(server.URL, map[string]string{"event": "new_hashtables"}); err != nil {
+			t.Fatalf("PostWebhook() error = %v", err)
+		}
+		if received["event"] != "new_hashtables" {
+			t.Errorf("webhook payload = %v", received)
+		}
+	})
+
+	t.Run("non-2xx status", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		if err := PostWebhook(server.URL, map[string]string{}); err == nil {
+			t.Error("PostWebhook() expected error for 500 response")
+		}
+	})
+}
@@ -0,0 +1,32 @@
+package osversion
+
+import (
+	"fmt"
+	"strings"
+)
+
+func Compare(v1, v2 string) int {
+	p1 := strings.Split(v1, ".")
+	p2 := strings.Split(v2, ".")
+
+	maxLen := len(p1)
+	if len(p2) > maxLen {
+		maxLen = len(p2)
+	}
+
+	for i := 0; i < maxLen; i++ {
+		var n1, n2 int
+		if i < len(p1) {
+			fmt.Sscanf(p1[i], "%d", &n1)
+		}
+		if i < len(p2) {
+			fmt.Sscanf(p2[i], "%d", &n2)
+		}
+
+		if n1 != n2 {
+			return n1 - n2
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,89 @@
+package picker
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Scores for a fuzzy match, roughly as in fzf: every matched character
+// scores, runs of consecutive matches and matches at the start of a path
+// segment or word score extra, and skipped characters between matches cost.
+const (
+	scoreMatch       = 1
+	bonusConsecutive = 4
+	bonusBoundary    = 3
+	penaltyGap       = 1
+)
+
+// Score reports whether every character of query appears in text in order,
+// ignoring case, and how well it matches. Higher is better.
+func Score(query, text string) (int, bool) {
+	q := []rune(strings.ToLower(query))
+	if len(q) == 0 {
+		return 0, true
+	}
+
+	t := []rune(strings.ToLower(text))
+	score, qi := 0, 0
+	last := -1
+	for i, r := range t {
+		if qi == len(q) {
+			break
+		}
+		if r != q[qi] {
+			continue
+		}
+		score += scoreMatch
+		switch {
+		case last < 0:
+		case i == last+1:
+			score += bonusConsecutive
+		default:
+			score -= penaltyGap * (i - last - 1)
+		}
+		if i == 0 || isBoundary(t[i-1]) {
+			score += bonusBoundary
+		}
+		last = i
+		qi++
+	}
+	if qi < len(q) {
+		return 0, false
+	}
+	return score, true
+}
+
+func isBoundary(r rune) bool {
+	return r == '/' || r == '\\' || r == '_' || r == '-' || r == '.' || unicode.IsSpace(r)
+}
+
+// Filter returns the indexes of the items matching query, best first. Ties
+// go to the shorter item, then to the earlier one.
+func Filter(query string, items []string) []int {
+	type match struct {
+		index int
+		score int
+	}
+	var matches []match
+	for i, item := range items {
+		if score, ok := Score(query, item); ok {
+			matches = append(matches, match{i, score})
+		}
+	}
+	if query != "" {
+		sort.SliceStable(matches, func(i, j int) bool {
+			a, b := matches[i], matches[j]
+			if a.score != b.score {
+				return a.score > b.score
+			}
+			return len(items[a.index]) < len(items[b.index])
+		})
+	}
+
+	indexes := make([]int, len(matches))
+	for i, m := range matches {
+		indexes[i] = m.index
+	}
+	return indexes
+}
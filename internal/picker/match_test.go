@@ -0,0 +1,49 @@
+package picker
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		query string
+		text  string
+		want  bool
+	}{
+		{query: "", text: "anything", want: true},
+		{query: "bat", text: "overlays/battery.qmd", want: true},
+		{query: "BAT", text: "overlays/battery.qmd", want: true},
+		{query: "obq", text: "overlays/battery.qmd", want: true},
+		{query: "tab", text: "overlays/battery.qmd", want: false},
+		{query: "batteryx", text: "overlays/battery.qmd", want: false},
+	}
+	for _, tt := range tests {
+		if _, got := Score(tt.query, tt.text); got != tt.want {
+			t.Errorf("Score(%q, %q) matched = %v, want %v", tt.query, tt.text, got, tt.want)
+		}
+	}
+
+	boundary, _ := Score("bat", "ui/battery.qmd")
+	scattered, _ := Score("bat", "ui/b_a_t.qmd")
+	if boundary <= scattered {
+		t.Errorf("consecutive match at a segment start scored %d, scattered match %d", boundary, scattered)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	items := []string{"ui/clock.qmd", "ui/battery.qmd", "system/battery-icons.qmd", "ui/b_a_t.qmd"}
+
+	if got := Filter("", items); !reflect.DeepEqual(got, []int{0, 1, 2, 3}) {
+		t.Errorf("Filter(\"\") = %v, want every item in order", got)
+	}
+	if got := Filter("battery", items); !reflect.DeepEqual(got, []int{1, 2}) {
+		t.Errorf("Filter(battery) = %v, want [1 2]", got)
+	}
+	if got := Filter("bat", items); len(got) != 3 || got[2] != 3 {
+		t.Errorf("Filter(bat) = %v, want the scattered match last", got)
+	}
+	if got := Filter("zzz", items); len(got) != 0 {
+		t.Errorf("Filter(zzz) = %v, want no matches", got)
+	}
+}
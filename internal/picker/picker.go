@@ -0,0 +1,312 @@
+// Package picker is a small in-process fuzzy finder for choosing several
+// items in the terminal.
+package picker
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/term"
+)
+
+const DefaultHeight = 12
+
+var (
+	ErrCancelled   = errors.New("selection cancelled")
+	ErrNotTerminal = errors.New("an interactive terminal is required")
+)
+
+var (
+	cursorStyle   = lipgloss.NewStyle().Bold(true)
+	selectedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#00FF00"))
+	countStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("#666666"))
+)
+
+type keyType int
+
+const (
+	keyRune keyType = iota
+	keyEnter
+	keyBackspace
+	keyUp
+	keyDown
+	keyToggle
+	keyToggleAll
+	keyClear
+	keyCancel
+	keyUnknown
+)
+
+type key struct {
+	typ keyType
+	r   rune
+}
+
+// Picker holds the state of a selection: the query, the items matching it
+// and which items are selected.
+type Picker struct {
+	items    []string
+	height   int
+	query    []rune
+	matches  []int
+	cursor   int
+	offset   int
+	selected map[int]bool
+}
+
+func New(items []string, height int) *Picker {
+	if height <= 0 {
+		height = DefaultHeight
+	}
+	p := &Picker{items: items, height: height, selected: make(map[int]bool)}
+	p.filter()
+	return p
+}
+
+func (p *Picker) filter() {
+	p.matches = Filter(string(p.query), p.items)
+	p.cursor, p.offset = 0, 0
+}
+
+// Selected returns the indexes of the selected items in their original
+// order, or the item under the cursor if none were selected.
+func (p *Picker) Selected() []int {
+	var indexes []int
+	for i := range p.selected {
+		indexes = append(indexes, i)
+	}
+	if len(indexes) == 0 && len(p.matches) > 0 {
+		indexes = append(indexes, p.matches[p.cursor])
+	}
+	sort.Ints(indexes)
+	return indexes
+}
+
+// handle applies a key press and reports whether the selection is complete.
+func (p *Picker) handle(k key) (bool, error) {
+	switch k.typ {
+	case keyRune:
+		p.query = append(p.query, k.r)
+		p.filter()
+	case keyBackspace:
+		if len(p.query) > 0 {
+			p.query = p.query[:len(p.query)-1]
+			p.filter()
+		}
+	case keyClear:
+		p.query = nil
+		p.filter()
+	case keyUp:
+		p.move(-1)
+	case keyDown:
+		p.move(1)
+	case keyToggle:
+		if len(p.matches) > 0 {
+			i := p.matches[p.cursor]
+			if p.selected[i] {
+				delete(p.selected, i)
+			} else {
+				p.selected[i] = true
+			}
+			p.move(1)
+		}
+	case keyToggleAll:
+		all := true
+		for _, i := range p.matches {
+			all = all && p.selected[i]
+		}
+		for _, i := range p.matches {
+			if all {
+				delete(p.selected, i)
+			} else {
+				p.selected[i] = true
+			}
+		}
+	case keyEnter:
+		if len(p.Selected()) == 0 {
+			return false, nil
+		}
+		return true, nil
+	case keyCancel:
+		return true, ErrCancelled
+	}
+	return false, nil
+}
+
+func (p *Picker) move(delta int) {
+	if len(p.matches) == 0 {
+		return
+	}
+	p.cursor = min(max(p.cursor+delta, 0), len(p.matches)-1)
+	if p.cursor < p.offset {
+		p.offset = p.cursor
+	}
+	if p.cursor >= p.offset+p.height {
+		p.offset = p.cursor - p.height + 1
+	}
+}
+
+// view renders the prompt, the visible matches and a count, one line each.
+func (p *Picker) view(width int) []string {
+	lines := []string{"> " + string(p.query)}
+	end := min(p.offset+p.height, len(p.matches))
+	for row := p.offset; row < end; row++ {
+		i := p.matches[row]
+		mark := "  "
+		if p.selected[i] {
+			mark = selectedStyle.Render("● ")
+		}
+		item := truncate(p.items[i], width-4)
+		if row == p.cursor {
+			lines = append(lines, cursorStyle.Render("▌ ")+mark+cursorStyle.Render(item))
+		} else {
+			lines = append(lines, "  "+mark+item)
+		}
+	}
+	count := fmt.Sprintf("  %d/%d", len(p.matches), len(p.items))
+	if len(p.selected) > 0 {
+		count += fmt.Sprintf(" (%d selected)", len(p.selected))
+	}
+	count += "  tab: select  ctrl-a: all  enter: confirm  esc: cancel"
+	return append(lines, countStyle.Render(truncate(count, width)))
+}
+
+// truncate shortens s to width cells, keeping its end, which for paths is
+// the file name.
+func truncate(s string, width int) string {
+	r := []rune(s)
+	if width <= 1 || len(r) <= width {
+		return s
+	}
+	return "…" + string(r[len(r)-width+1:])
+}
+
+// Run shows the picker on the terminal and returns the indexes of the chosen
+// items. It reads keys from the controlling terminal, so it works even when
+// stdin is redirected, and draws on out.
+func Run(out io.Writer, items []string) ([]int, error) {
+	in, closeTerminal, err := openTerminal()
+	if err != nil {
+		return nil, err
+	}
+	defer closeTerminal()
+
+	fd := in.Fd()
+	width, height, err := term.GetSize(fd)
+	if err != nil {
+		width, height = 80, DefaultHeight+2
+	}
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from the terminal: %w", err)
+	}
+	defer term.Restore(fd, state)
+
+	p := New(items, min(DefaultHeight, height-2))
+	r := bufio.NewReader(in)
+	for {
+		redraw(out, p.view(width))
+		k, err := readKey(r)
+		if err != nil {
+			redraw(out, nil)
+			return nil, fmt.Errorf("failed to read from the terminal: %w", err)
+		}
+		done, err := p.handle(k)
+		if done || err != nil {
+			redraw(out, nil)
+			if err != nil {
+				return nil, err
+			}
+			return p.Selected(), nil
+		}
+	}
+}
+
+func openTerminal() (*os.File, func(), error) {
+	if tty, err := os.Open("/dev/tty"); err == nil {
+		if term.IsTerminal(tty.Fd()) {
+			return tty, func() { tty.Close() }, nil
+		}
+		tty.Close()
+	}
+	if term.IsTerminal(os.Stdin.Fd()) {
+		return os.Stdin, func() {}, nil
+	}
+	return nil, nil, ErrNotTerminal
+}
+
+// redraw replaces what the last call drew with lines, leaving the cursor at
+// the end of the first line where the next call starts.
+func redraw(out io.Writer, lines []string) {
+	var b strings.Builder
+	b.WriteString("\r\x1b[J")
+	b.WriteString(strings.Join(lines, "\r\n"))
+	if len(lines) > 1 {
+		fmt.Fprintf(&b, "\x1b[%dA", len(lines)-1)
+	}
+	if len(lines) > 0 {
+		fmt.Fprintf(&b, "\r\x1b[%dC", len([]rune(lines[0])))
+	}
+	io.WriteString(out, b.String())
+}
+
+func readKey(r *bufio.Reader) (key, error) {
+	c, _, err := r.ReadRune()
+	if err != nil {
+		return key{}, err
+	}
+
+	switch c {
+	case '\r', '\n':
+		return key{typ: keyEnter}, nil
+	case 0x7f, 0x08:
+		return key{typ: keyBackspace}, nil
+	case '\t':
+		return key{typ: keyToggle}, nil
+	case 0x01:
+		return key{typ: keyToggleAll}, nil
+	case 0x15:
+		return key{typ: keyClear}, nil
+	case 0x10, 0x0b:
+		return key{typ: keyUp}, nil
+	case 0x0e:
+		return key{typ: keyDown}, nil
+	case 0x03, 0x07:
+		return key{typ: keyCancel}, nil
+	case 0x1b:
+		return readEscape(r)
+	}
+	if c < 0x20 {
+		return key{typ: keyUnknown}, nil
+	}
+	return key{typ: keyRune, r: c}, nil
+}
+
+// readEscape parses the arrow key sequences ESC [ A and ESC O A. A lone ESC
+// cancels.
+func readEscape(r *bufio.Reader) (key, error) {
+	if r.Buffered() == 0 {
+		return key{typ: keyCancel}, nil
+	}
+	intro, err := r.ReadByte()
+	if err != nil || (intro != '[' && intro != 'O') {
+		return key{typ: keyUnknown}, err
+	}
+	code, err := r.ReadByte()
+	if err != nil {
+		return key{typ: keyUnknown}, err
+	}
+	switch code {
+	case 'A':
+		return key{typ: keyUp}, nil
+	case 'B':
+		return key{typ: keyDown}, nil
+	}
+	return key{typ: keyUnknown}, nil
+}
@@ -0,0 +1,85 @@
+package picker
+
+import (
+	"bufio"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// play feeds keystrokes to a picker over items until it finishes.
+func play(t *testing.T, items []string, input string) ([]int, error) {
+	t.Helper()
+	p := New(items, 2)
+	r := bufio.NewReader(strings.NewReader(input))
+	for {
+		k, err := readKey(r)
+		if err != nil {
+			t.Fatalf("input ended before the picker finished: %v", err)
+		}
+		done, err := p.handle(k)
+		if err != nil {
+			return nil, err
+		}
+		if done {
+			return p.Selected(), nil
+		}
+	}
+}
+
+func TestPicker(t *testing.T) {
+	items := []string{"ui/clock.qmd", "ui/battery.qmd", "system/battery-icons.qmd", "ui/wifi.qmd"}
+
+	tests := []struct {
+		name    string
+		input   string
+		want    []int
+		wantErr error
+	}{
+		{name: "enter picks the cursor item", input: "\r", want: []int{0}},
+		{name: "arrow keys move", input: "\x1b[B\x1b[B\x1b[A\r", want: []int{1}},
+		{name: "query narrows matches", input: "wifi\r", want: []int{3}},
+		{name: "backspace widens matches", input: "wifx\x7fi\r", want: []int{3}},
+		{name: "tab selects several", input: "\t\x1b[B\t\r", want: []int{0, 2}},
+		{name: "tab toggles off", input: "\t\x1b[A\t\r", want: []int{1}},
+		{name: "ctrl-a selects all matches", input: "battery\x01\r", want: []int{1, 2}},
+		{name: "selection survives a new query", input: "clock\t\x15wifi\t\r", want: []int{0, 3}},
+		{name: "enter with no matches waits", input: "zzz\r\x15\r", want: []int{0}},
+		{name: "esc cancels", input: "\x1b", wantErr: ErrCancelled},
+		{name: "ctrl-c cancels", input: "\x03", wantErr: ErrCancelled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := play(t, items, tt.input)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selected = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPickerView(t *testing.T) {
+	p := New([]string{"a.qmd", "b.qmd", "c.qmd"}, 2)
+	p.handle(key{typ: keyToggle})
+
+	lines := p.view(80)
+	if len(lines) != 4 {
+		t.Fatalf("view() = %d lines, want prompt, 2 visible items and a count", len(lines))
+	}
+	if !strings.Contains(lines[1], "● a.qmd") || !strings.HasPrefix(lines[2], "▌") || !strings.Contains(lines[2], "b.qmd") {
+		t.Errorf("view() = %q, want a selected and the cursor on b", lines)
+	}
+	if !strings.Contains(lines[3], "3/3 (1 selected)") {
+		t.Errorf("count line = %q", lines[3])
+	}
+
+	p.handle(key{typ: keyDown})
+	if lines := p.view(80); !strings.Contains(lines[1], "b.qmd") || !strings.Contains(lines[2], "c.qmd") {
+		t.Errorf("view() = %q, want the list scrolled to the cursor", lines)
+	}
+}
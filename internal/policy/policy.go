@@ -0,0 +1,156 @@
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/osversion"
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+type RuleKind int
+
+const (
+	MinVersion RuleKind = iota
+	LatestN
+)
+
+type Rule struct {
+	Raw     string
+	Device  string
+	Kind    RuleKind
+	Version string
+	Count   int
+}
+
+type Violation struct {
+	Rule      string `json:"rule"`
+	Device    string `json:"device"`
+	OSVersion string `json:"os_version"`
+	Detail    string `json:"detail,omitempty"`
+}
+
+// devices are the device names results are reported under. A rule for any
+// other name would match no results and always pass.
+var devices = map[string]bool{
+	"rm1":   true,
+	"rm2":   true,
+	"rmpp":  true,
+	"rmppm": true,
+}
+
+// ParseRule accepts "<device> >= <version>" and "<device> latest-<N>".
+func ParseRule(raw string) (Rule, error) {
+	fields := strings.Fields(raw)
+	rule := Rule{Raw: strings.Join(fields, " ")}
+
+	switch {
+	case len(fields) == 3 && fields[1] == ">=":
+		rule.Device = fields[0]
+		rule.Kind = MinVersion
+		rule.Version = fields[2]
+	case len(fields) == 2 && strings.HasPrefix(fields[1], "latest-"):
+		count, err := strconv.Atoi(strings.TrimPrefix(fields[1], "latest-"))
+		if err != nil || count < 1 {
+			return Rule{}, fmt.Errorf("invalid policy rule '%s': latest-N requires a positive number", raw)
+		}
+		rule.Device = fields[0]
+		rule.Kind = LatestN
+		rule.Count = count
+	default:
+		return Rule{}, fmt.Errorf("invalid policy rule '%s': expected '<device> >= <version>' or '<device> latest-<N>'", raw)
+	}
+
+	if !devices[rule.Device] {
+		return Rule{}, fmt.Errorf("invalid policy rule '%s': unknown device '%s' (expected rm1, rm2, rmpp or rmppm)", raw, rule.Device)
+	}
+
+	return rule, nil
+}
+
+func ParseRules(raw []string) ([]Rule, error) {
+	rules := make([]Rule, 0, len(raw))
+	for _, r := range raw {
+		rule, err := ParseRule(r)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func Evaluate(response *api.ComparisonResponse, rules []Rule) []Violation {
+	var violations []Violation
+
+	for _, rule := range rules {
+		versions := deviceVersions(response, rule.Device)
+		required := rule.requiredVersions(versions)
+
+		for _, version := range required {
+			result := findResult(response.Incompatible, rule.Device, version)
+			if result == nil {
+				continue
+			}
+			violations = append(violations, Violation{
+				Rule:      rule.Raw,
+				Device:    rule.Device,
+				OSVersion: version,
+				Detail:    result.ErrorDetail,
+			})
+		}
+	}
+
+	return violations
+}
+
+func (r Rule) requiredVersions(versions []string) []string {
+	switch r.Kind {
+	case MinVersion:
+		var required []string
+		for _, v := range versions {
+			if osversion.Compare(v, r.Version) >= 0 {
+				required = append(required, v)
+			}
+		}
+		return required
+	case LatestN:
+		if len(versions) <= r.Count {
+			return versions
+		}
+		return versions[len(versions)-r.Count:]
+	}
+	return nil
+}
+
+func deviceVersions(response *api.ComparisonResponse, device string) []string {
+	seen := make(map[string]bool)
+	var versions []string
+
+	for _, results := range [][]api.ComparisonResult{response.Compatible, response.Incompatible} {
+		for _, result := range results {
+			if result.Device != device || seen[result.OSVersion] {
+				continue
+			}
+			seen[result.OSVersion] = true
+			versions = append(versions, result.OSVersion)
+		}
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return osversion.Compare(versions[i], versions[j]) < 0
+	})
+
+	return versions
+}
+
+func findResult(results []api.ComparisonResult, device, version string) *api.ComparisonResult {
+	for i := range results {
+		if results[i].Device == device && results[i].OSVersion == version {
+			return &results[i]
+		}
+	}
+	return nil
+}
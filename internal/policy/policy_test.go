@@ -0,0 +1,147 @@
+package policy
+
+import (
+	"reflect"
+	"testing"
+
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    Rule
+		wantErr bool
+	}{
+		{
+			name: "minimum version",
+			raw:  "rmpp >= 3.20",
+			want: Rule{Raw: "rmpp >= 3.20", Device: "rmpp", Kind: MinVersion, Version: "3.20"},
+		},
+		{
+			name: "minimum version with extra whitespace",
+			raw:  "  rmpp   >=  3.20 ",
+			want: Rule{Raw: "rmpp >= 3.20", Device: "rmpp", Kind: MinVersion, Version: "3.20"},
+		},
+		{
+			name: "latest N",
+			raw:  "rm2 latest-3",
+			want: Rule{Raw: "rm2 latest-3", Device: "rm2", Kind: LatestN, Count: 3},
+		},
+		{
+			name:    "latest with zero",
+			raw:     "rm2 latest-0",
+			wantErr: true,
+		},
+		{
+			name:    "latest without number",
+			raw:     "rm2 latest-x",
+			wantErr: true,
+		},
+		{
+			name:    "unsupported operator",
+			raw:     "rmpp < 3.20",
+			wantErr: true,
+		},
+		{
+			name:    "empty rule",
+			raw:     "",
+			wantErr: true,
+		},
+		{
+			name:    "unknown device",
+			raw:     "rmp >= 3.20",
+			wantErr: true,
+		},
+		{
+			name:    "unknown device with latest N",
+			raw:     "kindle latest-2",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRule(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRule() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseRule() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	response := &api.ComparisonResponse{
+		Compatible: []api.ComparisonResult{
+			{Device: "rmpp", OSVersion: "3.20.0.92"},
+			{Device: "rmpp", OSVersion: "3.22.0.64"},
+			{Device: "rm2", OSVersion: "3.22.0.64"},
+			{Device: "rm2", OSVersion: "3.23.0.64"},
+		},
+		Incompatible: []api.ComparisonResult{
+			{Device: "rmpp", OSVersion: "3.19.0.0", ErrorDetail: "old"},
+			{Device: "rmpp", OSVersion: "3.23.0.64", ErrorDetail: "Cannot resolve hash 1"},
+			{Device: "rm2", OSVersion: "3.20.0.92"},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		rules []string
+		want  []Violation
+	}{
+		{
+			name:  "no rules",
+			rules: nil,
+			want:  nil,
+		},
+		{
+			name:  "minimum version ignores older failures",
+			rules: []string{"rmpp >= 3.20"},
+			want: []Violation{
+				{Rule: "rmpp >= 3.20", Device: "rmpp", OSVersion: "3.23.0.64", Detail: "Cannot resolve hash 1"},
+			},
+		},
+		{
+			name:  "latest N satisfied",
+			rules: []string{"rm2 latest-2"},
+			want:  nil,
+		},
+		{
+			name:  "latest N includes older failure",
+			rules: []string{"rm2 latest-3"},
+			want: []Violation{
+				{Rule: "rm2 latest-3", Device: "rm2", OSVersion: "3.20.0.92"},
+			},
+		},
+		{
+			name:  "latest N larger than available versions",
+			rules: []string{"rm2 latest-10"},
+			want: []Violation{
+				{Rule: "rm2 latest-10", Device: "rm2", OSVersion: "3.20.0.92"},
+			},
+		},
+		{
+			name:  "device without results",
+			rules: []string{"rm1 >= 3.0"},
+			want:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := ParseRules(tt.rules)
+			if err != nil {
+				t.Fatalf("ParseRules() error = %v", err)
+			}
+			got := Evaluate(response, rules)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Evaluate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
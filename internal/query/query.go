@@ -0,0 +1,260 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Query is a compiled jq-style expression. It supports the subset most
+// extractions need: paths (.a.b, .a[0], .a[-1], .a[], .["key"]), pipes and
+// the length and keys builtins.
+type Query struct {
+	stages [][]step
+}
+
+type stepKind int
+
+const (
+	stepField stepKind = iota
+	stepIndex
+	stepIterate
+	stepLength
+	stepKeys
+)
+
+type step struct {
+	kind  stepKind
+	field string
+	index int
+}
+
+// Parse compiles an expression such as '.incompatible[].os_version'.
+func Parse(expr string) (*Query, error) {
+	q := &Query{}
+	for _, stage := range strings.Split(expr, "|") {
+		steps, err := parseStage(strings.TrimSpace(stage))
+		if err != nil {
+			return nil, fmt.Errorf("invalid query %q: %w", expr, err)
+		}
+		q.stages = append(q.stages, steps)
+	}
+	return q, nil
+}
+
+func parseStage(stage string) ([]step, error) {
+	switch stage {
+	case "length":
+		return []step{{kind: stepLength}}, nil
+	case "keys":
+		return []step{{kind: stepKeys}}, nil
+	case "":
+		return nil, fmt.Errorf("empty expression")
+	}
+
+	if stage[0] != '.' {
+		return nil, fmt.Errorf("expression must start with '.': %q", stage)
+	}
+
+	var steps []step
+	rest := stage
+	for rest != "" {
+		switch {
+		case rest == ".":
+			rest = ""
+		case strings.HasPrefix(rest, ".["):
+			rest = rest[1:]
+		case rest[0] == '.':
+			end := 1
+			for end < len(rest) && isIdentChar(rest[end]) {
+				end++
+			}
+			if end == 1 {
+				return nil, fmt.Errorf("expected field name at %q", rest)
+			}
+			steps = append(steps, step{kind: stepField, field: rest[1:end]})
+			rest = rest[end:]
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated '[' at %q", rest)
+			}
+			s, err := parseBracket(rest[1:end])
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, s)
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("unexpected %q", rest)
+		}
+	}
+
+	return steps, nil
+}
+
+func parseBracket(inner string) (step, error) {
+	inner = strings.TrimSpace(inner)
+	if inner == "" {
+		return step{kind: stepIterate}, nil
+	}
+	if strings.HasPrefix(inner, `"`) {
+		field, err := strconv.Unquote(inner)
+		if err != nil {
+			return step{}, fmt.Errorf("invalid key %s", inner)
+		}
+		return step{kind: stepField, field: field}, nil
+	}
+	index, err := strconv.Atoi(inner)
+	if err != nil {
+		return step{}, fmt.Errorf("invalid index %q", inner)
+	}
+	return step{kind: stepIndex, index: index}, nil
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// Run evaluates the query against any JSON-encodable value and returns the
+// resulting stream of values.
+func (q *Query) Run(v any) ([]any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	values := []any{doc}
+	for _, stage := range q.stages {
+		for _, s := range stage {
+			var next []any
+			for _, value := range values {
+				out, err := apply(s, value)
+				if err != nil {
+					return nil, err
+				}
+				next = append(next, out...)
+			}
+			values = next
+		}
+	}
+
+	return values, nil
+}
+
+func apply(s step, value any) ([]any, error) {
+	switch s.kind {
+	case stepField:
+		if value == nil {
+			return []any{nil}, nil
+		}
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("cannot index %s with %q", typeName(value), s.field)
+		}
+		return []any{obj[s.field]}, nil
+
+	case stepIndex:
+		if value == nil {
+			return []any{nil}, nil
+		}
+		arr, ok := value.([]any)
+		if !ok {
+			return nil, fmt.Errorf("cannot index %s with a number", typeName(value))
+		}
+		i := s.index
+		if i < 0 {
+			i += len(arr)
+		}
+		if i < 0 || i >= len(arr) {
+			return []any{nil}, nil
+		}
+		return []any{arr[i]}, nil
+
+	case stepIterate:
+		switch v := value.(type) {
+		case []any:
+			return v, nil
+		case map[string]any:
+			var out []any
+			for _, key := range sortedKeys(v) {
+				out = append(out, v[key])
+			}
+			return out, nil
+		case nil:
+			return nil, nil
+		}
+		return nil, fmt.Errorf("cannot iterate over %s", typeName(value))
+
+	case stepLength:
+		switch v := value.(type) {
+		case []any:
+			return []any{float64(len(v))}, nil
+		case map[string]any:
+			return []any{float64(len(v))}, nil
+		case string:
+			return []any{float64(len(v))}, nil
+		case nil:
+			return []any{float64(0)}, nil
+		}
+		return nil, fmt.Errorf("%s has no length", typeName(value))
+
+	case stepKeys:
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%s has no keys", typeName(value))
+		}
+		var keys []any
+		for _, key := range sortedKeys(obj) {
+			keys = append(keys, key)
+		}
+		return []any{keys}, nil
+	}
+
+	return nil, fmt.Errorf("unknown query step")
+}
+
+func sortedKeys(obj map[string]any) []string {
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func typeName(value any) string {
+	switch value.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	}
+	return "null"
+}
+
+// FormatValue renders a result like jq -r: strings are printed raw and
+// everything else as compact JSON.
+func FormatValue(value any) (string, error) {
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,75 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestQuery(t *testing.T) {
+	doc := map[string]any{
+		"total_checked": 3,
+		"compatible": []any{
+			map[string]any{"device": "rmpp", "os_version": "3.22.0.64"},
+		},
+		"incompatible": []any{
+			map[string]any{"device": "rm2", "os_version": "3.22.0.64"},
+			map[string]any{"device": "rm1", "os_version": "3.20.0.92"},
+		},
+	}
+
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{expr: ".", want: []string{`{"compatible":[{"device":"rmpp","os_version":"3.22.0.64"}],"incompatible":[{"device":"rm2","os_version":"3.22.0.64"},{"device":"rm1","os_version":"3.20.0.92"}],"total_checked":3}`}},
+		{expr: ".total_checked", want: []string{"3"}},
+		{expr: ".incompatible[].os_version", want: []string{"3.22.0.64", "3.20.0.92"}},
+		{expr: ".incompatible[0].device", want: []string{"rm2"}},
+		{expr: ".incompatible[-1].device", want: []string{"rm1"}},
+		{expr: ".incompatible[5]", want: []string{"null"}},
+		{expr: `.["compatible"][].device`, want: []string{"rmpp"}},
+		{expr: ".incompatible | length", want: []string{"2"}},
+		{expr: ". | keys", want: []string{`["compatible","incompatible","total_checked"]`}},
+		{expr: ".missing.deeper", want: []string{"null"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			q, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			values, err := q.Run(doc)
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			var got []string
+			for _, value := range values {
+				line, err := FormatValue(value)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, line)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuery_Errors(t *testing.T) {
+	for _, expr := range []string{"", "incompatible", ".incompatible[", ".[x]", ".a |", ".a-b"} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) expected error", expr)
+		}
+	}
+
+	q, err := Parse(".total_checked[]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.Run(map[string]any{"total_checked": 3}); err == nil {
+		t.Error("Run() expected error iterating over a number")
+	}
+}
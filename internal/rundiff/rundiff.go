@@ -0,0 +1,272 @@
+// Package rundiff compares the results of two check runs.
+package rundiff
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/cache"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/osversion"
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+type Kind string
+
+const (
+	// Regression was compatible in the first run and is incompatible in the
+	// second.
+	Regression Kind = "regression"
+	// Fix was incompatible in the first run and is compatible in the second.
+	Fix Kind = "fix"
+	// Covered was not checked by the first run.
+	Covered Kind = "covered"
+)
+
+// Kinds lists the kinds of change in the order they are reported.
+var Kinds = []Kind{Regression, Fix, Covered}
+
+var headings = map[Kind]string{
+	Regression: "Regressions",
+	Fix:        "Fixes",
+	Covered:    "Newly covered",
+}
+
+// Heading is the section title for changes of kind k.
+func (k Kind) Heading() string {
+	return headings[k]
+}
+
+// Run is the results of one check, keyed by file. Name is the job ID or the
+// path the results were read from.
+type Run struct {
+	Name    string                             `json:"name"`
+	Note    string                             `json:"note,omitempty"`
+	Results map[string]*api.ComparisonResponse `json:"-"`
+}
+
+type Change struct {
+	Kind       Kind   `json:"kind"`
+	File       string `json:"file,omitempty"`
+	Device     string `json:"device"`
+	OSVersion  string `json:"os_version"`
+	Compatible bool   `json:"compatible"`
+	Detail     string `json:"detail,omitempty"`
+}
+
+type Diff struct {
+	From    *Run     `json:"from"`
+	To      *Run     `json:"to"`
+	Changes []Change `json:"changes"`
+}
+
+// Load reads a run from a results file, or else from the stored results of
+// the job with that ID.
+func Load(ref string) (*Run, error) {
+	data, err := os.ReadFile(ref)
+	if err == nil {
+		results, err := Parse(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", ref, err)
+		}
+		return &Run{Name: ref, Results: results}, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read %s: %w", ref, err)
+	}
+
+	job, found, err := cache.LoadJobResults(ref)
+	if err != nil || !found {
+		return nil, fmt.Errorf("%s is neither a results file nor a stored job", ref)
+	}
+	results, err := Parse(job.Results)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse results of job %s: %w", ref, err)
+	}
+	return &Run{Name: ref, Note: job.Note, Results: results}, nil
+}
+
+// Parse reads results as the server returns them, for one file or keyed by
+// file, or wrapped in an object under "results" as in a stored job.
+func Parse(data []byte) (map[string]*api.ComparisonResponse, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	if inner, ok := fields["results"]; ok {
+		return Parse(inner)
+	}
+	_, compatible := fields["compatible"]
+	_, incompatible := fields["incompatible"]
+	if compatible || incompatible {
+		var response api.ComparisonResponse
+		if err := json.Unmarshal(data, &response); err != nil {
+			return nil, err
+		}
+		return map[string]*api.ComparisonResponse{"": &response}, nil
+	}
+
+	var batch map[string]*api.ComparisonResponse
+	if err := json.Unmarshal(data, &batch); err != nil {
+		return nil, fmt.Errorf("not a check result: %w", err)
+	}
+	return batch, nil
+}
+
+// Compare lists what changed from one run to the next: regressions, fixes
+// and newly covered versions. When each run checked a single file, the two
+// are compared even if their names differ.
+func Compare(from, to *Run) *Diff {
+	before, after := from.Results, to.Results
+	if len(before) == 1 && len(after) == 1 {
+		for file := range after {
+			before = renamed(before, file)
+		}
+	}
+
+	var changes []Change
+	for file, response := range after {
+		old := statuses(before[file])
+		for _, results := range [][]api.ComparisonResult{response.Compatible, response.Incompatible} {
+			for _, result := range results {
+				compatible := isCompatible(response, result)
+				change := Change{File: file, Device: result.Device, OSVersion: result.OSVersion, Compatible: compatible}
+				was, checked := old[[2]string{result.Device, result.OSVersion}]
+				switch {
+				case !checked:
+					change.Kind = Covered
+				case was && !compatible:
+					change.Kind = Regression
+				case !was && compatible:
+					change.Kind = Fix
+				default:
+					continue
+				}
+				if !compatible {
+					change.Detail = result.ErrorDetail
+				}
+				changes = append(changes, change)
+			}
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		switch {
+		case a.Kind != b.Kind:
+			return slices.Index(Kinds, a.Kind) < slices.Index(Kinds, b.Kind)
+		case a.File != b.File:
+			return a.File < b.File
+		case a.Device != b.Device:
+			return a.Device < b.Device
+		}
+		return osversion.Compare(a.OSVersion, b.OSVersion) > 0
+	})
+
+	return &Diff{From: from, To: to, Changes: changes}
+}
+
+// renamed returns the only results of a run under file.
+func renamed(results map[string]*api.ComparisonResponse, file string) map[string]*api.ComparisonResponse {
+	for _, response := range results {
+		return map[string]*api.ComparisonResponse{file: response}
+	}
+	return results
+}
+
+func statuses(response *api.ComparisonResponse) map[[2]string]bool {
+	status := make(map[[2]string]bool)
+	if response == nil {
+		return status
+	}
+	for _, results := range [][]api.ComparisonResult{response.Compatible, response.Incompatible} {
+		for _, result := range results {
+			status[[2]string{result.Device, result.OSVersion}] = isCompatible(response, result)
+		}
+	}
+	return status
+}
+
+// isCompatible reports whether result is one of response's compatible
+// results.
+func isCompatible(response *api.ComparisonResponse, result api.ComparisonResult) bool {
+	for _, r := range response.Compatible {
+		if r.Device == result.Device && r.OSVersion == result.OSVersion {
+			return true
+		}
+	}
+	return false
+}
+
+// Count returns the number of changes of each kind.
+func (d *Diff) Count(kind Kind) int {
+	n := 0
+	for _, change := range d.Changes {
+		if change.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+func (d *Diff) WriteJSON(w io.Writer) error {
+	if d.Changes == nil {
+		d.Changes = []Change{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(d)
+}
+
+// WriteMarkdown writes the diff as changelog sections, one per kind of
+// change.
+func (d *Diff) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Compatibility changes from %s to %s\n", d.From.Name, d.To.Name)
+	if d.To.Note != "" {
+		fmt.Fprintf(&b, "\n%s\n", d.To.Note)
+	}
+	if len(d.Changes) == 0 {
+		b.WriteString("\nNo changes.\n")
+	}
+
+	for _, kind := range Kinds {
+		if d.Count(kind) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n### %s\n\n", kind.Heading())
+		for _, change := range d.Changes {
+			if change.Kind == kind {
+				fmt.Fprintf(&b, "- %s\n", change.markdown())
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (c Change) markdown() string {
+	line := fmt.Sprintf("%s on `%s`", c.OSVersion, c.Device)
+	if c.File != "" {
+		line = fmt.Sprintf("`%s`: %s", c.File, line)
+	}
+	if c.Kind == Covered {
+		if c.Compatible {
+			line += " (compatible)"
+		} else {
+			line += " (incompatible)"
+		}
+	}
+	if c.Detail != "" {
+		line += " — " + c.Detail
+	}
+	return line
+}
@@ -0,0 +1,171 @@
+package rundiff
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/cache"
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+func response(compatible, incompatible []string) *api.ComparisonResponse {
+	r := &api.ComparisonResponse{}
+	for _, cell := range compatible {
+		device, version, _ := strings.Cut(cell, "@")
+		r.Compatible = append(r.Compatible, api.ComparisonResult{Device: device, OSVersion: version, Compatible: true})
+	}
+	for _, cell := range incompatible {
+		device, version, _ := strings.Cut(cell, "@")
+		r.Incompatible = append(r.Incompatible, api.ComparisonResult{Device: device, OSVersion: version, ErrorDetail: "hash not found"})
+	}
+	r.TotalChecked = len(compatible) + len(incompatible)
+	return r
+}
+
+func TestParse(t *testing.T) {
+	single := `{"compatible":[{"device":"rm2","os_version":"3.22.0.64"}],"incompatible":[],"total_checked":1}`
+	batch := `{"a.qmd":` + single + `,"b.qmd":` + single + `}`
+
+	tests := []struct {
+		name      string
+		data      string
+		wantFiles []string
+		wantErr   bool
+	}{
+		{name: "single file", data: single, wantFiles: []string{""}},
+		{name: "batch", data: batch, wantFiles: []string{"a.qmd", "b.qmd"}},
+		{name: "stored job", data: `{"job_id":"job-1","results":` + batch + `}`, wantFiles: []string{"a.qmd", "b.qmd"}},
+		{name: "not an object", data: `[1, 2]`, wantErr: true},
+		{name: "not results", data: `{"a.qmd": 3}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := Parse([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			var files []string
+			for file, r := range results {
+				files = append(files, file)
+				if len(r.Compatible) != 1 {
+					t.Errorf("Parse() %s = %+v", file, r)
+				}
+			}
+			if len(files) > 1 && files[0] > files[1] {
+				files[0], files[1] = files[1], files[0]
+			}
+			if !reflect.DeepEqual(files, tt.wantFiles) {
+				t.Errorf("Parse() files = %v, want %v", files, tt.wantFiles)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	from := &Run{Name: "a", Results: map[string]*api.ComparisonResponse{
+		"main.qmd": response([]string{"rm2@3.20.0.92", "rmpp@3.22.0.64"}, []string{"rm2@3.22.0.64"}),
+	}}
+	to := &Run{Name: "b", Results: map[string]*api.ComparisonResponse{
+		"main.qmd": response([]string{"rm2@3.20.0.92", "rm2@3.22.0.64", "rmpp@3.23.0.64"}, []string{"rmpp@3.22.0.64"}),
+	}}
+
+	diff := Compare(from, to)
+	want := []Change{
+		{Kind: Regression, File: "main.qmd", Device: "rmpp", OSVersion: "3.22.0.64", Detail: "hash not found"},
+		{Kind: Fix, File: "main.qmd", Device: "rm2", OSVersion: "3.22.0.64", Compatible: true},
+		{Kind: Covered, File: "main.qmd", Device: "rmpp", OSVersion: "3.23.0.64", Compatible: true},
+	}
+	if !reflect.DeepEqual(diff.Changes, want) {
+		t.Errorf("Compare() = %+v, want %+v", diff.Changes, want)
+	}
+
+	t.Run("single files with different names", func(t *testing.T) {
+		renamed := &Run{Results: map[string]*api.ComparisonResponse{"": to.Results["main.qmd"]}}
+		if diff := Compare(from, renamed); len(diff.Changes) != 3 || diff.Changes[0].File != "" {
+			t.Errorf("Compare() = %+v", diff.Changes)
+		}
+	})
+
+	t.Run("no changes", func(t *testing.T) {
+		if diff := Compare(from, from); len(diff.Changes) != 0 {
+			t.Errorf("Compare() = %+v, want none", diff.Changes)
+		}
+	})
+}
+
+func TestLoad(t *testing.T) {
+	t.Setenv(cache.EnvVarCacheDir, t.TempDir())
+
+	body := []byte(`{"compatible":[],"incompatible":[{"device":"rm2","os_version":"3.22.0.64"}],"total_checked":1}`)
+	if err := cache.SaveJobResults("https://qmd.example.com", "job-1", "before the fix", body); err != nil {
+		t.Fatal(err)
+	}
+	run, err := Load("job-1")
+	if err != nil {
+		t.Fatalf("Load(job-1) error = %v", err)
+	}
+	if run.Name != "job-1" || run.Note != "before the fix" || len(run.Results[""].Incompatible) != 1 {
+		t.Errorf("Load(job-1) = %+v", run)
+	}
+
+	path := filepath.Join(t.TempDir(), "results.json")
+	if err := os.WriteFile(path, body, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if run, err := Load(path); err != nil || run.Name != path || len(run.Results[""].Incompatible) != 1 {
+		t.Errorf("Load(%s) = %+v, %v", path, run, err)
+	}
+
+	if _, err := Load("job-2"); err == nil || !strings.Contains(err.Error(), "neither a results file nor a stored job") {
+		t.Errorf("Load(job-2) error = %v", err)
+	}
+}
+
+func TestDiffOutput(t *testing.T) {
+	diff := &Diff{
+		From: &Run{Name: "job-1"},
+		To:   &Run{Name: "job-2", Note: "after migrating to new SwipeArea API"},
+		Changes: []Change{
+			{Kind: Regression, File: "main.qmd", Device: "rmpp", OSVersion: "3.22.0.64", Detail: "hash not found"},
+			{Kind: Covered, Device: "rm2", OSVersion: "3.23.0.64", Compatible: true},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := diff.WriteMarkdown(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"## Compatibility changes from job-1 to job-2",
+		"after migrating to new SwipeArea API",
+		"### Regressions\n\n- `main.qmd`: 3.22.0.64 on `rmpp` — hash not found\n",
+		"### Newly covered\n\n- 3.23.0.64 on `rm2` (compatible)\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("WriteMarkdown() missing %q:\n%s", want, buf.String())
+		}
+	}
+	if strings.Contains(buf.String(), "### Fixes") {
+		t.Errorf("WriteMarkdown() has an empty section:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := diff.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var decoded Diff
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("WriteJSON() wrote invalid JSON: %v", err)
+	}
+	if decoded.To.Note != diff.To.Note || !reflect.DeepEqual(decoded.Changes, diff.Changes) {
+		t.Errorf("WriteJSON() = %s", buf.String())
+	}
+}
@@ -0,0 +1,120 @@
+package scan
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	propertyRe     = regexp.MustCompile(`Q_PROPERTY\s*\(([^)]*)\)`)
+	invokableRe    = regexp.MustCompile(`Q_INVOKABLE\s+[^;{(]*?\b(\w+)\s*\(`)
+	sectionRe      = regexp.MustCompile(`(?m)^\s*(?:(?:public|protected|private)\s+)?(signals|slots|Q_SIGNALS|Q_SLOTS)\s*:`)
+	sectionEndRe   = regexp.MustCompile(`(?m)^\s*(?:(?:public|protected|private)(?:\s+\w+)?\s*:|(?:signals|Q_SIGNALS)\s*:|\};)`)
+	methodRe       = regexp.MustCompile(`\b(\w+)\s*\(`)
+	classRe        = regexp.MustCompile(`\bclass\s+(?:[A-Z][A-Z0-9_]*\s+)?(\w+)\s*(?:final\s*)?[:{]`)
+	namedElementRe = regexp.MustCompile(`QML_NAMED_ELEMENT\s*\(\s*(\w+)\s*\)`)
+	registerRe     = regexp.MustCompile(`qmlRegister\w*(?:\s*<[^>]*>)?\s*\(([^;]*)\)\s*;`)
+	literalRe      = regexp.MustCompile(`"([A-Za-z_]\w*)"`)
+	enumDeclRe     = regexp.MustCompile(`Q_(?:ENUM|FLAG)(?:_NS)?\s*\(\s*(\w+)\s*\)`)
+	enumRe         = regexp.MustCompile(`\benum\s+(?:class\s+)?(\w+)\s*(?::\s*[\w:]+\s*)?\{([^}]*)\}`)
+	identRe        = regexp.MustCompile(`[A-Za-z_]\w*`)
+
+	propertyKeywords = map[string]bool{
+		"READ": true, "WRITE": true, "RESET": true, "NOTIFY": true, "MEMBER": true,
+		"REVISION": true, "DESIGNABLE": true, "SCRIPTABLE": true, "STORED": true,
+		"USER": true, "BINDABLE": true, "CONSTANT": true, "FINAL": true, "REQUIRED": true,
+		"true": true, "false": true,
+	}
+	cppKeywords = map[string]bool{
+		"if": true, "for": true, "while": true, "switch": true, "return": true,
+		"sizeof": true, "Q_INVOKABLE": true, "Q_SIGNAL": true, "Q_SLOT": true,
+	}
+)
+
+// Cpp returns the identifiers that C++ sources expose to QML: Q_PROPERTY
+// names, types and accessors, Q_INVOKABLE methods, signals and slots, the
+// names of QObject and gadget classes and of registered QML types, and the
+// names and values of Q_ENUM enums. It works on patterns rather than a full
+// parse, so unusual formatting can hide a declaration.
+func Cpp(src string) []string {
+	src = stripCppComments(src)
+	var idents []string
+
+	for _, m := range propertyRe.FindAllStringSubmatch(src, -1) {
+		for _, ident := range identRe.FindAllString(m[1], -1) {
+			if !propertyKeywords[ident] {
+				idents = append(idents, ident)
+			}
+		}
+	}
+	for _, m := range invokableRe.FindAllStringSubmatch(src, -1) {
+		idents = append(idents, m[1])
+	}
+
+	for _, loc := range sectionRe.FindAllStringIndex(src, -1) {
+		body := src[loc[1]:]
+		if end := sectionEndRe.FindStringIndex(body); end != nil {
+			body = body[:end[0]]
+		}
+		for _, m := range methodRe.FindAllStringSubmatch(body, -1) {
+			if !cppKeywords[m[1]] {
+				idents = append(idents, m[1])
+			}
+		}
+	}
+
+	if strings.Contains(src, "Q_OBJECT") || strings.Contains(src, "Q_GADGET") || strings.Contains(src, "QML_ELEMENT") {
+		for _, m := range classRe.FindAllStringSubmatch(src, -1) {
+			idents = append(idents, m[1])
+		}
+	}
+	for _, m := range namedElementRe.FindAllStringSubmatch(src, -1) {
+		idents = append(idents, m[1])
+	}
+	for _, m := range registerRe.FindAllStringSubmatch(src, -1) {
+		if literals := literalRe.FindAllStringSubmatch(m[1], -1); len(literals) > 0 {
+			idents = append(idents, literals[len(literals)-1][1])
+		}
+	}
+
+	exposed := make(map[string]bool)
+	for _, m := range enumDeclRe.FindAllStringSubmatch(src, -1) {
+		exposed[m[1]] = true
+	}
+	for _, m := range enumRe.FindAllStringSubmatch(src, -1) {
+		if !exposed[m[1]] {
+			continue
+		}
+		idents = append(idents, m[1])
+		for _, value := range strings.Split(m[2], ",") {
+			if ident := identRe.FindString(strings.TrimSpace(value)); ident != "" {
+				idents = append(idents, ident)
+			}
+		}
+	}
+
+	return idents
+}
+
+// stripCppComments blanks out comments, leaving string literals intact.
+func stripCppComments(src string) string {
+	var b strings.Builder
+	for i := 0; i < len(src); {
+		switch {
+		case strings.HasPrefix(src[i:], "//"):
+			i = skipLine(src, i)
+			b.WriteByte('\n')
+		case strings.HasPrefix(src[i:], "/*"):
+			i = skipBlockComment(src, i)
+			b.WriteByte(' ')
+		case src[i] == '"' || src[i] == '\'':
+			end := skipString(src, i, src[i])
+			b.WriteString(src[i:end])
+			i = end
+		default:
+			b.WriteByte(src[i])
+			i++
+		}
+	}
+	return b.String()
+}
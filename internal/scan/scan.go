@@ -0,0 +1,162 @@
+// Package scan extracts the identifiers a hashtab needs from Qt/QML source
+// trees, for building hashtabs without a firmware dump.
+package scan
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	qmlExtensions = map[string]bool{".qml": true, ".js": true, ".mjs": true}
+	cppExtensions = map[string]bool{".h": true, ".hh": true, ".hpp": true, ".hxx": true, ".cpp": true, ".cc": true, ".cxx": true}
+)
+
+// Result is what a scan found.
+type Result struct {
+	// Strings holds identifiers and the paths of QML files, which AFFECT
+	// statements name.
+	Strings  map[string]bool
+	QMLFiles int
+	CppFiles int
+}
+
+// Dir scans the QML and JavaScript files under root and, with includeCpp,
+// the C++ sources. File paths are recorded relative to root with a leading
+// slash, e.g. /qml/Main.qml. Hidden files and directories are skipped.
+func Dir(root string, includeCpp bool) (*Result, error) {
+	result := &Result{Strings: make(map[string]bool)}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != root && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		ext := strings.ToLower(filepath.Ext(path))
+		isQML, isCpp := qmlExtensions[ext], includeCpp && cppExtensions[ext]
+		if !isQML && !isCpp {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		var found []string
+		if isQML {
+			result.QMLFiles++
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			result.Strings["/"+filepath.ToSlash(rel)] = true
+			found = QML(string(data))
+		} else {
+			result.CppFiles++
+			found = Cpp(string(data))
+		}
+		for _, s := range found {
+			result.Strings[s] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", root, err)
+	}
+	return result, nil
+}
+
+// QML returns the identifiers in QML or JavaScript source, skipping
+// comments and string literals.
+func QML(src string) []string {
+	var idents []string
+	for i := 0; i < len(src); {
+		r, size := utf8.DecodeRuneInString(src[i:])
+		switch {
+		case strings.HasPrefix(src[i:], "//"):
+			i = skipLine(src, i)
+		case strings.HasPrefix(src[i:], "/*"):
+			i = skipBlockComment(src, i)
+		case r == '"' || r == '\'' || r == '`':
+			i = skipString(src, i, byte(r))
+		case isIdentStart(r):
+			end := identEnd(src, i)
+			idents = append(idents, src[i:end])
+			i = end
+		case unicode.IsDigit(r):
+			for i < len(src) {
+				r, size := utf8.DecodeRuneInString(src[i:])
+				if !isIdentPart(r) && r != '.' {
+					break
+				}
+				i += size
+			}
+		default:
+			i += size
+		}
+	}
+	return idents
+}
+
+func isIdentStart(r rune) bool {
+	return r == '_' || r == '$' || unicode.IsLetter(r)
+}
+
+func isIdentPart(r rune) bool {
+	return isIdentStart(r) || unicode.IsDigit(r)
+}
+
+func identEnd(src string, i int) int {
+	for i < len(src) {
+		r, size := utf8.DecodeRuneInString(src[i:])
+		if !isIdentPart(r) {
+			break
+		}
+		i += size
+	}
+	return i
+}
+
+func skipLine(src string, i int) int {
+	if end := strings.IndexByte(src[i:], '\n'); end >= 0 {
+		return i + end + 1
+	}
+	return len(src)
+}
+
+func skipBlockComment(src string, i int) int {
+	if end := strings.Index(src[i+2:], "*/"); end >= 0 {
+		return i + 2 + end + 2
+	}
+	return len(src)
+}
+
+func skipString(src string, i int, quote byte) int {
+	for i++; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			i++
+		case quote:
+			return i + 1
+		case '\n':
+			if quote != '`' {
+				return i + 1
+			}
+		}
+	}
+	return len(src)
+}
@@ -0,0 +1,115 @@
+package scan
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestQML(t *testing.T) {
+	src := `import QtQuick 2.15
+// a comment with Ignored words
+Item {
+    id: root
+    property int swipeCount: 0 /* block Hidden */
+    signal swiped(string direction)
+    Text { text: "Not an identifier"; width: parent.width * 1.5e3 }
+    function onSwipe() { return qsTr('also ignored') + ` + "`template ${skipped}`" + ` }
+}`
+
+	got := QML(src)
+	want := []string{"import", "QtQuick", "Item", "id", "root", "property", "int", "swipeCount",
+		"signal", "swiped", "string", "direction", "Text", "text", "width", "parent", "width",
+		"function", "onSwipe", "return", "qsTr"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("QML() = %v, want %v", got, want)
+	}
+}
+
+func TestCpp(t *testing.T) {
+	src := `#include <QObject>
+// class Commented : public QObject
+class EXPORT_API SwipeHandler : public QObject {
+    Q_OBJECT
+    QML_NAMED_ELEMENT(Swiper)
+    Q_PROPERTY(int threshold READ threshold WRITE setThreshold NOTIFY thresholdChanged FINAL)
+public:
+    enum Direction { Left, Right = 2 };
+    Q_ENUM(Direction)
+    enum Hidden { Internal };
+    Q_INVOKABLE void reset(bool hard);
+    int threshold() const;
+signals:
+    void thresholdChanged(int value);
+    void swiped(Direction direction);
+public slots:
+    void cancel();
+private:
+    void helper();
+};
+
+static void registerTypes() {
+    qmlRegisterType<SwipeHandler>("com.example.swipe", 1, 0, "SwipeArea");
+}`
+
+	got := Cpp(src)
+	sort.Strings(got)
+	want := []string{"Direction", "Left", "Right", "SwipeArea", "SwipeHandler", "Swiper", "cancel", "int", "reset",
+		"setThreshold", "swiped", "threshold", "threshold", "thresholdChanged", "thresholdChanged"}
+	sort.Strings(want)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Cpp() = %v, want %v", got, want)
+	}
+}
+
+func TestDir(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"qml/Main.qml":       "Item { id: main }",
+		"qml/util.js":        "function helper() {}",
+		"src/handler.h":      "class Handler : public QObject { Q_OBJECT Q_INVOKABLE void go(); };",
+		"README.md":          "ignored",
+		".git/objects/x.qml": "Item { id: hidden }",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		includeCpp bool
+		want       []string
+		wantCpp    int
+	}{
+		{name: "qml only", want: []string{"/qml/Main.qml", "/qml/util.js", "Item", "function", "helper", "id", "main"}},
+		{name: "with cpp", includeCpp: true, wantCpp: 1, want: []string{"/qml/Main.qml", "/qml/util.js", "Handler", "Item", "function", "go", "helper", "id", "main"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Dir(root, tt.includeCpp)
+			if err != nil {
+				t.Fatalf("Dir() error = %v", err)
+			}
+			var got []string
+			for s := range result.Strings {
+				got = append(got, s)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) || result.QMLFiles != 2 || result.CppFiles != tt.wantCpp {
+				t.Errorf("Dir() = %v (%d QML, %d C++), want %v", got, result.QMLFiles, result.CppFiles, tt.want)
+			}
+		})
+	}
+
+	if _, err := Dir(filepath.Join(root, "missing"), false); err == nil {
+		t.Error("Dir() expected error for a missing directory")
+	}
+}
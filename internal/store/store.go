@@ -0,0 +1,279 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/bundle"
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/cache"
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+const defaultDirName = "hashtabs"
+
+// Store is a local hashtable mirror. It uses the same layout as an extracted
+// offline bundle: manifest.json plus hashtables/<name>.
+type Store struct {
+	Dir string
+}
+
+// Source is the part of the API client a sync needs.
+type Source interface {
+	ListHashtables(ctx context.Context) (*api.HashtablesResponse, error)
+	DownloadHashtable(ctx context.Context, name string, w io.Writer) (int64, error)
+}
+
+type SyncResult struct {
+	Added     []string
+	Updated   []string
+	Unchanged []string
+	Removed   []string
+}
+
+func DefaultDir() (string, error) {
+	dir, err := cache.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, defaultDirName), nil
+}
+
+func Open(dir string) *Store {
+	return &Store{Dir: dir}
+}
+
+// Manifest returns the store's manifest, or an empty one if the store has
+// never been synced.
+func (s *Store) Manifest() (*bundle.Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(s.Dir, bundle.ManifestName))
+	if errors.Is(err, os.ErrNotExist) {
+		return &bundle.Manifest{FormatVersion: bundle.FormatVersion, Hashtables: make([]bundle.Entry, 0)}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mirror manifest: %w", err)
+	}
+
+	var manifest bundle.Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse mirror manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+func (s *Store) SaveManifest(manifest *bundle.Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode mirror manifest: %w", err)
+	}
+	return writeAtomic(filepath.Join(s.Dir, bundle.ManifestName), func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+func (s *Store) Path(entry bundle.Entry) string {
+	return filepath.Join(s.Dir, filepath.FromSlash(entry.Path))
+}
+
+// Sync brings the store up to date with the server. A hashtable is fetched
+// only when it is new or its checksum differs from the local copy; servers
+// that don't publish checksums are compared by entry count instead.
+// Hashtables no longer on the server are removed.
+func (s *Store) Sync(ctx context.Context, src Source, server string) (*SyncResult, error) {
+	manifest, err := s.Manifest()
+	if err != nil {
+		return nil, err
+	}
+
+	local := make(map[string]bundle.Entry)
+	for _, entry := range manifest.Hashtables {
+		local[entry.Name] = entry
+	}
+
+	remote, err := src.ListHashtables(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list hashtables: %w", err)
+	}
+
+	result := &SyncResult{}
+	synced := make([]bundle.Entry, 0, len(remote.Hashtables))
+	onServer := make(map[string]bool)
+
+	for _, ht := range remote.Hashtables {
+		if ht.Name == "" || filepath.Base(ht.Name) != ht.Name || ht.Name == ".." {
+			return nil, fmt.Errorf("server returned an invalid hashtable name %q", ht.Name)
+		}
+		onServer[ht.Name] = true
+
+		existing, ok := local[ht.Name]
+		if ok && upToDate(existing, ht) && s.intact(existing) {
+			result.Unchanged = append(result.Unchanged, ht.Name)
+			synced = append(synced, existing)
+			continue
+		}
+
+		entry, err := s.Fetch(ctx, src, ht)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			result.Updated = append(result.Updated, ht.Name)
+		} else {
+			result.Added = append(result.Added, ht.Name)
+		}
+		synced = append(synced, entry)
+	}
+
+	for name, entry := range local {
+		if onServer[name] {
+			continue
+		}
+		if err := os.Remove(s.Path(entry)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to remove %s: %w", name, err)
+		}
+		result.Removed = append(result.Removed, name)
+	}
+	sort.Strings(result.Removed)
+
+	manifest.FormatVersion = bundle.FormatVersion
+	manifest.Server = server
+	manifest.CreatedAt = time.Now().UTC()
+	manifest.Hashtables = synced
+	if err := s.SaveManifest(manifest); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+type VerifyResult struct {
+	OK       []string
+	Repaired []string
+}
+
+// Verify recomputes the checksum of every mirrored hashtable and compares it
+// with the server's, falling back to the checksum recorded at sync time when
+// the server doesn't publish one. Corrupt, truncated or missing files are
+// downloaded again.
+func (s *Store) Verify(ctx context.Context, src Source) (*VerifyResult, error) {
+	manifest, err := s.Manifest()
+	if err != nil {
+		return nil, err
+	}
+
+	remote, err := src.ListHashtables(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list hashtables: %w", err)
+	}
+	infos := make(map[string]api.HashtableInfo)
+	for _, ht := range remote.Hashtables {
+		infos[ht.Name] = ht
+	}
+
+	result := &VerifyResult{}
+	for i, entry := range manifest.Hashtables {
+		want := entry.SHA256
+		ht, ok := infos[entry.Name]
+		if ok && ht.SHA256 != "" {
+			want = ht.SHA256
+		}
+
+		if sum, err := bundle.FileSHA256(s.Path(entry)); err == nil && sum == want {
+			result.OK = append(result.OK, entry.Name)
+			continue
+		}
+
+		if !ok {
+			ht = api.HashtableInfo{Name: entry.Name, OSVersion: entry.OSVersion, Device: entry.Device, EntryCount: entry.EntryCount, SHA256: entry.SHA256}
+		}
+		repaired, err := s.Fetch(ctx, src, ht)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Hashtables[i] = repaired
+		result.Repaired = append(result.Repaired, entry.Name)
+	}
+
+	if len(result.Repaired) > 0 {
+		if err := s.SaveManifest(manifest); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func upToDate(entry bundle.Entry, ht api.HashtableInfo) bool {
+	if ht.SHA256 != "" {
+		return entry.SHA256 == ht.SHA256
+	}
+	return entry.EntryCount == ht.EntryCount && entry.OSVersion == ht.OSVersion && entry.Device == ht.Device
+}
+
+// intact reports whether the local file still exists with the recorded
+// size. Full checksum verification is left to an explicit verify.
+func (s *Store) intact(entry bundle.Entry) bool {
+	info, err := os.Stat(s.Path(entry))
+	return err == nil && info.Size() == entry.Size
+}
+
+// Fetch downloads one hashtable into the store, replacing any existing copy
+// only once the download is complete and matches the server's checksum.
+func (s *Store) Fetch(ctx context.Context, src Source, ht api.HashtableInfo) (bundle.Entry, error) {
+	entry := bundle.Entry{
+		Path:       path.Join(bundle.HashtableDir, ht.Name),
+		Name:       ht.Name,
+		OSVersion:  ht.OSVersion,
+		Device:     ht.Device,
+		EntryCount: ht.EntryCount,
+	}
+
+	h := sha256.New()
+	err := writeAtomic(s.Path(entry), func(w io.Writer) error {
+		n, err := src.DownloadHashtable(ctx, ht.Name, io.MultiWriter(w, h))
+		if err != nil {
+			return err
+		}
+		entry.Size = n
+		entry.SHA256 = hex.EncodeToString(h.Sum(nil))
+		if ht.SHA256 != "" && entry.SHA256 != ht.SHA256 {
+			return fmt.Errorf("checksum mismatch: server says %s, downloaded %s", ht.SHA256, entry.SHA256)
+		}
+		return nil
+	})
+	if err != nil {
+		return bundle.Entry{}, fmt.Errorf("failed to fetch %s: %w", ht.Name, err)
+	}
+
+	return entry, nil
+}
+
+func writeAtomic(dest string, write func(io.Writer) error) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return fmt.Errorf("failed to create mirror directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dest), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dest)
+}
@@ -0,0 +1,205 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	api "github.com/rmitchellscott/rm-qmd-verify-cli/pkg/client"
+)
+
+type fakeSource struct {
+	files      map[string]string
+	checksums  bool
+	downloaded []string
+}
+
+func (f *fakeSource) ListHashtables(_ context.Context) (*api.HashtablesResponse, error) {
+	resp := &api.HashtablesResponse{}
+	for name, content := range f.files {
+		ht := api.HashtableInfo{Name: name, EntryCount: len(content)}
+		if f.checksums {
+			ht.SHA256 = checksum(content)
+		}
+		resp.Hashtables = append(resp.Hashtables, ht)
+	}
+	return resp, nil
+}
+
+func (f *fakeSource) DownloadHashtable(_ context.Context, name string, w io.Writer) (int64, error) {
+	f.downloaded = append(f.downloaded, name)
+	content, ok := f.files[name]
+	if !ok {
+		return 0, fmt.Errorf("not found")
+	}
+	n, err := io.WriteString(w, content)
+	return int64(n), err
+}
+
+func checksum(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestSync(t *testing.T) {
+	for _, checksums := range []bool{true, false} {
+		t.Run(fmt.Sprintf("checksums=%v", checksums), func(t *testing.T) {
+			s := Open(t.TempDir())
+			src := &fakeSource{checksums: checksums, files: map[string]string{
+				"3.22-rmpp": "aaa",
+				"3.22-rm2":  "bbb",
+			}}
+
+			result, err := s.Sync(context.Background(), src, "https://qmd.example.com")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(result.Added) != 2 || len(src.downloaded) != 2 {
+				t.Fatalf("first sync added %v, downloaded %v", result.Added, src.downloaded)
+			}
+
+			src.downloaded = nil
+			src.files["3.22-rmpp"] = "aaaa"
+			delete(src.files, "3.22-rm2")
+			src.files["3.23-rm2"] = "ccc"
+
+			result, err = s.Sync(context.Background(), src, "https://qmd.example.com")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(result.Added, []string{"3.23-rm2"}) ||
+				!reflect.DeepEqual(result.Updated, []string{"3.22-rmpp"}) ||
+				!reflect.DeepEqual(result.Removed, []string{"3.22-rm2"}) {
+				t.Errorf("second sync = %+v", result)
+			}
+			if len(src.downloaded) != 2 {
+				t.Errorf("second sync downloaded %v, want only the changed hashtables", src.downloaded)
+			}
+
+			src.downloaded = nil
+			result, err = s.Sync(context.Background(), src, "https://qmd.example.com")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(result.Unchanged) != 2 || len(src.downloaded) != 0 {
+				t.Errorf("third sync = %+v, downloaded %v", result, src.downloaded)
+			}
+
+			data, err := os.ReadFile(filepath.Join(s.Dir, "hashtables", "3.22-rmpp"))
+			if err != nil || string(data) != "aaaa" {
+				t.Errorf("mirrored file = %q, %v", data, err)
+			}
+			if _, err := os.Stat(filepath.Join(s.Dir, "hashtables", "3.22-rm2")); !os.IsNotExist(err) {
+				t.Errorf("removed hashtable still on disk: %v", err)
+			}
+
+			manifest, err := s.Manifest()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if manifest.Server != "https://qmd.example.com" || len(manifest.Hashtables) != 2 {
+				t.Errorf("manifest = %+v", manifest)
+			}
+		})
+	}
+}
+
+func TestSyncRefetchesMissingFile(t *testing.T) {
+	s := Open(t.TempDir())
+	src := &fakeSource{checksums: true, files: map[string]string{"3.22-rmpp": "aaa"}}
+
+	if _, err := s.Sync(context.Background(), src, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(s.Dir, "hashtables", "3.22-rmpp")); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := s.Sync(context.Background(), src, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.Updated, []string{"3.22-rmpp"}) {
+		t.Errorf("result = %+v, want the missing file re-fetched", result)
+	}
+}
+
+func TestSyncChecksumMismatch(t *testing.T) {
+	s := Open(t.TempDir())
+	src := &badSource{fakeSource{files: map[string]string{"3.22-rmpp": "aaa"}}}
+
+	_, err := s.Sync(context.Background(), src, "")
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("err = %v, want checksum mismatch", err)
+	}
+	if _, err := os.Stat(filepath.Join(s.Dir, "hashtables", "3.22-rmpp")); !os.IsNotExist(err) {
+		t.Errorf("corrupt download was kept: %v", err)
+	}
+}
+
+type badSource struct{ fakeSource }
+
+func (b *badSource) ListHashtables(_ context.Context) (*api.HashtablesResponse, error) {
+	return &api.HashtablesResponse{Hashtables: []api.HashtableInfo{{Name: "3.22-rmpp", SHA256: checksum("other")}}}, nil
+}
+
+func TestSyncRejectsInvalidName(t *testing.T) {
+	s := Open(t.TempDir())
+	src := &fakeSource{files: map[string]string{"../escape": "aaa"}}
+
+	if _, err := s.Sync(context.Background(), src, ""); err == nil {
+		t.Fatal("expected an error for a path-traversing name")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	for _, checksums := range []bool{true, false} {
+		t.Run(fmt.Sprintf("checksums=%v", checksums), func(t *testing.T) {
+			s := Open(t.TempDir())
+			src := &fakeSource{checksums: checksums, files: map[string]string{
+				"3.22-rmpp": "aaa",
+				"3.22-rm2":  "bbb",
+				"3.23-rm2":  "ccc",
+			}}
+			if _, err := s.Sync(context.Background(), src, ""); err != nil {
+				t.Fatal(err)
+			}
+
+			dir := filepath.Join(s.Dir, "hashtables")
+			if err := os.WriteFile(filepath.Join(dir, "3.22-rmpp"), []byte("aax"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, "3.22-rm2"), []byte("b"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			src.downloaded = nil
+			result, err := s.Verify(context.Background(), src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sort.Strings(result.Repaired)
+			if !reflect.DeepEqual(result.Repaired, []string{"3.22-rm2", "3.22-rmpp"}) || !reflect.DeepEqual(result.OK, []string{"3.23-rm2"}) {
+				t.Errorf("result = %+v", result)
+			}
+			if len(src.downloaded) != 2 {
+				t.Errorf("downloaded %v, want only the corrupt files", src.downloaded)
+			}
+
+			for name, want := range src.files {
+				data, err := os.ReadFile(filepath.Join(dir, name))
+				if err != nil || string(data) != want {
+					t.Errorf("%s = %q, %v; want %q", name, data, err, want)
+				}
+			}
+		})
+	}
+}
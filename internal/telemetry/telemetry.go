@@ -0,0 +1,150 @@
+package telemetry
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/config"
+)
+
+const (
+	EnvVarTelemetry = "QMDVERIFY_TELEMETRY"
+	EnvVarEndpoint  = "QMDVERIFY_TELEMETRY_ENDPOINT"
+	EndpointPath    = "/api/telemetry"
+	SendTimeout     = 2 * time.Second
+	settingsFile    = "telemetry.json"
+)
+
+type Settings struct {
+	Enabled  bool   `json:"enabled"`
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+// Event is everything that is ever sent: no arguments, paths or file data.
+type Event struct {
+	Command    string `json:"command"`
+	DurationMS int64  `json:"duration_ms"`
+	Success    bool   `json:"success"`
+	CLIVersion string `json:"cli_version"`
+	OS         string `json:"os"`
+	Arch       string `json:"arch"`
+}
+
+func NewEvent(command string, duration time.Duration, success bool, version string) Event {
+	return Event{
+		Command:    command,
+		DurationMS: duration.Milliseconds(),
+		Success:    success,
+		CLIVersion: version,
+		OS:         runtime.GOOS,
+		Arch:       runtime.GOARCH,
+	}
+}
+
+func settingsPath() (string, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, settingsFile), nil
+}
+
+func LoadSettings() (*Settings, error) {
+	path, err := settingsPath()
+	if err != nil {
+		return nil, err
+	}
+
+	settings := &Settings{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return settings, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read telemetry settings: %w", err)
+	}
+
+	if err := json.Unmarshal(data, settings); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	return settings, nil
+}
+
+func (s *Settings) Save() error {
+	path, err := settingsPath()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode telemetry settings: %w", err)
+	}
+
+	if err := os.WriteFile(path, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write telemetry settings: %w", err)
+	}
+
+	return nil
+}
+
+// DisabledByEnv reports whether the environment forces telemetry off,
+// regardless of the stored setting.
+func DisabledByEnv() bool {
+	if os.Getenv("DO_NOT_TRACK") != "" && os.Getenv("DO_NOT_TRACK") != "0" {
+		return true
+	}
+	switch os.Getenv(EnvVarTelemetry) {
+	case "0", "false", "off":
+		return true
+	}
+	return false
+}
+
+func (s *Settings) Active() bool {
+	return s.Enabled && !DisabledByEnv()
+}
+
+// ResolveEndpoint picks QMDVERIFY_TELEMETRY_ENDPOINT, then the stored
+// endpoint, then the configured server's telemetry path.
+func (s *Settings) ResolveEndpoint(server string) string {
+	if endpoint := os.Getenv(EnvVarEndpoint); endpoint != "" {
+		return endpoint
+	}
+	if s.Endpoint != "" {
+		return s.Endpoint
+	}
+	return server + EndpointPath
+}
+
+func Send(endpoint string, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode telemetry event: %w", err)
+	}
+
+	client := &http.Client{Timeout: SendTimeout}
+	resp, err := client.Post(endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to send telemetry: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("telemetry endpoint returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/config"
+)
+
+func TestSettings(t *testing.T) {
+	t.Setenv(config.EnvVarConfigDir, t.TempDir())
+
+	settings, err := LoadSettings()
+	if err != nil {
+		t.Fatalf("LoadSettings() error = %v", err)
+	}
+	if settings.Enabled {
+		t.Error("telemetry must be disabled by default")
+	}
+
+	settings.Enabled = true
+	settings.Endpoint = "https://telemetry.example.com"
+	if err := settings.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	reloaded, err := LoadSettings()
+	if err != nil {
+		t.Fatalf("LoadSettings() error = %v", err)
+	}
+	if *reloaded != *settings {
+		t.Errorf("LoadSettings() = %+v, want %+v", reloaded, settings)
+	}
+}
+
+func TestActive(t *testing.T) {
+	tests := []struct {
+		name       string
+		enabled    bool
+		telemetry  string
+		doNotTrack string
+		want       bool
+	}{
+		{name: "default off", enabled: false, want: false},
+		{name: "opted in", enabled: true, want: true},
+		{name: "env off", enabled: true, telemetry: "0", want: false},
+		{name: "do not track", enabled: true, doNotTrack: "1", want: false},
+		{name: "do not track zero", enabled: true, doNotTrack: "0", want: true},
+		{name: "env cannot opt in", enabled: false, telemetry: "1", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(EnvVarTelemetry, tt.telemetry)
+			t.Setenv("DO_NOT_TRACK", tt.doNotTrack)
+
+			settings := &Settings{Enabled: tt.enabled}
+			if got := settings.Active(); got != tt.want {
+				t.Errorf("Active() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveEndpoint(t *testing.T) {
+	t.Setenv(EnvVarEndpoint, "")
+
+	settings := &Settings{Enabled: true}
+	if got := settings.ResolveEndpoint("https://qmd.example.com"); got != "https://qmd.example.com/api/telemetry" {
+		t.Errorf("ResolveEndpoint() = %q", got)
+	}
+
+	settings.Endpoint = "https://stored.example.com"
+	if got := settings.ResolveEndpoint("https://qmd.example.com"); got != "https://stored.example.com" {
+		t.Errorf("ResolveEndpoint() = %q", got)
+	}
+
+	t.Setenv(EnvVarEndpoint, "https://env.example.com")
+	if got := settings.ResolveEndpoint("https://qmd.example.com"); got != "https://env.example.com" {
+		t.Errorf("ResolveEndpoint() = %q", got)
+	}
+}
+
+func TestSend(t *testing.T) {
+	var got Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("bad event body: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	event := NewEvent("qmdverify check", 1500*time.Millisecond, true, "v1.2.3")
+	if err := Send(server.URL, event); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if got != event || got.DurationMS != 1500 {
+		t.Errorf("server received %+v, want %+v", got, event)
+	}
+}
+
+func TestSend_ServerError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	if err := Send(server.URL, NewEvent("qmdverify list", time.Second, true, "dev")); err == nil {
+		t.Error("Send() expected error for non-2xx status, got nil")
+	}
+}
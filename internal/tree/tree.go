@@ -0,0 +1,173 @@
+package tree
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/rmitchellscott/rm-qmd-verify-cli/internal/cache"
+)
+
+const defaultDirName = "trees"
+
+// DefaultDir is where downloaded trees are kept unless told otherwise.
+func DefaultDir() (string, error) {
+	dir, err := cache.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, defaultDirName), nil
+}
+
+// Name returns the directory name of a tree, in the same <version>-<device>
+// form as hashtable names.
+func Name(version, device string) string {
+	return version + "-" + device
+}
+
+// ParseName splits a <version>-<device> tree name.
+func ParseName(name string) (version, device string, err error) {
+	version, device, ok := strings.Cut(name, "-")
+	if !ok || version == "" || device == "" || strings.ContainsAny(name, `/\`) {
+		return "", "", fmt.Errorf("invalid tree %q (expected <version>-<device>, e.g. 3.22.4.2-rmpp)", name)
+	}
+	return version, device, nil
+}
+
+// Tree is a downloaded QML tree, indexed by the slash-separated paths of
+// its files relative to the tree root.
+type Tree struct {
+	Name    string
+	Version string
+	Device  string
+	Files   map[string]bool
+}
+
+// LoadAll indexes every <version>-<device> tree under dir, keyed by name.
+// A missing dir holds no trees.
+func LoadAll(dir string) (map[string]*Tree, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]*Tree{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trees from %s: %w", dir, err)
+	}
+
+	trees := make(map[string]*Tree)
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		version, device, err := ParseName(entry.Name())
+		if err != nil {
+			continue
+		}
+
+		t := &Tree{Name: entry.Name(), Version: version, Device: device, Files: make(map[string]bool)}
+		root := filepath.Join(dir, entry.Name())
+		err = filepath.WalkDir(root, func(p string, d os.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(root, p)
+			if err != nil {
+				return err
+			}
+			t.Files[filepath.ToSlash(rel)] = true
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tree %s: %w", entry.Name(), err)
+		}
+		trees[t.Name] = t
+		cache.Touch(root)
+	}
+	return trees, nil
+}
+
+// Has reports whether the tree contains the file a QMD AFFECT names. Trees
+// are unpacked from different roots on different servers, so a path also
+// matches when one is a suffix of the other at a directory boundary.
+func (t *Tree) Has(file string) bool {
+	file = strings.TrimPrefix(path.Clean("/"+file), "/")
+	if t.Files[file] {
+		return true
+	}
+	for name := range t.Files {
+		if strings.HasSuffix(name, "/"+file) || strings.HasSuffix(file, "/"+name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Unpack extracts a tree archive into dir, replacing any previous copy only
+// once the whole archive has been read. It returns the number of files
+// extracted.
+func Unpack(r io.Reader, dir string) (int, error) {
+	parent := filepath.Dir(dir)
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return 0, fmt.Errorf("failed to create tree directory: %w", err)
+	}
+
+	staging, err := os.MkdirTemp(parent, ".tmp-*")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create tree directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+
+	count := 0
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read tree archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name := path.Clean(header.Name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return 0, fmt.Errorf("tree archive contains unsafe path %q", header.Name)
+		}
+		if err := extractFile(tr, filepath.Join(staging, filepath.FromSlash(name))); err != nil {
+			return 0, err
+		}
+		count++
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return 0, fmt.Errorf("failed to replace %s: %w", dir, err)
+	}
+	if err := os.Rename(staging, dir); err != nil {
+		return 0, fmt.Errorf("failed to replace %s: %w", dir, err)
+	}
+	return count, nil
+}
+
+func extractFile(r io.Reader, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	f, err := os.Create(dest)
+	if err != nil {
+		return fmt.Errorf("failed to extract %s: %w", dest, err)
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, r); err != nil {
+		return fmt.Errorf("failed to extract %s: %w", dest, err)
+	}
+	return nil
+}
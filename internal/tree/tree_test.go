@@ -0,0 +1,146 @@
+package tree
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseName(t *testing.T) {
+	tests := []struct {
+		name        string
+		wantVersion string
+		wantDevice  string
+		wantErr     bool
+	}{
+		{name: "3.22.4.2-rmpp", wantVersion: "3.22.4.2", wantDevice: "rmpp"},
+		{name: "3.22.4.2", wantErr: true},
+		{name: "-rmpp", wantErr: true},
+		{name: "3.22.4.2-", wantErr: true},
+		{name: "../3.22-rmpp", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version, device, err := ParseName(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if version != tt.wantVersion || device != tt.wantDevice {
+				t.Errorf("got %q, %q", version, device)
+			}
+			if err == nil && Name(version, device) != tt.name {
+				t.Errorf("Name round trip = %q", Name(version, device))
+			}
+		})
+	}
+}
+
+func archive(t *testing.T, files map[string]string) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func TestUnpack(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "3.22.4.2-rmpp")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "stale.qml"), []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := Unpack(archive(t, map[string]string{
+		"qml/main.qml":         "Item {}",
+		"qml/components/A.qml": "Rectangle {}",
+	}), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("count = %d, want 2", count)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "qml", "components", "A.qml"))
+	if err != nil || string(data) != "Rectangle {}" {
+		t.Errorf("A.qml = %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "stale.qml")); !os.IsNotExist(err) {
+		t.Errorf("previous tree was not replaced: %v", err)
+	}
+}
+
+func TestUnpackUnsafePath(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "3.22.4.2-rmpp")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "main.qml"), []byte("keep"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := Unpack(archive(t, map[string]string{"../escape.qml": "x"}), dir)
+	if err == nil || !strings.Contains(err.Error(), "unsafe path") {
+		t.Fatalf("err = %v, want unsafe path", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "main.qml")); string(data) != "keep" {
+		t.Error("existing tree was modified by a failed unpack")
+	}
+}
+
+func TestLoadAll(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"3.22.4.2-rmpp/qml/main.qml", "3.22.4.2-rmpp/qml/components/Button.qml", "3.20.0.92-rm2/main.qml", "notatree/x.qml"} {
+		dest := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(dest), 0755)
+		if err := os.WriteFile(dest, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	trees, err := LoadAll(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trees) != 2 {
+		t.Fatalf("loaded %d trees, want 2", len(trees))
+	}
+
+	tr := trees["3.22.4.2-rmpp"]
+	if tr == nil || tr.Version != "3.22.4.2" || tr.Device != "rmpp" {
+		t.Fatalf("tree = %+v", tr)
+	}
+	for file, want := range map[string]bool{
+		"qml/main.qml":                       true,
+		"/qml/main.qml":                      true,
+		"components/Button.qml":              true,
+		"/usr/share/remarkable/qml/main.qml": true,
+		"main.qml":                           true,
+		"ain.qml":                            false,
+		"qml/Missing.qml":                    false,
+	} {
+		if got := tr.Has(file); got != want {
+			t.Errorf("Has(%q) = %v, want %v", file, got, want)
+		}
+	}
+
+	if trees, err := LoadAll(filepath.Join(dir, "missing")); err != nil || len(trees) != 0 {
+		t.Errorf("missing dir: %v, %v", trees, err)
+	}
+}
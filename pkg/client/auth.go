@@ -0,0 +1,229 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// tokenExpirySkew refreshes tokens slightly before they expire so requests in
+// flight don't race the deadline.
+const tokenExpirySkew = 30 * time.Second
+
+var (
+	ErrAuthNotSupported     = errors.New("server does not support this authentication method")
+	ErrUnauthorized         = errors.New("server rejected the credentials")
+	ErrAuthorizationPending = errors.New("authorization pending")
+	ErrSlowDown             = errors.New("polling too quickly")
+)
+
+type DeviceCodeResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete,omitempty"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	TokenType    string `json:"token_type,omitempty"`
+	ExpiresIn    int    `json:"expires_in,omitempty"`
+}
+
+type WhoAmIResponse struct {
+	User string `json:"user"`
+}
+
+type tokenErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// StartDeviceLogin begins an OAuth 2.0 device authorization grant (RFC 8628).
+func (c *Client) StartDeviceLogin(ctx context.Context) (*DeviceCodeResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", c.BaseURL+"/api/auth/device", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	c.scope(req)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrAuthNotSupported
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &APIError{StatusCode: resp.StatusCode}
+	}
+
+	var result DeviceCodeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &result, nil
+}
+
+// PollDeviceToken exchanges a device code for a token. It returns
+// ErrAuthorizationPending or ErrSlowDown while the user has not yet approved.
+func (c *Client) PollDeviceToken(ctx context.Context, deviceCode string) (*TokenResponse, error) {
+	return c.requestToken(ctx, map[string]string{
+		"grant_type":  "urn:ietf:params:oauth:grant-type:device_code",
+		"device_code": deviceCode,
+	})
+}
+
+// RefreshAccessToken exchanges a refresh token for a new access token.
+func (c *Client) RefreshAccessToken(ctx context.Context, refreshToken string) (*TokenResponse, error) {
+	return c.requestToken(ctx, map[string]string{
+		"grant_type":    "refresh_token",
+		"refresh_token": refreshToken,
+	})
+}
+
+// errNoRefreshToken is returned by refreshAccessToken when the client has no
+// refresh token to exchange.
+var errNoRefreshToken = errors.New("no refresh token")
+
+// accessToken returns the current access token and whether it is due for a
+// refresh.
+func (c *Client) accessToken() (string, bool) {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	return c.Token, c.tokenExpired()
+}
+
+// tokenExpired must be called with tokenMu held.
+func (c *Client) tokenExpired() bool {
+	return c.RefreshToken != "" && !c.TokenExpiry.IsZero() && time.Now().Add(tokenExpirySkew).After(c.TokenExpiry)
+}
+
+// refreshAccessToken swaps in a new access token for stale and reports it
+// through OnTokenRefresh so callers can persist it. If another request has
+// already replaced stale, its token is kept and no refresh is made.
+func (c *Client) refreshAccessToken(ctx context.Context, stale string) error {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+
+	if c.Token != stale {
+		return nil
+	}
+	if c.RefreshToken == "" {
+		return errNoRefreshToken
+	}
+
+	token, err := c.RefreshAccessToken(ctx, c.RefreshToken)
+	if err != nil {
+		return err
+	}
+
+	c.Token = token.AccessToken
+	if token.RefreshToken != "" {
+		c.RefreshToken = token.RefreshToken
+	}
+	c.TokenExpiry = time.Time{}
+	if token.ExpiresIn > 0 {
+		c.TokenExpiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+
+	if c.OnTokenRefresh != nil {
+		c.OnTokenRefresh(token)
+	}
+
+	return nil
+}
+
+func (c *Client) requestToken(ctx context.Context, grant map[string]string) (*TokenResponse, error) {
+	body, err := json.Marshal(grant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.BaseURL+"/api/auth/token", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c.scope(req)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrAuthNotSupported
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp tokenErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil {
+			return nil, &APIError{StatusCode: resp.StatusCode}
+		}
+		switch errResp.Error {
+		case "authorization_pending":
+			return nil, ErrAuthorizationPending
+		case "slow_down":
+			return nil, ErrSlowDown
+		case "":
+			return nil, &APIError{StatusCode: resp.StatusCode}
+		}
+		if errResp.ErrorDescription != "" {
+			return nil, fmt.Errorf("login failed: %s (%s)", errResp.ErrorDescription, errResp.Error)
+		}
+		return nil, fmt.Errorf("login failed: %s", errResp.Error)
+	}
+
+	var result TokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if result.AccessToken == "" {
+		return nil, fmt.Errorf("server returned an empty access token")
+	}
+
+	return &result, nil
+}
+
+// WhoAmI validates the client's token and returns the identity it belongs to.
+func (c *Client) WhoAmI(ctx context.Context) (*WhoAmIResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.BaseURL+"/api/auth/whoami", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, ErrAuthNotSupported
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, ErrUnauthorized
+	default:
+		return nil, &APIError{StatusCode: resp.StatusCode}
+	}
+
+	var result WhoAmIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &result, nil
+}
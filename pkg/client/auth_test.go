@@ -0,0 +1,368 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_TokenHeader(t *testing.T) {
+	tests := []struct {
+		name  string
+		token string
+		want  string
+	}{
+		{name: "no token", token: "", want: ""},
+		{name: "token", token: "abc123", want: "Bearer abc123"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.Header.Get("Authorization")
+				json.NewEncoder(w).Encode(VersionResponse{Version: "v1"})
+			}))
+			defer server.Close()
+
+			client := NewClient(server.URL)
+			client.Token = tt.token
+			if _, err := client.GetVersion(context.Background()); err != nil {
+				t.Fatalf("GetVersion() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Authorization = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_TokenOnUploadsAndPolling(t *testing.T) {
+	polls := 0
+	seen := make(map[string]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen[r.Method+" "+r.URL.Path] = r.Header.Get("Authorization")
+		switch r.URL.Path {
+		case "/api/compare":
+			json.NewEncoder(w).Encode(CompareJobResponse{JobID: "job"})
+		case "/api/results/job":
+			polls++
+			if polls == 1 {
+				json.NewEncoder(w).Encode(JobResultsResponse{Status: "pending"})
+				return
+			}
+			json.NewEncoder(w).Encode(ComparisonResponse{TotalChecked: 1})
+		}
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "a.qmd")
+	if err := os.WriteFile(path, []byte("AFFECT [[1]]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	client := NewClient(server.URL)
+	client.Token = "abc123"
+	if _, err := client.CompareQMD(context.Background(), path); err != nil {
+		t.Fatalf("CompareQMD() error = %v", err)
+	}
+	if polls < 2 {
+		t.Fatalf("results polled %d times, want at least 2", polls)
+	}
+	for _, request := range []string{"POST /api/compare", "GET /api/results/job"} {
+		if got := seen[request]; got != "Bearer abc123" {
+			t.Errorf("%s Authorization = %q, want %q", request, got, "Bearer abc123")
+		}
+	}
+}
+
+func TestClient_PollDeviceToken(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    any
+		wantErr error
+	}{
+		{
+			name:   "approved",
+			status: http.StatusOK,
+			body:   TokenResponse{AccessToken: "abc123", ExpiresIn: 3600},
+		},
+		{
+			name:    "pending",
+			status:  http.StatusBadRequest,
+			body:    tokenErrorResponse{Error: "authorization_pending"},
+			wantErr: ErrAuthorizationPending,
+		},
+		{
+			name:    "slow down",
+			status:  http.StatusBadRequest,
+			body:    tokenErrorResponse{Error: "slow_down"},
+			wantErr: ErrSlowDown,
+		},
+		{
+			name:    "not supported",
+			status:  http.StatusNotFound,
+			body:    ErrorResponse{Error: "not found"},
+			wantErr: ErrAuthNotSupported,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/auth/token" {
+					t.Errorf("Unexpected path: %s", r.URL.Path)
+				}
+				var grant map[string]string
+				json.NewDecoder(r.Body).Decode(&grant)
+				if grant["device_code"] != "dev-1" {
+					t.Errorf("device_code = %q", grant["device_code"])
+				}
+				w.WriteHeader(tt.status)
+				json.NewEncoder(w).Encode(tt.body)
+			}))
+			defer server.Close()
+
+			token, err := NewClient(server.URL).PollDeviceToken(context.Background(), "dev-1")
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("PollDeviceToken() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PollDeviceToken() error = %v", err)
+			}
+			if token.AccessToken != "abc123" {
+				t.Errorf("AccessToken = %q", token.AccessToken)
+			}
+		})
+	}
+}
+
+func TestClient_WhoAmI(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr error
+	}{
+		{name: "valid", status: http.StatusOK},
+		{name: "unauthorized", status: http.StatusUnauthorized, wantErr: ErrUnauthorized},
+		{name: "not supported", status: http.StatusNotFound, wantErr: ErrAuthNotSupported},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				json.NewEncoder(w).Encode(WhoAmIResponse{User: "scott"})
+			}))
+			defer server.Close()
+
+			whoami, err := NewClient(server.URL).WhoAmI(context.Background())
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("WhoAmI() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("WhoAmI() error = %v", err)
+			}
+			if whoami.User != "scott" {
+				t.Errorf("User = %q", whoami.User)
+			}
+		})
+	}
+}
+
+func TestClient_RefreshOn401(t *testing.T) {
+	refreshes := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/auth/token":
+			var grant map[string]string
+			json.NewDecoder(r.Body).Decode(&grant)
+			if grant["grant_type"] != "refresh_token" || grant["refresh_token"] != "refresh-1" {
+				t.Errorf("unexpected grant %v", grant)
+			}
+			refreshes++
+			json.NewEncoder(w).Encode(TokenResponse{AccessToken: "new", RefreshToken: "refresh-2", ExpiresIn: 3600})
+		case "/api/compare":
+			if r.Header.Get("Authorization") != "Bearer new" {
+				w.WriteHeader(http.StatusUnauthorized)
+				json.NewEncoder(w).Encode(ErrorResponse{Error: "token expired"})
+				return
+			}
+			if err := r.ParseMultipartForm(1 << 20); err != nil || r.MultipartForm.File["file"] == nil {
+				t.Errorf("retried request lost its body: %v", err)
+			}
+			json.NewEncoder(w).Encode(CompareJobResponse{JobID: "job-1"})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "a.qmd")
+	if err := os.WriteFile(path, []byte("REPLACE foo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var persisted *TokenResponse
+	client := NewClient(server.URL)
+	client.Token = "old"
+	client.RefreshToken = "refresh-1"
+	client.OnTokenRefresh = func(token *TokenResponse) { persisted = token }
+
+	jobID, err := client.submitCompareJob(context.Background(), path)
+	if err != nil {
+		t.Fatalf("submitCompareJob() error = %v", err)
+	}
+	if jobID != "job-1" {
+		t.Errorf("jobID = %q", jobID)
+	}
+	if refreshes != 1 {
+		t.Errorf("refreshes = %d, want 1", refreshes)
+	}
+	if client.Token != "new" || client.RefreshToken != "refresh-2" || client.TokenExpiry.IsZero() {
+		t.Errorf("client tokens not updated: %q %q %v", client.Token, client.RefreshToken, client.TokenExpiry)
+	}
+	if persisted == nil || persisted.AccessToken != "new" {
+		t.Errorf("OnTokenRefresh got %+v", persisted)
+	}
+}
+
+func TestClient_RefreshExpiredToken(t *testing.T) {
+	var seen []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.URL.Path)
+		switch r.URL.Path {
+		case "/api/auth/token":
+			json.NewEncoder(w).Encode(TokenResponse{AccessToken: "new"})
+		default:
+			if r.Header.Get("Authorization") != "Bearer new" {
+				t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+			}
+			json.NewEncoder(w).Encode(HashtablesResponse{})
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	client.Token = "old"
+	client.RefreshToken = "refresh-1"
+	client.TokenExpiry = time.Now().Add(-time.Minute)
+
+	if _, err := client.ListHashtables(context.Background()); err != nil {
+		t.Fatalf("ListHashtables() error = %v", err)
+	}
+	if len(seen) != 2 || seen[0] != "/api/auth/token" {
+		t.Errorf("requests = %v, want refresh before the request", seen)
+	}
+}
+
+func TestClient_ConcurrentRefresh(t *testing.T) {
+	var refreshes atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/auth/token":
+			refreshes.Add(1)
+			time.Sleep(10 * time.Millisecond)
+			json.NewEncoder(w).Encode(TokenResponse{AccessToken: "new", ExpiresIn: 3600})
+		default:
+			if r.Header.Get("Authorization") != "Bearer new" {
+				w.WriteHeader(http.StatusUnauthorized)
+				json.NewEncoder(w).Encode(ErrorResponse{Error: "token expired"})
+				return
+			}
+			json.NewEncoder(w).Encode(HashtablesResponse{})
+		}
+	}))
+	defer server.Close()
+
+	for _, tt := range []struct {
+		name   string
+		expiry time.Time
+	}{
+		{name: "expired", expiry: time.Now().Add(-time.Minute)},
+		{name: "rejected", expiry: time.Now().Add(time.Hour)},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			refreshes.Store(0)
+			client := NewClient(server.URL)
+			client.Token = "old"
+			client.RefreshToken = "refresh-1"
+			client.TokenExpiry = tt.expiry
+
+			var wg sync.WaitGroup
+			for i := 0; i < 8; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if _, err := client.ListHashtables(context.Background()); err != nil {
+						t.Errorf("ListHashtables() error = %v", err)
+					}
+				}()
+			}
+			wg.Wait()
+
+			if got := refreshes.Load(); got != 1 {
+				t.Errorf("refreshes = %d, want 1", got)
+			}
+		})
+	}
+}
+
+func TestClient_RefreshExpiredTokenError(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "invalid_grant"})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	client.Token = "old"
+	client.RefreshToken = "refresh-1"
+	client.TokenExpiry = time.Now().Add(-time.Minute)
+
+	_, err := client.ListHashtables(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "failed to refresh access token") {
+		t.Errorf("ListHashtables() error = %v, want the refresh error", err)
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want only the refresh", calls)
+	}
+}
+
+func TestClient_401WithoutRefreshToken(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "unauthorized"})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	client.Token = "old"
+
+	if _, err := client.ListHashtables(context.Background()); err == nil {
+		t.Error("ListHashtables() expected error")
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want no retry", calls)
+	}
+}
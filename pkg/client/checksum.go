@@ -0,0 +1,35 @@
+package client
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ChecksumField is the multipart field carrying each file's SHA-256. In a
+// batch it follows the file's "paths" field.
+const ChecksumField = "sha256"
+
+// verifyChecksums compares the digests computed before upload with the ones
+// the server echoed back, so verdicts are known to cover the bytes on disk.
+func verifyChecksums(sent, echoed map[string]string) error {
+	if len(echoed) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(sent))
+	for name := range sent {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		got, ok := echoed[name]
+		if !ok {
+			return fmt.Errorf("server did not report a checksum for %s", name)
+		}
+		if got != sent[name] {
+			return fmt.Errorf("checksum mismatch for %s: sent %s, server received %s", name, sent[name], got)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerifyChecksums(t *testing.T) {
+	sent := map[string]string{"a.qmd": "aaa", "lib/b.qmd": "bbb"}
+	tests := []struct {
+		name    string
+		echoed  map[string]string
+		wantErr bool
+	}{
+		{name: "not echoed", echoed: nil},
+		{name: "match", echoed: map[string]string{"a.qmd": "aaa", "lib/b.qmd": "bbb"}},
+		{name: "mismatch", echoed: map[string]string{"a.qmd": "aaa", "lib/b.qmd": "ccc"}, wantErr: true},
+		{name: "missing file", echoed: map[string]string{"a.qmd": "aaa"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := verifyChecksums(sent, tt.echoed); (err != nil) != tt.wantErr {
+				t.Errorf("verifyChecksums() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClient_SubmitChecksums(t *testing.T) {
+	content := []byte("test content")
+	sum := sha256.Sum256(content)
+	want := hex.EncodeToString(sum[:])
+
+	for _, tt := range []struct {
+		name    string
+		echo    string
+		wantErr bool
+	}{
+		{name: "echoed digest matches", echo: want},
+		{name: "body mangled in transit", echo: "0000", wantErr: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.FormValue(ChecksumField); got != want {
+					t.Errorf("%s field = %q, want %q", ChecksumField, got, want)
+				}
+				json.NewEncoder(w).Encode(CompareJobResponse{JobID: "job", Checksums: map[string]string{"test.qmd": tt.echo}})
+			}))
+			defer server.Close()
+
+			path := filepath.Join(t.TempDir(), "test.qmd")
+			if err := os.WriteFile(path, content, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := NewClient(server.URL).submitCompareJob(context.Background(), path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("submitCompareJob() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
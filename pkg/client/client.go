@@ -0,0 +1,651 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	RequestTimeout      = 60 * time.Second
+	PollInterval        = 500 * time.Millisecond
+	PollIntervalSlow    = 1 * time.Second
+	PollSlowAfter       = 10 * time.Second
+	MaxPollingDuration  = 60 * time.Second
+	OrgHeader           = "X-QMDVerify-Org"
+)
+
+// Client talks to a qmdverify server. Create one with NewClient; its fields
+// may be changed before the first request but not during requests.
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+	Token      string
+	Org        string
+
+	RefreshToken   string
+	TokenExpiry    time.Time
+	OnTokenRefresh func(*TokenResponse)
+
+	// OnTiming is called after each comparison with where its time went.
+	OnTiming func(Timing)
+
+	// OnUploadProgress is called as the body of a batch upload is sent.
+	OnUploadProgress func(UploadProgress)
+
+	// ChunkedUpload is ChunkedAuto (the default when empty), ChunkedAlways
+	// or ChunkedNever.
+	ChunkedUpload string
+
+	// UploadRateLimit caps request bodies at this many bytes per second,
+	// across all requests of the client. Zero means unlimited.
+	UploadRateLimit int64
+
+	// OnJobResults is called with the raw body of each completed job's
+	// results, so they can be kept after the server discards the job.
+	OnJobResults func(jobID string, body []byte)
+
+	// Retry is how network errors and 5xx responses are retried.
+	Retry RetryPolicy
+
+	tokenMu     sync.Mutex
+	limiterOnce sync.Once
+	limiter     *rateLimiter
+	uploadOnce  sync.Once
+	upload      *http.Client
+	socket      string
+}
+
+type HashError struct {
+	HashID uint64 `json:"hash_id"`
+	Error  string `json:"error"`
+}
+
+type ValidationResult struct {
+	Status     string      `json:"status"`
+	HashErrors []HashError `json:"hash_errors,omitempty"`
+	// Shared marks a reference to a dependency whose full results are
+	// reported once for every file that uses it.
+	Shared bool `json:"shared,omitempty"`
+}
+
+// ComparisonResult is the outcome of a check against one device and OS
+// version.
+type ComparisonResult struct {
+	Hashtable          string                       `json:"hashtable"`
+	OSVersion          string                       `json:"os_version"`
+	Device             string                       `json:"device"`
+	Compatible         bool                         `json:"compatible"`
+	ErrorDetail        string                       `json:"error_detail,omitempty"`
+	ValidationMode     string                       `json:"validation_mode"`
+	FilesProcessed     int                          `json:"files_processed,omitempty"`
+	FilesModified      int                          `json:"files_modified,omitempty"`
+	FilesWithErrors    int                          `json:"files_with_errors,omitempty"`
+	TreeValidationUsed bool                         `json:"tree_validation_used"`
+	DependencyResults  map[string]*ValidationResult `json:"dependency_results,omitempty"`
+}
+
+// ComparisonResponse holds the results of checking one file against every
+// hashtable the server has.
+type ComparisonResponse struct {
+	Compatible   []ComparisonResult `json:"compatible"`
+	Incompatible []ComparisonResult `json:"incompatible"`
+	TotalChecked int                `json:"total_checked"`
+	Mode         string             `json:"mode"`
+}
+
+// BatchComparisonResponse maps the relative path of each file of a batch to
+// its results.
+type BatchComparisonResponse map[string]ComparisonResponse
+
+type TreeInfo struct {
+	Version   string `json:"version"`
+	Device    string `json:"device"`
+	QMLCount  int    `json:"qml_count"`
+	Path      string `json:"path"`
+	Directory string `json:"directory"`
+}
+
+type TreesResponse struct {
+	Trees []TreeInfo `json:"trees"`
+	Count int        `json:"count"`
+}
+
+type HashtableInfo struct {
+	Name       string `json:"name"`
+	OSVersion  string `json:"os_version"`
+	Device     string `json:"device"`
+	EntryCount int    `json:"entry_count"`
+	SHA256     string `json:"sha256,omitempty"`
+}
+
+type HashtablesResponse struct {
+	Hashtables []HashtableInfo `json:"hashtables"`
+	Count      int             `json:"count"`
+}
+
+type VersionResponse struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+type CompareJobResponse struct {
+	JobID string `json:"jobId"`
+	// Checksums echoes the SHA-256 the server computed for each uploaded
+	// file, keyed by path. Older servers leave it out.
+	Checksums map[string]string `json:"checksums,omitempty"`
+}
+
+type JobResultsResponse struct {
+	Status       string              `json:"status"`
+	Results      *ComparisonResponse `json:"results,omitempty"`
+	Error        string              `json:"error,omitempty"`
+	Message      string              `json:"message,omitempty"`
+	QueueMS      int64               `json:"queue_ms,omitempty"`
+	ProcessingMS int64               `json:"processing_ms,omitempty"`
+}
+
+// Timing records where a check spent its time, in milliseconds. The queue
+// and processing times are only known when the server reports them, and
+// TotalMS is the wall time of the whole run.
+type Timing struct {
+	UploadMS     int64 `json:"upload_ms,omitempty"`
+	QueueMS      int64 `json:"queue_ms,omitempty"`
+	ProcessingMS int64 `json:"processing_ms,omitempty"`
+	TotalMS      int64 `json:"total_ms,omitempty"`
+}
+
+// NewClient returns a client for the server at baseURL, which may be a
+// unix:///path/to.sock socket address.
+func NewClient(baseURL string, opts ...Option) *Client {
+	client := &Client{
+		BaseURL: baseURL,
+		HTTPClient: &http.Client{
+			Timeout: RequestTimeout,
+		},
+	}
+	for _, opt := range opts {
+		opt(client)
+	}
+	if socket, ok := strings.CutPrefix(baseURL, "unix://"); ok {
+		hc := *client.HTTPClient
+		hc.Transport = unixTransport(socket)
+		client.BaseURL = unixBaseURL
+		client.socket = socket
+		client.HTTPClient = &hc
+	}
+	return client
+}
+
+// do sends an authenticated request, retrying transient failures as Retry
+// allows. An expired token is refreshed first, and a 401 is retried once
+// after refreshing when a refresh token is known. Concurrent requests share
+// a single refresh.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if token, expired := c.accessToken(); expired {
+		if err := c.refreshAccessToken(req.Context(), token); err != nil {
+			return nil, fmt.Errorf("failed to refresh access token: %w", err)
+		}
+	}
+
+	sent := c.authorize(req)
+	c.limitUpload(req)
+	resp, err := c.send(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	retry, ok := cloneRequest(req)
+	if !ok || c.refreshAccessToken(req.Context(), sent) != nil {
+		return resp, nil
+	}
+	resp.Body.Close()
+
+	c.authorize(retry)
+	return c.send(retry)
+}
+
+// authorize sets the current access token on req and returns it.
+func (c *Client) authorize(req *http.Request) string {
+	c.scope(req)
+	token, _ := c.accessToken()
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return token
+}
+
+// scope sets the organization header for multi-tenant servers.
+func (c *Client) scope(req *http.Request) {
+	if c.Org != "" {
+		req.Header.Set(OrgHeader, c.Org)
+	}
+}
+
+// cloneRequest returns a copy of req with a fresh body, or false if the body
+// cannot be replayed.
+func cloneRequest(req *http.Request) (*http.Request, bool) {
+	clone := req.Clone(req.Context())
+	if req.Body == nil || req.Body == http.NoBody {
+		return clone, true
+	}
+	if req.GetBody == nil {
+		return nil, false
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, false
+	}
+	clone.Body = body
+
+	return clone, true
+}
+
+// CompareQMD uploads a QMD file, waits for the job to finish and returns its
+// results. A job the server failed matches ErrJobFailed.
+func (c *Client) CompareQMD(ctx context.Context, filePath string) (*ComparisonResponse, error) {
+	// Step 1: Upload file and get job ID
+	start := time.Now()
+	jobID, err := c.submitCompareJob(ctx, filePath)
+	if err != nil {
+		return nil, err
+	}
+	timing := Timing{UploadMS: time.Since(start).Milliseconds()}
+
+	// Step 2: Poll for results
+	results, err := c.pollJobResults(ctx, jobID, &timing)
+	if err != nil {
+		return nil, err
+	}
+	c.reportTiming(timing)
+	return results, nil
+}
+
+// sleepContext pauses for d, returning the context's error early if it is
+// cancelled.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (c *Client) reportTiming(timing Timing) {
+	if c.OnTiming != nil {
+		c.OnTiming(timing)
+	}
+}
+
+func (c *Client) submitCompareJob(ctx context.Context, filePath string) (string, error) {
+	form := newUploadForm("file", []string{filePath}, []string{filepath.Base(filePath)}, false)
+	resp, sums, err := c.submitForm(ctx, form)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	return decodeCompareJob(resp, sums)
+}
+
+func (c *Client) pollJobResults(ctx context.Context, jobID string, timing *Timing) (*ComparisonResponse, error) {
+	startTime := time.Now()
+	pollInterval := PollInterval
+
+	for {
+		// Check timeout
+		if time.Since(startTime) > MaxPollingDuration {
+			return nil, fmt.Errorf("job polling timed out after %v", MaxPollingDuration)
+		}
+
+		// Switch to slower polling after initial period
+		if time.Since(startTime) > PollSlowAfter {
+			pollInterval = PollIntervalSlow
+		}
+
+		// Poll for results
+		results, status, err := c.getJobResults(ctx, jobID, timing)
+		if err != nil {
+			return nil, err
+		}
+
+		switch status {
+		case "success":
+			if results == nil {
+				return nil, fmt.Errorf("job succeeded but no results returned")
+			}
+			return results, nil
+		case "error":
+			return nil, ErrJobFailed
+		case "running", "pending":
+			// Continue polling
+			if err := sleepContext(ctx, pollInterval); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unknown job status: %s", status)
+		}
+	}
+}
+
+func (c *Client) getJobResults(ctx context.Context, jobID string, timing *Timing) (*ComparisonResponse, string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.BaseURL+"/api/results/"+jobID, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Status 202 means still processing
+	if resp.StatusCode == http.StatusAccepted {
+		return nil, "running", nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", responseError(resp)
+	}
+
+	// Try to decode as direct ComparisonResponse (some endpoints return this directly)
+	var directResult ComparisonResponse
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	// Try direct ComparisonResponse first
+	if err := json.Unmarshal(bodyBytes, &directResult); err == nil {
+		// If it has results, return as success
+		if directResult.TotalChecked > 0 || len(directResult.Compatible) > 0 || len(directResult.Incompatible) > 0 {
+			c.reportJobResults(jobID, bodyBytes)
+			return &directResult, "success", nil
+		}
+	}
+
+	// Otherwise try wrapped JobResultsResponse
+	var jobResult JobResultsResponse
+	if err := json.Unmarshal(bodyBytes, &jobResult); err != nil {
+		return nil, "", fmt.Errorf("failed to decode results: %w", err)
+	}
+
+	if jobResult.Status == "error" {
+		errorMsg := jobResult.Error
+		if errorMsg == "" {
+			errorMsg = jobResult.Message
+		}
+		if errorMsg == "" {
+			errorMsg = "unknown error"
+		}
+		return nil, "error", &JobError{JobID: jobID, Message: errorMsg}
+	}
+
+	if jobResult.Status == "success" && jobResult.Results != nil {
+		c.reportJobResults(jobID, bodyBytes)
+	}
+
+	timing.QueueMS = jobResult.QueueMS
+	timing.ProcessingMS = jobResult.ProcessingMS
+	return jobResult.Results, jobResult.Status, nil
+}
+
+func (c *Client) reportJobResults(jobID string, body []byte) {
+	if c.OnJobResults != nil {
+		c.OnJobResults(jobID, body)
+	}
+}
+
+// GetJobResults returns the raw results of a completed job.
+func (c *Client) GetJobResults(ctx context.Context, jobID string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.BaseURL+"/api/results/"+jobID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusAccepted {
+		return nil, fmt.Errorf("job %s is still running", jobID)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var wrapped JobResultsResponse
+	if json.Unmarshal(body, &wrapped) == nil && wrapped.Status != "" && wrapped.Status != "success" {
+		return nil, fmt.Errorf("job %s has status %s", jobID, wrapped.Status)
+	}
+	return body, nil
+}
+
+// ListHashtables returns the hashtables the server checks against.
+func (c *Client) ListHashtables(ctx context.Context) (*HashtablesResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.BaseURL+"/api/hashtables", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp)
+	}
+
+	var result HashtablesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &result, nil
+}
+
+// GetVersion returns the server's version.
+func (c *Client) GetVersion(ctx context.Context) (*VersionResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.BaseURL+"/api/version", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp)
+	}
+
+	var result VersionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &result, nil
+}
+
+// CompareQMDFiles uploads files as one job, so files that LOAD each other
+// are resolved together, and returns the results keyed by relativePaths.
+func (c *Client) CompareQMDFiles(ctx context.Context, filePaths []string, relativePaths []string) (*BatchComparisonResponse, error) {
+	start := time.Now()
+	jobID, err := c.submitCompareJobMulti(ctx, filePaths, relativePaths)
+	if err != nil {
+		return nil, err
+	}
+	timing := Timing{UploadMS: time.Since(start).Milliseconds()}
+
+	results, err := c.pollBatchJobResults(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	c.reportTiming(timing)
+	return results, nil
+}
+
+func (c *Client) submitCompareJobMulti(ctx context.Context, filePaths []string, relativePaths []string) (string, error) {
+	names := make([]string, len(filePaths))
+	for i, filePath := range filePaths {
+		if i < len(relativePaths) {
+			names[i] = relativePaths[i]
+		} else {
+			names[i] = filepath.Base(filePath)
+		}
+	}
+
+	resp, sums, err := c.submitForm(ctx, newUploadForm("files", filePaths, names, true))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	return decodeCompareJob(resp, sums)
+}
+
+// decodeCompareJob reads the job ID from the response to a submission and
+// checks the checksums the server echoed against sums.
+func decodeCompareJob(resp *http.Response, sums map[string]string) (string, error) {
+	if resp.StatusCode != http.StatusOK {
+		return "", responseError(resp)
+	}
+
+	var jobResp CompareJobResponse
+	if err := json.NewDecoder(resp.Body).Decode(&jobResp); err != nil {
+		return "", fmt.Errorf("failed to decode job response: %w", err)
+	}
+
+	if jobResp.JobID == "" {
+		return "", fmt.Errorf("server returned empty job ID")
+	}
+
+	if err := verifyChecksums(sums, jobResp.Checksums); err != nil {
+		return "", err
+	}
+
+	return jobResp.JobID, nil
+}
+
+func (c *Client) pollBatchJobResults(ctx context.Context, jobID string) (*BatchComparisonResponse, error) {
+	startTime := time.Now()
+	pollInterval := PollInterval
+
+	for {
+		if time.Since(startTime) > MaxPollingDuration {
+			return nil, fmt.Errorf("job polling timed out after %v", MaxPollingDuration)
+		}
+
+		if time.Since(startTime) > PollSlowAfter {
+			pollInterval = PollIntervalSlow
+		}
+
+		results, status, err := c.getBatchJobResults(ctx, jobID)
+		if err != nil {
+			return nil, err
+		}
+
+		switch status {
+		case "success":
+			if results == nil {
+				return nil, fmt.Errorf("job succeeded but no results returned")
+			}
+			return results, nil
+		case "error":
+			return nil, ErrJobFailed
+		case "running", "pending":
+			if err := sleepContext(ctx, pollInterval); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unknown job status: %s", status)
+		}
+	}
+}
+
+func (c *Client) getBatchJobResults(ctx context.Context, jobID string) (*BatchComparisonResponse, string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.BaseURL+"/api/results/"+jobID, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusAccepted {
+		return nil, "running", nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", responseError(resp)
+	}
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var batchResult BatchComparisonResponse
+	if err := json.Unmarshal(bodyBytes, &batchResult); err != nil {
+		return nil, "", fmt.Errorf("failed to decode batch results: %w", err)
+	}
+
+	c.reportJobResults(jobID, bodyBytes)
+	return &batchResult, "success", nil
+}
+
+// ListTrees returns the QML trees the server can download.
+func (c *Client) ListTrees(ctx context.Context) (*TreesResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.BaseURL+"/api/trees", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp)
+	}
+
+	var result TreesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &result, nil
+}
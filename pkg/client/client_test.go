@@ -1,7 +1,9 @@
-package api
+package client
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -68,7 +70,7 @@ func TestClient_CompareQMD(t *testing.T) {
 			t.Fatalf("Failed to create test file: %v", err)
 		}
 
-		response, err := client.CompareQMD(testFile)
+		response, err := client.CompareQMD(context.Background(), testFile)
 		if err != nil {
 			t.Fatalf("CompareQMD() error = %v", err)
 		}
@@ -84,9 +86,41 @@ func TestClient_CompareQMD(t *testing.T) {
 		}
 	})
 
+	t.Run("success - server timing", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/api/compare" {
+				json.NewEncoder(w).Encode(CompareJobResponse{JobID: "timed"})
+				return
+			}
+			json.NewEncoder(w).Encode(JobResultsResponse{
+				Status:       "success",
+				Results:      &ComparisonResponse{TotalChecked: 1},
+				QueueMS:      120,
+				ProcessingMS: 2100,
+			})
+		}))
+		defer server.Close()
+
+		var got []Timing
+		client := NewClient(server.URL)
+		client.OnTiming = func(timing Timing) { got = append(got, timing) }
+
+		testFile := filepath.Join(t.TempDir(), "test.qmd")
+		if err := os.WriteFile(testFile, []byte("test"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+
+		if _, err := client.CompareQMD(context.Background(), testFile); err != nil {
+			t.Fatalf("CompareQMD() error = %v", err)
+		}
+		if len(got) != 1 || got[0].QueueMS != 120 || got[0].ProcessingMS != 2100 {
+			t.Errorf("OnTiming() calls = %+v", got)
+		}
+	})
+
 	t.Run("error - file not found", func(t *testing.T) {
 		client := NewClient("http://example.com")
-		_, err := client.CompareQMD("/nonexistent/file.qmd")
+		_, err := client.CompareQMD(context.Background(), "/nonexistent/file.qmd")
 		if err == nil {
 			t.Error("CompareQMD() expected error for nonexistent file, got nil")
 		}
@@ -109,7 +143,7 @@ func TestClient_CompareQMD(t *testing.T) {
 			t.Fatalf("Failed to create test file: %v", err)
 		}
 
-		_, err := client.CompareQMD(testFile)
+		_, err := client.CompareQMD(context.Background(), testFile)
 		if err == nil {
 			t.Error("CompareQMD() expected error for server error, got nil")
 		}
@@ -132,7 +166,7 @@ func TestClient_CompareQMD(t *testing.T) {
 			t.Fatalf("Failed to create test file: %v", err)
 		}
 
-		_, err := client.CompareQMD(testFile)
+		_, err := client.CompareQMD(context.Background(), testFile)
 		if err == nil {
 			t.Error("CompareQMD() expected error for bad JSON, got nil")
 		}
@@ -142,7 +176,7 @@ func TestClient_CompareQMD(t *testing.T) {
 		client := NewClient("http://example.com")
 		tmpDir := t.TempDir()
 
-		_, err := client.CompareQMD(tmpDir)
+		_, err := client.CompareQMD(context.Background(), tmpDir)
 		if err == nil {
 			t.Error("CompareQMD() expected error for directory, got nil")
 		}
@@ -173,7 +207,7 @@ func TestClient_ListHashtables(t *testing.T) {
 		defer server.Close()
 
 		client := NewClient(server.URL)
-		response, err := client.ListHashtables()
+		response, err := client.ListHashtables(context.Background())
 		if err != nil {
 			t.Fatalf("ListHashtables() error = %v", err)
 		}
@@ -194,7 +228,7 @@ func TestClient_ListHashtables(t *testing.T) {
 		defer server.Close()
 
 		client := NewClient(server.URL)
-		_, err := client.ListHashtables()
+		_, err := client.ListHashtables(context.Background())
 		if err == nil {
 			t.Error("ListHashtables() expected error for server error, got nil")
 		}
@@ -208,7 +242,7 @@ func TestClient_ListHashtables(t *testing.T) {
 		defer server.Close()
 
 		client := NewClient(server.URL)
-		_, err := client.ListHashtables()
+		_, err := client.ListHashtables(context.Background())
 		if err == nil {
 			t.Error("ListHashtables() expected error for bad JSON, got nil")
 		}
@@ -237,7 +271,7 @@ func TestClient_GetVersion(t *testing.T) {
 		defer server.Close()
 
 		client := NewClient(server.URL)
-		response, err := client.GetVersion()
+		response, err := client.GetVersion(context.Background())
 		if err != nil {
 			t.Fatalf("GetVersion() error = %v", err)
 		}
@@ -258,7 +292,7 @@ func TestClient_GetVersion(t *testing.T) {
 		defer server.Close()
 
 		client := NewClient(server.URL)
-		_, err := client.GetVersion()
+		_, err := client.GetVersion(context.Background())
 		if err == nil {
 			t.Error("GetVersion() expected error for server error, got nil")
 		}
@@ -272,9 +306,145 @@ func TestClient_GetVersion(t *testing.T) {
 		defer server.Close()
 
 		client := NewClient(server.URL)
-		_, err := client.GetVersion()
+		_, err := client.GetVersion(context.Background())
 		if err == nil {
 			t.Error("GetVersion() expected error for bad JSON, got nil")
 		}
 	})
 }
+
+func TestClient_OrgHeader(t *testing.T) {
+	var got string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get(OrgHeader)
+		json.NewEncoder(w).Encode(HashtablesResponse{})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	if _, err := client.ListHashtables(context.Background()); err != nil {
+		t.Fatalf("ListHashtables() error = %v", err)
+	}
+	if got != "" {
+		t.Errorf("%s = %q without an org", OrgHeader, got)
+	}
+
+	client.Org = "team-a"
+	if _, err := client.ListHashtables(context.Background()); err != nil {
+		t.Fatalf("ListHashtables() error = %v", err)
+	}
+	if got != "team-a" {
+		t.Errorf("%s = %q, want team-a", OrgHeader, got)
+	}
+}
+
+func TestClient_OnJobResults(t *testing.T) {
+	polls := 0
+	nextJob := "job-1"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/compare":
+			json.NewEncoder(w).Encode(CompareJobResponse{JobID: nextJob})
+		case "/api/results/job-1":
+			polls++
+			if polls == 1 {
+				w.WriteHeader(http.StatusAccepted)
+				return
+			}
+			json.NewEncoder(w).Encode(JobResultsResponse{Status: "success", Results: &ComparisonResponse{TotalChecked: 1}})
+		case "/api/results/job-2":
+			json.NewEncoder(w).Encode(BatchComparisonResponse{"a.qmd": {TotalChecked: 1}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	got := make(map[string]string)
+	client := NewClient(server.URL)
+	client.OnJobResults = func(jobID string, body []byte) { got[jobID] = string(body) }
+
+	testFile := filepath.Join(t.TempDir(), "a.qmd")
+	if err := os.WriteFile(testFile, []byte("test"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if _, err := client.CompareQMD(context.Background(), testFile); err != nil {
+		t.Fatalf("CompareQMD() error = %v", err)
+	}
+	nextJob = "job-2"
+	if _, err := client.CompareQMDFiles(context.Background(), []string{testFile}, []string{"a.qmd"}); err != nil {
+		t.Fatalf("CompareQMDFiles() error = %v", err)
+	}
+
+	if len(got) != 2 || !json.Valid([]byte(got["job-1"])) || !json.Valid([]byte(got["job-2"])) {
+		t.Errorf("OnJobResults() calls = %v, want the results of job-1 and job-2 once each", got)
+	}
+}
+
+func TestClient_CompareQMDCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/compare":
+			json.NewEncoder(w).Encode(CompareJobResponse{JobID: "job-1"})
+		case "/api/results/job-1":
+			polls++
+			cancel()
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	testFile := filepath.Join(t.TempDir(), "a.qmd")
+	if err := os.WriteFile(testFile, []byte("test"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	_, err := NewClient(server.URL).CompareQMD(ctx, testFile)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("CompareQMD() error = %v, want context.Canceled", err)
+	}
+	if polls != 1 {
+		t.Errorf("polled %d times after cancel, want 1", polls)
+	}
+}
+
+func TestClient_GetJobResults(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/results/done":
+			json.NewEncoder(w).Encode(ComparisonResponse{TotalChecked: 1})
+		case "/api/results/running":
+			w.WriteHeader(http.StatusAccepted)
+		case "/api/results/failed":
+			json.NewEncoder(w).Encode(JobResultsResponse{Status: "error", Error: "boom"})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "job not found"})
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	tests := []struct {
+		jobID   string
+		wantErr bool
+	}{
+		{jobID: "done"},
+		{jobID: "running", wantErr: true},
+		{jobID: "failed", wantErr: true},
+		{jobID: "gone", wantErr: true},
+	}
+	for _, tt := range tests {
+		body, err := client.GetJobResults(context.Background(), tt.jobID)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("GetJobResults(%s) error = %v, wantErr %v", tt.jobID, err, tt.wantErr)
+		}
+		if !tt.wantErr && !json.Valid(body) {
+			t.Errorf("GetJobResults(%s) = %q", tt.jobID, body)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+// Package client is a Go client for the qmdverify server API. It submits
+// QMD files for compatibility checks against reMarkable firmware hashtables
+// and lists the hashtables and QML trees the server has:
+//
+//	c := client.NewClient("https://qmdverify.example.com",
+//		client.WithToken(token),
+//		client.WithRetry(client.RetryPolicy{Retries: 3}),
+//	)
+//	results, err := c.CompareQMD(ctx, "overlay.qmd")
+//
+// Every request takes a context. A non-success response is returned as an
+// *APIError, and a job the server failed matches ErrJobFailed.
+package client
@@ -0,0 +1,43 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// DownloadHashtable streams the raw hashtab file for the named hashtable.
+func (c *Client) DownloadHashtable(ctx context.Context, name string, w io.Writer) (int64, error) {
+	return c.download(ctx, "/api/hashtables/"+url.PathEscape(name)+"/download", w)
+}
+
+// DownloadTree streams a tar archive of the QML tree for a device and OS
+// version.
+func (c *Client) DownloadTree(ctx context.Context, device, version string, w io.Writer) (int64, error) {
+	return c.download(ctx, "/api/trees/"+url.PathEscape(device)+"/"+url.PathEscape(version)+"/download", w)
+}
+
+func (c *Client) download(ctx context.Context, path string, w io.Writer) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.BaseURL+path, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, responseError(resp)
+	}
+
+	n, err := io.Copy(w, resp.Body)
+	if err != nil {
+		return n, fmt.Errorf("failed to download %s: %w", path, err)
+	}
+	return n, nil
+}
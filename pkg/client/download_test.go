@@ -0,0 +1,42 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient_Download(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/hashtables/3.22.0.64-rmpp/download":
+			w.Write([]byte("hashtab"))
+		case "/api/trees/rmpp/3.22.0.64/download":
+			w.Write([]byte("tree"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "not found"})
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+
+	var buf bytes.Buffer
+	n, err := client.DownloadHashtable(context.Background(), "3.22.0.64-rmpp", &buf)
+	if err != nil || n != 7 || buf.String() != "hashtab" {
+		t.Errorf("DownloadHashtable() = %d, %q, %v", n, buf.String(), err)
+	}
+
+	buf.Reset()
+	if _, err := client.DownloadTree(context.Background(), "rmpp", "3.22.0.64", &buf); err != nil || buf.String() != "tree" {
+		t.Errorf("DownloadTree() = %q, %v", buf.String(), err)
+	}
+
+	if _, err := client.DownloadHashtable(context.Background(), "missing", &buf); err == nil {
+		t.Error("DownloadHashtable() expected error for 404")
+	}
+}
@@ -0,0 +1,46 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrJobFailed is matched by the error of a job the server ran and failed.
+var ErrJobFailed = errors.New("job failed on server")
+
+// APIError is a response the server answered with a non-success status.
+type APIError struct {
+	StatusCode int
+	// Message is the server's error message, if it sent one.
+	Message string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("server returned status %d", e.StatusCode)
+	}
+	return "server error: " + e.Message
+}
+
+// JobError is the error a failed job reported.
+type JobError struct {
+	JobID   string
+	Message string
+}
+
+func (e *JobError) Error() string {
+	return e.Message
+}
+
+func (e *JobError) Is(target error) bool {
+	return target == ErrJobFailed
+}
+
+// responseError reads the error message, if any, from a failed response.
+func responseError(resp *http.Response) error {
+	var errResp ErrorResponse
+	json.NewDecoder(resp.Body).Decode(&errResp)
+	return &APIError{StatusCode: resp.StatusCode, Message: errResp.Error}
+}
@@ -0,0 +1,71 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAPIError(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		want    APIError
+		wantMsg string
+	}{
+		{name: "message", status: http.StatusInternalServerError, body: `{"error": "database unavailable"}`, want: APIError{StatusCode: 500, Message: "database unavailable"}, wantMsg: "server error: database unavailable"},
+		{name: "no body", status: http.StatusBadGateway, want: APIError{StatusCode: 502}, wantMsg: "server returned status 502"},
+		{name: "empty message", status: http.StatusForbidden, body: `{"error": ""}`, want: APIError{StatusCode: 403}, wantMsg: "server returned status 403"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			_, err := NewClient(server.URL).ListHashtables(context.Background())
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("ListHashtables() error = %v, want an *APIError", err)
+			}
+			if *apiErr != tt.want {
+				t.Errorf("APIError = %+v, want %+v", *apiErr, tt.want)
+			}
+			if err.Error() != tt.wantMsg {
+				t.Errorf("Error() = %q, want %q", err.Error(), tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestJobError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			json.NewEncoder(w).Encode(CompareJobResponse{JobID: "job-1"})
+			return
+		}
+		json.NewEncoder(w).Encode(JobResultsResponse{Status: "error", Error: "hashtable missing"})
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "mod.qmd")
+	if err := os.WriteFile(path, []byte("AFFECT [[1]] {}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := NewClient(server.URL).CompareQMD(context.Background(), path)
+	var jobErr *JobError
+	if !errors.As(err, &jobErr) || !errors.Is(err, ErrJobFailed) {
+		t.Fatalf("CompareQMD() error = %v, want a *JobError matching ErrJobFailed", err)
+	}
+	if jobErr.JobID != "job-1" || err.Error() != "hashtable missing" {
+		t.Errorf("JobError = %+v", *jobErr)
+	}
+}
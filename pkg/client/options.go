@@ -0,0 +1,63 @@
+package client
+
+import (
+	"net/http"
+	"time"
+)
+
+// Option configures a Client created by NewClient.
+type Option func(*Client)
+
+// WithHTTPClient sends requests with hc instead of a client with
+// RequestTimeout. For a unix:// address, a copy of hc with the socket
+// transport is used.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.HTTPClient = hc
+	}
+}
+
+// WithTimeout sets the timeout of each request.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		hc := *c.HTTPClient
+		hc.Timeout = d
+		c.HTTPClient = &hc
+	}
+}
+
+// WithToken authenticates requests with a bearer token.
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.Token = token
+	}
+}
+
+// WithOrg scopes requests to an organization on a multi-tenant server.
+func WithOrg(org string) Option {
+	return func(c *Client) {
+		c.Org = org
+	}
+}
+
+// WithRetry sets how network errors and 5xx responses are retried.
+func WithRetry(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.Retry = policy
+	}
+}
+
+// WithChunkedUpload sets when batches are uploaded in resumable chunks:
+// ChunkedAuto, ChunkedAlways or ChunkedNever.
+func WithChunkedUpload(mode string) Option {
+	return func(c *Client) {
+		c.ChunkedUpload = mode
+	}
+}
+
+// WithUploadRateLimit caps uploads at this many bytes per second.
+func WithUploadRateLimit(bytesPerSecond int64) Option {
+	return func(c *Client) {
+		c.UploadRateLimit = bytesPerSecond
+	}
+}
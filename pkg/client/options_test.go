@@ -0,0 +1,36 @@
+package client
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestNewClientOptions(t *testing.T) {
+	c := NewClient("https://qmdverify.example.com",
+		WithToken("token"),
+		WithOrg("acme"),
+		WithTimeout(5*time.Second),
+		WithRetry(RetryPolicy{Retries: 3}),
+		WithChunkedUpload(ChunkedNever),
+		WithUploadRateLimit(1024),
+	)
+	if c.Token != "token" || c.Org != "acme" || c.Retry.Retries != 3 || c.ChunkedUpload != ChunkedNever || c.UploadRateLimit != 1024 {
+		t.Errorf("NewClient() = %+v", c)
+	}
+	if c.HTTPClient.Timeout != 5*time.Second {
+		t.Errorf("Timeout = %v, want 5s", c.HTTPClient.Timeout)
+	}
+	if NewClient("https://qmdverify.example.com").HTTPClient.Timeout != RequestTimeout {
+		t.Error("WithTimeout changed the default client")
+	}
+
+	hc := &http.Client{Timeout: time.Minute}
+	c = NewClient("unix:///run/qmdverify.sock", WithHTTPClient(hc))
+	if c.HTTPClient == hc || hc.Transport != nil {
+		t.Error("NewClient() set the socket transport on the caller's http.Client")
+	}
+	if c.HTTPClient.Timeout != time.Minute || c.HTTPClient.Transport == nil {
+		t.Errorf("HTTPClient = %+v, want a copy with the socket transport", c.HTTPClient)
+	}
+}
@@ -0,0 +1,54 @@
+package client
+
+import "io"
+
+// UploadProgress reports how far an upload has got. File is the file
+// whose bytes are currently being sent; Index counts from 1.
+type UploadProgress struct {
+	File       string
+	Index      int
+	Files      int
+	BytesSent  int64
+	BytesTotal int64
+}
+
+// progressReader reports progress while a multipart body is read by the
+// transport. ends holds the offset at which each file's part ends.
+type progressReader struct {
+	r      io.Reader
+	size   int64
+	files  []string
+	ends   []int64
+	sent   int64
+	report func(UploadProgress)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.sent += int64(n)
+		p.report(p.progress())
+	}
+	return n, err
+}
+
+func (p *progressReader) progress() UploadProgress {
+	index := len(p.files) - 1
+	for i, end := range p.ends {
+		if p.sent <= end {
+			index = i
+			break
+		}
+	}
+
+	progress := UploadProgress{
+		Index:      index + 1,
+		Files:      len(p.files),
+		BytesSent:  p.sent,
+		BytesTotal: p.size,
+	}
+	if index >= 0 {
+		progress.File = p.files[index]
+	}
+	return progress
+}
@@ -0,0 +1,62 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestClient_UploadProgress(t *testing.T) {
+	var received int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := io.Copy(io.Discard, r.Body)
+		received = n
+		json.NewEncoder(w).Encode(CompareJobResponse{JobID: "job"})
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	var paths []string
+	for _, name := range []string{"a.qmd", "b.qmd"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(strings.Repeat("x", 100000)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+
+	var updates []UploadProgress
+	client := NewClient(server.URL)
+	client.OnUploadProgress = func(p UploadProgress) { updates = append(updates, p) }
+
+	if _, err := client.submitCompareJobMulti(context.Background(), paths, []string{"a.qmd", "b.qmd"}); err != nil {
+		t.Fatalf("submitCompareJobMulti() error = %v", err)
+	}
+
+	if len(updates) == 0 {
+		t.Fatal("OnUploadProgress was never called")
+	}
+	last := updates[len(updates)-1]
+	if last.BytesSent != last.BytesTotal || last.BytesTotal != received {
+		t.Errorf("last update = %+v, server received %d bytes", last, received)
+	}
+
+	seen := make(map[string]bool)
+	index := 0
+	for _, p := range updates {
+		if p.Index < index || p.Files != 2 {
+			t.Fatalf("update out of order: %+v", p)
+		}
+		index = p.Index
+		seen[p.File] = true
+	}
+	if !seen["a.qmd"] || !seen["b.qmd"] {
+		t.Errorf("files reported = %v, want both", seen)
+	}
+}
@@ -0,0 +1,134 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// rateChunk caps how much a single read may take from the bucket, so a large
+// read is spread over several waits instead of one long one.
+const rateChunk = 32 << 10
+
+// rateLimiter is a token bucket holding up to one second of bytes. It is
+// shared by every request of a client, so the limit applies to all uploads
+// together.
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+	sleep  func(context.Context, time.Duration) error
+}
+
+func newRateLimiter(bytesPerSecond int64) *rateLimiter {
+	return &rateLimiter{
+		rate:   float64(bytesPerSecond),
+		tokens: float64(bytesPerSecond),
+		now:    time.Now,
+		sleep:  sleepContext,
+	}
+}
+
+// wait takes n bytes from the bucket, sleeping until they have been earned
+// or ctx is done.
+func (l *rateLimiter) wait(ctx context.Context, n int) error {
+	l.mu.Lock()
+	now := l.now()
+	if !l.last.IsZero() {
+		l.tokens = min(l.rate, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+	l.tokens -= float64(n)
+	deficit := -l.tokens
+	l.mu.Unlock()
+
+	if deficit > 0 {
+		return l.sleep(ctx, time.Duration(deficit/l.rate*float64(time.Second)))
+	}
+	return nil
+}
+
+func (l *rateLimiter) chunk() int {
+	return max(1, min(rateChunk, int(l.rate)))
+}
+
+type limitedReader struct {
+	ctx     context.Context
+	r       io.ReadCloser
+	limiter *rateLimiter
+}
+
+func (r *limitedReader) Read(b []byte) (int, error) {
+	if len(b) > r.limiter.chunk() {
+		b = b[:r.limiter.chunk()]
+	}
+	n, err := r.r.Read(b)
+	if n > 0 {
+		if werr := r.limiter.wait(r.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+func (r *limitedReader) Close() error {
+	return r.r.Close()
+}
+
+// limitUpload throttles the body of req to UploadRateLimit. Throttled
+// requests are sent with uploadClient, since the upload alone may outlast
+// the client's timeout.
+func (c *Client) limitUpload(req *http.Request) {
+	if c.UploadRateLimit <= 0 || req.Body == nil || req.Body == http.NoBody {
+		return
+	}
+
+	c.limiterOnce.Do(func() { c.limiter = newRateLimiter(c.UploadRateLimit) })
+	ctx := req.Context()
+	req.Body = &limitedReader{ctx: ctx, r: req.Body, limiter: c.limiter}
+	if getBody := req.GetBody; getBody != nil {
+		req.GetBody = func() (io.ReadCloser, error) {
+			body, err := getBody()
+			if err != nil {
+				return nil, err
+			}
+			return &limitedReader{ctx: ctx, r: body, limiter: c.limiter}, nil
+		}
+	}
+}
+
+// httpClient returns the client to send req with.
+func (c *Client) httpClient(req *http.Request) *http.Client {
+	if _, ok := req.Body.(*limitedReader); ok {
+		return c.uploadClient()
+	}
+	return c.HTTPClient
+}
+
+// uploadClient returns a copy of HTTPClient for throttled uploads. It drops
+// the overall timeout, which would include the time spent sending the body,
+// and uses it instead to bound the wait for the response headers. The
+// upload itself is bounded by the request's context.
+func (c *Client) uploadClient() *http.Client {
+	c.uploadOnce.Do(func() {
+		hc := *c.HTTPClient
+		if hc.Timeout > 0 {
+			transport, ok := hc.Transport.(*http.Transport)
+			if hc.Transport == nil {
+				transport, ok = http.DefaultTransport.(*http.Transport)
+			}
+			if ok {
+				transport = transport.Clone()
+				transport.ResponseHeaderTimeout = hc.Timeout
+				hc.Transport = transport
+			}
+			hc.Timeout = 0
+		}
+		c.upload = &hc
+	})
+	return c.upload
+}
@@ -0,0 +1,126 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// fakeClock advances only when the limiter sleeps.
+type fakeClock struct {
+	now   time.Time
+	slept time.Duration
+}
+
+func (c *fakeClock) limiter(rate int64) *rateLimiter {
+	l := newRateLimiter(rate)
+	l.now = func() time.Time { return c.now }
+	l.sleep = func(_ context.Context, d time.Duration) error {
+		c.slept += d
+		c.now = c.now.Add(d)
+		return nil
+	}
+	return l
+}
+
+func TestRateLimiter(t *testing.T) {
+	tests := []struct {
+		name      string
+		rate      int64
+		reads     []int
+		idle      time.Duration
+		wantSlept time.Duration
+	}{
+		{name: "within burst", rate: 1000, reads: []int{400, 600}, wantSlept: 0},
+		{name: "beyond burst", rate: 1000, reads: []int{1000, 500, 500}, wantSlept: time.Second},
+		{name: "idle time refills", rate: 1000, reads: []int{1000, 1000}, idle: time.Second, wantSlept: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := &fakeClock{now: time.Unix(0, 0)}
+			l := clock.limiter(tt.rate)
+			for i, n := range tt.reads {
+				if i > 0 {
+					clock.now = clock.now.Add(tt.idle)
+				}
+				l.wait(context.Background(), n)
+			}
+			if clock.slept != tt.wantSlept {
+				t.Errorf("slept %v, want %v", clock.slept, tt.wantSlept)
+			}
+		})
+	}
+}
+
+func TestClient_UploadRateLimit(t *testing.T) {
+	var received int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = len(body)
+	}))
+	defer server.Close()
+
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	client := NewClient(server.URL)
+	client.UploadRateLimit = 100 << 10
+	client.limiterOnce.Do(func() { client.limiter = clock.limiter(client.UploadRateLimit) })
+
+	req, err := http.NewRequest("POST", server.URL, bytes.NewReader(make([]byte, 300<<10)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.do(req)
+	if err != nil {
+		t.Fatalf("do() error = %v", err)
+	}
+	resp.Body.Close()
+
+	if received != 300<<10 {
+		t.Errorf("server received %d bytes, want %d", received, 300<<10)
+	}
+	if d := clock.slept - 2*time.Second; d < -time.Millisecond || d > time.Millisecond {
+		t.Errorf("upload throttled for %v, want 2s", clock.slept)
+	}
+}
+
+func TestClient_UploadRateLimitOutlastsTimeout(t *testing.T) {
+	var received int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = len(body)
+	}))
+	defer server.Close()
+
+	// 200KB at 100KB/s takes about a second, well past the timeout.
+	client := NewClient(server.URL, WithTimeout(200*time.Millisecond), WithUploadRateLimit(100<<10))
+	req, err := http.NewRequest("POST", server.URL, bytes.NewReader(make([]byte, 200<<10)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.do(req)
+	if err != nil {
+		t.Fatalf("do() error = %v", err)
+	}
+	resp.Body.Close()
+
+	if received != 200<<10 {
+		t.Errorf("server received %d bytes, want %d", received, 200<<10)
+	}
+}
+
+func TestRateLimiterCanceled(t *testing.T) {
+	l := newRateLimiter(1000)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	l.wait(ctx, 1000)
+	if err := l.wait(ctx, 1000); !errors.Is(err, context.Canceled) {
+		t.Errorf("wait() error = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,98 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// retryBaseDelay is the pause before the first retry. Each later retry
+// waits twice as long as the one before, up to the policy's MaxDelay.
+var retryBaseDelay = 500 * time.Millisecond
+
+// RetryPolicy decides how requests that fail with a network error or a 5xx
+// are retried. The zero value never retries.
+type RetryPolicy struct {
+	// Retries is how many times a failed request is sent again.
+	Retries int
+	// MaxDelay caps the backoff between attempts. Zero means no cap.
+	MaxDelay time.Duration
+	// Uploads also retries requests that submit files, which the server may
+	// already have accepted as a job. Only bodies that can be replayed are
+	// sent again.
+	Uploads bool
+}
+
+// retries reports how many times req may be sent again.
+func (p RetryPolicy) retries(req *http.Request) int {
+	if p.Retries <= 0 {
+		return 0
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return p.Retries
+	}
+	if p.Uploads {
+		return p.Retries
+	}
+	return 0
+}
+
+// delay is the pause before retry number attempt, counting from 1: the
+// exponential backoff with up to half of it replaced by jitter. A
+// Retry-After in seconds from the server is honored up to MaxDelay.
+func (p RetryPolicy) delay(attempt int, resp *http.Response) time.Duration {
+	d := retryBaseDelay << min(attempt-1, 20)
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			d = time.Duration(seconds) * time.Second
+		}
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// transient reports whether a request's outcome is worth retrying.
+func transient(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// send sends req, retrying transient failures as the client's Retry policy
+// allows. The last response or error is returned when retries run out.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	retries := c.Retry.retries(req)
+	for attempt := 1; ; attempt++ {
+		resp, err := c.httpClient(req).Do(req)
+		if attempt > retries || !transient(resp, err) || req.Context().Err() != nil {
+			return resp, err
+		}
+		next, ok := cloneRequest(req)
+		if !ok {
+			return resp, err
+		}
+
+		wait := c.Retry.delay(attempt, resp)
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if err := sleepContext(req.Context(), wait); err != nil {
+			return nil, err
+		}
+		req = next
+	}
+}
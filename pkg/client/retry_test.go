@@ -0,0 +1,97 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestClient_RetryTransient(t *testing.T) {
+	defer func(d time.Duration) { retryBaseDelay = d }(retryBaseDelay)
+	retryBaseDelay = time.Millisecond
+
+	tests := []struct {
+		name        string
+		policy      RetryPolicy
+		status      int
+		wantUploads int
+		wantPolls   int
+		wantErr     bool
+	}{
+		{name: "no retries", status: http.StatusBadGateway, wantUploads: 1, wantPolls: 1, wantErr: true},
+		{name: "polls retried", policy: RetryPolicy{Retries: 2}, status: http.StatusBadGateway, wantUploads: 1, wantPolls: 3},
+		{name: "uploads retried", policy: RetryPolicy{Retries: 2, Uploads: true}, status: http.StatusServiceUnavailable, wantUploads: 3, wantPolls: 3},
+		{name: "client errors not retried", policy: RetryPolicy{Retries: 2, Uploads: true}, status: http.StatusBadRequest, wantUploads: 1, wantPolls: 0, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uploads, polls := 0, 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/api/compare":
+					uploads++
+					if uploads <= 2 && tt.policy.Uploads {
+						w.WriteHeader(tt.status)
+						return
+					}
+					json.NewEncoder(w).Encode(CompareJobResponse{JobID: "job-1"})
+				case "/api/results/job-1":
+					polls++
+					if polls <= 2 {
+						w.WriteHeader(tt.status)
+						return
+					}
+					json.NewEncoder(w).Encode(JobResultsResponse{Status: "success", Results: &ComparisonResponse{TotalChecked: 1}})
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+
+			testFile := filepath.Join(t.TempDir(), "a.qmd")
+			if err := os.WriteFile(testFile, []byte("test"), 0644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+			client := NewClient(server.URL)
+			client.Retry = tt.policy
+			_, err := client.CompareQMD(context.Background(), testFile)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CompareQMD() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if uploads != tt.wantUploads || polls != tt.wantPolls {
+				t.Errorf("sent %d uploads and %d polls, want %d and %d", uploads, polls, tt.wantUploads, tt.wantPolls)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{MaxDelay: 4 * time.Second}
+	tests := []struct {
+		attempt    int
+		retryAfter string
+		min, max   time.Duration
+	}{
+		{attempt: 1, min: retryBaseDelay / 2, max: retryBaseDelay},
+		{attempt: 3, min: 2 * retryBaseDelay, max: 4 * retryBaseDelay},
+		{attempt: 10, min: 2 * time.Second, max: 4 * time.Second},
+		{attempt: 1, retryAfter: "2", min: time.Second, max: 2 * time.Second},
+		{attempt: 1, retryAfter: "60", min: 2 * time.Second, max: 4 * time.Second},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{Header: http.Header{}}
+		if tt.retryAfter != "" {
+			resp.Header.Set("Retry-After", tt.retryAfter)
+		}
+		for range 20 {
+			if d := policy.delay(tt.attempt, resp); d < tt.min || d > tt.max {
+				t.Fatalf("delay(%d, Retry-After %q) = %v, want between %v and %v", tt.attempt, tt.retryAfter, d, tt.min, tt.max)
+			}
+		}
+	}
+}
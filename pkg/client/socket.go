@@ -0,0 +1,30 @@
+package client
+
+import (
+	"context"
+	"net"
+	"net/http"
+)
+
+// unixBaseURL stands in for the server's URL when it is reached over a unix
+// socket; the transport ignores the host and dials the socket.
+const unixBaseURL = "http://unix"
+
+func unixTransport(socket string) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, "unix", socket)
+	}
+	return transport
+}
+
+// Address returns the server address the client was created with, such as
+// unix:///var/run/qmdverify.sock, for messages.
+func (c *Client) Address() string {
+	if c.socket != "" {
+		return "unix://" + c.socket
+	}
+	return c.BaseURL
+}
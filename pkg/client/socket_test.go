@@ -0,0 +1,51 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewClient_UnixSocket(t *testing.T) {
+	// Socket paths are limited to about 100 bytes, which t.TempDir() can
+	// exceed.
+	dir, err := os.MkdirTemp("", "qv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "verify.sock")
+
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/version" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(VersionResponse{Version: "1.2.3"})
+	}))
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	client := NewClient("unix://" + socket)
+	if got := client.Address(); got != "unix://"+socket {
+		t.Errorf("Address() = %q, want the socket address", got)
+	}
+
+	version, err := client.GetVersion(context.Background())
+	if err != nil {
+		t.Fatalf("GetVersion() error = %v", err)
+	}
+	if version.Version != "1.2.3" {
+		t.Errorf("GetVersion() = %+v", version)
+	}
+}
@@ -0,0 +1,226 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// uploadForm is a compare request body: each file's content as a part named
+// field, followed by its path when the form is a batch, and its SHA-256.
+// The body is streamed from disk as the request reads it, so no more than a
+// copy buffer of file content is held in memory.
+type uploadForm struct {
+	field     string
+	filePaths []string
+	names     []string
+	withPaths bool
+	boundary  string
+
+	// sizes is each file's size when the body was laid out.
+	sizes []int64
+}
+
+func newUploadForm(field string, filePaths, names []string, withPaths bool) *uploadForm {
+	return &uploadForm{
+		field:     field,
+		filePaths: filePaths,
+		names:     names,
+		withPaths: withPaths,
+		boundary:  multipart.NewWriter(io.Discard).Boundary(),
+	}
+}
+
+func (f *uploadForm) contentType() string {
+	return "multipart/form-data; boundary=" + f.boundary
+}
+
+// layout returns the size of the body and the offset at which each file's
+// fields end, from the sizes of the files on disk.
+func (f *uploadForm) layout() (int64, []int64, error) {
+	f.sizes = make([]int64, len(f.filePaths))
+	for i, filePath := range f.filePaths {
+		info, err := os.Stat(filePath)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to open file %s: %w", filePath, err)
+		}
+		f.sizes[i] = info.Size()
+	}
+
+	var counter countingWriter
+	ends := make([]int64, len(f.filePaths))
+	placeholder := strings.Repeat("0", hex.EncodedLen(sha256.Size))
+	err := f.encode(&counter, func(i int, _ io.Writer) (string, error) {
+		counter += countingWriter(f.sizes[i])
+		return placeholder, nil
+	}, func(i int) {
+		ends[i] = int64(counter)
+	})
+	return int64(counter), ends, err
+}
+
+// encode writes the form to w, with content writing each file's part and
+// returning its digest. done is called once each file's fields are written.
+func (f *uploadForm) encode(w io.Writer, content func(i int, part io.Writer) (string, error), done func(i int)) error {
+	writer := multipart.NewWriter(w)
+	if err := writer.SetBoundary(f.boundary); err != nil {
+		return err
+	}
+
+	for i, filePath := range f.filePaths {
+		part, err := writer.CreateFormFile(f.field, filepath.Base(filePath))
+		if err != nil {
+			return fmt.Errorf("failed to create form file: %w", err)
+		}
+		sum, err := content(i, part)
+		if err != nil {
+			return err
+		}
+		if f.withPaths {
+			writer.WriteField("paths", f.names[i])
+		}
+		writer.WriteField(ChecksumField, sum)
+		done(i)
+	}
+	return writer.Close()
+}
+
+type countingWriter int64
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	*c += countingWriter(len(p))
+	return len(p), nil
+}
+
+// multipartStream is a form written to a pipe as the request reads it.
+type multipartStream struct {
+	*io.PipeReader
+	done chan struct{}
+	// sums is complete once done is closed without an error.
+	sums map[string]string
+	err  error
+}
+
+func newMultipartStream(form *uploadForm) *multipartStream {
+	pr, pw := io.Pipe()
+	s := &multipartStream{PipeReader: pr, done: make(chan struct{}), sums: make(map[string]string, len(form.filePaths))}
+
+	go func() {
+		defer close(s.done)
+		s.err = form.encode(pw, s.copyFile(form), func(int) {})
+		pw.CloseWithError(s.err)
+	}()
+	return s
+}
+
+// copyFile returns a content function that copies each file into its part
+// while hashing it. A file whose size changed since the form was laid out
+// is an error, since the request length no longer matches.
+func (s *multipartStream) copyFile(form *uploadForm) func(int, io.Writer) (string, error) {
+	return func(i int, part io.Writer) (string, error) {
+		file, err := os.Open(form.filePaths[i])
+		if err != nil {
+			return "", fmt.Errorf("failed to open file %s: %w", form.filePaths[i], err)
+		}
+		defer file.Close()
+
+		digest := sha256.New()
+		n, err := io.Copy(io.MultiWriter(part, digest), io.LimitReader(file, form.sizes[i]+1))
+		if err != nil {
+			return "", fmt.Errorf("failed to copy file content: %w", err)
+		}
+		if n != form.sizes[i] {
+			return "", fmt.Errorf("%s changed while it was being uploaded", form.filePaths[i])
+		}
+
+		sum := hex.EncodeToString(digest.Sum(nil))
+		s.sums[form.names[i]] = sum
+		return sum, nil
+	}
+}
+
+// wait stops the stream if the request hasn't read all of it and returns
+// the error that ended it, if any.
+func (s *multipartStream) wait() error {
+	s.Close()
+	<-s.done
+	return s.err
+}
+
+// submitForm posts a compare request body streamed from disk, in resumable
+// chunks when the client's mode and the body size call for it, and returns
+// the response with the digest of each file sent. A request that is retried
+// reads the files again.
+func (c *Client) submitForm(ctx context.Context, form *uploadForm) (*http.Response, map[string]string, error) {
+	size, ends, err := form.layout()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if c.useChunked(size) {
+		uploadID, sums, err := c.uploadChunked(ctx, form, size, ends)
+		switch {
+		case err == nil:
+			resp, err := c.submitUpload(ctx, uploadID)
+			return resp, sums, err
+		case !errors.Is(err, errChunkedUnsupported) || c.ChunkedUpload == ChunkedAlways:
+			return nil, nil, err
+		}
+	}
+
+	var mu sync.Mutex
+	var last *multipartStream
+	open := func() (io.ReadCloser, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if last != nil {
+			last.wait()
+		}
+		last = newMultipartStream(form)
+		if c.OnUploadProgress == nil {
+			return last, nil
+		}
+		progress := &progressReader{r: last, size: size, files: form.names, ends: ends, report: c.OnUploadProgress}
+		return struct {
+			io.Reader
+			io.Closer
+		}{progress, last}, nil
+	}
+
+	body, _ := open()
+	req, err := http.NewRequestWithContext(ctx, "POST", c.BaseURL+"/api/compare", body)
+	if err != nil {
+		body.Close()
+		return nil, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.ContentLength = size
+	req.GetBody = open
+	req.Header.Set("Content-Type", form.contentType())
+
+	resp, err := c.do(req)
+
+	mu.Lock()
+	stream := last
+	mu.Unlock()
+	streamErr := stream.wait()
+	if err != nil {
+		if streamErr != nil && !errors.Is(streamErr, io.ErrClosedPipe) {
+			return nil, nil, streamErr
+		}
+		return nil, nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	if streamErr != nil && resp.StatusCode == http.StatusOK {
+		resp.Body.Close()
+		return nil, nil, streamErr
+	}
+	return resp, stream.sums, nil
+}
@@ -0,0 +1,117 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestClient_StreamedUpload(t *testing.T) {
+	dir := t.TempDir()
+	var files []string
+	want := make(map[string]string)
+	for _, name := range []string{"a.qmd", "b.qmd"} {
+		content := strings.Repeat(name+" content ", 10000)
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, path)
+		sum := sha256.Sum256([]byte(content))
+		want["sub/"+name] = hex.EncodeToString(sum[:])
+	}
+
+	tests := []struct {
+		name    string
+		echo    func(map[string]string) map[string]string
+		missing bool
+		wantErr string
+	}{
+		{name: "checksums match", echo: func(got map[string]string) map[string]string { return got }},
+		{name: "checksum mismatch", echo: func(got map[string]string) map[string]string {
+			return map[string]string{"sub/a.qmd": "bad", "sub/b.qmd": got["sub/b.qmd"]}
+		}, wantErr: "checksum mismatch for sub/a.qmd"},
+		{name: "missing file", missing: true, wantErr: "failed to open file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make(map[string]string)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/api/compare":
+					body, _ := io.ReadAll(r.Body)
+					if r.ContentLength != int64(len(body)) {
+						t.Errorf("ContentLength = %d, received %d bytes", r.ContentLength, len(body))
+					}
+					r.Body = io.NopCloser(bytes.NewReader(body))
+					reader, err := r.MultipartReader()
+					if err != nil {
+						w.WriteHeader(http.StatusBadRequest)
+						return
+					}
+					var paths []string
+					var sums []string
+					for {
+						part, err := reader.NextPart()
+						if err != nil {
+							break
+						}
+						data, _ := io.ReadAll(part)
+						switch part.FormName() {
+						case "files":
+							sum := sha256.Sum256(data)
+							sums = append(sums, hex.EncodeToString(sum[:]))
+						case "paths":
+							paths = append(paths, string(data))
+						}
+					}
+					for i, path := range paths {
+						got[path] = sums[i]
+					}
+					if tt.echo == nil {
+						w.WriteHeader(http.StatusBadRequest)
+						return
+					}
+					json.NewEncoder(w).Encode(CompareJobResponse{JobID: "job", Checksums: tt.echo(got)})
+				case "/api/results/job":
+					json.NewEncoder(w).Encode(BatchComparisonResponse{"sub/a.qmd": {TotalChecked: 1}, "sub/b.qmd": {TotalChecked: 1}})
+				}
+			}))
+			defer server.Close()
+
+			paths := files
+			if tt.missing {
+				paths = append(files[:1:1], filepath.Join(dir, "missing.qmd"))
+			}
+
+			client := NewClient(server.URL)
+			results, err := client.CompareQMDFiles(context.Background(), paths, []string{"sub/a.qmd", "sub/b.qmd"})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("CompareQMDFiles() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CompareQMDFiles() error = %v", err)
+			}
+			if len(*results) != 2 {
+				t.Errorf("CompareQMDFiles() = %v", *results)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("server received %v, want %v", got, want)
+			}
+		})
+	}
+}
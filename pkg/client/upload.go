@@ -0,0 +1,223 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"time"
+)
+
+// Chunked uploads follow the tus 1.0 core protocol with the creation
+// extension: the multipart body is created as an upload, sent in chunks with
+// PATCH and resumed from the server's offset after a failure, then submitted
+// for comparison by its ID.
+const (
+	UploadsPath            = "/api/uploads"
+	TusVersion             = "1.0.0"
+	ChunkSize              = 8 << 20
+	ChunkedUploadThreshold = 64 << 20
+	MaxChunkRetries        = 5
+)
+
+// Chunked upload modes. In auto mode bodies above ChunkedUploadThreshold are
+// uploaded in chunks when the server supports it.
+const (
+	ChunkedAuto   = "auto"
+	ChunkedAlways = "always"
+	ChunkedNever  = "never"
+)
+
+var errChunkedUnsupported = errors.New("server does not support chunked uploads")
+
+// chunkRetryDelay is the pause before resuming a failed chunk.
+var chunkRetryDelay = time.Second
+
+func (c *Client) useChunked(size int64) bool {
+	switch c.ChunkedUpload {
+	case ChunkedAlways:
+		return true
+	case ChunkedNever:
+		return false
+	default:
+		return size > ChunkedUploadThreshold
+	}
+}
+
+// submitUpload starts a comparison of a completed chunked upload.
+func (c *Client) submitUpload(ctx context.Context, uploadID string) (*http.Response, error) {
+	payload, err := json.Marshal(map[string]string{"upload_id": uploadID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.BaseURL+"/api/compare", bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	return resp, nil
+}
+
+// uploadChunked sends a form as a resumable upload, reading it from disk one
+// chunk at a time, and returns its ID with the digest of each file. A chunk
+// that fails is retried from the offset the server reports, up to
+// MaxChunkRetries times in a row; the upload cannot resume before the start
+// of the chunk being sent.
+func (c *Client) uploadChunked(ctx context.Context, form *uploadForm, size int64, ends []int64) (string, map[string]string, error) {
+	location, err := c.createUpload(ctx, size, form.contentType())
+	if err != nil {
+		return "", nil, err
+	}
+
+	stream := newMultipartStream(form)
+	defer stream.wait()
+
+	buf := make([]byte, min(ChunkSize, size))
+	var chunk []byte
+	var start, offset int64
+	failures := 0
+	for offset < size {
+		if offset == start+int64(len(chunk)) {
+			start = offset
+			n, err := io.ReadFull(stream, buf[:min(ChunkSize, size-offset)])
+			if err != nil {
+				if streamErr := stream.wait(); streamErr != nil {
+					return "", nil, streamErr
+				}
+				return "", nil, fmt.Errorf("failed to read upload body: %w", err)
+			}
+			chunk = buf[:n]
+		}
+
+		next, err := c.patchChunk(ctx, location, chunk[offset-start:], offset)
+		if err == nil && (next <= offset || next > start+int64(len(chunk))) {
+			err = fmt.Errorf("server did not accept the chunk at byte %d", offset)
+		}
+		if err == nil {
+			offset = next
+			failures = 0
+			if c.OnUploadProgress != nil {
+				p := &progressReader{size: size, files: form.names, ends: ends, sent: offset}
+				c.OnUploadProgress(p.progress())
+			}
+			continue
+		}
+
+		failures++
+		if failures > MaxChunkRetries {
+			return "", nil, fmt.Errorf("chunked upload failed at byte %d of %d: %w", offset, size, err)
+		}
+		if err := sleepContext(ctx, chunkRetryDelay); err != nil {
+			return "", nil, err
+		}
+		if resumed, err := c.uploadOffset(ctx, location); err == nil {
+			if resumed < start || resumed > start+int64(len(chunk)) {
+				return "", nil, fmt.Errorf("chunked upload cannot resume at byte %d of %d", resumed, size)
+			}
+			offset = resumed
+		}
+	}
+
+	if err := stream.wait(); err != nil {
+		return "", nil, err
+	}
+	return path.Base(location), stream.sums, nil
+}
+
+// createUpload creates an upload of the given size and returns its URL.
+func (c *Client) createUpload(ctx context.Context, size int64, contentType string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", c.BaseURL+UploadsPath, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Tus-Resumable", TusVersion)
+	req.Header.Set("Upload-Length", strconv.FormatInt(size, 10))
+	req.Header.Set("Upload-Metadata", "content-type "+base64.StdEncoding.EncodeToString([]byte(contentType)))
+
+	resp, err := c.do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to create upload: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusCreated:
+	case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return "", errChunkedUnsupported
+	default:
+		return "", fmt.Errorf("failed to create upload: %w", responseError(resp))
+	}
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || location.String() == "" {
+		return "", fmt.Errorf("server created an upload without a valid Location")
+	}
+	base, err := url.Parse(c.BaseURL + UploadsPath)
+	if err != nil {
+		return "", fmt.Errorf("invalid server URL: %w", err)
+	}
+	return base.ResolveReference(location).String(), nil
+}
+
+// patchChunk sends one chunk at offset and returns the server's new offset.
+func (c *Client) patchChunk(ctx context.Context, location string, chunk []byte, offset int64) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, "PATCH", location, bytes.NewReader(chunk))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Tus-Resumable", TusVersion)
+	req.Header.Set("Upload-Offset", strconv.FormatInt(offset, 10))
+	req.Header.Set("Content-Type", "application/offset+octet-stream")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return 0, responseError(resp)
+	}
+	return parseOffset(resp)
+}
+
+// uploadOffset asks the server how much of an upload it has received.
+func (c *Client) uploadOffset(ctx context.Context, location string) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, "HEAD", location, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Tus-Resumable", TusVersion)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return 0, &APIError{StatusCode: resp.StatusCode}
+	}
+	return parseOffset(resp)
+}
+
+func parseOffset(resp *http.Response) (int64, error) {
+	offset, err := strconv.ParseInt(resp.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("server returned an invalid Upload-Offset %q", resp.Header.Get("Upload-Offset"))
+	}
+	return offset, nil
+}
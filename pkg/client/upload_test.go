@@ -0,0 +1,126 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// tusServer accepts one chunked upload. When failFirstPatch is set, the first
+// PATCH keeps only part of its chunk and fails, so the client must resume.
+type tusServer struct {
+	failFirstPatch bool
+	noUploads      bool
+
+	received  bytes.Buffer
+	patches   int
+	submitted string
+	multipart bool
+}
+
+func (s *tusServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == UploadsPath && r.Method == "POST":
+		if s.noUploads {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Location", UploadsPath+"/up-1")
+		w.WriteHeader(http.StatusCreated)
+	case r.URL.Path == UploadsPath+"/up-1" && r.Method == "HEAD":
+		w.Header().Set("Upload-Offset", strconv.Itoa(s.received.Len()))
+		w.WriteHeader(http.StatusOK)
+	case r.URL.Path == UploadsPath+"/up-1" && r.Method == "PATCH":
+		s.patches++
+		if r.Header.Get("Upload-Offset") != strconv.Itoa(s.received.Len()) {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		chunk, _ := io.ReadAll(r.Body)
+		if s.failFirstPatch && s.patches == 1 {
+			s.received.Write(chunk[:len(chunk)/2])
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		s.received.Write(chunk)
+		w.Header().Set("Upload-Offset", strconv.Itoa(s.received.Len()))
+		w.WriteHeader(http.StatusNoContent)
+	case r.URL.Path == "/api/compare":
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/") {
+			s.multipart = true
+			io.Copy(&s.received, r.Body)
+		} else {
+			var req map[string]string
+			json.NewDecoder(r.Body).Decode(&req)
+			s.submitted = req["upload_id"]
+		}
+		json.NewEncoder(w).Encode(CompareJobResponse{JobID: "job"})
+	case r.URL.Path == "/api/results/job":
+		json.NewEncoder(w).Encode(BatchComparisonResponse{"a.qmd": {TotalChecked: 1}})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestClient_ChunkedUpload(t *testing.T) {
+	defer func(d time.Duration) { chunkRetryDelay = d }(chunkRetryDelay)
+	chunkRetryDelay = 0
+
+	testFile := filepath.Join(t.TempDir(), "a.qmd")
+	if err := os.WriteFile(testFile, []byte(strings.Repeat("qmd content ", 1000)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		mode          string
+		server        tusServer
+		wantErr       bool
+		wantChunked   bool
+		wantMultipart bool
+	}{
+		{name: "always", mode: ChunkedAlways, wantChunked: true},
+		{name: "resumes after failed chunk", mode: ChunkedAlways, server: tusServer{failFirstPatch: true}, wantChunked: true},
+		{name: "auto falls back without uploads endpoint", mode: ChunkedAuto, server: tusServer{noUploads: true}, wantMultipart: true},
+		{name: "always requires uploads endpoint", mode: ChunkedAlways, server: tusServer{noUploads: true}, wantErr: true},
+		{name: "small body in auto mode", mode: ChunkedAuto, wantMultipart: true},
+		{name: "never", mode: ChunkedNever, wantMultipart: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := tt.server
+			server := httptest.NewServer(&s)
+			defer server.Close()
+
+			client := NewClient(server.URL)
+			client.ChunkedUpload = tt.mode
+			_, err := client.CompareQMDFiles(context.Background(), []string{testFile}, []string{"a.qmd"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CompareQMDFiles() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if chunked := s.submitted == "up-1"; chunked != tt.wantChunked {
+				t.Errorf("submitted upload %q, want chunked = %v", s.submitted, tt.wantChunked)
+			}
+			if s.multipart != tt.wantMultipart {
+				t.Errorf("multipart request = %v, want %v", s.multipart, tt.wantMultipart)
+			}
+			if !strings.Contains(s.received.String(), "qmd content qmd content") || strings.Count(s.received.String(), `filename="a.qmd"`) != 1 {
+				t.Errorf("server did not receive the complete body (%d bytes)", s.received.Len())
+			}
+		})
+	}
+}